@@ -0,0 +1,341 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/renderer"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/urfave/cli/v2"
+
+	"github.com/opencloud-eu/opencloud/opencloud/pkg/eventbus"
+	"github.com/opencloud-eu/opencloud/opencloud/pkg/register"
+	"github.com/opencloud-eu/opencloud/pkg/config"
+	"github.com/opencloud-eu/opencloud/pkg/config/configlog"
+	"github.com/opencloud-eu/opencloud/pkg/config/parser"
+	"github.com/opencloud-eu/opencloud/pkg/runner"
+	ogrpc "github.com/opencloud-eu/opencloud/pkg/service/grpc"
+	ehsvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/eventhistory/v0"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/accounts"
+)
+
+// EventsCommand is the entrypoint for the events command.
+func EventsCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:     "events",
+		Usage:    "inspect and replay the events of the event bus",
+		Category: "maintenance",
+		Subcommands: []*cli.Command{
+			EventsTailCommand(cfg),
+			EventsReplayCommand(cfg),
+			EventsStatsCommand(cfg),
+		},
+		Before: func(_ *cli.Context) error {
+			return configlog.ReturnError(parser.ParseConfig(cfg, true))
+		},
+		Action: func(_ *cli.Context) error {
+			fmt.Println("Read the docs")
+			return nil
+		},
+	}
+}
+
+// eventFilterFlags are the flags selecting the events of the tail and replay commands.
+func eventFilterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "type",
+			Aliases: []string{"t"},
+			Usage:   "only events of this type, e.g. 'UploadReady' or 'events.UploadReady'. Can be repeated",
+		},
+		&cli.StringFlag{
+			Name:    "user",
+			Aliases: []string{"u"},
+			Usage:   "only events mentioning this user id",
+		},
+		&cli.StringFlag{
+			Name:    "space",
+			Aliases: []string{"s"},
+			Usage:   "only events concerning this space id",
+		},
+	}
+}
+
+// EventsTailCommand prints the events sent on the event bus.
+func EventsTailCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "tail",
+		Usage: "print the events sent on the event bus",
+		Flags: append(eventFilterFlags(),
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "also print the events sent since this time, either RFC3339 or a duration like '1h' ago",
+			},
+		),
+		Action: func(c *cli.Context) error {
+			since, err := parseEventsTime(c.String("since"))
+			if err != nil {
+				return err
+			}
+			f, err := eventbus.NewFilter(c.StringSlice("type"), c.String("user"), c.String("space"))
+			if err != nil {
+				return err
+			}
+			nc, js, err := connectEvents(cfg, "opencloud-events-tail")
+			if err != nil {
+				return err
+			}
+			defer nc.Close()
+
+			ctx, cancel := signal.NotifyContext(c.Context, runner.StopSignals...)
+			defer cancel()
+			return eventbus.Tail(ctx, js, since, f, printEvent)
+		},
+	}
+}
+
+// EventsReplayCommand sends events to a single consumer group again.
+func EventsReplayCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "replay",
+		Usage: "send events to a single consumer group again, e.g. to rebuild the activities of the activitylog",
+		Description: "The events are read from the event stream or from the eventhistory and delivered to one running service " +
+			"of the consumer group. Other consumer groups don't see them. Replayed events can't be acknowledged, " +
+			"so they are not redelivered when the service fails to process them. Pull consumer groups like " +
+			"'postprocessing-pull' and 'search-pull' are not supported.",
+		Flags: append(eventFilterFlags(),
+			&cli.StringFlag{
+				Name:     "group",
+				Aliases:  []string{"g"},
+				Usage:    "the consumer group to send the events to, see 'opencloud events stats'",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "source",
+				Usage: "where to read the events from. Can be 'stream' or 'eventhistory'",
+				Value: "stream",
+			},
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "replay the events of the stream sent since this time, either RFC3339 or a duration like '1h' ago",
+			},
+			&cli.StringFlag{
+				Name:  "until",
+				Usage: "replay the events of the stream sent until this time, either RFC3339 or a duration like '1h' ago",
+			},
+			&cli.StringSliceFlag{
+				Name:  "id",
+				Usage: "replay the events with this id from the eventhistory. Can be repeated",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "do not replay anything, just print what would be replayed",
+			},
+		),
+		Action: func(c *cli.Context) error {
+			f, err := eventbus.NewFilter(c.StringSlice("type"), c.String("user"), c.String("space"))
+			if err != nil {
+				return err
+			}
+			nc, js, err := connectEvents(cfg, "opencloud-events-replay")
+			if err != nil {
+				return err
+			}
+			defer nc.Close()
+
+			replay := func(e eventbus.Event) error {
+				fmt.Printf("%s %s\n", e.Type, e.ID)
+				return nil
+			}
+			if !c.Bool("dry-run") {
+				r, err := eventbus.NewReplayer(c.Context, nc, js, c.String("group"))
+				if err != nil {
+					return err
+				}
+				defer func() { _ = r.Flush() }()
+				replay = func(e eventbus.Event) error {
+					if err := r.Replay(e); err != nil {
+						return err
+					}
+					fmt.Printf("%s %s\n", e.Type, e.ID)
+					return nil
+				}
+			}
+
+			switch c.String("source") {
+			case "stream":
+				since, err := parseEventsTime(c.String("since"))
+				if err != nil {
+					return err
+				}
+				until, err := parseEventsTime(c.String("until"))
+				if err != nil {
+					return err
+				}
+				if since.IsZero() {
+					return errors.New("the start of the time range is required")
+				}
+				return eventbus.Range(c.Context, js, since, until, f, replay)
+			case "eventhistory":
+				return replayEventHistory(c.Context, cfg, c.StringSlice("id"), c.String("user"), f, replay)
+			default:
+				return fmt.Errorf("unknown source '%s'", c.String("source"))
+			}
+		},
+	}
+}
+
+// replayEventHistory replays the events with the given ids, or all events mentioning the user, from
+// the eventhistory.
+func replayEventHistory(ctx context.Context, cfg *config.Config, ids []string, user string, f *eventbus.Filter, fn func(eventbus.Event) error) error {
+	if len(ids) == 0 && user == "" {
+		return errors.New("the ids of the events or a user is required")
+	}
+	client, err := ogrpc.NewClient(ogrpc.GetClientOptions(cfg.GRPCClientTLS)...)
+	if err != nil {
+		return err
+	}
+	ehClient := ehsvc.NewEventHistoryService("eu.opencloud.api.eventhistory", client)
+
+	var resp *ehsvc.GetEventsResponse
+	if len(ids) > 0 {
+		resp, err = ehClient.GetEvents(ctx, &ehsvc.GetEventsRequest{Ids: ids})
+	} else {
+		resp, err = ehClient.GetEventsForUser(ctx, &ehsvc.GetEventsForUserRequest{UserID: user})
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, ev := range resp.GetEvents() {
+		e, err := eventbus.NewEvent(ev.GetId(), ev.GetType(), ev.GetEvent())
+		if err != nil {
+			return err
+		}
+		if !f.Match(e) {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EventsStatsCommand prints the lag of the consumer groups.
+func EventsStatsCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "stats",
+		Usage: "print the lag of the consumer group of each service",
+		Action: func(c *cli.Context) error {
+			nc, js, err := connectEvents(cfg, "opencloud-events-stats")
+			if err != nil {
+				return err
+			}
+			defer nc.Close()
+
+			stats, err := eventbus.Stats(c.Context, js)
+			if err != nil {
+				return err
+			}
+
+			r := tw.Rendition{
+				Settings: tw.Settings{
+					Separators: tw.Separators{
+						BetweenRows: tw.On,
+					},
+				},
+			}
+			tbl := tablewriter.NewTable(os.Stdout, tablewriter.WithRenderer(renderer.NewBlueprint(r)))
+			tbl.Header([]string{"Service", "Consumer Group", "Pending", "Ack Pending", "Redelivered", "Last Active", "Running"})
+			for _, s := range stats {
+				lastActive := "-"
+				if !s.LastActive.IsZero() {
+					lastActive = s.LastActive.Format(time.RFC3339)
+				}
+				_ = tbl.Append([]string{
+					s.Service,
+					s.Group,
+					strconv.FormatUint(s.Pending, 10),
+					strconv.Itoa(s.AckPending),
+					strconv.Itoa(s.Redelivered),
+					lastActive,
+					strconv.FormatBool(s.Running),
+				})
+			}
+			return tbl.Render()
+		},
+	}
+}
+
+// connectEvents connects to the event bus. When the nats service requires each service to use its
+// own user, the internal user of the nats service is used to see all consumer groups.
+func connectEvents(cfg *config.Config, name string) (*nats.Conn, jetstream.JetStream, error) {
+	evcfg := cfg.Postprocessing.Postprocessing.Events
+	bcfg := eventbus.Config{
+		Endpoint:             evcfg.Endpoint,
+		EnableTLS:            evcfg.EnableTLS,
+		TLSInsecure:          evcfg.TLSInsecure,
+		TLSRootCACertificate: evcfg.TLSRootCACertificate,
+		AuthUsername:         evcfg.AuthUsername,
+		AuthPassword:         evcfg.AuthPassword,
+	}
+	if auth := cfg.Nats.Nats.Authorization; auth.Enabled {
+		if auth.Secret == "" {
+			return nil, nil, errors.New("the nats authorization secret is required")
+		}
+		bcfg.AuthUsername = accounts.InternalUser
+		bcfg.AuthPassword = accounts.Password(auth.Secret, accounts.InternalUser)
+	}
+
+	nc, err := eventbus.Connect(name, bcfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, nil, err
+	}
+	return nc, js, nil
+}
+
+// parseEventsTime parses a time given as RFC3339 or as a duration before now.
+func parseEventsTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time '%s', expected RFC3339 or a duration", v)
+	}
+	return t, nil
+}
+
+// printEvent prints an event with its indented payload.
+func printEvent(e eventbus.Event) error {
+	var payload bytes.Buffer
+	if err := json.Indent(&payload, e.Payload, "", "  "); err != nil {
+		payload.Reset()
+		payload.Write(e.Payload)
+	}
+	fmt.Printf("%s %s %s\n%s\n\n", e.Timestamp.Format(time.RFC3339), e.Type, e.ID, payload.String())
+	return nil
+}
+
+func init() {
+	register.AddCommand(EventsCommand)
+}
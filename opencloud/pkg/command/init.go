@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	ocinit "github.com/opencloud-eu/opencloud/opencloud/pkg/init"
 	"github.com/opencloud-eu/opencloud/opencloud/pkg/register"
@@ -45,6 +46,16 @@ func InitCommand(cfg *config.Config) *cli.Command {
 				Usage:   "Config path for the OpenCloud runtime",
 				EnvVars: []string{"OC_CONFIG_DIR", "OC_BASE_DATA_PATH"},
 			},
+			&cli.BoolFlag{
+				Name:  "rotate",
+				Usage: "Replace the secrets of an existing config with new ones. The old secrets are still accepted until the overlap has passed",
+				Value: false,
+			},
+			&cli.DurationFlag{
+				Name:  "overlap",
+				Usage: "How long the old secrets are still accepted after a rotation",
+				Value: 24 * time.Hour,
+			},
 			&cli.StringFlag{
 				Name:    "admin-password",
 				Aliases: []string{"ap"},
@@ -53,6 +64,16 @@ func InitCommand(cfg *config.Config) *cli.Command {
 			},
 		},
 		Action: func(c *cli.Context) error {
+			if c.Bool("rotate") {
+				if c.Bool("diff") || c.Bool("force-overwrite") || c.String("admin-password") != "" {
+					log.Fatalf("Could not rotate secrets: the rotate flag can not be combined with the diff, force-overwrite and admin-password flags")
+				}
+				if err := ocinit.RotateSecrets(c.String("config-path"), c.Duration("overlap")); err != nil {
+					log.Fatalf("Could not rotate secrets: %s", err)
+				}
+				return nil
+			}
+
 			insecureFlag := c.String("insecure")
 			insecure := false
 			if insecureFlag == "ask" {
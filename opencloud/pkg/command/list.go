@@ -17,20 +17,7 @@ func ListCommand(cfg *config.Config) *cli.Command {
 		Name:     "list",
 		Usage:    "list OpenCloud services running in the runtime (supervised mode)",
 		Category: "runtime",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:        "hostname",
-				Value:       "localhost",
-				EnvVars:     []string{"OC_RUNTIME_HOST"},
-				Destination: &cfg.Runtime.Host,
-			},
-			&cli.StringFlag{
-				Name:        "port",
-				Value:       "9250",
-				EnvVars:     []string{"OC_RUNTIME_PORT"},
-				Destination: &cfg.Runtime.Port,
-			},
-		},
+		Flags:    runtimeFlags(cfg),
 		Action: func(c *cli.Context) error {
 			client, err := rpc.DialHTTP("tcp", net.JoinHostPort(cfg.Runtime.Host, cfg.Runtime.Port))
 			if err != nil {
@@ -0,0 +1,139 @@
+package command
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+
+	"github.com/opencloud-eu/opencloud/opencloud/pkg/register"
+	"github.com/opencloud-eu/opencloud/opencloud/pkg/runtime/service"
+	"github.com/opencloud-eu/opencloud/pkg/config"
+	"github.com/opencloud-eu/opencloud/pkg/config/configlog"
+	"github.com/opencloud-eu/opencloud/pkg/config/parser"
+)
+
+// StopCommand is the entrypoint for the stop command.
+func StopCommand(cfg *config.Config) *cli.Command {
+	return serviceControlCommand(cfg, "stop", "stop a service running in the runtime (supervised mode)", "Service.Stop")
+}
+
+// StartCommand is the entrypoint for the start command.
+func StartCommand(cfg *config.Config) *cli.Command {
+	return serviceControlCommand(cfg, "start", "start a stopped service in the runtime (supervised mode)", "Service.Start")
+}
+
+// RestartCommand is the entrypoint for the restart command.
+func RestartCommand(cfg *config.Config) *cli.Command {
+	return serviceControlCommand(cfg, "restart", "restart a service running in the runtime (supervised mode)", "Service.Restart")
+}
+
+// StatusCommand is the entrypoint for the status command.
+func StatusCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:     "status",
+		Usage:    "show the status of the OpenCloud services in the runtime (supervised mode)",
+		Category: "runtime",
+		Flags:    runtimeFlags(cfg),
+		Action: func(c *cli.Context) error {
+			client, err := dialRuntime(cfg)
+			if err != nil {
+				return err
+			}
+
+			var status []service.ServiceStatus
+			if err := client.Call("Service.Status", struct{}{}, &status); err != nil {
+				return err
+			}
+
+			table := tablewriter.NewTable(os.Stdout)
+			table.Header([]string{"Service", "Status", "Uptime", "Restarts", "Last Failure"})
+			for _, s := range status {
+				state, uptime := "stopped", ""
+				if s.Running {
+					state, uptime = "running", time.Since(s.StartedAt).Truncate(time.Second).String()
+				}
+				lastFailure := ""
+				if s.LastFailure != "" {
+					lastFailure = fmt.Sprintf("%s: %s", s.LastFailureAt.Format(time.RFC3339), s.LastFailure)
+				}
+				table.Append([]string{s.Name, state, uptime, fmt.Sprint(s.Restarts), lastFailure})
+			}
+			return table.Render()
+		},
+	}
+}
+
+// serviceControlCommand returns a command calling the given runtime rpc method with a service name.
+func serviceControlCommand(cfg *config.Config, name, usage, method string) *cli.Command {
+	return &cli.Command{
+		Name:      name,
+		Usage:     usage,
+		ArgsUsage: "SERVICE",
+		Category:  "runtime",
+		Flags:     runtimeFlags(cfg),
+		Before: func(c *cli.Context) error {
+			// the machine auth api key authorizes the request
+			return configlog.ReturnError(parser.ParseConfig(cfg, true))
+		},
+		Action: func(c *cli.Context) error {
+			svc := strings.TrimSpace(c.Args().First())
+			if svc == "" || c.NArg() > 1 {
+				return fmt.Errorf("exactly one service name is required, use the 'list' command to see the running services")
+			}
+
+			client, err := dialRuntime(cfg)
+			if err != nil {
+				return err
+			}
+
+			var reply string
+			req := service.ControlRequest{Service: svc, MachineAuthAPIKey: cfg.MachineAuthAPIKey}
+			if err := client.Call(method, req, &reply); err != nil {
+				return err
+			}
+
+			fmt.Println(reply)
+			return nil
+		},
+	}
+}
+
+// runtimeFlags returns the flags needed to connect to the runtime.
+func runtimeFlags(cfg *config.Config) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "hostname",
+			Value:       "localhost",
+			EnvVars:     []string{"OC_RUNTIME_HOST"},
+			Destination: &cfg.Runtime.Host,
+		},
+		&cli.StringFlag{
+			Name:        "port",
+			Value:       "9250",
+			EnvVars:     []string{"OC_RUNTIME_PORT"},
+			Destination: &cfg.Runtime.Port,
+		},
+	}
+}
+
+// dialRuntime connects to the rpc endpoint of the runtime.
+func dialRuntime(cfg *config.Config) (*rpc.Client, error) {
+	client, err := rpc.DialHTTP("tcp", net.JoinHostPort(cfg.Runtime.Host, cfg.Runtime.Port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the runtime. Has the runtime been started and did you configure the right runtime address (\"%s\"): %w", net.JoinHostPort(cfg.Runtime.Host, cfg.Runtime.Port), err)
+	}
+	return client, nil
+}
+
+func init() {
+	register.AddCommand(StopCommand)
+	register.AddCommand(StartCommand)
+	register.AddCommand(RestartCommand)
+	register.AddCommand(StatusCommand)
+}
@@ -0,0 +1,242 @@
+// Package eventbus inspects the event stream the services exchange their events on: it follows
+// and decodes the events, replays them to a consumer group and reports the lag of the consumer
+// groups.
+package eventbus
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	mevents "go-micro.dev/v4/events"
+)
+
+// _typePrefix is the package prefix of the event types in the metadata of the events
+const _typePrefix = "events."
+
+// Config holds the settings to connect to the event bus.
+type Config struct {
+	Endpoint             string
+	EnableTLS            bool
+	TLSInsecure          bool
+	TLSRootCACertificate string
+	AuthUsername         string
+	AuthPassword         string
+}
+
+// Connect connects to the event bus.
+func Connect(name string, cfg Config) (*nats.Conn, error) {
+	opts := []nats.Option{nats.Name(name)}
+	if cfg.AuthUsername != "" {
+		opts = append(opts, nats.UserInfo(cfg.AuthUsername, cfg.AuthPassword))
+	}
+	if cfg.EnableTLS {
+		tlsConf := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: cfg.TLSInsecure, //nolint:gosec
+		}
+		if cfg.TLSRootCACertificate != "" {
+			pem, err := os.ReadFile(cfg.TLSRootCACertificate)
+			if err != nil {
+				return nil, err
+			}
+			tlsConf.RootCAs = x509.NewCertPool()
+			if !tlsConf.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, errors.New("could not load the root ca certificate")
+			}
+			tlsConf.InsecureSkipVerify = false
+		}
+		opts = append(opts, nats.Secure(tlsConf))
+	}
+	return nats.Connect(cfg.Endpoint, opts...)
+}
+
+// Event is an event as it is sent on the event bus.
+type Event struct {
+	// ID is the id of the event the services know it by
+	ID        string
+	Type      string
+	Timestamp time.Time
+	Payload   json.RawMessage
+	// data is the encoded message of the event
+	data []byte
+}
+
+// Decode decodes a message of the event stream.
+func Decode(data []byte) (Event, error) {
+	var e mevents.Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:        e.Metadata[events.MetadatakeyEventID],
+		Type:      e.Metadata[events.MetadatakeyEventType],
+		Timestamp: e.Timestamp,
+		Payload:   e.Payload,
+		data:      data,
+	}, nil
+}
+
+// NewEvent returns an event of the given type, e.g. from the eventhistory.
+func NewEvent(id, typ string, payload []byte) (Event, error) {
+	e := mevents.Event{
+		ID:        id,
+		Topic:     events.MainQueueName,
+		Timestamp: time.Now(),
+		Metadata: map[string]string{
+			events.MetadatakeyEventType: typ,
+			events.MetadatakeyEventID:   id,
+		},
+		Payload: payload,
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return Event{}, err
+	}
+	return Decode(data)
+}
+
+// Filter selects events by their type, the user and the space they concern.
+type Filter struct {
+	types []string
+	user  *regexp.Regexp
+	space *regexp.Regexp
+}
+
+// NewFilter returns a filter matching events of one of the types, mentioning the user and the space.
+// Empty values match all events. The types may omit the "events." prefix.
+func NewFilter(types []string, user, space string) (*Filter, error) {
+	f := &Filter{}
+	for _, t := range types {
+		if !strings.HasPrefix(t, _typePrefix) {
+			t = _typePrefix + t
+		}
+		f.types = append(f.types, t)
+	}
+
+	// events put the ids in many different fields, like the eventhistory we look for the id
+	// between two non-word characters
+	var err error
+	if user != "" {
+		if f.user, err = regexp.Compile(fmt.Sprintf(`\W%s\W`, regexp.QuoteMeta(user))); err != nil {
+			return nil, err
+		}
+	}
+	if space != "" {
+		// resource ids only carry the space id itself
+		if rid, err := storagespace.ParseID(space); err == nil && rid.GetSpaceId() != "" {
+			space = rid.GetSpaceId()
+		}
+		if f.space, err = regexp.Compile(fmt.Sprintf(`\W%s\W`, regexp.QuoteMeta(space))); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Match checks whether an event passes the filter.
+func (f *Filter) Match(e Event) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.types) > 0 && !slices.Contains(f.types, e.Type) {
+		return false
+	}
+	if f.user != nil && !f.user.Match(e.Payload) {
+		return false
+	}
+	if f.space != nil && !f.space.Match(e.Payload) {
+		return false
+	}
+	return true
+}
+
+// Tail follows the event stream from the given time, or from now on if it is zero, and calls fn for
+// every event passing the filter until the context is done or fn returns an error.
+func Tail(ctx context.Context, js jetstream.JetStream, since time.Time, f *Filter, fn func(Event) error) error {
+	cfg := jetstream.OrderedConsumerConfig{DeliverPolicy: jetstream.DeliverNewPolicy}
+	if !since.IsZero() {
+		cfg.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+		cfg.OptStartTime = &since
+	}
+	c, err := js.OrderedConsumer(ctx, events.MainQueueName, cfg)
+	if err != nil {
+		return err
+	}
+	msgs, err := c.Messages()
+	if err != nil {
+		return err
+	}
+	defer msgs.Stop()
+	go func() {
+		<-ctx.Done()
+		msgs.Stop()
+	}()
+
+	for {
+		msg, err := msgs.Next()
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil:
+			return err
+		}
+		e, err := Decode(msg.Data())
+		if err != nil || !f.Match(e) {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+// Range calls fn for every event of the stream published between since and until passing the
+// filter. A zero until reads up to the end of the stream.
+func Range(ctx context.Context, js jetstream.JetStream, since, until time.Time, f *Filter, fn func(Event) error) error {
+	c, err := js.OrderedConsumer(ctx, events.MainQueueName, jetstream.OrderedConsumerConfig{
+		DeliverPolicy: jetstream.DeliverByStartTimePolicy,
+		OptStartTime:  &since,
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := c.Next(jetstream.FetchMaxWait(time.Second))
+		switch {
+		case errors.Is(err, nats.ErrTimeout) || errors.Is(err, jetstream.ErrNoMessages):
+			// nothing was published since
+			return nil
+		case err != nil:
+			return err
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			return err
+		}
+		if !until.IsZero() && meta.Timestamp.After(until) {
+			return nil
+		}
+		if e, err := Decode(msg.Data()); err == nil && f.Match(e) {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		if meta.NumPending == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package eventbus_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-micro/plugins/v4/events/natsjs"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/events/raw"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencloud-eu/opencloud/opencloud/pkg/eventbus"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/logging"
+	natsserver "github.com/opencloud-eu/opencloud/services/nats/pkg/server/nats"
+)
+
+// startServer starts a nats server and returns its address.
+func startServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	s, err := natsserver.NewNATSServer(
+		logging.NewLogWrapper(log.NopLogger()),
+		natsserver.Host("127.0.0.1"),
+		natsserver.Port(port),
+		natsserver.StoreDir(t.TempDir()),
+	)
+	require.NoError(t, err)
+	go func() { _ = s.ListenAndServe() }()
+	t.Cleanup(s.Shutdown)
+	require.True(t, s.Ready(10*time.Second))
+	return fmt.Sprintf("127.0.0.1:%d", port)
+}
+
+func stream(t *testing.T, addr string) events.Stream {
+	t.Helper()
+	s, err := natsjs.NewStream(natsjs.Address(addr), natsjs.SynchronousPublish(true))
+	require.NoError(t, err)
+	return s
+}
+
+func jetStream(t *testing.T, addr string) (*nats.Conn, jetstream.JetStream) {
+	t.Helper()
+	nc, err := eventbus.Connect("eventbus-test", eventbus.Config{Endpoint: addr})
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	return nc, js
+}
+
+func TestFilter(t *testing.T) {
+	ev, err := eventbus.NewEvent("1", "events.ContainerCreated", []byte(`{"Executant":{"opaque_id":"einstein"},"Ref":{"resource_id":{"storage_id":"storage","space_id":"space-1","opaque_id":"node"}}}`))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		types        []string
+		user, space  string
+		expectsMatch bool
+	}{
+		{expectsMatch: true},
+		{types: []string{"ContainerCreated"}, expectsMatch: true},
+		{types: []string{"events.FileUploaded", "events.ContainerCreated"}, expectsMatch: true},
+		{types: []string{"FileUploaded"}},
+		{user: "einstein", expectsMatch: true},
+		{user: "einst"},
+		{space: "space-1", expectsMatch: true},
+		{space: "storage$space-1", expectsMatch: true},
+		{space: "storage$space-1!node", expectsMatch: true},
+		{space: "space-2"},
+		{types: []string{"ContainerCreated"}, user: "einstein", space: "space-2"},
+	} {
+		f, err := eventbus.NewFilter(tc.types, tc.user, tc.space)
+		require.NoError(t, err)
+		require.Equal(t, tc.expectsMatch, f.Match(ev), "%+v", tc)
+	}
+}
+
+func TestRangeAndReplay(t *testing.T) {
+	addr := startServer(t)
+	ctx := context.Background()
+	since := time.Now()
+
+	thumbnails, err := events.Consume(stream(t, addr), "thumbnails", events.UserDeleted{}, events.SpaceCreated{})
+	require.NoError(t, err)
+	audit, err := events.Consume(stream(t, addr), "audit", events.UserDeleted{}, events.SpaceCreated{})
+	require.NoError(t, err)
+	pull, err := raw.FromConfig(ctx, "search", raw.Config{Endpoint: addr})
+	require.NoError(t, err)
+	_, err = pull.Consume("search-pull", events.UserDeleted{})
+	require.NoError(t, err)
+
+	publisher := stream(t, addr)
+	require.NoError(t, events.Publish(ctx, publisher, events.UserDeleted{UserID: "einstein"}))
+	require.NoError(t, events.Publish(ctx, publisher, events.SpaceCreated{ID: &provider.StorageSpaceId{OpaqueId: "storage$space-1"}}))
+	require.NoError(t, events.Publish(ctx, publisher, events.UserDeleted{UserID: "marie"}))
+	for _, ch := range []<-chan events.Event{thumbnails, audit, thumbnails, audit, thumbnails, audit} {
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatal("event not delivered")
+		}
+	}
+
+	nc, js := jetStream(t, addr)
+	f, err := eventbus.NewFilter([]string{"UserDeleted"}, "", "")
+	require.NoError(t, err)
+	var found []eventbus.Event
+	require.NoError(t, eventbus.Range(ctx, js, since, time.Time{}, f, func(e eventbus.Event) error {
+		found = append(found, e)
+		return nil
+	}))
+	require.Len(t, found, 2)
+	require.Equal(t, "events.UserDeleted", found[0].Type)
+	require.NotEmpty(t, found[0].ID)
+
+	_, err = eventbus.NewReplayer(ctx, nc, js, "search-pull")
+	require.ErrorIs(t, err, eventbus.ErrPullConsumer)
+
+	r, err := eventbus.NewReplayer(ctx, nc, js, "thumbnails")
+	require.NoError(t, err)
+	require.NoError(t, r.Replay(found[1]))
+	require.NoError(t, r.Flush())
+
+	select {
+	case ev := <-thumbnails:
+		require.Equal(t, found[1].ID, ev.ID)
+		require.Equal(t, "marie", ev.Event.(events.UserDeleted).UserID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("replayed event not delivered")
+	}
+	select {
+	case ev := <-audit:
+		t.Fatalf("replayed event delivered to another consumer group: %v", ev)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	stats, err := eventbus.Stats(ctx, js)
+	require.NoError(t, err)
+	services := map[string]string{}
+	for _, s := range stats {
+		services[s.Group] = s.Service
+	}
+	require.Equal(t, map[string]string{"thumbnails": "thumbnails", "audit": "audit", "search-pull": "search"}, services)
+}
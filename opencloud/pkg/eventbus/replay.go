@@ -0,0 +1,58 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+)
+
+var (
+	// ErrPullConsumer is returned when events are replayed to a consumer group fetching its events.
+	// The events can only be delivered to consumer groups the server pushes the events to.
+	ErrPullConsumer = errors.New("events can't be replayed to a pull consumer")
+	// ErrNotBound is returned when no service of the consumer group is running.
+	ErrNotBound = errors.New("no service of the consumer group is running")
+)
+
+// Replayer delivers events to a single consumer group, bypassing the event stream. All other
+// consumer groups don't see the replayed events.
+type Replayer struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewReplayer returns a Replayer for the consumer group. The services of the consumer group have to
+// be running, as the events are delivered to them directly.
+func NewReplayer(ctx context.Context, conn *nats.Conn, js jetstream.JetStream, group string) (*Replayer, error) {
+	s, err := js.Stream(ctx, events.MainQueueName)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.PushConsumer(ctx, group)
+	switch {
+	case errors.Is(err, jetstream.ErrNotPushConsumer):
+		return nil, ErrPullConsumer
+	case err != nil:
+		return nil, fmt.Errorf("could not get the consumer group '%s': %w", group, err)
+	}
+	info := c.CachedInfo()
+	if !info.PushBound {
+		return nil, ErrNotBound
+	}
+	return &Replayer{conn: conn, subject: info.Config.DeliverSubject}, nil
+}
+
+// Replay delivers the event to one service of the consumer group. The services can't acknowledge
+// replayed events, so events failing to be processed are not redelivered.
+func (r *Replayer) Replay(e Event) error {
+	return r.conn.Publish(r.subject, e.data)
+}
+
+// Flush waits until the server received all replayed events.
+func (r *Replayer) Flush() error {
+	return r.conn.Flush()
+}
@@ -0,0 +1,76 @@
+package eventbus
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+)
+
+// _groupServices maps the consumer groups not named after their service to the service.
+var _groupServices = map[string]string{
+	"evhistory":           "eventhistory",
+	"graph-quota":         "graph",
+	"postprocessing-pull": "postprocessing",
+	"search-pull":         "search",
+}
+
+// ConsumerStats describes the lag of a consumer group.
+type ConsumerStats struct {
+	Group   string
+	Service string
+	// Pending is the number of events not yet delivered to the consumer group
+	Pending uint64
+	// AckPending is the number of delivered events not yet acknowledged
+	AckPending int
+	// Redelivered is the number of events delivered more than once
+	Redelivered int
+	// LastActive is the time of the last delivery, it is zero if nothing was delivered yet
+	LastActive time.Time
+	// Running is set when a service of the consumer group is connected
+	Running bool
+}
+
+// Stats returns the lag of all consumer groups of the event stream. Ephemeral consumers are skipped.
+func Stats(ctx context.Context, js jetstream.JetStream) ([]ConsumerStats, error) {
+	s, err := js.Stream(ctx, events.MainQueueName)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []ConsumerStats
+	lister := s.ListConsumers(ctx)
+	for info := range lister.Info() {
+		if info.Config.Durable == "" {
+			// ephemeral consumers, like the ones following the stream, don't belong to a service
+			continue
+		}
+		cs := ConsumerStats{
+			Group:       info.Name,
+			Service:     Service(info.Name),
+			Pending:     info.NumPending,
+			AckPending:  info.NumAckPending,
+			Redelivered: info.NumRedelivered,
+			Running:     info.PushBound || info.NumWaiting > 0,
+		}
+		if info.Delivered.Last != nil {
+			cs.LastActive = *info.Delivered.Last
+		}
+		stats = append(stats, cs)
+	}
+	return stats, lister.Err()
+}
+
+// Service returns the service of a consumer group.
+func Service(group string) string {
+	if s, ok := _groupServices[group]; ok {
+		return s
+	}
+	// every sse instance has its own consumer group
+	if strings.HasPrefix(group, "sse-") {
+		return "sse"
+	}
+	return group
+}
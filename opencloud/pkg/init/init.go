@@ -69,6 +69,7 @@ func CreateConfig(insecure, forceOverwrite, diff bool, configPath, adminPassword
 		idmServicePassword, idpServicePassword, ocAdminServicePassword, revaServicePassword  string
 		tokenManagerJwtSecret, collaborationWOPISecret, machineAuthAPIKey, systemUserAPIKey  string
 		revaTransferSecret, thumbnailsTransferSecret, serviceAccountSecret                   string
+		natsAuthorizationSecret                                                              string
 	)
 
 	if diff {
@@ -95,6 +96,13 @@ func CreateConfig(insecure, forceOverwrite, diff bool, configPath, adminPassword
 		revaTransferSecret = oldCfg.TransferSecret
 		thumbnailsTransferSecret = oldCfg.Thumbnails.Thumbnail.TransferSecret
 		serviceAccountSecret = oldCfg.Graph.ServiceAccount.ServiceAccountSecret
+		natsAuthorizationSecret = oldCfg.Nats.Nats.Authorization.Secret
+		if natsAuthorizationSecret == "" {
+			natsAuthorizationSecret, err = generators.GenerateRandomPassword(passwordLength)
+			if err != nil {
+				return fmt.Errorf("could not generate random secret for the nats authorization: %s", err)
+			}
+		}
 	} else {
 		systemUserID = uuid.Must(uuid.NewV4()).String()
 		adminUserID = uuid.Must(uuid.NewV4()).String()
@@ -150,6 +158,10 @@ func CreateConfig(insecure, forceOverwrite, diff bool, configPath, adminPassword
 		if err != nil {
 			return fmt.Errorf("could not generate random password for thumbnailsTransferSecret: %s", err)
 		}
+		natsAuthorizationSecret, err = generators.GenerateRandomPassword(passwordLength)
+		if err != nil {
+			return fmt.Errorf("could not generate random secret for the nats authorization: %s", err)
+		}
 	}
 
 	serviceAccount := ServiceAccount{
@@ -179,6 +191,11 @@ func CreateConfig(insecure, forceOverwrite, diff bool, configPath, adminPassword
 				BindPassword: idpServicePassword,
 			},
 		},
+		Invitations: LdapBasedService{
+			Ldap: LdapSettings{
+				BindPassword: idmServicePassword,
+			},
+		},
 		AuthBasic: AuthbasicService{
 			AuthProviders: LdapBasedService{
 				Ldap: LdapSettings{
@@ -261,6 +278,15 @@ func CreateConfig(insecure, forceOverwrite, diff bool, configPath, adminPassword
 			ServiceAccount: serviceAccount,
 		},
 	}
+	cfg.Nats.Nats.Authorization.Secret = natsAuthorizationSecret
+
+	if diff {
+		// keep the secrets of a previous rotation
+		cfg.TokenManager.PreviousJWTSecrets = oldCfg.TokenManager.PreviousJWTSecrets
+		cfg.PreviousMachineAuthAPIKeys = oldCfg.PreviousMachineAuthAPIKeys
+		cfg.Thumbnails.Thumbnail.PreviousTransferSecrets = oldCfg.Thumbnails.Thumbnail.PreviousTransferSecrets
+		cfg.AuthService.ServiceAccount.PreviousServiceAccountSecrets = oldCfg.AuthService.ServiceAccount.PreviousServiceAccountSecrets
+	}
 
 	if insecure {
 		cfg.AuthBearer = AuthbearerService{
@@ -0,0 +1,224 @@
+package init
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/opencloud-eu/opencloud/pkg/generators"
+)
+
+// rotatedSecret describes where a rotatable secret and the list of its previous values are stored in the config file.
+type rotatedSecret struct {
+	name    string
+	current []string
+	// previous is empty for secrets that are replaced without an overlap
+	previous []string
+	// copies is the path below each service where the services using the secret keep a copy of it
+	copies []string
+}
+
+var _rotatedSecrets = []rotatedSecret{
+	{
+		name:     "jwt secret",
+		current:  []string{"token_manager", "jwt_secret"},
+		previous: []string{"token_manager", "previous_jwt_secrets"},
+	},
+	{
+		name:     "machine auth api key",
+		current:  []string{"machine_auth_api_key"},
+		previous: []string{"previous_machine_auth_api_keys"},
+	},
+	{
+		name:     "thumbnails transfer secret",
+		current:  []string{"thumbnails", "thumbnail", "transfer_secret"},
+		previous: []string{"thumbnails", "thumbnail", "previous_transfer_secrets"},
+	},
+	{
+		name:     "service account secret",
+		current:  []string{"auth_service", "service_account", "service_account_secret"},
+		previous: []string{"auth_service", "service_account", "previous_service_account_secrets"},
+		copies:   []string{"service_account", "service_account_secret"},
+	},
+	{
+		// the data gateway and the storage providers only know a single transfer secret, running up- and
+		// downloads are interrupted when the services restart with the new one
+		name:    "reva transfer secret",
+		current: []string{"transfer_secret"},
+	},
+}
+
+// RotateSecrets replaces the secrets in the config file at configPath with new random ones. The replaced
+// secrets are kept as previous secrets, which are still accepted until the overlap has passed.
+// Previous secrets that have already expired are removed.
+func RotateSecrets(configPath string, overlap time.Duration) error {
+	if !configExists(configPath) {
+		return fmt.Errorf("no config file found in %s, run 'opencloud init' first", configPath)
+	}
+
+	targetPath := path.Join(configPath, configFilename)
+	content, err := os.ReadFile(targetPath)
+	if err != nil {
+		return err
+	}
+
+	var cfg yaml.MapSlice
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return fmt.Errorf("could not parse %s: %s", targetPath, err)
+	}
+
+	now := time.Now()
+	cfg, rotated, skipped, err := rotateSecrets(cfg, now, now.Add(overlap).UTC().Truncate(time.Second))
+	if err != nil {
+		return err
+	}
+	if len(rotated) == 0 {
+		return fmt.Errorf("%s contains no secrets to rotate, not set in the config file: %v", targetPath, skipped)
+	}
+
+	yamlOutput, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("could not marshall config into yaml: %s", err)
+	}
+
+	targetBackupConfig, err := backupOpenCloudConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(targetPath, yamlOutput, 0600); err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"\n=========================================\n"+
+			" rotated OpenCloud secrets\n"+
+			"=========================================\n\n"+
+			"rotated: %v\n"+
+			"the previous secrets are accepted until %s\n\n"+
+			"Restart all OpenCloud services to use the new secrets.\n"+
+			"The REVA transfer secret has no overlap, up- and downloads running during the restart have to be started again.\n"+
+			"config written to %s\n"+
+			"an older config file was backed up to %s\n\n",
+		rotated, now.Add(overlap).Format(time.RFC3339), targetPath, targetBackupConfig,
+	)
+	if len(skipped) > 0 {
+		fmt.Printf(
+			"not rotated, because they are not set in the config file: %v\n"+
+				"Rotate them where they are configured, e.g. in the environment, and pass the replaced values\n"+
+				"to the services via the OC_PREVIOUS_* environment variables.\n\n",
+			skipped,
+		)
+	}
+	return nil
+}
+
+// rotateSecrets rotates the secrets in cfg and returns the updated config, the names of the rotated secrets
+// and the names of the secrets that are not set in cfg.
+func rotateSecrets(cfg yaml.MapSlice, now, expires time.Time) (yaml.MapSlice, []string, []string, error) {
+	var rotated, skipped []string
+	for _, s := range _rotatedSecrets {
+		v, _ := lookup(cfg, s.current...)
+		old, _ := v.(string)
+		if old == "" {
+			// the secret is configured via environment variables or not at all
+			skipped = append(skipped, s.name)
+			continue
+		}
+
+		secret, err := generators.GenerateRandomPassword(passwordLength)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("could not generate random %s: %s", s.name, err)
+		}
+
+		cfg = set(cfg, secret, s.current...)
+		if len(s.previous) > 0 {
+			prev, _ := lookup(cfg, s.previous...)
+			previous := append(pruneExpired(prev, now), yaml.MapSlice{
+				{Key: "secret", Value: old},
+				{Key: "expires", Value: expires},
+			})
+			cfg = set(cfg, previous, s.previous...)
+		}
+		rotated = append(rotated, s.name)
+
+		if len(s.copies) == 0 {
+			continue
+		}
+		for _, item := range cfg {
+			service, ok := item.Key.(string)
+			if !ok {
+				continue
+			}
+			keys := append([]string{service}, s.copies...)
+			if v, _ := lookup(cfg, keys...); v == old {
+				cfg = set(cfg, secret, keys...)
+			}
+		}
+	}
+	return cfg, rotated, skipped, nil
+}
+
+// pruneExpired returns the previous secrets that have not expired at now.
+func pruneExpired(previous interface{}, now time.Time) []interface{} {
+	list, _ := previous.([]interface{})
+	kept := make([]interface{}, 0, len(list))
+	for _, p := range list {
+		entry, ok := p.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+		var expires time.Time
+		switch e, _ := lookup(entry, "expires"); e := e.(type) {
+		case time.Time:
+			expires = e
+		case string:
+			expires, _ = time.Parse(time.RFC3339Nano, e)
+		}
+		if now.Before(expires) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// lookup returns the value at the path of keys.
+func lookup(m yaml.MapSlice, keys ...string) (interface{}, bool) {
+	for _, item := range m {
+		if item.Key != keys[0] {
+			continue
+		}
+		if len(keys) == 1 {
+			return item.Value, true
+		}
+		child, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			return nil, false
+		}
+		return lookup(child, keys[1:]...)
+	}
+	return nil, false
+}
+
+// set sets the value at the path of keys, missing keys are created.
+func set(m yaml.MapSlice, value interface{}, keys ...string) yaml.MapSlice {
+	for i, item := range m {
+		if item.Key != keys[0] {
+			continue
+		}
+		if len(keys) == 1 {
+			m[i].Value = value
+			return m
+		}
+		child, _ := item.Value.(yaml.MapSlice)
+		m[i].Value = set(child, value, keys[1:]...)
+		return m
+	}
+
+	if len(keys) == 1 {
+		return append(m, yaml.MapItem{Key: keys[0], Value: value})
+	}
+	return append(m, yaml.MapItem{Key: keys[0], Value: set(nil, value, keys[1:]...)})
+}
@@ -0,0 +1,90 @@
+package init
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const _rotateConfig = `
+token_manager:
+  jwt_secret: jwt
+  previous_jwt_secrets:
+  - secret: expired
+    expires: 2020-01-01T00:00:00Z
+  - secret: valid
+    expires: 2999-01-01T00:00:00Z
+machine_auth_api_key: machine
+transfer_secret: transfer
+thumbnails:
+  thumbnail:
+    transfer_secret: thumbnails
+graph:
+  service_account:
+    service_account_id: id
+    service_account_secret: serviceaccount
+auth_service:
+  service_account:
+    service_account_id: id
+    service_account_secret: serviceaccount
+proxy:
+  custom_setting: keep
+`
+
+func TestRotateSecrets(t *testing.T) {
+	var in yaml.MapSlice
+	require.NoError(t, yaml.Unmarshal([]byte(_rotateConfig), &in))
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	expires := now.Add(time.Hour)
+	out, rotated, skipped, err := rotateSecrets(in, now, expires)
+	require.NoError(t, err)
+	assert.Len(t, rotated, 5)
+	assert.Empty(t, skipped)
+
+	b, err := yaml.Marshal(out)
+	require.NoError(t, err)
+
+	var cfg OpenCloudConfig
+	require.NoError(t, yaml.Unmarshal(b, &cfg))
+
+	assert.NotEqual(t, "jwt", cfg.TokenManager.JWTSecret)
+	assert.Equal(t, []PreviousSecret{
+		{Secret: "valid", Expires: time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Secret: "jwt", Expires: expires},
+	}, cfg.TokenManager.PreviousJWTSecrets)
+
+	assert.NotEqual(t, "machine", cfg.MachineAuthAPIKey)
+	assert.Equal(t, []PreviousSecret{{Secret: "machine", Expires: expires}}, cfg.PreviousMachineAuthAPIKeys)
+
+	assert.NotEqual(t, "thumbnails", cfg.Thumbnails.Thumbnail.TransferSecret)
+	assert.Equal(t, []PreviousSecret{{Secret: "thumbnails", Expires: expires}}, cfg.Thumbnails.Thumbnail.PreviousTransferSecrets)
+
+	assert.NotEqual(t, "serviceaccount", cfg.AuthService.ServiceAccount.ServiceAccountSecret)
+	assert.Equal(t, cfg.AuthService.ServiceAccount.ServiceAccountSecret, cfg.Graph.ServiceAccount.ServiceAccountSecret)
+	assert.Equal(t, []PreviousSecret{{Secret: "serviceaccount", Expires: expires}}, cfg.AuthService.ServiceAccount.PreviousServiceAccountSecrets)
+	assert.Empty(t, cfg.Graph.ServiceAccount.PreviousServiceAccountSecrets)
+
+	// the reva transfer secret is replaced without overlap and unknown settings are kept
+	assert.NotEqual(t, "transfer", cfg.TransferSecret)
+	assert.NotEmpty(t, cfg.TransferSecret)
+	_, ok := lookup(out, "previous_transfer_secrets")
+	assert.False(t, ok)
+	v, ok := lookup(out, "proxy", "custom_setting")
+	assert.True(t, ok)
+	assert.Equal(t, "keep", v)
+}
+
+func TestRotateSecretsReportsSkipped(t *testing.T) {
+	var in yaml.MapSlice
+	require.NoError(t, yaml.Unmarshal([]byte("token_manager:\n  jwt_secret: jwt\n"), &in))
+
+	now := time.Now()
+	_, rotated, skipped, err := rotateSecrets(in, now, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jwt secret"}, rotated)
+	assert.Equal(t, []string{"machine auth api key", "thumbnails transfer secret", "service account secret", "reva transfer secret"}, skipped)
+}
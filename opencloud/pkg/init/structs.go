@@ -1,5 +1,7 @@
 package init
 
+import "time"
+
 // TODO: use the opencloud config struct instead of this custom struct
 // We can't use it right now, because it would need  "omitempty" on
 // all elements, in order to produce a slim config file with `opencloud init`.
@@ -15,37 +17,39 @@ package init
 
 // OpenCloudConfig is the configuration for the OpenCloud services
 type OpenCloudConfig struct {
-	TokenManager      TokenManager          `yaml:"token_manager"`
-	MachineAuthAPIKey string                `yaml:"machine_auth_api_key"`
-	SystemUserAPIKey  string                `yaml:"system_user_api_key"`
-	TransferSecret    string                `yaml:"transfer_secret"`
-	SystemUserID      string                `yaml:"system_user_id"`
-	AdminUserID       string                `yaml:"admin_user_id"`
-	Graph             GraphService          `yaml:"graph"`
-	Idp               LdapBasedService      `yaml:"idp"`
-	Idm               IdmService            `yaml:"idm"`
-	Collaboration     Collaboration         `yaml:"collaboration"`
-	Proxy             ProxyService          `yaml:"proxy"`
-	Frontend          FrontendService       `yaml:"frontend"`
-	AuthBasic         AuthbasicService      `yaml:"auth_basic"`
-	AuthBearer        AuthbearerService     `yaml:"auth_bearer"`
-	Users             UsersAndGroupsService `yaml:"users"`
-	Groups            UsersAndGroupsService `yaml:"groups"`
-	Ocdav             InsecureService       `yaml:"ocdav"`
-	Ocm               OcmService            `yaml:"ocm"`
-	Thumbnails        ThumbnailService      `yaml:"thumbnails"`
-	Search            Search                `yaml:"search"`
-	Audit             Audit                 `yaml:"audit"`
-	Settings          SettingsService       `yaml:"settings"`
-	Sharing           Sharing               `yaml:"sharing"`
-	StorageUsers      StorageUsers          `yaml:"storage_users"`
-	Notifications     Notifications         `yaml:"notifications"`
-	Nats              Nats                  `yaml:"nats"`
-	Gateway           Gateway               `yaml:"gateway"`
-	Userlog           Userlog               `yaml:"userlog"`
-	AuthService       AuthService           `yaml:"auth_service"`
-	Clientlog         Clientlog             `yaml:"clientlog"`
-	Activitylog       Activitylog           `yaml:"activitylog"`
+	TokenManager               TokenManager          `yaml:"token_manager"`
+	MachineAuthAPIKey          string                `yaml:"machine_auth_api_key"`
+	PreviousMachineAuthAPIKeys []PreviousSecret      `yaml:"previous_machine_auth_api_keys,omitempty"`
+	SystemUserAPIKey           string                `yaml:"system_user_api_key"`
+	TransferSecret             string                `yaml:"transfer_secret"`
+	SystemUserID               string                `yaml:"system_user_id"`
+	AdminUserID                string                `yaml:"admin_user_id"`
+	Graph                      GraphService          `yaml:"graph"`
+	Idp                        LdapBasedService      `yaml:"idp"`
+	Idm                        IdmService            `yaml:"idm"`
+	Invitations                LdapBasedService      `yaml:"invitations"`
+	Collaboration              Collaboration         `yaml:"collaboration"`
+	Proxy                      ProxyService          `yaml:"proxy"`
+	Frontend                   FrontendService       `yaml:"frontend"`
+	AuthBasic                  AuthbasicService      `yaml:"auth_basic"`
+	AuthBearer                 AuthbearerService     `yaml:"auth_bearer"`
+	Users                      UsersAndGroupsService `yaml:"users"`
+	Groups                     UsersAndGroupsService `yaml:"groups"`
+	Ocdav                      InsecureService       `yaml:"ocdav"`
+	Ocm                        OcmService            `yaml:"ocm"`
+	Thumbnails                 ThumbnailService      `yaml:"thumbnails"`
+	Search                     Search                `yaml:"search"`
+	Audit                      Audit                 `yaml:"audit"`
+	Settings                   SettingsService       `yaml:"settings"`
+	Sharing                    Sharing               `yaml:"sharing"`
+	StorageUsers               StorageUsers          `yaml:"storage_users"`
+	Notifications              Notifications         `yaml:"notifications"`
+	Nats                       Nats                  `yaml:"nats"`
+	Gateway                    Gateway               `yaml:"gateway"`
+	Userlog                    Userlog               `yaml:"userlog"`
+	AuthService                AuthService           `yaml:"auth_service"`
+	Clientlog                  Clientlog             `yaml:"clientlog"`
+	Activitylog                Activitylog           `yaml:"activitylog"`
 }
 
 // Activitylog is the configuration for the activitylog service
@@ -154,10 +158,16 @@ type LdapSettings struct {
 type Nats struct {
 	// The nats config has a field called nats
 	Nats struct {
-		TLSSkipVerifyClientCert bool `yaml:"tls_skip_verify_client_cert"`
+		TLSSkipVerifyClientCert bool              `yaml:"tls_skip_verify_client_cert"`
+		Authorization           NatsAuthorization `yaml:"authorization"`
 	}
 }
 
+// NatsAuthorization is the configuration for the users of the services on the event bus
+type NatsAuthorization struct {
+	Secret string `yaml:"secret"`
+}
+
 // Notifications is the configuration for the notifications service
 type Notifications struct {
 	Notifications  struct{ Events Events } // The notifications config has a field called notifications
@@ -182,10 +192,17 @@ type Search struct {
 	ServiceAccount ServiceAccount `yaml:"service_account"`
 }
 
+// PreviousSecret is a replaced secret that is still accepted until it expires
+type PreviousSecret struct {
+	Secret  string    `yaml:"secret"`
+	Expires time.Time `yaml:"expires"`
+}
+
 // ServiceAccount is the configuration for the used service account
 type ServiceAccount struct {
-	ServiceAccountID     string `yaml:"service_account_id"`
-	ServiceAccountSecret string `yaml:"service_account_secret"`
+	ServiceAccountID              string           `yaml:"service_account_id"`
+	ServiceAccountSecret          string           `yaml:"service_account_secret"`
+	PreviousServiceAccountSecrets []PreviousSecret `yaml:"previous_service_account_secrets,omitempty"`
 }
 
 // ServiceUserPasswordsSettings is the configuration for service user passwords
@@ -220,9 +237,10 @@ type StorageUsers struct {
 
 // ThumbnailSettings is the configuration for the thumbnail settings
 type ThumbnailSettings struct {
-	TransferSecret      string `yaml:"transfer_secret"`
-	WebdavAllowInsecure bool   `yaml:"webdav_allow_insecure"`
-	Cs3AllowInsecure    bool   `yaml:"cs3_allow_insecure"`
+	TransferSecret          string           `yaml:"transfer_secret"`
+	PreviousTransferSecrets []PreviousSecret `yaml:"previous_transfer_secrets,omitempty"`
+	WebdavAllowInsecure     bool             `yaml:"webdav_allow_insecure"`
+	Cs3AllowInsecure        bool             `yaml:"cs3_allow_insecure"`
 }
 
 // ThumbnailService is the configuration for the thumbnail service
@@ -232,7 +250,8 @@ type ThumbnailService struct {
 
 // TokenManager is the configuration for the token manager
 type TokenManager struct {
-	JWTSecret string `yaml:"jwt_secret"`
+	JWTSecret          string           `yaml:"jwt_secret"`
+	PreviousJWTSecrets []PreviousSecret `yaml:"previous_jwt_secrets,omitempty"`
 }
 
 // UsersAndGroupsService is the configuration for the users and groups service
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mohae/deepcopy"
+	occfg "github.com/opencloud-eu/opencloud/pkg/config"
+	"github.com/thejerf/suture/v4"
+)
+
+// ControlRequest asks the runtime to stop, start or restart a service. The rpc endpoint of the runtime is
+// reachable by anyone who can connect to its port, so the requests need to carry the machine auth api key.
+type ControlRequest struct {
+	Service           string
+	MachineAuthAPIKey string
+}
+
+// ServiceStatus describes the state of a supervised service.
+type ServiceStatus struct {
+	Name    string
+	Running bool
+	// StartedAt is the time the service was (re)started the last time
+	StartedAt time.Time
+	// Restarts counts how often the service was started again, either by the supervisor after a failure or manually.
+	Restarts int
+	// LastFailure is the error the service failed with the last time, if any.
+	LastFailure   string
+	LastFailureAt time.Time
+}
+
+// serviceState keeps track of the lifecycle of a supervised service. It is kept
+// when the service is stopped, so restart and failure counts survive a manual restart.
+type serviceState struct {
+	sync.Mutex
+	name          string
+	running       bool
+	starts        int
+	startedAt     time.Time
+	lastFailure   string
+	lastFailureAt time.Time
+}
+
+func (st *serviceState) started() {
+	st.Lock()
+	defer st.Unlock()
+
+	st.running = true
+	st.starts++
+	st.startedAt = time.Now()
+
+	_metrics.up.WithLabelValues(st.name).Set(1)
+	_metrics.startTime.WithLabelValues(st.name).Set(float64(st.startedAt.Unix()))
+	if st.starts > 1 {
+		_metrics.restarts.WithLabelValues(st.name).Inc()
+	}
+}
+
+func (st *serviceState) stopped(failure error) {
+	st.Lock()
+	defer st.Unlock()
+
+	st.running = false
+	_metrics.up.WithLabelValues(st.name).Set(0)
+
+	if failure != nil {
+		st.lastFailure = failure.Error()
+		st.lastFailureAt = time.Now()
+		_metrics.failures.WithLabelValues(st.name).Inc()
+		_metrics.lastFailureTime.WithLabelValues(st.name).Set(float64(st.lastFailureAt.Unix()))
+	}
+}
+
+func (st *serviceState) status() ServiceStatus {
+	st.Lock()
+	defer st.Unlock()
+
+	return ServiceStatus{
+		Name:          st.name,
+		Running:       st.running,
+		StartedAt:     st.startedAt,
+		Restarts:      max(st.starts-1, 0),
+		LastFailure:   st.lastFailure,
+		LastFailureAt: st.lastFailureAt,
+	}
+}
+
+// supervisedService wraps a suture service to record its lifecycle.
+type supervisedService struct {
+	service suture.Service
+	state   *serviceState
+}
+
+// Serve to fullfil Server interface
+func (s supervisedService) Serve(ctx context.Context) (err error) {
+	s.state.started()
+	defer func() {
+		if r := recover(); r != nil {
+			s.state.stopped(fmt.Errorf("panic: %v", r))
+			// let the supervisor handle the panic
+			panic(r)
+		}
+
+		switch {
+		case err == nil, ctx.Err() != nil, errors.Is(err, suture.ErrDoNotRestart):
+			// stopped on purpose
+			s.state.stopped(nil)
+		default:
+			s.state.stopped(err)
+		}
+	}()
+
+	return s.service.Serve(ctx)
+}
+
+// String returns the name the supervisor uses for the service in its events.
+func (s supervisedService) String() string {
+	return s.state.name
+}
+
+// add builds the named service and adds it to the supervisor. The caller needs to hold s.mu.
+func (s *Service) add(name string, builder func(*occfg.Config) suture.Service) {
+	st, ok := s.states[name]
+	if !ok {
+		st = &serviceState{name: name}
+		s.states[name] = st
+	}
+
+	swap := deepcopy.Copy(s.cfg)
+	svc := supervisedService{
+		service: builder(swap.(*occfg.Config)),
+		state:   st,
+	}
+	s.serviceToken[name] = append(s.serviceToken[name], s.Supervisor.Add(svc))
+}
+
+// builder returns the function building the named service.
+func (s *Service) builder(name string) (func(*occfg.Config) suture.Service, bool) {
+	for _, funcSet := range append(s.Services, s.Additional) {
+		if b, ok := funcSet[name]; ok {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// stop removes the named service from the supervisor and waits for it to shut down. The caller needs to hold s.mu.
+func (s *Service) stop(name string) error {
+	tokens := s.serviceToken[name]
+	if len(tokens) == 0 {
+		return fmt.Errorf("service '%s' is not running", name)
+	}
+
+	for _, t := range tokens {
+		if err := s.Supervisor.RemoveAndWait(t, _defaultShutdownTimeoutDuration); err != nil && !errors.Is(err, suture.ErrSupervisorNotRunning) {
+			return fmt.Errorf("could not stop service '%s': %w", name, err)
+		}
+	}
+	delete(s.serviceToken, name)
+	return nil
+}
+
+// start adds the named service to the supervisor. The caller needs to hold s.mu.
+func (s *Service) start(name string) error {
+	if len(s.serviceToken[name]) > 0 {
+		return fmt.Errorf("service '%s' is already running", name)
+	}
+
+	b, ok := s.builder(name)
+	if !ok {
+		return fmt.Errorf("unknown service '%s'", name)
+	}
+	s.add(name, b)
+	return nil
+}
+
+// authorize checks the machine auth api key of a control request against the current and the
+// not yet expired previous keys.
+func (s *Service) authorize(req ControlRequest) error {
+	if req.MachineAuthAPIKey != "" {
+		given := sha256.Sum256([]byte(req.MachineAuthAPIKey))
+		keys := append([]string{s.cfg.MachineAuthAPIKey}, s.cfg.PreviousMachineAuthAPIKeys.Active(time.Now())...)
+		for _, k := range keys {
+			if k == "" {
+				continue
+			}
+			want := sha256.Sum256([]byte(k))
+			if subtle.ConstantTimeCompare(given[:], want[:]) == 1 {
+				return nil
+			}
+		}
+	}
+	s.Log.Warn().Str("service", req.Service).Msg("rejected control request with an invalid machine auth api key")
+	return errors.New("invalid machine auth api key")
+}
+
+// Stop stops a running service.
+func (s *Service) Stop(req ControlRequest, reply *string) error {
+	if err := s.authorize(req); err != nil {
+		return err
+	}
+	name := req.Service
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.stop(name); err != nil {
+		return err
+	}
+
+	s.Log.Info().Str("service", name).Msg("service stopped")
+	*reply = fmt.Sprintf("service '%s' stopped", name)
+	return nil
+}
+
+// Start starts a service that is not running.
+func (s *Service) Start(req ControlRequest, reply *string) error {
+	if err := s.authorize(req); err != nil {
+		return err
+	}
+	name := req.Service
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.start(name); err != nil {
+		return err
+	}
+
+	s.Log.Info().Str("service", name).Msg("service started")
+	*reply = fmt.Sprintf("service '%s' started", name)
+	return nil
+}
+
+// Restart stops a service, if it is running, and starts it again.
+func (s *Service) Restart(req ControlRequest, reply *string) error {
+	if err := s.authorize(req); err != nil {
+		return err
+	}
+	name := req.Service
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.builder(name); !ok {
+		return fmt.Errorf("unknown service '%s'", name)
+	}
+
+	if len(s.serviceToken[name]) > 0 {
+		if err := s.stop(name); err != nil {
+			return err
+		}
+	}
+	if err := s.start(name); err != nil {
+		return err
+	}
+
+	s.Log.Info().Str("service", name).Msg("service restarted")
+	*reply = fmt.Sprintf("service '%s' restarted", name)
+	return nil
+}
+
+// Status returns the status of all services that have been started by the runtime.
+func (s *Service) Status(_ struct{}, reply *[]ServiceStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := make([]ServiceStatus, 0, len(s.states))
+	for _, st := range s.states {
+		status = append(status, st.status())
+	}
+
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Name < status[j].Name
+	})
+
+	*reply = status
+	return nil
+}
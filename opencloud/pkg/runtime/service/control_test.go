@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thejerf/suture/v4"
+
+	occfg "github.com/opencloud-eu/opencloud/pkg/config"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/pkg/shared"
+)
+
+const _machineAuthAPIKey = "machine-auth-api-key"
+
+// control returns a control request for the named service carrying the machine auth api key
+func control(name string) ControlRequest {
+	return ControlRequest{Service: name, MachineAuthAPIKey: _machineAuthAPIKey}
+}
+
+func newTestService(t *testing.T, builders serviceFuncMap) *Service {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	s := &Service{
+		Supervisor: suture.New("test", suture.Spec{
+			FailureBackoff: time.Millisecond,
+		}),
+		Additional:   builders,
+		Log:          log.NopLogger(),
+		serviceToken: make(map[string][]suture.ServiceToken),
+		states:       make(map[string]*serviceState),
+		cfg:          &occfg.Config{MachineAuthAPIKey: _machineAuthAPIKey},
+	}
+	go s.Supervisor.ServeBackground(ctx)
+	return s
+}
+
+func status(t *testing.T, s *Service, name string) ServiceStatus {
+	var st []ServiceStatus
+	require.NoError(t, s.Status(struct{}{}, &st))
+	for _, ss := range st {
+		if ss.Name == name {
+			return ss
+		}
+	}
+	t.Fatalf("no status for service '%s'", name)
+	return ServiceStatus{}
+}
+
+func TestStartStopRestart(t *testing.T) {
+	s := newTestService(t, serviceFuncMap{
+		"blocking": NewSutureServiceBuilder(func(ctx context.Context, _ *occfg.Config) error {
+			<-ctx.Done()
+			return nil
+		}),
+	})
+
+	var reply string
+	assert.Error(t, s.Start(control("unknown"), &reply))
+	assert.Error(t, s.Stop(control("blocking"), &reply))
+
+	require.NoError(t, s.Start(control("blocking"), &reply))
+	assert.Error(t, s.Start(control("blocking"), &reply), "starting a running service must fail")
+	require.Eventually(t, func() bool { return status(t, s, "blocking").Running }, time.Second, time.Millisecond)
+
+	require.NoError(t, s.Stop(control("blocking"), &reply))
+	st := status(t, s, "blocking")
+	assert.False(t, st.Running)
+	assert.Empty(t, st.LastFailure, "stopping a service is no failure")
+
+	require.NoError(t, s.Restart(control("blocking"), &reply))
+	require.Eventually(t, func() bool { return status(t, s, "blocking").Running }, time.Second, time.Millisecond)
+	require.NoError(t, s.Restart(control("blocking"), &reply))
+	require.Eventually(t, func() bool {
+		st := status(t, s, "blocking")
+		return st.Running && st.Restarts == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestFailureIsRecorded(t *testing.T) {
+	var calls atomic.Int32
+	s := newTestService(t, serviceFuncMap{
+		"flaky": NewSutureServiceBuilder(func(ctx context.Context, _ *occfg.Config) error {
+			if calls.Add(1) == 1 {
+				return errors.New("boom")
+			}
+			<-ctx.Done()
+			return nil
+		}),
+	})
+
+	var reply string
+	require.NoError(t, s.Start(control("flaky"), &reply))
+	require.Eventually(t, func() bool {
+		st := status(t, s, "flaky")
+		return st.Running && st.Restarts == 1
+	}, time.Second, time.Millisecond)
+
+	st := status(t, s, "flaky")
+	assert.Equal(t, "boom", st.LastFailure)
+	assert.False(t, st.LastFailureAt.IsZero())
+}
+
+func TestControlRequiresMachineAuthAPIKey(t *testing.T) {
+	s := newTestService(t, serviceFuncMap{
+		"blocking": NewSutureServiceBuilder(func(ctx context.Context, _ *occfg.Config) error {
+			<-ctx.Done()
+			return nil
+		}),
+	})
+
+	var reply string
+	for _, key := range []string{"", "wrong-key"} {
+		req := ControlRequest{Service: "blocking", MachineAuthAPIKey: key}
+		assert.Error(t, s.Start(req, &reply))
+		assert.Error(t, s.Restart(req, &reply))
+	}
+	var st []ServiceStatus
+	require.NoError(t, s.Status(struct{}{}, &st))
+	assert.Empty(t, st, "no service must have been started")
+
+	s.cfg.PreviousMachineAuthAPIKeys = []shared.PreviousSecret{{Secret: "previous-key", Expires: time.Now().Add(time.Hour)}}
+	require.NoError(t, s.Start(ControlRequest{Service: "blocking", MachineAuthAPIKey: "previous-key"}, &reply))
+}
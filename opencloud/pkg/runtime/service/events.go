@@ -0,0 +1,70 @@
+package service
+
+import (
+	"os"
+
+	occfg "github.com/opencloud-eu/opencloud/pkg/config"
+	"github.com/opencloud-eu/opencloud/pkg/generators"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/accounts"
+)
+
+// provisionEventsCredentials sets the credentials the services authenticate with on the event bus,
+// when the nats service requires each service to use its own user. The stores, the caches and the
+// registry share the stores user, which is set in the environment unless configured otherwise, as
+// the services read their store configs only when they start.
+func provisionEventsCredentials(cfg *occfg.Config) error {
+	auth := &cfg.Nats.Nats.Authorization
+	if !auth.Enabled {
+		return nil
+	}
+	if auth.Secret == "" {
+		// all services run in this process, they don't need to share the secret with anyone else
+		secret, err := generators.GenerateRandomPassword(32)
+		if err != nil {
+			return err
+		}
+		auth.Secret = secret
+	}
+
+	creds := func(username string) (string, string) {
+		return username, accounts.Password(auth.Secret, username)
+	}
+	cfg.Activitylog.Events.AuthUsername, cfg.Activitylog.Events.AuthPassword = creds("activitylog")
+	cfg.Antivirus.Events.AuthUsername, cfg.Antivirus.Events.AuthPassword = creds("antivirus")
+	cfg.Audit.Events.AuthUsername, cfg.Audit.Events.AuthPassword = creds("audit")
+	cfg.Clientlog.Events.AuthUsername, cfg.Clientlog.Events.AuthPassword = creds("clientlog")
+	cfg.EventHistory.Events.AuthUsername, cfg.EventHistory.Events.AuthPassword = creds("eventhistory")
+	cfg.Frontend.Events.AuthUsername, cfg.Frontend.Events.AuthPassword = creds("frontend")
+	cfg.Graph.Events.AuthUsername, cfg.Graph.Events.AuthPassword = creds("graph")
+	cfg.Invitations.Events.AuthUsername, cfg.Invitations.Events.AuthPassword = creds("invitations")
+	cfg.Notifications.Notifications.Events.AuthUsername, cfg.Notifications.Notifications.Events.AuthPassword = creds("notifications")
+	cfg.OCM.Events.AuthUsername, cfg.OCM.Events.AuthPassword = creds("ocm")
+	cfg.Policies.Events.AuthUsername, cfg.Policies.Events.AuthPassword = creds("policies")
+	cfg.Postprocessing.Postprocessing.Events.AuthUsername, cfg.Postprocessing.Postprocessing.Events.AuthPassword = creds("postprocessing")
+	cfg.Proxy.Events.AuthUsername, cfg.Proxy.Events.AuthPassword = creds("proxy")
+	cfg.Search.Events.AuthUsername, cfg.Search.Events.AuthPassword = creds("search")
+	cfg.Settings.Events.AuthUsername, cfg.Settings.Events.AuthPassword = creds("settings")
+	cfg.Sharing.Events.AuthUsername, cfg.Sharing.Events.AuthPassword = creds("sharing")
+	cfg.SSE.Events.AuthUsername, cfg.SSE.Events.AuthPassword = creds("sse")
+	cfg.StorageUsers.Events.AuthUsername, cfg.StorageUsers.Events.AuthPassword = creds("storage-users")
+	cfg.Thumbnails.Events.AuthUsername, cfg.Thumbnails.Events.AuthPassword = creds("thumbnails")
+	cfg.Userlog.Events.AuthUsername, cfg.Userlog.Events.AuthPassword = creds("userlog")
+
+	username, password := creds(accounts.StoresUser)
+	for _, env := range [][2]string{
+		{"OC_PERSISTENT_STORE_AUTH_USERNAME", "OC_PERSISTENT_STORE_AUTH_PASSWORD"},
+		{"OC_CACHE_AUTH_USERNAME", "OC_CACHE_AUTH_PASSWORD"},
+		{"MICRO_REGISTRY_AUTH_USERNAME", "MICRO_REGISTRY_AUTH_PASSWORD"},
+	} {
+		if _, ok := os.LookupEnv(env[0]); ok {
+			continue
+		}
+		if err := os.Setenv(env[0], username); err != nil {
+			return err
+		}
+		if err := os.Setenv(env[1], password); err != nil {
+			return err
+		}
+	}
+	return nil
+}
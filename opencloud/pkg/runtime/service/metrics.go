@@ -0,0 +1,80 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+)
+
+var (
+	// Namespace defines the namespace for the defines metrics.
+	Namespace = "opencloud"
+
+	// Subsystem defines the subsystem for the defines metrics.
+	Subsystem = "runtime"
+
+	// _metrics are shared by all services in the process, they are exposed by the debug servers of the services.
+	_metrics = newMetrics()
+)
+
+// metrics defines the available metrics of the runtime.
+type metrics struct {
+	up              *prometheus.GaugeVec
+	startTime       *prometheus.GaugeVec
+	restarts        *prometheus.CounterVec
+	failures        *prometheus.CounterVec
+	lastFailureTime *prometheus.GaugeVec
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "service_up",
+			Help:      "Whether the supervised service is running",
+		}, []string{"service"}),
+		startTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "service_start_time_seconds",
+			Help:      "Unix time the supervised service was started the last time",
+		}, []string{"service"}),
+		restarts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "service_restarts_total",
+			Help:      "How often the supervised service was restarted",
+		}, []string{"service"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "service_failures_total",
+			Help:      "How often the supervised service failed",
+		}, []string{"service"}),
+		lastFailureTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "service_last_failure_time_seconds",
+			Help:      "Unix time the supervised service failed the last time",
+		}, []string{"service"}),
+	}
+}
+
+// register registers the metrics with the default prometheus registry.
+func (m *metrics) register(logger log.Logger) {
+	for name, c := range map[string]prometheus.Collector{
+		"up":              m.up,
+		"startTime":       m.startTime,
+		"restarts":        m.restarts,
+		"failures":        m.failures,
+		"lastFailureTime": m.lastFailureTime,
+	} {
+		if err := prometheus.Register(c); err != nil {
+			logger.Error().
+				Err(err).
+				Str("metric", name).
+				Msg("Failed to register prometheus metric")
+		}
+	}
+}
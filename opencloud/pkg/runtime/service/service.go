@@ -13,7 +13,6 @@ import (
 	"time"
 
 	"github.com/cenkalti/backoff"
-	"github.com/mohae/deepcopy"
 	"github.com/olekukonko/tablewriter"
 	occfg "github.com/opencloud-eu/opencloud/pkg/config"
 	"github.com/opencloud-eu/opencloud/pkg/log"
@@ -89,7 +88,10 @@ type Service struct {
 	Additional serviceFuncMap
 	Log        log.Logger
 
+	// mu guards the service tokens and states, it is held while services are stopped or started
+	mu           sync.Mutex
 	serviceToken map[string][]suture.ServiceToken
+	states       map[string]*serviceState
 	cfg          *occfg.Config
 }
 
@@ -117,6 +119,7 @@ func NewService(ctx context.Context, options ...Option) (*Service, error) {
 		Log:        l,
 
 		serviceToken: make(map[string][]suture.ServiceToken),
+		states:       make(map[string]*serviceState),
 		cfg:          opts.Config,
 	}
 
@@ -401,12 +404,18 @@ func Start(ctx context.Context, o ...Option) error {
 	}
 	rpc.HandleHTTP()
 
+	_metrics.register(s.Log)
+
 	l, err := net.Listen("tcp", net.JoinHostPort(s.cfg.Runtime.Host, s.cfg.Runtime.Port))
 	if err != nil {
 		s.Log.Fatal().Err(err).Msg("could not start listener")
 	}
 	srv := new(http.Server)
 
+	if err := provisionEventsCredentials(s.cfg); err != nil {
+		s.Log.Fatal().Err(err).Msg("could not provision the credentials for the event bus")
+	}
+
 	// prepare the set of services to run
 	s.generateRunSet(s.cfg)
 
@@ -441,13 +450,15 @@ func Start(ctx context.Context, o ...Option) error {
 
 // scheduleServiceTokens adds service tokens to the service supervisor.
 func scheduleServiceTokens(s *Service, funcSet serviceFuncMap) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	for name := range runset {
 		if _, ok := funcSet[name]; !ok {
 			continue
 		}
 
-		swap := deepcopy.Copy(s.cfg)
-		s.serviceToken[name] = append(s.serviceToken[name], s.Supervisor.Add(funcSet[name](swap.(*occfg.Config))))
+		s.add(name, funcSet[name])
 	}
 }
 
@@ -481,6 +492,9 @@ func (s *Service) generateRunSet(cfg *occfg.Config) {
 
 // List running processes for the Service Controller.
 func (s *Service) List(_ struct{}, reply *string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	tableString := &strings.Builder{}
 	table := tablewriter.NewTable(tableString)
 	table.Header([]string{"Service"})
@@ -521,6 +535,10 @@ func trapShutdownCtx(s *Service, srv *http.Server, ctx context.Context) error {
 		s.Log.Debug().Msg("runtime listener shutdown done")
 	}()
 
+	// don't let services be started or stopped while shutting down
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	for sName := range s.serviceToken {
 		for i := range s.serviceToken[sName] {
 			wg.Add(1)
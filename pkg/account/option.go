@@ -2,6 +2,7 @@ package account
 
 import (
 	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/pkg/shared"
 )
 
 // Option defines a single option function.
@@ -13,6 +14,8 @@ type Options struct {
 	Logger log.Logger
 	// JWTSecret is the jwt secret for the reva token manager
 	JWTSecret string
+	// PreviousJWTSecrets are the previous jwt secrets that are still accepted
+	PreviousJWTSecrets shared.PreviousSecrets
 }
 
 // Logger provides a function to set the logger option.
@@ -28,3 +31,10 @@ func JWTSecret(s string) Option {
 		o.JWTSecret = s
 	}
 }
+
+// PreviousJWTSecrets provides a function to set the previous jwt secrets option.
+func PreviousJWTSecrets(ps shared.PreviousSecrets) Option {
+	return func(o *Options) {
+		o.PreviousJWTSecrets = ps
+	}
+}
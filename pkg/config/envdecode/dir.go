@@ -0,0 +1,42 @@
+package envdecode
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSecretsDir is the directory the DirResolver reads secrets from if none is configured.
+const DefaultSecretsDir = "/run/secrets"
+
+// DirResolver resolves references like "secret://smtp_password" to the content of the file
+// with that name in a directory, e.g. the secrets mounted into a container.
+type DirResolver struct {
+	// Dir is the directory containing the secrets. Defaults to the directory set in
+	// OC_SECRETS_DIR or DefaultSecretsDir.
+	Dir string
+}
+
+// Resolve returns the content of the referenced file without trailing newlines.
+func (r *DirResolver) Resolve(ref *url.URL) (string, error) {
+	dir := r.Dir
+	if dir == "" {
+		dir = os.Getenv("OC_SECRETS_DIR")
+	}
+	if dir == "" {
+		dir = DefaultSecretsDir
+	}
+
+	name := filepath.FromSlash(strings.TrimPrefix(ref.Host+ref.Path, "/"))
+	if name == "" || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("invalid secret file name '%s'", ref.Host+ref.Path)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
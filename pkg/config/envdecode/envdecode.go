@@ -58,6 +58,11 @@ type Decoder interface {
 // time.ParseDuration() function and *url.URL is supported via the
 // url.Parse() function. Slices are supported for all above mentioned
 // primitive types. Semicolon is used as delimiter in environment variables.
+//
+// If an environment variable is not set, its value is read from the file
+// the same variable with a "_FILE" suffix points to. Values referencing a
+// secret, like "vault://kv/opencloud#smtp_password", are replaced with the
+// secret by the SecretResolver registered for the scheme.
 func Decode(target interface{}) error {
 	nFields, err := decode(target, false)
 	if err != nil {
@@ -151,11 +156,22 @@ func decode(target interface{}, strict bool) (int, error) {
 		var env string
 		var envSet bool
 		for _, override := range overrides {
-			if v, set := os.LookupEnv(override); set {
+			v, set, err := lookupEnv(override)
+			if err != nil {
+				return 0, err
+			}
+			if set {
 				env = v
 				envSet = true
 			}
 		}
+		if envSet && f.Kind() != reflect.Slice {
+			// the elements of slices are resolved one by one
+			var err error
+			if env, err = ResolveSecret(env); err != nil {
+				return 0, fmt.Errorf("could not resolve the secret for \"%s\": %w", parts[0], err)
+			}
+		}
 
 		required := false
 		hasDefault := false
@@ -213,6 +229,25 @@ func decode(target interface{}, strict bool) (int, error) {
 	return setFieldCount, nil
 }
 
+// lookupEnv returns the value of the environment variable. If it is not set, the value is read
+// from the file the variable with the "_FILE" suffix points to, if that is set.
+func lookupEnv(name string) (string, bool, error) {
+	if v, set := os.LookupEnv(name); set {
+		return v, true, nil
+	}
+
+	file, set := os.LookupEnv(name + "_FILE")
+	if !set {
+		return "", false, nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", false, fmt.Errorf("could not read the file for \"%s\": %w", name+"_FILE", err)
+	}
+	// files usually end with a newline which is not part of the value
+	return strings.TrimRight(string(b), "\r\n"), true, nil
+}
+
 func decodeSlice(f *reflect.Value, env string) error {
 	parts := strings.Split(env, ",")
 
@@ -227,8 +262,12 @@ func decodeSlice(f *reflect.Value, env string) error {
 	slice := reflect.MakeSlice(f.Type(), valuesCount, valuesCount)
 	if valuesCount > 0 {
 		for i := 0; i < valuesCount; i++ {
+			v, err := ResolveSecret(values[i])
+			if err != nil {
+				return err
+			}
 			e := slice.Index(i)
-			err := decodePrimitiveType(&e, values[i])
+			err = decodePrimitiveType(&e, v)
 			if err != nil {
 				return err
 			}
@@ -379,7 +418,7 @@ func Export(target interface{}) ([]*ConfigInfo, error) {
 		ci := &ConfigInfo{
 			Field:   fName,
 			EnvVar:  parts[0],
-			UsesEnv: os.Getenv(parts[0]) != "",
+			UsesEnv: os.Getenv(parts[0]) != "" || os.Getenv(parts[0]+"_FILE") != "",
 		}
 
 		for _, o := range parts[1:] {
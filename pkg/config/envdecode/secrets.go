@@ -0,0 +1,115 @@
+package envdecode
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// SecretResolver is the interface implemented by an object that can resolve
+// references to secrets kept outside of the configuration, e.g. in a secret store.
+type SecretResolver interface {
+	// Resolve returns the secret the reference points to.
+	Resolve(ref *url.URL) (string, error)
+}
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   = map[string]SecretResolver{}
+)
+
+func init() {
+	RegisterSecretResolver("vault", &VaultResolver{})
+	// not "file", that would rewrite file URLs configured in other settings
+	RegisterSecretResolver("secret", &DirResolver{})
+}
+
+// RegisterSecretResolver registers the resolver for references with the given URL scheme.
+// A resolver registered before for the same scheme is replaced.
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers[scheme] = r
+}
+
+// ResolveSecret returns the secret if the value is a reference with the scheme of a
+// registered SecretResolver, e.g. "vault://kv/opencloud#smtp_password". Other values are returned as is.
+func ResolveSecret(value string) (string, error) {
+	scheme, _, found := strings.Cut(value, "://")
+	if !found {
+		return value, nil
+	}
+
+	resolversMu.RLock()
+	r, ok := resolvers[scheme]
+	resolversMu.RUnlock()
+	if !ok {
+		return value, nil
+	}
+
+	ref, err := url.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret reference: %w", err)
+	}
+	return r.Resolve(ref)
+}
+
+// ResolveSecrets replaces the secret references in all string fields of the target,
+// recursing into nested structs and pointers to structs. The target must be a non-nil
+// pointer to a struct. It is used for values that are not read from environment variables.
+func ResolveSecrets(target interface{}) error {
+	s := reflect.ValueOf(target)
+	if s.Kind() != reflect.Ptr || s.IsNil() {
+		return ErrInvalidTarget
+	}
+
+	s = s.Elem()
+	if s.Kind() != reflect.Struct {
+		return ErrInvalidTarget
+	}
+	return resolveSecrets(s)
+}
+
+func resolveSecrets(s reflect.Value) error {
+	t := s.Type()
+	for i := 0; i < s.NumField(); i++ {
+		f := s.Field(i)
+		if !f.CanSet() {
+			continue
+		}
+
+		switch f.Kind() {
+		case reflect.Ptr:
+			if f.IsNil() || f.Elem().Kind() != reflect.Struct {
+				continue
+			}
+			if err := resolveSecrets(f.Elem()); err != nil {
+				return err
+			}
+		case reflect.Struct:
+			if err := resolveSecrets(f); err != nil {
+				return err
+			}
+		case reflect.String:
+			v, err := ResolveSecret(f.String())
+			if err != nil {
+				return fmt.Errorf("could not resolve the secret for \"%s\": %w", t.Field(i).Name, err)
+			}
+			f.SetString(v)
+		case reflect.Slice:
+			if f.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			for j := 0; j < f.Len(); j++ {
+				v, err := ResolveSecret(f.Index(j).String())
+				if err != nil {
+					return fmt.Errorf("could not resolve the secret for \"%s\": %w", t.Field(i).Name, err)
+				}
+				f.Index(j).SetString(v)
+			}
+		}
+	}
+	return nil
+}
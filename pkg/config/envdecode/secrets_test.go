@@ -0,0 +1,200 @@
+package envdecode
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testSecretConfig struct {
+	Password string   `env:"TEST_SECRET_PASSWORD"`
+	Keys     []string `env:"TEST_SECRET_KEYS"`
+	Nested   *nested
+}
+
+type stubResolver map[string]string
+
+func (r stubResolver) Resolve(ref *url.URL) (string, error) {
+	return r[ref.Host], nil
+}
+
+func TestDecodeFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_SECRET_PASSWORD_FILE", file)
+
+	var tc testSecretConfig
+	if err := Decode(&tc); err != nil {
+		t.Fatal(err)
+	}
+	if tc.Password != "from-file" {
+		t.Fatalf(`Expected "from-file", got "%s"`, tc.Password)
+	}
+
+	// the variable itself takes precedence
+	t.Setenv("TEST_SECRET_PASSWORD", "from-env")
+	if err := Decode(&tc); err != nil {
+		t.Fatal(err)
+	}
+	if tc.Password != "from-env" {
+		t.Fatalf(`Expected "from-env", got "%s"`, tc.Password)
+	}
+
+	os.Unsetenv("TEST_SECRET_PASSWORD")
+	t.Setenv("TEST_SECRET_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	if err := Decode(&tc); err == nil {
+		t.Fatal("Expected an error for a missing file")
+	}
+}
+
+func TestDecodeSecretReference(t *testing.T) {
+	RegisterSecretResolver("stub", stubResolver{"password": "secret"})
+	t.Cleanup(func() {
+		resolversMu.Lock()
+		delete(resolvers, "stub")
+		resolversMu.Unlock()
+	})
+
+	t.Setenv("TEST_SECRET_PASSWORD", "stub://password")
+	t.Setenv("TEST_SECRET_KEYS", "stub://password,plain")
+	t.Setenv("TEST_STRING", "file:///var/lib/opencloud/kept")
+
+	tc := testSecretConfig{Nested: &nested{}}
+	if err := Decode(&tc); err != nil {
+		t.Fatal(err)
+	}
+	if tc.Password != "secret" {
+		t.Fatalf(`Expected "secret", got "%s"`, tc.Password)
+	}
+	if len(tc.Keys) != 2 || tc.Keys[0] != "secret" || tc.Keys[1] != "plain" {
+		t.Fatalf(`Expected the first key to be resolved, got "%v"`, tc.Keys)
+	}
+	if tc.Nested.String != "file:///var/lib/opencloud/kept" {
+		t.Fatalf(`Expected file urls to be kept, got "%s"`, tc.Nested.String)
+	}
+
+	tc = testSecretConfig{Password: "stub://password", Keys: []string{"plain", "stub://password"}, Nested: &nested{String: "stub://password"}}
+	if err := ResolveSecrets(&tc); err != nil {
+		t.Fatal(err)
+	}
+	if tc.Password != "secret" || tc.Keys[1] != "secret" || tc.Nested.String != "secret" {
+		t.Fatalf("Expected all references to be resolved, got %+v %+v", tc, tc.Nested)
+	}
+}
+
+func TestDirResolver(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "smtp_password"), []byte("smtp\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r := &DirResolver{Dir: dir}
+
+	for ref, expected := range map[string]string{
+		"secret://smtp_password":  "smtp",
+		"secret:///smtp_password": "smtp",
+	} {
+		u, _ := url.Parse(ref)
+		v, err := r.Resolve(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != expected {
+			t.Fatalf(`Expected "%s", got "%s"`, expected, v)
+		}
+	}
+
+	for _, ref := range []string{"secret://", "secret://../etc/passwd", "secret://missing"} {
+		u, _ := url.Parse(ref)
+		if _, err := r.Resolve(u); err == nil {
+			t.Fatalf(`Expected an error for "%s"`, ref)
+		}
+	}
+}
+
+func TestVaultResolver(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		data := map[string]interface{}{"smtp_password": "smtp", "port": 25}
+		switch r.URL.Path {
+		case "/v1/kv/data/opencloud":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"data": data, "metadata": map[string]interface{}{"version": 1}},
+			})
+		case "/v1/kv1/opencloud":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	resolve := func(r *VaultResolver, ref string) (string, error) {
+		u, err := url.Parse(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.Resolve(u)
+	}
+
+	r := &VaultResolver{Address: srv.URL, Token: "token"}
+	for ref, expected := range map[string]string{
+		"vault://kv/opencloud#smtp_password": "smtp",
+		"vault://kv/opencloud#port":          "25",
+	} {
+		v, err := resolve(r, ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != expected {
+			t.Fatalf(`Expected "%s", got "%s"`, expected, v)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("Expected the secret to be read once, got %d requests", requests)
+	}
+
+	for _, ref := range []string{"vault://kv/opencloud#missing", "vault://kv/missing#key", "vault://kv/opencloud"} {
+		if _, err := resolve(r, ref); err == nil {
+			t.Fatalf(`Expected an error for "%s"`, ref)
+		}
+	}
+
+	v, err := resolve(&VaultResolver{Address: srv.URL, Token: "token", KVVersion: 1}, "vault://kv1/opencloud#smtp_password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "smtp" {
+		t.Fatalf(`Expected "smtp", got "%s"`, v)
+	}
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN_FILE", tokenFile)
+	v, err = ResolveSecret("vault://kv/opencloud#smtp_password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "smtp" {
+		t.Fatalf(`Expected "smtp", got "%s"`, v)
+	}
+
+	_, err = resolve(&VaultResolver{Address: srv.URL, Token: "wrong"}, "vault://kv/opencloud#smtp_password")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Expected a permission error, got %v", err)
+	}
+}
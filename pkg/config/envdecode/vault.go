@@ -0,0 +1,136 @@
+package envdecode
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultResolver resolves references like "vault://kv/opencloud#smtp_password" to the value of
+// the key "smtp_password" of the secret "opencloud" in the HashiCorp Vault KV secrets engine
+// mounted at "kv". Secrets are read once and cached.
+type VaultResolver struct {
+	// Address of the vault server. Defaults to VAULT_ADDR.
+	Address string
+	// Token used to authenticate. Defaults to VAULT_TOKEN or the content of the file VAULT_TOKEN_FILE points to.
+	Token string
+	// Namespace of the secrets. Defaults to VAULT_NAMESPACE.
+	Namespace string
+	// KVVersion is the version of the KV secrets engine, 1 or 2. Defaults to OC_VAULT_KV_VERSION or 2.
+	KVVersion int
+	// Client is the http client used to talk to vault.
+	Client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]map[string]interface{}
+}
+
+// Resolve returns the value of the referenced key.
+func (r *VaultResolver) Resolve(ref *url.URL) (string, error) {
+	mount, secret, key := ref.Host, strings.Trim(ref.Path, "/"), ref.Fragment
+	if mount == "" || secret == "" || key == "" {
+		return "", fmt.Errorf("invalid vault reference '%s', expected vault://<mount>/<secret>#<key>", ref.Redacted())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, ok := r.cache[mount+"/"+secret]
+	if !ok {
+		var err error
+		if data, err = r.read(mount, secret); err != nil {
+			return "", err
+		}
+		if r.cache == nil {
+			r.cache = make(map[string]map[string]interface{})
+		}
+		r.cache[mount+"/"+secret] = data
+	}
+
+	v, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret '%s/%s' has no key '%s'", mount, secret, key)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(v), nil
+}
+
+func (r *VaultResolver) read(mount, secret string) (map[string]interface{}, error) {
+	address := r.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("no vault address configured, set VAULT_ADDR")
+	}
+
+	token := r.Token
+	if token == "" {
+		var err error
+		if token, _, err = lookupEnv("VAULT_TOKEN"); err != nil {
+			return nil, err
+		}
+	}
+
+	namespace := r.Namespace
+	if namespace == "" {
+		namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+
+	version := r.KVVersion
+	if version == 0 && os.Getenv("OC_VAULT_KV_VERSION") == "1" {
+		version = 1
+	}
+
+	u := strings.TrimRight(address, "/") + "/v1/" + mount + "/"
+	if version != 1 {
+		u += "data/"
+	}
+	u += secret
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not read vault secret '%s/%s': %w", mount, secret, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not read vault secret '%s/%s': unexpected status %s", mount, secret, res.Status)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("could not decode vault secret '%s/%s': %w", mount, secret, err)
+	}
+
+	if version == 1 {
+		return body.Data, nil
+	}
+	// the kv version 2 engine wraps the secret with its metadata
+	data, _ := body.Data["data"].(map[string]interface{})
+	return data, nil
+}
@@ -9,6 +9,7 @@ import (
 	gofig "github.com/gookit/config/v2"
 	gooyaml "github.com/gookit/config/v2/yaml"
 	"github.com/opencloud-eu/opencloud/pkg/config/defaults"
+	"github.com/opencloud-eu/opencloud/pkg/config/envdecode"
 )
 
 var (
@@ -29,6 +30,7 @@ func bindSourcesToStructs(fileSystem fs.FS, filePath, service string, dst interf
 	cnf := gofig.NewWithOptions(service)
 	cnf.WithOptions(func(options *gofig.Options) {
 		options.ParseEnv = true
+		options.ParseTime = true
 		options.DecoderConfig.TagName = decoderConfigTagName
 	})
 	cnf.AddDriver(gooyaml.Driver)
@@ -48,7 +50,8 @@ func bindSourcesToStructs(fileSystem fs.FS, filePath, service string, dst interf
 		return err
 	}
 
-	return nil
+	// replace references to secrets, like vault://kv/opencloud#smtp_password, with the secrets
+	return envdecode.ResolveSecrets(dst)
 }
 
 // LocalEndpoint returns the local endpoint for a given protocol and address.
@@ -4,6 +4,7 @@ import (
 	"gotest.tools/v3/assert"
 	"testing"
 	"testing/fstest"
+	"time"
 )
 
 type TestConfig struct {
@@ -186,3 +187,28 @@ clientlog:
 
 	assert.Equal(t, c.Graph.Identity.LDAP.BindPassword, "$ZZ8fSJR&YA02jBBPx6IRCzW0kVZ#cBO")
 }
+
+func TestBindSourcesToStructs_PreviousSecrets(t *testing.T) {
+	// setup test env
+	yaml := `
+token_manager:
+  jwt_secret: current
+  previous_jwt_secrets:
+  - secret: previous
+    expires: 2025-01-02T03:04:05Z
+`
+	filePath := "etc/opencloud/foo.yaml"
+	fs := fstest.MapFS{
+		filePath: {Data: []byte(yaml)},
+	}
+	// perform test
+	c := Config{}
+	err := bindSourcesToStructs(fs, filePath, "foo", &c)
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, len(c.TokenManager.PreviousJWTSecrets), 1)
+	assert.Equal(t, c.TokenManager.PreviousJWTSecrets[0].Secret, "previous")
+	assert.Assert(t, c.TokenManager.PreviousJWTSecrets[0].Expires.Equal(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)))
+}
@@ -90,6 +90,7 @@ func EnsureCommons(cfg *config.Config) {
 	if cfg.MachineAuthAPIKey != "" {
 		cfg.Commons.MachineAuthAPIKey = cfg.MachineAuthAPIKey
 	}
+	cfg.Commons.PreviousMachineAuthAPIKeys = cfg.PreviousMachineAuthAPIKeys
 
 	if cfg.SystemUserAPIKey != "" {
 		cfg.Commons.SystemUserAPIKey = cfg.SystemUserAPIKey
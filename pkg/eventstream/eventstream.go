@@ -0,0 +1,69 @@
+// Package eventstream publishes the events of the services to the subjects of their types.
+//
+// The event stream accepts the events on the subjects of their types and transforms the subjects back
+// to its own subject, so the consumers receive the events of all types as before. Publishing to the
+// subjects of the types lets the nats server restrict the events a service can publish.
+package eventstream
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	mevents "go-micro.dev/v4/events"
+)
+
+// _fallback is how long events are published to the subject of the stream after the stream didn't
+// accept the subject of an event type, e.g. when an external nats server is used.
+const _fallback = time.Minute
+
+// Subjects matches the subjects of all event types. The types consist of the package and the name
+// of the events.
+var Subjects = events.MainQueueName + ".*.*"
+
+// Subject returns the subject the events of the type are published to. The type is the one reva
+// puts into the metadata of the events, like "events.UserCreated".
+func Subject(eventType string) string {
+	return events.MainQueueName + "." + eventType
+}
+
+// SubjectOf returns the subject the event is published to.
+func SubjectOf(ev any) string {
+	return Subject(reflect.TypeOf(ev).String())
+}
+
+type stream struct {
+	events.Stream
+	untypedUntil atomic.Int64
+}
+
+// Typed returns a stream publishing the events to the subjects of their types. Events published
+// to other topics or without a type are passed on unchanged.
+func Typed(s events.Stream) events.Stream {
+	return &stream{Stream: s}
+}
+
+// Publish publishes the event to the subject of its type.
+func (s *stream) Publish(topic string, msg interface{}, opts ...mevents.PublishOption) error {
+	if topic != events.MainQueueName || time.Now().UnixNano() < s.untypedUntil.Load() {
+		return s.Stream.Publish(topic, msg, opts...)
+	}
+	var o mevents.PublishOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	typ := o.Metadata[events.MetadatakeyEventType]
+	if typ == "" {
+		return s.Stream.Publish(topic, msg, opts...)
+	}
+
+	err := s.Stream.Publish(Subject(typ), msg, opts...)
+	if errors.Is(err, nats.ErrNoStreamResponse) {
+		s.untypedUntil.Store(time.Now().Add(_fallback).UnixNano())
+		return s.Stream.Publish(topic, msg, opts...)
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package eventstream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	mevents "go-micro.dev/v4/events"
+)
+
+type recorder struct {
+	events.Stream
+	topics []string
+	typed  bool
+}
+
+func (r *recorder) Publish(topic string, _ interface{}, _ ...mevents.PublishOption) error {
+	r.topics = append(r.topics, topic)
+	if topic != events.MainQueueName && !r.typed {
+		return nats.ErrNoStreamResponse
+	}
+	return nil
+}
+
+func TestPublish(t *testing.T) {
+	r := &recorder{typed: true}
+	s := Typed(r)
+
+	require.NoError(t, events.Publish(context.Background(), s, events.UserCreated{}))
+	require.NoError(t, s.Publish("other", []byte("{}")))
+	require.NoError(t, s.Publish(events.MainQueueName, []byte("{}")))
+	assert.Equal(t, []string{"main-queue.events.UserCreated", "other", "main-queue"}, r.topics)
+	assert.Equal(t, "main-queue.events.UserCreated", SubjectOf(events.UserCreated{}))
+}
+
+func TestPublishFallback(t *testing.T) {
+	r := &recorder{}
+	s := Typed(r)
+
+	// the stream doesn't accept the subjects of the types
+	require.NoError(t, events.Publish(context.Background(), s, events.UserCreated{}))
+	require.NoError(t, events.Publish(context.Background(), s, events.UserDeleted{}))
+	assert.Equal(t, []string{"main-queue.events.UserCreated", "main-queue", "main-queue"}, r.topics)
+}
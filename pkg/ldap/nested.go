@@ -0,0 +1,357 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/jellydator/ttlcache/v3"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+)
+
+const (
+	// MatchingRuleInChainOID is the OID of the LDAP_MATCHING_RULE_IN_CHAIN extensible match rule of Active Directory.
+	// It walks the chain of ancestry of an attribute, e.g. it matches all groups a user is a member of through nested groups.
+	MatchingRuleInChainOID = "1.2.840.113556.1.4.1941"
+	// activeDirectoryCapabilityOID is announced in the supportedCapabilities of the root DSE by Active Directory servers
+	activeDirectoryCapabilityOID = "1.2.840.113556.1.4.800"
+	// activeDirectoryMemberOfAttribute is the attribute Active Directory maintains for the groups of an entry
+	activeDirectoryMemberOfAttribute = "memberOf"
+	// _groupByDNChunkSize is the number of member DNs that are looked up with a single search
+	_groupByDNChunkSize = 100
+
+	// MatchingRuleInChainAuto uses the matching rule in chain if the server announces itself as Active Directory
+	MatchingRuleInChainAuto = "auto"
+	// MatchingRuleInChainAlways always uses the matching rule in chain
+	MatchingRuleInChainAlways = "always"
+	// MatchingRuleInChainNever resolves nested groups by searching level by level
+	MatchingRuleInChainNever = "never"
+)
+
+// NestedGroupsConfig configures the resolution of nested group memberships
+type NestedGroupsConfig struct {
+	// GroupBaseDN and GroupScope are used to search for groups
+	GroupBaseDN string
+	GroupScope  int
+	// GroupFilter is a complete LDAP filter matching all groups, e.g. "(&(objectClass=groupOfNames)(cn=*))"
+	GroupFilter string
+	// MemberAttribute is the group attribute holding the DNs of the group members
+	MemberAttribute string
+	// MaxDepth is the number of nesting levels that are followed below the direct memberships
+	MaxDepth int
+	// MatchingRuleInChain is one of 'auto', 'always' or 'never'
+	MatchingRuleInChain string
+	// CacheTTL is the time resolved memberships are cached, a zero value disables the cache
+	CacheTTL time.Duration
+}
+
+// NestedGroupResolver resolves transitive group memberships. Groups can be members of other groups,
+// the members of a nested group are members of all parent groups as well.
+type NestedGroupResolver struct {
+	conn   ldap.Client
+	cfg    NestedGroupsConfig
+	logger log.Logger
+
+	inChainOnce sync.Once
+	inChain     bool
+
+	cache *ttlcache.Cache[string, []*ldap.Entry]
+}
+
+// NewNestedGroupResolver returns a NestedGroupResolver for the given connection
+func NewNestedGroupResolver(conn ldap.Client, cfg NestedGroupsConfig, logger log.Logger) (*NestedGroupResolver, error) {
+	switch cfg.MatchingRuleInChain {
+	case "":
+		cfg.MatchingRuleInChain = MatchingRuleInChainAuto
+	case MatchingRuleInChainAuto, MatchingRuleInChainAlways, MatchingRuleInChainNever:
+	default:
+		return nil, fmt.Errorf("invalid matching rule in chain mode '%s', supported values are 'auto', 'always' and 'never'", cfg.MatchingRuleInChain)
+	}
+	if cfg.MaxDepth < 0 {
+		return nil, errors.New("the maximum depth of nested groups must not be negative")
+	}
+	if cfg.MemberAttribute == "" {
+		return nil, errors.New("the group member attribute is required to resolve nested groups")
+	}
+
+	r := &NestedGroupResolver{
+		conn:   conn,
+		cfg:    cfg,
+		logger: logger,
+	}
+	if cfg.CacheTTL > 0 {
+		r.cache = ttlcache.New(
+			ttlcache.WithTTL[string, []*ldap.Entry](cfg.CacheTTL),
+			ttlcache.WithDisableTouchOnHit[string, []*ldap.Entry](),
+		)
+		go r.cache.Start()
+	}
+	return r, nil
+}
+
+// GroupsOf returns all groups the entry with the given DN is a member of, directly or through nested groups.
+// The returned group entries contain the requested attributes.
+func (r *NestedGroupResolver) GroupsOf(dn string, attrs []string) ([]*ldap.Entry, error) {
+	if r.useMatchingRuleInChain() {
+		return r.searchGroups(fmt.Sprintf("(%s:%s:=%s)", r.cfg.MemberAttribute, MatchingRuleInChainOID, ldap.EscapeFilter(dn)), attrs)
+	}
+
+	seen := map[string]struct{}{normalizeDN(dn): {}}
+	result := []*ldap.Entry{}
+	level := []string{dn}
+	for depth := 0; len(level) > 0; depth++ {
+		if depth > r.cfg.MaxDepth {
+			r.logger.Debug().Str("dn", dn).Int("maxDepth", r.cfg.MaxDepth).Msg("stopped resolving nested groups at the depth limit")
+			break
+		}
+		var next []string
+		for _, memberDN := range level {
+			groups, err := r.searchGroups(fmt.Sprintf("(%s=%s)", r.cfg.MemberAttribute, ldap.EscapeFilter(memberDN)), attrs)
+			if err != nil {
+				return nil, err
+			}
+			for _, group := range groups {
+				key := normalizeDN(group.DN)
+				if _, ok := seen[key]; ok {
+					// a cycle or a group that is reachable on several paths
+					continue
+				}
+				seen[key] = struct{}{}
+				result = append(result, group)
+				next = append(next, group.DN)
+			}
+		}
+		level = next
+	}
+	return result, nil
+}
+
+// MemberDNs returns the DNs of all members of the group entry that are not groups themselves, including the
+// members of nested groups. The group entry must contain the member attribute.
+func (r *NestedGroupResolver) MemberDNs(group *ldap.Entry) ([]string, error) {
+	groupDNs := map[string]struct{}{normalizeDN(group.DN): {}}
+	level := []*ldap.Entry{group}
+
+	inChain := r.useMatchingRuleInChain()
+	if inChain {
+		nested, err := r.searchGroups(fmt.Sprintf("(%s:%s:=%s)", activeDirectoryMemberOfAttribute, MatchingRuleInChainOID, ldap.EscapeFilter(group.DN)), []string{r.cfg.MemberAttribute})
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range nested {
+			groupDNs[normalizeDN(g.DN)] = struct{}{}
+		}
+		level = append(level, nested...)
+	}
+
+	seen := map[string]struct{}{}
+	result := []string{}
+	for depth := 0; len(level) > 0; depth++ {
+		var candidates []string
+		for _, g := range level {
+			for _, memberDN := range g.GetEqualFoldAttributeValues(r.cfg.MemberAttribute) {
+				if memberDN == "" {
+					continue
+				}
+				key := normalizeDN(memberDN)
+				if _, ok := groupDNs[key]; ok {
+					continue
+				}
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = struct{}{}
+				candidates = append(candidates, memberDN)
+			}
+		}
+
+		nested := map[string]*ldap.Entry{}
+		if !inChain {
+			var err error
+			if nested, err = r.groupsByDN(candidates); err != nil {
+				return nil, err
+			}
+		}
+
+		var next []*ldap.Entry
+		for _, memberDN := range candidates {
+			key := normalizeDN(memberDN)
+			g, ok := nested[key]
+			if !ok {
+				result = append(result, memberDN)
+				continue
+			}
+			groupDNs[key] = struct{}{}
+			if depth < r.cfg.MaxDepth {
+				next = append(next, g)
+			} else {
+				r.logger.Debug().Str("group", group.DN).Str("nested", memberDN).Int("maxDepth", r.cfg.MaxDepth).Msg("skipping nested group beyond the depth limit")
+			}
+		}
+		level = next
+	}
+	return result, nil
+}
+
+// useMatchingRuleInChain tells if the server can resolve nested groups on its own
+func (r *NestedGroupResolver) useMatchingRuleInChain() bool {
+	switch r.cfg.MatchingRuleInChain {
+	case MatchingRuleInChainAlways:
+		return true
+	case MatchingRuleInChainNever:
+		return false
+	}
+	r.inChainOnce.Do(func() {
+		searchRequest := ldap.NewSearchRequest(
+			"", ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 0, false,
+			"(objectClass=*)",
+			[]string{"supportedCapabilities"},
+			nil,
+		)
+		res, err := r.conn.Search(searchRequest)
+		if err != nil || len(res.Entries) == 0 {
+			r.logger.Warn().Err(err).Msg("could not read the root DSE, resolving nested groups without the matching rule in chain")
+			return
+		}
+		r.inChain = slices.Contains(res.Entries[0].GetAttributeValues("supportedCapabilities"), activeDirectoryCapabilityOID)
+		r.logger.Debug().Bool("matchingRuleInChain", r.inChain).Msg("detected support for the matching rule in chain")
+	})
+	return r.inChain
+}
+
+// searchGroups searches for groups matching the filter
+func (r *NestedGroupResolver) searchGroups(filter string, attrs []string) ([]*ldap.Entry, error) {
+	key := "search\x00" + filter + "\x00" + strings.Join(attrs, ",")
+	if entries, ok := r.cached(key); ok {
+		return entries, nil
+	}
+
+	searchRequest := ldap.NewSearchRequest(
+		r.cfg.GroupBaseDN, r.cfg.GroupScope, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf("(&%s%s)", r.cfg.GroupFilter, filter),
+		attrs,
+		nil,
+	)
+	r.logger.Debug().Str("base", searchRequest.BaseDN).Str("filter", searchRequest.Filter).Msg("searching nested groups")
+	res, err := r.conn.Search(searchRequest)
+	if err != nil {
+		var lerr *ldap.Error
+		if errors.As(err, &lerr) && lerr.ResultCode == ldap.LDAPResultNoSuchObject {
+			return []*ldap.Entry{}, nil
+		}
+		return nil, err
+	}
+	r.store(key, res.Entries)
+	return res.Entries, nil
+}
+
+// groupsByDN returns the groups with the given DNs including their members by normalized DN. DNs that are
+// not groups are missing in the result. The groups are searched in chunks with one filter on the RDNs of
+// all DNs of a chunk, the results are matched by DN.
+func (r *NestedGroupResolver) groupsByDN(dns []string) (map[string]*ldap.Entry, error) {
+	groups := map[string]*ldap.Entry{}
+	var uncached []string
+	for _, dn := range dns {
+		entries, ok := r.cached("group\x00" + normalizeDN(dn))
+		switch {
+		case !ok:
+			uncached = append(uncached, dn)
+		case len(entries) > 0:
+			groups[normalizeDN(dn)] = entries[0]
+		}
+	}
+
+	for chunk := range slices.Chunk(uncached, _groupByDNChunkSize) {
+		var filter strings.Builder
+		for _, dn := range chunk {
+			rdnFilter, err := rdnFilter(dn)
+			if err != nil {
+				// not a valid DN, it can not be a group either
+				r.logger.Debug().Err(err).Str("dn", dn).Msg("skipping invalid member dn")
+				continue
+			}
+			filter.WriteString(rdnFilter)
+		}
+		if filter.Len() == 0 {
+			continue
+		}
+
+		entries, err := r.searchGroups("(|"+filter.String()+")", []string{r.cfg.MemberAttribute})
+		if err != nil {
+			return nil, err
+		}
+		found := make(map[string]*ldap.Entry, len(entries))
+		for _, entry := range entries {
+			found[normalizeDN(entry.DN)] = entry
+		}
+		for _, dn := range chunk {
+			key := normalizeDN(dn)
+			if entry, ok := found[key]; ok {
+				groups[key] = entry
+				r.store("group\x00"+key, []*ldap.Entry{entry})
+				continue
+			}
+			r.store("group\x00"+key, nil)
+		}
+	}
+	return groups, nil
+}
+
+// rdnFilter returns a filter matching the relative distinguished name of the DN
+func rdnFilter(dn string) (string, error) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return "", err
+	}
+	if len(parsed.RDNs) == 0 {
+		return "", errors.New("empty dn")
+	}
+	attributes := parsed.RDNs[0].Attributes
+	if len(attributes) == 1 {
+		return fmt.Sprintf("(%s=%s)", attributes[0].Type, ldap.EscapeFilter(attributes[0].Value)), nil
+	}
+	var filter strings.Builder
+	filter.WriteString("(&")
+	for _, attribute := range attributes {
+		fmt.Fprintf(&filter, "(%s=%s)", attribute.Type, ldap.EscapeFilter(attribute.Value))
+	}
+	filter.WriteString(")")
+	return filter.String(), nil
+}
+
+// Purge drops all cached memberships. It must be called after changing group memberships, otherwise
+// the changes are only visible after the cache ttl.
+func (r *NestedGroupResolver) Purge() {
+	if r.cache != nil {
+		r.cache.DeleteAll()
+	}
+}
+
+func (r *NestedGroupResolver) cached(key string) ([]*ldap.Entry, bool) {
+	if r.cache == nil {
+		return nil, false
+	}
+	if item := r.cache.Get(key); item != nil {
+		return item.Value(), true
+	}
+	return nil, false
+}
+
+func (r *NestedGroupResolver) store(key string, entries []*ldap.Entry) {
+	if r.cache != nil {
+		r.cache.Set(key, entries, ttlcache.DefaultTTL)
+	}
+}
+
+// normalizeDN returns a representation of the DN that can be compared case insensitively
+func normalizeDN(dn string) string {
+	if parsed, err := ldap.ParseDN(dn); err == nil {
+		dn = parsed.String()
+	}
+	return strings.ToLower(dn)
+}
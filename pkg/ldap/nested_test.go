@@ -0,0 +1,200 @@
+package ldap_test
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	oldap "github.com/opencloud-eu/opencloud/pkg/ldap"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+)
+
+// fakeDirectory answers the searches of the NestedGroupResolver from a map of group DNs to member DNs
+type fakeDirectory struct {
+	ldap.Client
+	groups       map[string][]string
+	capabilities []string
+	filters      []string
+}
+
+var (
+	_memberFilter = regexp.MustCompile(`\((member|memberOf)(:[0-9.]+:)?=([^)]*)\)`)
+	_rdnFilter    = regexp.MustCompile(`\((\w+)=([^)]*)\)`)
+)
+
+func (d *fakeDirectory) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	d.filters = append(d.filters, req.Filter)
+	newGroup := func(dn string) *ldap.Entry {
+		return ldap.NewEntry(dn, map[string][]string{"cn": {strings.TrimPrefix(strings.Split(dn, ",")[0], "cn=")}, "member": d.groups[dn]})
+	}
+
+	switch {
+	case req.BaseDN == "":
+		return &ldap.SearchResult{Entries: []*ldap.Entry{ldap.NewEntry("", map[string][]string{"supportedCapabilities": d.capabilities})}}, nil
+	case strings.Contains(req.Filter, "(|"):
+		// the groups with the given RDNs
+		res := &ldap.SearchResult{}
+		for _, m := range _rdnFilter.FindAllStringSubmatch(req.Filter[strings.Index(req.Filter, "(|"):], -1) {
+			dn := m[1] + "=" + m[2] + ",ou=groups"
+			if _, ok := d.groups[dn]; ok {
+				res.Entries = append(res.Entries, newGroup(dn))
+			}
+		}
+		return res, nil
+	}
+
+	m := _memberFilter.FindStringSubmatch(req.Filter)
+	Expect(m).ToNot(BeNil())
+	res := &ldap.SearchResult{}
+	for dn, members := range d.groups {
+		switch {
+		case m[1] == "member" && m[2] == "":
+			for _, member := range members {
+				if member == m[3] {
+					res.Entries = append(res.Entries, newGroup(dn))
+				}
+			}
+		case m[1] == "member":
+			// the transitive groups of bob
+			if dn == "cn=a,ou=groups" || dn == "cn=b,ou=groups" {
+				res.Entries = append(res.Entries, newGroup(dn))
+			}
+		case m[1] == "memberOf":
+			if dn != "cn=a,ou=groups" {
+				res.Entries = append(res.Entries, newGroup(dn))
+			}
+		}
+	}
+	return res, nil
+}
+
+var _ = Describe("NestedGroupResolver", func() {
+	var (
+		directory *fakeDirectory
+		cfg       oldap.NestedGroupsConfig
+	)
+
+	names := func(entries []*ldap.Entry) []string {
+		n := make([]string, 0, len(entries))
+		for _, e := range entries {
+			n = append(n, e.GetEqualFoldAttributeValue("cn"))
+		}
+		return n
+	}
+
+	BeforeEach(func() {
+		// a contains b, b contains c and a again, c contains d
+		directory = &fakeDirectory{groups: map[string][]string{
+			"cn=a,ou=groups": {"uid=alice,ou=users", "cn=b,ou=groups"},
+			"cn=b,ou=groups": {"uid=bob,ou=users", "cn=c,ou=groups", "cn=a,ou=groups"},
+			"cn=c,ou=groups": {"uid=carol,ou=users", "cn=d,ou=groups"},
+			"cn=d,ou=groups": {"uid=dave,ou=users", "uid=alice,ou=users"},
+		}}
+		cfg = oldap.NestedGroupsConfig{
+			GroupBaseDN:         "ou=groups",
+			GroupScope:          ldap.ScopeWholeSubtree,
+			GroupFilter:         "(objectClass=groupOfNames)",
+			MemberAttribute:     "member",
+			MaxDepth:            10,
+			MatchingRuleInChain: oldap.MatchingRuleInChainNever,
+		}
+	})
+
+	It("rejects invalid configurations", func() {
+		cfg.MatchingRuleInChain = "sometimes"
+		_, err := oldap.NewNestedGroupResolver(directory, cfg, log.NopLogger())
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("GroupsOf", func() {
+		It("resolves the parent groups and stops at cycles", func() {
+			r, err := oldap.NewNestedGroupResolver(directory, cfg, log.NopLogger())
+			Expect(err).ToNot(HaveOccurred())
+			groups, err := r.GroupsOf("uid=dave,ou=users", []string{"cn"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(groups)).To(Equal([]string{"d", "c", "b", "a"}))
+		})
+
+		It("respects the depth limit", func() {
+			cfg.MaxDepth = 1
+			r, err := oldap.NewNestedGroupResolver(directory, cfg, log.NopLogger())
+			Expect(err).ToNot(HaveOccurred())
+			groups, err := r.GroupsOf("uid=dave,ou=users", []string{"cn"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(groups)).To(Equal([]string{"d", "c"}))
+		})
+
+		It("uses the matching rule in chain on active directory", func() {
+			cfg.MatchingRuleInChain = oldap.MatchingRuleInChainAuto
+			directory.capabilities = []string{"1.2.840.113556.1.4.800"}
+			r, err := oldap.NewNestedGroupResolver(directory, cfg, log.NopLogger())
+			Expect(err).ToNot(HaveOccurred())
+			groups, err := r.GroupsOf("uid=bob,ou=users", []string{"cn"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(groups)).To(ConsistOf("a", "b"))
+			Expect(directory.filters).To(ContainElement("(&(objectClass=groupOfNames)(member:1.2.840.113556.1.4.1941:=uid=bob,ou=users))"))
+		})
+
+		It("caches the searches", func() {
+			cfg.CacheTTL = time.Minute
+			r, err := oldap.NewNestedGroupResolver(directory, cfg, log.NopLogger())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = r.GroupsOf("uid=dave,ou=users", []string{"cn"})
+			Expect(err).ToNot(HaveOccurred())
+			searches := len(directory.filters)
+			_, err = r.GroupsOf("uid=dave,ou=users", []string{"cn"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(directory.filters).To(HaveLen(searches))
+
+			r.Purge()
+			_, err = r.GroupsOf("uid=dave,ou=users", []string{"cn"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(directory.filters)).To(BeNumerically(">", searches))
+		})
+	})
+
+	Describe("MemberDNs", func() {
+		It("resolves the members of nested groups and stops at cycles", func() {
+			r, err := oldap.NewNestedGroupResolver(directory, cfg, log.NopLogger())
+			Expect(err).ToNot(HaveOccurred())
+			members, err := r.MemberDNs(ldap.NewEntry("cn=a,ou=groups", map[string][]string{"member": directory.groups["cn=a,ou=groups"]}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(members).To(Equal([]string{"uid=alice,ou=users", "uid=bob,ou=users", "uid=carol,ou=users", "uid=dave,ou=users"}))
+		})
+
+		It("looks up the members of a nesting level with a single search", func() {
+			r, err := oldap.NewNestedGroupResolver(directory, cfg, log.NopLogger())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = r.MemberDNs(ldap.NewEntry("cn=a,ou=groups", map[string][]string{"member": directory.groups["cn=a,ou=groups"]}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(directory.filters).To(Equal([]string{
+				"(&(objectClass=groupOfNames)(|(uid=alice)(cn=b)))",
+				"(&(objectClass=groupOfNames)(|(uid=bob)(cn=c)))",
+				"(&(objectClass=groupOfNames)(|(uid=carol)(cn=d)))",
+				"(&(objectClass=groupOfNames)(|(uid=dave)))",
+			}))
+		})
+
+		It("respects the depth limit", func() {
+			cfg.MaxDepth = 1
+			r, err := oldap.NewNestedGroupResolver(directory, cfg, log.NopLogger())
+			Expect(err).ToNot(HaveOccurred())
+			members, err := r.MemberDNs(ldap.NewEntry("cn=a,ou=groups", map[string][]string{"member": directory.groups["cn=a,ou=groups"]}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(members).To(Equal([]string{"uid=alice,ou=users", "uid=bob,ou=users"}))
+		})
+
+		It("uses the matching rule in chain", func() {
+			cfg.MatchingRuleInChain = oldap.MatchingRuleInChainAlways
+			r, err := oldap.NewNestedGroupResolver(directory, cfg, log.NopLogger())
+			Expect(err).ToNot(HaveOccurred())
+			members, err := r.MemberDNs(ldap.NewEntry("cn=a,ou=groups", map[string][]string{"member": directory.groups["cn=a,ou=groups"]}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(members).To(ConsistOf("uid=alice,ou=users", "uid=bob,ou=users", "uid=carol,ou=users", "uid=dave,ou=users"))
+		})
+	})
+})
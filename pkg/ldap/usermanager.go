@@ -0,0 +1,192 @@
+package ldap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/user"
+	revaldap "github.com/opencloud-eu/reva/v2/pkg/user/manager/ldap"
+	"github.com/opencloud-eu/reva/v2/pkg/user/manager/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	ldapIdentity "github.com/opencloud-eu/reva/v2/pkg/utils/ldap"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+)
+
+// NestedGroupsUserManagerName is the name of the reva user manager resolving nested LDAP groups.
+const NestedGroupsUserManagerName = "ldapnested"
+
+func init() {
+	registry.Register(NestedGroupsUserManagerName, NewNestedGroupsUserManager)
+}
+
+type userManagerConfig struct {
+	utils.LDAPConn `mapstructure:",squash"`
+	LDAPIdentity   ldapIdentity.Identity `mapstructure:",squash"`
+	Idp            string                `mapstructure:"idp"`
+	GroupScope     string                `mapstructure:"group_scope"`
+	Nesting        nestingConfig         `mapstructure:"group_nesting"`
+}
+
+type nestingConfig struct {
+	MaxDepth            int           `mapstructure:"max_depth"`
+	MatchingRuleInChain string        `mapstructure:"matching_rule_in_chain"`
+	CacheTTL            time.Duration `mapstructure:"cache_ttl"`
+}
+
+// nestedGroupsUserManager wraps the reva LDAP user manager and adds the groups a user is a member of
+// through nested groups.
+type nestedGroupsUserManager struct {
+	user.Manager
+	c        *userManagerConfig
+	conn     ldap.Client
+	resolver *NestedGroupResolver
+}
+
+// NewNestedGroupsUserManager returns a user manager that resolves users with the reva LDAP user manager
+// and their group memberships with a NestedGroupResolver. It accepts the configuration of the reva
+// LDAP user manager plus a 'group_nesting' section.
+func NewNestedGroupsUserManager(m map[string]interface{}) (user.Manager, error) {
+	inner, err := revaldap.New(m)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &userManagerConfig{
+		LDAPIdentity: ldapIdentity.New(),
+	}
+	if err := mapstructure.WeakDecode(m, c); err != nil {
+		return nil, fmt.Errorf("error decoding conf: %w", err)
+	}
+	if err := c.LDAPIdentity.Setup(); err != nil {
+		return nil, fmt.Errorf("error setting up Identity config: %w", err)
+	}
+
+	conn, err := utils.GetLDAPClientWithReconnect(&c.LDAPConn)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err := stringToScope(c.GroupScope)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := NewNestedGroupResolver(conn, NestedGroupsConfig{
+		GroupBaseDN:         c.LDAPIdentity.Group.BaseDN,
+		GroupScope:          scope,
+		GroupFilter:         fmt.Sprintf("(&%s(objectClass=%s))", c.LDAPIdentity.Group.Filter, c.LDAPIdentity.Group.Objectclass),
+		MemberAttribute:     c.LDAPIdentity.Group.Schema.Member,
+		MaxDepth:            c.Nesting.MaxDepth,
+		MatchingRuleInChain: c.Nesting.MatchingRuleInChain,
+		CacheTTL:            c.Nesting.CacheTTL,
+	}, log.NopLogger())
+	if err != nil {
+		return nil, err
+	}
+
+	return &nestedGroupsUserManager{
+		Manager:  inner,
+		c:        c,
+		conn:     conn,
+		resolver: resolver,
+	}, nil
+}
+
+// GetUser implements the user.Manager interface
+func (m *nestedGroupsUserManager) GetUser(ctx context.Context, uid *userpb.UserId, skipFetchingGroups bool) (*userpb.User, error) {
+	u, err := m.Manager.GetUser(ctx, uid, true)
+	if err != nil || skipFetchingGroups {
+		return u, err
+	}
+	return m.withGroups(ctx, u)
+}
+
+// GetUserByClaim implements the user.Manager interface
+func (m *nestedGroupsUserManager) GetUserByClaim(ctx context.Context, claim, value, tenantID string, skipFetchingGroups bool) (*userpb.User, error) {
+	u, err := m.Manager.GetUserByClaim(ctx, claim, value, tenantID, true)
+	if err != nil || skipFetchingGroups {
+		return u, err
+	}
+	return m.withGroups(ctx, u)
+}
+
+// FindUsers implements the user.Manager interface
+func (m *nestedGroupsUserManager) FindUsers(ctx context.Context, query, tenantID string, skipFetchingGroups bool) ([]*userpb.User, error) {
+	users, err := m.Manager.FindUsers(ctx, query, tenantID, true)
+	if err != nil || skipFetchingGroups {
+		return users, err
+	}
+	for i := range users {
+		if users[i], err = m.withGroups(ctx, users[i]); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+// GetUserGroups implements the user.Manager interface. It returns the ids of all groups the user
+// is a member of, directly or through nested groups.
+func (m *nestedGroupsUserManager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]string, error) {
+	if uid.Idp != "" && uid.Idp != m.c.Idp {
+		return nil, errtypes.NotFound("idp mismatch")
+	}
+	if strings.EqualFold(m.c.LDAPIdentity.Group.Objectclass, "posixGroup") {
+		// posixGroups reference their members by username, they can't be nested
+		return m.Manager.GetUserGroups(ctx, uid)
+	}
+
+	userEntry, err := m.c.LDAPIdentity.GetLDAPUserByID(ctx, m.conn, uid)
+	if err != nil {
+		appctx.GetLogger(ctx).Debug().Err(err).Interface("userid", uid).Msg("Failed to lookup user")
+		return []string{}, err
+	}
+
+	schema := m.c.LDAPIdentity.Group.Schema
+	entries, err := m.resolver.GroupsOf(userEntry.DN, []string{schema.ID})
+	if err != nil {
+		return []string{}, err
+	}
+	groups := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !schema.IDIsOctetString {
+			groups = append(groups, e.GetEqualFoldAttributeValue(schema.ID))
+			continue
+		}
+		id, err := uuid.FromBytes(e.GetEqualFoldRawAttributeValue(schema.ID))
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, id.String())
+	}
+	return groups, nil
+}
+
+func (m *nestedGroupsUserManager) withGroups(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	groups, err := m.GetUserGroups(ctx, u.GetId())
+	if err != nil {
+		return nil, err
+	}
+	u.Groups = groups
+	return u, nil
+}
+
+func stringToScope(scope string) (int, error) {
+	switch scope {
+	case "", "sub":
+		return ldap.ScopeWholeSubtree, nil
+	case "one":
+		return ldap.ScopeSingleLevel, nil
+	case "base":
+		return ldap.ScopeBaseObject, nil
+	default:
+		return 0, fmt.Errorf("invalid scope '%s'", scope)
+	}
+}
@@ -7,8 +7,8 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/auth/scope"
 
 	"github.com/opencloud-eu/opencloud/pkg/account"
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
-	"github.com/opencloud-eu/reva/v2/pkg/token/manager/jwt"
 	"go-micro.dev/v4/metadata"
 )
 
@@ -33,10 +33,9 @@ const RoleIDs string = "Role-Ids"
 // and write it to the context. If there is no x-access-token the middleware is omitted.
 func ExtractAccountUUID(opts ...account.Option) func(http.Handler) http.Handler {
 	opt := newAccountOptions(opts...)
-	tokenManager, err := jwt.New(map[string]interface{}{
-		"secret":  opt.JWTSecret,
-		"expires": int64(24 * 60 * 60),
-	})
+	tokenManagerConfig := secrets.TokenManagerConfig(opt.JWTSecret, opt.PreviousJWTSecrets)
+	tokenManagerConfig["expires"] = int64(24 * 60 * 60)
+	tokenManager, err := secrets.NewTokenManager(tokenManagerConfig)
 	if err != nil {
 		opt.Logger.Fatal().Err(err).Msgf("Could not initialize token-manager")
 	}
@@ -0,0 +1,24 @@
+package retention
+
+import (
+	"encoding/json"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+// PolicyChanged is emitted when the retention policy or a legal hold of a space was changed. The
+// storage providers read the policy of the space again instead of waiting for their cached one to expire.
+type PolicyChanged struct {
+	Executant *user.UserId
+	// SpaceID is the id of the space in the storageid$spaceid format
+	SpaceID   string
+	Timestamp *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (PolicyChanged) Unmarshal(v []byte) (interface{}, error) {
+	e := PolicyChanged{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
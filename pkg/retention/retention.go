@@ -0,0 +1,205 @@
+// Package retention describes the retention policies and legal holds of spaces.
+//
+// The policy of a space is kept in the arbitrary metadata of its root. It is managed by the graph service
+// and enforced by the storage-users service.
+package retention
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+)
+
+// The metadata keys of the policy. They are not namespaced, so they can't be changed with a PROPPATCH.
+const (
+	// KeyPrefix is shared by all keys of the policy.
+	KeyPrefix        = "opencloud.retention."
+	VersionsDaysKey  = "opencloud.retention.versions.days"
+	VersionsCountKey = "opencloud.retention.versions.count"
+	TrashDaysKey     = "opencloud.retention.trash.days"
+	MinAgeDaysKey    = "opencloud.retention.minage.days"
+	LegalHoldKey     = "opencloud.retention.legalhold"
+	// HoldKeyPrefix is followed by the id of a folder under legal hold, the value is the path of the folder.
+	HoldKeyPrefix = "opencloud.retention.hold."
+)
+
+const _day = 24 * time.Hour
+
+// Policy is the retention policy of a space. Rules with a zero value are not applied.
+type Policy struct {
+	// VersionsDays is the number of days the versions of a file are kept.
+	VersionsDays int
+	// VersionsCount is the number of versions of a file that are kept.
+	VersionsCount int
+	// TrashDays is the number of days after which items are purged from the trash-bin.
+	TrashDays int
+	// MinAgeDays is the number of days a resource can't be deleted after it was changed.
+	MinAgeDays int
+	// LegalHold prevents deleting and purging anything in the space.
+	LegalHold bool
+	// Holds maps the ids of the folders under legal hold to their paths in the space.
+	Holds map[string]string
+}
+
+// FromMetadata reads the policy from the arbitrary metadata of a space root.
+func FromMetadata(md map[string]string) Policy {
+	days := func(key string) int {
+		n, err := strconv.Atoi(md[key])
+		if err != nil || n < 0 {
+			return 0
+		}
+		return n
+	}
+	p := Policy{
+		VersionsDays:  days(VersionsDaysKey),
+		VersionsCount: days(VersionsCountKey),
+		TrashDays:     days(TrashDaysKey),
+		MinAgeDays:    days(MinAgeDaysKey),
+	}
+	p.LegalHold, _ = strconv.ParseBool(md[LegalHoldKey])
+	for k, v := range md {
+		if id, ok := strings.CutPrefix(k, HoldKeyPrefix); ok && id != "" {
+			if p.Holds == nil {
+				p.Holds = map[string]string{}
+			}
+			p.Holds[id] = cleanPath(v)
+		}
+	}
+	return p
+}
+
+// Metadata returns the metadata to set on the space root and the keys to unset for the rules of the policy.
+// The legal holds of folders are not part of it.
+func (p Policy) Metadata() (map[string]string, []string) {
+	set, unset := map[string]string{}, []string{}
+	for k, v := range map[string]int{
+		VersionsDaysKey:  p.VersionsDays,
+		VersionsCountKey: p.VersionsCount,
+		TrashDaysKey:     p.TrashDays,
+		MinAgeDaysKey:    p.MinAgeDays,
+	} {
+		if v > 0 {
+			set[k] = strconv.Itoa(v)
+		} else {
+			unset = append(unset, k)
+		}
+	}
+	if p.LegalHold {
+		set[LegalHoldKey] = "true"
+	} else {
+		unset = append(unset, LegalHoldKey)
+	}
+	return set, unset
+}
+
+// IsKey tells if the metadata key belongs to the policy.
+func IsKey(key string) bool {
+	return strings.HasPrefix(key, KeyPrefix)
+}
+
+// Empty tells if the policy has neither rules nor legal holds.
+func (p Policy) Empty() bool {
+	return p.VersionsDays == 0 && p.VersionsCount == 0 && p.TrashDays == 0 && p.MinAgeDays == 0 && !p.Holding()
+}
+
+// Holding tells if the space or any of its folders is under legal hold.
+func (p Policy) Holding() bool {
+	return p.LegalHold || len(p.Holds) > 0
+}
+
+// Held tells if the resource at the path is protected by a legal hold. That is the case when the space, a
+// parent folder or the folder itself is under legal hold, or when it contains a folder under legal hold.
+func (p Policy) Held(resourcePath string) bool {
+	if p.LegalHold {
+		return true
+	}
+	resourcePath = cleanPath(resourcePath)
+	for _, h := range p.Holds {
+		if within(resourcePath, h) || within(h, resourcePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrashExpired tells if an item deleted at the given time is to be purged from the trash-bin.
+func (p Policy) TrashExpired(deleted, now time.Time) bool {
+	return p.TrashDays > 0 && deleted.Before(now.Add(-time.Duration(p.TrashDays)*_day))
+}
+
+// TooYoung tells if a resource changed at the given time can't be deleted yet.
+func (p Policy) TooYoung(mtime, now time.Time) bool {
+	return p.MinAgeDays > 0 && mtime.After(now.Add(-time.Duration(p.MinAgeDays)*_day))
+}
+
+// PrunesVersions tells if the policy limits the versions of files.
+func (p Policy) PrunesVersions() bool {
+	return p.VersionsDays > 0 || p.VersionsCount > 0
+}
+
+// KeepVersion tells if a version is kept, rank is the position of the version counted from the newest one,
+// starting at 0. A version is kept as long as any of the rules keeps it.
+func (p Policy) KeepVersion(rank int, mtime, now time.Time) bool {
+	switch {
+	case !p.PrunesVersions():
+		return true
+	case p.VersionsCount > 0 && rank < p.VersionsCount:
+		return true
+	case p.VersionsDays > 0 && mtime.After(now.Add(-time.Duration(p.VersionsDays)*_day)):
+		return true
+	default:
+		return false
+	}
+}
+
+// SpaceRoot returns the id of the root of the space the resource belongs to.
+func SpaceRoot(id *provider.ResourceId) *provider.ResourceId {
+	return &provider.ResourceId{
+		StorageId: id.GetStorageId(),
+		SpaceId:   id.GetSpaceId(),
+		OpaqueId:  id.GetSpaceId(),
+	}
+}
+
+// Get reads the policy of the space the resource belongs to. The paths of the folders under legal hold are
+// updated to where the folders are now.
+func Get(ctx context.Context, gwc gateway.GatewayAPIClient, id *provider.ResourceId) (Policy, error) {
+	root := SpaceRoot(id)
+	res, err := gwc.Stat(ctx, &provider.StatRequest{
+		Ref:                   &provider.Reference{ResourceId: root},
+		ArbitraryMetadataKeys: []string{"*"},
+	})
+	if err != nil {
+		return Policy{}, err
+	}
+	if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		return Policy{}, errtypes.NewErrtypeFromStatus(res.GetStatus())
+	}
+
+	p := FromMetadata(res.GetInfo().GetArbitraryMetadata().GetMetadata())
+	for hid := range p.Holds {
+		pres, err := gwc.GetPath(ctx, &provider.GetPathRequest{
+			ResourceId: &provider.ResourceId{StorageId: root.GetStorageId(), SpaceId: root.GetSpaceId(), OpaqueId: hid},
+		})
+		if err == nil && pres.GetStatus().GetCode() == rpc.Code_CODE_OK {
+			p.Holds[hid] = cleanPath(pres.GetPath())
+		}
+	}
+	return p, nil
+}
+
+func cleanPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// within tells if p is the same as or below parent.
+func within(p, parent string) bool {
+	return p == parent || strings.HasPrefix(p, strings.TrimSuffix(parent, "/")+"/")
+}
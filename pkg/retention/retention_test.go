@@ -0,0 +1,76 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	p := Policy{VersionsDays: 30, TrashDays: 7, LegalHold: true}
+
+	set, unset := p.Metadata()
+	assert.Equal(t, map[string]string{
+		VersionsDaysKey: "30",
+		TrashDaysKey:    "7",
+		LegalHoldKey:    "true",
+	}, set)
+	assert.ElementsMatch(t, []string{VersionsCountKey, MinAgeDaysKey}, unset)
+
+	set[HoldKeyPrefix+"folder-id"] = "projects/a"
+	set[VersionsCountKey] = "invalid"
+	got := FromMetadata(set)
+	assert.Equal(t, 30, got.VersionsDays)
+	assert.Equal(t, 0, got.VersionsCount)
+	assert.Equal(t, 7, got.TrashDays)
+	assert.True(t, got.LegalHold)
+	assert.Equal(t, map[string]string{"folder-id": "/projects/a"}, got.Holds)
+	assert.True(t, FromMetadata(map[string]string{"tags": "a,b"}).Empty())
+}
+
+func TestHeld(t *testing.T) {
+	p := Policy{Holds: map[string]string{"id": "/projects/a"}}
+
+	assert.True(t, p.Held("/projects/a"))
+	assert.True(t, p.Held("projects/a/file.txt"))
+	assert.True(t, p.Held("/projects"), "a parent of a held folder is held")
+	assert.True(t, p.Held("/"))
+	assert.False(t, p.Held("/projects/ab"))
+	assert.False(t, p.Held("/other"))
+	assert.True(t, Policy{LegalHold: true}.Held("/other"))
+	assert.False(t, Policy{}.Held("/other"))
+}
+
+func TestRules(t *testing.T) {
+	now := time.Now()
+	p := Policy{TrashDays: 2, MinAgeDays: 1}
+
+	assert.True(t, p.TrashExpired(now.Add(-49*time.Hour), now))
+	assert.False(t, p.TrashExpired(now.Add(-47*time.Hour), now))
+	assert.False(t, Policy{}.TrashExpired(now.Add(-1000*time.Hour), now))
+
+	assert.True(t, p.TooYoung(now.Add(-23*time.Hour), now))
+	assert.False(t, p.TooYoung(now.Add(-25*time.Hour), now))
+	assert.False(t, Policy{}.TooYoung(now, now))
+}
+
+func TestKeepVersion(t *testing.T) {
+	now := time.Now()
+	old, recent := now.Add(-72*time.Hour), now.Add(-time.Hour)
+
+	assert.True(t, Policy{}.KeepVersion(10, old, now))
+
+	count := Policy{VersionsCount: 2}
+	assert.True(t, count.KeepVersion(1, old, now))
+	assert.False(t, count.KeepVersion(2, recent, now))
+
+	days := Policy{VersionsDays: 2}
+	assert.True(t, days.KeepVersion(5, recent, now))
+	assert.False(t, days.KeepVersion(0, old, now))
+
+	both := Policy{VersionsDays: 2, VersionsCount: 1}
+	assert.True(t, both.KeepVersion(0, old, now))
+	assert.True(t, both.KeepVersion(3, recent, now))
+	assert.False(t, both.KeepVersion(1, old, now))
+}
@@ -0,0 +1,123 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/mitchellh/mapstructure"
+	"github.com/opencloud-eu/reva/v2/pkg/auth"
+	"github.com/opencloud-eu/reva/v2/pkg/auth/manager/registry"
+
+	"github.com/opencloud-eu/opencloud/pkg/shared"
+)
+
+// RotatingAuthManagerName is the name of the reva auth manager accepting previous secrets.
+const RotatingAuthManagerName = "rotating"
+
+func init() {
+	registry.Register(RotatingAuthManagerName, NewAuthManager)
+}
+
+type authManagerConfig struct {
+	AuthManager string                      `mapstructure:"auth_manager"`
+	Current     map[string]interface{}      `mapstructure:"current"`
+	Previous    []previousAuthManagerConfig `mapstructure:"previous"`
+}
+
+type previousAuthManagerConfig struct {
+	// Expires is the unix time after which the wrapped auth manager is no longer used.
+	Expires int64                  `mapstructure:"expires"`
+	Config  map[string]interface{} `mapstructure:"config"`
+}
+
+type previousAuthManager struct {
+	auth.Manager
+	expires time.Time
+}
+
+type authManager struct {
+	current  auth.Manager
+	previous []previousAuthManager
+}
+
+// AuthManagerConfig returns the configuration of the rotating reva auth manager. It wraps the
+// auth manager with the given name, configured by config for the current and each previous secret.
+func AuthManagerConfig(name, current string, previous shared.PreviousSecrets, config func(secret string) map[string]interface{}) map[string]interface{} {
+	prev := make([]map[string]interface{}, 0, len(previous))
+	for _, p := range previous {
+		if p.Secret == "" {
+			continue
+		}
+		prev = append(prev, map[string]interface{}{
+			"expires": p.Expires.Unix(),
+			"config":  config(p.Secret),
+		})
+	}
+	return map[string]interface{}{
+		"auth_manager": name,
+		"current":      config(current),
+		"previous":     prev,
+	}
+}
+
+// NewAuthManager returns an auth manager that authenticates with the wrapped auth manager
+// configured for the current secret and falls back to the ones configured for previous secrets.
+func NewAuthManager(m map[string]interface{}) (auth.Manager, error) {
+	am := &authManager{}
+	if err := am.Configure(m); err != nil {
+		return nil, err
+	}
+	return am, nil
+}
+
+// Configure configures the wrapped auth managers.
+func (am *authManager) Configure(m map[string]interface{}) error {
+	c := &authManagerConfig{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return fmt.Errorf("error decoding conf: %w", err)
+	}
+
+	newFunc, ok := registry.NewFuncs[c.AuthManager]
+	if !ok || c.AuthManager == RotatingAuthManagerName {
+		return fmt.Errorf("unknown auth manager '%s'", c.AuthManager)
+	}
+
+	current, err := newFunc(c.Current)
+	if err != nil {
+		return err
+	}
+
+	previous := make([]previousAuthManager, 0, len(c.Previous))
+	for _, p := range c.Previous {
+		mgr, err := newFunc(p.Config)
+		if err != nil {
+			return err
+		}
+		previous = append(previous, previousAuthManager{Manager: mgr, expires: time.Unix(p.Expires, 0)})
+	}
+
+	am.current, am.previous = current, previous
+	return nil
+}
+
+// Authenticate authenticates with the current secret first and tries the previous secrets that have not expired yet.
+func (am *authManager) Authenticate(ctx context.Context, clientID, clientSecret string) (*userpb.User, map[string]*authpb.Scope, error) {
+	u, scope, err := am.current.Authenticate(ctx, clientID, clientSecret)
+	if err == nil {
+		return u, scope, nil
+	}
+
+	now := time.Now()
+	for _, p := range am.previous {
+		if !now.Before(p.expires) {
+			continue
+		}
+		if u, scope, perr := p.Authenticate(ctx, clientID, clientSecret); perr == nil {
+			return u, scope, nil
+		}
+	}
+	return nil, nil, err
+}
@@ -0,0 +1,71 @@
+package secrets_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/auth"
+	"github.com/opencloud-eu/reva/v2/pkg/auth/manager/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
+	"github.com/opencloud-eu/opencloud/pkg/shared"
+)
+
+type secretAuthManager struct {
+	secret string
+}
+
+func (m *secretAuthManager) Configure(c map[string]interface{}) error {
+	m.secret, _ = c["secret"].(string)
+	return nil
+}
+
+func (m *secretAuthManager) Authenticate(_ context.Context, clientID, clientSecret string) (*userpb.User, map[string]*authpb.Scope, error) {
+	if clientSecret != m.secret {
+		return nil, nil, errors.New("secrets do not match")
+	}
+	return &userpb.User{Id: &userpb.UserId{OpaqueId: clientID}}, nil, nil
+}
+
+func init() {
+	registry.Register("secret", func(c map[string]interface{}) (auth.Manager, error) {
+		m := &secretAuthManager{}
+		return m, m.Configure(c)
+	})
+}
+
+func TestAuthManager(t *testing.T) {
+	cfg := secrets.AuthManagerConfig("secret", "current", shared.PreviousSecrets{
+		{Secret: "previous", Expires: time.Now().Add(time.Hour)},
+		{Secret: "expired", Expires: time.Now().Add(-time.Hour)},
+	}, func(secret string) map[string]interface{} {
+		return map[string]interface{}{"secret": secret}
+	})
+
+	am, err := registry.NewFuncs[secrets.RotatingAuthManagerName](cfg)
+	require.NoError(t, err)
+
+	for secret, valid := range map[string]bool{"current": true, "previous": true, "expired": false, "unknown": false} {
+		u, _, err := am.Authenticate(context.Background(), "client", secret)
+		if !valid {
+			assert.EqualError(t, err, "secrets do not match")
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, "client", u.GetId().GetOpaqueId())
+	}
+}
+
+func TestAuthManagerUnknown(t *testing.T) {
+	_, err := secrets.NewAuthManager(map[string]interface{}{"auth_manager": "nonexistent"})
+	assert.Error(t, err)
+
+	_, err = secrets.NewAuthManager(map[string]interface{}{"auth_manager": secrets.RotatingAuthManagerName})
+	assert.Error(t, err)
+}
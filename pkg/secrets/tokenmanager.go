@@ -0,0 +1,159 @@
+// Package secrets allows services to accept previous secrets next to the current ones,
+// so that secrets can be rotated without invalidating everything signed with the old ones.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	auth "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/mitchellh/mapstructure"
+	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
+	"github.com/opencloud-eu/reva/v2/pkg/token"
+	"github.com/opencloud-eu/reva/v2/pkg/token/manager/jwt"
+	"github.com/opencloud-eu/reva/v2/pkg/token/manager/registry"
+
+	"github.com/opencloud-eu/opencloud/pkg/shared"
+)
+
+var (
+	mu         sync.RWMutex
+	registered = map[string]shared.PreviousSecrets{}
+)
+
+func init() {
+	// replace the reva jwt token manager, so that the reva services accept the previous secrets of their config as well
+	registry.Register("jwt", NewTokenManager)
+}
+
+// TokenManagerConfig returns the configuration of the jwt token manager for the current and the previous secrets.
+func TokenManagerConfig(current string, previous shared.PreviousSecrets) map[string]interface{} {
+	prev := make([]map[string]interface{}, 0, len(previous))
+	for _, p := range previous {
+		if p.Secret == "" {
+			continue
+		}
+		prev = append(prev, map[string]interface{}{
+			"secret":  p.Secret,
+			"expires": p.Expires.Unix(),
+		})
+	}
+	return map[string]interface{}{
+		"secret":   current,
+		"previous": prev,
+	}
+}
+
+// TokenManagersConfig returns the 'token_managers' configuration of the reva services, interceptors and
+// middlewares that validate tokens.
+func TokenManagersConfig(current string, previous shared.PreviousSecrets) map[string]interface{} {
+	return map[string]interface{}{
+		"jwt": TokenManagerConfig(current, previous),
+	}
+}
+
+// RegisterPreviousJWTSecrets makes the token managers for the current secret accept the previous secrets,
+// when their configuration doesn't contain previous secrets. It is only needed for token managers whose
+// configuration is built by reva and can't be passed in, like the one of the ocdav service.
+func RegisterPreviousJWTSecrets(current string, previous shared.PreviousSecrets) {
+	mu.Lock()
+	defer mu.Unlock()
+	registered[current] = previous
+}
+
+func registeredPreviousJWTSecrets(current string) shared.PreviousSecrets {
+	mu.RLock()
+	defer mu.RUnlock()
+	return registered[current]
+}
+
+type tokenManagerConfig struct {
+	Previous []previousTokenManagerConfig `mapstructure:"previous"`
+}
+
+type previousTokenManagerConfig struct {
+	Secret string `mapstructure:"secret"`
+	// Expires is the unix time after which the previous secret is no longer accepted.
+	Expires int64 `mapstructure:"expires"`
+}
+
+type previousTokenManager struct {
+	token.Manager
+	expires time.Time
+}
+
+type tokenManager struct {
+	current  token.Manager
+	previous []previousTokenManager
+}
+
+// NewTokenManager returns a jwt token manager that mints tokens with the configured secret and
+// accepts tokens signed with the configured secret or any of the configured previous secrets
+// that has not expired yet.
+func NewTokenManager(m map[string]interface{}) (token.Manager, error) {
+	c := &tokenManagerConfig{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, fmt.Errorf("error decoding conf: %w", err)
+	}
+
+	jwtConfig := maps.Clone(m)
+	if jwtConfig == nil {
+		jwtConfig = map[string]interface{}{}
+	}
+	delete(jwtConfig, "previous")
+
+	current, err := jwt.New(jwtConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := m["previous"]; !ok {
+		secret, _ := jwtConfig["secret"].(string)
+		for _, p := range registeredPreviousJWTSecrets(sharedconf.GetJWTSecret(secret)) {
+			c.Previous = append(c.Previous, previousTokenManagerConfig{Secret: p.Secret, Expires: p.Expires.Unix()})
+		}
+	}
+
+	tm := &tokenManager{current: current}
+	for _, p := range c.Previous {
+		if p.Secret == "" {
+			continue
+		}
+		pc := maps.Clone(jwtConfig)
+		pc["secret"] = p.Secret
+		prev, err := jwt.New(pc)
+		if err != nil {
+			return nil, err
+		}
+		tm.previous = append(tm.previous, previousTokenManager{Manager: prev, expires: time.Unix(p.Expires, 0)})
+	}
+	return tm, nil
+}
+
+// MintToken mints a token with the current secret.
+func (tm *tokenManager) MintToken(ctx context.Context, u *user.User, scope map[string]*auth.Scope) (string, error) {
+	return tm.current.MintToken(ctx, u, scope)
+}
+
+// DismantleToken validates the token with the current secret and falls back to the previous secrets.
+func (tm *tokenManager) DismantleToken(ctx context.Context, tkn string) (*user.User, map[string]*auth.Scope, error) {
+	u, scope, err := tm.current.DismantleToken(ctx, tkn)
+	if err == nil {
+		return u, scope, nil
+	}
+
+	now := time.Now()
+	for _, p := range tm.previous {
+		if !now.Before(p.expires) {
+			continue
+		}
+		if u, scope, perr := p.DismantleToken(ctx, tkn); perr == nil {
+			return u, scope, nil
+		}
+	}
+	return nil, nil, err
+}
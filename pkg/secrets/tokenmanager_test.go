@@ -0,0 +1,76 @@
+package secrets_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/token/manager/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
+	"github.com/opencloud-eu/opencloud/pkg/shared"
+)
+
+func mint(t *testing.T, secret string) string {
+	t.Helper()
+	tm, err := secrets.NewTokenManager(map[string]interface{}{"secret": secret})
+	require.NoError(t, err)
+	tkn, err := tm.MintToken(context.Background(), &user.User{Id: &user.UserId{OpaqueId: "einstein", Idp: "idp"}}, nil)
+	require.NoError(t, err)
+	return tkn
+}
+
+func TestTokenManager(t *testing.T) {
+	current := mint(t, "current")
+	previous := mint(t, "previous")
+	expired := mint(t, "expired")
+	unknown := mint(t, "unknown")
+
+	tm, err := registry.NewFuncs["jwt"](secrets.TokenManagerConfig("current", shared.PreviousSecrets{
+		{Secret: "previous", Expires: time.Now().Add(time.Hour)},
+		{Secret: "expired", Expires: time.Now().Add(-time.Hour)},
+	}))
+	require.NoError(t, err, "the reva jwt token manager needs to be replaced")
+
+	for tkn, valid := range map[string]bool{current: true, previous: true, expired: false, unknown: false} {
+		u, _, err := tm.DismantleToken(context.Background(), tkn)
+		if !valid {
+			assert.Error(t, err)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, "einstein", u.GetId().GetOpaqueId())
+	}
+
+	// new tokens are always signed with the current secret
+	tkn, err := tm.MintToken(context.Background(), &user.User{Id: &user.UserId{OpaqueId: "marie"}}, nil)
+	require.NoError(t, err)
+	tm, err = secrets.NewTokenManager(map[string]interface{}{"secret": "current"})
+	require.NoError(t, err)
+	_, _, err = tm.DismantleToken(context.Background(), tkn)
+	assert.NoError(t, err)
+
+	// without previous secrets in the config only the current secret is accepted
+	_, _, err = tm.DismantleToken(context.Background(), previous)
+	assert.Error(t, err)
+}
+
+func TestRegisteredPreviousJWTSecrets(t *testing.T) {
+	previous := mint(t, "old")
+
+	secrets.RegisterPreviousJWTSecrets("registered", shared.PreviousSecrets{{Secret: "old", Expires: time.Now().Add(time.Hour)}})
+	t.Cleanup(func() { secrets.RegisterPreviousJWTSecrets("registered", nil) })
+
+	tm, err := secrets.NewTokenManager(map[string]interface{}{"secret": "registered"})
+	require.NoError(t, err)
+	_, _, err = tm.DismantleToken(context.Background(), previous)
+	assert.NoError(t, err)
+
+	tm, err = secrets.NewTokenManager(map[string]interface{}{"secret": "other"})
+	require.NoError(t, err)
+	_, _, err = tm.DismantleToken(context.Background(), previous)
+	assert.Error(t, err, "previous secrets are only registered for their current secret")
+}
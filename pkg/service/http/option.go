@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"crypto/x509"
 	"net/http"
 
 	"github.com/opencloud-eu/opencloud/pkg/log"
@@ -17,6 +18,7 @@ type Option func(o *Options)
 type Options struct {
 	Logger        log.Logger
 	TLSConfig     shared.HTTPServiceTLS
+	ClientCAs     *x509.CertPool
 	Namespace     string
 	Name          string
 	Version       string
@@ -96,6 +98,14 @@ func TLSConfig(config shared.HTTPServiceTLS) Option {
 	}
 }
 
+// ClientCAs provides a function to set the ClientCAs option. When set, the TLS listener requests
+// client certificates issued by one of the CAs. Clients without a certificate are still accepted.
+func ClientCAs(pool *x509.CertPool) Option {
+	return func(o *Options) {
+		o.ClientCAs = pool
+	}
+}
+
 // TraceProvider provides a function to set the TraceProvider option.
 func TraceProvider(tp trace.TracerProvider) Option {
 	return func(o *Options) {
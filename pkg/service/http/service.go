@@ -50,6 +50,10 @@ func NewService(opts ...Option) (Service, error) {
 		tlsConfig := &tls.Config{
 			Certificates: []tls.Certificate{cert},
 		}
+		if sopts.ClientCAs != nil {
+			tlsConfig.ClientCAs = sopts.ClientCAs
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
 		mServer = mhttps.NewServer(server.TLSConfig(tlsConfig))
 	} else {
 		mServer = mhttps.NewServer()
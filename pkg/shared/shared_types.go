@@ -1,6 +1,10 @@
 package shared
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // EnvBinding represents a direct binding from an env variable to a go kind. Along with gookit/config, its primal goal
 // is to unpack environment variables into a Go value. We do so with reflection, and this data structure is just a step
@@ -28,7 +32,39 @@ type Tracing struct {
 
 // TokenManager is the config for using the reva token manager
 type TokenManager struct {
-	JWTSecret string `mask:"password" yaml:"jwt_secret" env:"OC_JWT_SECRET" desc:"The secret to mint and validate jwt tokens." introductionVersion:"1.0.0"`
+	JWTSecret          string          `mask:"password" yaml:"jwt_secret" env:"OC_JWT_SECRET" desc:"The secret to mint and validate jwt tokens." introductionVersion:"1.0.0"`
+	PreviousJWTSecrets PreviousSecrets `mask:"struct" yaml:"previous_jwt_secrets" env:"OC_PREVIOUS_JWT_SECRETS" desc:"Secrets that were replaced by the current jwt secret. Tokens signed with them are still accepted until they expire. Written by 'opencloud init --rotate'. In the environment they are set as a JSON list of objects with a 'secret' and an 'expires' timestamp." introductionVersion:"%%NEXT%%"`
+}
+
+// PreviousSecret is a secret that has been replaced by a new one, but is still accepted until it expires.
+type PreviousSecret struct {
+	Secret  string    `mask:"password" yaml:"secret" json:"secret" desc:"The previous secret." introductionVersion:"%%NEXT%%"`
+	Expires time.Time `yaml:"expires" json:"expires" desc:"The point in time after which the previous secret is no longer accepted." introductionVersion:"%%NEXT%%"`
+}
+
+// PreviousSecrets is a list of secrets that are accepted in addition to the current one.
+type PreviousSecrets []PreviousSecret
+
+// Decode decodes the previous secrets from an environment variable. They are given as JSON list, e.g.
+// '[{"secret":"...","expires":"2025-01-01T00:00:00Z"}]'.
+func (ps *PreviousSecrets) Decode(value string) error {
+	var decoded []PreviousSecret
+	if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+		return fmt.Errorf("could not decode the previous secrets: %w", err)
+	}
+	*ps = decoded
+	return nil
+}
+
+// Active returns the previous secrets that have not expired at t.
+func (ps PreviousSecrets) Active(t time.Time) []string {
+	active := make([]string, 0, len(ps))
+	for _, p := range ps {
+		if p.Secret != "" && t.Before(p.Expires) {
+			active = append(active, p.Secret)
+		}
+	}
+	return active
 }
 
 // Reva defines all available REVA client configuration.
@@ -69,21 +105,22 @@ type Cache struct {
 // Commons holds configuration that are common to all extensions. Each extension can then decide whether
 // to overwrite its values.
 type Commons struct {
-	Log                *Log            `yaml:"log"`
-	Tracing            *Tracing        `yaml:"tracing"`
-	Cache              *Cache          `yaml:"cache"`
-	GRPCClientTLS      *GRPCClientTLS  `yaml:"grpc_client_tls"`
-	GRPCServiceTLS     *GRPCServiceTLS `yaml:"grpc_service_tls"`
-	HTTPServiceTLS     HTTPServiceTLS  `yaml:"http_service_tls"`
-	OpenCloudURL       string          `yaml:"opencloud_url" env:"OC_URL" desc:"URL, where OpenCloud is reachable for users." introductionVersion:"1.0.0"`
-	TokenManager       *TokenManager   `mask:"struct" yaml:"token_manager"`
-	Reva               *Reva           `yaml:"reva"`
-	MachineAuthAPIKey  string          `mask:"password" yaml:"machine_auth_api_key" env:"OC_MACHINE_AUTH_API_KEY" desc:"Machine auth API key used to validate internal requests necessary for the access to resources from other services." introductionVersion:"1.0.0"`
-	TransferSecret     string          `mask:"password" yaml:"transfer_secret,omitempty" env:"REVA_TRANSFER_SECRET" desc:"The secret used for signing the requests towards the data gateway for up- and downloads." introductionVersion:"1.0.0"`
-	SystemUserID       string          `yaml:"system_user_id" env:"OC_SYSTEM_USER_ID" desc:"ID of the OpenCloud storage-system system user. Admins need to set the ID for the storage-system system user in this config option which is then used to reference the user. Any reasonable long string is possible, preferably this would be an UUIDv4 format." introductionVersion:"1.0.0"`
-	SystemUserAPIKey   string          `mask:"password" yaml:"system_user_api_key" env:"SYSTEM_USER_API_KEY" desc:"API key for all system users." introductionVersion:"1.0.0"`
-	AdminUserID        string          `yaml:"admin_user_id" env:"OC_ADMIN_USER_ID" desc:"ID of a user, that should receive admin privileges. Consider that the UUID can be encoded in some LDAP deployment configurations like in .ldif files. These need to be decoded beforehand." introductionVersion:"1.0.0"`
-	MultiTenantEnabled bool            `yaml:"multi_tenant_enabled" env:"OC_MULTI_TENANT_ENABLED" desc:"Set this to true to enable multi-tenant support." introductionVersion:"%%NEXT%%"`
+	Log                        *Log            `yaml:"log"`
+	Tracing                    *Tracing        `yaml:"tracing"`
+	Cache                      *Cache          `yaml:"cache"`
+	GRPCClientTLS              *GRPCClientTLS  `yaml:"grpc_client_tls"`
+	GRPCServiceTLS             *GRPCServiceTLS `yaml:"grpc_service_tls"`
+	HTTPServiceTLS             HTTPServiceTLS  `yaml:"http_service_tls"`
+	OpenCloudURL               string          `yaml:"opencloud_url" env:"OC_URL" desc:"URL, where OpenCloud is reachable for users." introductionVersion:"1.0.0"`
+	TokenManager               *TokenManager   `mask:"struct" yaml:"token_manager"`
+	Reva                       *Reva           `yaml:"reva"`
+	MachineAuthAPIKey          string          `mask:"password" yaml:"machine_auth_api_key" env:"OC_MACHINE_AUTH_API_KEY" desc:"Machine auth API key used to validate internal requests necessary for the access to resources from other services." introductionVersion:"1.0.0"`
+	PreviousMachineAuthAPIKeys PreviousSecrets `mask:"struct" yaml:"previous_machine_auth_api_keys" env:"OC_PREVIOUS_MACHINE_AUTH_API_KEYS" desc:"Machine auth API keys that were replaced by the current one. They are still accepted until they expire. Written by 'opencloud init --rotate'. In the environment they are set as a JSON list of objects with a 'secret' and an 'expires' timestamp." introductionVersion:"%%NEXT%%"`
+	TransferSecret             string          `mask:"password" yaml:"transfer_secret,omitempty" env:"REVA_TRANSFER_SECRET" desc:"The secret used for signing the requests towards the data gateway for up- and downloads." introductionVersion:"1.0.0"`
+	SystemUserID               string          `yaml:"system_user_id" env:"OC_SYSTEM_USER_ID" desc:"ID of the OpenCloud storage-system system user. Admins need to set the ID for the storage-system system user in this config option which is then used to reference the user. Any reasonable long string is possible, preferably this would be an UUIDv4 format." introductionVersion:"1.0.0"`
+	SystemUserAPIKey           string          `mask:"password" yaml:"system_user_api_key" env:"SYSTEM_USER_API_KEY" desc:"API key for all system users." introductionVersion:"1.0.0"`
+	AdminUserID                string          `yaml:"admin_user_id" env:"OC_ADMIN_USER_ID" desc:"ID of a user, that should receive admin privileges. Consider that the UUID can be encoded in some LDAP deployment configurations like in .ldif files. These need to be decoded beforehand." introductionVersion:"1.0.0"`
+	MultiTenantEnabled         bool            `yaml:"multi_tenant_enabled" env:"OC_MULTI_TENANT_ENABLED" desc:"Set this to true to enable multi-tenant support." introductionVersion:"%%NEXT%%"`
 
 	// NOTE: you will not fing GRPCMaxReceivedMessageSize size being used in the code. The envvar is actually extracted in revas `pool` package: https://github.com/cs3org/reva/blob/edge/pkg/rgrpc/todo/pool/connection.go
 	// It is mentioned here again so it is documented
@@ -8,6 +8,7 @@ import (
 	_ "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2/options"
 	_ "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/messages/eventhistory/v0"
 	proto "google.golang.org/protobuf/proto"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
 	math "math"
 )
 
@@ -42,6 +43,8 @@ type EventHistoryService interface {
 	GetEvents(ctx context.Context, in *GetEventsRequest, opts ...client.CallOption) (*GetEventsResponse, error)
 	// returns all events for the specified userID
 	GetEventsForUser(ctx context.Context, in *GetEventsForUserRequest, opts ...client.CallOption) (*GetEventsResponse, error)
+	// returns the events matching all given filters, ordered by the time they were recorded
+	QueryEvents(ctx context.Context, in *QueryEventsRequest, opts ...client.CallOption) (*QueryEventsResponse, error)
 }
 
 type eventHistoryService struct {
@@ -76,6 +79,16 @@ func (c *eventHistoryService) GetEventsForUser(ctx context.Context, in *GetEvent
 	return out, nil
 }
 
+func (c *eventHistoryService) QueryEvents(ctx context.Context, in *QueryEventsRequest, opts ...client.CallOption) (*QueryEventsResponse, error) {
+	req := c.c.NewRequest(c.name, "EventHistoryService.QueryEvents", in)
+	out := new(QueryEventsResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for EventHistoryService service
 
 type EventHistoryServiceHandler interface {
@@ -83,12 +96,15 @@ type EventHistoryServiceHandler interface {
 	GetEvents(context.Context, *GetEventsRequest, *GetEventsResponse) error
 	// returns all events for the specified userID
 	GetEventsForUser(context.Context, *GetEventsForUserRequest, *GetEventsResponse) error
+	// returns the events matching all given filters, ordered by the time they were recorded
+	QueryEvents(context.Context, *QueryEventsRequest, *QueryEventsResponse) error
 }
 
 func RegisterEventHistoryServiceHandler(s server.Server, hdlr EventHistoryServiceHandler, opts ...server.HandlerOption) error {
 	type eventHistoryService interface {
 		GetEvents(ctx context.Context, in *GetEventsRequest, out *GetEventsResponse) error
 		GetEventsForUser(ctx context.Context, in *GetEventsForUserRequest, out *GetEventsResponse) error
+		QueryEvents(ctx context.Context, in *QueryEventsRequest, out *QueryEventsResponse) error
 	}
 	type EventHistoryService struct {
 		eventHistoryService
@@ -108,3 +124,7 @@ func (h *eventHistoryServiceHandler) GetEvents(ctx context.Context, in *GetEvent
 func (h *eventHistoryServiceHandler) GetEventsForUser(ctx context.Context, in *GetEventsForUserRequest, out *GetEventsResponse) error {
 	return h.EventHistoryServiceHandler.GetEventsForUser(ctx, in, out)
 }
+
+func (h *eventHistoryServiceHandler) QueryEvents(ctx context.Context, in *QueryEventsRequest, out *QueryEventsResponse) error {
+	return h.EventHistoryServiceHandler.QueryEvents(ctx, in, out)
+}
@@ -204,3 +204,86 @@ func (_c *EventHistoryService_GetEventsForUser_Call) RunAndReturn(run func(ctx c
 	_c.Call.Return(run)
 	return _c
 }
+
+// QueryEvents provides a mock function for the type EventHistoryService
+func (_mock *EventHistoryService) QueryEvents(ctx context.Context, in *v0.QueryEventsRequest, opts ...client.CallOption) (*v0.QueryEventsResponse, error) {
+	var tmpRet mock.Arguments
+	if len(opts) > 0 {
+		tmpRet = _mock.Called(ctx, in, opts)
+	} else {
+		tmpRet = _mock.Called(ctx, in)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryEvents")
+	}
+
+	var r0 *v0.QueryEventsResponse
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *v0.QueryEventsRequest, ...client.CallOption) (*v0.QueryEventsResponse, error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *v0.QueryEventsRequest, ...client.CallOption) *v0.QueryEventsResponse); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v0.QueryEventsResponse)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *v0.QueryEventsRequest, ...client.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// EventHistoryService_QueryEvents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueryEvents'
+type EventHistoryService_QueryEvents_Call struct {
+	*mock.Call
+}
+
+// QueryEvents is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *v0.QueryEventsRequest
+//   - opts ...client.CallOption
+func (_e *EventHistoryService_Expecter) QueryEvents(ctx interface{}, in interface{}, opts ...interface{}) *EventHistoryService_QueryEvents_Call {
+	return &EventHistoryService_QueryEvents_Call{Call: _e.mock.On("QueryEvents",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *EventHistoryService_QueryEvents_Call) Run(run func(ctx context.Context, in *v0.QueryEventsRequest, opts ...client.CallOption)) *EventHistoryService_QueryEvents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *v0.QueryEventsRequest
+		if args[1] != nil {
+			arg1 = args[1].(*v0.QueryEventsRequest)
+		}
+		var arg2 []client.CallOption
+		var variadicArgs []client.CallOption
+		if len(args) > 2 {
+			variadicArgs = args[2].([]client.CallOption)
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *EventHistoryService_QueryEvents_Call) Return(queryEventsResponse *v0.QueryEventsResponse, err error) *EventHistoryService_QueryEvents_Call {
+	_c.Call.Return(queryEventsResponse, err)
+	return _c
+}
+
+func (_c *EventHistoryService_QueryEvents_Call) RunAndReturn(run func(ctx context.Context, in *v0.QueryEventsRequest, opts ...client.CallOption) (*v0.QueryEventsResponse, error)) *EventHistoryService_QueryEvents_Call {
+	_c.Call.Return(run)
+	return _c
+}
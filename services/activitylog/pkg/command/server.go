@@ -26,6 +26,7 @@ import (
 	"github.com/opencloud-eu/opencloud/services/activitylog/pkg/metrics"
 	"github.com/opencloud-eu/opencloud/services/activitylog/pkg/server/debug"
 	"github.com/opencloud-eu/opencloud/services/activitylog/pkg/server/http"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
 )
 
 var _registeredEvents = []events.Unmarshaller{
@@ -44,6 +45,7 @@ var _registeredEvents = []events.Unmarshaller{
 	events.LinkRemoved{},
 	events.SpaceShared{},
 	events.SpaceUnshared{},
+	selfdeletion.AccountPurged{},
 }
 
 // Server is the entrypoint for the server command.
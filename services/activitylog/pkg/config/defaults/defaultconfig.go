@@ -54,6 +54,7 @@ func DefaultConfig() *config.Config {
 		},
 		WriteBufferDuration: 10 * time.Second,
 		MaxActivities:       6000,
+		FeedBurstWindow:     5 * time.Minute,
 	}
 }
 
@@ -77,7 +78,8 @@ func EnsureDefaults(cfg *config.Config) {
 
 	if cfg.TokenManager == nil && cfg.Commons != nil && cfg.Commons.TokenManager != nil {
 		cfg.TokenManager = &config.TokenManager{
-			JWTSecret: cfg.Commons.TokenManager.JWTSecret,
+			JWTSecret:          cfg.Commons.TokenManager.JWTSecret,
+			PreviousJWTSecrets: cfg.Commons.TokenManager.PreviousJWTSecrets,
 		}
 	} else if cfg.TokenManager == nil {
 		cfg.TokenManager = &config.TokenManager{}
@@ -55,6 +55,7 @@ func Server(opts ...Option) (http.Service, error) {
 		middleware.ExtractAccountUUID(
 			account.Logger(options.Logger),
 			account.JWTSecret(options.Config.TokenManager.JWTSecret),
+			account.PreviousJWTSecrets(options.Config.TokenManager.PreviousJWTSecrets),
 		),
 		middleware.Cors(
 			cors.Logger(options.Logger),
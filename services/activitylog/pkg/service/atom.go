@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/xml"
+	"net/http"
+	"regexp"
+	"time"
+
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+)
+
+// _templateVar matches the variables of an activity message like {user}
+var _templateVar = regexp.MustCompile(`\{\w+\}`)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  *atomPerson `xml:"author,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+// writeAtom writes the activities as an Atom feed.
+func (s *ActivitylogService) writeAtom(w http.ResponseWriter, r *http.Request, title string, activities []libregraph.Activity, nextLink string) {
+	feed := atomFeed{
+		ID:      s.feedURL(r.URL.Path),
+		Title:   title,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Href: s.feedURL(r.URL.RequestURI())},
+		},
+		Entries: make([]atomEntry, 0, len(activities)),
+	}
+	if len(activities) > 0 {
+		feed.Updated = activities[0].Times.RecordedTime.UTC().Format(time.RFC3339)
+	}
+	if nextLink != "" {
+		feed.Links = append(feed.Links, atomLink{Rel: "next", Href: nextLink})
+	}
+
+	for _, a := range activities {
+		entry := atomEntry{
+			ID:      "urn:uuid:" + a.Id,
+			Title:   renderMessage(a.Template.Message, a.Template.Variables),
+			Updated: a.Times.RecordedTime.UTC().Format(time.RFC3339),
+		}
+		if u, ok := a.Template.Variables["user"].(Actor); ok {
+			entry.Author = &atomPerson{Name: u.DisplayName}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	b, err := xml.Marshal(feed)
+	if err != nil {
+		s.log.Error().Err(err).Msg("error marshalling feed")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	if _, err := w.Write(append([]byte(xml.Header), b...)); err != nil {
+		s.log.Error().Err(err).Msg("error writing response")
+	}
+}
+
+// renderMessage replaces the variables of an activity message with their names.
+func renderMessage(message string, vars map[string]interface{}) string {
+	return _templateVar.ReplaceAllStringFunc(message, func(v string) string {
+		switch v := vars[v[1:len(v)-1]].(type) {
+		case Resource:
+			return v.Name
+		case Actor:
+			return v.DisplayName
+		case Sharee:
+			return v.DisplayName
+		}
+		return v
+	})
+}
@@ -0,0 +1,528 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/opencloud-eu/opencloud/pkg/l10n"
+	ehmsg "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/messages/eventhistory/v0"
+	ehsvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/eventhistory/v0"
+)
+
+const (
+	_feedDefaultTop = 50
+	_feedMaxTop     = 200
+	// the number of events read per requested activity, so bursts can be grouped
+	_feedScanFactor = 5
+)
+
+// _feedEvents are the events shown in the feeds. Downloads are only shown in the space feed.
+var _feedEvents = []interface{}{
+	events.UploadReady{},
+	events.FileTouched{},
+	events.ContainerCreated{},
+	events.ItemTrashed{},
+	events.ItemMoved{},
+	events.ShareCreated{},
+	events.ShareUpdated{},
+	events.ShareRemoved{},
+	events.LinkCreated{},
+	events.LinkUpdated{},
+	events.LinkRemoved{},
+	events.SpaceShared{},
+	events.SpaceUnshared{},
+}
+
+// feedEventTypes returns the types of the events shown in a feed.
+func feedEventTypes(downloads bool) []string {
+	types := make([]string, 0, len(_feedEvents)+1)
+	for _, e := range _feedEvents {
+		types = append(types, reflect.TypeOf(e).String())
+	}
+	if downloads {
+		types = append(types, reflect.TypeOf(events.FileDownloaded{}).String())
+	}
+	return types
+}
+
+// HandleGetSpaceActivities handles the request to get the activities of a whole space. Like the
+// activities of an item, it requires the permission to list the grants of the space, so it is only
+// available to the managers of the space. Unlike the personal feed it includes the downloads.
+func (s *ActivitylogService) HandleGetSpaceActivities(w http.ResponseWriter, r *http.Request) {
+	ctx := metadata.AppendToOutgoingContext(r.Context(), revactx.TokenHeader, r.Header.Get(revactx.TokenHeader))
+
+	if _, ok := revactx.ContextGetUser(ctx); !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	rid, err := storagespace.ParseID(chi.URLParam(r, "spaceID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rid.OpaqueId = rid.GetSpaceId()
+	spaceID := storagespace.FormatStorageID(rid.GetStorageId(), rid.GetSpaceId())
+
+	gwc, err := s.gws.Next()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	info, err := utils.GetResourceByID(ctx, &rid, gwc)
+	if err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// you need ListGrants to see activities
+	if !info.GetPermissionSet().GetListGrants() {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	title := spaceID
+	if space, err := utils.GetSpace(ctx, spaceID, gwc); err == nil {
+		title = space.GetName()
+	}
+
+	s.serveFeed(ctx, w, r, title, []feedSpace{{id: spaceID, listGrants: true}}, feedEventTypes(true))
+}
+
+// HandleGetMyActivities handles the request to get the activities of all spaces the user is a
+// member of. The sharing activities are only shown for the spaces the user can list the grants of.
+func (s *ActivitylogService) HandleGetMyActivities(w http.ResponseWriter, r *http.Request) {
+	ctx := metadata.AppendToOutgoingContext(r.Context(), revactx.TokenHeader, r.Header.Get(revactx.TokenHeader))
+
+	activeUser, ok := revactx.ContextGetUser(ctx)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	gwc, err := s.gws.Next()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res, err := gwc.ListStorageSpaces(ctx, &provider.ListStorageSpacesRequest{})
+	switch {
+	case err != nil:
+		s.log.Error().Err(err).Msg("error listing spaces")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		s.log.Error().Str("message", res.GetStatus().GetMessage()).Msg("error listing spaces")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	spaces := make([]feedSpace, 0, len(res.GetStorageSpaces()))
+	for _, space := range res.GetStorageSpaces() {
+		switch space.GetSpaceType() {
+		case "personal", "project":
+			spaces = append(spaces, feedSpace{
+				id:         space.GetId().GetOpaqueId(),
+				listGrants: space.GetRootInfo().GetPermissionSet().GetListGrants(),
+			})
+		}
+	}
+
+	s.serveFeed(ctx, w, r, activeUser.GetDisplayName(), spaces, feedEventTypes(false))
+}
+
+// feedSpace is a space shown in a feed.
+type feedSpace struct {
+	id string
+	// listGrants is set when the user can list the grants of the space, which is needed to see
+	// its sharing activities
+	listGrants bool
+}
+
+// serveFeed writes a page of the activities of the given spaces, newest first. Bursts of similar
+// activities are grouped into one.
+func (s *ActivitylogService) serveFeed(ctx context.Context, w http.ResponseWriter, r *http.Request, title string, spaces []feedSpace, types []string) {
+	activeUser, _ := revactx.ContextGetUser(ctx)
+
+	params, err := parseFeedParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]string, 0, len(spaces))
+	listGrants := make(map[string]bool, len(spaces))
+	for _, space := range spaces {
+		ids = append(ids, space.id)
+		listGrants[spaceOf(space.id)] = space.listGrants
+	}
+
+	evs, more, err := s.feedEvents(ctx, ids, types, params.since, params.cursor, min(params.top*_feedScanFactor, 1000))
+	if err != nil {
+		s.log.Error().Err(err).Msg("error getting events")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	fevs := make([]feedEvent, 0, len(evs))
+	for _, e := range evs {
+		ev := s.unwrapEvent(e)
+		if ev == nil {
+			continue
+		}
+		if space, ok := sharingSpace(ev); ok && !listGrants[space] {
+			continue
+		}
+		fevs = append(fevs, feedEvent{event: e, ev: ev})
+	}
+
+	bursts, seen, cut := pageBursts(groupBursts(fevs, s.cfg.FeedBurstWindow), evs, params.top)
+	more = more || cut
+
+	loc := l10n.MustGetUserLocale(r.Context(), activeUser.GetId().GetOpaqueId(), r.Header.Get(l10n.HeaderAcceptLanguage), s.valService)
+	t := l10n.NewTranslatorFromCommonConfig(s.cfg.DefaultLanguage, _domain, s.cfg.TranslationPath, _localeFS, _localeSubPath)
+
+	activities := make([]libregraph.Activity, 0, len(bursts))
+	for _, b := range bursts {
+		message, ts, vars, err := s.activity(ctx, b.ev, &t, loc)
+		if err != nil {
+			s.log.Error().Err(err).Msg("error getting response data")
+			continue
+		}
+		if message == "" {
+			continue
+		}
+		if len(b.events) > 1 {
+			message = b.message
+			vars["count"] = Resource{Name: strconv.Itoa(len(b.events))}
+		}
+
+		activities = append(activities, NewActivity(t.Translate(message, loc), ts, b.events[0].GetId(), vars))
+	}
+
+	var nextLink string
+	if more && len(seen) > 0 {
+		nextLink = s.feedLink(r.URL, nextFeedCursor(seen, params.cursor).token())
+	}
+
+	if params.atom {
+		s.writeAtom(w, r, title, activities, nextLink)
+		return
+	}
+
+	b, err := json.Marshal(GetFeedResponse{Activities: activities, NextLink: nextLink})
+	if err != nil {
+		s.log.Error().Err(err).Msg("error marshalling activities")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(b); err != nil {
+		s.log.Error().Err(err).Msg("error writing response")
+	}
+}
+
+// feedEvents returns up to limit events of the given spaces recorded before the cursor, newest
+// first, and whether there are more.
+func (s *ActivitylogService) feedEvents(ctx context.Context, spaces []string, types []string, since time.Time, cursor *feedCursor, limit int) ([]*ehmsg.Event, bool, error) {
+	req := &ehsvc.QueryEventsRequest{
+		Types:      types,
+		SpaceIDs:   spaces,
+		PageSize:   int32(limit),
+		Descending: true,
+	}
+	if !since.IsZero() {
+		req.Since = timestamppb.New(since)
+	}
+	if cursor != nil {
+		// the events recorded at the same time as the cursor are skipped by their id
+		req.Until = timestamppb.New(time.Unix(0, cursor.ts+1))
+		req.PageSize += int32(len(cursor.ids))
+	}
+
+	res, err := s.evHistory.QueryEvents(ctx, req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	more := res.GetNextPageToken() != ""
+	evs := make([]*ehmsg.Event, 0, len(res.GetEvents()))
+	for _, e := range res.GetEvents() {
+		if !cursor.skips(e) {
+			evs = append(evs, e)
+		}
+	}
+	if len(evs) > limit {
+		evs = evs[:limit]
+		more = true
+	}
+	return evs, more, nil
+}
+
+// sharingSpace returns the space of a sharing event, seeing it requires the permission to list
+// the grants of the space.
+func sharingSpace(ev interface{}) (string, bool) {
+	var rid *provider.ResourceId
+	switch ev := ev.(type) {
+	case events.ShareCreated:
+		rid = ev.ItemID
+	case events.ShareUpdated:
+		rid = ev.ItemID
+	case events.ShareRemoved:
+		rid = ev.ItemID
+	case events.LinkCreated:
+		rid = ev.ItemID
+	case events.LinkUpdated:
+		rid = ev.ItemID
+	case events.LinkRemoved:
+		rid = ev.ItemID
+	case events.SpaceShared:
+		return spaceOf(ev.ID.GetOpaqueId()), true
+	case events.SpaceUnshared:
+		return spaceOf(ev.ID.GetOpaqueId()), true
+	default:
+		return "", false
+	}
+	return storagespace.FormatStorageID(rid.GetStorageId(), rid.GetSpaceId()), true
+}
+
+// spaceOf returns the "storageid$spaceid" form of a space id.
+func spaceOf(id string) string {
+	rid, err := storagespace.ParseID(id)
+	if err != nil {
+		return id
+	}
+	return storagespace.FormatStorageID(rid.GetStorageId(), rid.GetSpaceId())
+}
+
+// feedLink returns the link to the page of the feed starting at the given token.
+func (s *ActivitylogService) feedLink(u *url.URL, token string) string {
+	q := u.Query()
+	q.Set("$skiptoken", token)
+	return s.feedURL(u.Path) + "?" + q.Encode()
+}
+
+// feedURL returns the public url of a feed.
+func (s *ActivitylogService) feedURL(path string) string {
+	if s.cfg.Commons == nil {
+		return path
+	}
+	return strings.TrimSuffix(s.cfg.Commons.OpenCloudURL, "/") + path
+}
+
+// feedParams are the parameters of a feed request.
+type feedParams struct {
+	since  time.Time
+	top    int
+	cursor *feedCursor
+	atom   bool
+}
+
+func parseFeedParams(q url.Values) (feedParams, error) {
+	p := feedParams{top: _feedDefaultTop}
+
+	if v := q.Get("since"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			p.since = time.Now().Add(-d)
+		} else if p.since, err = time.Parse(time.RFC3339, v); err != nil {
+			return p, fmt.Errorf("invalid since '%s', expected RFC3339 or a duration", v)
+		}
+	}
+
+	if v := q.Get("$top"); v != "" {
+		top, err := strconv.Atoi(v)
+		if err != nil || top <= 0 {
+			return p, fmt.Errorf("invalid $top '%s'", v)
+		}
+		p.top = min(top, _feedMaxTop)
+	}
+
+	if v := q.Get("$skiptoken"); v != "" {
+		c, err := parseFeedCursor(v)
+		if err != nil {
+			return p, err
+		}
+		p.cursor = c
+	}
+
+	switch q.Get("format") {
+	case "", "json":
+	case "atom":
+		p.atom = true
+	default:
+		return p, fmt.Errorf("unsupported format '%s'", q.Get("format"))
+	}
+	return p, nil
+}
+
+// feedCursor is the position of the next page of a feed. It is the time the last event of the
+// previous page was recorded and the ids of the events of the previous page recorded at that time.
+type feedCursor struct {
+	ts  int64
+	ids []string
+}
+
+// pageBursts cuts the bursts to the page size. It returns the events the next page starts after:
+// the events of the bursts when they were cut, otherwise all scanned events, so events filtered out
+// are not scanned again and a page without activities still continues the feed.
+func pageBursts(bursts []*burst, scanned []*ehmsg.Event, top int) ([]*burst, []*ehmsg.Event, bool) {
+	if len(bursts) <= top {
+		return bursts, scanned, false
+	}
+	bursts = bursts[:top]
+	var seen []*ehmsg.Event
+	for _, b := range bursts {
+		seen = append(seen, b.events...)
+	}
+	return bursts, seen, true
+}
+
+// nextFeedCursor returns the cursor positioned after the given events, which are expected newest
+// first.
+func nextFeedCursor(evs []*ehmsg.Event, prev *feedCursor) *feedCursor {
+	c := &feedCursor{ts: evs[len(evs)-1].GetTimestamp().AsTime().UnixNano()}
+	if prev != nil && prev.ts == c.ts {
+		c.ids = append(c.ids, prev.ids...)
+	}
+	for _, e := range evs {
+		if e.GetTimestamp().AsTime().UnixNano() == c.ts {
+			c.ids = append(c.ids, e.GetId())
+		}
+	}
+	return c
+}
+
+func (c *feedCursor) token() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.ts, 10) + "/" + strings.Join(c.ids, ",")))
+}
+
+// skips checks whether the event was already part of a previous page.
+func (c *feedCursor) skips(e *ehmsg.Event) bool {
+	return c != nil && e.GetTimestamp().AsTime().UnixNano() == c.ts && slices.Contains(c.ids, e.GetId())
+}
+
+func parseFeedCursor(token string) (*feedCursor, error) {
+	errInvalid := errors.New("invalid $skiptoken")
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalid
+	}
+	ts, ids, ok := strings.Cut(string(b), "/")
+	if !ok {
+		return nil, errInvalid
+	}
+	c := &feedCursor{}
+	if c.ts, err = strconv.ParseInt(ts, 10, 64); err != nil {
+		return nil, errInvalid
+	}
+	if ids != "" {
+		c.ids = strings.Split(ids, ",")
+	}
+	return c, nil
+}
+
+// feedEvent is an event of a feed and its unwrapped content.
+type feedEvent struct {
+	event *ehmsg.Event
+	ev    interface{}
+}
+
+// burst is a group of similar events, newest first. The activity of the newest event represents
+// the group.
+type burst struct {
+	ev      interface{}
+	events  []*ehmsg.Event
+	key     string
+	message string
+}
+
+// groupBursts groups consecutive events of the same kind by the same user in the same folder that
+// were recorded at most window apart. The events are expected newest first.
+func groupBursts(evs []feedEvent, window time.Duration) []*burst {
+	var bursts []*burst
+	for _, e := range evs {
+		key, message := burstKey(e.ev)
+		if n := len(bursts); n > 0 && key != "" && window > 0 {
+			b := bursts[n-1]
+			prev := b.events[len(b.events)-1]
+			if b.key == key && prev.GetTimestamp().AsTime().Sub(e.event.GetTimestamp().AsTime()) <= window {
+				b.events = append(b.events, e.event)
+				continue
+			}
+		}
+		bursts = append(bursts, &burst{ev: e.ev, events: []*ehmsg.Event{e.event}, key: key, message: message})
+	}
+	return bursts
+}
+
+// burstKey returns the key shared by similar events and the message of a group of them. Events
+// with an empty key are never grouped.
+func burstKey(ev interface{}) (string, string) {
+	switch ev := ev.(type) {
+	case events.UploadReady:
+		if ev.IsVersion {
+			return strings.Join([]string{"updated", userKey(ev.ExecutingUser.GetId(), ev.ImpersonatingUser), folderKey(ev.FileRef, ev.ParentID)}, "|"), MessageResourcesUpdated
+		}
+		return strings.Join([]string{"created", userKey(ev.ExecutingUser.GetId(), ev.ImpersonatingUser), folderKey(ev.FileRef, ev.ParentID)}, "|"), MessageResourcesCreated
+	case events.FileTouched:
+		return strings.Join([]string{"created", userKey(ev.Executant, ev.ImpersonatingUser), folderKey(ev.Ref, ev.ParentID)}, "|"), MessageResourcesCreated
+	case events.ContainerCreated:
+		return strings.Join([]string{"created", userKey(ev.Executant, ev.ImpersonatingUser), folderKey(ev.Ref, ev.ParentID)}, "|"), MessageResourcesCreated
+	case events.ItemTrashed:
+		return strings.Join([]string{"trashed", userKey(ev.Executant, ev.ImpersonatingUser), folderKey(ev.Ref, nil)}, "|"), MessageResourcesTrashed
+	case events.ItemMoved:
+		if isRename(ev.OldReference, ev.Ref) {
+			return "", ""
+		}
+		return strings.Join([]string{"moved", userKey(ev.Executant, ev.ImpersonatingUser), folderKey(ev.Ref, nil)}, "|"), MessageResourcesMoved
+	case events.FileDownloaded:
+		resource := storagespace.FormatResourceID(ev.Ref.GetResourceId()) + ev.Ref.GetPath()
+		if isPublicDownload(ev) {
+			return strings.Join([]string{"downloaded", ev.ImpersonatingUser.GetId().GetOpaqueId(), resource}, "|"), MessageResourceDownloads
+		}
+		return strings.Join([]string{"downloaded", userKey(ev.Executant, ev.ImpersonatingUser), resource}, "|"), MessageResourceUserDownloads
+	}
+	return "", ""
+}
+
+// userKey returns the id of the user shown for an event.
+func userKey(uid *user.UserId, impersonator *user.User) string {
+	if impersonator != nil {
+		return impersonator.GetId().GetOpaqueId()
+	}
+	return uid.GetOpaqueId()
+}
+
+// folderKey returns the id of the folder of the resource of an event.
+func folderKey(ref *provider.Reference, parentID *provider.ResourceId) string {
+	if parentID != nil {
+		return storagespace.FormatResourceID(parentID)
+	}
+	return storagespace.FormatResourceID(ref.GetResourceId()) + filepath.Dir(ref.GetPath())
+}
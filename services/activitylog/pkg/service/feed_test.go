@@ -0,0 +1,165 @@
+package service
+
+import (
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ehmsg "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/messages/eventhistory/v0"
+)
+
+var _ = Describe("Feed", func() {
+	var (
+		now      = time.Now()
+		einstein = &user.UserId{OpaqueId: "einstein"}
+		marie    = &user.UserId{OpaqueId: "marie"}
+		folder   = resourceID("storage$space!folder")
+		other    = resourceID("storage$space!other")
+	)
+
+	feedEvents := func(evs ...interface{}) []feedEvent {
+		fevs := make([]feedEvent, 0, len(evs))
+		for i, ev := range evs {
+			fevs = append(fevs, feedEvent{
+				event: &ehmsg.Event{Id: string(rune('a' + i)), Timestamp: timestamppb.New(now.Add(-time.Duration(i) * time.Minute))},
+				ev:    ev,
+			})
+		}
+		return fevs
+	}
+	scanned := func(fevs []feedEvent) []*ehmsg.Event {
+		evs := make([]*ehmsg.Event, 0, len(fevs))
+		for _, e := range fevs {
+			evs = append(evs, e.event)
+		}
+		return evs
+	}
+	counts := func(bursts []*burst) []int {
+		c := make([]int, 0, len(bursts))
+		for _, b := range bursts {
+			c = append(c, len(b.events))
+		}
+		return c
+	}
+
+	Describe("groupBursts", func() {
+		It("groups similar events of the same user in the same folder", func() {
+			bursts := groupBursts(feedEvents(
+				events.UploadReady{ExecutingUser: &user.User{Id: einstein}, ParentID: folder},
+				events.UploadReady{ExecutingUser: &user.User{Id: einstein}, ParentID: folder},
+				events.ContainerCreated{Executant: einstein, ParentID: folder},
+				events.UploadReady{ExecutingUser: &user.User{Id: marie}, ParentID: folder},
+				events.UploadReady{ExecutingUser: &user.User{Id: marie}, ParentID: other},
+				events.ItemTrashed{Executant: marie, Ref: &provider.Reference{ResourceId: other, Path: "./a"}},
+				events.ItemTrashed{Executant: marie, Ref: &provider.Reference{ResourceId: other, Path: "./b"}},
+				events.ShareCreated{Executant: marie},
+				events.ShareCreated{Executant: marie},
+			), 5*time.Minute)
+
+			Expect(counts(bursts)).To(Equal([]int{3, 1, 1, 2, 1, 1}))
+			Expect(bursts[0].message).To(Equal(MessageResourcesCreated))
+			Expect(bursts[0].events[0].GetId()).To(Equal("a"))
+			Expect(bursts[3].message).To(Equal(MessageResourcesTrashed))
+		})
+
+		It("only groups events within the window", func() {
+			evs := feedEvents(
+				events.ContainerCreated{Executant: einstein, ParentID: folder},
+				events.ContainerCreated{Executant: einstein, ParentID: folder},
+				events.ContainerCreated{Executant: einstein, ParentID: folder},
+			)
+			evs[2].event.Timestamp = timestamppb.New(now.Add(-time.Hour))
+
+			Expect(counts(groupBursts(evs, 5*time.Minute))).To(Equal([]int{2, 1}))
+			Expect(counts(groupBursts(evs, 0))).To(Equal([]int{1, 1, 1}))
+		})
+	})
+
+	Describe("feedCursor", func() {
+		It("continues after the events of the previous page", func() {
+			evs := feedEvents(
+				events.ContainerCreated{Executant: einstein, ParentID: folder},
+				events.ShareCreated{Executant: einstein},
+				events.ShareCreated{Executant: einstein},
+			)
+			evs[2].event.Timestamp = evs[1].event.Timestamp
+			bursts, seen, cut := pageBursts(groupBursts(evs, time.Minute), scanned(evs), 2)
+			Expect(cut).To(BeTrue())
+			Expect(bursts).To(HaveLen(2))
+
+			c := nextFeedCursor(seen, nil)
+			Expect(c.ts).To(Equal(evs[1].event.GetTimestamp().AsTime().UnixNano()))
+			Expect(c.ids).To(Equal([]string{"b"}))
+			Expect(c.skips(evs[1].event)).To(BeTrue())
+			Expect(c.skips(evs[2].event)).To(BeFalse())
+
+			parsed, err := parseFeedCursor(c.token())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed).To(Equal(c))
+
+			Expect(nextFeedCursor(scanned(evs[2:]), c).ids).To(Equal([]string{"b", "c"}))
+
+			_, err = parseFeedCursor("invalid")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("continues after the scanned events when all of them were filtered out", func() {
+			evs := feedEvents(
+				events.ShareCreated{Executant: einstein},
+				events.ShareCreated{Executant: marie},
+			)
+
+			bursts, seen, cut := pageBursts(nil, scanned(evs), 10)
+			Expect(bursts).To(BeEmpty())
+			Expect(cut).To(BeFalse())
+			c := nextFeedCursor(seen, nil)
+			Expect(c.skips(evs[1].event)).To(BeTrue())
+			Expect(c.ts).To(Equal(evs[1].event.GetTimestamp().AsTime().UnixNano()))
+		})
+	})
+
+	Describe("parseFeedParams", func() {
+		It("parses the parameters", func() {
+			p, err := parseFeedParams(map[string][]string{"since": {"24h"}, "$top": {"1000"}, "format": {"atom"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.since).To(BeTemporally("~", time.Now().Add(-24*time.Hour), time.Minute))
+			Expect(p.top).To(Equal(_feedMaxTop))
+			Expect(p.atom).To(BeTrue())
+
+			_, err = parseFeedParams(map[string][]string{"since": {"yesterday"}})
+			Expect(err).To(HaveOccurred())
+			_, err = parseFeedParams(map[string][]string{"format": {"rss"}})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("sharingSpace", func() {
+		It("returns the space of the sharing events", func() {
+			space, ok := sharingSpace(events.ShareCreated{ItemID: folder})
+			Expect(ok).To(BeTrue())
+			Expect(space).To(Equal("storageid$spaceid"))
+
+			space, ok = sharingSpace(events.SpaceShared{ID: &provider.StorageSpaceId{OpaqueId: "storage$space!space"}})
+			Expect(ok).To(BeTrue())
+			Expect(space).To(Equal("storage$space"))
+
+			_, ok = sharingSpace(events.ContainerCreated{Ref: &provider.Reference{ResourceId: folder}})
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("renderMessage", func() {
+		It("replaces the variables with their names", func() {
+			Expect(renderMessage(MessageResourcesCreated, map[string]interface{}{
+				"user":   Actor{ID: "einstein", DisplayName: "Albert Einstein"},
+				"count":  Resource{Name: "37"},
+				"folder": Resource{ID: "folder", Name: "Photos"},
+			})).To(Equal("Albert Einstein added 37 items to Photos"))
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
 	"errors"
@@ -106,6 +107,9 @@ func (s *ActivitylogService) HandleGetItemActivities(w http.ResponseWriter, r *h
 	evs := evRes.GetEvents()
 	sort(evs)
 
+	loc := l10n.MustGetUserLocale(r.Context(), activeUser.GetId().GetOpaqueId(), r.Header.Get(l10n.HeaderAcceptLanguage), s.valService)
+	t := l10n.NewTranslatorFromCommonConfig(s.cfg.DefaultLanguage, _domain, s.cfg.TranslationPath, _localeFS, _localeSubPath)
+
 	resp := GetActivitiesResponse{Activities: make([]libregraph.Activity, 0, len(evRes.GetEvents()))}
 	for _, e := range evs {
 		delete(toDelete, e.GetId())
@@ -118,111 +122,20 @@ func (s *ActivitylogService) HandleGetItemActivities(w http.ResponseWriter, r *h
 			continue
 		}
 
-		var (
-			message string
-			ts      time.Time
-			vars    map[string]interface{}
-		)
-
-		loc := l10n.MustGetUserLocale(r.Context(), activeUser.GetId().GetOpaqueId(), r.Header.Get(l10n.HeaderAcceptLanguage), s.valService)
-		t := l10n.NewTranslatorFromCommonConfig(s.cfg.DefaultLanguage, _domain, s.cfg.TranslationPath, _localeFS, _localeSubPath)
-
-		switch ev := s.unwrapEvent(e).(type) {
-		case nil:
+		ev := s.unwrapEvent(e)
+		if ev == nil {
 			// error already logged in unwrapEvent
 			continue
-		case events.UploadReady:
-			message = MessageResourceCreated
-			if ev.IsVersion {
-				message = MessageResourceUpdated
-			}
-			ts = utils.TSToTime(ev.Timestamp)
-			vars, err = s.GetVars(ctx, WithResource(ev.FileRef, false, ""), WithUser(nil, ev.ExecutingUser, ev.ImpersonatingUser))
-		case events.FileTouched:
-			message = MessageResourceCreated
-			ts = utils.TSToTime(ev.Timestamp)
-			vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, ""), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
-		case events.FileDownloaded:
-			message = MessageResourceDownloaded
-			ts = utils.TSToTime(ev.Timestamp)
-			vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, ""), WithUser(ev.Executant, nil, ev.ImpersonatingUser), WithVar("token", "", ev.ImpersonatingUser.GetId().GetOpaqueId()))
-		case events.ContainerCreated:
-			message = MessageResourceCreated
-			ts = utils.TSToTime(ev.Timestamp)
-			vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, ""), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
-		case events.ItemTrashed:
-			message = MessageResourceTrashed
-			ts = utils.TSToTime(ev.Timestamp)
-			vars, err = s.GetVars(ctx, WithTrashedResource(ev.Ref, ev.ID), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
-		case events.ItemMoved:
-			switch isRename(ev.OldReference, ev.Ref) {
-			case true:
-				message = MessageResourceRenamed
-				vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, ""), WithOldResource(ev.OldReference), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
-			case false:
-				message = MessageResourceMoved
-				vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, ""), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
-			}
-			ts = utils.TSToTime(ev.Timestamp)
-		case events.ShareCreated:
-			message = MessageShareCreated
-			ts = utils.TSToTime(ev.CTime)
-			vars, err = s.GetVars(ctx,
-				WithResource(toRef(ev.ItemID), false, ev.ResourceName),
-				WithUser(ev.Executant, nil, nil),
-				WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
-		case events.ShareUpdated:
-			if ev.Sharer != nil && ev.ItemID != nil && ev.Sharer.GetOpaqueId() == ev.ItemID.GetSpaceId() {
-				continue
-			}
-			message = MessageShareUpdated
-			ts = utils.TSToTime(ev.MTime)
-			vars, err = s.GetVars(ctx,
-				WithResource(toRef(ev.ItemID), false, ev.ResourceName),
-				WithUser(ev.Executant, nil, nil),
-				WithTranslation(&t, loc, "field", ev.UpdateMask))
-		case events.ShareRemoved:
-			message = MessageShareDeleted
-			ts = ev.Timestamp
-			vars, err = s.GetVars(ctx,
-				WithResource(toRef(ev.ItemID), false, ev.ResourceName),
-				WithUser(ev.Executant, nil, nil),
-				WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
-		case events.LinkCreated:
-			message = MessageLinkCreated
-			ts = utils.TSToTime(ev.CTime)
-			vars, err = s.GetVars(ctx,
-				WithResource(toRef(ev.ItemID), false, ev.ResourceName),
-				WithUser(ev.Executant, nil, nil))
-		case events.LinkUpdated:
-			if ev.Sharer != nil && ev.ItemID != nil && ev.Sharer.GetOpaqueId() == ev.ItemID.GetSpaceId() {
-				continue
-			}
-			message = MessageLinkUpdated
-			ts = utils.TSToTime(ev.MTime)
-			vars, err = s.GetVars(ctx,
-				WithVar("resource", storagespace.FormatResourceID(ev.ItemID), ev.ResourceName),
-				WithUser(ev.Executant, nil, nil),
-				WithTranslation(&t, loc, "field", []string{ev.FieldUpdated}),
-				WithVar("token", ev.ItemID.GetOpaqueId(), ev.DisplayName))
-		case events.LinkRemoved:
-			message = MessageLinkDeleted
-			ts = utils.TSToTime(ev.Timestamp)
-			vars, err = s.GetVars(ctx, WithResource(toRef(ev.ItemID), false, ""), WithUser(ev.Executant, nil, nil))
-		case events.SpaceShared:
-			message = MessageSpaceShared
-			ts = ev.Timestamp
-			vars, err = s.GetVars(ctx, WithSpace(ev.ID), WithUser(ev.Executant, nil, nil), WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
-		case events.SpaceUnshared:
-			message = MessageSpaceUnshared
-			ts = ev.Timestamp
-			vars, err = s.GetVars(ctx, WithSpace(ev.ID), WithUser(ev.Executant, nil, nil), WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
 		}
 
+		message, ts, vars, err := s.activity(ctx, ev, &t, loc)
 		if err != nil {
 			s.log.Error().Err(err).Msg("error getting response data")
 			continue
 		}
+		if message == "" {
+			continue
+		}
 
 		resp.Activities = append(resp.Activities, NewActivity(t.Translate(message, loc), ts, e.GetId(), vars))
 	}
@@ -252,6 +165,107 @@ func (s *ActivitylogService) HandleGetItemActivities(w http.ResponseWriter, r *h
 	w.WriteHeader(http.StatusOK)
 }
 
+// activity returns the message, the time and the variables of the activity of an event. An empty
+// message means that the event is not shown as an activity.
+func (s *ActivitylogService) activity(ctx context.Context, ev interface{}, t *l10n.Translator, loc string) (message string, ts time.Time, vars map[string]interface{}, err error) {
+	switch ev := ev.(type) {
+	case events.UploadReady:
+		message = MessageResourceCreated
+		if ev.IsVersion {
+			message = MessageResourceUpdated
+		}
+		ts = utils.TSToTime(ev.Timestamp)
+		vars, err = s.GetVars(ctx, WithResource(ev.FileRef, false, ""), WithUser(nil, ev.ExecutingUser, ev.ImpersonatingUser))
+	case events.FileTouched:
+		message = MessageResourceCreated
+		ts = utils.TSToTime(ev.Timestamp)
+		vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, ""), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
+	case events.FileDownloaded:
+		switch isPublicDownload(ev) {
+		case true:
+			message = MessageResourceDownloaded
+			vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, ""), WithUser(ev.Executant, nil, ev.ImpersonatingUser), WithVar("token", "", ev.ImpersonatingUser.GetId().GetOpaqueId()))
+		case false:
+			message = MessageResourceDownloadedByUser
+			vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, ""), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
+		}
+		ts = utils.TSToTime(ev.Timestamp)
+	case events.ContainerCreated:
+		message = MessageResourceCreated
+		ts = utils.TSToTime(ev.Timestamp)
+		vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, ""), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
+	case events.ItemTrashed:
+		message = MessageResourceTrashed
+		ts = utils.TSToTime(ev.Timestamp)
+		vars, err = s.GetVars(ctx, WithTrashedResource(ev.Ref, ev.ID), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
+	case events.ItemMoved:
+		switch isRename(ev.OldReference, ev.Ref) {
+		case true:
+			message = MessageResourceRenamed
+			vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, ""), WithOldResource(ev.OldReference), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
+		case false:
+			message = MessageResourceMoved
+			vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, ""), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
+		}
+		ts = utils.TSToTime(ev.Timestamp)
+	case events.ShareCreated:
+		message = MessageShareCreated
+		ts = utils.TSToTime(ev.CTime)
+		vars, err = s.GetVars(ctx,
+			WithResource(toRef(ev.ItemID), false, ev.ResourceName),
+			WithUser(ev.Executant, nil, nil),
+			WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
+	case events.ShareUpdated:
+		if ev.Sharer != nil && ev.ItemID != nil && ev.Sharer.GetOpaqueId() == ev.ItemID.GetSpaceId() {
+			return "", ts, nil, nil
+		}
+		message = MessageShareUpdated
+		ts = utils.TSToTime(ev.MTime)
+		vars, err = s.GetVars(ctx,
+			WithResource(toRef(ev.ItemID), false, ev.ResourceName),
+			WithUser(ev.Executant, nil, nil),
+			WithTranslation(t, loc, "field", ev.UpdateMask))
+	case events.ShareRemoved:
+		message = MessageShareDeleted
+		ts = ev.Timestamp
+		vars, err = s.GetVars(ctx,
+			WithResource(toRef(ev.ItemID), false, ev.ResourceName),
+			WithUser(ev.Executant, nil, nil),
+			WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
+	case events.LinkCreated:
+		message = MessageLinkCreated
+		ts = utils.TSToTime(ev.CTime)
+		vars, err = s.GetVars(ctx,
+			WithResource(toRef(ev.ItemID), false, ev.ResourceName),
+			WithUser(ev.Executant, nil, nil))
+	case events.LinkUpdated:
+		if ev.Sharer != nil && ev.ItemID != nil && ev.Sharer.GetOpaqueId() == ev.ItemID.GetSpaceId() {
+			return "", ts, nil, nil
+		}
+		message = MessageLinkUpdated
+		ts = utils.TSToTime(ev.MTime)
+		vars, err = s.GetVars(ctx,
+			WithVar("resource", storagespace.FormatResourceID(ev.ItemID), ev.ResourceName),
+			WithUser(ev.Executant, nil, nil),
+			WithTranslation(t, loc, "field", []string{ev.FieldUpdated}),
+			WithVar("token", ev.ItemID.GetOpaqueId(), ev.DisplayName))
+	case events.LinkRemoved:
+		message = MessageLinkDeleted
+		ts = utils.TSToTime(ev.Timestamp)
+		vars, err = s.GetVars(ctx, WithResource(toRef(ev.ItemID), false, ""), WithUser(ev.Executant, nil, nil))
+	case events.SpaceShared:
+		message = MessageSpaceShared
+		ts = ev.Timestamp
+		vars, err = s.GetVars(ctx, WithSpace(ev.ID), WithUser(ev.Executant, nil, nil), WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
+	case events.SpaceUnshared:
+		message = MessageSpaceUnshared
+		ts = ev.Timestamp
+		vars, err = s.GetVars(ctx, WithSpace(ev.ID), WithUser(ev.Executant, nil, nil), WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
+	}
+
+	return message, ts, vars, err
+}
+
 func (s *ActivitylogService) unwrapEvent(e *ehmsg.Event) interface{} {
 	etype, ok := s.registeredEvents[e.GetType()]
 	if !ok {
@@ -364,6 +378,11 @@ func (s *ActivitylogService) getFilters(query string) (*provider.ResourceId, int
 	return &rid, limit, pref, postf, sortby, nil
 }
 
+// returns true if the file was downloaded via a public link
+func isPublicDownload(ev events.FileDownloaded) bool {
+	return ev.ImpersonatingUser.GetDisplayName() == "Public"
+}
+
 // returns true if this is just a rename
 func isRename(o, n *provider.Reference) bool {
 	// if resourceids are different we assume it is a move
@@ -21,20 +21,28 @@ import (
 
 // Translations
 var (
-	MessageResourceCreated    = l10n.Template("{user} added {resource} to {folder}")
-	MessageResourceUpdated    = l10n.Template("{user} updated {resource} in {folder}")
-	MessageResourceDownloaded = l10n.Template("{resource} was downloaded via public link {token}")
-	MessageResourceTrashed    = l10n.Template("{user} deleted {resource} from {folder}")
-	MessageResourceMoved      = l10n.Template("{user} moved {resource} to {folder}")
-	MessageResourceRenamed    = l10n.Template("{user} renamed {oldResource} to {resource}")
-	MessageShareCreated       = l10n.Template("{user} shared {resource} with {sharee}")
-	MessageShareUpdated       = l10n.Template("{user} updated {field} for the {resource}")
-	MessageShareDeleted       = l10n.Template("{user} removed {sharee} from {resource}")
-	MessageLinkCreated        = l10n.Template("{user} shared {resource} via link")
-	MessageLinkUpdated        = l10n.Template("{user} updated {field} for a link {token} on {resource}")
-	MessageLinkDeleted        = l10n.Template("{user} removed link to {resource}")
-	MessageSpaceShared        = l10n.Template("{user} added {sharee} as member of {space}")
-	MessageSpaceUnshared      = l10n.Template("{user} removed {sharee} from {space}")
+	MessageResourceCreated          = l10n.Template("{user} added {resource} to {folder}")
+	MessageResourceUpdated          = l10n.Template("{user} updated {resource} in {folder}")
+	MessageResourceDownloaded       = l10n.Template("{resource} was downloaded via public link {token}")
+	MessageResourceDownloadedByUser = l10n.Template("{user} downloaded {resource}")
+	MessageResourceTrashed          = l10n.Template("{user} deleted {resource} from {folder}")
+	MessageResourceMoved            = l10n.Template("{user} moved {resource} to {folder}")
+	MessageResourceRenamed          = l10n.Template("{user} renamed {oldResource} to {resource}")
+	MessageShareCreated             = l10n.Template("{user} shared {resource} with {sharee}")
+	MessageShareUpdated             = l10n.Template("{user} updated {field} for the {resource}")
+	MessageShareDeleted             = l10n.Template("{user} removed {sharee} from {resource}")
+	MessageLinkCreated              = l10n.Template("{user} shared {resource} via link")
+	MessageLinkUpdated              = l10n.Template("{user} updated {field} for a link {token} on {resource}")
+	MessageLinkDeleted              = l10n.Template("{user} removed link to {resource}")
+	MessageSpaceShared              = l10n.Template("{user} added {sharee} as member of {space}")
+	MessageSpaceUnshared            = l10n.Template("{user} removed {sharee} from {space}")
+
+	MessageResourcesCreated      = l10n.Template("{user} added {count} items to {folder}")
+	MessageResourcesUpdated      = l10n.Template("{user} updated {count} items in {folder}")
+	MessageResourcesTrashed      = l10n.Template("{user} deleted {count} items from {folder}")
+	MessageResourcesMoved        = l10n.Template("{user} moved {count} items to {folder}")
+	MessageResourceDownloads     = l10n.Template("{resource} was downloaded {count} times via public link {token}")
+	MessageResourceUserDownloads = l10n.Template("{user} downloaded {resource} {count} times")
 
 	StrSomeField      = l10n.Template("some field")
 	StrPermission     = l10n.Template("permission")
@@ -49,6 +57,12 @@ type GetActivitiesResponse struct {
 	Activities []libregraph.Activity `json:"value"`
 }
 
+// GetFeedResponse is the response on GET requests of the space and personal feeds
+type GetFeedResponse struct {
+	Activities []libregraph.Activity `json:"value"`
+	NextLink   string                `json:"@odata.nextLink,omitempty"`
+}
+
 // Resource represents an item such as a file or folder
 type Resource struct {
 	ID   string `json:"id"`
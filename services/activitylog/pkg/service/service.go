@@ -30,6 +30,7 @@ import (
 	ehsvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/eventhistory/v0"
 	settingssvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/settings/v0"
 	"github.com/opencloud-eu/opencloud/services/activitylog/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
 )
 
 // Nats runs into max payload exceeded errors at around 7k activities. Let's keep a buffer.
@@ -164,7 +165,9 @@ func New(opts ...Option) (*ActivitylogService, error) {
 
 	// Connect to NATS servers
 	natsOptions := nats.Options{
-		Servers: o.Config.Store.Nodes,
+		Servers:  o.Config.Store.Nodes,
+		User:     o.Config.Store.AuthUsername,
+		Password: o.Config.Store.AuthPassword,
 	}
 	conn, err := natsOptions.Connect()
 	if err != nil {
@@ -218,6 +221,8 @@ func New(opts ...Option) (*ActivitylogService, error) {
 	}
 
 	s.mux.Get("/graph/v1beta1/extensions/org.libregraph/activities", s.HandleGetItemActivities)
+	s.mux.Get("/graph/v1beta1/extensions/org.libregraph/activities/me", s.HandleGetMyActivities)
+	s.mux.Get("/graph/v1beta1/extensions/org.libregraph/activities/spaces/{spaceID}", s.HandleGetSpaceActivities)
 
 	for _, e := range o.RegisteredEvents {
 		typ := reflect.TypeOf(e)
@@ -239,6 +244,7 @@ func (a *ActivitylogService) Run() {
 		case events.FileTouched:
 			err = a.AddActivity(ev.Ref, ev.ParentID, e.ID, utils.TSToTime(ev.Timestamp))
 		// Disabled https://github.com/owncloud/ocis/issues/10293
+		// The space feed shows the downloads, it reads them from the eventhistory.
 		//case events.FileDownloaded:
 		// we are only interested in public link downloads - so no need to store others.
 		//if ev.ImpersonatingUser.GetDisplayName() == "Public" {
@@ -275,6 +281,10 @@ func (a *ActivitylogService) Run() {
 			err = a.AddSpaceActivity(ev.ID, e.ID, ev.Timestamp)
 		case events.SpaceUnshared:
 			err = a.AddSpaceActivity(ev.ID, e.ID, ev.Timestamp)
+		case selfdeletion.AccountPurged:
+			if ev.PersonalSpaceID != nil {
+				err = a.RemoveSpace(ev.PersonalSpaceID)
+			}
 		}
 
 		if err != nil {
@@ -407,6 +417,49 @@ func (a *ActivitylogService) RemoveResource(rid *provider.ResourceId) error {
 	return a.natskv.Delete(storagespace.FormatResourceID(rid))
 }
 
+// RemoveSpace removes the activities of all resources in the space from the store
+func (a *ActivitylogService) RemoveSpace(spaceID *provider.StorageSpaceId) error {
+	if spaceID == nil {
+		return fmt.Errorf("space id is required")
+	}
+	space, err := storagespace.ParseID(spaceID.GetOpaqueId())
+	if err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	lister, err := a.natskv.ListKeys()
+	if err != nil {
+		return err
+	}
+	defer lister.Stop()
+
+	var toDelete []string
+	for key := range lister.Keys() {
+		encoded, _, _ := strings.Cut(key, ".")
+		decoded, err := base32.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		rid, err := storagespace.ParseID(string(decoded))
+		if err != nil {
+			continue
+		}
+		if rid.GetStorageId() == space.GetStorageId() && rid.GetSpaceId() == space.GetSpaceId() {
+			toDelete = append(toDelete, key)
+		}
+	}
+
+	for _, key := range toDelete {
+		if err := a.natskv.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (a *ActivitylogService) activities(rid *provider.ResourceId) ([]RawActivity, error) {
 	resourceID := storagespace.FormatResourceID(rid)
 
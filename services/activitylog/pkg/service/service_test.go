@@ -265,6 +265,34 @@ var _ = Describe("ActivitylogService", func() {
 				}).Should(Succeed())
 			})
 		})
+
+		Describe("RemoveSpace", func() {
+			It("removes the activities of all resources in the space", func() {
+				getResource = func(_ context.Context, ref *provider.Reference) (*provider.ResourceInfo, error) {
+					return tree[ref.GetResourceId().GetOpaqueId()], nil
+				}
+
+				err := alog.addActivity(context.Background(), reference("base"), nil, "activity1", time.Time{}, getResource)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(func(g Gomega) {
+					activities, err := alog.Activities(resourceID("base"))
+					g.Expect(err).NotTo(HaveOccurred())
+					g.Expect(activities).To(HaveLen(1))
+				}).Should(Succeed())
+
+				err = alog.RemoveSpace(&provider.StorageSpaceId{OpaqueId: "storageid$otherspace"})
+				Expect(err).NotTo(HaveOccurred())
+				activities, err := alog.Activities(resourceID("base"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(activities).To(HaveLen(1))
+
+				err = alog.RemoveSpace(&provider.StorageSpaceId{OpaqueId: "storageid$spaceid"})
+				Expect(err).NotTo(HaveOccurred())
+				activities, err = alog.Activities(resourceID("base"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(activities).To(BeEmpty())
+			})
+		})
 	})
 })
 
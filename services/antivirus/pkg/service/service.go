@@ -21,6 +21,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/opencloud-eu/opencloud/pkg/eventstream"
 	"github.com/opencloud-eu/opencloud/pkg/generators"
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	"github.com/opencloud-eu/opencloud/services/antivirus/pkg/config"
@@ -131,6 +132,7 @@ func (av Antivirus) Run() error {
 	if err != nil {
 		return err
 	}
+	natsStream = eventstream.Typed(natsStream)
 
 	ch, err := events.Consume(natsStream, "antivirus", events.StartPostprocessingStep{})
 	if err != nil {
@@ -1,6 +1,9 @@
 package config
 
+import "github.com/opencloud-eu/opencloud/pkg/shared"
+
 // TokenManager is the config for using the reva token manager
 type TokenManager struct {
-	JWTSecret string `yaml:"jwt_secret" env:"OC_JWT_SECRET;APP_PROVIDER_JWT_SECRET" desc:"The secret to mint and validate jwt tokens." introductionVersion:"1.0.0"`
+	JWTSecret          string                 `yaml:"jwt_secret" env:"OC_JWT_SECRET;APP_PROVIDER_JWT_SECRET" desc:"The secret to mint and validate jwt tokens." introductionVersion:"1.0.0"`
+	PreviousJWTSecrets shared.PreviousSecrets `yaml:"previous_jwt_secrets" env:"OC_PREVIOUS_JWT_SECRETS;APP_PROVIDER_PREVIOUS_JWT_SECRETS" desc:"Secrets that were replaced by the current jwt secret. Tokens signed with them are still accepted until they expire. Written by 'opencloud init --rotate'. In the environment they are set as a JSON list of objects with a 'secret' and an 'expires' timestamp." introductionVersion:"%%NEXT%%"`
 }
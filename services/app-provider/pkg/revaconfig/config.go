@@ -2,6 +2,7 @@
 package revaconfig
 
 import (
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/app-provider/pkg/config"
 )
 
@@ -46,6 +47,9 @@ func AppProviderConfigFromStruct(cfg *config.Config) map[string]interface{} {
 				},
 			},
 			"interceptors": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"prometheus": map[string]interface{}{
 					"namespace": "opencloud",
 					"subsystem": "app_provider",
@@ -3,6 +3,7 @@ package revaconfig
 import (
 	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/app-registry/pkg/config"
 )
 
@@ -41,6 +42,9 @@ func AppRegistryConfigFromStruct(cfg *config.Config, logger log.Logger) map[stri
 				},
 			},
 			"interceptors": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"prometheus": map[string]interface{}{
 					"namespace": "opencloud",
 					"subsystem": "app_registry",
@@ -9,6 +9,9 @@ import (
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	"github.com/opencloud-eu/opencloud/services/audit/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/audit/pkg/types"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/ratelimit"
 	"github.com/opencloud-eu/reva/v2/pkg/events"
 )
 
@@ -115,6 +118,26 @@ func StartAuditLogger(ctx context.Context, ch <-chan events.Event, log log.Logge
 				auditEvent = types.GroupMemberRemoved(ev)
 			case events.ScienceMeshInviteTokenGenerated:
 				auditEvent = types.ScienceMeshInviteTokenGenerated(ev)
+			case invitations.InvitationCreated:
+				auditEvent = types.InvitationCreated(ev)
+			case invitations.InvitationResent:
+				auditEvent = types.InvitationResent(ev)
+			case invitations.InvitationRedeemed:
+				auditEvent = types.InvitationRedeemed(ev)
+			case invitations.InvitationRevoked:
+				auditEvent = types.InvitationRevoked(ev)
+			case invitations.InvitationExpired:
+				auditEvent = types.InvitationExpired(ev)
+			case selfdeletion.AccountDeletionRequested:
+				auditEvent = types.AccountDeletionRequested(ev)
+			case selfdeletion.AccountDeletionCancelled:
+				auditEvent = types.AccountDeletionCancelled(ev)
+			case selfdeletion.AccountDeletionConfirmed:
+				auditEvent = types.AccountDeletionConfirmed(ev)
+			case selfdeletion.AccountPurged:
+				auditEvent = types.AccountPurged(ev)
+			case ratelimit.LockoutStarted:
+				auditEvent = types.LockoutStarted(ev)
 			default:
 				log.Error().Interface("event", ev).Msg(fmt.Sprintf("can't handle event of type '%T'", ev))
 				if ctx.Err() != nil {
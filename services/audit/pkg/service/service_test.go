@@ -9,6 +9,9 @@ import (
 
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	"github.com/opencloud-eu/opencloud/services/audit/pkg/types"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/ratelimit"
 	"github.com/opencloud-eu/reva/v2/pkg/events"
 
 	group "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
@@ -586,6 +589,158 @@ var testCases = []struct {
 			require.Equal(t, "http://opencloud.test/invite", ev.InviteLink)
 		},
 	},
+	{
+		Alias: "Invitations - InvitationCreated",
+		SystemEvent: events.Event{
+			Event: invitations.InvitationCreated{
+				Executant:               userID("inviting-user-id"),
+				InvitationID:            "invitation-id",
+				InvitedUserID:           "guest-user-id",
+				InvitedUserEmailAddress: "guest@opencloud.test",
+				RedeemLinkID:            "link-id",
+				Timestamp:               timestamp(10e8),
+			},
+		},
+		CheckAuditEvent: func(t *testing.T, b []byte) {
+			ev := types.AuditEventInvitationCreated{}
+			require.NoError(t, json.Unmarshal(b, &ev))
+
+			// AuditEvent fields
+			checkBaseAuditEvent(t, ev.AuditEvent, "inviting-user-id", "2001-09-09T01:46:40Z", "user 'inviting-user-id' invited the guest 'guest-user-id' with invitation 'invitation-id'", "invitation_created")
+			// AuditEventInvitationCreated fields
+			require.Equal(t, "invitation-id", ev.InvitationID)
+			require.Equal(t, "guest-user-id", ev.InvitedUserID)
+			require.Equal(t, "guest@opencloud.test", ev.InvitedUserEmailAddress)
+			// the redemption token must not end up in the audit log
+			require.NotContains(t, string(b), "secret")
+		},
+	},
+	{
+		Alias: "Invitations - InvitationExpired",
+		SystemEvent: events.Event{
+			Event: invitations.InvitationExpired{
+				InvitationID:  "invitation-id",
+				InvitedUserID: "guest-user-id",
+				Timestamp:     timestamp(10e8),
+			},
+		},
+		CheckAuditEvent: func(t *testing.T, b []byte) {
+			ev := types.AuditEventInvitationExpired{}
+			require.NoError(t, json.Unmarshal(b, &ev))
+
+			// AuditEvent fields
+			checkBaseAuditEvent(t, ev.AuditEvent, "", "2001-09-09T01:46:40Z", "invitation 'invitation-id' expired and the guest 'guest-user-id' was deleted", "invitation_expired")
+			// AuditEventInvitationExpired fields
+			require.Equal(t, "invitation-id", ev.InvitationID)
+			require.Equal(t, "guest-user-id", ev.InvitedUserID)
+		},
+	},
+	{
+		Alias: "Account deletion - AccountDeletionRequested",
+		SystemEvent: events.Event{
+			Event: selfdeletion.AccountDeletionRequested{
+				UserID:              userID("deleting-userid"),
+				ConfirmationTokenID: "token-id",
+				ExpiresAt:           timestamp(10e8 + 3600),
+				Timestamp:           timestamp(10e8),
+			},
+		},
+		CheckAuditEvent: func(t *testing.T, b []byte) {
+			ev := types.AuditEventAccountDeletionRequested{}
+			require.NoError(t, json.Unmarshal(b, &ev))
+
+			// AuditEvent fields
+			checkBaseAuditEvent(t, ev.AuditEvent, "deleting-userid", "2001-09-09T01:46:40Z", "user 'deleting-userid' requested the deletion of the own account", "account_deletion_requested")
+			// AuditEventAccountDeletionRequested fields
+			require.Equal(t, "deleting-userid", ev.UserID)
+			require.Equal(t, "2001-09-09T02:46:40Z", ev.ExpiresAt)
+		},
+	},
+	{
+		Alias: "Account deletion - AccountDeletionCancelled",
+		SystemEvent: events.Event{
+			Event: selfdeletion.AccountDeletionCancelled{
+				UserID:    userID("deleting-userid"),
+				Timestamp: timestamp(10e8),
+			},
+		},
+		CheckAuditEvent: func(t *testing.T, b []byte) {
+			ev := types.AuditEventAccountDeletionCancelled{}
+			require.NoError(t, json.Unmarshal(b, &ev))
+
+			// AuditEvent fields
+			checkBaseAuditEvent(t, ev.AuditEvent, "deleting-userid", "2001-09-09T01:46:40Z", "user 'deleting-userid' cancelled the deletion of the own account", "account_deletion_cancelled")
+			// AuditEventAccountDeletionCancelled fields
+			require.Equal(t, "deleting-userid", ev.UserID)
+		},
+	},
+	{
+		Alias: "Account deletion - AccountDeletionConfirmed",
+		SystemEvent: events.Event{
+			Event: selfdeletion.AccountDeletionConfirmed{
+				UserID:            userID("deleting-userid"),
+				PurgeAt:           timestamp(10e8 + 86400),
+				TransferredSpaces: []string{"space-1"},
+				DeletedSpaces:     []string{"space-2"},
+				Timestamp:         timestamp(10e8),
+			},
+		},
+		CheckAuditEvent: func(t *testing.T, b []byte) {
+			ev := types.AuditEventAccountDeletionConfirmed{}
+			require.NoError(t, json.Unmarshal(b, &ev))
+
+			// AuditEvent fields
+			checkBaseAuditEvent(t, ev.AuditEvent, "deleting-userid", "2001-09-09T01:46:40Z", "user 'deleting-userid' confirmed the deletion of the own account, it will be purged at '2001-09-10T01:46:40Z'", "account_deletion_confirmed")
+			// AuditEventAccountDeletionConfirmed fields
+			require.Equal(t, "deleting-userid", ev.UserID)
+			require.Equal(t, "2001-09-10T01:46:40Z", ev.PurgeAt)
+			require.Equal(t, []string{"space-1"}, ev.TransferredSpaces)
+			require.Equal(t, []string{"space-2"}, ev.DeletedSpaces)
+		},
+	},
+	{
+		Alias: "Account deletion - AccountPurged",
+		SystemEvent: events.Event{
+			Event: selfdeletion.AccountPurged{
+				UserID:          userID("deleting-userid"),
+				PersonalSpaceID: &provider.StorageSpaceId{OpaqueId: "personal-space-id"},
+				Timestamp:       timestamp(10e8),
+			},
+		},
+		CheckAuditEvent: func(t *testing.T, b []byte) {
+			ev := types.AuditEventAccountPurged{}
+			require.NoError(t, json.Unmarshal(b, &ev))
+
+			// AuditEvent fields
+			checkBaseAuditEvent(t, ev.AuditEvent, "", "2001-09-09T01:46:40Z", "the account of user 'deleting-userid' was purged", "account_purged")
+			// AuditEventAccountPurged fields
+			require.Equal(t, "deleting-userid", ev.UserID)
+			require.Equal(t, "personal-space-id", ev.PersonalSpaceID)
+		},
+	},
+	{
+		Alias: "Brute-force protection - LockoutStarted",
+		SystemEvent: events.Event{
+			Event: ratelimit.LockoutStarted{
+				Kind:        ratelimit.KindUser,
+				Subject:     "alice",
+				RemoteAddr:  "10.0.0.1",
+				LockedUntil: timestamp(10e8 + 900),
+				Timestamp:   timestamp(10e8),
+			},
+		},
+		CheckAuditEvent: func(t *testing.T, b []byte) {
+			ev := types.AuditEventLockoutStarted{}
+			require.NoError(t, json.Unmarshal(b, &ev))
+
+			// AuditEvent fields
+			checkBaseAuditEvent(t, ev.AuditEvent, "", "2001-09-09T01:46:40Z", "user 'alice' was locked out after too many failed authentication attempts from '10.0.0.1' until '2001-09-09T02:01:40Z'", "lockout_started")
+			// AuditEventLockoutStarted fields
+			require.Equal(t, "user", ev.Kind)
+			require.Equal(t, "alice", ev.Subject)
+			require.Equal(t, "10.0.0.1", ev.RemoteAddr)
+		},
+	},
 }
 
 func TestAuditLogging(t *testing.T) {
@@ -5,6 +5,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/ratelimit"
 	"github.com/opencloud-eu/reva/v2/pkg/events"
 	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
@@ -520,6 +523,140 @@ func ScienceMeshInviteTokenGenerated(ev events.ScienceMeshInviteTokenGenerated)
 	}
 }
 
+// AccountDeletionRequested converts an AccountDeletionRequested event to an AuditEventAccountDeletionRequested
+func AccountDeletionRequested(ev selfdeletion.AccountDeletionRequested) AuditEventAccountDeletionRequested {
+	uid := ev.UserID.GetOpaqueId()
+	base := BasicAuditEvent(uid, formatTime(ev.Timestamp), MessageAccountDeletionRequested(uid), ActionAccountDeletionRequested)
+	return AuditEventAccountDeletionRequested{
+		AuditEventAccountDeletion: AuditEventAccountDeletion{
+			AuditEvent: base,
+			UserID:     uid,
+		},
+		ExpiresAt: formatTime(ev.ExpiresAt),
+	}
+}
+
+// AccountDeletionCancelled converts an AccountDeletionCancelled event to an AuditEventAccountDeletionCancelled
+func AccountDeletionCancelled(ev selfdeletion.AccountDeletionCancelled) AuditEventAccountDeletionCancelled {
+	uid := ev.UserID.GetOpaqueId()
+	base := BasicAuditEvent(uid, formatTime(ev.Timestamp), MessageAccountDeletionCancelled(uid), ActionAccountDeletionCancelled)
+	return AuditEventAccountDeletionCancelled{
+		AuditEventAccountDeletion: AuditEventAccountDeletion{
+			AuditEvent: base,
+			UserID:     uid,
+		},
+	}
+}
+
+// AccountDeletionConfirmed converts an AccountDeletionConfirmed event to an AuditEventAccountDeletionConfirmed
+func AccountDeletionConfirmed(ev selfdeletion.AccountDeletionConfirmed) AuditEventAccountDeletionConfirmed {
+	uid := ev.UserID.GetOpaqueId()
+	purgeAt := formatTime(ev.PurgeAt)
+	base := BasicAuditEvent(uid, formatTime(ev.Timestamp), MessageAccountDeletionConfirmed(uid, purgeAt), ActionAccountDeletionConfirmed)
+	return AuditEventAccountDeletionConfirmed{
+		AuditEventAccountDeletion: AuditEventAccountDeletion{
+			AuditEvent: base,
+			UserID:     uid,
+		},
+		PurgeAt:           purgeAt,
+		TransferredSpaces: ev.TransferredSpaces,
+		DeletedSpaces:     ev.DeletedSpaces,
+	}
+}
+
+// AccountPurged converts an AccountPurged event to an AuditEventAccountPurged
+func AccountPurged(ev selfdeletion.AccountPurged) AuditEventAccountPurged {
+	uid := ev.UserID.GetOpaqueId()
+	base := BasicAuditEvent("", formatTime(ev.Timestamp), MessageAccountPurged(uid), ActionAccountPurged)
+	return AuditEventAccountPurged{
+		AuditEventAccountDeletion: AuditEventAccountDeletion{
+			AuditEvent: base,
+			UserID:     uid,
+		},
+		PersonalSpaceID: ev.PersonalSpaceID.GetOpaqueId(),
+	}
+}
+
+// LockoutStarted converts a LockoutStarted event to an AuditEventLockoutStarted
+func LockoutStarted(ev ratelimit.LockoutStarted) AuditEventLockoutStarted {
+	lockedUntil := formatTime(ev.LockedUntil)
+	base := BasicAuditEvent("", formatTime(ev.Timestamp), MessageLockoutStarted(ev.Kind, ev.Subject, ev.RemoteAddr, lockedUntil), ActionLockoutStarted)
+	return AuditEventLockoutStarted{
+		AuditEvent:  base,
+		Kind:        ev.Kind,
+		Subject:     ev.Subject,
+		RemoteAddr:  ev.RemoteAddr,
+		LockedUntil: lockedUntil,
+	}
+}
+
+// InvitationCreated converts an InvitationCreated event to an AuditEventInvitationCreated
+func InvitationCreated(ev invitations.InvitationCreated) AuditEventInvitationCreated {
+	msg := MessageInvitationCreated(ev.Executant.GetOpaqueId(), ev.InvitationID, ev.InvitedUserID)
+	base := BasicAuditEvent(ev.Executant.GetOpaqueId(), formatTime(ev.Timestamp), msg, ActionInvitationCreated)
+	return AuditEventInvitationCreated{
+		AuditEventInvitation: AuditEventInvitation{
+			AuditEvent:    base,
+			InvitationID:  ev.InvitationID,
+			InvitedUserID: ev.InvitedUserID,
+		},
+		InvitedUserEmailAddress: ev.InvitedUserEmailAddress,
+	}
+}
+
+// InvitationResent converts an InvitationResent event to an AuditEventInvitationResent
+func InvitationResent(ev invitations.InvitationResent) AuditEventInvitationResent {
+	msg := MessageInvitationResent(ev.Executant.GetOpaqueId(), ev.InvitationID, ev.InvitedUserID)
+	base := BasicAuditEvent(ev.Executant.GetOpaqueId(), formatTime(ev.Timestamp), msg, ActionInvitationResent)
+	return AuditEventInvitationResent{
+		AuditEventInvitation: AuditEventInvitation{
+			AuditEvent:    base,
+			InvitationID:  ev.InvitationID,
+			InvitedUserID: ev.InvitedUserID,
+		},
+		InvitedUserEmailAddress: ev.InvitedUserEmailAddress,
+	}
+}
+
+// InvitationRedeemed converts an InvitationRedeemed event to an AuditEventInvitationRedeemed
+func InvitationRedeemed(ev invitations.InvitationRedeemed) AuditEventInvitationRedeemed {
+	msg := MessageInvitationRedeemed(ev.InvitationID, ev.InvitedUserID)
+	base := BasicAuditEvent(ev.InvitedUserID, formatTime(ev.Timestamp), msg, ActionInvitationRedeemed)
+	return AuditEventInvitationRedeemed{
+		AuditEventInvitation: AuditEventInvitation{
+			AuditEvent:    base,
+			InvitationID:  ev.InvitationID,
+			InvitedUserID: ev.InvitedUserID,
+		},
+	}
+}
+
+// InvitationRevoked converts an InvitationRevoked event to an AuditEventInvitationRevoked
+func InvitationRevoked(ev invitations.InvitationRevoked) AuditEventInvitationRevoked {
+	msg := MessageInvitationRevoked(ev.Executant.GetOpaqueId(), ev.InvitationID, ev.InvitedUserID)
+	base := BasicAuditEvent(ev.Executant.GetOpaqueId(), formatTime(ev.Timestamp), msg, ActionInvitationRevoked)
+	return AuditEventInvitationRevoked{
+		AuditEventInvitation: AuditEventInvitation{
+			AuditEvent:    base,
+			InvitationID:  ev.InvitationID,
+			InvitedUserID: ev.InvitedUserID,
+		},
+	}
+}
+
+// InvitationExpired converts an InvitationExpired event to an AuditEventInvitationExpired
+func InvitationExpired(ev invitations.InvitationExpired) AuditEventInvitationExpired {
+	msg := MessageInvitationExpired(ev.InvitationID, ev.InvitedUserID)
+	base := BasicAuditEvent("", formatTime(ev.Timestamp), msg, ActionInvitationExpired)
+	return AuditEventInvitationExpired{
+		AuditEventInvitation: AuditEventInvitation{
+			AuditEvent:    base,
+			InvitationID:  ev.InvitationID,
+			InvitedUserID: ev.InvitedUserID,
+		},
+	}
+}
+
 func extractGrantee(uid *user.UserId, gid *group.GroupId) (string, string) {
 	switch {
 	case uid != nil && uid.OpaqueId != "":
@@ -1,6 +1,9 @@
 package types
 
 import (
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/ratelimit"
 	"github.com/opencloud-eu/reva/v2/pkg/events"
 )
 
@@ -41,5 +44,15 @@ func RegisteredEvents() []events.Unmarshaller {
 		events.GroupMemberRemoved{},
 		events.BackchannelLogout{},
 		events.ScienceMeshInviteTokenGenerated{},
+		invitations.InvitationCreated{},
+		invitations.InvitationResent{},
+		invitations.InvitationRedeemed{},
+		invitations.InvitationRevoked{},
+		invitations.InvitationExpired{},
+		selfdeletion.AccountDeletionRequested{},
+		selfdeletion.AccountDeletionCancelled{},
+		selfdeletion.AccountDeletionConfirmed{},
+		selfdeletion.AccountPurged{},
+		ratelimit.LockoutStarted{},
 	}
 }
@@ -54,6 +54,22 @@ const (
 
 	// ScienceMesh
 	ActionScienceMeshInviteTokenGenerated = "science_mesh_invite_token_generated"
+
+	// Invitations
+	ActionInvitationCreated  = "invitation_created"
+	ActionInvitationResent   = "invitation_resent"
+	ActionInvitationRedeemed = "invitation_redeemed"
+	ActionInvitationRevoked  = "invitation_revoked"
+	ActionInvitationExpired  = "invitation_expired"
+
+	// Account deletion
+	ActionAccountDeletionRequested = "account_deletion_requested"
+	ActionAccountDeletionCancelled = "account_deletion_cancelled"
+	ActionAccountDeletionConfirmed = "account_deletion_confirmed"
+	ActionAccountPurged            = "account_purged"
+
+	// Brute-force protection
+	ActionLockoutStarted = "lockout_started"
 )
 
 // MessageShareCreated returns the human-readable string that describes the action
@@ -242,3 +258,53 @@ func MessageGroupMemberRemoved(executant, userID, groupID string) string {
 func MessageScienceMeshInviteTokenGenerated(user, token string) string {
 	return fmt.Sprintf("user '%s' generated a ScienceMesh invite with token '%s'", user, token)
 }
+
+// MessageInvitationCreated returns the human-readable string that describes the action
+func MessageInvitationCreated(executant, invitationID, userID string) string {
+	return fmt.Sprintf("user '%s' invited the guest '%s' with invitation '%s'", executant, userID, invitationID)
+}
+
+// MessageInvitationResent returns the human-readable string that describes the action
+func MessageInvitationResent(executant, invitationID, userID string) string {
+	return fmt.Sprintf("user '%s' resent the invitation '%s' to the guest '%s'", executant, invitationID, userID)
+}
+
+// MessageInvitationRedeemed returns the human-readable string that describes the action
+func MessageInvitationRedeemed(invitationID, userID string) string {
+	return fmt.Sprintf("guest '%s' redeemed the invitation '%s'", userID, invitationID)
+}
+
+// MessageInvitationRevoked returns the human-readable string that describes the action
+func MessageInvitationRevoked(executant, invitationID, userID string) string {
+	return fmt.Sprintf("user '%s' revoked the invitation '%s' and deleted the guest '%s'", executant, invitationID, userID)
+}
+
+// MessageInvitationExpired returns the human-readable string that describes the action
+func MessageInvitationExpired(invitationID, userID string) string {
+	return fmt.Sprintf("invitation '%s' expired and the guest '%s' was deleted", invitationID, userID)
+}
+
+// MessageAccountDeletionRequested returns the human-readable string that describes the action
+func MessageAccountDeletionRequested(userID string) string {
+	return fmt.Sprintf("user '%s' requested the deletion of the own account", userID)
+}
+
+// MessageAccountDeletionCancelled returns the human-readable string that describes the action
+func MessageAccountDeletionCancelled(userID string) string {
+	return fmt.Sprintf("user '%s' cancelled the deletion of the own account", userID)
+}
+
+// MessageAccountDeletionConfirmed returns the human-readable string that describes the action
+func MessageAccountDeletionConfirmed(userID, purgeAt string) string {
+	return fmt.Sprintf("user '%s' confirmed the deletion of the own account, it will be purged at '%s'", userID, purgeAt)
+}
+
+// MessageAccountPurged returns the human-readable string that describes the action
+func MessageAccountPurged(userID string) string {
+	return fmt.Sprintf("the account of user '%s' was purged", userID)
+}
+
+// MessageLockoutStarted returns the human-readable string that describes the action
+func MessageLockoutStarted(kind, subject, remoteAddr, lockedUntil string) string {
+	return fmt.Sprintf("%s '%s' was locked out after too many failed authentication attempts from '%s' until '%s'", kind, subject, remoteAddr, lockedUntil)
+}
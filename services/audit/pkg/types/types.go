@@ -284,3 +284,77 @@ type AuditEventScienceMeshInviteTokenGenerated struct {
 	Expiration    uint64
 	InviteLink    string
 }
+
+// AuditEventInvitation is the base of the events logged for the lifecycle of guest invitations
+type AuditEventInvitation struct {
+	AuditEvent
+	InvitationID  string
+	InvitedUserID string
+}
+
+// AuditEventInvitationCreated is the event logged when a guest is invited
+type AuditEventInvitationCreated struct {
+	AuditEventInvitation
+	InvitedUserEmailAddress string
+}
+
+// AuditEventInvitationResent is the event logged when an invitation is sent again
+type AuditEventInvitationResent struct {
+	AuditEventInvitation
+	InvitedUserEmailAddress string
+}
+
+// AuditEventInvitationRedeemed is the event logged when an invitation is redeemed
+type AuditEventInvitationRedeemed struct {
+	AuditEventInvitation
+}
+
+// AuditEventInvitationRevoked is the event logged when an invitation is revoked
+type AuditEventInvitationRevoked struct {
+	AuditEventInvitation
+}
+
+// AuditEventInvitationExpired is the event logged when an invitation expired
+type AuditEventInvitationExpired struct {
+	AuditEventInvitation
+}
+
+// AuditEventAccountDeletion is the base of the events logged for the deletion of accounts by their users
+type AuditEventAccountDeletion struct {
+	AuditEvent
+	UserID string
+}
+
+// AuditEventAccountDeletionRequested is the event logged when a user requests the deletion of the own account
+type AuditEventAccountDeletionRequested struct {
+	AuditEventAccountDeletion
+	ExpiresAt string
+}
+
+// AuditEventAccountDeletionCancelled is the event logged when a user cancels the deletion of the own account
+type AuditEventAccountDeletionCancelled struct {
+	AuditEventAccountDeletion
+}
+
+// AuditEventAccountDeletionConfirmed is the event logged when a user confirms the deletion of the own account
+type AuditEventAccountDeletionConfirmed struct {
+	AuditEventAccountDeletion
+	PurgeAt           string
+	TransferredSpaces []string
+	DeletedSpaces     []string
+}
+
+// AuditEventLockoutStarted is the event logged when a client IP address, a username or a public link is locked out
+type AuditEventLockoutStarted struct {
+	AuditEvent
+	Kind        string
+	Subject     string
+	RemoteAddr  string
+	LockedUntil string
+}
+
+// AuditEventAccountPurged is the event logged when a self deleted account is purged
+type AuditEventAccountPurged struct {
+	AuditEventAccountDeletion
+	PersonalSpaceID string
+}
@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 
 	"github.com/opencloud-eu/opencloud/pkg/config/defaults"
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/auth-app/pkg/config"
 )
 
@@ -62,6 +63,9 @@ func AuthAppConfigFromStruct(cfg *config.Config) map[string]interface{} {
 				},
 			},
 			"interceptors": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"prometheus": map[string]interface{}{
 					"namespace": "opencloud",
 					"subsystem": "auth_app",
@@ -115,7 +115,8 @@ func EnsureDefaults(cfg *config.Config) {
 
 	if cfg.TokenManager == nil && cfg.Commons != nil && cfg.Commons.TokenManager != nil {
 		cfg.TokenManager = &config.TokenManager{
-			JWTSecret: cfg.Commons.TokenManager.JWTSecret,
+			JWTSecret:          cfg.Commons.TokenManager.JWTSecret,
+			PreviousJWTSecrets: cfg.Commons.TokenManager.PreviousJWTSecrets,
 		}
 	} else if cfg.TokenManager == nil {
 		cfg.TokenManager = &config.TokenManager{}
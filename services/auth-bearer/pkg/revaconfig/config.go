@@ -2,6 +2,7 @@
 package revaconfig
 
 import (
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/auth-bearer/pkg/config"
 )
 
@@ -38,6 +39,9 @@ func AuthBearerConfigFromStruct(cfg *config.Config) map[string]interface{} {
 				},
 			},
 			"interceptors": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"prometheus": map[string]interface{}{
 					"namespace": "opencloud",
 					"subsystem": "auth_bearer",
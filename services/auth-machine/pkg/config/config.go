@@ -20,7 +20,8 @@ type Config struct {
 
 	SkipUserGroupsInToken bool `yaml:"skip_user_groups_in_token" env:"AUTH_MACHINE_SKIP_USER_GROUPS_IN_TOKEN" desc:"Disables the encoding of the user's group memberships in the reva access token. This reduces the token size, especially when users are members of a large number of groups." introductionVersion:"1.0.0"`
 
-	MachineAuthAPIKey string `yaml:"machine_auth_api_key" env:"OC_MACHINE_AUTH_API_KEY;AUTH_MACHINE_API_KEY" desc:"Machine auth API key used to validate internal requests necessary for the access to resources from other services." introductionVersion:"1.0.0"`
+	MachineAuthAPIKey          string                 `yaml:"machine_auth_api_key" env:"OC_MACHINE_AUTH_API_KEY;AUTH_MACHINE_API_KEY" desc:"Machine auth API key used to validate internal requests necessary for the access to resources from other services." introductionVersion:"1.0.0"`
+	PreviousMachineAuthAPIKeys shared.PreviousSecrets `yaml:"previous_machine_auth_api_keys" env:"OC_PREVIOUS_MACHINE_AUTH_API_KEYS;AUTH_MACHINE_PREVIOUS_API_KEYS" desc:"Machine auth API keys that were replaced by the current one. They are still accepted until they expire." introductionVersion:"%%NEXT%%"`
 
 	Context context.Context `yaml:"-"`
 }
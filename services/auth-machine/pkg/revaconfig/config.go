@@ -1,6 +1,7 @@
 package revaconfig
 
 import (
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/auth-machine/pkg/config"
 )
 
@@ -24,16 +25,21 @@ func AuthMachineConfigFromStruct(cfg *config.Config) map[string]interface{} {
 			},
 			"services": map[string]interface{}{
 				"authprovider": map[string]interface{}{
-					"auth_manager": "machine",
+					"auth_manager": secrets.RotatingAuthManagerName,
 					"auth_managers": map[string]interface{}{
-						"machine": map[string]interface{}{
-							"api_key":      cfg.MachineAuthAPIKey,
-							"gateway_addr": cfg.Reva.Address,
-						},
+						secrets.RotatingAuthManagerName: secrets.AuthManagerConfig("machine", cfg.MachineAuthAPIKey, cfg.PreviousMachineAuthAPIKeys, func(secret string) map[string]interface{} {
+							return map[string]interface{}{
+								"api_key":      secret,
+								"gateway_addr": cfg.Reva.Address,
+							}
+						}),
 					},
 				},
 			},
 			"interceptors": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"prometheus": map[string]interface{}{
 					"namespace": "opencloud",
 					"subsystem": "auth_machine",
@@ -53,4 +53,6 @@ type GRPCConfig struct {
 type ServiceAccount struct {
 	ServiceAccountID     string `yaml:"service_account_id" env:"OC_SERVICE_ACCOUNT_ID;AUTH_SERVICE_SERVICE_ACCOUNT_ID" desc:"The ID of the service account the service should use. See the 'auth-service' service description for more details." introductionVersion:"1.0.0"`
 	ServiceAccountSecret string `yaml:"service_account_secret" env:"OC_SERVICE_ACCOUNT_SECRET;AUTH_SERVICE_SERVICE_ACCOUNT_SECRET" desc:"The service account secret." introductionVersion:"1.0.0"`
+	// PreviousServiceAccountSecrets are only needed by the auth-service, the other services authenticate with the current secret.
+	PreviousServiceAccountSecrets shared.PreviousSecrets `yaml:"previous_service_account_secrets" env:"OC_PREVIOUS_SERVICE_ACCOUNT_SECRETS;AUTH_SERVICE_PREVIOUS_SERVICE_ACCOUNT_SECRETS" desc:"Service account secrets that were replaced by the current one. They are still accepted until they expire. Written by 'opencloud init --rotate'. In the environment they are set as a JSON list of objects with a 'secret' and an 'expires' timestamp." introductionVersion:"%%NEXT%%"`
 }
@@ -1,6 +1,7 @@
 package revaconfig
 
 import (
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/auth-service/pkg/config"
 )
 
@@ -30,20 +31,25 @@ func AuthMachineConfigFromStruct(cfg *config.Config) map[string]interface{} {
 			},
 			"services": map[string]interface{}{
 				"authprovider": map[string]interface{}{
-					"auth_manager": "serviceaccounts",
+					"auth_manager": secrets.RotatingAuthManagerName,
 					"auth_managers": map[string]interface{}{
-						"serviceaccounts": map[string]interface{}{
-							"service_accounts": []map[string]interface{}{
-								{
-									"id":     cfg.ServiceAccount.ServiceAccountID,
-									"secret": cfg.ServiceAccount.ServiceAccountSecret,
+						secrets.RotatingAuthManagerName: secrets.AuthManagerConfig("serviceaccounts", cfg.ServiceAccount.ServiceAccountSecret, cfg.ServiceAccount.PreviousServiceAccountSecrets, func(secret string) map[string]interface{} {
+							return map[string]interface{}{
+								"service_accounts": []map[string]interface{}{
+									{
+										"id":     cfg.ServiceAccount.ServiceAccountID,
+										"secret": secret,
+									},
 								},
-							},
-						},
+							}
+						}),
 					},
 				},
 			},
 			"interceptors": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"prometheus": map[string]interface{}{
 					"namespace": "opencloud",
 					"subsystem": "auth_service",
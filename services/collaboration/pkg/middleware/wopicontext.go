@@ -14,10 +14,10 @@ import (
 	appproviderv1beta1 "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
 	providerv1beta1 "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/collaboration/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/collaboration/pkg/helpers"
 	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
-	rjwt "github.com/opencloud-eu/reva/v2/pkg/token/manager/jwt"
 	"github.com/rs/zerolog"
 	microstore "go-micro.dev/v4/store"
 	"google.golang.org/grpc/metadata"
@@ -111,10 +111,9 @@ func WopiContextAuthMiddleware(cfg *config.Config, st microstore.Store, next htt
 			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 			return
 		}
-		tokenManager, err := rjwt.New(map[string]interface{}{
-			"secret":  cfg.TokenManager.JWTSecret,
-			"expires": int64(24 * 60 * 60),
-		})
+		tokenManagerConfig := secrets.TokenManagerConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets)
+		tokenManagerConfig["expires"] = int64(24 * 60 * 60)
+		tokenManager, err := secrets.NewTokenManager(tokenManagerConfig)
 		if err != nil {
 			wopiLogger.Error().Err(err).Msg("failed to get a reva token manager")
 			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
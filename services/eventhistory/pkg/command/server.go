@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os/signal"
 
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/opencloud-eu/reva/v2/pkg/events/stream"
 	"github.com/opencloud-eu/reva/v2/pkg/store"
 	"github.com/urfave/cli/v2"
@@ -22,6 +24,7 @@ import (
 	"github.com/opencloud-eu/opencloud/services/eventhistory/pkg/metrics"
 	"github.com/opencloud-eu/opencloud/services/eventhistory/pkg/server/debug"
 	"github.com/opencloud-eu/opencloud/services/eventhistory/pkg/server/grpc"
+	"github.com/opencloud-eu/opencloud/services/eventhistory/pkg/service"
 )
 
 // Server is the entrypoint for the server command.
@@ -67,13 +70,18 @@ func Server(cfg *config.Config) *cli.Command {
 
 			st := store.Create(
 				store.Store(cfg.Store.Store),
-				store.TTL(cfg.Store.TTL),
+				store.TTL(cfg.StoreTTL()),
 				microstore.Nodes(cfg.Store.Nodes...),
 				microstore.Database(cfg.Store.Database),
 				microstore.Table(cfg.Store.Table),
 				store.Authentication(cfg.Store.AuthUsername, cfg.Store.AuthPassword),
 			)
 
+			index, err := newIndex(ctx, cfg)
+			if err != nil {
+				return err
+			}
+
 			service := grpc.NewService(
 				grpc.Logger(logger),
 				grpc.Context(ctx),
@@ -84,6 +92,7 @@ func Server(cfg *config.Config) *cli.Command {
 				grpc.Metrics(m),
 				grpc.Consumer(consumer),
 				grpc.Persistence(st),
+				grpc.Index(index),
 				grpc.TraceProvider(traceProvider),
 			)
 
@@ -115,3 +124,35 @@ func Server(cfg *config.Config) *cli.Command {
 		},
 	}
 }
+
+// newIndex returns the index of the events. With the nats-js-kv store the index is kept in its own
+// bucket next to the events, with other stores it is kept in memory.
+func newIndex(ctx context.Context, cfg *config.Config) (service.Index, error) {
+	if cfg.Store.Store != "nats-js-kv" {
+		return service.NewMemoryIndex(cfg.StoreTTL()), nil
+	}
+
+	natsOptions := nats.Options{
+		Servers:  cfg.Store.Nodes,
+		User:     cfg.Store.AuthUsername,
+		Password: cfg.Store.AuthPassword,
+	}
+	conn, err := natsOptions.Connect()
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := cfg.Store.Database + "-index"
+	kv, err := js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket: bucket,
+		TTL:    cfg.StoreTTL(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bucket (%s): %w", bucket, err)
+	}
+	return service.NewNatsIndex(kv), nil
+}
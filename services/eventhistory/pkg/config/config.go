@@ -2,6 +2,8 @@ package config
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/opencloud-eu/opencloud/pkg/shared"
@@ -22,8 +24,9 @@ type Config struct {
 	GRPCClientTLS *shared.GRPCClientTLS `yaml:"grpc_client_tls"`
 	GrpcClient    client.Client         `yaml:"-"`
 
-	Events Events `yaml:"events"`
-	Store  Store  `yaml:"store"`
+	Events    Events    `yaml:"events"`
+	Store     Store     `yaml:"store"`
+	Retention Retention `yaml:"retention"`
 
 	Context context.Context `yaml:"-"`
 }
@@ -46,6 +49,47 @@ type Store struct {
 	AuthPassword string        `yaml:"password" env:"OC_PERSISTENT_STORE_AUTH_PASSWORD;EVENTHISTORY_STORE_AUTH_PASSWORD" desc:"The password to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"1.0.0"`
 }
 
+// Retention configures how long the events are kept.
+type Retention struct {
+	Types         []string      `yaml:"types" env:"EVENTHISTORY_RETENTION_TYPES" desc:"A list of event types and how long to keep events of the type, in the format 'type=duration' like 'ShareCreated=8760h' or 'events.UploadReady=168h'. Events of other types are kept for EVENTHISTORY_STORE_TTL. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"EVENTHISTORY_RETENTION_PURGE_INTERVAL" desc:"The interval in which expired events are removed from the store. Set to 0 to not remove expired events, they are still not returned anymore. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+}
+
+// Durations returns how long to keep the events of the configured types, keyed by the full event
+// type like 'events.ShareCreated'.
+func (r Retention) Durations() (map[string]time.Duration, error) {
+	durations := make(map[string]time.Duration, len(r.Types))
+	for _, t := range r.Types {
+		typ, d, ok := strings.Cut(t, "=")
+		if !ok || strings.TrimSpace(typ) == "" {
+			return nil, fmt.Errorf("invalid retention '%s', expected 'type=duration'", t)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid retention duration of '%s'", t)
+		}
+		typ = strings.TrimSpace(typ)
+		if !strings.HasPrefix(typ, "events.") {
+			typ = "events." + typ
+		}
+		durations[typ] = duration
+	}
+	return durations, nil
+}
+
+// StoreTTL returns the time to live of the store, which has to cover the longest retention.
+func (c *Config) StoreTTL() time.Duration {
+	durations, err := c.Retention.Durations()
+	if err != nil || c.Store.TTL <= 0 {
+		return c.Store.TTL
+	}
+	ttl := c.Store.TTL
+	for _, d := range durations {
+		ttl = max(ttl, d)
+	}
+	return ttl
+}
+
 // Events combines the configuration options for the event bus.
 type Events struct {
 	Endpoint             string `yaml:"endpoint" env:"OC_EVENTS_ENDPOINT;EVENTHISTORY_EVENTS_ENDPOINT" desc:"The address of the event system. The event system is the message queuing service. It is used as message broker for the microservice architecture." introductionVersion:"1.0.0"`
@@ -39,6 +39,9 @@ func DefaultConfig() *config.Config {
 			Table:    "",
 			TTL:      336 * time.Hour,
 		},
+		Retention: config.Retention{
+			PurgeInterval: time.Hour,
+		},
 		GRPC: config.GRPCConfig{
 			Addr:      "127.0.0.1:9274",
 			Namespace: "eu.opencloud.api",
@@ -34,5 +34,8 @@ func ParseConfig(cfg *config.Config) error {
 
 // Validate validates the config
 func Validate(cfg *config.Config) error {
+	if _, err := cfg.Retention.Durations(); err != nil {
+		return err
+	}
 	return nil
 }
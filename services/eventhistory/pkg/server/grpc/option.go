@@ -6,6 +6,7 @@ import (
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	"github.com/opencloud-eu/opencloud/services/eventhistory/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/eventhistory/pkg/metrics"
+	svc "github.com/opencloud-eu/opencloud/services/eventhistory/pkg/service"
 	"github.com/opencloud-eu/reva/v2/pkg/events"
 	"go-micro.dev/v4/store"
 	"go.opentelemetry.io/otel/trace"
@@ -24,6 +25,7 @@ type Options struct {
 	Metrics       *metrics.Metrics
 	Namespace     string
 	Persistence   store.Store
+	Index         svc.Index
 	Consumer      events.Consumer
 	TraceProvider trace.TracerProvider
 }
@@ -95,6 +97,13 @@ func Persistence(store store.Store) Option {
 	}
 }
 
+// Index provides a function to configure the index of the events
+func Index(index svc.Index) Option {
+	return func(o *Options) {
+		o.Index = index
+	}
+}
+
 // Consumer provides a function to configure the consumer
 func Consumer(consumer events.Consumer) Option {
 	return func(o *Options) {
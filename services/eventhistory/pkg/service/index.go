@@ -0,0 +1,248 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+)
+
+// _indexBucket is the time span the index entries are grouped by. A query lists the entries of one
+// group at a time, so it reads only the entries of the time range it asks for.
+const _indexBucket = int64(time.Hour)
+
+// _bucketsIndex is the index of the groups holding entries for an index value.
+const _bucketsIndex = "buckets"
+
+// the secondary indexes of the events
+const (
+	_indexTime      = "time"
+	_indexExpiry    = "expiry"
+	_indexType      = "type"
+	_indexSpace     = "space"
+	_indexResource  = "resource"
+	_indexExecutant = "executant"
+)
+
+// encodeToken encodes a value to a token of an index key. Tokens must not be empty and may only
+// contain the characters allowed in the keys of NATS key value buckets.
+func encodeToken(value string) string {
+	if value == "" {
+		return "="
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+func decodeToken(token string) (string, bool) {
+	if token == "=" {
+		return "", true
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	return string(b), err == nil
+}
+
+// bucketOf returns the group of a timestamp.
+func bucketOf(ts int64) int64 {
+	return ts / _indexBucket
+}
+
+// bucketsPrefix returns the prefix of the keys marking the groups of an index value.
+func bucketsPrefix(index, value string) string {
+	return _bucketsIndex + "." + index + "." + encodeToken(value) + "."
+}
+
+// entriesPrefix returns the prefix of the entries of an index value in a group.
+func entriesPrefix(index, value string, bucket int64) string {
+	return index + "." + encodeToken(value) + "." + strconv.FormatInt(bucket, 10) + "."
+}
+
+// indexKeys returns the keys of an index entry and the marker of its group.
+func indexKeys(index, value string, ts int64, id string) []string {
+	b := bucketOf(ts)
+	return []string{
+		entriesPrefix(index, value, b) + fmt.Sprintf("%020d", ts) + "." + encodeToken(id),
+		bucketsPrefix(index, value) + strconv.FormatInt(b, 10),
+	}
+}
+
+// eventIndexKeys returns the keys of all index entries of an event.
+func eventIndexKeys(ev StoreEvent) []string {
+	ts := ev.Timestamp.UnixNano()
+	keys := append(indexKeys(_indexTime, "", ts, ev.ID), indexKeys(_indexType, ev.Type, ts, ev.ID)...)
+	if !ev.Expires.IsZero() {
+		keys = append(keys, indexKeys(_indexExpiry, "", ev.Expires.UnixNano(), ev.ID)...)
+	}
+	if ev.SpaceID != "" {
+		keys = append(keys, indexKeys(_indexSpace, ev.SpaceID, ts, ev.ID)...)
+	}
+	if ev.ResourceID != "" {
+		keys = append(keys, indexKeys(_indexResource, ev.ResourceID, ts, ev.ID)...)
+	}
+	if ev.ExecutantID != "" {
+		keys = append(keys, indexKeys(_indexExecutant, ev.ExecutantID, ts, ev.ID)...)
+	}
+	return keys
+}
+
+// eventEntryKeys returns the keys of the index entries of an event without the markers of the groups,
+// which are shared with other events.
+func eventEntryKeys(ev StoreEvent) []string {
+	var keys []string
+	for _, k := range eventIndexKeys(ev) {
+		if !strings.HasPrefix(k, _bucketsIndex+".") {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// parseBucketKey returns the group of a marker key.
+func parseBucketKey(key string) (int64, bool) {
+	i := strings.LastIndex(key, ".")
+	if i < 0 {
+		return 0, false
+	}
+	b, err := strconv.ParseInt(key[i+1:], 10, 64)
+	return b, err == nil
+}
+
+// cursor is the position of an event in an index.
+type cursor struct {
+	ts int64
+	id string
+}
+
+// parseIndexKey returns the position of the event of an index entry.
+func parseIndexKey(key string) (cursor, bool) {
+	tokens := strings.Split(key, ".")
+	if len(tokens) < 2 {
+		return cursor{}, false
+	}
+	ts, err := strconv.ParseInt(tokens[len(tokens)-2], 10, 64)
+	if err != nil {
+		return cursor{}, false
+	}
+	id, ok := decodeToken(tokens[len(tokens)-1])
+	if !ok {
+		return cursor{}, false
+	}
+	return cursor{ts: ts, id: id}, true
+}
+
+// before checks whether c is positioned before o.
+func (c cursor) before(o cursor) bool {
+	if c.ts != o.ts {
+		return c.ts < o.ts
+	}
+	return c.id < o.id
+}
+
+// token returns the page token continuing after the cursor.
+func (c cursor) token() string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d/%s", c.ts, c.id))
+}
+
+// parseToken parses a page token.
+func parseToken(token string) (cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor{}, fmt.Errorf("invalid page token")
+	}
+	ts, id, ok := strings.Cut(string(b), "/")
+	if !ok {
+		return cursor{}, fmt.Errorf("invalid page token")
+	}
+	n, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return cursor{}, fmt.Errorf("invalid page token")
+	}
+	return cursor{ts: n, id: id}, nil
+}
+
+// spaceKey returns the space id of a space or resource id, which is how the space index is keyed.
+func spaceKey(id string) string {
+	_, space, _, err := storagespace.SplitID(id)
+	if err != nil {
+		return ""
+	}
+	return space
+}
+
+// resourceKey returns the key of a resource id without the storage id, which is how the resource
+// index is keyed. A space id is the id of its root.
+func resourceKey(id string) string {
+	_, space, node, err := storagespace.SplitID(id)
+	if err != nil {
+		return ""
+	}
+	if node == "" {
+		node = space
+	}
+	return space + "!" + node
+}
+
+type resourceID struct {
+	SpaceID  string `json:"space_id"`
+	OpaqueID string `json:"opaque_id"`
+}
+
+type reference struct {
+	ResourceID *resourceID `json:"resource_id"`
+}
+
+type userID struct {
+	OpaqueID string `json:"opaque_id"`
+}
+
+// extractIndexes returns the space, the resource and the executant of an event. The events put
+// them in different fields, so the common ones are tried in order.
+func extractIndexes(payload []byte) (space, resource, executant string) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return "", "", ""
+	}
+	decode := func(field string, v interface{}) bool {
+		raw, ok := fields[field]
+		return ok && json.Unmarshal(raw, v) == nil
+	}
+
+	for _, f := range []string{"Ref", "FileRef"} {
+		ref := reference{}
+		if resource == "" && decode(f, &ref) && ref.ResourceID != nil && ref.ResourceID.SpaceID != "" && ref.ResourceID.OpaqueID != "" {
+			resource = ref.ResourceID.SpaceID + "!" + ref.ResourceID.OpaqueID
+		}
+	}
+	for _, f := range []string{"ItemID", "ResourceID", "ID"} {
+		rid := resourceID{}
+		if resource == "" && decode(f, &rid) && rid.SpaceID != "" && rid.OpaqueID != "" {
+			resource = rid.SpaceID + "!" + rid.OpaqueID
+		}
+	}
+
+	if resource != "" {
+		space, _, _ = strings.Cut(resource, "!")
+	}
+	// space events only carry the id of the space
+	for _, f := range []string{"ID", "SpaceID"} {
+		sid := resourceID{}
+		if space == "" && decode(f, &sid) && sid.SpaceID == "" && sid.OpaqueID != "" {
+			space = spaceKey(sid.OpaqueID)
+		}
+	}
+
+	u := userID{}
+	executingUser := struct {
+		ID *userID `json:"id"`
+	}{}
+	switch {
+	case decode("Executant", &u) && u.OpaqueID != "":
+		executant = u.OpaqueID
+	case decode("ExecutingUser", &executingUser) && executingUser.ID != nil:
+		executant = executingUser.ID.OpaqueID
+	}
+	return space, resource, executant
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Index keeps the secondary indexes of the events. The keys consist of dot separated tokens, so the
+// entries sharing a prefix can be listed without reading the others.
+type Index interface {
+	// Put adds an index entry.
+	Put(ctx context.Context, key string) error
+	// Keys returns at most limit keys starting with the prefix, which ends with a dot.
+	Keys(ctx context.Context, prefix string, limit int) ([]string, error)
+	// Delete removes an index entry.
+	Delete(ctx context.Context, key string) error
+}
+
+// memoryIndex keeps the index entries in memory.
+type memoryIndex struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]time.Time
+	swept   time.Time
+}
+
+// NewMemoryIndex returns an Index keeping the entries in memory. Entries are dropped after the ttl,
+// a ttl of 0 keeps them forever.
+func NewMemoryIndex(ttl time.Duration) Index {
+	return &memoryIndex{
+		ttl:     ttl,
+		entries: make(map[string]time.Time),
+		swept:   time.Now(),
+	}
+}
+
+// Put implements the Index interface.
+func (i *memoryIndex) Put(_ context.Context, key string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := time.Now()
+	var expires time.Time
+	if i.ttl > 0 {
+		expires = now.Add(i.ttl)
+	}
+	i.entries[key] = expires
+	i.sweep(now)
+	return nil
+}
+
+// Keys implements the Index interface.
+func (i *memoryIndex) Keys(_ context.Context, prefix string, limit int) ([]string, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := time.Now()
+	var keys []string
+	for k, expires := range i.entries {
+		if strings.HasPrefix(k, prefix) && (expires.IsZero() || now.Before(expires)) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, nil
+}
+
+// Delete implements the Index interface.
+func (i *memoryIndex) Delete(_ context.Context, key string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.entries, key)
+	return nil
+}
+
+// sweep drops the expired entries once per ttl.
+func (i *memoryIndex) sweep(now time.Time) {
+	if i.ttl <= 0 || now.Sub(i.swept) < i.ttl {
+		return
+	}
+	for k, expires := range i.entries {
+		if !expires.IsZero() && now.After(expires) {
+			delete(i.entries, k)
+		}
+	}
+	i.swept = now
+}
+
+// natsIndex keeps the index entries in a NATS key value bucket.
+type natsIndex struct {
+	kv jetstream.KeyValue
+}
+
+// NewNatsIndex returns an Index keeping the entries in a NATS key value bucket. The keys are listed
+// with a subject filter, so the server only sends the keys of the prefix.
+func NewNatsIndex(kv jetstream.KeyValue) Index {
+	return natsIndex{kv: kv}
+}
+
+// Put implements the Index interface.
+func (i natsIndex) Put(ctx context.Context, key string) error {
+	_, err := i.kv.Put(ctx, key, nil)
+	return err
+}
+
+// Keys implements the Index interface.
+func (i natsIndex) Keys(ctx context.Context, prefix string, limit int) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	lister, err := i.kv.ListKeysFiltered(ctx, prefix+">")
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	defer func() {
+		// the lister stops sending when the context is done, the keys sent until then are discarded
+		cancel()
+		_ = lister.Stop()
+		go func() {
+			for range lister.Keys() {
+			}
+		}()
+	}()
+
+	var keys []string
+	for k := range lister.Keys() {
+		if len(keys) == limit {
+			break
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Delete implements the Index interface.
+func (i natsIndex) Delete(ctx context.Context, key string) error {
+	if err := i.kv.Delete(ctx, key); err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
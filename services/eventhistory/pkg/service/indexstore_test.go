@@ -0,0 +1,53 @@
+package service_test
+
+import (
+	"context"
+	"time"
+
+	nserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/opencloud/services/eventhistory/pkg/service"
+)
+
+var _ = Describe("Index", func() {
+	natsIndex := func() service.Index {
+		s, err := nserver.NewServer(&nserver.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: GinkgoT().TempDir()})
+		Expect(err).ToNot(HaveOccurred())
+		s.Start()
+		DeferCleanup(s.Shutdown)
+		Expect(s.ReadyForConnections(10 * time.Second)).To(BeTrue())
+
+		conn, err := nats.Connect("", nats.InProcessServer(s))
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(conn.Close)
+		js, err := jetstream.New(conn)
+		Expect(err).ToNot(HaveOccurred())
+		kv, err := js.CreateKeyValue(context.Background(), jetstream.KeyValueConfig{Bucket: "eventhistory-index"})
+		Expect(err).ToNot(HaveOccurred())
+		return service.NewNatsIndex(kv)
+	}
+
+	for name, newIndex := range map[string]func() service.Index{
+		"in memory": func() service.Index { return service.NewMemoryIndex(0) },
+		"in nats":   natsIndex,
+	} {
+		It("lists the keys of a prefix "+name, func() {
+			ctx := context.Background()
+			index := newIndex()
+			for _, k := range []string{"space.a.1.1.x", "space.a.1.2.y", "space.a.2.3.z", "space.b.1.1.x", "spaces.a.1.1.x"} {
+				Expect(index.Put(ctx, k)).To(Succeed())
+			}
+
+			Expect(index.Keys(ctx, "space.a.1.", 10)).To(Equal([]string{"space.a.1.1.x", "space.a.1.2.y"}))
+			Expect(index.Keys(ctx, "space.a.", 2)).To(HaveLen(2))
+			Expect(index.Keys(ctx, "space.c.", 10)).To(BeEmpty())
+
+			Expect(index.Delete(ctx, "space.a.1.1.x")).To(Succeed())
+			Expect(index.Delete(ctx, "space.a.1.1.x")).To(Succeed())
+			Expect(index.Keys(ctx, "space.a.1.", 10)).To(Equal([]string{"space.a.1.2.y"}))
+		})
+	}
+})
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+	"time"
+
+	ehsvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/eventhistory/v0"
+	"go-micro.dev/v4/store"
+)
+
+const (
+	_defaultPageSize = 100
+	_maxPageSize     = 1000
+	// _maxIndexKeys is the number of index keys a query reads at most
+	_maxIndexKeys = 10000
+)
+
+// ErrTooManyEvents is returned when a query has to read more index keys than allowed to find the
+// first event.
+var ErrTooManyEvents = errors.New("the query matches too many events, narrow it down with more filters")
+
+// query is a parsed QueryEventsRequest.
+type query struct {
+	types       []string
+	since       int64
+	until       int64
+	spaceIDs    []string
+	resourceID  string
+	executantID string
+	pageSize    int
+	after       *cursor
+	descending  bool
+	// empty is set when the filters contradict each other
+	empty bool
+}
+
+func newQuery(req *ehsvc.QueryEventsRequest) (*query, error) {
+	q := &query{
+		types:      req.GetTypes(),
+		pageSize:   int(req.GetPageSize()),
+		descending: req.GetDescending(),
+	}
+	if req.GetSince() != nil {
+		q.since = req.GetSince().AsTime().UnixNano()
+	}
+	if req.GetUntil() != nil {
+		q.until = req.GetUntil().AsTime().UnixNano()
+	}
+	for _, id := range req.GetSpaceIDs() {
+		if k := spaceKey(id); !slices.Contains(q.spaceIDs, k) {
+			q.spaceIDs = append(q.spaceIDs, k)
+		}
+	}
+	if req.GetSpaceID() != "" {
+		k := spaceKey(req.GetSpaceID())
+		// both space filters have to match
+		q.empty = len(q.spaceIDs) > 0 && !slices.Contains(q.spaceIDs, k)
+		q.spaceIDs = []string{k}
+	}
+	if req.GetResourceID() != "" {
+		q.resourceID = resourceKey(req.GetResourceID())
+	}
+	q.executantID = req.GetExecutantID()
+	switch {
+	case q.pageSize <= 0:
+		q.pageSize = _defaultPageSize
+	case q.pageSize > _maxPageSize:
+		q.pageSize = _maxPageSize
+	}
+	if req.GetPageToken() != "" {
+		c, err := parseToken(req.GetPageToken())
+		if err != nil {
+			return nil, err
+		}
+		q.after = &c
+	}
+	return q, nil
+}
+
+// index returns the most selective index for the query and the values to look up, an event
+// matches the index when it has one of the values.
+func (q *query) index() (string, []string) {
+	switch {
+	case q.resourceID != "":
+		return _indexResource, []string{q.resourceID}
+	case len(q.spaceIDs) > 0:
+		return _indexSpace, q.spaceIDs
+	case q.executantID != "":
+		return _indexExecutant, []string{q.executantID}
+	case len(q.types) == 1:
+		return _indexType, q.types[0:1]
+	default:
+		return _indexTime, []string{""}
+	}
+}
+
+// buckets returns the groups of the index entries within the time range and after the page token,
+// in the order of the query.
+func (q *query) buckets(groups map[int64][]string) []int64 {
+	var buckets []int64
+	for b := range groups {
+		start, end := b*_indexBucket, (b+1)*_indexBucket
+		switch {
+		case q.since != 0 && end <= q.since:
+		case q.until != 0 && start >= q.until:
+		case q.after != nil && q.descending && start > q.after.ts:
+		case q.after != nil && !q.descending && end <= q.after.ts:
+		default:
+			buckets = append(buckets, b)
+		}
+	}
+	slices.Sort(buckets)
+	if q.descending {
+		slices.Reverse(buckets)
+	}
+	return buckets
+}
+
+// sort orders the cursors like the query.
+func (q *query) sort(cursors []cursor) {
+	slices.SortFunc(cursors, func(a, b cursor) int {
+		switch {
+		case a == b:
+			return 0
+		case a.before(b) != q.descending:
+			return -1
+		default:
+			return 1
+		}
+	})
+}
+
+// inRange checks whether an index entry is within the time range and after the page token.
+func (q *query) inRange(c cursor) bool {
+	switch {
+	case q.since != 0 && c.ts < q.since:
+		return false
+	case q.until != 0 && c.ts >= q.until:
+		return false
+	case q.after == nil:
+		return true
+	case q.descending:
+		return c.before(*q.after)
+	default:
+		return q.after.before(c)
+	}
+}
+
+// matches checks the filters the index didn't cover.
+func (q *query) matches(ev StoreEvent) bool {
+	switch {
+	case len(q.types) > 0 && !slices.Contains(q.types, ev.Type):
+		return false
+	case len(q.spaceIDs) > 0 && !slices.Contains(q.spaceIDs, ev.SpaceID):
+		return false
+	case q.resourceID != "" && ev.ResourceID != q.resourceID:
+		return false
+	case q.executantID != "" && ev.ExecutantID != q.executantID:
+		return false
+	}
+	return true
+}
+
+// QueryEvents returns the events matching all filters of the request, ordered by the time they
+// were recorded. Only events recorded since the indexes were introduced are found.
+func (eh *EventHistoryService) QueryEvents(ctx context.Context, req *ehsvc.QueryEventsRequest, resp *ehsvc.QueryEventsResponse) error {
+	q, err := newQuery(req)
+	if err != nil {
+		return err
+	}
+	if q.empty {
+		return nil
+	}
+
+	// the index is read group by group, so a query only reads the entries of the groups it needs
+	index, values := q.index()
+	budget := _maxIndexKeys
+	groups := make(map[int64][]string)
+	for _, value := range values {
+		markers, err := eh.index.Keys(ctx, bucketsPrefix(index, value), budget+1)
+		if err != nil {
+			eh.log.Error().Err(err).Msg("could not list the index")
+			return err
+		}
+		if len(markers) > budget {
+			return ErrTooManyEvents
+		}
+		budget -= len(markers)
+		for _, m := range markers {
+			if b, ok := parseBucketKey(m); ok {
+				groups[b] = append(groups[b], value)
+			}
+		}
+	}
+
+	var last *cursor
+	for _, b := range q.buckets(groups) {
+		var candidates []cursor
+		for _, value := range groups[b] {
+			keys, err := eh.index.Keys(ctx, entriesPrefix(index, value, b), budget+1)
+			if err != nil {
+				eh.log.Error().Err(err).Msg("could not list the index")
+				return err
+			}
+			if len(keys) > budget {
+				if last == nil {
+					return ErrTooManyEvents
+				}
+				// continue with the next group on the next page, which can be empty
+				resp.NextPageToken = last.token()
+				return nil
+			}
+			budget -= len(keys)
+
+			for _, k := range keys {
+				if c, ok := parseIndexKey(k); ok && q.inRange(c) {
+					candidates = append(candidates, c)
+				}
+			}
+		}
+		q.sort(candidates)
+
+		for _, c := range candidates {
+			if len(resp.Events) == q.pageSize {
+				// the remaining candidates might all be filtered out, so the next page can be empty
+				resp.NextPageToken = last.token()
+				return nil
+			}
+			last = &c
+			ev, err := eh.readEvent(c.id)
+			if err != nil || !q.matches(ev) {
+				continue
+			}
+			resp.Events = append(resp.Events, toMessage(ev))
+		}
+	}
+	return nil
+}
+
+// Purge removes the expired events and their index entries.
+func (eh *EventHistoryService) Purge() error {
+	ctx := context.Background()
+	markers, err := eh.index.Keys(ctx, bucketsPrefix(_indexExpiry, ""), math.MaxInt)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixNano()
+	for _, m := range markers {
+		b, ok := parseBucketKey(m)
+		if !ok || b*_indexBucket > now {
+			continue
+		}
+		keys, err := eh.index.Keys(ctx, entriesPrefix(_indexExpiry, "", b), math.MaxInt)
+		if err != nil {
+			return err
+		}
+		purged := true
+		for _, k := range keys {
+			c, ok := parseIndexKey(k)
+			if !ok || c.ts > now {
+				purged = false
+				continue
+			}
+			if !eh.purgeEvent(ctx, c.id) {
+				purged = false
+				continue
+			}
+			_ = eh.index.Delete(ctx, k)
+		}
+		if purged && (b+1)*_indexBucket <= now {
+			_ = eh.index.Delete(ctx, m)
+		}
+	}
+	return nil
+}
+
+// purgeEvent removes an event and its index entries, it returns false when the event could not be removed.
+func (eh *EventHistoryService) purgeEvent(ctx context.Context, id string) bool {
+	recs, err := eh.store.Read(id)
+	if err == nil && len(recs) > 0 {
+		var ev StoreEvent
+		if err := json.Unmarshal(recs[0].Value, &ev); err == nil {
+			for _, k := range eventEntryKeys(ev) {
+				_ = eh.index.Delete(ctx, k)
+			}
+		}
+	}
+	if err := eh.store.Delete(id); err != nil && !errors.Is(err, store.ErrNotFound) {
+		eh.log.Error().Err(err).Str("eventid", id).Msg("could not delete expired event")
+		return false
+	}
+	return true
+}
+
+// purgeExpired purges the expired events periodically until the service stops.
+func (eh *EventHistoryService) purgeExpired() {
+	ctx := eh.cfg.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ticker := time.NewTicker(eh.cfg.Retention.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := eh.Purge(); err != nil {
+				eh.log.Error().Err(err).Msg("could not purge expired events")
+			}
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	ehmsg "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/messages/eventhistory/v0"
@@ -13,6 +14,7 @@ import (
 	"github.com/opencloud-eu/opencloud/services/eventhistory/pkg/config"
 	"github.com/opencloud-eu/reva/v2/pkg/events"
 	"go-micro.dev/v4/store"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // StoreEvent is data structure in the store
@@ -20,20 +22,35 @@ type StoreEvent struct {
 	ID    string
 	Type  string
 	Event []byte
+
+	// Timestamp is the time the event was recorded
+	Timestamp time.Time
+	// Expires is the time the event is removed, it is zero for events kept until the store expires them
+	Expires     time.Time
+	SpaceID     string
+	ResourceID  string
+	ExecutantID string
 }
 
 // EventHistoryService is the service responsible for event history
 type EventHistoryService struct {
-	ch    <-chan events.Event
-	store store.Store
-	cfg   *config.Config
-	log   log.Logger
+	ch        <-chan events.Event
+	store     store.Store
+	index     Index
+	cfg       *config.Config
+	log       log.Logger
+	retention map[string]time.Duration
 }
 
 // NewEventHistoryService returns an EventHistory service
-func NewEventHistoryService(cfg *config.Config, consumer events.Consumer, store store.Store, log log.Logger) (*EventHistoryService, error) {
-	if consumer == nil || store == nil {
-		return nil, fmt.Errorf("need non nil consumer (%v) and store (%v) to work properly", consumer, store)
+func NewEventHistoryService(cfg *config.Config, consumer events.Consumer, store store.Store, index Index, log log.Logger) (*EventHistoryService, error) {
+	if consumer == nil || store == nil || index == nil {
+		return nil, fmt.Errorf("need non nil consumer (%v), store (%v) and index (%v) to work properly", consumer, store, index)
+	}
+
+	retention, err := cfg.Retention.Durations()
+	if err != nil {
+		return nil, err
 	}
 
 	ch, err := events.ConsumeAll(consumer, "evhistory")
@@ -41,8 +58,11 @@ func NewEventHistoryService(cfg *config.Config, consumer events.Consumer, store
 		return nil, err
 	}
 
-	eh := &EventHistoryService{ch: ch, store: store, cfg: cfg, log: log}
+	eh := &EventHistoryService{ch: ch, store: store, index: index, cfg: cfg, log: log, retention: retention}
 	go eh.StoreEvents()
+	if cfg.Retention.PurgeInterval > 0 {
+		go eh.purgeExpired()
+	}
 
 	return eh, nil
 }
@@ -50,11 +70,19 @@ func NewEventHistoryService(cfg *config.Config, consumer events.Consumer, store
 // StoreEvents consumes all events and stores them in the store. Will block
 func (eh *EventHistoryService) StoreEvents() {
 	for event := range eh.ch {
-		ev, err := json.Marshal(StoreEvent{
-			ID:    event.ID,
-			Type:  event.Type,
-			Event: event.Event.([]byte),
-		})
+		se := StoreEvent{
+			ID:        event.ID,
+			Type:      event.Type,
+			Event:     event.Event.([]byte),
+			Timestamp: time.Now(),
+		}
+		ttl := eh.ttl(event.Type)
+		if ttl > 0 {
+			se.Expires = se.Timestamp.Add(ttl)
+		}
+		se.SpaceID, se.ResourceID, se.ExecutantID = extractIndexes(se.Event)
+
+		ev, err := json.Marshal(se)
 		if err != nil {
 			eh.log.Error().Err(err).Str("eventid", event.ID).Msg("could not marshal event")
 			continue
@@ -62,7 +90,7 @@ func (eh *EventHistoryService) StoreEvents() {
 		if err := eh.store.Write(&store.Record{
 			Key:    event.ID,
 			Value:  ev,
-			Expiry: eh.cfg.Store.TTL,
+			Expiry: ttl,
 			Metadata: map[string]interface{}{
 				"type": event.Type,
 			},
@@ -71,7 +99,20 @@ func (eh *EventHistoryService) StoreEvents() {
 			eh.log.Error().Err(err).Str("eventid", event.ID).Msg("could not store event")
 			continue
 		}
+		for _, k := range eventIndexKeys(se) {
+			if err := eh.index.Put(context.Background(), k); err != nil {
+				eh.log.Error().Err(err).Str("eventid", event.ID).Str("key", k).Msg("could not index event")
+			}
+		}
+	}
+}
+
+// ttl returns how long to keep events of the type.
+func (eh *EventHistoryService) ttl(typ string) time.Duration {
+	if d, ok := eh.retention[typ]; ok {
+		return d
 	}
+	return eh.cfg.Store.TTL
 }
 
 // GetEvents allows retrieving events from the eventstore by id
@@ -122,27 +163,46 @@ func (eh *EventHistoryService) GetEventsForUser(ctx context.Context, req *ehsvc.
 }
 
 func (eh *EventHistoryService) getEvent(id string) (*ehmsg.Event, error) {
+	ev, err := eh.readEvent(id)
+	if err != nil {
+		return nil, err
+	}
+	return toMessage(ev), nil
+}
+
+// readEvent reads an event from the store. Expired events are not found.
+func (eh *EventHistoryService) readEvent(id string) (StoreEvent, error) {
 	evs, err := eh.store.Read(id)
 	if err != nil {
 		if !errors.Is(err, store.ErrNotFound) {
 			eh.log.Error().Err(err).Str("eventid", id).Msg("could not read event")
 		}
-		return nil, err
+		return StoreEvent{}, err
 	}
 
 	if len(evs) == 0 {
-		return nil, store.ErrNotFound
+		return StoreEvent{}, store.ErrNotFound
 	}
 
 	var ev StoreEvent
 	if err := json.Unmarshal(evs[0].Value, &ev); err != nil {
 		eh.log.Error().Err(err).Str("eventid", id).Msg("could not unmarshal event")
-		return nil, err
+		return StoreEvent{}, err
+	}
+	if !ev.Expires.IsZero() && time.Now().After(ev.Expires) {
+		return StoreEvent{}, store.ErrNotFound
 	}
+	return ev, nil
+}
 
-	return &ehmsg.Event{
+func toMessage(ev StoreEvent) *ehmsg.Event {
+	e := &ehmsg.Event{
 		Id:    ev.ID,
 		Event: ev.Event,
 		Type:  ev.Type,
-	}, nil
+	}
+	if !ev.Timestamp.IsZero() {
+		e.Timestamp = timestamppb.New(ev.Timestamp)
+	}
+	return e
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -35,7 +36,7 @@ var _ = Describe("EventHistoryService", func() {
 		var err error
 		sto = store.Create()
 		bus = testBus(make(chan events.Event))
-		eh, err = service.NewEventHistoryService(cfg, bus, sto, log.Logger{})
+		eh, err = service.NewEventHistoryService(cfg, bus, sto, service.NewMemoryIndex(0), log.Logger{})
 		Expect(err).ToNot(HaveOccurred())
 	})
 
@@ -100,6 +101,74 @@ var _ = Describe("EventHistoryService", func() {
 		Expect(gotIDs[0]).To(Equal(expectedIDs[0]))
 		Expect(gotIDs[1]).To(Equal(expectedIDs[1]))
 	})
+
+	It("Queries events by type, space and executant", func() {
+		ref := &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "storage", SpaceId: "space-1", OpaqueId: "node"}}
+		einstein := &userv1beta1.UserId{OpaqueId: "einstein"}
+		ids := make([]string, 4)
+		ids[0] = bus.Publish(events.SpaceCreated{ID: &provider.StorageSpaceId{OpaqueId: "storage$space-1"}, Executant: einstein})
+		time.Sleep(10 * time.Millisecond)
+		ids[1] = bus.Publish(events.ContainerCreated{Ref: ref, Executant: einstein})
+		time.Sleep(10 * time.Millisecond)
+		ids[2] = bus.Publish(events.FileTouched{Ref: ref, Executant: &userv1beta1.UserId{OpaqueId: "marie"}})
+		time.Sleep(10 * time.Millisecond)
+		ids[3] = bus.Publish(events.ContainerCreated{
+			Ref:       &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "storage", SpaceId: "space-2", OpaqueId: "node"}},
+			Executant: einstein,
+		})
+
+		time.Sleep(500 * time.Millisecond)
+
+		query := func(req *ehsvc.QueryEventsRequest) []string {
+			resp := &ehsvc.QueryEventsResponse{}
+			Expect(eh.QueryEvents(context.Background(), req, resp)).To(Succeed())
+			var got []string
+			for _, ev := range resp.Events {
+				Expect(ev.Timestamp).ToNot(BeNil())
+				got = append(got, ev.Id)
+			}
+			return got
+		}
+
+		Expect(query(&ehsvc.QueryEventsRequest{})).To(Equal(ids))
+		Expect(query(&ehsvc.QueryEventsRequest{Types: []string{"events.ContainerCreated"}})).To(Equal([]string{ids[1], ids[3]}))
+		Expect(query(&ehsvc.QueryEventsRequest{SpaceID: "storage$space-1"})).To(Equal(ids[:3]))
+		Expect(query(&ehsvc.QueryEventsRequest{SpaceIDs: []string{"storage$space-1", "storage$space-2"}})).To(Equal(ids))
+		Expect(query(&ehsvc.QueryEventsRequest{SpaceIDs: []string{"storage$space-2", "storage$space-3"}, Descending: true})).To(Equal(ids[3:]))
+		Expect(query(&ehsvc.QueryEventsRequest{SpaceID: "storage$space-1", SpaceIDs: []string{"storage$space-2"}})).To(BeEmpty())
+		Expect(query(&ehsvc.QueryEventsRequest{ResourceID: "storage$space-1!node"})).To(Equal(ids[1:3]))
+		Expect(query(&ehsvc.QueryEventsRequest{SpaceID: "storage$space-1", ExecutantID: "einstein"})).To(Equal(ids[:2]))
+		Expect(query(&ehsvc.QueryEventsRequest{ExecutantID: "einstein", Descending: true})).To(Equal([]string{ids[3], ids[1], ids[0]}))
+
+		resp := &ehsvc.QueryEventsResponse{}
+		Expect(eh.QueryEvents(context.Background(), &ehsvc.QueryEventsRequest{PageSize: 3}, resp)).To(Succeed())
+		Expect(resp.Events).To(HaveLen(3))
+		Expect(resp.NextPageToken).ToNot(BeEmpty())
+		Expect(query(&ehsvc.QueryEventsRequest{PageSize: 3, PageToken: resp.NextPageToken})).To(Equal(ids[3:]))
+	})
+
+	It("Purges events after their retention", func() {
+		cfg := &config.Config{Retention: config.Retention{Types: []string{"UserCreated=100ms"}}}
+		bus := testBus(make(chan events.Event))
+		defer close(bus)
+		eh, err := service.NewEventHistoryService(cfg, bus, store.Create(), service.NewMemoryIndex(0), log.Logger{})
+		Expect(err).ToNot(HaveOccurred())
+
+		created := bus.Publish(events.UserCreated{UserID: "test-id"})
+		deleted := bus.Publish(events.UserDeleted{UserID: "test-id"})
+		time.Sleep(500 * time.Millisecond)
+
+		Expect(eh.Purge()).To(Succeed())
+
+		resp := &ehsvc.GetEventsResponse{}
+		Expect(eh.GetEvents(context.Background(), &ehsvc.GetEventsRequest{Ids: []string{created, deleted}}, resp)).To(Succeed())
+		Expect(resp.Events).To(HaveLen(1))
+		Expect(resp.Events[0].Id).To(Equal(deleted))
+
+		qresp := &ehsvc.QueryEventsResponse{}
+		Expect(eh.QueryEvents(context.Background(), &ehsvc.QueryEventsRequest{}, qresp)).To(Succeed())
+		Expect(qresp.Events).To(HaveLen(1))
+	})
 })
 
 type testBus chan events.Event
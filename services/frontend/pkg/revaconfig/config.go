@@ -13,6 +13,7 @@ import (
 	"github.com/opencloud-eu/opencloud/pkg/capabilities"
 	"github.com/opencloud-eu/opencloud/pkg/config/defaults"
 	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/pkg/version"
 	"github.com/opencloud-eu/opencloud/services/frontend/pkg/config"
 )
@@ -113,6 +114,7 @@ func FrontendConfigFromStruct(cfg *config.Config, logger log.Logger) (map[string
 				},
 				"auth": map[string]interface{}{
 					"credentials_by_user_agent": cfg.Middleware.Auth.CredentialsByUserAgent,
+					"token_managers":            secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
 				},
 				"prometheus": map[string]interface{}{
 					"namespace": "opencloud",
@@ -0,0 +1,102 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/urfave/cli/v2"
+
+	"github.com/opencloud-eu/opencloud/pkg/config/configlog"
+	"github.com/opencloud-eu/opencloud/pkg/registry"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config/parser"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/logging"
+	svc "github.com/opencloud-eu/opencloud/services/graph/pkg/service/v0"
+)
+
+// AccessReport builds a report of all space memberships, shares and public links
+func AccessReport(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "access-report",
+		Usage: "report who can access which spaces and resources through space memberships, shares and public links",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "user",
+				Usage: "only report the access of the user with this id, including the access through the user's groups",
+			},
+			&cli.StringFlag{
+				Name:  "group",
+				Usage: "only report the access of the group with this id",
+			},
+			&cli.StringFlag{
+				Name:  "space",
+				Usage: "only report the access to the space with this id",
+			},
+			&cli.StringFlag{
+				Name:  "link-type",
+				Usage: "only report public links of this type, e.g. 'view', 'edit' or 'upload'",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: svc.AccessReportFormatJSON,
+				Usage: "the output format, supported values are 'json' and 'csv'",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "write the report to this file instead of stdout",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			format := c.String("format")
+			if format != svc.AccessReportFormatJSON && format != svc.AccessReportFormatCSV {
+				return fmt.Errorf("invalid format '%s', supported values are 'json' and 'csv'", format)
+			}
+			filter, err := svc.ParseAccessReportFilter(c.String("user"), c.String("group"), c.String("space"), c.String("link-type"))
+			if err != nil {
+				return err
+			}
+
+			gatewaySelector, err := pool.GatewaySelector(
+				cfg.Reva.Address,
+				append(cfg.Reva.GetRevaOptions(), pool.WithRegistry(registry.GetRegistry()))...,
+			)
+			if err != nil {
+				return fmt.Errorf("could not create the gateway selector: %w", err)
+			}
+
+			logger := logging.Configure(cfg.Service.Name, cfg.Log)
+			reporter, err := svc.NewAccessReporter(logger, gatewaySelector, cfg)
+			if err != nil {
+				return fmt.Errorf("could not create the access reporter: %w", err)
+			}
+			entries, err := reporter.Report(c.Context, filter)
+			if err != nil {
+				return fmt.Errorf("could not build the access report: %w", err)
+			}
+
+			var out io.Writer = os.Stdout
+			if name := c.String("output"); name != "" {
+				f, err := os.Create(name)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+
+			if format == svc.AccessReportFormatCSV {
+				return svc.WriteAccessReportCSV(out, entries)
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(entries)
+		},
+	}
+}
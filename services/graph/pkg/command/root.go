@@ -17,6 +17,7 @@ func GetCommands(cfg *config.Config) cli.Commands {
 		Server(cfg),
 
 		// interaction with this service
+		AccessReport(cfg),
 
 		// infos about this service
 		Health(cfg),
@@ -18,8 +18,10 @@ import (
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/config/parser"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/logging"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/metrics"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/server/debug"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/server/http"
+	svc "github.com/opencloud-eu/opencloud/services/graph/pkg/service/v0"
 )
 
 // Server is the entrypoint for the server command.
@@ -48,7 +50,7 @@ func Server(cfg *config.Config) *cli.Command {
 			mtrcs := metrics.New()
 			mtrcs.BuildInfo.WithLabelValues(version.GetString()).Set(1)
 
-			var kv jetstream.KeyValue
+			var kv, deltakv, copyjobkv, tokenkv jetstream.KeyValue
 			// Allow to run without a NATS store (e.g. for the standalone Education provisioning service)
 			if len(cfg.Store.Nodes) > 0 {
 				//Connect to NATS servers
@@ -79,6 +81,38 @@ func Server(cfg *config.Config) *cli.Command {
 						return fmt.Errorf("failed to create bucket (%s): %w", cfg.Store.Database, err)
 					}
 				}
+
+				// the folder states of delta queries expire, so that abandoned synchronizations don't pile up
+				deltaBucket := cfg.Store.Database + "-delta"
+				deltakv, err = js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+					Bucket: deltaBucket,
+					TTL:    cfg.API.DeltaSnapshotTTL,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to create bucket (%s): %w", deltaBucket, err)
+				}
+
+				// the copy jobs expire after their status can no longer be requested, also when no client asks for it
+				copyJobBucket := cfg.Store.Database + "-copyjobs"
+				copyjobkv, err = js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+					Bucket: copyJobBucket,
+					TTL:    svc.CopyJobTTL,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to create bucket (%s): %w", copyJobBucket, err)
+				}
+
+				// the confirmation tokens of self deletion requests are handed to the notifications service
+				// in their own bucket, they expire with the requests
+				if cfg.SelfDeletion.Enabled {
+					tokenkv, err = js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+						Bucket: selfdeletion.TokenBucket,
+						TTL:    cfg.SelfDeletion.ConfirmationExpiration,
+					})
+					if err != nil {
+						return fmt.Errorf("failed to create bucket (%s): %w", selfdeletion.TokenBucket, err)
+					}
+				}
 			}
 
 			gr := runner.NewGroup()
@@ -90,6 +124,9 @@ func Server(cfg *config.Config) *cli.Command {
 					http.Metrics(mtrcs),
 					http.TraceProvider(traceProvider),
 					http.NatsKeyValue(kv),
+					http.DeltaKeyValue(deltakv),
+					http.CopyJobKeyValue(copyjobkv),
+					http.TokenKeyValue(tokenkv),
 				)
 				if err != nil {
 					logger.Error().Err(err).Str("transport", "http").Msg("Failed to initialize server")
@@ -22,6 +22,10 @@ type Config struct {
 
 	API API `yaml:"api"`
 
+	SCIM SCIM `yaml:"scim"`
+
+	SelfDeletion SelfDeletion `yaml:"self_deletion"`
+
 	Reva          *shared.Reva          `yaml:"reva"`
 	TokenManager  *TokenManager         `yaml:"token_manager"`
 	GRPCClientTLS *shared.GRPCClientTLS `yaml:"grpc_client_tls"`
@@ -56,6 +60,10 @@ type Spaces struct {
 	StorageUsersAddress             string `yaml:"storage_users_address" env:"GRAPH_SPACES_STORAGE_USERS_ADDRESS" desc:"The address of the storage-users service." introductionVersion:"1.0.0"`
 	DefaultLanguage                 string `yaml:"default_language" env:"OC_DEFAULT_LANGUAGE" desc:"The default language used by services and the WebUI. If not defined, English will be used as default. See the documentation for more details." introductionVersion:"1.0.0"`
 	TranslationPath                 string `yaml:"translation_path" env:"OC_TRANSLATION_PATH;GRAPH_TRANSLATION_PATH" desc:"(optional) Set this to a path with custom translations to overwrite the builtin translations. Note that file and folder naming rules apply, see the documentation for more details." introductionVersion:"1.0.0"`
+	// GroupQuotas hold groupid:quota mappings. These are used as the default quota of personal spaces.
+	GroupQuotas                map[string]uint64 `yaml:"group_quotas"`
+	QuotaNotificationsEnabled  bool              `yaml:"quota_notifications_enabled" env:"GRAPH_SPACES_QUOTA_NOTIFICATIONS_ENABLED" desc:"Emit an event and notify the owner or the managers of personal and project spaces when the quota state of the space changes to 'nearing', 'critical' or 'exceeded'." introductionVersion:"%%NEXT%%"`
+	QuotaNotificationsCoolDown time.Duration     `yaml:"quota_notifications_cool_down" env:"GRAPH_SPACES_QUOTA_NOTIFICATIONS_COOL_DOWN" desc:"The minimum time between two quota notifications for the same space. A notification for a more severe quota state is sent regardless. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
 }
 
 type LDAP struct {
@@ -93,6 +101,11 @@ type LDAP struct {
 	GroupIDAttribute     string `yaml:"group_id_attribute" env:"OC_LDAP_GROUP_SCHEMA_ID;GRAPH_LDAP_GROUP_ID_ATTRIBUTE" desc:"LDAP Attribute to use as the unique id for groups. This should be a stable globally unique ID like a UUID." introductionVersion:"1.0.0"`
 	GroupIDIsOctetString bool   `yaml:"group_id_is_octet_string" env:"OC_LDAP_GROUP_SCHEMA_ID_IS_OCTETSTRING;GRAPH_LDAP_GROUP_SCHEMA_ID_IS_OCTETSTRING" desc:"Set this to true if the defined 'ID' attribute for groups is of the 'OCTETSTRING' syntax. This is required when using the 'objectGUID' attribute of Active Directory for the group ID's." introductionVersion:"1.0.0"`
 
+	GroupNestingEnabled             bool          `yaml:"group_nesting_enabled" env:"OC_LDAP_GROUP_NESTING_ENABLED;GRAPH_LDAP_GROUP_NESTING_ENABLED" desc:"Resolve nested groups. Members of a group that is itself a member of another group are treated as members of the parent group as well, e.g. when listing the members of a group or the groups of a user." introductionVersion:"%%NEXT%%"`
+	GroupNestingMaxDepth            int           `yaml:"group_nesting_max_depth" env:"OC_LDAP_GROUP_NESTING_MAX_DEPTH;GRAPH_LDAP_GROUP_NESTING_MAX_DEPTH" desc:"The number of nesting levels below the direct group memberships that are resolved when 'OC_LDAP_GROUP_NESTING_ENABLED' is set." introductionVersion:"%%NEXT%%"`
+	GroupNestingMatchingRuleInChain string        `yaml:"group_nesting_matching_rule_in_chain" env:"OC_LDAP_GROUP_NESTING_MATCHING_RULE_IN_CHAIN;GRAPH_LDAP_GROUP_NESTING_MATCHING_RULE_IN_CHAIN" desc:"Use the 'LDAP_MATCHING_RULE_IN_CHAIN' of Active Directory to resolve nested groups with a single search. Supported values are 'auto', 'always' and 'never'. 'auto' uses the matching rule if the LDAP server announces itself as Active Directory." introductionVersion:"%%NEXT%%"`
+	GroupNestingCacheTTL            time.Duration `yaml:"group_nesting_cache_ttl" env:"OC_LDAP_GROUP_NESTING_CACHE_TTL;GRAPH_LDAP_GROUP_NESTING_CACHE_TTL" desc:"The time resolved nested group memberships are cached. Set to 0 to disable the cache. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+
 	EducationResourcesEnabled bool `yaml:"education_resources_enabled" env:"GRAPH_LDAP_EDUCATION_RESOURCES_ENABLED" desc:"Enable LDAP support for managing education related resources." introductionVersion:"1.0.0"`
 	EducationConfig           LDAPEducationConfig
 }
@@ -119,11 +132,29 @@ type Identity struct {
 
 // API represents API configuration parameters.
 type API struct {
-	GroupMembersPatchLimit  int    `yaml:"group_members_patch_limit" env:"GRAPH_GROUP_MEMBERS_PATCH_LIMIT" desc:"The amount of group members allowed to be added with a single patch request." introductionVersion:"1.0.0"`
-	UsernameMatch           string `yaml:"graph_username_match" env:"GRAPH_USERNAME_MATCH" desc:"Apply restrictions to usernames. Supported values are 'default' and 'none'. When set to 'default', user names must not start with a number and are restricted to ASCII characters. When set to 'none', no restrictions are applied. The default value is 'default'." introductionVersion:"1.0.0"`
-	AssignDefaultUserRole   bool   `yaml:"graph_assign_default_user_role" env:"GRAPH_ASSIGN_DEFAULT_USER_ROLE" desc:"Whether to assign newly created users the default role 'User'. Set this to 'false' if you want to assign roles manually, or if the role assignment should happen at first login. Set this to 'true' (the default) to assign the role 'User' when creating a new user." introductionVersion:"1.0.0"`
-	IdentitySearchMinLength int    `yaml:"graph_identity_search_min_length" env:"GRAPH_IDENTITY_SEARCH_MIN_LENGTH" desc:"The minimum length the search term needs to have for unprivileged users when searching for users or groups." introductionVersion:"1.0.0"`
-	ShowUserEmailInResults  bool   `yaml:"show_email_in_results" env:"OC_SHOW_USER_EMAIL_IN_RESULTS" desc:"Include user email addresses in responses. If absent or set to false emails will be omitted from results. Please note that admin users can always see all email addresses." introductionVersion:"1.0.0"`
+	GroupMembersPatchLimit  int           `yaml:"group_members_patch_limit" env:"GRAPH_GROUP_MEMBERS_PATCH_LIMIT" desc:"The amount of group members allowed to be added with a single patch request." introductionVersion:"1.0.0"`
+	UsernameMatch           string        `yaml:"graph_username_match" env:"GRAPH_USERNAME_MATCH" desc:"Apply restrictions to usernames. Supported values are 'default' and 'none'. When set to 'default', user names must not start with a number and are restricted to ASCII characters. When set to 'none', no restrictions are applied. The default value is 'default'." introductionVersion:"1.0.0"`
+	AssignDefaultUserRole   bool          `yaml:"graph_assign_default_user_role" env:"GRAPH_ASSIGN_DEFAULT_USER_ROLE" desc:"Whether to assign newly created users the default role 'User'. Set this to 'false' if you want to assign roles manually, or if the role assignment should happen at first login. Set this to 'true' (the default) to assign the role 'User' when creating a new user." introductionVersion:"1.0.0"`
+	IdentitySearchMinLength int           `yaml:"graph_identity_search_min_length" env:"GRAPH_IDENTITY_SEARCH_MIN_LENGTH" desc:"The minimum length the search term needs to have for unprivileged users when searching for users or groups." introductionVersion:"1.0.0"`
+	ShowUserEmailInResults  bool          `yaml:"show_email_in_results" env:"OC_SHOW_USER_EMAIL_IN_RESULTS" desc:"Include user email addresses in responses. If absent or set to false emails will be omitted from results. Please note that admin users can always see all email addresses." introductionVersion:"1.0.0"`
+	BatchMaxRequests        int           `yaml:"batch_max_requests" env:"GRAPH_BATCH_MAX_REQUESTS" desc:"The maximum number of requests allowed in a single JSON batch request to the '$batch' endpoint." introductionVersion:"%%NEXT%%"`
+	DeltaSnapshotTTL        time.Duration `yaml:"delta_snapshot_ttl" env:"GRAPH_DELTA_SNAPSHOT_TTL" desc:"How long the state of a folder synchronized with a delta query is kept. Folders whose state expired are listed completely by the next delta query. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+}
+
+// SCIM configures the SCIM 2.0 provisioning endpoint.
+type SCIM struct {
+	Enabled           bool   `yaml:"enabled" env:"GRAPH_SCIM_ENABLED" desc:"Enable the SCIM 2.0 endpoint at '/graph/scim/v2', which allows an external identity provider to provision users and groups." introductionVersion:"%%NEXT%%"`
+	Token             string `yaml:"token" env:"GRAPH_SCIM_TOKEN" desc:"The bearer token the identity provider needs to send to access the SCIM endpoint. It is required when the SCIM endpoint is enabled." introductionVersion:"%%NEXT%%"`
+	BulkMaxOperations int    `yaml:"bulk_max_operations" env:"GRAPH_SCIM_BULK_MAX_OPERATIONS" desc:"The maximum number of operations allowed in a single SCIM bulk request." introductionVersion:"%%NEXT%%"`
+}
+
+// SelfDeletion configures the deletion of accounts requested by the users themselves.
+type SelfDeletion struct {
+	Enabled                 bool          `yaml:"enabled" env:"GRAPH_SELF_DELETION_ENABLED" desc:"Allow users to request the deletion of their own account with the '/graph/v1.0/me/deletionRequest' endpoint. The deletion follows GRAPH_USER_SOFT_DELETE_RETENTION_TIME." introductionVersion:"%%NEXT%%"`
+	ConfirmationExpiration  time.Duration `yaml:"confirmation_expiration" env:"GRAPH_SELF_DELETION_CONFIRMATION_EXPIRATION" desc:"The time a user has to confirm the deletion request with the token sent by mail. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	ProjectSpacePolicy      string        `yaml:"project_space_policy" env:"GRAPH_SELF_DELETION_PROJECT_SPACE_POLICY" desc:"What happens to project spaces the user is the only manager of. Supported values are 'block', which refuses the deletion until the user added another manager, 'transfer', which makes the user configured in GRAPH_SELF_DELETION_PROJECT_SPACE_TRANSFER_USER_ID a manager, and 'delete', which deletes the spaces." introductionVersion:"%%NEXT%%"`
+	ProjectSpaceTransferUID string        `yaml:"project_space_transfer_user_id" env:"GRAPH_SELF_DELETION_PROJECT_SPACE_TRANSFER_USER_ID" desc:"The ID of the user who becomes the manager of the project spaces of deleted accounts when the project space policy is 'transfer'." introductionVersion:"%%NEXT%%"`
+	PurgeInterval           time.Duration `yaml:"purge_interval" env:"GRAPH_SELF_DELETION_PURGE_INTERVAL" desc:"The interval in which the accounts whose retention time has passed are purged. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
 }
 
 // Events combines the configuration options for the event bus.
@@ -169,6 +200,9 @@ type Metadata struct {
 	SystemUserID     string `yaml:"system_user_id" env:"OC_SYSTEM_USER_ID;GRAPH_SYSTEM_USER_ID" desc:"ID of the OpenCloud STORAGE-SYSTEM system user. Admins need to set the ID for the STORAGE-SYSTEM system user in this config option which is then used to reference the user. Any reasonable long string is possible, preferably this would be an UUIDv4 format." introductionVersion:"%%NEXT%%"`
 	SystemUserIDP    string `yaml:"system_user_idp" env:"OC_SYSTEM_USER_IDP;GRAPH_SYSTEM_USER_IDP" desc:"IDP of the OpenCloud STORAGE-SYSTEM system user." introductionVersion:"%%NEXT%%"`
 	SystemUserAPIKey string `yaml:"system_user_api_key" env:"OC_SYSTEM_USER_API_KEY" desc:"API key for the STORAGE-SYSTEM system user." introductionVersion:"%%NEXT%%"`
+
+	UserSharingDriver   string `yaml:"user_sharing_driver" env:"SHARING_USER_DRIVER;GRAPH_USER_SHARING_DRIVER" desc:"Driver the sharing service persists shares with. The access report can only read the shares of the 'jsoncs3' driver." introductionVersion:"%%NEXT%%"`
+	PublicSharingDriver string `yaml:"public_sharing_driver" env:"SHARING_PUBLIC_DRIVER;GRAPH_PUBLIC_SHARING_DRIVER" desc:"Driver the sharing service persists public shares with. The access report can only read the public shares of the 'jsoncs3' driver." introductionVersion:"%%NEXT%%"`
 }
 
 // Store configures the store to use
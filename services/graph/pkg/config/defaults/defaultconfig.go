@@ -9,6 +9,7 @@ import (
 	"github.com/opencloud-eu/opencloud/pkg/shared"
 	"github.com/opencloud-eu/opencloud/pkg/structs"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/unifiedrole"
 )
 
@@ -62,13 +63,24 @@ func DefaultConfig() *config.Config {
 			UsernameMatch:           "default",
 			AssignDefaultUserRole:   true,
 			IdentitySearchMinLength: 3,
+			BatchMaxRequests:        20,
+			DeltaSnapshotTTL:        30 * 24 * time.Hour,
+		},
+		SCIM: config.SCIM{
+			BulkMaxOperations: 100,
+		},
+		SelfDeletion: config.SelfDeletion{
+			ConfirmationExpiration: time.Hour,
+			ProjectSpacePolicy:     selfdeletion.ProjectSpacePolicyBlock,
+			PurgeInterval:          time.Hour,
 		},
 		Reva: shared.DefaultRevaConfig(),
 		Spaces: config.Spaces{
-			StorageUsersAddress: "eu.opencloud.api.storage-users",
-			WebDavBase:          "https://localhost:9200",
-			WebDavPath:          "/dav/spaces/",
-			DefaultQuota:        "1000000000",
+			StorageUsersAddress:        "eu.opencloud.api.storage-users",
+			WebDavBase:                 "https://localhost:9200",
+			WebDavPath:                 "/dav/spaces/",
+			DefaultQuota:               "1000000000",
+			QuotaNotificationsCoolDown: 24 * time.Hour,
 			// 1 minute
 			ExtendedSpacePropertiesCacheTTL: 60,
 			// 1 minute
@@ -108,6 +120,10 @@ func DefaultConfig() *config.Config {
 				GroupMemberAttribute:      "member",
 				GroupIDAttribute:          "openCloudUUID",
 				EducationResourcesEnabled: false,
+
+				GroupNestingMaxDepth:            10,
+				GroupNestingMatchingRuleInChain: "auto",
+				GroupNestingCacheTTL:            time.Minute,
 			},
 		},
 		Cache: &config.Cache{
@@ -129,6 +145,9 @@ func DefaultConfig() *config.Config {
 			GatewayAddress: "eu.opencloud.api.storage-system",
 			StorageAddress: "eu.opencloud.api.storage-system",
 			SystemUserIDP:  "internal",
+
+			UserSharingDriver:   "jsoncs3",
+			PublicSharingDriver: "jsoncs3",
 		},
 		UserSoftDeleteRetentionTime: 0,
 		Store: config.Store{
@@ -174,7 +193,8 @@ func EnsureDefaults(cfg *config.Config) {
 
 	if cfg.TokenManager == nil && cfg.Commons != nil && cfg.Commons.TokenManager != nil {
 		cfg.TokenManager = &config.TokenManager{
-			JWTSecret: cfg.Commons.TokenManager.JWTSecret,
+			JWTSecret:          cfg.Commons.TokenManager.JWTSecret,
+			PreviousJWTSecrets: cfg.Commons.TokenManager.PreviousJWTSecrets,
 		}
 	} else if cfg.TokenManager == nil {
 		cfg.TokenManager = &config.TokenManager{}
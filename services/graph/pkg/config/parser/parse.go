@@ -13,6 +13,7 @@ import (
 	"github.com/opencloud-eu/opencloud/pkg/shared"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/config/defaults"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/unifiedrole"
 )
 
@@ -75,6 +76,28 @@ func Validate(cfg *config.Config) error {
 			"graph", defaults2.BaseConfigPath())
 	}
 
+	if cfg.SCIM.Enabled && cfg.SCIM.Token == "" {
+		return fmt.Errorf("The SCIM endpoint is enabled for %s, but no SCIM token has been configured. "+
+			"Make sure your %s config contains the proper values "+
+			"(e.g. by setting GRAPH_SCIM_TOKEN).",
+			"graph", defaults2.BaseConfigPath())
+	}
+
+	if cfg.SelfDeletion.Enabled {
+		switch cfg.SelfDeletion.ProjectSpacePolicy {
+		case selfdeletion.ProjectSpacePolicyBlock, selfdeletion.ProjectSpacePolicyDelete:
+		case selfdeletion.ProjectSpacePolicyTransfer:
+			if cfg.SelfDeletion.ProjectSpaceTransferUID == "" {
+				return fmt.Errorf("The project space policy for self deletion is '%s', but no user to transfer the spaces to has been configured. "+
+					"Make sure your %s config contains the proper values "+
+					"(e.g. by setting GRAPH_SELF_DELETION_PROJECT_SPACE_TRANSFER_USER_ID).",
+					selfdeletion.ProjectSpacePolicyTransfer, defaults2.BaseConfigPath())
+			}
+		default:
+			return fmt.Errorf("unsupported project space policy for self deletion: '%s'", cfg.SelfDeletion.ProjectSpacePolicy)
+		}
+	}
+
 	if cfg.ServiceAccount.ServiceAccountID == "" {
 		return shared.MissingServiceAccountID(cfg.Service.Name)
 	}
@@ -58,6 +58,15 @@ type Backend interface {
 	RemoveMemberFromGroup(ctx context.Context, groupID string, memberID string) error
 }
 
+// PagingBackend is implemented by identity backends that can page through users and
+// groups on the server, so that listings don't need to load the complete result set.
+type PagingBackend interface {
+	// GetUsersPage returns at most top users after skipping the first skip users.
+	GetUsersPage(ctx context.Context, oreq *godata.GoDataRequest, skip, top int) ([]*libregraph.User, error)
+	// GetGroupsPage returns at most top groups after skipping the first skip groups.
+	GetGroupsPage(ctx context.Context, oreq *godata.GoDataRequest, skip, top int) ([]*libregraph.Group, error)
+}
+
 // EducationBackend defines the Interface for an EducationBackend implementation
 type EducationBackend interface {
 	// CreateEducationSchool creates the supplied school in the identity backend.
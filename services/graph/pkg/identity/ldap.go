@@ -16,6 +16,7 @@ import (
 	"github.com/libregraph/idm/pkg/ldapdn"
 	libregraph "github.com/opencloud-eu/libre-graph-api-go"
 
+	oldap "github.com/opencloud-eu/opencloud/pkg/ldap"
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
@@ -73,6 +74,9 @@ type LDAP struct {
 
 	educationConfig educationConfig
 
+	// nestedGroups resolves nested group memberships, it is nil if nested groups are disabled
+	nestedGroups *oldap.NestedGroupResolver
+
 	logger *log.Logger
 	conn   ldap.Client
 }
@@ -154,6 +158,22 @@ func NewLDAPBackend(lc ldap.Client, config config.LDAP, logger *log.Logger) (*LD
 		return nil, fmt.Errorf("error configuring disable user mechanism: %w", err)
 	}
 
+	var nestedGroups *oldap.NestedGroupResolver
+	if config.GroupNestingEnabled {
+		nestedGroups, err = oldap.NewNestedGroupResolver(lc, oldap.NestedGroupsConfig{
+			GroupBaseDN:         config.GroupBaseDN,
+			GroupScope:          groupScope,
+			GroupFilter:         fmt.Sprintf("(&%s(objectClass=%s))", config.GroupFilter, config.GroupObjectClass),
+			MemberAttribute:     config.GroupMemberAttribute,
+			MaxDepth:            config.GroupNestingMaxDepth,
+			MatchingRuleInChain: config.GroupNestingMatchingRuleInChain,
+			CacheTTL:            config.GroupNestingCacheTTL,
+		}, *logger)
+		if err != nil {
+			return nil, fmt.Errorf("error configuring nested groups: %w", err)
+		}
+	}
+
 	return &LDAP{
 		useServerUUID:           config.UseServerUUID,
 		usePwModifyExOp:         config.UsePasswordModExOp,
@@ -171,6 +191,7 @@ func NewLDAPBackend(lc ldap.Client, config config.LDAP, logger *log.Logger) (*LD
 		groupScope:              groupScope,
 		groupAttributeMap:       gam,
 		educationConfig:         educationConfig,
+		nestedGroups:            nestedGroups,
 		disableUserMechanism:    disableMechanismType,
 		localUserDisableGroupDN: config.LdapDisabledUsersGroupDN,
 		logger:                  logger,
@@ -233,6 +254,8 @@ func (i *LDAP) DeleteUser(ctx context.Context, nameOrID string) error {
 	if !i.writeEnabled {
 		return ErrReadOnly
 	}
+	defer i.purgeNestedGroups()
+
 	e, err := i.getLDAPUserByNameOrID(nameOrID)
 	if err != nil {
 		return err
@@ -570,7 +593,7 @@ func (i *LDAP) GetUser(ctx context.Context, nameOrID string, oreq *godata.GoData
 	}
 
 	if slices.Contains(exp, "memberOf") {
-		userGroups, err := i.getGroupsForUser(e.DN)
+		userGroups, err := i.getMemberOfGroups(e.DN)
 		if err != nil {
 			return nil, err
 		}
@@ -589,19 +612,57 @@ func (i *LDAP) FilterUsers(ctx context.Context, oreq *godata.GoDataRequest, filt
 	logger := i.logger.SubloggerWithRequestID(ctx)
 	logger.Debug().Str("backend", "ldap").Msg("GetUsers")
 
-	queryFilter, err := i.oDataFilterToLDAPFilter(filter)
+	searchRequest, exp, err := i.usersSearchRequest(oreq, filter)
 	if err != nil {
 		return nil, err
 	}
+	logger.Debug().Str("backend", "ldap").
+		Str("base", searchRequest.BaseDN).
+		Str("filter", searchRequest.Filter).
+		Int("scope", searchRequest.Scope).
+		Int("sizelimit", searchRequest.SizeLimit).
+		Interface("attributes", searchRequest.Attributes).
+		Msg("GetUsers")
+	res, err := i.conn.Search(searchRequest)
+	if err != nil {
+		return nil, i.mapListUsersError(logger, err)
+	}
 
-	search, err := odata.GetSearchValues(oreq.Query)
+	return i.usersFromLDAPEntries(res.Entries, exp)
+}
+
+// GetUsersPage implements the PagingBackend Interface. It uses the LDAP paged results
+// control to only keep a single page of entries in memory.
+func (i *LDAP) GetUsersPage(ctx context.Context, oreq *godata.GoDataRequest, skip, top int) ([]*libregraph.User, error) {
+	logger := i.logger.SubloggerWithRequestID(ctx)
+	logger.Debug().Str("backend", "ldap").Int("skip", skip).Int("top", top).Msg("GetUsersPage")
+
+	searchRequest, exp, err := i.usersSearchRequest(oreq, nil)
 	if err != nil {
 		return nil, err
 	}
+	entries, err := i.searchPage(searchRequest, skip, top)
+	if err != nil {
+		return nil, i.mapListUsersError(logger, err)
+	}
+
+	return i.usersFromLDAPEntries(entries, exp)
+}
+
+func (i *LDAP) usersSearchRequest(oreq *godata.GoDataRequest, filter *godata.ParseNode) (*ldap.SearchRequest, []string, error) {
+	queryFilter, err := i.oDataFilterToLDAPFilter(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	search, err := odata.GetSearchValues(oreq.Query)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	exp, err := odata.GetExpandValues(oreq.Query)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var userFilter string
@@ -621,25 +682,17 @@ func (i *LDAP) FilterUsers(ctx context.Context, oreq *godata.GoDataRequest, filt
 		i.getUserAttrTypesForSearch(),
 		nil,
 	)
-	logger.Debug().Str("backend", "ldap").
-		Str("base", searchRequest.BaseDN).
-		Str("filter", searchRequest.Filter).
-		Int("scope", searchRequest.Scope).
-		Int("sizelimit", searchRequest.SizeLimit).
-		Interface("attributes", searchRequest.Attributes).
-		Msg("GetUsers")
-	res, err := i.conn.Search(searchRequest)
-	if err != nil {
-		msg := "error listing users"
-		logger.Error().Err(err).Msg(msg)
-		errMap := ldapResultToErrMap{
-			ldap.LDAPResultInsufficientAccessRights: errorcode.New(errorcode.AccessDenied, msg),
-			ldapGenericErr:                          errorcode.New(errorcode.GeneralException, msg),
-		}
-		return nil, i.mapLDAPError(err, errMap)
-	}
+	return searchRequest, exp, nil
+}
 
-	return i.usersFromLDAPEntries(res.Entries, exp)
+func (i *LDAP) mapListUsersError(logger log.Logger, err error) error {
+	msg := "error listing users"
+	logger.Error().Err(err).Msg(msg)
+	errMap := ldapResultToErrMap{
+		ldap.LDAPResultInsufficientAccessRights: errorcode.New(errorcode.AccessDenied, msg),
+		ldapGenericErr:                          errorcode.New(errorcode.GeneralException, msg),
+	}
+	return i.mapLDAPError(err, errMap)
 }
 
 func (i *LDAP) usersFromLDAPEntries(entries []*ldap.Entry, exp []string) ([]*libregraph.User, error) {
@@ -661,7 +714,7 @@ func (i *LDAP) usersFromLDAPEntries(entries []*ldap.Entry, exp []string) ([]*lib
 		}
 
 		if slices.Contains(exp, "memberOf") {
-			userGroups, err := i.getGroupsForUser(e.DN)
+			userGroups, err := i.getMemberOfGroups(e.DN)
 			if err != nil {
 				return nil, err
 			}
@@ -39,7 +39,7 @@ func (i *LDAP) GetGroup(ctx context.Context, nameOrID string, queryParam url.Val
 		return nil, errorcode.New(errorcode.ItemNotFound, "not found")
 	}
 	if slices.Contains(sel, "members") || slices.Contains(exp, "members") {
-		members, err := i.expandLDAPAttributeEntries(ctx, e, i.groupAttributeMap.member, "")
+		members, err := i.expandLDAPGroupMembers(ctx, e, "")
 		if err != nil {
 			return nil, err
 		}
@@ -60,19 +60,56 @@ func (i *LDAP) GetGroups(ctx context.Context, oreq *godata.GoDataRequest) ([]*li
 	logger := i.logger.SubloggerWithRequestID(ctx)
 	logger.Debug().Str("backend", "ldap").Msg("GetGroups")
 
-	search, err := odata.GetSearchValues(oreq.Query)
+	searchRequest, expandMembers, err := i.groupsSearchRequest(oreq)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug().Str("backend", "ldap").
+		Str("base", searchRequest.BaseDN).
+		Str("filter", searchRequest.Filter).
+		Int("scope", searchRequest.Scope).
+		Int("sizelimit", searchRequest.SizeLimit).
+		Interface("attributes", searchRequest.Attributes).
+		Msg("GetGroups")
+	res, err := i.conn.Search(searchRequest)
+	if err != nil {
+		return nil, errorcode.New(errorcode.ItemNotFound, err.Error())
+	}
+
+	return i.groupsFromLDAPSearchEntries(ctx, res.Entries, expandMembers)
+}
+
+// GetGroupsPage implements the PagingBackend Interface for the LDAP Backend
+func (i *LDAP) GetGroupsPage(ctx context.Context, oreq *godata.GoDataRequest, skip, top int) ([]*libregraph.Group, error) {
+	logger := i.logger.SubloggerWithRequestID(ctx)
+	logger.Debug().Str("backend", "ldap").Int("skip", skip).Int("top", top).Msg("GetGroupsPage")
+
+	searchRequest, expandMembers, err := i.groupsSearchRequest(oreq)
 	if err != nil {
 		return nil, err
 	}
+	entries, err := i.searchPage(searchRequest, skip, top)
+	if err != nil {
+		return nil, errorcode.New(errorcode.ItemNotFound, err.Error())
+	}
+
+	return i.groupsFromLDAPSearchEntries(ctx, entries, expandMembers)
+}
+
+func (i *LDAP) groupsSearchRequest(oreq *godata.GoDataRequest) (*ldap.SearchRequest, bool, error) {
+	search, err := odata.GetSearchValues(oreq.Query)
+	if err != nil {
+		return nil, false, err
+	}
 
 	var expandMembers bool
 	exp, err := odata.GetExpandValues(oreq.Query)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	sel, err := odata.GetSelectValues(oreq.Query)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	if slices.Contains(exp, "members") || slices.Contains(sel, "members") {
@@ -104,27 +141,19 @@ func (i *LDAP) GetGroups(ctx context.Context, oreq *godata.GoDataRequest) ([]*li
 		groupAttrs,
 		nil,
 	)
-	logger.Debug().Str("backend", "ldap").
-		Str("base", searchRequest.BaseDN).
-		Str("filter", searchRequest.Filter).
-		Int("scope", searchRequest.Scope).
-		Int("sizelimit", searchRequest.SizeLimit).
-		Interface("attributes", searchRequest.Attributes).
-		Msg("GetGroups")
-	res, err := i.conn.Search(searchRequest)
-	if err != nil {
-		return nil, errorcode.New(errorcode.ItemNotFound, err.Error())
-	}
+	return searchRequest, expandMembers, nil
+}
 
-	groups := make([]*libregraph.Group, 0, len(res.Entries))
+func (i *LDAP) groupsFromLDAPSearchEntries(ctx context.Context, entries []*ldap.Entry, expandMembers bool) ([]*libregraph.Group, error) {
+	groups := make([]*libregraph.Group, 0, len(entries))
 
 	var g *libregraph.Group
-	for _, e := range res.Entries {
+	for _, e := range entries {
 		if g = i.createGroupModelFromLDAP(e); g == nil {
 			continue
 		}
 		if expandMembers {
-			members, err := i.expandLDAPAttributeEntries(ctx, e, i.groupAttributeMap.member, "")
+			members, err := i.expandLDAPGroupMembers(ctx, e, "")
 			if err != nil {
 				return nil, err
 			}
@@ -162,7 +191,7 @@ func (i *LDAP) GetGroupMembers(ctx context.Context, groupID string, req *godata.
 		return nil, err
 	}
 
-	memberEntries, err := i.expandLDAPAttributeEntries(ctx, e, i.groupAttributeMap.member, searchTerm)
+	memberEntries, err := i.expandLDAPGroupMembers(ctx, e, searchTerm)
 	result := make([]*libregraph.User, 0, len(memberEntries))
 	if err != nil {
 		return nil, err
@@ -170,7 +199,7 @@ func (i *LDAP) GetGroupMembers(ctx context.Context, groupID string, req *godata.
 	for _, member := range memberEntries {
 		if u := i.createUserModelFromLDAP(member); u != nil {
 			if slices.Contains(exp, "memberOf") {
-				userGroups, err := i.getGroupsForUser(member.DN)
+				userGroups, err := i.getMemberOfGroups(member.DN)
 				if err != nil {
 					return nil, err
 				}
@@ -193,6 +222,8 @@ func (i *LDAP) CreateGroup(ctx context.Context, group libregraph.Group) (*libreg
 	if !i.writeEnabled && i.groupCreateBaseDN == i.groupBaseDN {
 		return nil, errorcode.New(errorcode.NotAllowed, "server is configured read-only")
 	}
+	defer i.purgeNestedGroups()
+
 	ar, err := i.groupToAddRequest(group)
 	if err != nil {
 		return nil, err
@@ -224,6 +255,7 @@ func (i *LDAP) DeleteGroup(ctx context.Context, id string) error {
 	if !i.writeEnabled && i.groupCreateBaseDN == i.groupBaseDN {
 		return errorcode.New(errorcode.NotAllowed, "server is configured read-only")
 	}
+	defer i.purgeNestedGroups()
 
 	e, err := i.getLDAPGroupByID(id, false)
 	if err != nil {
@@ -248,6 +280,7 @@ func (i *LDAP) UpdateGroupName(ctx context.Context, groupID string, groupName st
 	if !i.writeEnabled && i.groupCreateBaseDN == i.groupBaseDN {
 		return errorcode.New(errorcode.NotAllowed, "server is configured read-only")
 	}
+	defer i.purgeNestedGroups()
 
 	ge, err := i.getLDAPGroupByID(groupID, true)
 	if err != nil {
@@ -294,6 +327,8 @@ func (i *LDAP) AddMembersToGroup(ctx context.Context, groupID string, memberIDs
 	if !i.writeEnabled && i.groupCreateBaseDN == i.groupBaseDN {
 		return errorcode.New(errorcode.NotAllowed, "server is configured read-only")
 	}
+	defer i.purgeNestedGroups()
+
 	ge, err := i.getLDAPGroupByNameOrID(groupID, true)
 	if err != nil {
 		return err
@@ -388,6 +423,7 @@ func (i *LDAP) RemoveMemberFromGroup(ctx context.Context, groupID string, member
 	if !i.writeEnabled && i.groupCreateBaseDN == i.groupBaseDN {
 		return errorcode.New(errorcode.NotAllowed, "server is configured read-only")
 	}
+	defer i.purgeNestedGroups()
 
 	ge, err := i.getLDAPGroupByID(groupID, true)
 	if err != nil {
@@ -558,6 +594,42 @@ func (i *LDAP) getGroupsForUser(dn string) ([]*ldap.Entry, error) {
 	return userGroups, nil
 }
 
+// getMemberOfGroups returns the groups of the entry with the given DN for the 'memberOf' property.
+// With nested groups enabled it includes the groups the direct groups are members of.
+func (i *LDAP) getMemberOfGroups(dn string) ([]*ldap.Entry, error) {
+	if i.nestedGroups == nil {
+		return i.getGroupsForUser(dn)
+	}
+	groups, err := i.nestedGroups.GroupsOf(dn, []string{i.groupAttributeMap.name, i.groupAttributeMap.id})
+	if err != nil {
+		i.logger.Debug().Str("backend", "ldap").Err(err).Str("dn", dn).Msg("failed to resolve nested groups")
+		return nil, errorcode.New(errorcode.GeneralException, "failed to resolve nested groups")
+	}
+	return groups, nil
+}
+
+// expandLDAPGroupMembers returns the user entries of the members of a group entry. With nested
+// groups enabled it includes the members of the groups that are members of the group.
+func (i *LDAP) expandLDAPGroupMembers(ctx context.Context, e *ldap.Entry, searchTerm string) ([]*ldap.Entry, error) {
+	if i.nestedGroups == nil {
+		return i.expandLDAPAttributeEntries(ctx, e, i.groupAttributeMap.member, searchTerm)
+	}
+	memberDNs, err := i.nestedGroups.MemberDNs(e)
+	if err != nil {
+		i.logger.Debug().Str("backend", "ldap").Err(err).Str("dn", e.DN).Msg("failed to resolve nested group members")
+		return nil, errorcode.New(errorcode.GeneralException, "failed to resolve nested group members")
+	}
+	members := ldap.NewEntry(e.DN, map[string][]string{i.groupAttributeMap.member: memberDNs})
+	return i.expandLDAPAttributeEntries(ctx, members, i.groupAttributeMap.member, searchTerm)
+}
+
+// purgeNestedGroups drops the cached nested group memberships after changes to groups
+func (i *LDAP) purgeNestedGroups() {
+	if i.nestedGroups != nil {
+		i.nestedGroups.Purge()
+	}
+}
+
 func (i *LDAP) createGroupModelFromLDAP(e *ldap.Entry) *libregraph.Group {
 	name := e.GetEqualFoldAttributeValue(i.groupAttributeMap.name)
 	id, err := i.ldapUUIDtoString(e, i.groupAttributeMap.id, i.groupIDisOctetString)
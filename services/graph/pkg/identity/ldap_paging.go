@@ -0,0 +1,59 @@
+package identity
+
+import (
+	"github.com/go-ldap/ldap/v3"
+)
+
+// _ldapPagingSize is the maximum number of entries requested from the LDAP server at once
+// when paging through a result set.
+const _ldapPagingSize = 500
+
+// searchPage runs the search request using the paged results control (RFC 2696) and returns
+// at most top entries after skipping the first skip entries. Only a single page of entries
+// is held in memory at a time and the search is abandoned once enough entries were read.
+func (i *LDAP) searchPage(searchRequest *ldap.SearchRequest, skip, top int) ([]*ldap.Entry, error) {
+	pagingSize := skip + top
+	if pagingSize > _ldapPagingSize || pagingSize <= 0 {
+		pagingSize = _ldapPagingSize
+	}
+	paging := ldap.NewControlPaging(uint32(pagingSize))
+	searchRequest.Controls = append(searchRequest.Controls, paging)
+
+	entries := make([]*ldap.Entry, 0, top)
+	for {
+		res, err := i.conn.Search(searchRequest)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range res.Entries {
+			if skip > 0 {
+				skip--
+				continue
+			}
+			entries = append(entries, e)
+		}
+
+		var cookie []byte
+		if ctrl, ok := ldap.FindControl(res.Controls, ldap.ControlTypePaging).(*ldap.ControlPaging); ok {
+			cookie = ctrl.Cookie
+		}
+		if len(cookie) == 0 {
+			break
+		}
+		paging.SetCookie(cookie)
+
+		if len(entries) >= top {
+			// a paging size of zero tells the server to release the result set
+			paging.PagingSize = 0
+			if _, err := i.conn.Search(searchRequest); err != nil {
+				i.logger.Debug().Err(err).Str("backend", "ldap").Msg("could not abandon paged search")
+			}
+			break
+		}
+	}
+
+	if len(entries) > top {
+		entries = entries[:top]
+	}
+	return entries, nil
+}
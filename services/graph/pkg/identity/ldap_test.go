@@ -312,6 +312,61 @@ func TestGetUsersSearch(t *testing.T) {
 	}
 }
 
+func TestGetUsersPage(t *testing.T) {
+	entries := make([]*ldap.Entry, 0, 5)
+	for n := 0; n < 5; n++ {
+		entries = append(entries, ldap.NewEntry(fmt.Sprintf("uid=user%d", n),
+			map[string][]string{
+				"uid":         {fmt.Sprintf("user%d", n)},
+				"displayname": {fmt.Sprintf("User %d", n)},
+				"entryuuid":   {fmt.Sprintf("id-%d", n)},
+			}))
+	}
+
+	var abandoned bool
+	lm := &mocks.Client{}
+	lm.On("Search", mock.Anything).Return(func(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+		paging, ok := ldap.FindControl(req.Controls, ldap.ControlTypePaging).(*ldap.ControlPaging)
+		if !ok {
+			return nil, errors.New("expected a paging control")
+		}
+		if paging.PagingSize == 0 {
+			abandoned = true
+			return &ldap.SearchResult{}, nil
+		}
+		var start int
+		if len(paging.Cookie) > 0 {
+			fmt.Sscan(string(paging.Cookie), &start)
+		}
+		end := min(start+int(paging.PagingSize), len(entries))
+		res := &ldap.SearchResult{Entries: entries[start:end]}
+		cookie := ldap.NewControlPaging(paging.PagingSize)
+		if end < len(entries) {
+			cookie.SetCookie([]byte(fmt.Sprint(end)))
+		}
+		res.Controls = append(res.Controls, cookie)
+		return res, nil
+	})
+
+	odataReq, err := godata.ParseRequest(context.Background(), "", url.Values{})
+	assert.NoError(t, err)
+	b, _ := getMockedBackend(lm, lconfig, &logger)
+
+	users, err := b.GetUsersPage(context.Background(), odataReq, 1, 2)
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, "id-1", users[0].GetId())
+	assert.Equal(t, "id-2", users[1].GetId())
+	assert.True(t, abandoned)
+
+	abandoned = false
+	users, err = b.GetUsersPage(context.Background(), odataReq, 4, 2)
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, "id-4", users[0].GetId())
+	assert.False(t, abandoned)
+}
+
 func TestUpdateUser(t *testing.T) {
 	falseBool := false
 	trueBool := true
@@ -9,10 +9,10 @@ import (
 	"github.com/opencloud-eu/opencloud/pkg/account"
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	opkgm "github.com/opencloud-eu/opencloud/pkg/middleware"
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
 	"github.com/opencloud-eu/reva/v2/pkg/auth/scope"
 	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
-	"github.com/opencloud-eu/reva/v2/pkg/token/manager/jwt"
 )
 
 // authOptions initializes the available default options.
@@ -32,10 +32,9 @@ func Auth(opts ...account.Option) func(http.Handler) http.Handler {
 	// Note: This largely duplicates what pkg/middleware/account.go already does (apart from a slightly different error
 	// handling). Ideally we should merge both middlewares.
 	opt := authOptions(opts...)
-	tokenManager, err := jwt.New(map[string]interface{}{
-		"secret":  opt.JWTSecret,
-		"expires": int64(24 * 60 * 60),
-	})
+	tokenManagerConfig := secrets.TokenManagerConfig(opt.JWTSecret, opt.PreviousJWTSecrets)
+	tokenManagerConfig["expires"] = int64(24 * 60 * 60)
+	tokenManager, err := secrets.NewTokenManager(tokenManagerConfig)
 	if err != nil {
 		opt.Logger.Fatal().Err(err).Msgf("Could not initialize token-manager")
 	}
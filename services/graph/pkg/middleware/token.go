@@ -13,13 +13,27 @@ var (
 	ErrInvalidToken = "invalid or missing token"
 )
 
-// Token provides a middleware to check access secured by a static token.
+// Token provides a middleware to check access secured by a static token. Requests are not checked
+// when no token is configured.
 func Token(token string) func(http.Handler) http.Handler {
-	h := sha256.New()
-	requiredTokenHash := h.Sum(([]byte("Bearer " + token)))
+	return checkToken(token, false)
+}
+
+// RequireToken provides a middleware to check access secured by a static token. Unlike Token all
+// requests are rejected when no token is configured.
+func RequireToken(token string) func(http.Handler) http.Handler {
+	return checkToken(token, true)
+}
+
+func checkToken(token string, required bool) func(http.Handler) http.Handler {
+	requiredTokenHash := sha256.Sum256([]byte("Bearer " + token))
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if token == "" {
+				if required {
+					errorcode.InvalidAuthenticationToken.Render(w, r, http.StatusUnauthorized, ErrInvalidToken)
+					return
+				}
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -31,10 +45,10 @@ func Token(token string) func(http.Handler) http.Handler {
 				return
 			}
 
-			h = sha256.New()
-			providedTokenHash := h.Sum([]byte(header))
+			// compare the digests so the comparison does not depend on the length of the token
+			providedTokenHash := sha256.Sum256([]byte(header))
 
-			if subtle.ConstantTimeCompare(requiredTokenHash, providedTokenHash) == 0 {
+			if subtle.ConstantTimeCompare(requiredTokenHash[:], providedTokenHash[:]) == 0 {
 				errorcode.InvalidAuthenticationToken.Render(w, r, http.StatusUnauthorized, ErrInvalidToken)
 				return
 			}
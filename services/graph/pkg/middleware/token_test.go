@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 )
 
@@ -47,3 +48,55 @@ func TestToken(t *testing.T) {
 	}
 
 }
+
+func TestRequireToken(t *testing.T) {
+	dh := dummyHandler{}
+	handler := RequireToken("")(dh)
+
+	for _, header := range []string{"", "Bearer ", "Bearer anything"} {
+		req, err := http.NewRequest("GET", "/token-protected", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		// Requests must be rejected when no token is configured.
+		if status := rr.Code; status != http.StatusUnauthorized {
+			t.Errorf("handler returned wrong status code for %q: got %v want %v",
+				header, status, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestTokenConcurrentRequests(t *testing.T) {
+	dh := dummyHandler{}
+	handler := Token("test-api-key")(dh)
+
+	var wg sync.WaitGroup
+	for _, header := range []string{"Bearer test-api-key", "Bearer wrong", "Bearer test-api-key", "Bearer test-api-keyx"} {
+		want := http.StatusUnauthorized
+		if header == "Bearer test-api-key" {
+			want = http.StatusOK
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "/token-protected", nil)
+			req.Header.Set("Authorization", header)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != want {
+				t.Errorf("handler returned wrong status code for %q: got %v want %v",
+					header, status, want)
+			}
+		}()
+	}
+	wg.Wait()
+}
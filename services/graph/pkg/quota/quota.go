@@ -0,0 +1,81 @@
+package quota
+
+import (
+	"encoding/json"
+	"slices"
+
+	group "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+// The quota states of a drive, ordered by severity
+const (
+	StateNormal   = "normal"
+	StateNearing  = "nearing"
+	StateCritical = "critical"
+	StateExceeded = "exceeded"
+)
+
+var _states = []string{StateNormal, StateNearing, StateCritical, StateExceeded}
+
+// State returns the quota state for the given number of used bytes of the total bytes
+func State(total int64, used int64) string {
+	percent := (float64(used) / float64(total)) * 100
+
+	switch {
+	case percent <= float64(75):
+		return StateNormal
+	case percent <= float64(90):
+		return StateNearing
+	case percent <= float64(99):
+		return StateCritical
+	default:
+		return StateExceeded
+	}
+}
+
+// Severity returns the position of the state in the order of severity, unknown states are
+// less severe than the normal state
+func Severity(state string) int {
+	return slices.Index(_states, state)
+}
+
+// DefaultForGroups returns the largest default quota of the given groups and false if none
+// of the groups has a default quota
+func DefaultForGroups(groupQuotas map[string]uint64, groupIDs []string) (uint64, bool) {
+	var (
+		quota uint64
+		found bool
+	)
+	for _, id := range groupIDs {
+		if q, ok := groupQuotas[id]; ok && (!found || q > quota) {
+			quota, found = q, true
+		}
+	}
+	return quota, found
+}
+
+// SpaceQuotaStateChanged is emitted when the quota state of a personal or project space changed.
+// The managers are only set for project spaces.
+type SpaceQuotaStateChanged struct {
+	SpaceID       *provider.StorageSpaceId
+	SpaceName     string
+	SpaceType     string
+	Owner         *user.UserId
+	Managers      []*user.UserId
+	ManagerGroups []*group.GroupId
+	PreviousState string
+	State         string
+	Used          int64
+	Total         int64
+	Timestamp     *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (SpaceQuotaStateChanged) Unmarshal(v []byte) (interface{}, error) {
+	e := SpaceQuotaStateChanged{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
@@ -0,0 +1,39 @@
+package quota_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/quota"
+)
+
+func TestState(t *testing.T) {
+	assert.Equal(t, quota.StateNormal, quota.State(100, 75))
+	assert.Equal(t, quota.StateNearing, quota.State(100, 76))
+	assert.Equal(t, quota.StateCritical, quota.State(100, 91))
+	assert.Equal(t, quota.StateExceeded, quota.State(100, 100))
+	assert.Equal(t, quota.StateExceeded, quota.State(100, 120))
+}
+
+func TestSeverity(t *testing.T) {
+	assert.Less(t, quota.Severity("unknown"), quota.Severity(quota.StateNormal))
+	assert.Less(t, quota.Severity(quota.StateNormal), quota.Severity(quota.StateNearing))
+	assert.Less(t, quota.Severity(quota.StateNearing), quota.Severity(quota.StateCritical))
+	assert.Less(t, quota.Severity(quota.StateCritical), quota.Severity(quota.StateExceeded))
+}
+
+func TestDefaultForGroups(t *testing.T) {
+	groupQuotas := map[string]uint64{"students": 1000, "staff": 5000}
+
+	q, ok := quota.DefaultForGroups(groupQuotas, []string{"students", "staff", "other"})
+	assert.True(t, ok)
+	assert.Equal(t, uint64(5000), q)
+
+	_, ok = quota.DefaultForGroups(groupQuotas, []string{"other"})
+	assert.False(t, ok)
+
+	q, ok = quota.DefaultForGroups(map[string]uint64{"unlimited": 0}, []string{"unlimited"})
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0), q)
+}
@@ -0,0 +1,104 @@
+// Package selfdeletion contains the events of the account deletion requested by the users themselves.
+package selfdeletion
+
+import (
+	"encoding/json"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+// TokenBucket is the nats key value bucket the confirmation tokens are handed to the notifications service
+// in. The tokens are not put on the event bus, the notifications service removes them once the mail was sent.
+const TokenBucket = "selfdeletion-tokens"
+
+// The policies for project spaces which would be left without a manager
+const (
+	// ProjectSpacePolicyBlock refuses the deletion until the user handed over the spaces
+	ProjectSpacePolicyBlock = "block"
+	// ProjectSpacePolicyTransfer makes the configured user a manager of the spaces
+	ProjectSpacePolicyTransfer = "transfer"
+	// ProjectSpacePolicyDelete deletes the spaces along with the account
+	ProjectSpacePolicyDelete = "delete"
+)
+
+// AccountDeletionRequested is emitted when a user requested the deletion of the own account. The
+// notifications service looks up the confirmation token by its id in the TokenBucket and sends it to the user.
+type AccountDeletionRequested struct {
+	UserID              *user.UserId
+	UserDisplayName     string
+	UserMail            string
+	ConfirmationTokenID string
+	ExpiresAt           *types.Timestamp
+	Timestamp           *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (AccountDeletionRequested) Unmarshal(v []byte) (interface{}, error) {
+	e := AccountDeletionRequested{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// AccountDeletionCancelled is emitted when a user cancelled a pending deletion request
+type AccountDeletionCancelled struct {
+	UserID    *user.UserId
+	Timestamp *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (AccountDeletionCancelled) Unmarshal(v []byte) (interface{}, error) {
+	e := AccountDeletionCancelled{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// AccountDeletionConfirmed is emitted when a user confirmed the deletion of the own account. The account
+// is disabled and its personal data is purged at PurgeAt.
+type AccountDeletionConfirmed struct {
+	UserID            *user.UserId
+	UserDisplayName   string
+	UserMail          string
+	PurgeAt           *types.Timestamp
+	TransferredSpaces []string
+	DeletedSpaces     []string
+	Timestamp         *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (AccountDeletionConfirmed) Unmarshal(v []byte) (interface{}, error) {
+	e := AccountDeletionConfirmed{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// PersonalFilesPurged is emitted while a self deleted account is purged, once for every chunk of files in
+// the personal space. The thumbnails service removes the thumbnails of the files.
+type PersonalFilesPurged struct {
+	UserID    *user.UserId
+	Checksums []string
+	Timestamp *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (PersonalFilesPurged) Unmarshal(v []byte) (interface{}, error) {
+	e := PersonalFilesPurged{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// AccountPurged is emitted when a self deleted account was removed for good. All services remove the
+// personal data they hold for the user or the personal space.
+type AccountPurged struct {
+	UserID          *user.UserId
+	PersonalSpaceID *provider.StorageSpaceId
+	Timestamp       *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (AccountPurged) Unmarshal(v []byte) (interface{}, error) {
+	e := AccountPurged{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
@@ -17,14 +17,17 @@ type Option func(o *Options)
 
 // Options defines the available options for this package.
 type Options struct {
-	Logger        log.Logger
-	Context       context.Context
-	Config        *config.Config
-	Metrics       *metrics.Metrics
-	Flags         []cli.Flag
-	Namespace     string
-	TraceProvider trace.TracerProvider
-	NatsKeyValue  jetstream.KeyValue
+	Logger          log.Logger
+	Context         context.Context
+	Config          *config.Config
+	Metrics         *metrics.Metrics
+	Flags           []cli.Flag
+	Namespace       string
+	TraceProvider   trace.TracerProvider
+	NatsKeyValue    jetstream.KeyValue
+	DeltaKeyValue   jetstream.KeyValue
+	TokenKeyValue   jetstream.KeyValue
+	CopyJobKeyValue jetstream.KeyValue
 }
 
 // newOptions initializes the available default options.
@@ -93,3 +96,24 @@ func NatsKeyValue(val jetstream.KeyValue) Option {
 		o.NatsKeyValue = val
 	}
 }
+
+// DeltaKeyValue provides a function to set the DeltaKeyValue option.
+func DeltaKeyValue(val jetstream.KeyValue) Option {
+	return func(o *Options) {
+		o.DeltaKeyValue = val
+	}
+}
+
+// CopyJobKeyValue provides a function to set the CopyJobKeyValue option.
+func CopyJobKeyValue(val jetstream.KeyValue) Option {
+	return func(o *Options) {
+		o.CopyJobKeyValue = val
+	}
+}
+
+// TokenKeyValue provides a function to set the TokenKeyValue option.
+func TokenKeyValue(val jetstream.KeyValue) Option {
+	return func(o *Options) {
+		o.TokenKeyValue = val
+	}
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/opencloud-eu/opencloud/pkg/account"
 	"github.com/opencloud-eu/opencloud/pkg/cors"
+	"github.com/opencloud-eu/opencloud/pkg/eventstream"
 	"github.com/opencloud-eu/opencloud/pkg/generators"
 	"github.com/opencloud-eu/opencloud/pkg/keycloak"
 	"github.com/opencloud-eu/opencloud/pkg/middleware"
@@ -65,6 +66,7 @@ func Server(opts ...Option) (http.Service, error) {
 				Msg("Error initializing events publisher")
 			return http.Service{}, fmt.Errorf("could not initialize events publisher: %w", err)
 		}
+		eventsStream = eventstream.Typed(eventsStream)
 	}
 
 	middlewares := []func(stdhttp.Handler) stdhttp.Handler{
@@ -99,6 +101,7 @@ func Server(opts ...Option) (http.Service, error) {
 			graphMiddleware.Auth(
 				account.Logger(options.Logger),
 				account.JWTSecret(options.Config.TokenManager.JWTSecret),
+				account.PreviousJWTSecrets(options.Config.TokenManager.PreviousJWTSecrets),
 			))
 		roleService = settingssvc.NewRoleService("eu.opencloud.api.settings", grpcClient)
 		valueService = settingssvc.NewValueService("eu.opencloud.api.settings", grpcClient)
@@ -134,8 +137,9 @@ func Server(opts ...Option) (http.Service, error) {
 	hClient := ehsvc.NewEventHistoryService("eu.opencloud.api.eventhistory", grpcClient)
 
 	var userProfilePhotoService svc.UsersUserProfilePhotoProvider
+	var spaceTemplatesService svc.SpaceTemplatesProvider
 	{
-		photoStorage, err := revaMetadata.NewCS3Storage(
+		metadataStorage, err := revaMetadata.NewCS3Storage(
 			options.Config.Metadata.GatewayAddress,
 			options.Config.Metadata.StorageAddress,
 			options.Config.Metadata.SystemUserID,
@@ -146,25 +150,41 @@ func Server(opts ...Option) (http.Service, error) {
 			return http.Service{}, fmt.Errorf("could not initialize reva metadata storage: %w", err)
 		}
 
-		photoStorage, err = metadata.NewLazyStorage(photoStorage)
+		metadataStorage, err = metadata.NewLazyStorage(metadataStorage)
 		if err != nil {
 			return http.Service{}, fmt.Errorf("could not initialize lazy metadata storage: %w", err)
 		}
 
-		if err := photoStorage.Init(context.Background(), "f2bdd61a-da7c-49fc-8203-0558109d1b4f"); err != nil {
+		if err := metadataStorage.Init(context.Background(), "f2bdd61a-da7c-49fc-8203-0558109d1b4f"); err != nil {
 			return http.Service{}, fmt.Errorf("could not initialize metadata storage: %w", err)
 		}
 
-		userProfilePhotoService, err = svc.NewUsersUserProfilePhotoService(photoStorage)
+		userProfilePhotoService, err = svc.NewUsersUserProfilePhotoService(metadataStorage)
 		if err != nil {
 			return http.Service{}, fmt.Errorf("could not initialize user profile photo service: %w", err)
 		}
+
+		spaceTemplatesService, err = svc.NewSpaceTemplatesService(metadataStorage)
+		if err != nil {
+			return http.Service{}, fmt.Errorf("could not initialize space templates service: %w", err)
+		}
+	}
+
+	shareIndex, err := svc.NewShareIndex(options.Config.Metadata)
+	switch {
+	case errors.Is(err, svc.ErrShareIndexNotSupported):
+		// the access report is not available without the index
+		options.Logger.Warn().Err(err).Msg("the access report is disabled")
+	case err != nil:
+		return http.Service{}, fmt.Errorf("could not initialize share index: %w", err)
 	}
 
 	var handle svc.Service
 	handle, err = svc.NewService(
 		svc.Context(options.Context),
 		svc.UserProfilePhotoService(userProfilePhotoService),
+		svc.WithSpaceTemplatesService(spaceTemplatesService),
+		svc.WithShareIndex(shareIndex),
 		svc.Logger(options.Logger),
 		svc.Config(options.Config),
 		svc.Middleware(middlewares...),
@@ -179,6 +199,9 @@ func Server(opts ...Option) (http.Service, error) {
 		svc.EventHistoryClient(hClient),
 		svc.TraceProvider(options.TraceProvider),
 		svc.WithNatsKeyValue(options.NatsKeyValue),
+		svc.WithDeltaKeyValue(options.DeltaKeyValue),
+		svc.WithCopyJobKeyValue(options.CopyJobKeyValue),
+		svc.WithTokenKeyValue(options.TokenKeyValue),
 	)
 
 	if err != nil {
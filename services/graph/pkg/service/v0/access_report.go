@@ -0,0 +1,535 @@
+package svc
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/go-chi/render"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/identity"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/linktype"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/unifiedrole"
+	settingsServiceExt "github.com/opencloud-eu/opencloud/services/settings/pkg/store/defaults"
+)
+
+const (
+	// AccessTypeOwner is the access of the owner of a personal space
+	AccessTypeOwner = "owner"
+	// AccessTypeMember is the access of a space member
+	AccessTypeMember = "member"
+	// AccessTypeShare is the access granted by a user or group share
+	AccessTypeShare = "share"
+	// AccessTypeLink is the access granted by a public link
+	AccessTypeLink = "link"
+
+	// AccessReportFormatJSON renders the access report as JSON
+	AccessReportFormatJSON = "json"
+	// AccessReportFormatCSV renders the access report as CSV
+	AccessReportFormatCSV = "csv"
+
+	_principalTypeUser  = "user"
+	_principalTypeGroup = "group"
+)
+
+// _accessReportCSVHeader is the first line of a CSV access report, it matches the json names of the AccessReportEntry
+var _accessReportCSVHeader = []string{
+	"spaceId", "spaceName", "spaceType", "resourceId", "resourcePath", "accessType",
+	"principalType", "principalId", "principalName", "roleId", "linkType",
+	"expirationDateTime", "passwordProtected", "createdById", "createdByName", "createdDateTime",
+}
+
+// AccessReportFilter restricts the entries of an access report. Empty fields do not filter.
+type AccessReportFilter struct {
+	// UserID matches entries granted to the user, directly or through one of the user's groups
+	UserID string
+	// GroupID matches entries granted to the group
+	GroupID string
+	// SpaceID matches entries of the space
+	SpaceID string
+	// LinkType matches public links of the given libregraph link type
+	LinkType string
+}
+
+// AccessReportEntry describes one way to access a space or a resource in a space
+type AccessReportEntry struct {
+	SpaceID            string     `json:"spaceId"`
+	SpaceName          string     `json:"spaceName"`
+	SpaceType          string     `json:"spaceType"`
+	ResourceID         string     `json:"resourceId"`
+	ResourcePath       string     `json:"resourcePath"`
+	AccessType         string     `json:"accessType"`
+	PrincipalType      string     `json:"principalType,omitempty"`
+	PrincipalID        string     `json:"principalId,omitempty"`
+	PrincipalName      string     `json:"principalName,omitempty"`
+	RoleID             string     `json:"roleId,omitempty"`
+	LinkType           string     `json:"linkType,omitempty"`
+	ExpirationDateTime *time.Time `json:"expirationDateTime,omitempty"`
+	PasswordProtected  bool       `json:"passwordProtected"`
+	CreatedByID        string     `json:"createdById,omitempty"`
+	CreatedByName      string     `json:"createdByName,omitempty"`
+	CreatedDateTime    *time.Time `json:"createdDateTime,omitempty"`
+}
+
+// AccessReporter builds access reports from the space grants and the indexes of the share managers.
+// All requests are made as the service account.
+type AccessReporter struct {
+	BaseGraphService
+	shareIndex ShareIndex
+}
+
+// NewAccessReporter returns an AccessReporter for usage outside the graph http service, e.g. in the cli.
+func NewAccessReporter(logger log.Logger, gatewaySelector pool.Selectable[gateway.GatewayAPIClient], cfg *config.Config) (AccessReporter, error) {
+	shareIndex, err := NewShareIndex(cfg.Metadata)
+	if err != nil {
+		return AccessReporter{}, err
+	}
+	return AccessReporter{
+		BaseGraphService: BaseGraphService{
+			logger:          &logger,
+			gatewaySelector: gatewaySelector,
+			identityCache: identity.NewIdentityCache(
+				identity.IdentityCacheWithGatewaySelector(gatewaySelector),
+				identity.IdentityCacheWithUsersTTL(time.Duration(cfg.Spaces.UsersCacheTTL)),
+				identity.IdentityCacheWithGroupsTTL(time.Duration(cfg.Spaces.GroupsCacheTTL)),
+			),
+			config:         cfg,
+			availableRoles: unifiedrole.GetRoles(unifiedrole.RoleFilterIDs(cfg.UnifiedRoles.AvailableRoles...)),
+		},
+		shareIndex: shareIndex,
+	}, nil
+}
+
+// Report builds the access report for all personal and project spaces matching the filter.
+// The entries are sorted by space, resource path and access type.
+func (a AccessReporter) Report(ctx context.Context, filter AccessReportFilter) ([]AccessReportEntry, error) {
+	if a.shareIndex == nil {
+		return nil, errorcode.New(errorcode.NotSupported, "the access report requires the jsoncs3 sharing drivers")
+	}
+
+	gatewayClient, err := a.gatewaySelector.Next()
+	if err != nil {
+		return nil, errorcode.New(errorcode.GeneralException, err.Error())
+	}
+
+	token, err := utils.GetServiceUserToken(ctx, gatewayClient, a.config.ServiceAccount.ServiceAccountID, a.config.ServiceAccount.ServiceAccountSecret)
+	if err != nil {
+		return nil, errorcode.New(errorcode.GeneralException, "could not authenticate the service account: "+err.Error())
+	}
+	ctx = withAccessToken(ctx, token)
+
+	userGroups := map[string]struct{}{}
+	if filter.UserID != "" {
+		res, err := gatewayClient.GetUserGroups(ctx, &userpb.GetUserGroupsRequest{UserId: &userpb.UserId{OpaqueId: filter.UserID}})
+		if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+			return nil, errCode
+		}
+		for _, groupID := range res.GetGroups() {
+			userGroups[groupID] = struct{}{}
+		}
+	}
+
+	spaces, err := a.listReportSpaces(ctx, gatewayClient, filter.SpaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &accessReport{
+		filter:     filter,
+		userGroups: userGroups,
+		spaces:     make(map[string]*storageprovider.StorageSpace, len(spaces)),
+		paths:      map[string]string{},
+	}
+	for _, space := range spaces {
+		report.spaces[space.GetId().GetOpaqueId()] = space
+		a.addSpaceMembers(ctx, report, space)
+		if err := a.addSpaceShares(ctx, gatewayClient, report, space); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.addPublicLinks(ctx, gatewayClient, report); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(report.entries, func(x, y AccessReportEntry) int {
+		return strings.Compare(
+			strings.Join([]string{x.SpaceName, x.SpaceID, x.ResourcePath, x.AccessType, x.PrincipalName, x.PrincipalID}, "\x00"),
+			strings.Join([]string{y.SpaceName, y.SpaceID, y.ResourcePath, y.AccessType, y.PrincipalName, y.PrincipalID}, "\x00"),
+		)
+	})
+	return report.entries, nil
+}
+
+// accessReport holds the state while building a report
+type accessReport struct {
+	filter     AccessReportFilter
+	userGroups map[string]struct{}
+	// spaces by storage space id
+	spaces map[string]*storageprovider.StorageSpace
+	// paths caches the path of resources by resource id
+	paths   map[string]string
+	entries []AccessReportEntry
+}
+
+// add appends the entry if it matches the filter
+func (r *accessReport) add(entry AccessReportEntry) {
+	f := r.filter
+	if f.SpaceID != "" && f.SpaceID != entry.SpaceID {
+		return
+	}
+	if f.LinkType != "" && (entry.AccessType != AccessTypeLink || entry.LinkType != f.LinkType) {
+		return
+	}
+	if f.GroupID != "" && (entry.PrincipalType != _principalTypeGroup || entry.PrincipalID != f.GroupID) {
+		return
+	}
+	if f.UserID != "" {
+		switch entry.PrincipalType {
+		case _principalTypeUser:
+			if entry.PrincipalID != f.UserID {
+				return
+			}
+		case _principalTypeGroup:
+			if _, ok := r.userGroups[entry.PrincipalID]; !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+	r.entries = append(r.entries, entry)
+}
+
+// listReportSpaces lists all personal and project spaces or the space with the given id
+func (a AccessReporter) listReportSpaces(ctx context.Context, gatewayClient gateway.GatewayAPIClient, spaceID string) ([]*storageprovider.StorageSpace, error) {
+	var filters []*storageprovider.ListStorageSpacesRequest_Filter
+	if spaceID != "" {
+		filters = append(filters, &storageprovider.ListStorageSpacesRequest_Filter{
+			Type: storageprovider.ListStorageSpacesRequest_Filter_TYPE_ID,
+			Term: &storageprovider.ListStorageSpacesRequest_Filter_Id{Id: &storageprovider.StorageSpaceId{OpaqueId: spaceID}},
+		})
+	}
+	all, err := listSpacesUnrestricted(ctx, gatewayClient, filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	spaces := make([]*storageprovider.StorageSpace, 0, len(all))
+	for _, space := range all {
+		switch space.GetSpaceType() {
+		case _spaceTypePersonal, _spaceTypeProject:
+			spaces = append(spaces, space)
+		}
+	}
+	return spaces, nil
+}
+
+// listSpacesUnrestricted lists the spaces matching the filters regardless of the memberships of the
+// authenticated user, which needs the permission to list all spaces
+func listSpacesUnrestricted(ctx context.Context, gatewayClient gateway.GatewayAPIClient, filters ...*storageprovider.ListStorageSpacesRequest_Filter) ([]*storageprovider.StorageSpace, error) {
+	value, err := json.Marshal(map[string]struct{}{settingsServiceExt.ListSpacesPermission(0).Name: {}})
+	if err != nil {
+		return nil, errorcode.New(errorcode.GeneralException, err.Error())
+	}
+	res, err := gatewayClient.ListStorageSpaces(ctx, &storageprovider.ListStorageSpacesRequest{
+		Opaque: &types.Opaque{Map: map[string]*types.OpaqueEntry{
+			"permissions":  {Decoder: "json", Value: value},
+			"unrestricted": {Decoder: "plain", Value: []byte(strconv.FormatBool(true))},
+		}},
+		Filters: filters,
+	})
+	if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+		return nil, errCode
+	}
+	return res.GetStorageSpaces(), nil
+}
+
+// addSpaceMembers adds the owner and the members of a space to the report
+func (a AccessReporter) addSpaceMembers(ctx context.Context, report *accessReport, space *storageprovider.StorageSpace) {
+	logger := a.logger.SubloggerWithRequestID(ctx)
+	var (
+		grants      map[string]*storageprovider.ResourcePermissions
+		expirations map[string]*types.Timestamp
+		groups      map[string]struct{}
+	)
+	for key, target := range map[string]any{"grants": &grants, "grants_expirations": &expirations, "groups": &groups} {
+		entry, ok := space.GetOpaque().GetMap()[key]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(entry.GetValue(), target); err != nil {
+			logger.Debug().Err(err).Str("space", space.GetId().GetOpaqueId()).Str("key", key).Msg("access report: could not read space opaque")
+		}
+	}
+
+	base := AccessReportEntry{
+		SpaceID:      space.GetId().GetOpaqueId(),
+		SpaceName:    space.GetName(),
+		SpaceType:    space.GetSpaceType(),
+		ResourceID:   storagespace.FormatResourceID(space.GetRoot()),
+		ResourcePath: "/",
+		AccessType:   AccessTypeMember,
+	}
+
+	if space.GetSpaceType() == _spaceTypePersonal && space.GetOwner().GetId() != nil {
+		ownerID := space.GetOwner().GetId().GetOpaqueId()
+		if _, ok := grants[ownerID]; !ok {
+			entry := base
+			entry.AccessType = AccessTypeOwner
+			entry.PrincipalType = _principalTypeUser
+			entry.PrincipalID = ownerID
+			entry.PrincipalName = a.userName(ctx, space.GetOwner().GetId())
+			entry.RoleID = unifiedrole.UnifiedRoleManagerID
+			report.add(entry)
+		}
+	}
+
+	for id, permissions := range grants {
+		entry := base
+		entry.PrincipalID = id
+		entry.ExpirationDateTime = timestampToTime(expirations[id])
+		if role := unifiedrole.CS3ResourcePermissionsToRole(a.availableRoles, permissions, unifiedrole.UnifiedRoleConditionDrive, false); role != nil {
+			entry.RoleID = role.GetId()
+		}
+
+		if _, ok := groups[id]; ok {
+			entry.PrincipalType = _principalTypeGroup
+			if group, err := groupIdToIdentity(ctx, a.identityCache, id); err == nil {
+				entry.PrincipalName = group.GetDisplayName()
+			}
+		} else {
+			entry.PrincipalType = _principalTypeUser
+			entry.PrincipalName = a.userName(ctx, &userpb.UserId{OpaqueId: id})
+		}
+		report.add(entry)
+	}
+}
+
+// addSpaceShares adds the user and group shares on resources of the space to the report
+func (a AccessReporter) addSpaceShares(ctx context.Context, gatewayClient gateway.GatewayAPIClient, report *accessReport, space *storageprovider.StorageSpace) error {
+	shares, err := a.shareIndex.ListSpaceShares(ctx, space.GetRoot().GetStorageId(), space.GetRoot().GetSpaceId())
+	if err != nil {
+		return errorcode.New(errorcode.GeneralException, "could not list the shares of space "+space.GetId().GetOpaqueId()+": "+err.Error())
+	}
+	for _, s := range shares {
+		entry, ok := a.newResourceEntry(ctx, gatewayClient, report, s.GetResourceId())
+		if !ok {
+			continue
+		}
+		entry.AccessType = AccessTypeShare
+		entry.ExpirationDateTime = timestampToTime(s.GetExpiration())
+		entry.CreatedByID = s.GetCreator().GetOpaqueId()
+		entry.CreatedByName = a.userName(ctx, s.GetCreator())
+		entry.CreatedDateTime = timestampToTime(s.GetCtime())
+		entry.RoleID = a.shareRoleID(s.GetPermissions().GetPermissions())
+
+		switch grantee := s.GetGrantee(); grantee.GetType() {
+		case storageprovider.GranteeType_GRANTEE_TYPE_GROUP:
+			entry.PrincipalType = _principalTypeGroup
+			entry.PrincipalID = grantee.GetGroupId().GetOpaqueId()
+			if group, err := groupIdToIdentity(ctx, a.identityCache, entry.PrincipalID); err == nil {
+				entry.PrincipalName = group.GetDisplayName()
+			}
+		default:
+			entry.PrincipalType = _principalTypeUser
+			entry.PrincipalID = grantee.GetUserId().GetOpaqueId()
+			entry.PrincipalName = a.userName(ctx, grantee.GetUserId())
+		}
+		report.add(entry)
+	}
+	return nil
+}
+
+// addPublicLinks adds the public links on resources of the reported spaces to the report
+func (a AccessReporter) addPublicLinks(ctx context.Context, gatewayClient gateway.GatewayAPIClient, report *accessReport) error {
+	links, err := a.shareIndex.ListPublicShares(ctx)
+	if err != nil {
+		return errorcode.New(errorcode.GeneralException, "could not list the public links: "+err.Error())
+	}
+	for _, l := range links {
+		entry, ok := a.newResourceEntry(ctx, gatewayClient, report, l.GetResourceId())
+		if !ok {
+			continue
+		}
+		entry.AccessType = AccessTypeLink
+		entry.ExpirationDateTime = timestampToTime(l.GetExpiration())
+		entry.PasswordProtected = l.GetPasswordProtected()
+		entry.CreatedByID = l.GetCreator().GetOpaqueId()
+		entry.CreatedByName = a.userName(ctx, l.GetCreator())
+		entry.CreatedDateTime = timestampToTime(l.GetCtime())
+		if linkType, _ := linktype.SharingLinkTypeFromCS3Permissions(l.GetPermissions()); linkType != nil {
+			entry.LinkType = string(*linkType)
+		}
+		report.add(entry)
+	}
+	return nil
+}
+
+// newResourceEntry returns an entry for a share or link on the given resource. It returns false if the
+// resource is not part of a reported space.
+func (a AccessReporter) newResourceEntry(ctx context.Context, gatewayClient gateway.GatewayAPIClient, report *accessReport, resourceID *storageprovider.ResourceId) (AccessReportEntry, bool) {
+	space, ok := report.spaces[storagespace.FormatStorageID(resourceID.GetStorageId(), resourceID.GetSpaceId())]
+	if !ok {
+		return AccessReportEntry{}, false
+	}
+
+	id := storagespace.FormatResourceID(resourceID)
+	resourcePath, ok := report.paths[id]
+	if !ok {
+		res, err := gatewayClient.GetPath(ctx, &storageprovider.GetPathRequest{ResourceId: resourceID})
+		if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+			a.logger.Debug().Err(errCode).Str("resource", id).Msg("access report: could not get resource path")
+		}
+		resourcePath = res.GetPath()
+		report.paths[id] = resourcePath
+	}
+
+	return AccessReportEntry{
+		SpaceID:      space.GetId().GetOpaqueId(),
+		SpaceName:    space.GetName(),
+		SpaceType:    space.GetSpaceType(),
+		ResourceID:   id,
+		ResourcePath: resourcePath,
+	}, true
+}
+
+// shareRoleID returns the id of the unified role matching the permissions of a share on a folder or a file
+func (a AccessReporter) shareRoleID(permissions *storageprovider.ResourcePermissions) string {
+	for _, condition := range []string{unifiedrole.UnifiedRoleConditionFolder, unifiedrole.UnifiedRoleConditionFile} {
+		if role := unifiedrole.CS3ResourcePermissionsToRole(a.availableRoles, permissions, condition, false); role != nil {
+			return role.GetId()
+		}
+	}
+	return ""
+}
+
+// userName returns the display name of a user or an empty string if the user can not be found
+func (a AccessReporter) userName(ctx context.Context, userID *userpb.UserId) string {
+	if userID == nil {
+		return ""
+	}
+	user, err := cs3UserIdToIdentity(ctx, a.identityCache, userID)
+	if err != nil {
+		return ""
+	}
+	return user.GetDisplayName()
+}
+
+// withAccessToken replaces the access token in the outgoing grpc metadata. Appending a token is not enough,
+// the first token wins and the context of an http request already carries the token of the caller.
+func withAccessToken(ctx context.Context, token string) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(ctxpkg.TokenHeader, token)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// timestampToTime converts an optional cs3 timestamp
+func timestampToTime(ts *types.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := utils.TSToTime(ts).UTC()
+	return &t
+}
+
+// WriteAccessReportCSV writes the entries as CSV including a header line
+func WriteAccessReportCSV(w io.Writer, entries []AccessReportEntry) error {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(_accessReportCSVHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := cw.Write([]string{
+			e.SpaceID, e.SpaceName, e.SpaceType, e.ResourceID, e.ResourcePath, e.AccessType,
+			e.PrincipalType, e.PrincipalID, e.PrincipalName, e.RoleID, e.LinkType,
+			formatTime(e.ExpirationDateTime), strconv.FormatBool(e.PasswordProtected), e.CreatedByID, e.CreatedByName, formatTime(e.CreatedDateTime),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ParseAccessReportFilter validates the link type of a filter
+func ParseAccessReportFilter(userID, groupID, spaceID, linkType string) (AccessReportFilter, error) {
+	if linkType != "" {
+		if _, err := libregraph.NewSharingLinkTypeFromValue(linkType); err != nil {
+			return AccessReportFilter{}, errorcode.New(errorcode.InvalidRequest, "invalid link type: "+linkType)
+		}
+	}
+	return AccessReportFilter{UserID: userID, GroupID: groupID, SpaceID: spaceID, LinkType: linkType}, nil
+}
+
+// GetAccessReport lists who can access which spaces and resources through space memberships, shares and public links.
+// The report is rendered as CSV when requested with the 'format=csv' query parameter or the 'text/csv' accept header.
+func (g Graph) GetAccessReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+	logger.Debug().Msg("calling get access report")
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	switch {
+	case format == "" && strings.Contains(r.Header.Get("Accept"), "text/csv"):
+		format = AccessReportFormatCSV
+	case format == "":
+		format = AccessReportFormatJSON
+	case format != AccessReportFormatJSON && format != AccessReportFormatCSV:
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid format: "+format)
+		return
+	}
+
+	filter, err := ParseAccessReportFilter(query.Get("user"), query.Get("group"), query.Get("space"), query.Get("linkType"))
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	entries, err := AccessReporter{BaseGraphService: g.BaseGraphService, shareIndex: g.shareIndex}.Report(ctx, filter)
+	if err != nil {
+		logger.Debug().Err(err).Msg("could not build access report")
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	if format == AccessReportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="access-report.csv"`)
+		w.WriteHeader(http.StatusOK)
+		if err := WriteAccessReportCSV(w, entries); err != nil {
+			logger.Error().Err(err).Msg("could not write access report")
+		}
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, &ListResponse{Value: entries})
+}
@@ -0,0 +1,327 @@
+package svc_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	"github.com/opencloud-eu/reva/v2/pkg/conversions"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/opencloud-eu/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/opencloud-eu/opencloud/pkg/shared"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config/defaults"
+	identitymocks "github.com/opencloud-eu/opencloud/services/graph/pkg/identity/mocks"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/linktype"
+	service "github.com/opencloud-eu/opencloud/services/graph/pkg/service/v0"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/unifiedrole"
+)
+
+// accessReportShareIndex is a service.ShareIndex returning fixed shares and links
+type accessReportShareIndex struct {
+	// shares by storage and space id
+	shares map[string][]*collaboration.Share
+	links  []*link.PublicShare
+	err    error
+}
+
+func (i *accessReportShareIndex) ListSpaceShares(_ context.Context, storageID, spaceID string) ([]*collaboration.Share, error) {
+	return i.shares[storageID+"$"+spaceID], i.err
+}
+
+func (i *accessReportShareIndex) ListPublicShares(_ context.Context) ([]*link.PublicShare, error) {
+	return i.links, i.err
+}
+
+var _ = Describe("AccessReport", func() {
+	var (
+		svc           service.Service
+		gatewayClient *cs3mocks.GatewayAPIClient
+		shareIndex    *accessReportShareIndex
+		rr            *httptest.ResponseRecorder
+		cfg           = defaults.FullDefaultConfig()
+
+		projectRoot = &provider.ResourceId{StorageId: "storageid", SpaceId: "project", OpaqueId: "project"}
+		folderID    = &provider.ResourceId{StorageId: "storageid", SpaceId: "project", OpaqueId: "folder"}
+		aliceRoot   = &provider.ResourceId{StorageId: "storageid", SpaceId: "alice", OpaqueId: "alice"}
+		aliceFile   = &provider.ResourceId{StorageId: "storageid", SpaceId: "alice", OpaqueId: "file"}
+		otherFile   = &provider.ResourceId{StorageId: "storageid", SpaceId: "other", OpaqueId: "file"}
+	)
+
+	// tokenUser returns the user of the token in the outgoing context, tokens are "token-<userid>"
+	tokenUser := func(ctx context.Context) string {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		tokens := md.Get(revactx.TokenHeader)
+		Expect(tokens).To(HaveLen(1))
+		return strings.TrimPrefix(tokens[0], "token-")
+	}
+
+	opaqueJSON := func(v any) *types.OpaqueEntry {
+		b, err := json.Marshal(v)
+		Expect(err).ToNot(HaveOccurred())
+		return &types.OpaqueEntry{Decoder: "json", Value: b}
+	}
+
+	linkPermissions := func(linkType libregraph.SharingLinkType) *link.PublicSharePermissions {
+		p, err := linktype.CS3ResourcePermissionsFromSharingLink(libregraph.DriveItemCreateLink{Type: &linkType}, provider.ResourceType_RESOURCE_TYPE_CONTAINER)
+		Expect(err).ToNot(HaveOccurred())
+		return &link.PublicSharePermissions{Permissions: p}
+	}
+
+	request := func(query string) *http.Request {
+		ctx := revactx.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{OpaqueId: "admin"}})
+		ctx = metadata.AppendToOutgoingContext(ctx, revactx.TokenHeader, "token-admin")
+		return httptest.NewRequest(http.MethodGet, "/graph/v1beta1/accessReport"+query, nil).WithContext(ctx)
+	}
+
+	report := func(query string) []service.AccessReportEntry {
+		svc.GetAccessReport(rr, request(query))
+		Expect(rr.Code).To(Equal(http.StatusOK), rr.Body.String())
+		res := struct {
+			Value []service.AccessReportEntry `json:"value"`
+		}{}
+		Expect(json.Unmarshal(rr.Body.Bytes(), &res)).To(Succeed())
+		return res.Value
+	}
+
+	BeforeEach(func() {
+		pool.RemoveSelector("GatewaySelector" + "eu.opencloud.api.gateway")
+		gatewayClient = &cs3mocks.GatewayAPIClient{}
+		gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+			"GatewaySelector",
+			"eu.opencloud.api.gateway",
+			func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+				return gatewayClient
+			},
+		)
+
+		gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(
+			func(ctx context.Context, req *gateway.AuthenticateRequest, _ ...grpc.CallOption) *gateway.AuthenticateResponse {
+				Expect(req.GetType()).To(Equal("serviceaccounts"))
+				return &gateway.AuthenticateResponse{
+					Status: status.NewOK(ctx),
+					Token:  "token-" + req.GetClientId(),
+					User:   &userpb.User{Id: &userpb.UserId{OpaqueId: req.GetClientId()}},
+				}
+			}, nil)
+		gatewayClient.On("GetUser", mock.Anything, mock.Anything).Return(
+			func(ctx context.Context, req *userpb.GetUserRequest, _ ...grpc.CallOption) *userpb.GetUserResponse {
+				id := req.GetUserId().GetOpaqueId()
+				return &userpb.GetUserResponse{
+					Status: status.NewOK(ctx),
+					User:   &userpb.User{Id: &userpb.UserId{OpaqueId: id}, DisplayName: strings.ToUpper(id)},
+				}
+			}, nil)
+		gatewayClient.On("GetGroup", mock.Anything, mock.Anything).Return(&grouppb.GetGroupResponse{
+			Status: status.NewOK(context.Background()),
+			Group:  &grouppb.Group{Id: &grouppb.GroupId{OpaqueId: "team"}, GroupName: "Team"},
+		}, nil)
+		gatewayClient.On("GetUserGroups", mock.Anything, mock.Anything).Return(&userpb.GetUserGroupsResponse{
+			Status: status.NewOK(context.Background()),
+			Groups: []string{"team"},
+		}, nil)
+		gatewayClient.On("GetPath", mock.Anything, mock.Anything).Return(
+			func(ctx context.Context, req *provider.GetPathRequest, _ ...grpc.CallOption) *provider.GetPathResponse {
+				Expect(tokenUser(ctx)).To(Equal("service-account"))
+				return &provider.GetPathResponse{Status: status.NewOK(ctx), Path: "/" + req.GetResourceId().GetOpaqueId()}
+			}, nil)
+
+		gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(
+			func(ctx context.Context, req *provider.ListStorageSpacesRequest, _ ...grpc.CallOption) *provider.ListStorageSpacesResponse {
+				Expect(tokenUser(ctx)).To(Equal("service-account"))
+				Expect(string(req.GetOpaque().GetMap()["unrestricted"].GetValue())).To(Equal("true"))
+				return &provider.ListStorageSpacesResponse{
+					Status: status.NewOK(ctx),
+					StorageSpaces: []*provider.StorageSpace{
+						{
+							Id:        &provider.StorageSpaceId{OpaqueId: "storageid$project"},
+							Root:      projectRoot,
+							Name:      "Project",
+							SpaceType: "project",
+							Opaque: &types.Opaque{Map: map[string]*types.OpaqueEntry{
+								"grants": opaqueJSON(map[string]*provider.ResourcePermissions{
+									"bob":  conversions.NewManagerRole().CS3ResourcePermissions(),
+									"dave": conversions.NewSpaceViewerRole().CS3ResourcePermissions(),
+									"team": conversions.NewSpaceEditorRole().CS3ResourcePermissions(),
+								}),
+								"grants_expirations": opaqueJSON(map[string]*types.Timestamp{
+									"dave": {Seconds: 1900000000},
+								}),
+								"groups": opaqueJSON(map[string]struct{}{"team": {}}),
+							}},
+						},
+						{
+							Id:        &provider.StorageSpaceId{OpaqueId: "storageid$alice"},
+							Root:      aliceRoot,
+							Name:      "Alice",
+							SpaceType: "personal",
+							Owner:     &userpb.User{Id: &userpb.UserId{OpaqueId: "alice"}},
+						},
+						{
+							Id:        &provider.StorageSpaceId{OpaqueId: "storageid$virtual"},
+							Name:      "Virtual",
+							SpaceType: "virtual",
+						},
+					},
+				}
+			}, nil)
+
+		shareIndex = &accessReportShareIndex{
+			shares: map[string][]*collaboration.Share{
+				"storageid$alice": {{
+					Id:          &collaboration.ShareId{OpaqueId: "share-alice"},
+					ResourceId:  aliceFile,
+					Grantee:     &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_GROUP, Id: &provider.Grantee_GroupId{GroupId: &grouppb.GroupId{OpaqueId: "team"}}},
+					Permissions: &collaboration.SharePermissions{Permissions: conversions.NewViewerRole().CS3ResourcePermissions()},
+					Creator:     &userpb.UserId{OpaqueId: "alice"},
+				}},
+				"storageid$project": {{
+					// shares created by users that are no space members anymore are reported, too
+					Id:          &collaboration.ShareId{OpaqueId: "share-bob"},
+					ResourceId:  folderID,
+					Grantee:     &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_USER, Id: &provider.Grantee_UserId{UserId: &userpb.UserId{OpaqueId: "erin"}}},
+					Permissions: &collaboration.SharePermissions{Permissions: conversions.NewEditorRole().CS3ResourcePermissions()},
+					Creator:     &userpb.UserId{OpaqueId: "frank"},
+					Expiration:  &types.Timestamp{Seconds: 1900000000},
+				}},
+			},
+			links: []*link.PublicShare{
+				{
+					Id:                &link.PublicShareId{OpaqueId: "link-carol"},
+					ResourceId:        folderID,
+					Permissions:       linkPermissions(libregraph.VIEW),
+					PasswordProtected: true,
+					Creator:           &userpb.UserId{OpaqueId: "carol"},
+				},
+				{
+					Id:          &link.PublicShareId{OpaqueId: "link-alice"},
+					ResourceId:  aliceFile,
+					Permissions: linkPermissions(libregraph.UPLOAD),
+					Creator:     &userpb.UserId{OpaqueId: "alice"},
+				},
+				{
+					// links in spaces that are not reported are ignored
+					Id:          &link.PublicShareId{OpaqueId: "link-other"},
+					ResourceId:  otherFile,
+					Permissions: linkPermissions(libregraph.VIEW),
+					Creator:     &userpb.UserId{OpaqueId: "alice"},
+				},
+			},
+		}
+
+		rr = httptest.NewRecorder()
+
+		cfg = defaults.FullDefaultConfig()
+		cfg.Identity.LDAP.CACert = "" // skip the startup checks, we don't use LDAP at all in this tests
+		cfg.TokenManager.JWTSecret = "loremipsum"
+		cfg.Commons = &shared.Commons{}
+		cfg.GRPCClientTLS = &shared.GRPCClientTLS{}
+		cfg.ServiceAccount.ServiceAccountID = "service-account"
+		cfg.ServiceAccount.ServiceAccountSecret = "secret"
+
+		var err error
+		svc, err = service.NewService(
+			service.Config(cfg),
+			service.WithGatewaySelector(gatewaySelector),
+			service.WithIdentityBackend(&identitymocks.Backend{}),
+			service.WithShareIndex(shareIndex),
+		)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("reports space members, shares and links of all spaces", func() {
+		entries := report("")
+
+		type row struct{ space, path, accessType, principal, role, linkType string }
+		rows := make([]row, 0, len(entries))
+		for _, e := range entries {
+			rows = append(rows, row{e.SpaceName, e.ResourcePath, e.AccessType, e.PrincipalID, e.RoleID, e.LinkType})
+		}
+		Expect(rows).To(Equal([]row{
+			{"Alice", "/", "owner", "alice", unifiedrole.UnifiedRoleManagerID, ""},
+			{"Alice", "/file", "link", "", "", string(libregraph.UPLOAD)},
+			{"Alice", "/file", "share", "team", unifiedrole.UnifiedRoleViewerID, ""},
+			{"Project", "/", "member", "bob", unifiedrole.UnifiedRoleManagerID, ""},
+			{"Project", "/", "member", "dave", unifiedrole.UnifiedRoleSpaceViewerID, ""},
+			{"Project", "/", "member", "team", unifiedrole.UnifiedRoleSpaceEditorID, ""},
+			{"Project", "/folder", "link", "", "", string(libregraph.VIEW)},
+			{"Project", "/folder", "share", "erin", unifiedrole.UnifiedRoleEditorID, ""},
+		}))
+
+		Expect(entries[1].CreatedByID).To(Equal("alice"))
+		Expect(entries[1].CreatedByName).To(Equal("ALICE"))
+		Expect(entries[4].ExpirationDateTime).ToNot(BeNil())
+		Expect(entries[5].PrincipalName).To(Equal("Team"))
+		Expect(entries[6].PasswordProtected).To(BeTrue())
+		Expect(entries[6].CreatedByID).To(Equal("carol"))
+		Expect(entries[7].ExpirationDateTime).ToNot(BeNil())
+		Expect(entries[7].CreatedByName).To(Equal("FRANK"))
+	})
+
+	It("filters by user including the groups of the user", func() {
+		entries := report("?user=erin")
+		Expect(entries).To(HaveLen(3))
+		Expect(entries[0].PrincipalID).To(Equal("team"))
+		Expect(entries[1].PrincipalID).To(Equal("team"))
+		Expect(entries[2].PrincipalID).To(Equal("erin"))
+	})
+
+	It("filters by group", func() {
+		entries := report("?group=team")
+		Expect(entries).To(HaveLen(2))
+		for _, e := range entries {
+			Expect(e.PrincipalType).To(Equal("group"))
+		}
+	})
+
+	It("filters by link type", func() {
+		entries := report("?linkType=view")
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].ResourceID).To(Equal("storageid$project!folder"))
+	})
+
+	It("renders csv", func() {
+		svc.GetAccessReport(rr, request("?format=csv&group=team"))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("Content-Type")).To(HavePrefix("text/csv"))
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(records).To(HaveLen(3))
+		Expect(records[0][0]).To(Equal("spaceId"))
+		Expect(records[1][5]).To(Equal("share"))
+	})
+
+	It("rejects invalid filters", func() {
+		svc.GetAccessReport(rr, request("?linkType=unknown"))
+		Expect(rr.Code).To(Equal(http.StatusBadRequest))
+
+		rr = httptest.NewRecorder()
+		svc.GetAccessReport(rr, request("?format=xml"))
+		Expect(rr.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("fails when the share index can not be read", func() {
+		shareIndex.err = errors.New("storage unavailable")
+		svc.GetAccessReport(rr, request(""))
+		Expect(rr.Code).To(Equal(http.StatusInternalServerError))
+	})
+})
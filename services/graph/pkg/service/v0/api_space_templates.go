@@ -0,0 +1,487 @@
+package svc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/metadata"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/unifiedrole"
+)
+
+const (
+	// _spaceTemplatesFolder is the folder of the metadata storage holding a folder per template
+	_spaceTemplatesFolder = "spacetemplates"
+	// _spaceTemplateFile is the file holding the settings of a template
+	_spaceTemplateFile = "template.json"
+	// _spaceTemplateFilesFolder is the folder of a template holding the contents of its files
+	_spaceTemplateFilesFolder = "files"
+)
+
+type (
+	// SpaceTemplatesProvider is the interface that defines the methods for the space templates service
+	SpaceTemplatesProvider interface {
+		// ListTemplates lists all space templates
+		ListTemplates(ctx context.Context) ([]SpaceTemplate, error)
+
+		// GetTemplate retrieves the requested space template
+		GetTemplate(ctx context.Context, name string) (SpaceTemplate, error)
+
+		// GetTemplateFile retrieves the content of a file of the space template
+		GetTemplateFile(ctx context.Context, name, filePath string) ([]byte, error)
+
+		// SaveTemplate creates or replaces the space template
+		SaveTemplate(ctx context.Context, template SpaceTemplate) error
+
+		// DeleteTemplate deletes the requested space template
+		DeleteTemplate(ctx context.Context, name string) error
+	}
+)
+
+// SpaceTemplate describes the contents and settings applied to a space created with the template.
+type SpaceTemplate struct {
+	// Name is the unique name of the template, which is passed to the create drive request.
+	Name string `json:"name"`
+	// Description is the default description of the space.
+	Description string `json:"description,omitempty"`
+	// Quota is the default quota of the space in bytes.
+	Quota *int64 `json:"quota,omitempty"`
+	// Tags are added to the root of the space.
+	Tags []string `json:"tags,omitempty"`
+	// Folders are created in the space, parent folders are created as needed.
+	Folders []string `json:"folders,omitempty"`
+	// Files are uploaded to the space.
+	Files []SpaceTemplateFile `json:"files,omitempty"`
+	// Members are the groups the space is shared with.
+	Members []SpaceTemplateMember `json:"members,omitempty"`
+}
+
+// SpaceTemplateFile is a file uploaded to spaces created with the template.
+type SpaceTemplateFile struct {
+	Path string `json:"path"`
+	// Content is only set when creating or updating the template, it is not returned.
+	Content []byte `json:"content,omitempty"`
+}
+
+// SpaceTemplateMember is a group the space is shared with using the given unified role.
+type SpaceTemplateMember struct {
+	GroupID string `json:"groupId"`
+	Role    string `json:"role"`
+}
+
+// spaceTemplateUpdate is the body of a request changing a template, absent attributes are not changed.
+type spaceTemplateUpdate struct {
+	Description *string                `json:"description"`
+	Quota       *int64                 `json:"quota"`
+	Tags        *[]string              `json:"tags"`
+	Folders     *[]string              `json:"folders"`
+	Files       *[]SpaceTemplateFile   `json:"files"`
+	Members     *[]SpaceTemplateMember `json:"members"`
+}
+
+var (
+	// ErrSpaceTemplateNotFound is returned when the space template does not exist
+	ErrSpaceTemplateNotFound = errors.New("space template not found")
+
+	_spaceTemplateNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,63}$`)
+)
+
+// SpaceTemplatesService is the implementation of the SpaceTemplatesProvider interface.
+// Every template is stored in a folder of the metadata storage.
+type SpaceTemplatesService struct {
+	storage metadata.Storage
+}
+
+// NewSpaceTemplatesService creates a new SpaceTemplatesService
+func NewSpaceTemplatesService(storage metadata.Storage) (SpaceTemplatesService, error) {
+	return SpaceTemplatesService{
+		storage: storage,
+	}, nil
+}
+
+// ListTemplates lists all space templates
+func (s SpaceTemplatesService) ListTemplates(ctx context.Context) ([]SpaceTemplate, error) {
+	names, err := s.storage.ReadDir(ctx, _spaceTemplatesFolder)
+	if err != nil {
+		var notFound errtypes.IsNotFound
+		if errors.As(err, &notFound) {
+			return []SpaceTemplate{}, nil
+		}
+		return nil, err
+	}
+
+	templates := make([]SpaceTemplate, 0, len(names))
+	for _, name := range names {
+		t, err := s.GetTemplate(ctx, path.Base(name))
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// GetTemplate retrieves the requested space template. Invalid names are never looked up in the storage.
+func (s SpaceTemplatesService) GetTemplate(ctx context.Context, name string) (SpaceTemplate, error) {
+	if !_spaceTemplateNameRegex.MatchString(name) {
+		return SpaceTemplate{}, ErrSpaceTemplateNotFound
+	}
+	b, err := s.storage.SimpleDownload(ctx, path.Join(_spaceTemplatesFolder, name, _spaceTemplateFile))
+	if err != nil {
+		var notFound errtypes.IsNotFound
+		if errors.As(err, &notFound) {
+			return SpaceTemplate{}, ErrSpaceTemplateNotFound
+		}
+		return SpaceTemplate{}, err
+	}
+	t := SpaceTemplate{}
+	if err := json.Unmarshal(b, &t); err != nil {
+		return SpaceTemplate{}, err
+	}
+	return t, nil
+}
+
+// GetTemplateFile retrieves the content of a file of the space template
+func (s SpaceTemplatesService) GetTemplateFile(ctx context.Context, name, filePath string) ([]byte, error) {
+	if !_spaceTemplateNameRegex.MatchString(name) {
+		return nil, ErrSpaceTemplateNotFound
+	}
+	return s.storage.SimpleDownload(ctx, spaceTemplateFileLocation(name, filePath))
+}
+
+// SaveTemplate creates or replaces the space template. Files without content keep their current content.
+func (s SpaceTemplatesService) SaveTemplate(ctx context.Context, template SpaceTemplate) error {
+	if template.Name == "" {
+		return fmt.Errorf("%w: %s", ErrMissingArgument, "name")
+	}
+	if !_spaceTemplateNameRegex.MatchString(template.Name) {
+		return fmt.Errorf("invalid space template name '%s'", template.Name)
+	}
+	folder := path.Join(_spaceTemplatesFolder, template.Name)
+	for _, dir := range []string{_spaceTemplatesFolder, folder, path.Join(folder, _spaceTemplateFilesFolder)} {
+		if err := s.storage.MakeDirIfNotExist(ctx, dir); err != nil {
+			return err
+		}
+	}
+
+	files := make([]SpaceTemplateFile, 0, len(template.Files))
+	for _, f := range template.Files {
+		if f.Content != nil {
+			if err := s.storage.SimpleUpload(ctx, spaceTemplateFileLocation(template.Name, f.Path), f.Content); err != nil {
+				return err
+			}
+		}
+		files = append(files, SpaceTemplateFile{Path: f.Path})
+	}
+	previous, err := s.GetTemplate(ctx, template.Name)
+	if err != nil && !errors.Is(err, ErrSpaceTemplateNotFound) {
+		return err
+	}
+	template.Files = files
+
+	b, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	if err := s.storage.SimpleUpload(ctx, path.Join(folder, _spaceTemplateFile), b); err != nil {
+		return err
+	}
+
+	// remove the contents of the files which are no longer part of the template
+	for _, f := range previous.Files {
+		if slices.ContainsFunc(files, func(n SpaceTemplateFile) bool { return n.Path == f.Path }) {
+			continue
+		}
+		if err := s.storage.Delete(ctx, spaceTemplateFileLocation(template.Name, f.Path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteTemplate deletes the requested space template
+func (s SpaceTemplatesService) DeleteTemplate(ctx context.Context, name string) error {
+	if _, err := s.GetTemplate(ctx, name); err != nil {
+		return err
+	}
+	return s.storage.Delete(ctx, path.Join(_spaceTemplatesFolder, name))
+}
+
+// spaceTemplateFileLocation returns the location of the content of a template file. The contents are
+// stored next to each other, so no folders need to be created for them.
+func spaceTemplateFileLocation(name, filePath string) string {
+	return path.Join(_spaceTemplatesFolder, name, _spaceTemplateFilesFolder, base64.RawURLEncoding.EncodeToString([]byte(filePath)))
+}
+
+// SpaceTemplatesApi contains all space template related api endpoints
+type SpaceTemplatesApi struct {
+	logger                log.Logger
+	config                *config.Config
+	spaceTemplatesService SpaceTemplatesProvider
+}
+
+// NewSpaceTemplatesApi creates a new SpaceTemplatesApi
+func NewSpaceTemplatesApi(spaceTemplatesService SpaceTemplatesProvider, logger log.Logger, c *config.Config) (SpaceTemplatesApi, error) {
+	return SpaceTemplatesApi{
+		logger:                log.Logger{Logger: logger.With().Str("graph api", "SpaceTemplatesApi").Logger()},
+		config:                c,
+		spaceTemplatesService: spaceTemplatesService,
+	}, nil
+}
+
+// ListSpaceTemplates lists the available space templates
+func (api SpaceTemplatesApi) ListSpaceTemplates(w http.ResponseWriter, r *http.Request) {
+	if !api.available(w, r) {
+		return
+	}
+	templates, err := api.spaceTemplatesService.ListTemplates(r.Context())
+	if err != nil {
+		api.logger.Debug().Err(err).Msg("could not list space templates")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "failed to list space templates")
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, &ListResponse{Value: templates})
+}
+
+// GetSpaceTemplate returns a single space template
+func (api SpaceTemplatesApi) GetSpaceTemplate(w http.ResponseWriter, r *http.Request) {
+	if !api.available(w, r) {
+		return
+	}
+	template, ok := api.getTemplateParam(w, r)
+	if !ok {
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, template)
+}
+
+// CreateSpaceTemplate creates a space template
+func (api SpaceTemplatesApi) CreateSpaceTemplate(w http.ResponseWriter, r *http.Request) {
+	if !api.available(w, r) {
+		return
+	}
+	template := SpaceTemplate{}
+	if err := StrictJSONUnmarshal(r.Body, &template); err != nil {
+		api.logger.Debug().Err(err).Msg("could not create space template: invalid request body")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err.Error()))
+		return
+	}
+	if err := api.validateTemplate(&template); err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	for i, f := range template.Files {
+		if f.Content == nil {
+			template.Files[i].Content = []byte{}
+		}
+	}
+
+	switch _, err := api.spaceTemplatesService.GetTemplate(r.Context(), template.Name); {
+	case err == nil:
+		errorcode.NameAlreadyExists.Render(w, r, http.StatusConflict, "a space template with this name already exists")
+		return
+	case !errors.Is(err, ErrSpaceTemplateNotFound):
+		api.logger.Debug().Err(err).Msg("could not create space template")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "failed to create space template")
+		return
+	}
+
+	if err := api.spaceTemplatesService.SaveTemplate(r.Context(), template); err != nil {
+		api.logger.Error().Err(err).Str("template", template.Name).Msg("could not create space template")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "failed to create space template")
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, withoutFileContents(template))
+}
+
+// UpdateSpaceTemplate changes the attributes of a space template present in the request
+func (api SpaceTemplatesApi) UpdateSpaceTemplate(w http.ResponseWriter, r *http.Request) {
+	if !api.available(w, r) {
+		return
+	}
+	template, ok := api.getTemplateParam(w, r)
+	if !ok {
+		return
+	}
+	update := spaceTemplateUpdate{}
+	if err := StrictJSONUnmarshal(r.Body, &update); err != nil {
+		api.logger.Debug().Err(err).Msg("could not update space template: invalid request body")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err.Error()))
+		return
+	}
+
+	if update.Description != nil {
+		template.Description = *update.Description
+	}
+	if update.Quota != nil {
+		template.Quota = update.Quota
+		if *update.Quota < 0 {
+			// a negative quota removes the default quota of the template
+			template.Quota = nil
+		}
+	}
+	if update.Tags != nil {
+		template.Tags = *update.Tags
+	}
+	if update.Folders != nil {
+		template.Folders = *update.Folders
+	}
+	if update.Files != nil {
+		// files without content keep their current content, new files without content are empty
+		for i, f := range *update.Files {
+			if f.Content == nil && !slices.ContainsFunc(template.Files, func(c SpaceTemplateFile) bool { return c.Path == f.Path }) {
+				(*update.Files)[i].Content = []byte{}
+			}
+		}
+		template.Files = *update.Files
+	}
+	if update.Members != nil {
+		template.Members = *update.Members
+	}
+	if err := api.validateTemplate(&template); err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	if err := api.spaceTemplatesService.SaveTemplate(r.Context(), template); err != nil {
+		api.logger.Error().Err(err).Str("template", template.Name).Msg("could not update space template")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "failed to update space template")
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, withoutFileContents(template))
+}
+
+// DeleteSpaceTemplate deletes a space template, spaces created with the template are not changed
+func (api SpaceTemplatesApi) DeleteSpaceTemplate(w http.ResponseWriter, r *http.Request) {
+	if !api.available(w, r) {
+		return
+	}
+	template, ok := api.getTemplateParam(w, r)
+	if !ok {
+		return
+	}
+	if err := api.spaceTemplatesService.DeleteTemplate(r.Context(), template.Name); err != nil {
+		api.logger.Error().Err(err).Str("template", template.Name).Msg("could not delete space template")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "failed to delete space template")
+		return
+	}
+
+	render.Status(r, http.StatusNoContent)
+	render.NoContent(w, r)
+}
+
+func (api SpaceTemplatesApi) available(w http.ResponseWriter, r *http.Request) bool {
+	if api.spaceTemplatesService == nil {
+		errorcode.NotSupported.Render(w, r, http.StatusNotImplemented, "space templates are not available")
+		return false
+	}
+	return true
+}
+
+func (api SpaceTemplatesApi) getTemplateParam(w http.ResponseWriter, r *http.Request) (SpaceTemplate, bool) {
+	name, err := url.PathUnescape(chi.URLParam(r, "templateName"))
+	if err != nil || !_spaceTemplateNameRegex.MatchString(name) {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid space template name")
+		return SpaceTemplate{}, false
+	}
+	template, err := api.spaceTemplatesService.GetTemplate(r.Context(), name)
+	switch {
+	case errors.Is(err, ErrSpaceTemplateNotFound):
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "space template not found")
+		return SpaceTemplate{}, false
+	case err != nil:
+		api.logger.Debug().Err(err).Str("template", name).Msg("could not get space template")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "failed to get space template")
+		return SpaceTemplate{}, false
+	}
+	return template, true
+}
+
+// validateTemplate checks the template and cleans the paths of its folders and files
+func (api SpaceTemplatesApi) validateTemplate(t *SpaceTemplate) error {
+	switch {
+	case !_spaceTemplateNameRegex.MatchString(t.Name):
+		return errorcode.New(errorcode.InvalidRequest, "the template name may only contain letters, digits, '.', '_' and '-'")
+	case isBuiltinSpaceTemplate(t.Name):
+		return errorcode.New(errorcode.InvalidRequest, fmt.Sprintf("'%s' is a built-in template", t.Name))
+	case t.Quota != nil && *t.Quota < 0:
+		return errorcode.New(errorcode.InvalidRequest, "the quota must not be negative")
+	}
+
+	for i, folder := range t.Folders {
+		p, err := cleanSpaceTemplatePath(folder)
+		if err != nil {
+			return err
+		}
+		t.Folders[i] = p
+	}
+	seen := map[string]struct{}{}
+	for i, f := range t.Files {
+		p, err := cleanSpaceTemplatePath(f.Path)
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[p]; ok {
+			return errorcode.New(errorcode.InvalidRequest, fmt.Sprintf("the file '%s' is listed twice", p))
+		}
+		seen[p] = struct{}{}
+		t.Files[i].Path = p
+	}
+
+	for _, m := range t.Members {
+		if m.GroupID == "" {
+			return errorcode.New(errorcode.InvalidRequest, "members need a group id")
+		}
+		if !slices.Contains(api.config.UnifiedRoles.AvailableRoles, m.Role) {
+			return errorcode.New(errorcode.InvalidRequest, fmt.Sprintf("the role '%s' is not available", m.Role))
+		}
+		role, err := unifiedrole.GetRole(unifiedrole.RoleFilterIDs(m.Role))
+		if err != nil || len(unifiedrole.GetAllowedResourceActions(role, unifiedrole.UnifiedRoleConditionDrive)) == 0 {
+			return errorcode.New(errorcode.InvalidRequest, fmt.Sprintf("the role '%s' is not applicable to spaces", m.Role))
+		}
+	}
+	return nil
+}
+
+// cleanSpaceTemplatePath returns the path relative to the space root
+func cleanSpaceTemplatePath(p string) (string, error) {
+	cleaned := path.Clean("/" + p)
+	if p == "" || cleaned == "/" {
+		return "", errorcode.New(errorcode.InvalidRequest, fmt.Sprintf("invalid path '%s'", p))
+	}
+	return cleaned[1:], nil
+}
+
+// withoutFileContents drops the contents of the files, which are not returned by the api
+func withoutFileContents(t SpaceTemplate) SpaceTemplate {
+	files := make([]SpaceTemplateFile, 0, len(t.Files))
+	for _, f := range t.Files {
+		files = append(files, SpaceTemplateFile{Path: f.Path})
+	}
+	t.Files = files
+	return t
+}
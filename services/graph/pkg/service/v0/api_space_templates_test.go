@@ -0,0 +1,176 @@
+package svc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/graph/mocks"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config"
+	svc "github.com/opencloud-eu/opencloud/services/graph/pkg/service/v0"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/unifiedrole"
+)
+
+// newMemoryStorage returns a metadata storage mock keeping the uploaded files in memory
+func newMemoryStorage(t *testing.T) (*mocks.Storage, map[string][]byte) {
+	files := map[string][]byte{}
+	storage := mocks.NewStorage(t)
+	storage.EXPECT().MakeDirIfNotExist(mock.Anything, mock.Anything).Return(nil).Maybe()
+	storage.EXPECT().SimpleUpload(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, p string, b []byte) error {
+		files[p] = b
+		return nil
+	}).Maybe()
+	storage.EXPECT().SimpleDownload(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, p string) ([]byte, error) {
+		b, ok := files[p]
+		if !ok {
+			return nil, errtypes.NotFound(p)
+		}
+		return b, nil
+	}).Maybe()
+	storage.EXPECT().Delete(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, p string) error {
+		for k := range files {
+			if k == p || strings.HasPrefix(k, p+"/") {
+				delete(files, k)
+			}
+		}
+		return nil
+	}).Maybe()
+	return storage, files
+}
+
+func TestSpaceTemplatesService(t *testing.T) {
+	storage, files := newMemoryStorage(t)
+	service, err := svc.NewSpaceTemplatesService(storage)
+	assert.NoError(t, err)
+
+	t.Run("GetTemplate reports missing templates", func(t *testing.T) {
+		_, err := service.GetTemplate(context.Background(), "missing")
+		assert.ErrorIs(t, err, svc.ErrSpaceTemplateNotFound)
+	})
+
+	t.Run("GetTemplate rejects invalid names without accessing the storage", func(t *testing.T) {
+		service, err := svc.NewSpaceTemplatesService(mocks.NewStorage(t))
+		assert.NoError(t, err)
+		for _, name := range []string{"", "..", "../../users/admin", "a/b", ".hidden"} {
+			_, err := service.GetTemplate(context.Background(), name)
+			assert.ErrorIs(t, err, svc.ErrSpaceTemplateNotFound, name)
+			_, err = service.GetTemplateFile(context.Background(), name, "readme.md")
+			assert.ErrorIs(t, err, svc.ErrSpaceTemplateNotFound, name)
+		}
+	})
+
+	t.Run("SaveTemplate stores the file contents next to the template", func(t *testing.T) {
+		err := service.SaveTemplate(context.Background(), svc.SpaceTemplate{
+			Name:  "project",
+			Files: []svc.SpaceTemplateFile{{Path: "docs/readme.md", Content: []byte("hello")}},
+		})
+		assert.NoError(t, err)
+
+		template, err := service.GetTemplate(context.Background(), "project")
+		assert.NoError(t, err)
+		assert.Equal(t, []svc.SpaceTemplateFile{{Path: "docs/readme.md"}}, template.Files)
+
+		content, err := service.GetTemplateFile(context.Background(), "project", "docs/readme.md")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("hello"), content)
+	})
+
+	t.Run("SaveTemplate keeps the contents of files without content and removes dropped files", func(t *testing.T) {
+		err := service.SaveTemplate(context.Background(), svc.SpaceTemplate{
+			Name:  "project",
+			Files: []svc.SpaceTemplateFile{{Path: "docs/readme.md"}, {Path: "plan.md", Content: []byte("plan")}},
+		})
+		assert.NoError(t, err)
+		content, err := service.GetTemplateFile(context.Background(), "project", "docs/readme.md")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("hello"), content)
+
+		err = service.SaveTemplate(context.Background(), svc.SpaceTemplate{
+			Name:  "project",
+			Files: []svc.SpaceTemplateFile{{Path: "plan.md"}},
+		})
+		assert.NoError(t, err)
+		_, err = service.GetTemplateFile(context.Background(), "project", "docs/readme.md")
+		assert.Error(t, err)
+	})
+
+	t.Run("DeleteTemplate removes the template folder", func(t *testing.T) {
+		assert.NoError(t, service.DeleteTemplate(context.Background(), "project"))
+		assert.Empty(t, files)
+		assert.ErrorIs(t, service.DeleteTemplate(context.Background(), "project"), svc.ErrSpaceTemplateNotFound)
+	})
+}
+
+func TestSpaceTemplatesApi(t *testing.T) {
+	storage, _ := newMemoryStorage(t)
+	service, err := svc.NewSpaceTemplatesService(storage)
+	assert.NoError(t, err)
+
+	cfg := &config.Config{}
+	cfg.UnifiedRoles.AvailableRoles = []string{unifiedrole.UnifiedRoleSpaceEditorID, unifiedrole.UnifiedRoleViewerID}
+	api, err := svc.NewSpaceTemplatesApi(service, log.NopLogger(), cfg)
+	assert.NoError(t, err)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		api.CreateSpaceTemplate(w, httptest.NewRequest(http.MethodPost, "/graph/v1beta1/spaceTemplates", strings.NewReader(body)))
+		return w
+	}
+
+	t.Run("CreateSpaceTemplate", func(t *testing.T) {
+		t.Run("rejects the built-in templates", func(t *testing.T) {
+			assert.Equal(t, http.StatusBadRequest, create(`{"name": "default"}`).Code)
+		})
+
+		t.Run("rejects roles which are not applicable to spaces", func(t *testing.T) {
+			w := create(`{"name": "project", "members": [{"groupId": "g1", "role": "` + unifiedrole.UnifiedRoleViewerID + `"}]}`)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+
+		t.Run("creates the template with clean paths", func(t *testing.T) {
+			w := create(`{
+				"name": "project",
+				"description": "A project",
+				"quota": 1000,
+				"folders": ["/docs/../specs", "meetings/"],
+				"files": [{"path": "specs/readme.md", "content": "aGVsbG8="}],
+				"members": [{"groupId": "g1", "role": "` + unifiedrole.UnifiedRoleSpaceEditorID + `"}]
+			}`)
+			assert.Equal(t, http.StatusCreated, w.Code)
+
+			template := svc.SpaceTemplate{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &template))
+			assert.Equal(t, []string{"specs", "meetings"}, template.Folders)
+			assert.Nil(t, template.Files[0].Content)
+		})
+
+		t.Run("rejects duplicate names", func(t *testing.T) {
+			assert.Equal(t, http.StatusConflict, create(`{"name": "project"}`).Code)
+		})
+	})
+
+	t.Run("UpdateSpaceTemplate changes the given attributes", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPatch, "/graph/v1beta1/spaceTemplates/project", strings.NewReader(`{"tags": ["project"]}`))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("templateName", "project")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		api.UpdateSpaceTemplate(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		template, err := service.GetTemplate(context.Background(), "project")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"project"}, template.Tags)
+		assert.Equal(t, "A project", template.Description)
+		assert.Equal(t, int64(1000), *template.Quota)
+	})
+}
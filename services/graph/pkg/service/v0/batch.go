@@ -0,0 +1,243 @@
+package svc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+)
+
+const _batchPath = "/$batch"
+
+// BatchRequest is a single request of a JSON batch.
+type BatchRequest struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+	// URL is the url of the request relative to the api version, e.g. "/users/{id}".
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+	// DependsOn lists the ids of the requests that need to succeed before this request is executed.
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// BatchResponse is the response to a single request of a JSON batch.
+type BatchResponse struct {
+	ID      string            `json:"id"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// batchRequests is the body of a JSON batch request.
+type batchRequests struct {
+	Requests []*BatchRequest `json:"requests"`
+}
+
+// batchResponses is the body of a JSON batch response.
+type batchResponses struct {
+	Responses []*BatchResponse `json:"responses"`
+}
+
+// Batch executes the requests of a JSON batch against the graph api and returns their responses.
+// Requests are executed concurrently unless they depend on other requests of the batch.
+func (g Graph) Batch(w http.ResponseWriter, r *http.Request) {
+	logger := g.logger.SubloggerWithRequestID(r.Context())
+	logger.Debug().Msg("calling batch")
+
+	var batch batchRequests
+	if err := StrictJSONUnmarshal(r.Body, &batch); err != nil {
+		logger.Debug().Err(err).Msg("could not execute batch: invalid request body")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err.Error()))
+		return
+	}
+	if err := g.validateBatch(batch.Requests); err != nil {
+		logger.Debug().Err(err).Msg("could not execute batch: invalid batch")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the urls of the requests are relative to the api version of the batch endpoint
+	prefix := strings.TrimSuffix(r.URL.Path, _batchPath)
+
+	responses := make([]*BatchResponse, len(batch.Requests))
+	done := make(map[string]chan struct{}, len(batch.Requests))
+	index := make(map[string]int, len(batch.Requests))
+	for i, req := range batch.Requests {
+		done[req.ID] = make(chan struct{})
+		index[req.ID] = i
+	}
+
+	var wg sync.WaitGroup
+	for i, req := range batch.Requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[req.ID])
+			for _, dep := range req.DependsOn {
+				<-done[dep]
+				if responses[index[dep]].Status >= http.StatusBadRequest {
+					responses[i] = failedDependencyResponse(r.Context(), req.ID, dep)
+					return
+				}
+			}
+			responses[i] = g.executeBatchRequest(r, prefix, req)
+		}()
+	}
+	wg.Wait()
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, &batchResponses{Responses: responses})
+}
+
+func (g Graph) validateBatch(requests []*BatchRequest) error {
+	switch {
+	case len(requests) == 0:
+		return fmt.Errorf("the batch contains no requests")
+	case len(requests) > g.config.API.BatchMaxRequests:
+		return fmt.Errorf("the batch exceeds the maximum of %d requests", g.config.API.BatchMaxRequests)
+	}
+
+	ids := make(map[string]*BatchRequest, len(requests))
+	for _, req := range requests {
+		switch {
+		case req.ID == "":
+			return fmt.Errorf("all requests need an id")
+		case ids[req.ID] != nil:
+			return fmt.Errorf("the request id '%s' is not unique", req.ID)
+		case req.Method == "":
+			return fmt.Errorf("request '%s' has no method", req.ID)
+		case !strings.HasPrefix(req.URL, "/"):
+			return fmt.Errorf("the url of request '%s' must be relative to the api version and start with a '/'", req.ID)
+		case strings.HasPrefix(req.URL, _batchPath):
+			return fmt.Errorf("request '%s' is a nested batch", req.ID)
+		}
+		ids[req.ID] = req
+	}
+
+	// reject unknown and circular dependencies, which would never be executed
+	state := make(map[string]int, len(requests))
+	var visit func(req *BatchRequest) error
+	visit = func(req *BatchRequest) error {
+		switch state[req.ID] {
+		case 1:
+			return fmt.Errorf("request '%s' has a circular dependency", req.ID)
+		case 2:
+			return nil
+		}
+		state[req.ID] = 1
+		for _, dep := range req.DependsOn {
+			d, ok := ids[dep]
+			if !ok {
+				return fmt.Errorf("request '%s' depends on the unknown request '%s'", req.ID, dep)
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		state[req.ID] = 2
+		return nil
+	}
+	for _, req := range requests {
+		if err := visit(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executeBatchRequest runs the request through the router of the graph service. It carries
+// the headers of the batch request, so that it is authenticated like the batch request itself.
+func (g Graph) executeBatchRequest(r *http.Request, prefix string, req *BatchRequest) *BatchResponse {
+	// drop the routing context of the batch request, the request needs to be routed from scratch
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, nil)
+	sub, err := http.NewRequestWithContext(ctx, strings.ToUpper(req.Method), prefix+req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return &BatchResponse{
+			ID:     req.ID,
+			Status: http.StatusBadRequest,
+			Body:   batchErrorBody(r.Context(), errorcode.InvalidRequest, err.Error()),
+		}
+	}
+	sub.RemoteAddr = r.RemoteAddr
+	sub.Header = r.Header.Clone()
+	sub.Header.Del("Content-Length")
+	sub.Header.Del("Content-Type")
+	for k, v := range req.Headers {
+		sub.Header.Set(k, v)
+	}
+	if len(req.Body) > 0 && sub.Header.Get("Content-Type") == "" {
+		sub.Header.Set("Content-Type", "application/json")
+	}
+
+	rw := &batchResponseWriter{header: http.Header{}}
+	g.mux.ServeHTTP(rw, sub)
+
+	res := &BatchResponse{
+		ID:     req.ID,
+		Status: rw.status,
+	}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+	for k := range rw.header {
+		if res.Headers == nil {
+			res.Headers = make(map[string]string, len(rw.header))
+		}
+		res.Headers[k] = rw.header.Get(k)
+	}
+	if body := bytes.TrimSpace(rw.body.Bytes()); len(body) > 0 {
+		if json.Valid(body) {
+			res.Body = body
+		} else {
+			// non json bodies are returned as a json string
+			res.Body, _ = json.Marshal(string(body))
+		}
+	}
+	return res
+}
+
+func failedDependencyResponse(ctx context.Context, id, dependency string) *BatchResponse {
+	return &BatchResponse{
+		ID:     id,
+		Status: http.StatusFailedDependency,
+		Body:   batchErrorBody(ctx, errorcode.GeneralException, fmt.Sprintf("the request depends on request '%s', which failed", dependency)),
+	}
+}
+
+func batchErrorBody(ctx context.Context, code errorcode.ErrorCode, msg string) json.RawMessage {
+	body, _ := json.Marshal(code.CreateOdataError(ctx, msg))
+	return body
+}
+
+// batchResponseWriter records the response to a request of a JSON batch.
+type batchResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *batchResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *batchResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
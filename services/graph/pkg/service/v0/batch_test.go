@@ -0,0 +1,152 @@
+package svc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/opencloud-eu/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+
+	"github.com/opencloud-eu/opencloud/pkg/shared"
+	settingsmsg "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/messages/settings/v0"
+	settings "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/settings/v0"
+	"github.com/opencloud-eu/opencloud/services/graph/mocks"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config/defaults"
+	identitymocks "github.com/opencloud-eu/opencloud/services/graph/pkg/identity/mocks"
+	service "github.com/opencloud-eu/opencloud/services/graph/pkg/service/v0"
+)
+
+var _ = Describe("Batch", func() {
+	var (
+		svc               service.Service
+		ctx               context.Context
+		cfg               *config.Config
+		identityBackend   *identitymocks.Backend
+		permissionService *mocks.Permissions
+
+		rr *httptest.ResponseRecorder
+	)
+
+	batch := func(requests ...map[string]interface{}) *http.Request {
+		body, err := json.Marshal(map[string]interface{}{"requests": requests})
+		Expect(err).ToNot(HaveOccurred())
+		r := httptest.NewRequest(http.MethodPost, "/graph/v1.0/$batch", bytes.NewReader(body))
+		return r.WithContext(ctx)
+	}
+
+	BeforeEach(func() {
+		pool.RemoveSelector("GatewaySelector" + "eu.opencloud.api.gateway")
+		gatewayClient := &cs3mocks.GatewayAPIClient{}
+		gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+			"GatewaySelector",
+			"eu.opencloud.api.gateway",
+			func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+				return gatewayClient
+			},
+		)
+		permissionService = &mocks.Permissions{}
+		permissionService.On("GetPermissionByID", mock.Anything, mock.Anything).Return(&settings.GetPermissionByIDResponse{
+			Permission: &settingsmsg.Permission{
+				Operation:  settingsmsg.Permission_OPERATION_UNKNOWN,
+				Constraint: settingsmsg.Permission_CONSTRAINT_ALL,
+			},
+		}, nil)
+		identityBackend = &identitymocks.Backend{}
+
+		rr = httptest.NewRecorder()
+		ctx = revactx.ContextSetUser(context.Background(), &userv1beta1.User{Id: &userv1beta1.UserId{OpaqueId: "admin"}})
+
+		cfg = defaults.FullDefaultConfig()
+		cfg.Identity.LDAP.CACert = "" // skip the startup checks, we don't use LDAP at all in this tests
+		cfg.TokenManager.JWTSecret = "loremipsum"
+		cfg.Commons = &shared.Commons{}
+		cfg.GRPCClientTLS = &shared.GRPCClientTLS{}
+		cfg.API.BatchMaxRequests = 3
+
+		var err error
+		svc, err = service.NewService(
+			service.Config(cfg),
+			service.WithGatewaySelector(gatewaySelector),
+			service.WithIdentityBackend(identityBackend),
+			service.PermissionService(permissionService),
+		)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("executes the requests and returns their responses", func() {
+		user := libregraph.NewUser("Alice", "alice")
+		user.SetId("alice")
+		identityBackend.On("GetUsers", mock.Anything, mock.Anything).Return([]*libregraph.User{user}, nil)
+		identityBackend.On("GetGroups", mock.Anything, mock.Anything).Return([]*libregraph.Group{}, nil)
+
+		svc.ServeHTTP(rr, batch(
+			map[string]interface{}{"id": "1", "method": "GET", "url": "/users"},
+			map[string]interface{}{"id": "2", "method": "GET", "url": "/groups?$orderby=invalid"},
+			map[string]interface{}{"id": "3", "method": "GET", "url": "/users", "dependsOn": []string{"2"}},
+		))
+
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		res := struct {
+			Responses []service.BatchResponse
+		}{}
+		Expect(json.Unmarshal(rr.Body.Bytes(), &res)).To(Succeed())
+		Expect(res.Responses).To(HaveLen(3))
+
+		Expect(res.Responses[0].ID).To(Equal("1"))
+		Expect(res.Responses[0].Status).To(Equal(http.StatusOK))
+		users := userList{}
+		Expect(json.Unmarshal(res.Responses[0].Body, &users)).To(Succeed())
+		Expect(users.Value).To(HaveLen(1))
+		Expect(users.Value[0].GetId()).To(Equal("alice"))
+
+		Expect(res.Responses[1].ID).To(Equal("2"))
+		Expect(res.Responses[1].Status).To(Equal(http.StatusBadRequest))
+
+		Expect(res.Responses[2].ID).To(Equal("3"))
+		Expect(res.Responses[2].Status).To(Equal(http.StatusFailedDependency))
+		identityBackend.AssertNumberOfCalls(GinkgoT(), "GetUsers", 1)
+	})
+
+	DescribeTable("rejects invalid batches",
+		func(requests ...map[string]interface{}) {
+			svc.ServeHTTP(rr, batch(requests...))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		},
+		Entry("without requests"),
+		Entry("with too many requests",
+			map[string]interface{}{"id": "1", "method": "GET", "url": "/users"},
+			map[string]interface{}{"id": "2", "method": "GET", "url": "/users"},
+			map[string]interface{}{"id": "3", "method": "GET", "url": "/users"},
+			map[string]interface{}{"id": "4", "method": "GET", "url": "/users"},
+		),
+		Entry("with duplicate ids",
+			map[string]interface{}{"id": "1", "method": "GET", "url": "/users"},
+			map[string]interface{}{"id": "1", "method": "GET", "url": "/groups"},
+		),
+		Entry("with absolute urls",
+			map[string]interface{}{"id": "1", "method": "GET", "url": "https://example.com/users"},
+		),
+		Entry("with nested batches",
+			map[string]interface{}{"id": "1", "method": "POST", "url": "/$batch"},
+		),
+		Entry("with unknown dependencies",
+			map[string]interface{}{"id": "1", "method": "GET", "url": "/users", "dependsOn": []string{"2"}},
+		),
+		Entry("with circular dependencies",
+			map[string]interface{}{"id": "1", "method": "GET", "url": "/users", "dependsOn": []string{"2"}},
+			map[string]interface{}{"id": "2", "method": "GET", "url": "/users", "dependsOn": []string{"1"}},
+		),
+	)
+})
@@ -0,0 +1,404 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	cs3rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+)
+
+const (
+	// _conflictBehavior selects how name conflicts are resolved when creating, moving or copying items
+	_conflictBehavior = "@microsoft.graph.conflictBehavior"
+	// _conflictFail rejects the request if an item with the same name exists
+	_conflictFail = "fail"
+	// _conflictReplace replaces the existing item
+	_conflictReplace = "replace"
+	// _conflictRename picks a free name for the new item
+	_conflictRename = "rename"
+)
+
+// _downloadHeaders are the headers of the datagateway response relevant for the content
+var _downloadHeaders = []string{
+	"Content-Type",
+	"Content-Length",
+	"Content-Range",
+	"Content-Disposition",
+	"ETag",
+	"Last-Modified",
+	"Accept-Ranges",
+}
+
+// GetDriveItemContent streams the content of a file. Range requests are passed on to the datagateway.
+func (g Graph) GetDriveItemContent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+	logger.Debug().Msg("calling get drive item content")
+
+	_, itemID, err := parseDriveItemParams(r, "driveItemID")
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	info, err := statDriveItem(ctx, gatewayClient, &storageprovider.Reference{ResourceId: &itemID})
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if info.GetType() == storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "folders have no content")
+		return
+	}
+
+	res, err := g.downloadFile(ctx, gatewayClient, &storageprovider.Reference{ResourceId: info.GetId()}, r.Header.Get("Range"))
+	if err != nil {
+		logger.Debug().Err(err).Msg("could not get drive item content: download failed")
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	defer res.Body.Close()
+
+	for _, h := range _downloadHeaders {
+		if v := res.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.WriteHeader(res.StatusCode)
+	if _, err := io.Copy(w, res.Body); err != nil {
+		logger.Error().Err(err).Msg("could not get drive item content: copying the content failed")
+	}
+}
+
+// PutDriveItemContent replaces the content of an existing file with the request body.
+func (g Graph) PutDriveItemContent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+	logger.Debug().Msg("calling put drive item content")
+
+	_, itemID, err := parseDriveItemParams(r, "driveItemID")
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if r.ContentLength < 0 {
+		errorcode.InvalidRequest.Render(w, r, http.StatusLengthRequired, "the Content-Length header is required")
+		return
+	}
+
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ref := &storageprovider.Reference{ResourceId: &itemID}
+	info, err := statDriveItem(ctx, gatewayClient, ref)
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if info.GetType() == storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "the content of folders can not be replaced")
+		return
+	}
+
+	if err := g.uploadFile(ctx, gatewayClient, ref, r.Body, r.ContentLength); err != nil {
+		logger.Debug().Err(err).Msg("could not put drive item content: upload failed")
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	g.renderDriveItem(w, r, gatewayClient, ref, http.StatusOK)
+}
+
+// PutDriveItemChildContent uploads the request body as a file into the folder. Existing files
+// are replaced unless the conflict behavior says otherwise.
+func (g Graph) PutDriveItemChildContent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+	logger.Debug().Msg("calling put drive item child content")
+
+	_, parentID, err := parseDriveItemParams(r, "driveItemID")
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	name, err := url.PathUnescape(chi.URLParam(r, "fileName"))
+	if err != nil {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateDriveItemName(name); err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	behavior, err := parseConflictBehavior(r, "", _conflictReplace)
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if r.ContentLength < 0 {
+		errorcode.InvalidRequest.Render(w, r, http.StatusLengthRequired, "the Content-Length header is required")
+		return
+	}
+
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	name, existing, err := resolveNameConflict(ctx, gatewayClient, &parentID, name, behavior)
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if existing.GetType() == storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		errorcode.NameAlreadyExists.Render(w, r, http.StatusConflict, fmt.Sprintf("a folder named '%s' already exists", name))
+		return
+	}
+
+	ref := &storageprovider.Reference{ResourceId: &parentID, Path: utils.MakeRelativePath(name)}
+	if err := g.uploadFile(ctx, gatewayClient, ref, r.Body, r.ContentLength); err != nil {
+		logger.Debug().Err(err).Msg("could not put drive item child content: upload failed")
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if existing != nil {
+		status = http.StatusOK
+	}
+	g.renderDriveItem(w, r, gatewayClient, ref, status)
+}
+
+// renderDriveItem stats the item and renders it with the given status
+func (g Graph) renderDriveItem(w http.ResponseWriter, r *http.Request, gatewayClient gateway.GatewayAPIClient, ref *storageprovider.Reference, status int) {
+	info, err := statDriveItem(r.Context(), gatewayClient, ref)
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	driveItem, err := cs3ResourceToDriveItem(g.logger, info)
+	if err != nil {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	render.Status(r, status)
+	render.JSON(w, r, driveItem)
+}
+
+// downloadFile requests the content of the file from the datagateway. The caller has to close the body of the response.
+func (g Graph) downloadFile(ctx context.Context, gatewayClient gateway.GatewayAPIClient, ref *storageprovider.Reference, byteRange string) (*http.Response, error) {
+	res, err := gatewayClient.InitiateFileDownload(ctx, &storageprovider.InitiateFileDownloadRequest{Ref: ref})
+	if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+		return nil, errCode
+	}
+
+	var endpoint, token string
+	for _, p := range res.GetProtocols() {
+		if p.GetProtocol() == "spaces" {
+			endpoint, token = p.GetDownloadEndpoint(), p.GetToken()
+		}
+	}
+	if endpoint == "" {
+		return nil, errorcode.New(errorcode.GeneralException, "the storage does not offer a download endpoint")
+	}
+
+	req, err := rhttp.NewRequest(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(TokenTransportHeader, token)
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+
+	httpRes, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch httpRes.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		return httpRes, nil
+	default:
+		httpRes.Body.Close()
+		return nil, transferErrorCode(httpRes.StatusCode, "download")
+	}
+}
+
+// uploadFile streams the body to the datagateway using the simple upload protocol
+func (g Graph) uploadFile(ctx context.Context, gatewayClient gateway.GatewayAPIClient, ref *storageprovider.Reference, body io.Reader, length int64) error {
+	res, err := gatewayClient.InitiateFileUpload(ctx, &storageprovider.InitiateFileUploadRequest{
+		Ref:    ref,
+		Opaque: utils.AppendPlainToOpaque(nil, "Upload-Length", strconv.FormatInt(length, 10)),
+	})
+	if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+		return errCode
+	}
+
+	var endpoint, token string
+	for _, p := range res.GetProtocols() {
+		if p.GetProtocol() == "simple" {
+			endpoint, token = p.GetUploadEndpoint(), p.GetToken()
+		}
+	}
+	if endpoint == "" {
+		return errorcode.New(errorcode.GeneralException, "the storage does not offer simple uploads")
+	}
+
+	req, err := rhttp.NewRequest(ctx, http.MethodPut, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(TokenTransportHeader, token)
+	req.ContentLength = length
+
+	httpRes, err := g.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode < http.StatusOK || httpRes.StatusCode >= http.StatusMultipleChoices {
+		return transferErrorCode(httpRes.StatusCode, "upload")
+	}
+	return nil
+}
+
+// transferErrorCode maps the status of a failed datagateway request to an error code
+func transferErrorCode(status int, transfer string) error {
+	msg := fmt.Sprintf("the %s failed with status %d", transfer, status)
+	switch status {
+	case http.StatusNotFound:
+		return errorcode.New(errorcode.ItemNotFound, msg)
+	case http.StatusForbidden:
+		return errorcode.New(errorcode.AccessDenied, msg)
+	case http.StatusRequestedRangeNotSatisfiable:
+		return errorcode.New(errorcode.InvalidRange, msg)
+	case http.StatusLocked:
+		return errorcode.New(errorcode.ItemIsLocked, msg)
+	case http.StatusInsufficientStorage:
+		return errorcode.New(errorcode.QuotaLimitReached, msg)
+	default:
+		return errorcode.New(errorcode.GeneralException, msg)
+	}
+}
+
+// parseDriveItemParams returns the drive id and the item id of the request. Items of other drives are not found.
+func parseDriveItemParams(r *http.Request, itemParam string) (storageprovider.ResourceId, storageprovider.ResourceId, error) {
+	driveID, err := parseIDParam(r, "driveID")
+	if err != nil {
+		return driveID, storageprovider.ResourceId{}, err
+	}
+	itemID, err := parseIDParam(r, itemParam)
+	if err != nil {
+		return driveID, itemID, err
+	}
+	if driveID.GetStorageId() != itemID.GetStorageId() || driveID.GetSpaceId() != itemID.GetSpaceId() {
+		return driveID, itemID, errorcode.New(errorcode.ItemNotFound, "item does not exist")
+	}
+	return driveID, itemID, nil
+}
+
+// statDriveItem stats the referenced item. Items the user is not allowed to see are not found.
+func statDriveItem(ctx context.Context, gatewayClient gateway.GatewayAPIClient, ref *storageprovider.Reference) (*storageprovider.ResourceInfo, error) {
+	res, err := gatewayClient.Stat(ctx, &storageprovider.StatRequest{Ref: ref})
+	if res.GetStatus().GetCode() == cs3rpc.Code_CODE_PERMISSION_DENIED {
+		return nil, errorcode.New(errorcode.ItemNotFound, res.GetStatus().GetMessage())
+	}
+	if errCode := errorcode.FromStat(res, err); errCode != nil {
+		return nil, errCode
+	}
+	return res.GetInfo(), nil
+}
+
+// parseConflictBehavior returns the conflict behavior given in the request body or the query
+func parseConflictBehavior(r *http.Request, bodyValue, defaultValue string) (string, error) {
+	behavior := bodyValue
+	if behavior == "" {
+		behavior = r.URL.Query().Get(_conflictBehavior)
+	}
+	switch behavior {
+	case "":
+		return defaultValue, nil
+	case _conflictFail, _conflictReplace, _conflictRename:
+		return behavior, nil
+	default:
+		return "", errorcode.New(errorcode.InvalidRequest, fmt.Sprintf("invalid %s '%s'", _conflictBehavior, behavior))
+	}
+}
+
+// validateDriveItemName rejects names which can not be used for items
+func validateDriveItemName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		return errorcode.New(errorcode.InvalidRequest, fmt.Sprintf("invalid item name '%s'", name))
+	}
+	return nil
+}
+
+// resolveNameConflict checks whether the folder already contains an item with the name and
+// resolves the conflict. It returns the name to use and the existing item which needs to be replaced.
+func resolveNameConflict(ctx context.Context, gatewayClient gateway.GatewayAPIClient, parentID *storageprovider.ResourceId, name, behavior string) (string, *storageprovider.ResourceInfo, error) {
+	res, err := gatewayClient.Stat(ctx, &storageprovider.StatRequest{
+		Ref: &storageprovider.Reference{ResourceId: parentID, Path: utils.MakeRelativePath(name)},
+	})
+	if errCode := errorcode.FromStat(res, err, cs3rpc.Code_CODE_NOT_FOUND); errCode != nil {
+		return "", nil, errCode
+	}
+	if res.GetStatus().GetCode() == cs3rpc.Code_CODE_NOT_FOUND {
+		return name, nil, nil
+	}
+
+	switch behavior {
+	case _conflictReplace:
+		return name, res.GetInfo(), nil
+	case _conflictRename:
+		name, err := freeItemName(ctx, gatewayClient, parentID, name)
+		return name, nil, err
+	default:
+		return "", nil, errorcode.New(errorcode.NameAlreadyExists, fmt.Sprintf("an item named '%s' already exists", name))
+	}
+}
+
+// freeItemName returns the first name following the pattern 'name (n).ext' which is not used in the folder
+func freeItemName(ctx context.Context, gatewayClient gateway.GatewayAPIClient, parentID *storageprovider.ResourceId, name string) (string, error) {
+	res, err := gatewayClient.ListContainer(ctx, &storageprovider.ListContainerRequest{
+		Ref: &storageprovider.Reference{ResourceId: parentID},
+	})
+	if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+		return "", errCode
+	}
+	used := make(map[string]struct{}, len(res.GetInfos()))
+	for _, info := range res.GetInfos() {
+		used[info.GetName()] = struct{}{}
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, ok := used[candidate]; !ok {
+			return candidate, nil
+		}
+	}
+}
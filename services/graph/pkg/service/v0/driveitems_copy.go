@@ -0,0 +1,531 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+)
+
+// CopyJobTTL is the time the status of a copy job can be requested for, it is the TTL of the copy job bucket
+const CopyJobTTL = 24 * time.Hour
+
+const (
+	// _copyJobSaveInterval limits how often the progress of a copy job is written to the key value store
+	_copyJobSaveInterval = time.Second
+	// _copyJobsPerUser is the number of copy jobs a user can run at the same time on an instance of the service
+	_copyJobsPerUser = 10
+
+	// the states of an async job
+	_jobNotStarted = "notStarted"
+	_jobInProgress = "inProgress"
+	_jobCompleted  = "completed"
+	_jobFailed     = "failed"
+)
+
+// AsyncJobStatus describes the progress of a long-running operation
+type AsyncJobStatus struct {
+	Operation          string  `json:"operation"`
+	PercentageComplete float64 `json:"percentageComplete"`
+	ResourceID         string  `json:"resourceId,omitempty"`
+	Status             string  `json:"status"`
+	StatusDescription  string  `json:"statusDescription,omitempty"`
+}
+
+// copyJobState is the state of a copy job kept in the key value store, so that the status can be
+// requested from every instance of the service
+type copyJobState struct {
+	UserID  string         `json:"userId"`
+	Expires time.Time      `json:"expires"`
+	Status  AsyncJobStatus `json:"status"`
+}
+
+// driveItemCopyJob tracks a copy running in the background
+type driveItemCopyJob struct {
+	mu     sync.Mutex
+	id     string
+	kv     jetstream.KeyValue
+	logger *log.Logger
+	saved  time.Time
+	total  int64
+	copied int64
+	state  copyJobState
+}
+
+// copyJobLimiter counts the running copy jobs of the users
+type copyJobLimiter struct {
+	mu      sync.Mutex
+	running map[string]int
+}
+
+func newCopyJobLimiter() *copyJobLimiter {
+	return &copyJobLimiter{running: map[string]int{}}
+}
+
+// acquire reserves a copy job for the user, it returns false if the user reached the limit
+func (l *copyJobLimiter) acquire(userID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.running[userID] >= _copyJobsPerUser {
+		return false
+	}
+	l.running[userID]++
+	return true
+}
+
+// release frees a copy job reserved for the user
+func (l *copyJobLimiter) release(userID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.running[userID] <= 1 {
+		delete(l.running, userID)
+		return
+	}
+	l.running[userID]--
+}
+
+// copyTreeEntry is an item below a copied folder
+type copyTreeEntry struct {
+	path string
+	info *storageprovider.ResourceInfo
+}
+
+// CopyDriveItem copies a drive item into a folder of the same or another drive. The copy runs
+// in the background, the response points to the monitor url reporting its progress.
+func (g Graph) CopyDriveItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+	logger.Debug().Msg("calling copy drive item")
+
+	_, itemID, err := parseDriveItemParams(r, "driveItemID")
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if IsSpaceRoot(&itemID) {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "the root of a drive can not be copied")
+		return
+	}
+
+	body := driveItemTarget{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	behavior, err := parseConflictBehavior(r, body.ConflictBehavior, _conflictFail)
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	if g.copyjobkv == nil {
+		errorcode.NotSupported.Render(w, r, http.StatusNotImplemented, "copying drive items requires the key value store")
+		return
+	}
+
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	source, err := statDriveItem(ctx, gatewayClient, &storageprovider.Reference{ResourceId: &itemID})
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	name := source.GetName()
+	if body.Name != nil {
+		name = *body.Name
+		if err := validateDriveItemName(name); err != nil {
+			errorcode.RenderError(w, r, err)
+			return
+		}
+	}
+	parentID, err := copyTargetID(body.ParentReference, source.GetParentId())
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	parent, err := statDriveItem(ctx, gatewayClient, &storageprovider.Reference{ResourceId: parentID})
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if parent.GetType() != storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "the parentReference is not a folder")
+		return
+	}
+
+	if source.GetType() == storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER &&
+		source.GetId().GetSpaceId() == parentID.GetSpaceId() {
+		inside, err := g.isInsideFolder(ctx, parentID, source.GetId())
+		if err != nil {
+			errorcode.RenderError(w, r, err)
+			return
+		}
+		if inside {
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "a folder can not be copied into itself")
+			return
+		}
+	}
+
+	name, existing, err := resolveNameConflict(ctx, gatewayClient, parentID, name, behavior)
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if existing != nil && utils.ResourceIDEqual(existing.GetId(), source.GetId()) {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "an item can not replace itself")
+		return
+	}
+
+	currentUser, _ := revactx.ContextGetUser(ctx)
+	userID := currentUser.GetId().GetOpaqueId()
+	if !g.copyJobs.acquire(userID) {
+		errorcode.ActivityLimitReached.Render(w, r, http.StatusTooManyRequests, "too many copy jobs are running")
+		return
+	}
+	job := &driveItemCopyJob{
+		id:     uuid.NewString(),
+		kv:     g.copyjobkv,
+		logger: g.logger,
+		state: copyJobState{
+			UserID:  userID,
+			Expires: time.Now().Add(CopyJobTTL),
+			Status:  AsyncJobStatus{Operation: "itemCopy", Status: _jobNotStarted},
+		},
+	}
+	if err := job.save(ctx); err != nil {
+		g.copyJobs.release(userID)
+		logger.Error().Err(err).Msg("could not store the copy job")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not start the copy job")
+		return
+	}
+
+	// the copy outlives the request but keeps the user and the token of its context
+	go func() {
+		defer g.copyJobs.release(userID)
+		g.runCopyJob(context.WithoutCancel(ctx), job, source, parentID, name, existing)
+	}()
+
+	w.Header().Set("Location", g.monitorURL(job.id))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// GetAsyncJobStatus reports the progress of a copy job started by the user
+func (g Graph) GetAsyncJobStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+	logger.Debug().Msg("calling get async job status")
+
+	u, ok := revactx.ContextGetUser(ctx)
+	jobID := chi.URLParam(r, "jobID")
+	if !ok || g.copyjobkv == nil || jobID == "" {
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "job does not exist")
+		return
+	}
+
+	entry, err := g.copyjobkv.Get(ctx, jobID)
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound), errors.Is(err, jetstream.ErrInvalidKey):
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "job does not exist")
+		return
+	case err != nil:
+		logger.Error().Err(err).Str("job", jobID).Msg("could not read the copy job")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not read the copy job")
+		return
+	}
+	state := copyJobState{}
+	if err := json.Unmarshal(entry.Value(), &state); err != nil {
+		logger.Error().Err(err).Str("job", jobID).Msg("could not decode the copy job")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not read the copy job")
+		return
+	}
+	// the bucket expires the jobs some time after their last update, the status of a
+	// job is only reported for CopyJobTTL after it was started
+	if time.Now().After(state.Expires) || state.UserID != u.GetId().GetOpaqueId() {
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "job does not exist")
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, state.Status)
+}
+
+// monitorURL returns the url reporting the status of the job
+func (g Graph) monitorURL(jobID string) string {
+	var base string
+	if g.config.Commons != nil {
+		base = strings.TrimRight(g.config.Commons.OpenCloudURL, "/")
+	}
+	return base + path.Join("/", g.config.HTTP.Root, "v1.0/monitor", jobID)
+}
+
+// copyTargetID returns the folder the item is copied to. The parent of the item is used if no folder
+// is given, the root of the drive if only the drive is given.
+func copyTargetID(ref *libregraph.ItemReference, sourceParentID *storageprovider.ResourceId) (*storageprovider.ResourceId, error) {
+	var id string
+	switch {
+	case ref == nil || (ref.GetId() == "" && ref.GetDriveId() == ""):
+		return sourceParentID, nil
+	case ref.GetId() != "":
+		id = ref.GetId()
+	default:
+		id = ref.GetDriveId()
+	}
+
+	parentID, err := storagespace.ParseID(id)
+	if err != nil {
+		return nil, errorcode.New(errorcode.InvalidRequest, "invalid parentReference: "+err.Error())
+	}
+	if ref.GetDriveId() != "" {
+		driveID, err := storagespace.ParseID(ref.GetDriveId())
+		if err != nil {
+			return nil, errorcode.New(errorcode.InvalidRequest, "invalid parentReference: "+err.Error())
+		}
+		if driveID.GetStorageId() != parentID.GetStorageId() || driveID.GetSpaceId() != parentID.GetSpaceId() {
+			return nil, errorcode.New(errorcode.InvalidRequest, "the parentReference id is not part of the drive")
+		}
+	}
+	if parentID.GetOpaqueId() == "" {
+		parentID.OpaqueId = parentID.GetSpaceId()
+	}
+	return &parentID, nil
+}
+
+// isInsideFolder returns true if the item is the folder or located below it
+func (g Graph) isInsideFolder(ctx context.Context, itemID, folderID *storageprovider.ResourceId) (bool, error) {
+	itemPath, err := g.getPathForResource(ctx, *itemID)
+	if err != nil {
+		return false, errorcode.New(errorcode.GeneralException, err.Error())
+	}
+	folderPath, err := g.getPathForResource(ctx, *folderID)
+	if err != nil {
+		return false, errorcode.New(errorcode.GeneralException, err.Error())
+	}
+	return itemPath == folderPath || strings.HasPrefix(itemPath, strings.TrimSuffix(folderPath, "/")+"/"), nil
+}
+
+func (g Graph) runCopyJob(ctx context.Context, job *driveItemCopyJob, source *storageprovider.ResourceInfo, parentID *storageprovider.ResourceId, name string, existing *storageprovider.ResourceInfo) {
+	job.update(ctx, true, func(s *AsyncJobStatus) {
+		s.Status = _jobInProgress
+	})
+
+	id, err := g.copyDriveItem(ctx, job, source, parentID, name, existing)
+	if err != nil {
+		g.logger.Error().Err(err).Str("source", storagespace.FormatResourceID(source.GetId())).Msg("could not copy drive item")
+		job.update(ctx, true, func(s *AsyncJobStatus) {
+			s.Status = _jobFailed
+			s.StatusDescription = err.Error()
+		})
+		return
+	}
+	job.update(ctx, true, func(s *AsyncJobStatus) {
+		s.Status = _jobCompleted
+		s.PercentageComplete = 100
+		s.ResourceID = storagespace.FormatResourceID(id)
+	})
+}
+
+// copyDriveItem copies the item and everything below it through the gateway. When an existing item
+// is replaced the copy is created with a temporary name first and swapped with the existing item
+// after it succeeded, so the existing item is kept if the copy fails.
+func (g Graph) copyDriveItem(ctx context.Context, job *driveItemCopyJob, source *storageprovider.ResourceInfo, parentID *storageprovider.ResourceId, name string, existing *storageprovider.ResourceInfo) (*storageprovider.ResourceId, error) {
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	copyName := name
+	if existing != nil {
+		copyName = ".~copy-" + job.id
+	}
+	target := &storageprovider.Reference{ResourceId: parentID, Path: utils.MakeRelativePath(copyName)}
+	if err := g.copyTree(ctx, gatewayClient, job, source, parentID, copyName); err != nil {
+		if existing != nil {
+			// don't leave the partial copy behind
+			res, derr := gatewayClient.Delete(ctx, &storageprovider.DeleteRequest{Ref: target})
+			if errCode := errorcode.FromCS3Status(res.GetStatus(), derr); errCode != nil {
+				g.logger.Debug().Err(errCode).Str("name", copyName).Msg("could not remove the partial copy")
+			}
+		}
+		return nil, err
+	}
+
+	if existing != nil {
+		copied, err := statDriveItem(ctx, gatewayClient, target)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.replaceDriveItem(ctx, gatewayClient, copied.GetId(), existing, parentID, name); err != nil {
+			return nil, err
+		}
+		return copied.GetId(), nil
+	}
+
+	info, err := statDriveItem(ctx, gatewayClient, target)
+	if err != nil {
+		return nil, err
+	}
+	return info.GetId(), nil
+}
+
+// copyTree copies the item and everything below it to the name in the folder
+func (g Graph) copyTree(ctx context.Context, gatewayClient gateway.GatewayAPIClient, job *driveItemCopyJob, source *storageprovider.ResourceInfo, parentID *storageprovider.ResourceId, name string) error {
+	target := &storageprovider.Reference{ResourceId: parentID, Path: utils.MakeRelativePath(name)}
+	if source.GetType() != storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		job.addTotal(source.GetSize())
+		return g.copyFile(ctx, gatewayClient, job, source, target)
+	}
+
+	entries, err := listCopyTree(ctx, gatewayClient, source.GetId(), "")
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.info.GetType() != storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			job.addTotal(e.info.GetSize())
+		}
+	}
+
+	if err := createFolder(ctx, gatewayClient, target); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		ref := &storageprovider.Reference{ResourceId: parentID, Path: utils.MakeRelativePath(path.Join(name, e.path))}
+		if e.info.GetType() == storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			if err := createFolder(ctx, gatewayClient, ref); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := g.copyFile(ctx, gatewayClient, job, e.info, ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile streams the content of the file from the datagateway back into the new file. The
+// transferred bytes are reported to the job.
+func (g Graph) copyFile(ctx context.Context, gatewayClient gateway.GatewayAPIClient, job *driveItemCopyJob, source *storageprovider.ResourceInfo, target *storageprovider.Reference) error {
+	res, err := g.downloadFile(ctx, gatewayClient, &storageprovider.Reference{ResourceId: source.GetId()}, "")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	length := res.ContentLength
+	if length < 0 {
+		length = int64(source.GetSize())
+	}
+	return g.uploadFile(ctx, gatewayClient, target, &copyProgressReader{ctx: ctx, r: res.Body, job: job}, length)
+}
+
+func createFolder(ctx context.Context, gatewayClient gateway.GatewayAPIClient, ref *storageprovider.Reference) error {
+	res, err := gatewayClient.CreateContainer(ctx, &storageprovider.CreateContainerRequest{Ref: ref})
+	return errorcode.FromCS3Status(res.GetStatus(), err)
+}
+
+// listCopyTree lists all items below the folder, folders are listed before their children
+func listCopyTree(ctx context.Context, gatewayClient gateway.GatewayAPIClient, folderID *storageprovider.ResourceId, folderPath string) ([]copyTreeEntry, error) {
+	res, err := gatewayClient.ListContainer(ctx, &storageprovider.ListContainerRequest{
+		Ref: &storageprovider.Reference{ResourceId: folderID},
+	})
+	if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+		return nil, errCode
+	}
+
+	var entries []copyTreeEntry
+	for _, info := range res.GetInfos() {
+		p := path.Join(folderPath, info.GetName())
+		entries = append(entries, copyTreeEntry{path: p, info: info})
+		if info.GetType() == storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			children, err := listCopyTree(ctx, gatewayClient, info.GetId(), p)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, children...)
+		}
+	}
+	return entries, nil
+}
+
+// copyProgressReader reports the bytes read from the source to the copy job
+type copyProgressReader struct {
+	ctx context.Context
+	r   io.Reader
+	job *driveItemCopyJob
+}
+
+func (c *copyProgressReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.job.addCopied(c.ctx, int64(n))
+	}
+	return n, err
+}
+
+func (j *driveItemCopyJob) addTotal(size uint64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.total += int64(size)
+}
+
+func (j *driveItemCopyJob) addCopied(ctx context.Context, n int64) {
+	j.update(ctx, false, func(s *AsyncJobStatus) {
+		j.copied += n
+		if j.total > 0 {
+			s.PercentageComplete = min(float64(j.copied)*100/float64(j.total), 100)
+		}
+	})
+}
+
+// update changes the status of the job and writes it to the key value store. Progress updates are
+// written at most once per _copyJobSaveInterval, changes of the state are always written.
+func (j *driveItemCopyJob) update(ctx context.Context, force bool, f func(s *AsyncJobStatus)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f(&j.state.Status)
+	if !force && time.Since(j.saved) < _copyJobSaveInterval {
+		return
+	}
+	if err := j.save(ctx); err != nil {
+		j.logger.Error().Err(err).Str("job", j.id).Msg("could not store the copy job")
+	}
+}
+
+// save writes the state of the job to the key value store, the caller has to hold the lock
+// unless the job is not running yet
+func (j *driveItemCopyJob) save(ctx context.Context) error {
+	b, err := json.Marshal(j.state)
+	if err != nil {
+		return err
+	}
+	if _, err := j.kv.Put(ctx, j.id, b); err != nil {
+		return err
+	}
+	j.saved = time.Now()
+	return nil
+}
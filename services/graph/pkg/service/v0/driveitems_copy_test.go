@@ -0,0 +1,69 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+)
+
+// putKeyValue records the values put into the key value store
+type putKeyValue struct {
+	jetstream.KeyValue
+	put func(key string, value []byte)
+}
+
+func (kv putKeyValue) Put(_ context.Context, key string, value []byte) (uint64, error) {
+	kv.put(key, value)
+	return 1, nil
+}
+
+func TestCopyProgressReader(t *testing.T) {
+	var stored []AsyncJobStatus
+	kv := putKeyValue{put: func(key string, b []byte) {
+		assert.Equal(t, "job", key)
+		state := copyJobState{}
+		assert.NoError(t, json.Unmarshal(b, &state))
+		stored = append(stored, state.Status)
+	}}
+
+	logger := log.NopLogger()
+	job := &driveItemCopyJob{id: "job", kv: kv, logger: &logger}
+	job.addTotal(10)
+
+	r := &copyProgressReader{ctx: context.Background(), r: io.LimitReader(strings.NewReader("0123456789"), 10), job: job}
+	buf := make([]byte, 4)
+	_, err := r.Read(buf)
+	assert.NoError(t, err)
+	// the progress of a single file is stored while it is copied
+	assert.Len(t, stored, 1)
+	assert.Equal(t, float64(40), stored[0].PercentageComplete)
+
+	_, err = io.Copy(io.Discard, r)
+	assert.NoError(t, err)
+	// later updates are throttled but kept in memory
+	assert.Len(t, stored, 1)
+	assert.Equal(t, float64(100), job.state.Status.PercentageComplete)
+}
+
+func TestCopyJobLimiter(t *testing.T) {
+	l := newCopyJobLimiter()
+	for i := 0; i < _copyJobsPerUser; i++ {
+		assert.True(t, l.acquire("user"))
+	}
+	assert.False(t, l.acquire("user"))
+	// the limit applies per user
+	assert.True(t, l.acquire("other"))
+
+	l.release("user")
+	assert.True(t, l.acquire("user"))
+
+	l.release("other")
+	assert.NotContains(t, l.running, "other")
+}
@@ -0,0 +1,594 @@
+package svc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	cs3rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+)
+
+const (
+	// _deltaToken is the query parameter carrying the opaque delta token
+	_deltaToken = "token"
+	// _deltaTokenLatest requests a delta token without enumerating the items
+	_deltaTokenLatest = "latest"
+	// _deltaPageSize is the number of items on a page of a delta query without $top
+	_deltaPageSize = 1000
+)
+
+// deltaState is the state of a delta query carried by its token. A synchronization is a session
+// of rounds, every round lists the changes since the previous one and may span several pages.
+type deltaState struct {
+	// Session identifies the snapshots of the folders the client synchronized
+	Session string `json:"s"`
+	// Drive is the id of the drive the token was issued for
+	Drive string `json:"v"`
+	// Item is the opaque id of the folder the token was issued for
+	Item string `json:"o"`
+	// Round counts the rounds of the session, snapshots written in a round are used by the following rounds
+	Round int `json:"r"`
+	// Since is the tree modification time of the folder at the start of the previous round,
+	// zero when all items are listed
+	Since int64 `json:"t,omitempty"`
+	// State is the tree modification time of the folder at the start of the current round
+	State int64 `json:"n,omitempty"`
+	// Stack is the position of the next page in the current round
+	Stack []deltaFrame `json:"p,omitempty"`
+	// Trash is set when the deleted items of a folder without snapshot have to be read from the trash bin
+	Trash bool `json:"d,omitempty"`
+}
+
+// next returns the state of the round following the current one
+func (s *deltaState) next() *deltaState {
+	return &deltaState{Session: s.Session, Drive: s.Drive, Item: s.Item, Round: s.Round + 1, Since: s.State}
+}
+
+// deltaFrame is a folder on the path to the position of the next page
+type deltaFrame struct {
+	// ID is the opaque id of the folder
+	ID string `json:"i"`
+	// After is the opaque id of the last child that was processed
+	After string `json:"a,omitempty"`
+	// Full is set when all items below the folder are listed
+	Full bool `json:"f,omitempty"`
+}
+
+// deltaSnapshot describes the children of a folder as they were reported to the client
+type deltaSnapshot struct {
+	Round    int               `json:"r"`
+	Children map[string]string `json:"c"`
+}
+
+// GetDriveItemDelta lists the items of a folder that were added, changed or deleted since the
+// state described by the delta token. Without a token all items of the folder are listed.
+// The last page of the response contains the deltaLink to request the following changes with,
+// the other pages the nextLink to the following page.
+//
+// Only folders whose tree modification time changed are read. Their children are compared with a
+// snapshot of the state reported to the client, so changed, moved, renamed and removed items are
+// found independent of the modification times set by clients.
+func (g Graph) GetDriveItemDelta(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+	logger.Debug().Msg("calling get drive item delta")
+
+	driveID, err := parseIDParam(r, "driveID")
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	// the root of the drive is requested if there is no item id
+	itemID := storageprovider.ResourceId{
+		StorageId: driveID.GetStorageId(),
+		SpaceId:   driveID.GetSpaceId(),
+		OpaqueId:  driveID.GetSpaceId(),
+	}
+	if chi.URLParam(r, "itemID") != "" {
+		if itemID, err = parseIDParam(r, "itemID"); err != nil {
+			errorcode.RenderError(w, r, err)
+			return
+		}
+		if driveID.GetStorageId() != itemID.GetStorageId() || driveID.GetSpaceId() != itemID.GetSpaceId() {
+			errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "item does not exist")
+			return
+		}
+	}
+
+	top := _deltaPageSize
+	if v := r.URL.Query().Get("$top"); v != "" {
+		if top, err = strconv.Atoi(v); err != nil || top <= 0 {
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid $top")
+			return
+		}
+	}
+
+	state := &deltaState{
+		Session: uuid.New().String(),
+		Drive:   storagespace.FormatStorageID(driveID.GetStorageId(), driveID.GetSpaceId()),
+		Item:    itemID.GetOpaqueId(),
+		Round:   1,
+	}
+	token := r.URL.Query().Get(_deltaToken)
+	if token != "" && token != _deltaTokenLatest {
+		decoded, err := decodeDeltaToken(token)
+		if err != nil {
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if decoded.Drive != state.Drive || decoded.Item != state.Item {
+			// the snapshots of the session describe another folder
+			errorcode.ResyncRequired.Render(w, r, http.StatusGone, "the delta token was issued for another item")
+			return
+		}
+		state = decoded
+	}
+
+	if len(state.Stack) == 0 {
+		// a new round starts, the tree modification time of the folder includes the changes
+		// of all items below it and becomes the state of the next delta token
+		gatewayClient, err := g.gatewaySelector.Next()
+		if err != nil {
+			errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		statRes, err := gatewayClient.Stat(ctx, &storageprovider.StatRequest{
+			Ref: &storageprovider.Reference{ResourceId: &itemID},
+		})
+		if errCode := deltaErrorCode(statRes.GetStatus(), err); errCode != nil {
+			logger.Debug().Err(errCode).Msg("could not get drive item delta: stat failed")
+			errorcode.RenderError(w, r, errCode)
+			return
+		}
+		folder := statRes.GetInfo()
+		if folder.GetType() != storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "delta is only supported for folders")
+			return
+		}
+		state.State = cs3TimestampToTime(folder.GetMtime()).UnixNano()
+
+		if token == _deltaTokenLatest {
+			render.Status(r, http.StatusOK)
+			render.JSON(w, r, &ListResponse{DeltaLink: g.deltaLink(r, state.next())})
+			return
+		}
+		if state.Since != 0 && state.State <= state.Since {
+			// nothing changed below the folder, the client can keep the token
+			render.Status(r, http.StatusOK)
+			render.JSON(w, r, &ListResponse{DeltaLink: g.deltaLink(r, state)})
+			return
+		}
+		state.Stack = []deltaFrame{{ID: itemID.GetOpaqueId()}}
+	}
+
+	items, err := g.deltaPage(ctx, &itemID, state, top)
+	if err != nil {
+		logger.Debug().Err(err).Msg("could not get drive item delta: listing the changes failed")
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	res := &ListResponse{Value: items}
+	if len(state.Stack) > 0 {
+		res.NextLink = g.deltaLink(r, state)
+	} else {
+		if state.Trash && state.Since != 0 {
+			deleted, err := g.deletedDriveItems(ctx, &itemID, time.Unix(0, state.Since))
+			if err != nil {
+				logger.Debug().Err(err).Msg("could not get drive item delta: listing the deleted items failed")
+				errorcode.RenderError(w, r, err)
+				return
+			}
+			res.Value = append(items, deleted...)
+		}
+		res.DeltaLink = g.deltaLink(r, state.next())
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, res)
+}
+
+// deltaPage walks the changed folders depth first, starting at the position of the stack,
+// until the page is full or all folders were walked. The stack is updated to the position
+// of the next page.
+func (g Graph) deltaPage(ctx context.Context, folderID *storageprovider.ResourceId, state *deltaState, top int) ([]*libregraph.DriveItem, error) {
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		return nil, err
+	}
+	resourceID := func(opaqueID string) *storageprovider.ResourceId {
+		return &storageprovider.ResourceId{
+			StorageId: folderID.GetStorageId(),
+			SpaceId:   folderID.GetSpaceId(),
+			OpaqueId:  opaqueID,
+		}
+	}
+
+	// folders are listed once per page, so that their children don't change while walking below them
+	type listing struct {
+		infos    []*storageprovider.ResourceInfo
+		snapshot *deltaSnapshot
+		// resumed is set if the folder was listed on a previous page already
+		resumed bool
+	}
+	listings := map[string]*listing{}
+
+	items := []*libregraph.DriveItem{}
+	for len(state.Stack) > 0 && len(items) < top {
+		frame := state.Stack[len(state.Stack)-1]
+		listAll := state.Since == 0 || frame.Full
+
+		l, ok := listings[frame.ID]
+		if !ok {
+			lcRes, err := gatewayClient.ListContainer(ctx, &storageprovider.ListContainerRequest{
+				Ref: &storageprovider.Reference{ResourceId: resourceID(frame.ID)},
+			})
+			if errCode := deltaErrorCode(lcRes.GetStatus(), err); errCode != nil {
+				return nil, errCode
+			}
+			l = &listing{infos: lcRes.GetInfos(), resumed: frame.After != ""}
+			slices.SortFunc(l.infos, func(a, b *storageprovider.ResourceInfo) int {
+				return strings.Compare(a.GetId().GetOpaqueId(), b.GetId().GetOpaqueId())
+			})
+
+			if !listAll {
+				if l.snapshot, err = g.deltaSnapshot(ctx, state.Session, frame.ID); err != nil {
+					return nil, err
+				}
+				if l.snapshot != nil && l.snapshot.Round >= state.Round {
+					// written by this or a later round of a token that was used before
+					l.snapshot = nil
+				}
+				if l.snapshot == nil {
+					state.Trash = true
+				}
+			}
+			listings[frame.ID] = l
+		}
+		infos, snapshot := l.infos, l.snapshot
+
+		var child *deltaFrame
+		for _, info := range infos {
+			id := info.GetId().GetOpaqueId()
+			if frame.After != "" && id <= frame.After {
+				continue
+			}
+			if len(items) >= top {
+				break
+			}
+
+			known := false
+			if snapshot != nil {
+				var fingerprint string
+				fingerprint, known = snapshot.Children[id]
+				known = known && fingerprint == deltaFingerprint(info)
+			}
+			if !known {
+				item, err := cs3ResourceToDriveItem(g.logger, info)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+			frame.After = id
+
+			if info.GetType() != storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER {
+				continue
+			}
+			// folders the client doesn't know yet, e.g. because they were moved here, are listed completely
+			_, seen := snapshot.children()[id]
+			full := listAll || (snapshot != nil && !seen)
+			if full || cs3TimestampToTime(info.GetMtime()).UnixNano() > state.Since {
+				child = &deltaFrame{ID: id, Full: full}
+				break
+			}
+		}
+		state.Stack[len(state.Stack)-1] = frame
+
+		switch {
+		case child != nil:
+			state.Stack = append(state.Stack, *child)
+			continue
+		case len(items) >= top && frame.After != lastOpaqueID(infos):
+			// the page is full before the folder was walked completely
+			continue
+		}
+
+		// the folder was walked completely
+		if snapshot != nil {
+			removed, err := g.removedDriveItems(ctx, folderID, snapshot, infos)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, removed...)
+		}
+		if err := g.storeDeltaSnapshot(ctx, state, frame.ID, infos, l.resumed); err != nil {
+			return nil, err
+		}
+		state.Stack = state.Stack[:len(state.Stack)-1]
+	}
+	return items, nil
+}
+
+// removedDriveItems returns the items of the snapshot that are not children of the folder anymore
+// and were deleted or moved out of the requested folder. Items moved to another folder below the
+// requested folder are listed as changed items of that folder.
+func (g Graph) removedDriveItems(ctx context.Context, folderID *storageprovider.ResourceId, snapshot *deltaSnapshot, infos []*storageprovider.ResourceInfo) ([]*libregraph.DriveItem, error) {
+	children := make(map[string]struct{}, len(infos))
+	for _, info := range infos {
+		children[info.GetId().GetOpaqueId()] = struct{}{}
+	}
+
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	var folderPath string
+	items := []*libregraph.DriveItem{}
+	for id := range snapshot.Children {
+		if _, ok := children[id]; ok {
+			continue
+		}
+		rid := &storageprovider.ResourceId{
+			StorageId: folderID.GetStorageId(),
+			SpaceId:   folderID.GetSpaceId(),
+			OpaqueId:  id,
+		}
+
+		statRes, err := gatewayClient.Stat(ctx, &storageprovider.StatRequest{
+			Ref: &storageprovider.Reference{ResourceId: rid},
+		})
+		switch {
+		case err != nil:
+			return nil, errorcode.New(errorcode.GeneralException, err.Error())
+		case statRes.GetStatus().GetCode() == cs3rpc.Code_CODE_OK:
+			// moved within the drive, every item of the drive is below its root
+			if folderID.GetOpaqueId() == folderID.GetSpaceId() {
+				continue
+			}
+			if folderPath == "" {
+				if folderPath, err = deltaPath(ctx, gatewayClient, folderID); err != nil {
+					return nil, err
+				}
+			}
+			itemPath, err := deltaPath(ctx, gatewayClient, rid)
+			if err != nil {
+				return nil, err
+			}
+			if isSubpath(folderPath, itemPath) {
+				continue
+			}
+		case statRes.GetStatus().GetCode() != cs3rpc.Code_CODE_NOT_FOUND:
+			return nil, errorcode.New(errorcode.GeneralException, statRes.GetStatus().GetMessage())
+		}
+
+		items = append(items, &libregraph.DriveItem{
+			Id:      libregraph.PtrString(storagespace.FormatResourceID(rid)),
+			Deleted: &libregraph.Deleted{State: libregraph.PtrString("deleted")},
+		})
+	}
+	return items, nil
+}
+
+// deletedDriveItems returns the items of the folder moved to the trash bin after the given time.
+// It is only needed for folders without a snapshot of their children.
+func (g Graph) deletedDriveItems(ctx context.Context, folderID *storageprovider.ResourceId, since time.Time) ([]*libregraph.DriveItem, error) {
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	var folderPath string
+	if folderID.GetOpaqueId() != folderID.GetSpaceId() {
+		if folderPath, err = deltaPath(ctx, gatewayClient, folderID); err != nil {
+			return nil, err
+		}
+	}
+
+	spaceRoot := &storageprovider.ResourceId{
+		StorageId: folderID.GetStorageId(),
+		SpaceId:   folderID.GetSpaceId(),
+		OpaqueId:  folderID.GetSpaceId(),
+	}
+	lrRes, err := gatewayClient.ListRecycle(ctx, &storageprovider.ListRecycleRequest{
+		Ref:    &storageprovider.Reference{ResourceId: spaceRoot, Path: "."},
+		FromTs: utils.TimeToTS(since),
+	})
+	if lrRes.GetStatus().GetCode() == cs3rpc.Code_CODE_PERMISSION_DENIED {
+		// the trash bin can only be listed with the permission to restore items
+		return nil, errorcode.New(errorcode.AccessDenied, "the deleted items can't be listed without permission to list the trash bin")
+	}
+	if errCode := deltaErrorCode(lrRes.GetStatus(), err); errCode != nil {
+		return nil, errCode
+	}
+
+	items := []*libregraph.DriveItem{}
+	for _, ri := range lrRes.GetRecycleItems() {
+		if !cs3TimestampToTime(ri.GetDeletionTime()).After(since) {
+			continue
+		}
+		itemPath := path.Clean("/" + ri.GetRef().GetPath())
+		if !isSubpath(folderPath, itemPath) {
+			continue
+		}
+		items = append(items, &libregraph.DriveItem{
+			Id: libregraph.PtrString(storagespace.FormatResourceID(&storageprovider.ResourceId{
+				StorageId: folderID.GetStorageId(),
+				SpaceId:   folderID.GetSpaceId(),
+				OpaqueId:  ri.GetKey(),
+			})),
+			Name:    libregraph.PtrString(path.Base(itemPath)),
+			Deleted: &libregraph.Deleted{State: libregraph.PtrString("deleted")},
+		})
+	}
+	return items, nil
+}
+
+// deltaSnapshot returns the snapshot of the children of the folder of the session, nil if there is none.
+func (g Graph) deltaSnapshot(ctx context.Context, session, folderID string) (*deltaSnapshot, error) {
+	if g.deltakv == nil {
+		return nil, nil
+	}
+	entry, err := g.deltakv.Get(ctx, deltaSnapshotKey(session, folderID))
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, errorcode.New(errorcode.GeneralException, err.Error())
+	}
+	snapshot := &deltaSnapshot{}
+	if err := json.Unmarshal(entry.Value(), snapshot); err != nil {
+		// an unreadable snapshot is treated like a missing one
+		return nil, nil
+	}
+	return snapshot, nil
+}
+
+// storeDeltaSnapshot stores the children of a folder that were reported in the current round. The
+// children of a folder that was listed on several pages may have changed in between, the snapshot
+// is removed then, so that the next round lists the folder completely.
+func (g Graph) storeDeltaSnapshot(ctx context.Context, state *deltaState, folderID string, infos []*storageprovider.ResourceInfo, resumed bool) error {
+	if g.deltakv == nil {
+		return nil
+	}
+	key := deltaSnapshotKey(state.Session, folderID)
+	if resumed {
+		if err := g.deltakv.Delete(ctx, key); err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+			return errorcode.New(errorcode.GeneralException, err.Error())
+		}
+		return nil
+	}
+
+	snapshot := deltaSnapshot{Round: state.Round, Children: make(map[string]string, len(infos))}
+	for _, info := range infos {
+		snapshot.Children[info.GetId().GetOpaqueId()] = deltaFingerprint(info)
+	}
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return errorcode.New(errorcode.GeneralException, err.Error())
+	}
+	if _, err := g.deltakv.Put(ctx, key, b); err != nil {
+		return errorcode.New(errorcode.GeneralException, err.Error())
+	}
+	return nil
+}
+
+// children returns the children of the snapshot, it is safe to call on a nil snapshot
+func (s *deltaSnapshot) children() map[string]string {
+	if s == nil {
+		return nil
+	}
+	return s.Children
+}
+
+// deltaFingerprint changes whenever an item changes in a way the client has to know about. Clients
+// can set any modification time, so the content is compared by checksum and size.
+func deltaFingerprint(info *storageprovider.ResourceInfo) string {
+	h := fnv.New64a()
+	for _, s := range []string{
+		path.Base(info.GetPath()),
+		info.GetEtag(),
+		info.GetChecksum().GetSum(),
+		strconv.FormatUint(info.GetSize(), 10),
+		strconv.FormatInt(cs3TimestampToTime(info.GetMtime()).UnixNano(), 10),
+	} {
+		_, _ = h.Write([]byte(s))
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// deltaPath returns the path of the item relative to the root of its drive
+func deltaPath(ctx context.Context, gatewayClient gateway.GatewayAPIClient, id *storageprovider.ResourceId) (string, error) {
+	res, err := gatewayClient.GetPath(ctx, &storageprovider.GetPathRequest{ResourceId: id})
+	if errCode := deltaErrorCode(res.GetStatus(), err); errCode != nil {
+		return "", errCode
+	}
+	return res.GetPath(), nil
+}
+
+func deltaSnapshotKey(session, folderID string) string {
+	return session + "." + base64.RawURLEncoding.EncodeToString([]byte(folderID))
+}
+
+func lastOpaqueID(infos []*storageprovider.ResourceInfo) string {
+	if len(infos) == 0 {
+		return ""
+	}
+	return infos[len(infos)-1].GetId().GetOpaqueId()
+}
+
+// isSubpath returns true if the item path is below the folder path
+func isSubpath(folderPath, itemPath string) bool {
+	folderPath = strings.TrimSuffix(path.Clean("/"+folderPath), "/") + "/"
+	return strings.HasPrefix(path.Clean("/"+itemPath), folderPath)
+}
+
+// deltaLink returns the url to request the given state of the delta query with
+func (g Graph) deltaLink(r *http.Request, state *deltaState) string {
+	query := r.URL.Query()
+	query.Set(_deltaToken, encodeDeltaToken(state))
+
+	var base string
+	if g.config.Commons != nil {
+		base = strings.TrimRight(g.config.Commons.OpenCloudURL, "/")
+	}
+	return base + r.URL.Path + "?" + query.Encode()
+}
+
+func deltaErrorCode(status *cs3rpc.Status, err error) error {
+	switch {
+	case err != nil:
+		return errorcode.New(errorcode.GeneralException, err.Error())
+	case status.GetCode() == cs3rpc.Code_CODE_OK:
+		return nil
+	case status.GetCode() == cs3rpc.Code_CODE_NOT_FOUND, status.GetCode() == cs3rpc.Code_CODE_PERMISSION_DENIED:
+		return errorcode.New(errorcode.ItemNotFound, status.GetMessage())
+	default:
+		return errorcode.New(errorcode.GeneralException, status.GetMessage())
+	}
+}
+
+func encodeDeltaToken(state *deltaState) string {
+	b, _ := json.Marshal(state)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeDeltaToken(token string) (*deltaState, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid delta token")
+	}
+	state := &deltaState{}
+	if err := json.Unmarshal(b, state); err != nil || state.Round < 1 || state.Since < 0 {
+		return nil, errors.New("invalid delta token")
+	}
+	// the session is part of the snapshot keys, it must not contain any characters
+	// with a meaning in key value store keys
+	session, err := uuid.Parse(state.Session)
+	if err != nil || session.String() != state.Session {
+		return nil, errors.New("invalid delta token")
+	}
+	return state, nil
+}
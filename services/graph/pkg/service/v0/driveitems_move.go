@@ -0,0 +1,149 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/google/uuid"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+)
+
+// driveItemTarget is the body of move and copy requests
+type driveItemTarget struct {
+	Name             *string                   `json:"name,omitempty"`
+	ParentReference  *libregraph.ItemReference `json:"parentReference,omitempty"`
+	ConflictBehavior string                    `json:"@microsoft.graph.conflictBehavior,omitempty"`
+}
+
+// UpdateDriveItem renames a drive item or moves it to another folder of the same drive.
+// Conflicting names are resolved according to the '@microsoft.graph.conflictBehavior'.
+func (g Graph) UpdateDriveItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+	logger.Debug().Msg("calling update drive item")
+
+	driveID, itemID, err := parseDriveItemParams(r, "driveItemID")
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if IsSpaceRoot(&itemID) {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "the root of a drive can not be moved")
+		return
+	}
+
+	update := driveItemTarget{}
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	behavior, err := parseConflictBehavior(r, update.ConflictBehavior, _conflictFail)
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	info, err := statDriveItem(ctx, gatewayClient, &storageprovider.Reference{ResourceId: &itemID})
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	name := info.GetName()
+	if update.Name != nil {
+		name = *update.Name
+		if err := validateDriveItemName(name); err != nil {
+			errorcode.RenderError(w, r, err)
+			return
+		}
+	}
+	parentID := info.GetParentId()
+	if ref := update.ParentReference; ref != nil && ref.GetId() != "" {
+		id, err := storagespace.ParseID(ref.GetId())
+		if err != nil {
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid parentReference id: "+err.Error())
+			return
+		}
+		if id.GetStorageId() != driveID.GetStorageId() || id.GetSpaceId() != driveID.GetSpaceId() ||
+			(ref.GetDriveId() != "" && ref.GetDriveId() != storagespace.FormatResourceID(&driveID)) {
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "items can not be moved to other drives, copy them instead")
+			return
+		}
+		parentID = &id
+	}
+
+	if name != info.GetName() || !utils.ResourceIDEqual(parentID, info.GetParentId()) {
+		newName, existing, err := resolveNameConflict(ctx, gatewayClient, parentID, name, behavior)
+		if err != nil {
+			errorcode.RenderError(w, r, err)
+			return
+		}
+		if existing != nil && utils.ResourceIDEqual(existing.GetId(), info.GetId()) {
+			// a case only rename on a case insensitive storage
+			existing = nil
+		}
+		if existing != nil {
+			err = g.replaceDriveItem(ctx, gatewayClient, &itemID, existing, parentID, newName)
+		} else {
+			res, merr := gatewayClient.Move(ctx, &storageprovider.MoveRequest{
+				Source:      &storageprovider.Reference{ResourceId: &itemID},
+				Destination: &storageprovider.Reference{ResourceId: parentID, Path: utils.MakeRelativePath(newName)},
+			})
+			err = errorcode.FromCS3Status(res.GetStatus(), merr)
+		}
+		if err != nil {
+			logger.Debug().Err(err).Msg("could not update drive item: move failed")
+			errorcode.RenderError(w, r, err)
+			return
+		}
+	}
+
+	g.renderDriveItem(w, r, gatewayClient, &storageprovider.Reference{ResourceId: &itemID}, http.StatusOK)
+}
+
+// replaceDriveItem moves the item to the name in the folder and replaces the existing item with that
+// name. The existing item is moved aside first and only deleted after the move succeeded, it gets its
+// name back if the move fails.
+func (g Graph) replaceDriveItem(ctx context.Context, gatewayClient gateway.GatewayAPIClient, itemID *storageprovider.ResourceId, existing *storageprovider.ResourceInfo, parentID *storageprovider.ResourceId, name string) error {
+	existingRef := &storageprovider.Reference{ResourceId: existing.GetId()}
+	res, err := gatewayClient.Move(ctx, &storageprovider.MoveRequest{
+		Source:      existingRef,
+		Destination: &storageprovider.Reference{ResourceId: parentID, Path: utils.MakeRelativePath(".~replaced-" + uuid.NewString())},
+	})
+	if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+		return errCode
+	}
+
+	target := &storageprovider.Reference{ResourceId: parentID, Path: utils.MakeRelativePath(name)}
+	res, err = gatewayClient.Move(ctx, &storageprovider.MoveRequest{
+		Source:      &storageprovider.Reference{ResourceId: itemID},
+		Destination: target,
+	})
+	if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+		restore, rerr := gatewayClient.Move(ctx, &storageprovider.MoveRequest{Source: existingRef, Destination: target})
+		if rerrCode := errorcode.FromCS3Status(restore.GetStatus(), rerr); rerrCode != nil {
+			g.logger.Error().Err(rerrCode).Str("id", storagespace.FormatResourceID(existing.GetId())).Str("name", name).Msg("could not restore the name of the replaced item")
+		}
+		return errCode
+	}
+
+	// the item has been replaced, failing to delete the old item leaves it behind with its temporary name
+	dres, err := gatewayClient.Delete(ctx, &storageprovider.DeleteRequest{Ref: existingRef})
+	if errCode := errorcode.FromCS3Status(dres.GetStatus(), err); errCode != nil {
+		g.logger.Error().Err(errCode).Str("id", storagespace.FormatResourceID(existing.GetId())).Msg("could not delete the replaced item")
+	}
+	return nil
+}
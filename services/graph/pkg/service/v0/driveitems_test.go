@@ -2,17 +2,20 @@ package svc_test
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"time"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/go-chi/chi/v5"
+	"github.com/nats-io/nats.go/jetstream"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	libregraph "github.com/opencloud-eu/libre-graph-api-go"
@@ -452,4 +455,273 @@ var _ = Describe("Driveitems", func() {
 			})
 		})
 	})
+
+	Describe("GetDriveItemDelta", func() {
+		var (
+			past     = time.Now().Add(-2 * time.Hour)
+			since    = time.Now().Add(-time.Hour)
+			now      = time.Now()
+			deltaSvc service.Service
+			// the state of the storage
+			rootMtime time.Time
+			children  map[string][]*provider.ResourceInfo
+			existing  map[string]bool
+			listed    map[string]int
+			// the key value store of the folder snapshots
+			snapshots map[string][]byte
+		)
+
+		type deltaList struct {
+			Value     []*libregraph.DriveItem
+			NextLink  string `json:"@odata.nextLink"`
+			DeltaLink string `json:"@odata.deltaLink"`
+		}
+
+		info := func(id string, t provider.ResourceType, mtime time.Time, checksum string) *provider.ResourceInfo {
+			return &provider.ResourceInfo{
+				Type:     t,
+				Id:       &provider.ResourceId{StorageId: "storageid", SpaceId: "spaceid", OpaqueId: id},
+				Path:     "./" + id,
+				Mtime:    utils.TimeToTS(mtime),
+				Checksum: &provider.ResourceChecksum{Sum: checksum},
+			}
+		}
+
+		byOpaqueID := func(id string) interface{} {
+			return mock.MatchedBy(func(req *provider.ListContainerRequest) bool {
+				return req.GetRef().GetResourceId().GetOpaqueId() == id
+			})
+		}
+
+		deltaRequest := func(query string) *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/graph/v1beta1/drives/storageid$spaceid/root/delta"+query, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("driveID", "storageid$spaceid")
+			return r.WithContext(context.WithValue(revactx.ContextSetUser(ctx, currentUser), chi.RouteCtxKey, rctx))
+		}
+
+		deltaResponse := func(r *http.Request) deltaList {
+			rr = httptest.NewRecorder()
+			deltaSvc.GetDriveItemDelta(rr, r)
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			res := deltaList{}
+			Expect(json.Unmarshal(rr.Body.Bytes(), &res)).To(Succeed())
+			return res
+		}
+
+		follow := func(link string) string {
+			u, err := url.Parse(link)
+			Expect(err).ToNot(HaveOccurred())
+			return "?" + u.RawQuery
+		}
+
+		ids := func(items []*libregraph.DriveItem) []string {
+			res := make([]string, 0, len(items))
+			for _, item := range items {
+				id := item.GetId()
+				if item.Deleted != nil {
+					id += " deleted"
+				}
+				res = append(res, id)
+			}
+			return res
+		}
+
+		BeforeEach(func() {
+			rootMtime = now
+			children = map[string][]*provider.ResourceInfo{
+				"spaceid": {
+					info("changed", provider.ResourceType_RESOURCE_TYPE_FILE, now, "1"),
+					info("folder", provider.ResourceType_RESOURCE_TYPE_CONTAINER, past, ""),
+				},
+				"folder": {
+					info("unchanged", provider.ResourceType_RESOURCE_TYPE_FILE, past, "2"),
+				},
+			}
+			existing = map[string]bool{}
+			listed = map[string]int{}
+			snapshots = map[string][]byte{}
+
+			gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(func(_ context.Context, req *provider.StatRequest, _ ...grpc.CallOption) (*provider.StatResponse, error) {
+				id := req.GetRef().GetResourceId().GetOpaqueId()
+				switch {
+				case id == "spaceid":
+					return &provider.StatResponse{Status: status.NewOK(ctx), Info: info("spaceid", provider.ResourceType_RESOURCE_TYPE_CONTAINER, rootMtime, "")}, nil
+				case existing[id]:
+					return &provider.StatResponse{Status: status.NewOK(ctx), Info: info(id, provider.ResourceType_RESOURCE_TYPE_FILE, now, "")}, nil
+				default:
+					return &provider.StatResponse{Status: status.NewNotFound(ctx, "not found")}, nil
+				}
+			})
+			gatewayClient.On("ListContainer", mock.Anything, mock.Anything).Return(func(_ context.Context, req *provider.ListContainerRequest, _ ...grpc.CallOption) (*provider.ListContainerResponse, error) {
+				id := req.GetRef().GetResourceId().GetOpaqueId()
+				listed[id]++
+				return &provider.ListContainerResponse{Status: status.NewOK(ctx), Infos: children[id]}, nil
+			})
+
+			deltaKeyValue := &mocks.KeyValue{}
+			deltaKeyValue.EXPECT().Get(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, key string) (jetstream.KeyValueEntry, error) {
+				v, ok := snapshots[key]
+				if !ok {
+					return nil, jetstream.ErrKeyNotFound
+				}
+				kve := &mocks.KeyValueEntry{}
+				kve.On("Value").Return(v)
+				return kve, nil
+			}).Maybe()
+			deltaKeyValue.EXPECT().Put(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, key string, val []byte) (uint64, error) {
+				snapshots[key] = val
+				return 1, nil
+			}).Maybe()
+			deltaKeyValue.EXPECT().Delete(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, key string, _ ...jetstream.KVDeleteOpt) error {
+				delete(snapshots, key)
+				return nil
+			}).Maybe()
+
+			var err error
+			deltaSvc, err = service.NewService(
+				service.Config(cfg),
+				service.WithGatewaySelector(gatewaySelector),
+				service.EventsPublisher(&eventsPublisher),
+				service.WithIdentityBackend(identityBackend),
+				service.WithDeltaKeyValue(deltaKeyValue),
+			)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("lists all items without a token", func() {
+			res := deltaResponse(deltaRequest(""))
+			Expect(ids(res.Value)).To(ConsistOf("storageid$spaceid!changed", "storageid$spaceid!folder", "storageid$spaceid!unchanged"))
+			Expect(res.DeltaLink).To(ContainSubstring("token="))
+			Expect(res.NextLink).To(BeEmpty())
+			Expect(snapshots).To(HaveLen(2))
+			gatewayClient.AssertNotCalled(GinkgoT(), "ListRecycle", mock.Anything, mock.Anything)
+		})
+
+		It("lists the changed and removed items since the token", func() {
+			rootMtime = since
+			first := deltaResponse(deltaRequest(""))
+
+			// a new upload that kept the modification time, a moved and a deleted item
+			rootMtime = now
+			children["spaceid"] = []*provider.ResourceInfo{
+				info("changed", provider.ResourceType_RESOURCE_TYPE_FILE, now, "3"),
+				info("folder", provider.ResourceType_RESOURCE_TYPE_CONTAINER, past, ""),
+			}
+			children["folder"] = nil
+			existing["unchanged"] = true
+
+			res := deltaResponse(deltaRequest(follow(first.DeltaLink)))
+			Expect(ids(res.Value)).To(ConsistOf("storageid$spaceid!changed"))
+			Expect(res.DeltaLink).ToNot(Equal(first.DeltaLink))
+			Expect(listed).To(HaveKeyWithValue("folder", 1))
+			gatewayClient.AssertNotCalled(GinkgoT(), "ListRecycle", mock.Anything, mock.Anything)
+
+			rootMtime = now.Add(time.Minute)
+			children["spaceid"] = children["spaceid"][1:]
+			res = deltaResponse(deltaRequest(follow(res.DeltaLink)))
+			Expect(ids(res.Value)).To(ConsistOf("storageid$spaceid!changed deleted"))
+		})
+
+		It("lists the folders moved into the folder completely", func() {
+			rootMtime = since
+			first := deltaResponse(deltaRequest(""))
+
+			rootMtime = now
+			children["spaceid"] = append(children["spaceid"], info("moved", provider.ResourceType_RESOURCE_TYPE_CONTAINER, past, ""))
+			children["moved"] = []*provider.ResourceInfo{
+				info("movedchild", provider.ResourceType_RESOURCE_TYPE_FILE, past, "4"),
+			}
+
+			listed = map[string]int{}
+			res := deltaResponse(deltaRequest(follow(first.DeltaLink)))
+			Expect(ids(res.Value)).To(ConsistOf("storageid$spaceid!moved", "storageid$spaceid!movedchild"))
+			Expect(listed).To(Equal(map[string]int{"spaceid": 1, "moved": 1}))
+		})
+
+		It("reads the deleted items from the trash bin when there is no snapshot", func() {
+			gatewayClient.On("ListRecycle", mock.Anything, mock.Anything).Return(&provider.ListRecycleResponse{
+				Status: status.NewOK(ctx),
+				RecycleItems: []*provider.RecycleItem{
+					{
+						Key:          "deleted",
+						Ref:          &provider.Reference{Path: "/deleted.txt"},
+						DeletionTime: utils.TimeToTS(now),
+					},
+					{
+						Key:          "deletedbefore",
+						Ref:          &provider.Reference{Path: "/deletedbefore.txt"},
+						DeletionTime: utils.TimeToTS(since.Add(-time.Hour)),
+					},
+				},
+			}, nil)
+
+			rootMtime = since
+			latest := deltaResponse(deltaRequest("?token=latest"))
+			Expect(latest.Value).To(BeEmpty())
+
+			rootMtime = now
+			res := deltaResponse(deltaRequest(follow(latest.DeltaLink)))
+			Expect(ids(res.Value)).To(ConsistOf("storageid$spaceid!changed", "storageid$spaceid!folder", "storageid$spaceid!deleted deleted"))
+			Expect(res.Value[2].GetName()).To(Equal("deleted.txt"))
+			gatewayClient.AssertNotCalled(GinkgoT(), "ListContainer", mock.Anything, byOpaqueID("folder"))
+		})
+
+		It("fails if the deleted items can't be read from the trash bin", func() {
+			gatewayClient.On("ListRecycle", mock.Anything, mock.Anything).Return(&provider.ListRecycleResponse{
+				Status: status.NewPermissionDenied(ctx, nil, "permission denied"),
+			}, nil)
+
+			rootMtime = since
+			latest := deltaResponse(deltaRequest("?token=latest"))
+
+			rootMtime = now
+			rr = httptest.NewRecorder()
+			deltaSvc.GetDriveItemDelta(rr, deltaRequest(follow(latest.DeltaLink)))
+			Expect(rr.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("pages the changes", func() {
+			res := deltaResponse(deltaRequest("?$top=1"))
+			items := res.Value
+			for res.NextLink != "" {
+				Expect(res.DeltaLink).To(BeEmpty())
+				Expect(res.Value).To(HaveLen(1))
+				res = deltaResponse(deltaRequest(follow(res.NextLink)))
+				items = append(items, res.Value...)
+			}
+			Expect(res.DeltaLink).ToNot(BeEmpty())
+			Expect(ids(items)).To(ConsistOf("storageid$spaceid!changed", "storageid$spaceid!folder", "storageid$spaceid!unchanged"))
+		})
+
+		It("only returns the delta link for the latest token", func() {
+			res := deltaResponse(deltaRequest("?token=latest"))
+			Expect(res.Value).To(BeEmpty())
+			Expect(res.DeltaLink).To(ContainSubstring("token="))
+			gatewayClient.AssertNotCalled(GinkgoT(), "ListContainer", mock.Anything, mock.Anything)
+		})
+
+		It("rejects invalid tokens", func() {
+			deltaSvc.GetDriveItemDelta(rr, deltaRequest("?token=invalid"))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("rejects tokens with an invalid session", func() {
+			token := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"a.>","v":"storageid$spaceid","o":"spaceid","r":1}`))
+			deltaSvc.GetDriveItemDelta(rr, deltaRequest("?token="+token))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("rejects tokens of other items", func() {
+			latest := deltaResponse(deltaRequest("?token=latest"))
+
+			r := httptest.NewRequest(http.MethodGet, "/graph/v1beta1/drives/storageid$spaceid/items/storageid$spaceid!folder/delta"+follow(latest.DeltaLink), nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("driveID", "storageid$spaceid")
+			rctx.URLParams.Add("itemID", "storageid$spaceid!folder")
+			rr = httptest.NewRecorder()
+			deltaSvc.GetDriveItemDelta(rr, r.WithContext(context.WithValue(revactx.ContextSetUser(ctx, currentUser), chi.RouteCtxKey, rctx)))
+			Expect(rr.Code).To(Equal(http.StatusGone))
+		})
+	})
 })
@@ -0,0 +1,490 @@
+package svc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	"github.com/nats-io/nats.go/jetstream"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/opencloud-eu/reva/v2/tests/cs3mocks/mocks"
+
+	"github.com/opencloud-eu/opencloud/pkg/shared"
+	"github.com/opencloud-eu/opencloud/services/graph/mocks"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config/defaults"
+	identitymocks "github.com/opencloud-eu/opencloud/services/graph/pkg/identity/mocks"
+	service "github.com/opencloud-eu/opencloud/services/graph/pkg/service/v0"
+)
+
+var _ = Describe("Driveitem transfers", func() {
+	var (
+		svc           service.Service
+		ctx           context.Context
+		gatewayClient *cs3mocks.GatewayAPIClient
+		rr            *httptest.ResponseRecorder
+
+		// the fake datagateway keeps the uploads by the token of the upload
+		uploadsMu sync.Mutex
+		uploads   map[string][]byte
+
+		// the fake key value store keeps the copy jobs
+		jobsMu sync.Mutex
+		jobs   map[string][]byte
+
+		currentUser = &userpb.User{
+			Id: &userpb.UserId{
+				OpaqueId: "user",
+			},
+		}
+
+		rootID   = &provider.ResourceId{StorageId: "storageid", SpaceId: "spaceid", OpaqueId: "spaceid"}
+		folderID = &provider.ResourceId{StorageId: "storageid", SpaceId: "spaceid", OpaqueId: "folder"}
+		fileID   = &provider.ResourceId{StorageId: "storageid", SpaceId: "spaceid", OpaqueId: "file"}
+		folder   = &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER, Id: folderID, ParentId: rootID, Name: "folder", Size: 5}
+		file     = &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_FILE, Id: fileID, ParentId: folderID, Name: "file.txt", Size: 5}
+	)
+
+	newRequest := func(method, target string, body io.Reader, params map[string]string) *http.Request {
+		r := httptest.NewRequest(method, target, body)
+		rctx := chi.NewRouteContext()
+		for k, v := range params {
+			rctx.URLParams.Add(k, v)
+		}
+		return r.WithContext(context.WithValue(revactx.ContextSetUser(ctx, currentUser), chi.RouteCtxKey, rctx))
+	}
+
+	itemParams := func(itemID string) map[string]string {
+		return map[string]string{"driveID": "storageid$spaceid", "driveItemID": "storageid$spaceid!" + itemID}
+	}
+
+	statRef := func(id *provider.ResourceId, p string) interface{} {
+		return mock.MatchedBy(func(req *provider.StatRequest) bool {
+			return req.GetRef().GetResourceId().GetOpaqueId() == id.GetOpaqueId() && req.GetRef().GetPath() == p
+		})
+	}
+
+	// statNotFound only answers the first stat, the item exists afterwards
+	statNotFound := func(id *provider.ResourceId, p string) {
+		gatewayClient.On("Stat", mock.Anything, statRef(id, p)).Return(&provider.StatResponse{
+			Status: status.NewNotFound(ctx, "not found"),
+		}, nil).Once()
+	}
+
+	statOK := func(id *provider.ResourceId, p string, info *provider.ResourceInfo) {
+		gatewayClient.On("Stat", mock.Anything, statRef(id, p)).Return(&provider.StatResponse{
+			Status: status.NewOK(ctx),
+			Info:   info,
+		}, nil)
+	}
+
+	BeforeEach(func() {
+		pool.RemoveSelector("GatewaySelector" + "eu.opencloud.api.gateway")
+		gatewayClient = &cs3mocks.GatewayAPIClient{}
+		gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+			"GatewaySelector",
+			"eu.opencloud.api.gateway",
+			func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+				return gatewayClient
+			},
+		)
+
+		// downloads return the opaque id of the file as content, uploads are identified by the path of the reference
+		gatewayClient.On("InitiateFileDownload", mock.Anything, mock.Anything).Return(
+			func(_ context.Context, req *provider.InitiateFileDownloadRequest, _ ...grpc.CallOption) *gateway.InitiateFileDownloadResponse {
+				return &gateway.InitiateFileDownloadResponse{
+					Status: status.NewOK(ctx),
+					Protocols: []*gateway.FileDownloadProtocol{{
+						Protocol:         "spaces",
+						DownloadEndpoint: "https://datagateway/download",
+						Token:            req.GetRef().GetResourceId().GetOpaqueId(),
+					}},
+				}
+			}, nil)
+		gatewayClient.On("InitiateFileUpload", mock.Anything, mock.Anything).Return(
+			func(_ context.Context, req *provider.InitiateFileUploadRequest, _ ...grpc.CallOption) *gateway.InitiateFileUploadResponse {
+				return &gateway.InitiateFileUploadResponse{
+					Status: status.NewOK(ctx),
+					Protocols: []*gateway.FileUploadProtocol{{
+						Protocol:       "simple",
+						UploadEndpoint: "https://datagateway/upload",
+						Token:          req.GetRef().GetResourceId().GetOpaqueId() + "/" + req.GetRef().GetPath(),
+					}},
+				}
+			}, nil)
+
+		uploads = map[string][]byte{}
+		httpClient := &mocks.HTTPClient{}
+		httpClient.EXPECT().Do(mock.Anything).RunAndReturn(func(req *http.Request) (*http.Response, error) {
+			token := req.Header.Get("X-Reva-Transfer")
+			if req.Method == http.MethodPut {
+				b, err := io.ReadAll(req.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(int64(len(b))).To(Equal(req.ContentLength))
+				uploadsMu.Lock()
+				uploads[token] = b
+				uploadsMu.Unlock()
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}
+			res := &http.Response{
+				StatusCode:    http.StatusOK,
+				Header:        http.Header{"Content-Type": []string{"text/plain"}},
+				Body:          io.NopCloser(strings.NewReader(token)),
+				ContentLength: int64(len(token)),
+			}
+			if req.Header.Get("Range") != "" {
+				res.StatusCode = http.StatusPartialContent
+				res.Header.Set("Content-Range", "bytes 0-1/4")
+				res.Body = io.NopCloser(strings.NewReader(token[:2]))
+				res.ContentLength = 2
+			}
+			return res, nil
+		})
+
+		jobs = map[string][]byte{}
+		copyJobKeyValue := &mocks.KeyValue{}
+		copyJobKeyValue.EXPECT().Get(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, key string) (jetstream.KeyValueEntry, error) {
+			jobsMu.Lock()
+			defer jobsMu.Unlock()
+			v, ok := jobs[key]
+			if !ok {
+				return nil, jetstream.ErrKeyNotFound
+			}
+			kve := &mocks.KeyValueEntry{}
+			kve.On("Value").Return(v)
+			return kve, nil
+		}).Maybe()
+		copyJobKeyValue.EXPECT().Put(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, key string, val []byte) (uint64, error) {
+			jobsMu.Lock()
+			defer jobsMu.Unlock()
+			jobs[key] = val
+			return 1, nil
+		}).Maybe()
+
+		rr = httptest.NewRecorder()
+		ctx = context.Background()
+
+		cfg := defaults.FullDefaultConfig()
+		cfg.Identity.LDAP.CACert = "" // skip the startup checks, we don't use LDAP at all in this tests
+		cfg.TokenManager.JWTSecret = "loremipsum"
+		cfg.Commons = &shared.Commons{OpenCloudURL: "https://localhost:9200"}
+		cfg.GRPCClientTLS = &shared.GRPCClientTLS{}
+
+		var err error
+		svc, err = service.NewService(
+			service.Config(cfg),
+			service.WithGatewaySelector(gatewaySelector),
+			service.WithIdentityBackend(&identitymocks.Backend{}),
+			service.WithHTTPClient(httpClient),
+			service.WithCopyJobKeyValue(copyJobKeyValue),
+		)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("GetDriveItemContent", func() {
+		It("streams the content and passes on range requests", func() {
+			statOK(fileID, "", file)
+			r := newRequest(http.MethodGet, "/graph/v1.0/drives/storageid$spaceid/items/storageid$spaceid!file/content", nil, itemParams("file"))
+			r.Header.Set("Range", "bytes=0-1")
+			svc.GetDriveItemContent(rr, r)
+			Expect(rr.Code).To(Equal(http.StatusPartialContent))
+			Expect(rr.Body.String()).To(Equal("fi"))
+			Expect(rr.Header().Get("Content-Range")).To(Equal("bytes 0-1/4"))
+			Expect(rr.Header().Get("Content-Type")).To(Equal("text/plain"))
+		})
+
+		It("rejects folders", func() {
+			statOK(folderID, "", folder)
+			svc.GetDriveItemContent(rr, newRequest(http.MethodGet, "/graph/v1.0/drives/storageid$spaceid/items/storageid$spaceid!folder/content", nil, itemParams("folder")))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("does not find items of other drives", func() {
+			params := itemParams("file")
+			params["driveID"] = "storageid$otherspace"
+			svc.GetDriveItemContent(rr, newRequest(http.MethodGet, "/graph/v1.0/drives/storageid$otherspace/items/storageid$spaceid!file/content", nil, params))
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("PutDriveItemChildContent", func() {
+		childRequest := func(name, query string) *http.Request {
+			params := itemParams("folder")
+			params["fileName"] = name
+			return newRequest(http.MethodPut, "/graph/v1.0/drives/storageid$spaceid/items/storageid$spaceid!folder:/"+name+":/content"+query, bytes.NewBufferString("content"), params)
+		}
+
+		It("uploads new files", func() {
+			statNotFound(folderID, "./new.txt")
+			statOK(folderID, "./new.txt", file)
+			svc.PutDriveItemChildContent(rr, childRequest("new.txt", ""))
+			Expect(rr.Code).To(Equal(http.StatusCreated))
+			Expect(uploads).To(HaveKeyWithValue("folder/./new.txt", []byte("content")))
+		})
+
+		It("fails on existing files if requested", func() {
+			statOK(folderID, "./file.txt", file)
+			svc.PutDriveItemChildContent(rr, childRequest("file.txt", "?@microsoft.graph.conflictBehavior=fail"))
+			Expect(rr.Code).To(Equal(http.StatusConflict))
+			Expect(uploads).To(BeEmpty())
+		})
+
+		It("picks a free name when renaming", func() {
+			statOK(folderID, "./file.txt", file)
+			statOK(folderID, "./file (2).txt", file)
+			gatewayClient.On("ListContainer", mock.Anything, mock.Anything).Return(&provider.ListContainerResponse{
+				Status: status.NewOK(ctx),
+				Infos:  []*provider.ResourceInfo{{Name: "file.txt"}, {Name: "file (1).txt"}},
+			}, nil)
+			svc.PutDriveItemChildContent(rr, childRequest("file.txt", "?@microsoft.graph.conflictBehavior=rename"))
+			Expect(rr.Code).To(Equal(http.StatusCreated))
+			Expect(uploads).To(HaveKey("folder/./file (2).txt"))
+		})
+
+		It("requires the content length", func() {
+			r := childRequest("new.txt", "")
+			r.ContentLength = -1
+			svc.PutDriveItemChildContent(rr, r)
+			Expect(rr.Code).To(Equal(http.StatusLengthRequired))
+		})
+	})
+
+	Describe("UpdateDriveItem", func() {
+		patchRequest := func(body string) *http.Request {
+			return newRequest(http.MethodPatch, "/graph/v1.0/drives/storageid$spaceid/items/storageid$spaceid!file", strings.NewReader(body), itemParams("file"))
+		}
+
+		BeforeEach(func() {
+			statOK(fileID, "", file)
+		})
+
+		It("fails on name conflicts by default", func() {
+			statOK(rootID, "./file.txt", file)
+			svc.UpdateDriveItem(rr, patchRequest(`{"parentReference": {"id": "storageid$spaceid!spaceid"}}`))
+			Expect(rr.Code).To(Equal(http.StatusConflict))
+			gatewayClient.AssertNotCalled(GinkgoT(), "Move", mock.Anything, mock.Anything)
+		})
+
+		It("replaces the existing item after the move succeeded", func() {
+			existingID := &provider.ResourceId{StorageId: "storageid", SpaceId: "spaceid", OpaqueId: "existing"}
+			statOK(rootID, "./file.txt", &provider.ResourceInfo{Id: existingID, Name: "file.txt"})
+			var calls []string
+			gatewayClient.On("Move", mock.Anything, mock.MatchedBy(func(req *provider.MoveRequest) bool {
+				return req.GetSource().GetResourceId().GetOpaqueId() == "existing" && strings.HasPrefix(req.GetDestination().GetPath(), "./.~replaced-")
+			})).Run(func(mock.Arguments) { calls = append(calls, "aside") }).Return(&provider.MoveResponse{Status: status.NewOK(ctx)}, nil)
+			gatewayClient.On("Move", mock.Anything, mock.MatchedBy(func(req *provider.MoveRequest) bool {
+				return req.GetSource().GetResourceId().GetOpaqueId() == "file" &&
+					req.GetDestination().GetResourceId().GetOpaqueId() == "spaceid" && req.GetDestination().GetPath() == "./file.txt"
+			})).Run(func(mock.Arguments) { calls = append(calls, "move") }).Return(&provider.MoveResponse{Status: status.NewOK(ctx)}, nil)
+			gatewayClient.On("Delete", mock.Anything, mock.MatchedBy(func(req *provider.DeleteRequest) bool {
+				return req.GetRef().GetResourceId().GetOpaqueId() == "existing"
+			})).Run(func(mock.Arguments) { calls = append(calls, "delete") }).Return(&provider.DeleteResponse{Status: status.NewOK(ctx)}, nil)
+
+			svc.UpdateDriveItem(rr, patchRequest(`{"parentReference": {"id": "storageid$spaceid!spaceid"}, "@microsoft.graph.conflictBehavior": "replace"}`))
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(calls).To(Equal([]string{"aside", "move", "delete"}))
+		})
+
+		It("keeps the existing item when the move fails", func() {
+			existingID := &provider.ResourceId{StorageId: "storageid", SpaceId: "spaceid", OpaqueId: "existing"}
+			statOK(rootID, "./file.txt", &provider.ResourceInfo{Id: existingID, Name: "file.txt"})
+			gatewayClient.On("Move", mock.Anything, mock.MatchedBy(func(req *provider.MoveRequest) bool {
+				return req.GetSource().GetResourceId().GetOpaqueId() == "existing" && strings.HasPrefix(req.GetDestination().GetPath(), "./.~replaced-")
+			})).Return(&provider.MoveResponse{Status: status.NewOK(ctx)}, nil)
+			gatewayClient.On("Move", mock.Anything, mock.MatchedBy(func(req *provider.MoveRequest) bool {
+				return req.GetSource().GetResourceId().GetOpaqueId() == "file"
+			})).Return(&provider.MoveResponse{Status: status.NewPermissionDenied(ctx, nil, "denied")}, nil)
+			gatewayClient.On("Move", mock.Anything, mock.MatchedBy(func(req *provider.MoveRequest) bool {
+				return req.GetSource().GetResourceId().GetOpaqueId() == "existing" && req.GetDestination().GetPath() == "./file.txt"
+			})).Return(&provider.MoveResponse{Status: status.NewOK(ctx)}, nil).Once()
+
+			svc.UpdateDriveItem(rr, patchRequest(`{"parentReference": {"id": "storageid$spaceid!spaceid"}, "@microsoft.graph.conflictBehavior": "replace"}`))
+			Expect(rr.Code).To(Equal(http.StatusForbidden))
+			gatewayClient.AssertNotCalled(GinkgoT(), "Delete", mock.Anything, mock.Anything)
+			gatewayClient.AssertCalled(GinkgoT(), "Move", mock.Anything, mock.MatchedBy(func(req *provider.MoveRequest) bool {
+				return req.GetSource().GetResourceId().GetOpaqueId() == "existing" && req.GetDestination().GetPath() == "./file.txt"
+			}))
+		})
+
+		It("renames the item", func() {
+			statNotFound(folderID, "./renamed.txt")
+			gatewayClient.On("Move", mock.Anything, mock.MatchedBy(func(req *provider.MoveRequest) bool {
+				return req.GetDestination().GetResourceId().GetOpaqueId() == "folder" && req.GetDestination().GetPath() == "./renamed.txt"
+			})).Return(&provider.MoveResponse{Status: status.NewOK(ctx)}, nil)
+
+			svc.UpdateDriveItem(rr, patchRequest(`{"name": "renamed.txt"}`))
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			item := libregraph.DriveItem{}
+			Expect(json.Unmarshal(rr.Body.Bytes(), &item)).To(Succeed())
+			Expect(item.GetId()).To(Equal("storageid$spaceid!file"))
+		})
+
+		It("rejects moves to other drives", func() {
+			svc.UpdateDriveItem(rr, patchRequest(`{"parentReference": {"id": "storageid$otherspace!folder"}}`))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("CopyDriveItem", func() {
+		copiedID := &provider.ResourceId{StorageId: "storageid", SpaceId: "spaceid", OpaqueId: "copied"}
+
+		copyRequest := func(body string) *http.Request {
+			return newRequest(http.MethodPost, "/graph/v1.0/drives/storageid$spaceid/items/storageid$spaceid!folder/copy", strings.NewReader(body), itemParams("folder"))
+		}
+
+		monitor := func(location string, user *userpb.User) (int, service.AsyncJobStatus) {
+			jobID := location[strings.LastIndex(location, "/")+1:]
+			r := newRequest(http.MethodGet, location, nil, map[string]string{"jobID": jobID})
+			r = r.WithContext(revactx.ContextSetUser(r.Context(), user))
+			w := httptest.NewRecorder()
+			svc.GetAsyncJobStatus(w, r)
+			job := service.AsyncJobStatus{}
+			if w.Code == http.StatusOK {
+				Expect(json.Unmarshal(w.Body.Bytes(), &job)).To(Succeed())
+			}
+			return w.Code, job
+		}
+
+		BeforeEach(func() {
+			statOK(folderID, "", folder)
+			statOK(rootID, "", &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER, Id: rootID})
+			gatewayClient.On("GetPath", mock.Anything, mock.Anything).Return(
+				func(_ context.Context, req *provider.GetPathRequest, _ ...grpc.CallOption) *provider.GetPathResponse {
+					p := "/"
+					if req.GetResourceId().GetOpaqueId() == "folder" {
+						p = "/folder"
+					}
+					return &provider.GetPathResponse{Status: status.NewOK(ctx), Path: p}
+				}, nil)
+		})
+
+		It("copies folders in the background", func() {
+			statNotFound(rootID, "./copy")
+			statOK(rootID, "./copy", &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER, Id: copiedID})
+			gatewayClient.On("ListContainer", mock.Anything, mock.Anything).Return(&provider.ListContainerResponse{
+				Status: status.NewOK(ctx),
+				Infos:  []*provider.ResourceInfo{file},
+			}, nil)
+			gatewayClient.On("CreateContainer", mock.Anything, mock.MatchedBy(func(req *provider.CreateContainerRequest) bool {
+				return req.GetRef().GetPath() == "./copy"
+			})).Return(&provider.CreateContainerResponse{Status: status.NewOK(ctx)}, nil)
+
+			svc.CopyDriveItem(rr, copyRequest(`{"name": "copy"}`))
+			Expect(rr.Code).To(Equal(http.StatusAccepted))
+			location := rr.Header().Get("Location")
+			Expect(location).To(HavePrefix("https://localhost:9200/graph/v1.0/monitor/"))
+
+			Eventually(func() string {
+				_, job := monitor(location, currentUser)
+				return job.Status
+			}).Should(Equal("completed"))
+
+			_, job := monitor(location, currentUser)
+			Expect(job.ResourceID).To(Equal("storageid$spaceid!copied"))
+			Expect(job.PercentageComplete).To(Equal(float64(100)))
+			uploadsMu.Lock()
+			defer uploadsMu.Unlock()
+			Expect(uploads).To(HaveKeyWithValue("spaceid/./copy/file.txt", []byte("file")))
+
+			code, _ := monitor(location, &userpb.User{Id: &userpb.UserId{OpaqueId: "other"}})
+			Expect(code).To(Equal(http.StatusNotFound))
+		})
+
+		It("replaces the existing file after the copy succeeded", func() {
+			existingID := &provider.ResourceId{StorageId: "storageid", SpaceId: "spaceid", OpaqueId: "existing"}
+			copy := &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_FILE, Id: copiedID, ParentId: folderID}
+			statOK(fileID, "", file)
+			statOK(folderID, "./file.txt", &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_FILE, Id: existingID, Name: "file.txt"})
+			gatewayClient.On("Stat", mock.Anything, mock.MatchedBy(func(req *provider.StatRequest) bool {
+				return strings.HasPrefix(req.GetRef().GetPath(), "./.~copy-")
+			})).Return(&provider.StatResponse{Status: status.NewOK(ctx), Info: copy}, nil)
+			gatewayClient.On("Move", mock.Anything, mock.Anything).Return(&provider.MoveResponse{Status: status.NewOK(ctx)}, nil)
+			gatewayClient.On("Delete", mock.Anything, mock.Anything).Return(&provider.DeleteResponse{Status: status.NewOK(ctx)}, nil)
+
+			r := newRequest(http.MethodPost, "/graph/v1.0/drives/storageid$spaceid/items/storageid$spaceid!file/copy",
+				strings.NewReader(`{"@microsoft.graph.conflictBehavior": "replace"}`), itemParams("file"))
+			svc.CopyDriveItem(rr, r)
+			Expect(rr.Code).To(Equal(http.StatusAccepted))
+			location := rr.Header().Get("Location")
+
+			Eventually(func() string {
+				_, job := monitor(location, currentUser)
+				return job.Status
+			}).Should(Equal("completed"))
+			_, job := monitor(location, currentUser)
+			Expect(job.ResourceID).To(Equal("storageid$spaceid!copied"))
+			Expect(job.PercentageComplete).To(Equal(float64(100)))
+
+			// the content is uploaded with the temporary name, the existing file is deleted after the swap
+			uploadsMu.Lock()
+			var tmpName string
+			for k := range uploads {
+				tmpName = k
+			}
+			uploadsMu.Unlock()
+			Expect(tmpName).To(HavePrefix("folder/./.~copy-"))
+			gatewayClient.AssertCalled(GinkgoT(), "Move", mock.Anything, mock.MatchedBy(func(req *provider.MoveRequest) bool {
+				return req.GetSource().GetResourceId().GetOpaqueId() == "copied" && req.GetDestination().GetPath() == "./file.txt"
+			}))
+			gatewayClient.AssertCalled(GinkgoT(), "Delete", mock.Anything, mock.MatchedBy(func(req *provider.DeleteRequest) bool {
+				return req.GetRef().GetResourceId().GetOpaqueId() == "existing"
+			}))
+		})
+
+		It("keeps the existing file when the copy fails", func() {
+			existingID := &provider.ResourceId{StorageId: "storageid", SpaceId: "spaceid", OpaqueId: "existing"}
+			statOK(fileID, "", file)
+			statOK(folderID, "./file.txt", &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_FILE, Id: existingID, Name: "file.txt"})
+			gatewayClient.On("InitiateFileUpload", mock.Anything, mock.Anything).Unset()
+			gatewayClient.On("InitiateFileUpload", mock.Anything, mock.Anything).Return(&gateway.InitiateFileUploadResponse{
+				Status: status.NewInsufficientStorage(ctx, nil, "quota exceeded"),
+			}, nil)
+			gatewayClient.On("Delete", mock.Anything, mock.MatchedBy(func(req *provider.DeleteRequest) bool {
+				return strings.HasPrefix(req.GetRef().GetPath(), "./.~copy-")
+			})).Return(&provider.DeleteResponse{Status: status.NewOK(ctx)}, nil)
+
+			r := newRequest(http.MethodPost, "/graph/v1.0/drives/storageid$spaceid/items/storageid$spaceid!file/copy",
+				strings.NewReader(`{"@microsoft.graph.conflictBehavior": "replace"}`), itemParams("file"))
+			svc.CopyDriveItem(rr, r)
+			Expect(rr.Code).To(Equal(http.StatusAccepted))
+			location := rr.Header().Get("Location")
+
+			Eventually(func() string {
+				_, job := monitor(location, currentUser)
+				return job.Status
+			}).Should(Equal("failed"))
+			gatewayClient.AssertNotCalled(GinkgoT(), "Move", mock.Anything, mock.Anything)
+			gatewayClient.AssertNotCalled(GinkgoT(), "Delete", mock.Anything, mock.MatchedBy(func(req *provider.DeleteRequest) bool {
+				return req.GetRef().GetResourceId().GetOpaqueId() == "existing"
+			}))
+		})
+
+		It("rejects copying a folder into itself", func() {
+			svc.CopyDriveItem(rr, copyRequest(`{"parentReference": {"id": "storageid$spaceid!folder"}}`))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("fails on name conflicts before starting the job", func() {
+			statOK(rootID, "./folder", folder)
+			svc.CopyDriveItem(rr, copyRequest(`{}`))
+			Expect(rr.Code).To(Equal(http.StatusConflict))
+			Expect(rr.Header().Get("Location")).To(BeEmpty())
+		})
+	})
+})
@@ -34,6 +34,7 @@ import (
 	settingssvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/settings/v0"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/odata"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/quota"
 	settingsServiceExt "github.com/opencloud-eu/opencloud/services/settings/pkg/store/defaults"
 )
 
@@ -79,7 +80,7 @@ func (g Graph) GetDrives(version APIVersion) http.HandlerFunc {
 // GetDrivesV1 attempts to retrieve the current users drives;
 // it lists all drives the current user has access to.
 func (g Graph) GetDrivesV1(w http.ResponseWriter, r *http.Request) {
-	spaces, errCode := g.getDrives(r, false, APIVersion_1)
+	spaces, nextLink, errCode := g.getDrives(r, false, APIVersion_1)
 	if errCode != nil {
 		errorcode.RenderError(w, r, errCode)
 		return
@@ -91,7 +92,7 @@ func (g Graph) GetDrivesV1(w http.ResponseWriter, r *http.Request) {
 	case spaces == nil && errCode == nil:
 		render.JSON(w, r, nil)
 	default:
-		render.JSON(w, r, &ListResponse{Value: spaces})
+		render.JSON(w, r, &ListResponse{Value: spaces, NextLink: nextLink})
 	}
 
 }
@@ -100,7 +101,7 @@ func (g Graph) GetDrivesV1(w http.ResponseWriter, r *http.Request) {
 // it includes the grantedtoV2 property
 // it uses unified roles instead of the cs3 representations
 func (g Graph) GetDrivesV1Beta1(w http.ResponseWriter, r *http.Request) {
-	spaces, errCode := g.getDrives(r, false, APIVersion_1_Beta_1)
+	spaces, nextLink, errCode := g.getDrives(r, false, APIVersion_1_Beta_1)
 	if errCode != nil {
 		errorcode.RenderError(w, r, errCode)
 		return
@@ -112,7 +113,7 @@ func (g Graph) GetDrivesV1Beta1(w http.ResponseWriter, r *http.Request) {
 	case spaces == nil && errCode == nil:
 		render.JSON(w, r, nil)
 	default:
-		render.JSON(w, r, &ListResponse{Value: spaces})
+		render.JSON(w, r, &ListResponse{Value: spaces, NextLink: nextLink})
 	}
 }
 
@@ -134,7 +135,7 @@ func (g Graph) GetAllDrives(version APIVersion) http.HandlerFunc {
 // GetAllDrivesV1 attempts to retrieve the current users drives;
 // it includes another user's drives, if the current user has the permission.
 func (g Graph) GetAllDrivesV1(w http.ResponseWriter, r *http.Request) {
-	spaces, errCode := g.getDrives(r, true, APIVersion_1)
+	spaces, nextLink, errCode := g.getDrives(r, true, APIVersion_1)
 	if errCode != nil {
 		errorcode.RenderError(w, r, errCode)
 		return
@@ -146,7 +147,7 @@ func (g Graph) GetAllDrivesV1(w http.ResponseWriter, r *http.Request) {
 	case spaces == nil && errCode == nil:
 		render.JSON(w, r, nil)
 	default:
-		render.JSON(w, r, &ListResponse{Value: spaces})
+		render.JSON(w, r, &ListResponse{Value: spaces, NextLink: nextLink})
 	}
 }
 
@@ -154,7 +155,7 @@ func (g Graph) GetAllDrivesV1(w http.ResponseWriter, r *http.Request) {
 // it includes the grantedtoV2 property
 // it uses unified roles instead of the cs3 representations
 func (g Graph) GetAllDrivesV1Beta1(w http.ResponseWriter, r *http.Request) {
-	drives, errCode := g.getDrives(r, true, APIVersion_1_Beta_1)
+	drives, nextLink, errCode := g.getDrives(r, true, APIVersion_1_Beta_1)
 	if errCode != nil {
 		errorcode.RenderError(w, r, errCode)
 		return
@@ -166,7 +167,7 @@ func (g Graph) GetAllDrivesV1Beta1(w http.ResponseWriter, r *http.Request) {
 	case drives == nil && errCode == nil:
 		render.JSON(w, r, nil)
 	default:
-		render.JSON(w, r, &ListResponse{Value: drives})
+		render.JSON(w, r, &ListResponse{Value: drives, NextLink: nextLink})
 	}
 }
 
@@ -187,16 +188,37 @@ func parseDriveRequest(r *http.Request) (*godata.GoDataRequest, bool, error) {
 	if err != nil {
 		return nil, false, errorcode.New(errorcode.InvalidRequest, err.Error())
 	}
-	exp, err := odata.GetExpandValues(odataReq.Query)
+	expandPermissions, err := expandRootPermissions(odataReq)
 	if err != nil {
-		return nil, false, errorcode.New(errorcode.InvalidRequest, err.Error())
+		return nil, false, err
 	}
-	expandPermissions := slices.Contains(exp, "root.permissions")
 	return odataReq, expandPermissions, nil
 }
 
-// getDrives implements the Service interface.
-func (g Graph) getDrives(r *http.Request, unrestricted bool, apiVersion APIVersion) ([]*libregraph.Drive, error) {
+// parsePagedDriveRequest is like parseDriveRequest, but also returns the requested page of drives.
+func parsePagedDriveRequest(r *http.Request) (*godata.GoDataRequest, page, bool, error) {
+	odataReq, pg, err := parsePagedRequest(r.Context(), sanitizePath(r.URL.Path, APIVersion_1), r.URL.Query())
+	if err != nil {
+		return nil, pg, false, errorcode.New(errorcode.InvalidRequest, err.Error())
+	}
+	expandPermissions, err := expandRootPermissions(odataReq)
+	if err != nil {
+		return nil, pg, false, err
+	}
+	return odataReq, pg, expandPermissions, nil
+}
+
+func expandRootPermissions(odataReq *godata.GoDataRequest) (bool, error) {
+	exp, err := odata.GetExpandValues(odataReq.Query)
+	if err != nil {
+		return false, errorcode.New(errorcode.InvalidRequest, err.Error())
+	}
+	return slices.Contains(exp, "root.permissions"), nil
+}
+
+// getDrives implements the Service interface. It returns the requested page of drives and
+// the link to the next page, if there is one.
+func (g Graph) getDrives(r *http.Request, unrestricted bool, apiVersion APIVersion) ([]*libregraph.Drive, string, error) {
 	ctx := r.Context()
 	log := g.logger.SubloggerWithRequestID(ctx).With().Interface("query", r.URL.Query()).Bool("unrestricted", unrestricted).Logger()
 	log.Debug().Msg("calling get drives")
@@ -204,27 +226,27 @@ func (g Graph) getDrives(r *http.Request, unrestricted bool, apiVersion APIVersi
 	webDavBaseURL, err := g.getWebDavBaseURL()
 	if err != nil {
 		log.Error().Err(err).Msg("could not get drives: error parsing url")
-		return nil, errorcode.New(errorcode.GeneralException, err.Error())
+		return nil, "", errorcode.New(errorcode.GeneralException, err.Error())
 	}
 
 	log = log.With().Str("url", webDavBaseURL.String()).Logger()
 
-	odataReq, expandPermissions, err := parseDriveRequest(r)
+	odataReq, pg, expandPermissions, err := parsePagedDriveRequest(r)
 	if err != nil {
 		log.Debug().Err(err).Msg("could not get drives: error parsing odata request")
-		return nil, err
+		return nil, "", err
 	}
 
 	filters, err := generateCs3Filters(odataReq)
 	if err != nil {
 		log.Debug().Err(err).Msg("could not get drives: error parsing filters")
-		return nil, errorcode.New(errorcode.NotSupported, err.Error())
+		return nil, "", errorcode.New(errorcode.NotSupported, err.Error())
 	}
 	if !unrestricted {
 		user, ok := revactx.ContextGetUser(r.Context())
 		if !ok {
 			log.Debug().Msg("could not create drive: invalid user")
-			return nil, errorcode.New(errorcode.AccessDenied, "invalid user")
+			return nil, "", errorcode.New(errorcode.AccessDenied, "invalid user")
 		}
 		filters = append(filters, &storageprovider.ListStorageSpacesRequest_Filter{
 			Type: storageprovider.ListStorageSpacesRequest_Filter_TYPE_USER,
@@ -241,29 +263,47 @@ func (g Graph) getDrives(r *http.Request, unrestricted bool, apiVersion APIVersi
 	switch {
 	case err != nil:
 		log.Error().Err(err).Msg("could not get drives: transport error")
-		return nil, errorcode.New(errorcode.GeneralException, err.Error())
+		return nil, "", errorcode.New(errorcode.GeneralException, err.Error())
 	case res.Status.Code != cs3rpc.Code_CODE_OK:
 		if res.Status.Code == cs3rpc.Code_CODE_NOT_FOUND {
 			// ok, empty return
-			return nil, nil
+			return nil, "", nil
 		}
 		log.Debug().Str("message", res.GetStatus().GetMessage()).Msg("could not get drives: grpc error")
-		return nil, errorcode.New(errorcode.GeneralException, res.Status.Message)
+		return nil, "", errorcode.New(errorcode.GeneralException, res.Status.Message)
+	}
+
+	storageSpaces := res.StorageSpaces
+	var hasNextPage bool
+	if pg.paged() {
+		// page through the storage spaces before converting them, which requires further requests per space
+		storageSpaces = slices.DeleteFunc(storageSpaces, func(space *storageprovider.StorageSpace) bool {
+			return space.GetRoot().GetStorageId() == utils.OCMStorageProviderID
+		})
+		if err := sortStorageSpaces(odataReq, storageSpaces); err != nil {
+			log.Debug().Err(err).Msg("could not get drives: error sorting the spaces list according to query")
+			return nil, "", errorcode.New(errorcode.InvalidRequest, err.Error())
+		}
+		storageSpaces, hasNextPage = paginate(storageSpaces, pg)
 	}
 
-	spaces, err := g.formatDrives(ctx, webDavBaseURL, res.StorageSpaces, apiVersion, expandPermissions, getFieldMask(odataReq))
+	spaces, err := g.formatDrives(ctx, webDavBaseURL, storageSpaces, apiVersion, expandPermissions, getFieldMask(odataReq))
 	if err != nil {
 		log.Debug().Err(err).Msg("could not get drives: error parsing grpc response")
-		return nil, errorcode.New(errorcode.GeneralException, err.Error())
+		return nil, "", errorcode.New(errorcode.GeneralException, err.Error())
 	}
 
 	spaces, err = sortSpaces(odataReq, spaces)
 	if err != nil {
 		log.Debug().Err(err).Msg("could not get drives: error sorting the spaces list according to query")
-		return nil, errorcode.New(errorcode.InvalidRequest, err.Error())
+		return nil, "", errorcode.New(errorcode.InvalidRequest, err.Error())
 	}
 
-	return spaces, nil
+	var nextLink string
+	if hasNextPage {
+		nextLink = g.nextLink(r, pg)
+	}
+	return spaces, nextLink, nil
 }
 
 // GetSingleDrive does a lookup of a single space by spaceId
@@ -421,6 +461,23 @@ func (g Graph) CreateDrive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// admin defined templates provide the defaults of the space
+	template := r.URL.Query().Get(TemplateParameter)
+	var spaceTemplate *SpaceTemplate
+	if driveType == _spaceTypeProject && !isBuiltinSpaceTemplate(template) {
+		if spaceTemplate, err = g.getSpaceTemplate(ctx, template); err != nil {
+			log.Debug().Err(err).Str("template", template).Msg("could not create drive: could not get space template")
+			errorcode.RenderError(w, r, err)
+			return
+		}
+		if drive.Description == nil && spaceTemplate.Description != "" {
+			drive.Description = libregraph.PtrString(spaceTemplate.Description)
+		}
+		if (drive.Quota == nil || drive.Quota.Total == nil) && spaceTemplate.Quota != nil {
+			drive.Quota = &libregraph.Quota{Total: spaceTemplate.Quota}
+		}
+	}
+
 	csr := storageprovider.CreateStorageSpaceRequest{
 		Type:  driveType,
 		Name:  spaceName,
@@ -463,9 +520,9 @@ func (g Graph) CreateDrive(w http.ResponseWriter, r *http.Request) {
 	}
 
 	space := resp.GetStorageSpace()
-	if t := r.URL.Query().Get(TemplateParameter); t != "" && driveType == _spaceTypeProject {
+	if template != "" && driveType == _spaceTypeProject {
 		loc := l10n.MustGetUserLocale(ctx, us.GetId().GetOpaqueId(), r.Header.Get(HeaderAcceptLanguage), g.valueService)
-		if err := g.applySpaceTemplate(ctx, gatewayClient, space.GetRoot(), t, loc); err != nil {
+		if err := g.applySpaceTemplate(ctx, gatewayClient, space.GetRoot(), template, spaceTemplate, loc); err != nil {
 			log.Error().Err(err).Msg("could not apply template to space")
 			errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, err.Error())
 			return
@@ -661,15 +718,16 @@ func (g Graph) UpdateDrive(w http.ResponseWriter, r *http.Request) {
 
 func (g Graph) formatDrives(ctx context.Context, baseURL *url.URL, storageSpaces []*storageprovider.StorageSpace, apiVersion APIVersion, expandPermissions bool, fieldMask map[string]struct{}) ([]*libregraph.Drive, error) {
 	errg, ctx := errgroup.WithContext(ctx)
-	work := make(chan *storageprovider.StorageSpace, len(storageSpaces))
-	results := make(chan *libregraph.Drive, len(storageSpaces))
+	work := make(chan int, len(storageSpaces))
+	// the drives are kept in the order of the storage spaces
+	responses := make([]*libregraph.Drive, len(storageSpaces))
 
 	// Distribute work
 	errg.Go(func() error {
 		defer close(work)
-		for _, space := range storageSpaces {
+		for i := range storageSpaces {
 			select {
-			case work <- space:
+			case work <- i:
 			case <-ctx.Done():
 				return ctx.Err()
 			}
@@ -684,7 +742,8 @@ func (g Graph) formatDrives(ctx context.Context, baseURL *url.URL, storageSpaces
 	}
 	for i := 0; i < numWorkers; i++ {
 		errg.Go(func() error {
-			for storageSpace := range work {
+			for i := range work {
+				storageSpace := storageSpaces[i]
 				if storageSpace.GetRoot().GetStorageId() == utils.OCMStorageProviderID {
 					// skip OCM shares they are no supposed to show up in the drives list
 					continue
@@ -707,32 +766,18 @@ func (g Graph) formatDrives(ctx context.Context, baseURL *url.URL, storageSpaces
 						res.Quota = nil
 					}
 				}
-				select {
-				case results <- res:
-				case <-ctx.Done():
-					return ctx.Err()
-				}
+				responses[i] = res
 			}
 			return nil
 		})
 	}
 
-	// Wait for things to settle down, then close results chan
-	go func() {
-		_ = errg.Wait() // error is checked later
-		close(results)
-	}()
-
-	responses := make([]*libregraph.Drive, 0, len(storageSpaces))
-	for r := range results {
-		responses = append(responses, r)
-	}
-
 	if err := errg.Wait(); err != nil {
 		return nil, err
 	}
 
-	return responses, nil
+	// drop the skipped spaces
+	return slices.DeleteFunc(responses, func(d *libregraph.Drive) bool { return d == nil }), nil
 }
 
 // ListStorageSpacesWithFilters List Storage Spaces using filters
@@ -971,27 +1016,12 @@ func (g Graph) getDriveQuota(ctx context.Context, space *storageprovider.Storage
 		// Use remaining bytes to calculate state
 		t = remaining
 	}
-	state := calculateQuotaState(t, used)
+	state := quota.State(t, used)
 	qta.State = &state
 
 	return qta, nil
 }
 
-func calculateQuotaState(total int64, used int64) (state string) {
-	percent := (float64(used) / float64(total)) * 100
-
-	switch {
-	case percent <= float64(75):
-		return "normal"
-	case percent <= float64(90):
-		return "nearing"
-	case percent <= float64(99):
-		return "critical"
-	default:
-		return "exceeded"
-	}
-}
-
 func getQuota(quota *libregraph.Quota, defaultQuota string) *storageprovider.Quota {
 	switch {
 	case quota != nil && quota.Total != nil:
@@ -1162,6 +1192,47 @@ func sortSpaces(req *godata.GoDataRequest, spaces []*libregraph.Drive) ([]*libre
 	return spaces, nil
 }
 
+// sortStorageSpaces sorts the storage spaces like sortSpaces sorts the drives, so that they can be paged
+// through before converting them. Without $orderby the spaces are sorted by id to keep the pages stable.
+func sortStorageSpaces(req *godata.GoDataRequest, spaces []*storageprovider.StorageSpace) error {
+	if req.Query.OrderBy == nil || len(req.Query.OrderBy.OrderByItems) != 1 {
+		sort.Slice(spaces, func(i, j int) bool {
+			return spaces[i].GetId().GetOpaqueId() < spaces[j].GetId().GetOpaqueId()
+		})
+		return nil
+	}
+	var less func(i, j int) bool
+
+	switch req.Query.OrderBy.OrderByItems[0].Field.Value {
+	case "name":
+		less = func(i, j int) bool {
+			return strings.ToLower(spaces[i].GetName()) < strings.ToLower(spaces[j].GetName())
+		}
+	case "lastModifiedDateTime":
+		less = func(i, j int) bool {
+			mi, mj := spaces[i].GetMtime(), spaces[j].GetMtime()
+			switch {
+			case mi != nil && mj != nil:
+				return cs3TimestampToTime(mi).Before(cs3TimestampToTime(mj))
+			case mi == nil && mj != nil:
+				return true
+			case mi != nil && mj == nil:
+				return false
+			}
+			return strings.ToLower(spaces[i].GetName()) < strings.ToLower(spaces[j].GetName())
+		}
+	default:
+		return errors.Errorf("we do not support <%s> as a order parameter", req.Query.OrderBy.OrderByItems[0].Field.Value)
+	}
+
+	if req.Query.OrderBy.OrderByItems[0].Order == _sortDescending {
+		sort.Slice(spaces, reverse(less))
+	} else {
+		sort.Slice(spaces, less)
+	}
+	return nil
+}
+
 func validateSpaceName(name string) error {
 	if name == "" {
 		return ErrNameEmpty
@@ -56,6 +56,7 @@ type RoleService interface {
 type Graph struct {
 	BaseGraphService
 	mux                      *chi.Mux
+	scim                     *chi.Mux
 	identityBackend          identity.Backend
 	identityEducationBackend identity.EducationBackend
 	roleService              RoleService
@@ -69,6 +70,15 @@ type Graph struct {
 	historyClient            ehsvc.EventHistoryService
 	traceProvider            trace.TracerProvider
 	natskv                   jetstream.KeyValue
+	deltakv                  jetstream.KeyValue
+	copyjobkv                jetstream.KeyValue
+	copyJobs                 *copyJobLimiter
+	tokenkv                  jetstream.KeyValue
+	spaceTemplates           SpaceTemplatesProvider
+	shareIndex               ShareIndex
+	serviceToken             *serviceAccountToken
+	driveItemPermissions     DriveItemPermissionsProvider
+	httpClient               HTTPClient
 }
 
 // ServeHTTP implements the Service interface.
@@ -80,6 +90,11 @@ func (g Graph) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// https://github.com/go-chi/chi/issues/641#issuecomment-883156692
 	r.URL.RawPath = r.URL.EscapedPath()
 
+	// the SCIM endpoint is not authenticated by the middlewares of the graph api
+	if g.isSCIMRequest(r) {
+		g.scim.ServeHTTP(w, r)
+		return
+	}
 	g.mux.ServeHTTP(w, r)
 }
 
@@ -105,6 +120,10 @@ func (g Graph) getWebDavBaseURL() (*url.URL, error) {
 // ListResponse is used for proper marshalling of Graph list responses
 type ListResponse struct {
 	Value interface{} `json:"value,omitempty"`
+	// NextLink points to the next page of a paged collection
+	NextLink string `json:"@odata.nextLink,omitempty"`
+	// DeltaLink is used to request the changes after the state of a delta query
+	DeltaLink string `json:"@odata.deltaLink,omitempty"`
 }
 
 const (
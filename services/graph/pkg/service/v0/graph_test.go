@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"time"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
@@ -270,6 +271,65 @@ var _ = Describe("Graph", func() {
 			}
 			`))
 			})
+			It("can page through the spaces", func() {
+				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&provider.ListStorageSpacesResponse{
+					Status: status.NewOK(ctx),
+					StorageSpaces: []*provider.StorageSpace{
+						{
+							Id:        &provider.StorageSpaceId{OpaqueId: "asameID"},
+							SpaceType: "aspacetype",
+							Root:      &provider.ResourceId{StorageId: "pro-1", SpaceId: "asameID", OpaqueId: "asameID"},
+							Name:      "aspacename",
+						},
+						{
+							Id:        &provider.StorageSpaceId{OpaqueId: "bsameID"},
+							SpaceType: "bspacetype",
+							Root:      &provider.ResourceId{StorageId: "pro-1", SpaceId: "bsameID", OpaqueId: "bsameID"},
+							Name:      "bspacename",
+						},
+					},
+				}, nil)
+				gatewayClient.On("InitiateFileDownload", mock.Anything, mock.Anything).Return(&gateway.InitiateFileDownloadResponse{
+					Status: status.NewNotFound(ctx, "not found"),
+				}, nil)
+				gatewayClient.On("GetQuota", mock.Anything, mock.Anything).Return(&provider.GetQuotaResponse{
+					Status: status.NewUnimplemented(ctx, fmt.Errorf("not supported"), "not supported"),
+				}, nil)
+				gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{
+					Status: status.NewNotFound(ctx, "no special files found"),
+				}, nil)
+
+				type driveList struct {
+					Value    []*libregraph.Drive
+					NextLink string `json:"@odata.nextLink"`
+				}
+
+				r := httptest.NewRequest(http.MethodGet, "/graph/v1.0/me/drives?$orderby=name%20desc&$top=1", nil)
+				r = r.WithContext(ctx)
+				rr := httptest.NewRecorder()
+				svc.GetDrivesV1(rr, r)
+
+				Expect(rr.Code).To(Equal(http.StatusOK))
+				res := driveList{}
+				Expect(json.Unmarshal(rr.Body.Bytes(), &res)).To(Succeed())
+				Expect(res.Value).To(HaveLen(1))
+				Expect(res.Value[0].GetName()).To(Equal("bspacename"))
+				Expect(res.NextLink).To(ContainSubstring("/graph/v1.0/me/drives?"))
+
+				next, err := url.Parse(res.NextLink)
+				Expect(err).ToNot(HaveOccurred())
+				r = httptest.NewRequest(http.MethodGet, next.RequestURI(), nil)
+				r = r.WithContext(ctx)
+				rr = httptest.NewRecorder()
+				svc.GetDrivesV1(rr, r)
+
+				Expect(rr.Code).To(Equal(http.StatusOK))
+				res = driveList{}
+				Expect(json.Unmarshal(rr.Body.Bytes(), &res)).To(Succeed())
+				Expect(res.Value).To(HaveLen(1))
+				Expect(res.Value[0].GetName()).To(Equal("aspacename"))
+				Expect(res.NextLink).To(BeEmpty())
+			})
 			It("can list a spaces type mountpoint", func() {
 				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&provider.ListStorageSpacesResponse{
 					Status: status.NewOK(ctx),
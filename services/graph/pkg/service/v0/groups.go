@@ -10,6 +10,7 @@ import (
 
 	"github.com/CiscoM31/godata"
 	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/identity"
 	libregraph "github.com/opencloud-eu/libre-graph-api-go"
 
 	"github.com/go-chi/chi/v5"
@@ -25,7 +26,7 @@ func (g Graph) GetGroups(w http.ResponseWriter, r *http.Request) {
 	logger := g.logger.SubloggerWithRequestID(r.Context())
 	logger.Info().Interface("query", r.URL.Query()).Msg("calling get groups")
 	sanitizedPath := strings.TrimPrefix(r.URL.Path, "/graph/v1.0/")
-	odataReq, err := godata.ParseRequest(r.Context(), sanitizedPath, r.URL.Query())
+	odataReq, pg, err := parsePagedRequest(r.Context(), sanitizedPath, r.URL.Query())
 	if err != nil {
 		logger.Debug().Err(err).Interface("query", r.URL.Query()).Msg("could not get groups: query error")
 		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
@@ -56,7 +57,17 @@ func (g Graph) GetGroups(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	groups, err := g.identityBackend.GetGroups(r.Context(), odataReq)
+	// let the backend page through the groups, unless they need to be sorted first
+	pagingBackend, backendPaging := g.identityBackend.(identity.PagingBackend)
+	backendPaging = backendPaging && pg.top > 0 && odataReq.Query.OrderBy == nil
+
+	var groups []*libregraph.Group
+	if backendPaging {
+		// request one more group to know if there is a next page
+		groups, err = pagingBackend.GetGroupsPage(r.Context(), odataReq, pg.skip, pg.top+1)
+	} else {
+		groups, err = g.identityBackend.GetGroups(r.Context(), odataReq)
+	}
 	if err != nil {
 		logger.Debug().Err(err).Msg("could not get groups: backend error")
 		errorcode.RenderError(w, r, err)
@@ -81,8 +92,20 @@ func (g Graph) GetGroups(w http.ResponseWriter, r *http.Request) {
 		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
+
+	var hasNextPage bool
+	if backendPaging {
+		groups, hasNextPage = paginate(groups, page{top: pg.top})
+	} else {
+		groups, hasNextPage = paginate(groups, pg)
+	}
+
+	res := &ListResponse{Value: groups}
+	if hasNextPage {
+		res.NextLink = g.nextLink(r, pg)
+	}
 	render.Status(r, http.StatusOK)
-	render.JSON(w, r, &ListResponse{Value: groups})
+	render.JSON(w, r, res)
 }
 
 // PostGroup implements the Service interface.
@@ -33,7 +33,8 @@ import (
 )
 
 type groupList struct {
-	Value []*libregraph.Group
+	Value    []*libregraph.Group
+	NextLink string `json:"@odata.nextLink"`
 }
 
 var _ = Describe("Groups", func() {
@@ -126,6 +127,34 @@ var _ = Describe("Groups", func() {
 			Expect(odataerr.Error.Code).To(Equal("invalidRequest"))
 		})
 
+		It("pages through the groups", func() {
+			permissionService.On("GetPermissionByID", mock.Anything, mock.Anything).Return(&settings.GetPermissionByIDResponse{
+				Permission: &settingsmsg.Permission{
+					Operation:  settingsmsg.Permission_OPERATION_UNKNOWN,
+					Constraint: settingsmsg.Permission_CONSTRAINT_ALL,
+				},
+			}, nil)
+			groups := []*libregraph.Group{}
+			for _, name := range []string{"group1", "group2", "group3"} {
+				group := libregraph.NewGroup()
+				group.SetId(name)
+				group.SetDisplayName(name)
+				groups = append(groups, group)
+			}
+			identityBackend.On("GetGroups", ctx, mock.Anything).Return(groups, nil)
+
+			r := httptest.NewRequest(http.MethodGet, "/graph/v1.0/groups?$top=1&$skip=1", nil)
+			svc.GetGroups(rr, r)
+
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			res := groupList{}
+			Expect(json.Unmarshal(rr.Body.Bytes(), &res)).To(Succeed())
+			Expect(res.Value).To(HaveLen(1))
+			Expect(res.Value[0].GetId()).To(Equal("group2"))
+			Expect(res.NextLink).To(ContainSubstring("%24skiptoken="))
+			Expect(res.NextLink).ToNot(ContainSubstring("%24skip="))
+		})
+
 		It("handles unknown backend errors", func() {
 			permissionService.On("GetPermissionByID", mock.Anything, mock.Anything).Return(&settings.GetPermissionByIDResponse{
 				Permission: &settingsmsg.Permission{
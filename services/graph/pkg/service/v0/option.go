@@ -35,6 +35,8 @@ type Options struct {
 	IdentityEducationBackend identity.EducationBackend
 	RoleService              RoleService
 	UserProfilePhotoService  UsersUserProfilePhotoProvider
+	SpaceTemplatesService    SpaceTemplatesProvider
+	ShareIndex               ShareIndex
 	PermissionService        Permissions
 	ValueService             settingssvc.ValueService
 	RoleManager              *roles.Manager
@@ -45,6 +47,10 @@ type Options struct {
 	EventHistoryClient       ehsvc.EventHistoryService
 	TraceProvider            trace.TracerProvider
 	NatsKeyValue             jetstream.KeyValue
+	DeltaKeyValue            jetstream.KeyValue
+	CopyJobKeyValue          jetstream.KeyValue
+	TokenKeyValue            jetstream.KeyValue
+	HTTPClient               HTTPClient
 }
 
 // newOptions initializes the available default options.
@@ -121,6 +127,27 @@ func WithNatsKeyValue(val jetstream.KeyValue) Option {
 	}
 }
 
+// WithDeltaKeyValue provides a function to set the DeltaKeyValue option.
+func WithDeltaKeyValue(val jetstream.KeyValue) Option {
+	return func(o *Options) {
+		o.DeltaKeyValue = val
+	}
+}
+
+// WithCopyJobKeyValue provides a function to set the CopyJobKeyValue option.
+func WithCopyJobKeyValue(val jetstream.KeyValue) Option {
+	return func(o *Options) {
+		o.CopyJobKeyValue = val
+	}
+}
+
+// WithTokenKeyValue provides a function to set the TokenKeyValue option.
+func WithTokenKeyValue(val jetstream.KeyValue) Option {
+	return func(o *Options) {
+		o.TokenKeyValue = val
+	}
+}
+
 // WithRoleService provides a function to set the RoleService option.
 func WithRoleService(val RoleService) Option {
 	return func(o *Options) {
@@ -197,3 +224,24 @@ func UserProfilePhotoService(p UsersUserProfilePhotoProvider) Option {
 		o.UserProfilePhotoService = p
 	}
 }
+
+// WithSpaceTemplatesService provides a function to set the SpaceTemplatesService option.
+func WithSpaceTemplatesService(p SpaceTemplatesProvider) Option {
+	return func(o *Options) {
+		o.SpaceTemplatesService = p
+	}
+}
+
+// WithShareIndex provides a function to set the ShareIndex option.
+func WithShareIndex(val ShareIndex) Option {
+	return func(o *Options) {
+		o.ShareIndex = val
+	}
+}
+
+// WithHTTPClient provides a function to set the HTTPClient option.
+func WithHTTPClient(val HTTPClient) Option {
+	return func(o *Options) {
+		o.HTTPClient = val
+	}
+}
@@ -0,0 +1,100 @@
+package svc
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/CiscoM31/godata"
+)
+
+// _skipToken is the query parameter carrying the opaque continuation token of a paged collection
+const _skipToken = "$skiptoken"
+
+// page is the part of a collection requested with $top, $skip and $skiptoken
+type page struct {
+	skip int
+	// top is the maximum number of items on the page, zero means that all items are requested
+	top int
+}
+
+// paged returns true if only a part of the collection was requested
+func (p page) paged() bool {
+	return p.skip > 0 || p.top > 0
+}
+
+// parsePagedRequest parses the odata request of a collection that supports paging. The
+// $skiptoken is taken from the query before parsing it, godata does not support it.
+func parsePagedRequest(ctx context.Context, path string, query url.Values) (*godata.GoDataRequest, page, error) {
+	var p page
+	token := query.Get(_skipToken)
+	if token != "" {
+		skip, err := decodeSkipToken(token)
+		if err != nil {
+			return nil, p, err
+		}
+		p.skip = skip
+		query.Del(_skipToken)
+	}
+
+	odataReq, err := godata.ParseRequest(ctx, path, query)
+	if err != nil {
+		return nil, p, err
+	}
+	if odataReq.Query.Skip != nil && token == "" {
+		p.skip = int(*odataReq.Query.Skip)
+	}
+	if odataReq.Query.Top != nil {
+		p.top = int(*odataReq.Query.Top)
+	}
+	if p.skip < 0 || p.top < 0 {
+		return nil, p, errors.New("$top and $skip must not be negative")
+	}
+	return odataReq, p, nil
+}
+
+// paginate returns the items of the page from the complete collection
+// and whether more items follow the page.
+func paginate[T any](items []T, p page) ([]T, bool) {
+	if p.skip >= len(items) {
+		return items[:0], false
+	}
+	items = items[p.skip:]
+	if p.top > 0 && len(items) > p.top {
+		return items[:p.top], true
+	}
+	return items, false
+}
+
+// nextLink returns the url of the page following the given page of the requested collection
+func (g Graph) nextLink(r *http.Request, p page) string {
+	query := r.URL.Query()
+	query.Del("$skip")
+	query.Set(_skipToken, encodeSkipToken(p.skip+p.top))
+
+	var base string
+	if g.config.Commons != nil {
+		base = strings.TrimRight(g.config.Commons.OpenCloudURL, "/")
+	}
+	return base + r.URL.Path + "?" + query.Encode()
+}
+
+func encodeSkipToken(skip int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(skip)))
+}
+
+func decodeSkipToken(token string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errors.New("invalid $skiptoken")
+	}
+	skip, err := strconv.Atoi(string(b))
+	if err != nil || skip < 0 {
+		return 0, errors.New("invalid $skiptoken")
+	}
+	return skip, nil
+}
@@ -0,0 +1,322 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/render"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	"github.com/opencloud-eu/opencloud/pkg/retention"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+)
+
+// RetentionPolicy is the retention policy of a drive. Rules with a zero value are not applied.
+type RetentionPolicy struct {
+	// VersionsDays is the number of days the versions of a file are kept.
+	VersionsDays int `json:"versionsDays"`
+	// VersionsCount is the number of versions of a file that are kept.
+	VersionsCount int `json:"versionsCount"`
+	// TrashDays is the number of days after which items are purged from the trash-bin.
+	TrashDays int `json:"trashDays"`
+	// MinAgeDays is the number of days a file or folder can't be deleted after it was changed.
+	MinAgeDays int `json:"minAgeDays"`
+	// LegalHold prevents deleting and purging anything in the drive.
+	LegalHold bool `json:"legalHold"`
+	// LegalHolds are the folders of the drive under legal hold.
+	LegalHolds []LegalHold `json:"legalHolds"`
+}
+
+// LegalHold is a folder under legal hold.
+type LegalHold struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+}
+
+// retentionPolicyUpdate is the body of a retention policy update, only the given rules are changed.
+type retentionPolicyUpdate struct {
+	VersionsDays  *int  `json:"versionsDays"`
+	VersionsCount *int  `json:"versionsCount"`
+	TrashDays     *int  `json:"trashDays"`
+	MinAgeDays    *int  `json:"minAgeDays"`
+	LegalHold     *bool `json:"legalHold"`
+}
+
+func (u retentionPolicyUpdate) apply(p *retention.Policy) error {
+	for _, rule := range []struct {
+		value  *int
+		target *int
+	}{
+		{u.VersionsDays, &p.VersionsDays},
+		{u.VersionsCount, &p.VersionsCount},
+		{u.TrashDays, &p.TrashDays},
+		{u.MinAgeDays, &p.MinAgeDays},
+	} {
+		if rule.value == nil {
+			continue
+		}
+		if *rule.value < 0 {
+			return errorcode.New(errorcode.InvalidRequest, "retention rules can't be negative")
+		}
+		*rule.target = *rule.value
+	}
+	if u.LegalHold != nil {
+		p.LegalHold = *u.LegalHold
+	}
+	return nil
+}
+
+func newRetentionPolicy(root *storageprovider.ResourceId, p retention.Policy) RetentionPolicy {
+	rp := RetentionPolicy{
+		VersionsDays:  p.VersionsDays,
+		VersionsCount: p.VersionsCount,
+		TrashDays:     p.TrashDays,
+		MinAgeDays:    p.MinAgeDays,
+		LegalHold:     p.LegalHold,
+		LegalHolds:    []LegalHold{},
+	}
+	for id, path := range p.Holds {
+		rp.LegalHolds = append(rp.LegalHolds, LegalHold{
+			ID: storagespace.FormatResourceID(&storageprovider.ResourceId{
+				StorageId: root.GetStorageId(),
+				SpaceId:   root.GetSpaceId(),
+				OpaqueId:  id,
+			}),
+			Path: path,
+		})
+	}
+	slices.SortFunc(rp.LegalHolds, func(a, b LegalHold) int { return strings.Compare(a.Path, b.Path) })
+	return rp
+}
+
+// retentionPolicyError translates the errors of reading a retention policy into graph errors
+func retentionPolicyError(err error) error {
+	var notFound errtypes.IsNotFound
+	var permissionDenied errtypes.IsPermissionDenied
+	switch {
+	case errors.As(err, &notFound):
+		return errorcode.New(errorcode.ItemNotFound, "drive not found")
+	case errors.As(err, &permissionDenied):
+		return errorcode.New(errorcode.AccessDenied, err.Error())
+	default:
+		return err
+	}
+}
+
+// GetRetentionPolicy returns the retention policy of a drive
+func (g Graph) GetRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+
+	driveID, err := parseIDParam(r, "driveID")
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		logger.Error().Err(err).Msg("could not select next gateway client")
+		errorcode.ServiceNotAvailable.Render(w, r, http.StatusInternalServerError, "could not select next gateway client")
+		return
+	}
+
+	root := retention.SpaceRoot(&driveID)
+	policy, err := retention.Get(ctx, gatewayClient, root)
+	if err != nil {
+		logger.Debug().Err(err).Str("driveID", storagespace.FormatResourceID(root)).Msg("could not read the retention policy")
+		errorcode.RenderError(w, r, retentionPolicyError(err))
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newRetentionPolicy(root, policy))
+}
+
+// UpdateRetentionPolicy changes the retention rules and the legal hold of a drive
+func (g Graph) UpdateRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+
+	driveID, err := parseIDParam(r, "driveID")
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	update := retentionPolicyUpdate{}
+	if err := StrictJSONUnmarshal(r.Body, &update); err != nil {
+		logger.Debug().Err(err).Msg("could not decode the retention policy")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid retention policy: "+err.Error())
+		return
+	}
+
+	sctx, gatewayClient, err := g.serviceAccountContext(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("could not authenticate the service account")
+		errorcode.ServiceNotAvailable.Render(w, r, http.StatusInternalServerError, "could not update the retention policy")
+		return
+	}
+
+	root := retention.SpaceRoot(&driveID)
+	policy, err := retention.Get(sctx, gatewayClient, root)
+	if err != nil {
+		logger.Debug().Err(err).Str("driveID", storagespace.FormatResourceID(root)).Msg("could not read the retention policy")
+		errorcode.RenderError(w, r, retentionPolicyError(err))
+		return
+	}
+	if err := update.apply(&policy); err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	set, unset := policy.Metadata()
+	ref := &storageprovider.Reference{ResourceId: root}
+	setRes, err := gatewayClient.SetArbitraryMetadata(sctx, &storageprovider.SetArbitraryMetadataRequest{
+		Ref:               ref,
+		ArbitraryMetadata: &storageprovider.ArbitraryMetadata{Metadata: set},
+	})
+	if errCode := errorcode.FromCS3Status(setRes.GetStatus(), err); errCode != nil {
+		logger.Error().Err(errCode).Msg("could not set the retention policy")
+		errorcode.RenderError(w, r, errCode)
+		return
+	}
+	unsetRes, err := gatewayClient.UnsetArbitraryMetadata(sctx, &storageprovider.UnsetArbitraryMetadataRequest{
+		Ref:                   ref,
+		ArbitraryMetadataKeys: unset,
+	})
+	if errCode := errorcode.FromCS3Status(unsetRes.GetStatus(), err); errCode != nil {
+		logger.Error().Err(errCode).Msg("could not unset the retention rules")
+		errorcode.RenderError(w, r, errCode)
+		return
+	}
+	g.publishPolicyChanged(ctx, root)
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newRetentionPolicy(root, policy))
+}
+
+// PlaceLegalHold puts a folder of a drive under legal hold
+func (g Graph) PlaceLegalHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+
+	driveID, itemID, err := GetDriveAndItemIDParam(r, &logger)
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if IsSpaceRoot(itemID) {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "use the retention policy of the drive to put the whole drive under legal hold")
+		return
+	}
+
+	sctx, gatewayClient, err := g.serviceAccountContext(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("could not authenticate the service account")
+		errorcode.ServiceNotAvailable.Render(w, r, http.StatusInternalServerError, "could not place the legal hold")
+		return
+	}
+
+	statRes, err := gatewayClient.Stat(sctx, &storageprovider.StatRequest{Ref: &storageprovider.Reference{ResourceId: itemID}})
+	if errCode := errorcode.FromStat(statRes, err); errCode != nil {
+		logger.Debug().Err(errCode).Msg("could not stat the item")
+		errorcode.RenderError(w, r, errCode)
+		return
+	}
+	if statRes.GetInfo().GetType() != storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "only folders can be put under legal hold")
+		return
+	}
+
+	pathRes, err := gatewayClient.GetPath(sctx, &storageprovider.GetPathRequest{ResourceId: itemID})
+	if errCode := errorcode.FromCS3Status(pathRes.GetStatus(), err); errCode != nil {
+		logger.Error().Err(errCode).Msg("could not get the path of the item")
+		errorcode.RenderError(w, r, errCode)
+		return
+	}
+
+	res, err := gatewayClient.SetArbitraryMetadata(sctx, &storageprovider.SetArbitraryMetadataRequest{
+		Ref: &storageprovider.Reference{ResourceId: retention.SpaceRoot(driveID)},
+		ArbitraryMetadata: &storageprovider.ArbitraryMetadata{Metadata: map[string]string{
+			retention.HoldKeyPrefix + itemID.GetOpaqueId(): pathRes.GetPath(),
+		}},
+	})
+	if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+		logger.Error().Err(errCode).Msg("could not place the legal hold")
+		errorcode.RenderError(w, r, errCode)
+		return
+	}
+	g.publishPolicyChanged(ctx, driveID)
+
+	render.Status(r, http.StatusNoContent)
+	render.NoContent(w, r)
+}
+
+// ReleaseLegalHold releases the legal hold of a folder of a drive
+func (g Graph) ReleaseLegalHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+
+	driveID, itemID, err := GetDriveAndItemIDParam(r, &logger)
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if IsSpaceRoot(itemID) {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "use the retention policy of the drive to put the whole drive under legal hold")
+		return
+	}
+
+	sctx, gatewayClient, err := g.serviceAccountContext(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("could not authenticate the service account")
+		errorcode.ServiceNotAvailable.Render(w, r, http.StatusInternalServerError, "could not release the legal hold")
+		return
+	}
+
+	root := retention.SpaceRoot(driveID)
+	policy, err := retention.Get(sctx, gatewayClient, root)
+	if err != nil {
+		logger.Debug().Err(err).Msg("could not read the retention policy")
+		errorcode.RenderError(w, r, retentionPolicyError(err))
+		return
+	}
+	if _, ok := policy.Holds[itemID.GetOpaqueId()]; !ok {
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "the item is not under legal hold")
+		return
+	}
+
+	res, err := gatewayClient.UnsetArbitraryMetadata(sctx, &storageprovider.UnsetArbitraryMetadataRequest{
+		Ref:                   &storageprovider.Reference{ResourceId: root},
+		ArbitraryMetadataKeys: []string{retention.HoldKeyPrefix + itemID.GetOpaqueId()},
+	})
+	if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+		logger.Error().Err(errCode).Msg("could not release the legal hold")
+		errorcode.RenderError(w, r, errCode)
+		return
+	}
+	g.publishPolicyChanged(ctx, root)
+
+	render.Status(r, http.StatusNoContent)
+	render.NoContent(w, r)
+}
+
+// publishPolicyChanged tells the storage providers to read the retention policy of the space again.
+func (g Graph) publishPolicyChanged(ctx context.Context, id *storageprovider.ResourceId) {
+	ev := retention.PolicyChanged{
+		SpaceID:   storagespace.FormatStorageID(id.GetStorageId(), id.GetSpaceId()),
+		Timestamp: utils.TSNow(),
+	}
+	if u, ok := revactx.ContextGetUser(ctx); ok {
+		ev.Executant = u.GetId()
+	}
+	g.publishEvent(ctx, ev)
+}
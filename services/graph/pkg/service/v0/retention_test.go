@@ -0,0 +1,218 @@
+package svc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/opencloud-eu/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+
+	"github.com/opencloud-eu/opencloud/pkg/retention"
+	"github.com/opencloud-eu/opencloud/pkg/shared"
+	"github.com/opencloud-eu/opencloud/services/graph/mocks"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config/defaults"
+	identitymocks "github.com/opencloud-eu/opencloud/services/graph/pkg/identity/mocks"
+	service "github.com/opencloud-eu/opencloud/services/graph/pkg/service/v0"
+)
+
+var _ = Describe("RetentionPolicy", func() {
+	var (
+		svc             service.Service
+		gatewayClient   *cs3mocks.GatewayAPIClient
+		eventsPublisher mocks.Publisher
+		rr              *httptest.ResponseRecorder
+		metadata        map[string]string
+	)
+
+	request := func(method, body, driveID, itemID string) *http.Request {
+		ctx := revactx.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{OpaqueId: "admin"}})
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("driveID", driveID)
+		if itemID != "" {
+			rctx.URLParams.Add("itemID", itemID)
+		}
+		r := httptest.NewRequest(method, "/graph/v1beta1/drives/"+driveID, strings.NewReader(body))
+		return r.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+	}
+
+	policy := func() service.RetentionPolicy {
+		p := service.RetentionPolicy{}
+		Expect(json.Unmarshal(rr.Body.Bytes(), &p)).To(Succeed())
+		return p
+	}
+
+	BeforeEach(func() {
+		pool.RemoveSelector("GatewaySelector" + "eu.opencloud.api.gateway")
+		gatewayClient = &cs3mocks.GatewayAPIClient{}
+		gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+			"GatewaySelector",
+			"eu.opencloud.api.gateway",
+			func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+				return gatewayClient
+			},
+		)
+		rr = httptest.NewRecorder()
+		metadata = map[string]string{}
+		eventsPublisher = mocks.Publisher{}
+		eventsPublisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{
+			Status: status.NewOK(context.Background()),
+			Token:  "token",
+		}, nil)
+		gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(
+			func(ctx context.Context, req *provider.StatRequest, _ ...grpc.CallOption) *provider.StatResponse {
+				id := req.GetRef().GetResourceId()
+				switch id.GetOpaqueId() {
+				case "project":
+					return &provider.StatResponse{Status: status.NewOK(ctx), Info: &provider.ResourceInfo{
+						Id:                id,
+						Type:              provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+						ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: metadata},
+					}}
+				case "folder":
+					return &provider.StatResponse{Status: status.NewOK(ctx), Info: &provider.ResourceInfo{Id: id, Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER}}
+				case "file":
+					return &provider.StatResponse{Status: status.NewOK(ctx), Info: &provider.ResourceInfo{Id: id, Type: provider.ResourceType_RESOURCE_TYPE_FILE}}
+				default:
+					return &provider.StatResponse{Status: status.NewNotFound(ctx, "not found")}
+				}
+			}, nil)
+		gatewayClient.On("GetPath", mock.Anything, mock.Anything).Return(
+			func(ctx context.Context, req *provider.GetPathRequest, _ ...grpc.CallOption) *provider.GetPathResponse {
+				return &provider.GetPathResponse{Status: status.NewOK(ctx), Path: "./" + req.GetResourceId().GetOpaqueId()}
+			}, nil)
+		gatewayClient.On("SetArbitraryMetadata", mock.Anything, mock.Anything).Return(
+			func(ctx context.Context, req *provider.SetArbitraryMetadataRequest, _ ...grpc.CallOption) *provider.SetArbitraryMetadataResponse {
+				Expect(req.GetRef().GetResourceId().GetOpaqueId()).To(Equal("project"))
+				for k, v := range req.GetArbitraryMetadata().GetMetadata() {
+					metadata[k] = v
+				}
+				return &provider.SetArbitraryMetadataResponse{Status: status.NewOK(ctx)}
+			}, nil)
+		gatewayClient.On("UnsetArbitraryMetadata", mock.Anything, mock.Anything).Return(
+			func(ctx context.Context, req *provider.UnsetArbitraryMetadataRequest, _ ...grpc.CallOption) *provider.UnsetArbitraryMetadataResponse {
+				Expect(req.GetRef().GetResourceId().GetOpaqueId()).To(Equal("project"))
+				for _, k := range req.GetArbitraryMetadataKeys() {
+					delete(metadata, k)
+				}
+				return &provider.UnsetArbitraryMetadataResponse{Status: status.NewOK(ctx)}
+			}, nil)
+
+		cfg := defaults.FullDefaultConfig()
+		cfg.Identity.LDAP.CACert = "" // skip the startup checks, we don't use LDAP at all in this tests
+		cfg.TokenManager.JWTSecret = "loremipsum"
+		cfg.Commons = &shared.Commons{}
+		cfg.GRPCClientTLS = &shared.GRPCClientTLS{}
+		cfg.ServiceAccount.ServiceAccountID = "service-account"
+		cfg.ServiceAccount.ServiceAccountSecret = "secret"
+
+		var err error
+		svc, err = service.NewService(
+			service.Config(cfg),
+			service.WithGatewaySelector(gatewaySelector),
+			service.WithIdentityBackend(&identitymocks.Backend{}),
+			service.EventsPublisher(&eventsPublisher),
+		)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("GetRetentionPolicy", func() {
+		It("returns the policy of the drive", func() {
+			metadata[retention.TrashDaysKey] = "30"
+			metadata[retention.HoldKeyPrefix+"folder"] = "/old/path"
+
+			svc.GetRetentionPolicy(rr, request(http.MethodGet, "", "storageid$project", ""))
+			Expect(rr.Code).To(Equal(http.StatusOK), rr.Body.String())
+			Expect(policy()).To(Equal(service.RetentionPolicy{
+				TrashDays:  30,
+				LegalHolds: []service.LegalHold{{ID: "storageid$project!folder", Path: "/folder"}},
+			}))
+		})
+		It("returns not found for unknown drives", func() {
+			svc.GetRetentionPolicy(rr, request(http.MethodGet, "", "storageid$unknown", ""))
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("UpdateRetentionPolicy", func() {
+		It("changes the given rules only", func() {
+			metadata[retention.TrashDaysKey] = "30"
+			metadata[retention.MinAgeDaysKey] = "1"
+
+			svc.UpdateRetentionPolicy(rr, request(http.MethodPatch, `{"versionsCount": 5, "minAgeDays": 0, "legalHold": true}`, "storageid$project", ""))
+			Expect(rr.Code).To(Equal(http.StatusOK), rr.Body.String())
+			Expect(policy()).To(Equal(service.RetentionPolicy{
+				VersionsCount: 5,
+				TrashDays:     30,
+				LegalHold:     true,
+				LegalHolds:    []service.LegalHold{},
+			}))
+			Expect(metadata).To(Equal(map[string]string{
+				retention.VersionsCountKey: "5",
+				retention.TrashDaysKey:     "30",
+				retention.LegalHoldKey:     "true",
+			}))
+		})
+		It("rejects negative rules", func() {
+			svc.UpdateRetentionPolicy(rr, request(http.MethodPatch, `{"trashDays": -1}`, "storageid$project", ""))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+			Expect(metadata).To(BeEmpty())
+		})
+		It("rejects unknown properties", func() {
+			svc.UpdateRetentionPolicy(rr, request(http.MethodPatch, `{"keepForever": true}`, "storageid$project", ""))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("legal holds", func() {
+		It("places and releases the legal hold of a folder", func() {
+			svc.PlaceLegalHold(rr, request(http.MethodPost, "", "storageid$project", "storageid$project!folder"))
+			Expect(rr.Code).To(Equal(http.StatusNoContent), rr.Body.String())
+			Expect(metadata).To(HaveKeyWithValue(retention.HoldKeyPrefix+"folder", "./folder"))
+
+			rr = httptest.NewRecorder()
+			svc.ReleaseLegalHold(rr, request(http.MethodDelete, "", "storageid$project", "storageid$project!folder"))
+			Expect(rr.Code).To(Equal(http.StatusNoContent), rr.Body.String())
+			Expect(metadata).To(BeEmpty())
+
+			// the storage providers are told to read the policy again
+			eventsPublisher.AssertNumberOfCalls(GinkgoT(), "Publish", 2)
+			for _, call := range eventsPublisher.Calls {
+				ev, ok := call.Arguments.Get(1).(retention.PolicyChanged)
+				Expect(ok).To(BeTrue())
+				Expect(ev.SpaceID).To(Equal("storageid$project"))
+			}
+		})
+		It("only puts folders of the drive under legal hold", func() {
+			svc.PlaceLegalHold(rr, request(http.MethodPost, "", "storageid$project", "storageid$project!file"))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+
+			rr = httptest.NewRecorder()
+			svc.PlaceLegalHold(rr, request(http.MethodPost, "", "storageid$project", "storageid$project!project"))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+
+			rr = httptest.NewRecorder()
+			svc.PlaceLegalHold(rr, request(http.MethodPost, "", "storageid$project", "storageid$other!folder"))
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+			Expect(metadata).To(BeEmpty())
+		})
+		It("returns not found when releasing a folder that is not under legal hold", func() {
+			svc.ReleaseLegalHold(rr, request(http.MethodDelete, "", "storageid$project", "storageid$project!folder"))
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+})
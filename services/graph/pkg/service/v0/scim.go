@@ -0,0 +1,438 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	gmmetadata "go-micro.dev/v4/metadata"
+
+	"github.com/opencloud-eu/opencloud/pkg/middleware"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+	graphm "github.com/opencloud-eu/opencloud/services/graph/pkg/middleware"
+)
+
+const (
+	// _scimPath is the path of the SCIM endpoint below the root of the graph service
+	_scimPath = "/scim/v2"
+	// _scimContentType is the media type of SCIM requests and responses
+	_scimContentType = "application/scim+json"
+
+	_scimSchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	_scimSchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	_scimSchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	_scimSchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	_scimSchemaBulkResponse          = "urn:ietf:params:scim:api:messages:2.0:BulkResponse"
+	_scimSchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	_scimSchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	_scimSchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+
+	_scimTypeInvalidFilter = "invalidFilter"
+	_scimTypeInvalidSyntax = "invalidSyntax"
+	_scimTypeInvalidValue  = "invalidValue"
+	_scimTypeInvalidPath   = "invalidPath"
+	_scimTypeUniqueness    = "uniqueness"
+	_scimTypeTooMany       = "tooMany"
+)
+
+// SCIMMeta holds the resource metadata of a SCIM resource.
+type SCIMMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+// SCIMListResponse is the response to a SCIM query.
+type SCIMListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// SCIMError is the body of a SCIM error response.
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// SCIMPatchOperation is a single operation of a SCIM PATCH request.
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// scimPatchRequest is the body of a SCIM PATCH request.
+type scimPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// scimFilterTerm is a single comparison of a SCIM filter.
+type scimFilterTerm struct {
+	attribute string
+	operator  string
+	value     string
+}
+
+// scimFilter is a parsed SCIM filter. Only 'eq' and 'sw' comparisons combined with 'and' are supported.
+type scimFilter struct {
+	terms []scimFilterTerm
+}
+
+// scimError is returned by the SCIM handlers to render a SCIM error response.
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e scimError) Error() string {
+	return e.detail
+}
+
+func newSCIMError(status int, scimType, detail string) scimError {
+	return scimError{status: status, scimType: scimType, detail: detail}
+}
+
+var (
+	_scimFilterTermRegex = regexp.MustCompile(`(?i)^\s*([a-z][\w.]*)\s+(eq|sw)\s+"((?:[^"\\]|\\.)*)"`)
+	_scimFilterAndRegex  = regexp.MustCompile(`(?i)^\s+and\s+`)
+)
+
+const _scimFilterUnsupported = "only filters of the form 'attribute eq \"value\"' or 'attribute sw \"value\"' combined with 'and' are supported"
+
+// routeSCIM registers the routes of the SCIM endpoint. Requests to the SCIM endpoint are authenticated
+// with a static bearer token and executed with the permissions of the service account.
+func (g Graph) routeSCIM(m *chi.Mux) {
+	m.Use(
+		chimiddleware.RequestID,
+		graphm.RequireToken(g.config.SCIM.Token),
+		g.scimServiceAccountContext,
+	)
+	m.Route(path.Join(g.config.HTTP.Root, _scimPath), func(r chi.Router) {
+		r.Get("/ServiceProviderConfig", g.GetSCIMServiceProviderConfig)
+		r.Get("/ResourceTypes", g.GetSCIMResourceTypes)
+		r.Post("/Bulk", g.SCIMBulk)
+		r.Route("/Users", func(r chi.Router) {
+			r.Get("/", g.GetSCIMUsers)
+			r.Post("/", g.PostSCIMUser)
+			r.Route("/{userID}", func(r chi.Router) {
+				r.Get("/", g.GetSCIMUser)
+				r.Put("/", g.PutSCIMUser)
+				r.Patch("/", g.PatchSCIMUser)
+				r.Delete("/", g.DeleteSCIMUser)
+			})
+		})
+		r.Route("/Groups", func(r chi.Router) {
+			r.Get("/", g.GetSCIMGroups)
+			r.Post("/", g.PostSCIMGroup)
+			r.Route("/{groupID}", func(r chi.Router) {
+				r.Get("/", g.GetSCIMGroup)
+				r.Put("/", g.PutSCIMGroup)
+				r.Patch("/", g.PatchSCIMGroup)
+				r.Delete("/", g.DeleteSCIMGroup)
+			})
+		})
+	})
+}
+
+// isSCIMRequest returns true if the request needs to be handled by the SCIM endpoint
+func (g Graph) isSCIMRequest(r *http.Request) bool {
+	if g.scim == nil {
+		return false
+	}
+	scimRoot := path.Join(g.config.HTTP.Root, _scimPath)
+	return r.URL.Path == scimRoot || strings.HasPrefix(r.URL.Path, scimRoot+"/")
+}
+
+// scimServiceAccountContext authenticates the request as the service account, which is needed
+// to assign roles, delete personal spaces and publish the events of the changes.
+func (g Graph) scimServiceAccountContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		serviceAccountID := g.config.ServiceAccount.ServiceAccountID
+		if g.gatewaySelector != nil {
+			gatewayClient, err := g.gatewaySelector.Next()
+			if err != nil {
+				g.renderSCIMError(w, r, newSCIMError(http.StatusServiceUnavailable, "", "could not select gateway client"))
+				return
+			}
+			ctx, err = utils.GetServiceUserContextWithContext(ctx, gatewayClient, serviceAccountID, g.config.ServiceAccount.ServiceAccountSecret)
+			if err != nil {
+				g.logger.Error().Err(err).Msg("could not authenticate the service account for the SCIM endpoint")
+				g.renderSCIMError(w, r, newSCIMError(http.StatusInternalServerError, "", "could not authenticate the service account"))
+				return
+			}
+		}
+		ctx = revactx.ContextSetUser(ctx, &userv1beta1.User{
+			Id: &userv1beta1.UserId{
+				OpaqueId: serviceAccountID,
+				Type:     userv1beta1.UserType_USER_TYPE_SERVICE,
+			},
+		})
+		ctx = gmmetadata.Set(ctx, middleware.AccountID, serviceAccountID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetSCIMServiceProviderConfig returns the SCIM features supported by the endpoint
+func (g Graph) GetSCIMServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	g.renderSCIM(w, r, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{_scimSchemaServiceProviderConfig},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": true, "maxOperations": g.config.SCIM.BulkMaxOperations, "maxPayloadSize": _scimBulkMaxPayloadSize},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": 0},
+		"changePassword": map[string]bool{"supported": true},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]string{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "Authentication with the static bearer token configured in GRAPH_SCIM_TOKEN",
+		}},
+		"meta": SCIMMeta{ResourceType: "ServiceProviderConfig", Location: g.scimLocation("ServiceProviderConfig")},
+	})
+}
+
+// GetSCIMResourceTypes returns the resource types supported by the endpoint
+func (g Graph) GetSCIMResourceTypes(w http.ResponseWriter, r *http.Request) {
+	resourceTypes := []map[string]interface{}{
+		{
+			"schemas":  []string{_scimSchemaResourceType},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   _scimSchemaUser,
+			"meta":     SCIMMeta{ResourceType: "ResourceType", Location: g.scimLocation("ResourceTypes", "User")},
+		},
+		{
+			"schemas":  []string{_scimSchemaResourceType},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   _scimSchemaGroup,
+			"meta":     SCIMMeta{ResourceType: "ResourceType", Location: g.scimLocation("ResourceTypes", "Group")},
+		},
+	}
+	g.renderSCIM(w, r, http.StatusOK, &SCIMListResponse{
+		Schemas:      []string{_scimSchemaListResponse},
+		TotalResults: len(resourceTypes),
+		StartIndex:   1,
+		ItemsPerPage: len(resourceTypes),
+		Resources:    resourceTypes,
+	})
+}
+
+// scimLocation returns the url of a resource of the SCIM endpoint
+func (g Graph) scimLocation(elem ...string) string {
+	var base string
+	if g.config.Commons != nil {
+		base = strings.TrimRight(g.config.Commons.OpenCloudURL, "/")
+	}
+	return base + path.Join(append([]string{g.config.HTTP.Root, _scimPath}, elem...)...)
+}
+
+// renderSCIM writes a SCIM response
+func (g Graph) renderSCIM(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", _scimContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		g.logger.Error().Err(err).Msg("could not write SCIM response")
+	}
+}
+
+// renderSCIMError writes a SCIM error response. Errors of the identity backend are mapped
+// to the corresponding status codes.
+func (g Graph) renderSCIMError(w http.ResponseWriter, r *http.Request, err error) {
+	var se scimError
+	if !errors.As(err, &se) {
+		se = newSCIMError(http.StatusInternalServerError, "", err.Error())
+		if e, ok := errorcode.ToError(err); ok {
+			switch e.GetCode() {
+			case errorcode.InvalidRequest:
+				se = newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, err.Error())
+			case errorcode.ItemNotFound:
+				se = newSCIMError(http.StatusNotFound, "", err.Error())
+			case errorcode.NameAlreadyExists:
+				se = newSCIMError(http.StatusConflict, _scimTypeUniqueness, err.Error())
+			case errorcode.AccessDenied, errorcode.NotAllowed:
+				se = newSCIMError(http.StatusForbidden, "", err.Error())
+			case errorcode.NotSupported:
+				se = newSCIMError(http.StatusNotImplemented, "", err.Error())
+			}
+		}
+	}
+	g.logger.Debug().Err(err).Int("status", se.status).Str("path", r.URL.Path).Msg("SCIM request failed")
+	g.renderSCIM(w, r, se.status, &SCIMError{
+		Schemas:  []string{_scimSchemaError},
+		Status:   strconv.Itoa(se.status),
+		SCIMType: se.scimType,
+		Detail:   se.detail,
+	})
+}
+
+// decodeSCIMBody decodes the body of a SCIM request. Unknown attributes are ignored, identity
+// providers send attributes which are not supported by OpenCloud.
+func decodeSCIMBody(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return newSCIMError(http.StatusBadRequest, _scimTypeInvalidSyntax, fmt.Sprintf("invalid request body: %s", err.Error()))
+	}
+	return nil
+}
+
+// parseSCIMFilter parses the filter query parameter
+func parseSCIMFilter(r *http.Request) (*scimFilter, error) {
+	filter := r.URL.Query().Get("filter")
+	if strings.TrimSpace(filter) == "" {
+		return nil, nil
+	}
+	f := &scimFilter{}
+	for {
+		m := _scimFilterTermRegex.FindStringSubmatch(filter)
+		if m == nil {
+			return nil, newSCIMError(http.StatusBadRequest, _scimTypeInvalidFilter, _scimFilterUnsupported)
+		}
+		value, err := strconv.Unquote(`"` + m[3] + `"`)
+		if err != nil {
+			return nil, newSCIMError(http.StatusBadRequest, _scimTypeInvalidFilter, err.Error())
+		}
+		f.terms = append(f.terms, scimFilterTerm{attribute: strings.ToLower(m[1]), operator: strings.ToLower(m[2]), value: value})
+		filter = filter[len(m[0]):]
+		if strings.TrimSpace(filter) == "" {
+			return f, nil
+		}
+		and := _scimFilterAndRegex.FindString(filter)
+		if and == "" {
+			return nil, newSCIMError(http.StatusBadRequest, _scimTypeInvalidFilter, _scimFilterUnsupported)
+		}
+		filter = filter[len(and):]
+	}
+}
+
+// validate returns an invalidFilter error if the filter compares other than the given attributes
+func (f *scimFilter) validate(attributes ...string) error {
+	for _, t := range f.terms {
+		if !slices.Contains(attributes, t.attribute) {
+			return newSCIMError(http.StatusBadRequest, _scimTypeInvalidFilter, fmt.Sprintf("filtering by '%s' is not supported", t.attribute))
+		}
+	}
+	return nil
+}
+
+// lookup returns the compared value if the filter is a single 'eq' comparison of one of the given
+// attributes. These resources are read directly instead of filtering all resources. A nil filter
+// is never a lookup.
+func (f *scimFilter) lookup(attributes ...string) (string, string, bool) {
+	if f == nil || len(f.terms) != 1 || f.terms[0].operator != "eq" || !slices.Contains(attributes, f.terms[0].attribute) {
+		return "", "", false
+	}
+	return f.terms[0].attribute, f.terms[0].value, true
+}
+
+// matches returns true if one of the values of every compared attribute matches the comparison.
+// Ids are compared case sensitive, all other attributes case insensitive.
+func (f *scimFilter) matches(values func(attribute string) []string) bool {
+	for _, t := range f.terms {
+		match := false
+		for _, v := range values(t.attribute) {
+			want := t.value
+			if t.attribute != "id" {
+				v, want = strings.ToLower(v), strings.ToLower(want)
+			}
+			if (t.operator == "eq" && v == want) || (t.operator == "sw" && strings.HasPrefix(v, want)) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSCIMPage parses the 1-based startIndex and the count query parameters
+func parseSCIMPage(r *http.Request) (page, int, error) {
+	p := page{}
+	startIndex := 1
+	if v := r.URL.Query().Get("startIndex"); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return p, 0, newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid startIndex")
+		}
+		// values below 1 are interpreted as 1
+		startIndex = max(i, 1)
+	}
+	p.skip = startIndex - 1
+	if v := r.URL.Query().Get("count"); v != "" {
+		c, err := strconv.Atoi(v)
+		if err != nil {
+			return p, 0, newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid count")
+		}
+		// a count of 0 only returns the total number of results
+		p.top = max(c, 0)
+		if p.top == 0 {
+			p.top = -1
+		}
+	}
+	return p, startIndex, nil
+}
+
+// scimListResponse pages through the resources and returns the list response
+func scimListResponse[T any](resources []T, p page, startIndex int) *SCIMListResponse {
+	total := len(resources)
+	switch {
+	case p.top < 0:
+		resources = []T{}
+	case p.paged():
+		resources, _ = paginate(resources, p)
+	}
+	return &SCIMListResponse{
+		Schemas:      []string{_scimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// parseSCIMBool parses boolean values, which some identity providers send as strings
+func parseSCIMBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return false, newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid boolean value")
+	}
+	b, err := strconv.ParseBool(strings.ToLower(s))
+	if err != nil {
+		return false, newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid boolean value")
+	}
+	return b, nil
+}
+
+// parseSCIMString parses a string value of a patch operation
+func parseSCIMString(raw json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid string value")
+	}
+	return s, nil
+}
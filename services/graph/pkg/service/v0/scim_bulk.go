@@ -0,0 +1,192 @@
+package svc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// _scimBulkMaxPayloadSize is the maximum size of the body of a bulk request in bytes
+const _scimBulkMaxPayloadSize = 1 << 20
+
+// _scimBulkIDRegex matches the references to resources created by other operations of a bulk request
+var _scimBulkIDRegex = regexp.MustCompile(`bulkId:([\w-]+)`)
+
+// SCIMBulkOperation is a single operation of a SCIM bulk request or response.
+type SCIMBulkOperation struct {
+	Method   string          `json:"method"`
+	BulkID   string          `json:"bulkId,omitempty"`
+	Path     string          `json:"path,omitempty"`
+	Location string          `json:"location,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Status   string          `json:"status,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// scimBulkRequest is the body of a SCIM bulk request.
+type scimBulkRequest struct {
+	Schemas      []string             `json:"schemas"`
+	FailOnErrors int                  `json:"failOnErrors,omitempty"`
+	Operations   []*SCIMBulkOperation `json:"Operations"`
+}
+
+// scimBulkResponse is the body of a SCIM bulk response.
+type scimBulkResponse struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []*SCIMBulkOperation `json:"Operations"`
+}
+
+// SCIMBulk executes the operations of a SCIM bulk request in order. Operations can reference the
+// resources created by previous operations with 'bulkId:<bulkId>'. Processing stops once the
+// number of failed operations reaches 'failOnErrors'.
+func (g Graph) SCIMBulk(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, _scimBulkMaxPayloadSize+1))
+	if err != nil {
+		g.renderSCIMError(w, r, newSCIMError(http.StatusBadRequest, _scimTypeInvalidSyntax, err.Error()))
+		return
+	}
+	if len(body) > _scimBulkMaxPayloadSize {
+		g.renderSCIMError(w, r, newSCIMError(http.StatusRequestEntityTooLarge, _scimTypeTooMany,
+			fmt.Sprintf("the bulk request exceeds the maximum payload size of %d bytes", _scimBulkMaxPayloadSize)))
+		return
+	}
+	bulk := &scimBulkRequest{}
+	if err := json.Unmarshal(body, bulk); err != nil {
+		g.renderSCIMError(w, r, newSCIMError(http.StatusBadRequest, _scimTypeInvalidSyntax, fmt.Sprintf("invalid request body: %s", err.Error())))
+		return
+	}
+	if len(bulk.Operations) > g.config.SCIM.BulkMaxOperations {
+		g.renderSCIMError(w, r, newSCIMError(http.StatusRequestEntityTooLarge, _scimTypeTooMany,
+			fmt.Sprintf("the bulk request exceeds the maximum of %d operations", g.config.SCIM.BulkMaxOperations)))
+		return
+	}
+
+	// the paths of the operations are relative to the SCIM endpoint
+	prefix := strings.TrimSuffix(r.URL.Path, "/Bulk")
+
+	ids := map[string]string{}
+	results := make([]*SCIMBulkOperation, 0, len(bulk.Operations))
+	failed := 0
+	for _, op := range bulk.Operations {
+		if bulk.FailOnErrors > 0 && failed >= bulk.FailOnErrors {
+			break
+		}
+		result := g.executeSCIMBulkOperation(r, prefix, op, ids)
+		if status, _ := strconv.Atoi(result.Status); status >= http.StatusBadRequest {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	g.renderSCIM(w, r, http.StatusOK, &scimBulkResponse{
+		Schemas:    []string{_scimSchemaBulkResponse},
+		Operations: results,
+	})
+}
+
+// executeSCIMBulkOperation runs the operation through the SCIM router and records the id of
+// created resources for the following operations.
+func (g Graph) executeSCIMBulkOperation(r *http.Request, prefix string, op *SCIMBulkOperation, ids map[string]string) *SCIMBulkOperation {
+	result := &SCIMBulkOperation{
+		Method: op.Method,
+		BulkID: op.BulkID,
+	}
+	method := strings.ToUpper(op.Method)
+	if method == http.MethodPost && op.BulkID == "" {
+		return scimBulkError(result, newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "POST operations require a bulkId"))
+	}
+	if !strings.HasPrefix(op.Path, "/") || strings.HasPrefix(op.Path, "/Bulk") {
+		return scimBulkError(result, newSCIMError(http.StatusBadRequest, _scimTypeInvalidPath, "invalid path '"+op.Path+"'"))
+	}
+
+	opPath, err := resolveSCIMBulkIDs(op.Path, ids)
+	if err != nil {
+		return scimBulkError(result, err)
+	}
+	data, err := resolveSCIMBulkIDs(string(op.Data), ids)
+	if err != nil {
+		return scimBulkError(result, err)
+	}
+
+	// drop the routing context of the bulk request, the operation needs to be routed from scratch
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, nil)
+	sub, err := http.NewRequestWithContext(ctx, method, prefix+opPath, bytes.NewReader([]byte(data)))
+	if err != nil {
+		return scimBulkError(result, newSCIMError(http.StatusBadRequest, _scimTypeInvalidPath, err.Error()))
+	}
+	sub.RemoteAddr = r.RemoteAddr
+	sub.Header = r.Header.Clone()
+	sub.Header.Del("Content-Length")
+
+	rw := &batchResponseWriter{header: http.Header{}}
+	g.scim.ServeHTTP(rw, sub)
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	result.Status = strconv.Itoa(rw.status)
+
+	if rw.status >= http.StatusBadRequest {
+		result.Response = bytes.TrimSpace(rw.body.Bytes())
+		return result
+	}
+	if method == http.MethodDelete {
+		result.Location = g.scimLocation(strings.TrimPrefix(opPath, "/"))
+		return result
+	}
+
+	resource := struct {
+		ID   string    `json:"id"`
+		Meta *SCIMMeta `json:"meta"`
+	}{}
+	if err := json.Unmarshal(rw.body.Bytes(), &resource); err == nil {
+		if resource.Meta != nil {
+			result.Location = resource.Meta.Location
+		}
+		if op.BulkID != "" && resource.ID != "" {
+			ids[op.BulkID] = resource.ID
+		}
+	}
+	if result.Location == "" {
+		result.Location = g.scimLocation(strings.TrimPrefix(opPath, "/"))
+	}
+	return result
+}
+
+// resolveSCIMBulkIDs replaces the bulkId references with the ids of the created resources
+func resolveSCIMBulkIDs(s string, ids map[string]string) (string, error) {
+	var err error
+	resolved := _scimBulkIDRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		bulkID := _scimBulkIDRegex.FindStringSubmatch(ref)[1]
+		id, ok := ids[bulkID]
+		if !ok {
+			err = newSCIMError(http.StatusConflict, _scimTypeInvalidValue, "the bulkId '"+bulkID+"' does not reference a previously created resource")
+			return ref
+		}
+		return id
+	})
+	return resolved, err
+}
+
+func scimBulkError(result *SCIMBulkOperation, err error) *SCIMBulkOperation {
+	var se scimError
+	if !errors.As(err, &se) {
+		se = newSCIMError(http.StatusInternalServerError, "", err.Error())
+	}
+	result.Status = strconv.Itoa(se.status)
+	result.Response, _ = json.Marshal(&SCIMError{
+		Schemas:  []string{_scimSchemaError},
+		Status:   result.Status,
+		SCIMType: se.scimType,
+		Detail:   se.detail,
+	})
+	return result
+}
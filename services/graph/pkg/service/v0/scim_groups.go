@@ -0,0 +1,403 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/CiscoM31/godata"
+	"github.com/go-chi/chi/v5"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/identity"
+)
+
+// SCIMGroup is a group resource of the SCIM core schema.
+type SCIMGroup struct {
+	Schemas     []string         `json:"schemas"`
+	ID          string           `json:"id,omitempty"`
+	ExternalID  string           `json:"externalId,omitempty"`
+	DisplayName string           `json:"displayName"`
+	Members     []SCIMMultiValue `json:"members,omitempty"`
+	Meta        *SCIMMeta        `json:"meta,omitempty"`
+}
+
+// _scimMemberPathRegex matches the member filter of a patch path like 'members[value eq "id"]'
+var _scimMemberPathRegex = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"([^"]*)"\s*\]$`)
+
+// GetSCIMGroups lists the groups matching the SCIM filter. The members of the groups are not listed.
+func (g Graph) GetSCIMGroups(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filter, err := parseSCIMFilter(r)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	p, startIndex, err := parseSCIMPage(r)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	var groups []*libregraph.Group
+	if filter != nil {
+		err = filter.validate("displayname", "id")
+	}
+	if attribute, value, ok := filter.lookup("displayname", "id"); ok && err == nil {
+		var group *libregraph.Group
+		group, err = g.identityBackend.GetGroup(ctx, value, nil)
+		switch {
+		case errors.Is(err, identity.ErrNotFound) || isItemNotFound(err):
+			err = nil
+		case err != nil:
+		case attribute == "displayname" && strings.EqualFold(group.GetDisplayName(), value),
+			attribute == "id" && group.GetId() == value:
+			groups = append(groups, group)
+		}
+	} else if err == nil {
+		groups, err = g.identityBackend.GetGroups(ctx, &godata.GoDataRequest{})
+		if filter != nil {
+			groups = slices.DeleteFunc(groups, func(grp *libregraph.Group) bool {
+				return !filter.matches(func(attribute string) []string {
+					if attribute == "id" {
+						return []string{grp.GetId()}
+					}
+					return []string{grp.GetDisplayName()}
+				})
+			})
+		}
+	}
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	resources := make([]*SCIMGroup, 0, len(groups))
+	for _, grp := range groups {
+		resources = append(resources, g.toSCIMGroup(grp, false))
+	}
+	g.renderSCIM(w, r, http.StatusOK, scimListResponse(resources, p, startIndex))
+}
+
+// PostSCIMGroup creates a group and adds the members to it
+func (g Graph) PostSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	sg := &SCIMGroup{}
+	if err := decodeSCIMBody(r, sg); err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	if !isValidGroupName(sg.DisplayName) {
+		g.renderSCIMError(w, r, newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid displayName"))
+		return
+	}
+
+	group := libregraph.NewGroup()
+	group.SetDisplayName(sg.DisplayName)
+	created, err := g.identityBackend.CreateGroup(ctx, *group)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	e := events.GroupCreated{GroupID: created.GetId()}
+	if currentUser, ok := revactx.ContextGetUser(ctx); ok {
+		e.Executant = currentUser.GetId()
+	}
+	g.publishEvent(ctx, e)
+
+	if err := g.addSCIMGroupMembers(ctx, created.GetId(), scimValues(sg.Members)); err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	created, err = g.identityBackend.GetGroup(ctx, created.GetId(), url.Values{"$expand": {"members"}})
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	g.renderSCIM(w, r, http.StatusCreated, g.toSCIMGroup(created, true))
+}
+
+// GetSCIMGroup returns a single group including its members
+func (g Graph) GetSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := g.getSCIMGroupParam(r)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	g.renderSCIM(w, r, http.StatusOK, g.toSCIMGroup(group, true))
+}
+
+// PutSCIMGroup replaces the name and the members of a group
+func (g Graph) PutSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	group, err := g.getSCIMGroupParam(r)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	sg := &SCIMGroup{}
+	if err := decodeSCIMBody(r, sg); err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	if sg.DisplayName != "" {
+		if err := g.renameSCIMGroup(ctx, group, sg.DisplayName); err != nil {
+			g.renderSCIMError(w, r, err)
+			return
+		}
+	}
+	if err := g.replaceSCIMGroupMembers(ctx, group, scimValues(sg.Members)); err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	group, err = g.identityBackend.GetGroup(ctx, group.GetId(), url.Values{"$expand": {"members"}})
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	g.renderSCIM(w, r, http.StatusOK, g.toSCIMGroup(group, true))
+}
+
+// PatchSCIMGroup applies the operations of a SCIM PATCH request to a group
+func (g Graph) PatchSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	group, err := g.getSCIMGroupParam(r)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	patch := &scimPatchRequest{}
+	if err := decodeSCIMBody(r, patch); err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	for _, op := range patch.Operations {
+		if err := g.applySCIMGroupOperation(ctx, group, strings.ToLower(op.Op), op.Path, op.Value); err != nil {
+			g.renderSCIMError(w, r, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteSCIMGroup deletes a group
+func (g Graph) DeleteSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	group, err := g.getSCIMGroupParam(r)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	if err := g.identityBackend.DeleteGroup(ctx, group.GetId()); err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	e := events.GroupDeleted{GroupID: group.GetId()}
+	if currentUser, ok := revactx.ContextGetUser(ctx); ok {
+		e.Executant = currentUser.GetId()
+	}
+	g.publishEvent(ctx, e)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applySCIMGroupOperation applies a single PATCH operation to the group. Attributes other than
+// the display name and the members are ignored.
+func (g Graph) applySCIMGroupOperation(ctx context.Context, group *libregraph.Group, op, attrPath string, value json.RawMessage) error {
+	lowerPath := strings.ToLower(attrPath)
+	switch op {
+	case "add", "replace":
+		if attrPath == "" {
+			// the value contains the attributes to change
+			attributes := map[string]json.RawMessage{}
+			if err := json.Unmarshal(value, &attributes); err != nil {
+				return newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "the value of a patch operation without path must be an object")
+			}
+			for attr, v := range attributes {
+				if err := g.applySCIMGroupOperation(ctx, group, op, attr, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		switch lowerPath {
+		case "displayname":
+			name, err := parseSCIMString(value)
+			if err != nil {
+				return err
+			}
+			return g.renameSCIMGroup(ctx, group, name)
+		case "members":
+			members := []SCIMMultiValue{}
+			if err := json.Unmarshal(value, &members); err != nil {
+				return newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid members")
+			}
+			if op == "replace" {
+				return g.replaceSCIMGroupMembers(ctx, group, scimValues(members))
+			}
+			return g.addSCIMGroupMembers(ctx, group.GetId(), scimValues(members))
+		}
+	case "remove":
+		switch {
+		case lowerPath == "members" && len(value) == 0:
+			// without a value all members are removed
+			return g.replaceSCIMGroupMembers(ctx, group, nil)
+		case lowerPath == "members":
+			members := []SCIMMultiValue{}
+			if err := json.Unmarshal(value, &members); err != nil {
+				return newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid members")
+			}
+			return g.removeSCIMGroupMembers(ctx, group.GetId(), scimValues(members))
+		case _scimMemberPathRegex.MatchString(attrPath):
+			memberID := _scimMemberPathRegex.FindStringSubmatch(attrPath)[1]
+			return g.removeSCIMGroupMembers(ctx, group.GetId(), []string{memberID})
+		case attrPath == "":
+			return newSCIMError(http.StatusBadRequest, _scimTypeInvalidPath, "remove operations require a path")
+		}
+	default:
+		return newSCIMError(http.StatusBadRequest, _scimTypeInvalidSyntax, "unknown patch operation '"+op+"'")
+	}
+	return nil
+}
+
+// renameSCIMGroup changes the name of the group
+func (g Graph) renameSCIMGroup(ctx context.Context, group *libregraph.Group, name string) error {
+	if name == group.GetDisplayName() {
+		return nil
+	}
+	if !isValidGroupName(name) {
+		return newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid displayName")
+	}
+	if err := g.identityBackend.UpdateGroupName(ctx, group.GetId(), name); err != nil {
+		return err
+	}
+	group.SetDisplayName(name)
+	return nil
+}
+
+// replaceSCIMGroupMembers changes the members of the group to the given members
+func (g Graph) replaceSCIMGroupMembers(ctx context.Context, group *libregraph.Group, memberIDs []string) error {
+	current := map[string]struct{}{}
+	for _, m := range group.GetMembers() {
+		current[m.GetId()] = struct{}{}
+	}
+	wanted := map[string]struct{}{}
+	var added []string
+	for _, id := range memberIDs {
+		wanted[id] = struct{}{}
+		if _, ok := current[id]; !ok {
+			added = append(added, id)
+		}
+	}
+	var removed []string
+	for id := range current {
+		if _, ok := wanted[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+
+	if err := g.addSCIMGroupMembers(ctx, group.GetId(), added); err != nil {
+		return err
+	}
+	return g.removeSCIMGroupMembers(ctx, group.GetId(), removed)
+}
+
+// addSCIMGroupMembers adds the users to the group
+func (g Graph) addSCIMGroupMembers(ctx context.Context, groupID string, memberIDs []string) error {
+	if len(memberIDs) == 0 {
+		return nil
+	}
+	for _, id := range memberIDs {
+		if _, err := g.identityBackend.GetUser(ctx, id, &godata.GoDataRequest{}); err != nil {
+			return newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "member '"+id+"' does not exist")
+		}
+	}
+	if err := g.identityBackend.AddMembersToGroup(ctx, groupID, memberIDs); err != nil {
+		return err
+	}
+
+	for _, id := range memberIDs {
+		e := events.GroupMemberAdded{GroupID: groupID, UserID: id}
+		if currentUser, ok := revactx.ContextGetUser(ctx); ok {
+			e.Executant = currentUser.GetId()
+		}
+		g.publishEvent(ctx, e)
+	}
+	return nil
+}
+
+// removeSCIMGroupMembers removes the users from the group
+func (g Graph) removeSCIMGroupMembers(ctx context.Context, groupID string, memberIDs []string) error {
+	for _, id := range memberIDs {
+		if err := g.identityBackend.RemoveMemberFromGroup(ctx, groupID, id); err != nil {
+			return err
+		}
+		e := events.GroupMemberRemoved{GroupID: groupID, UserID: id}
+		if currentUser, ok := revactx.ContextGetUser(ctx); ok {
+			e.Executant = currentUser.GetId()
+		}
+		g.publishEvent(ctx, e)
+	}
+	return nil
+}
+
+// getSCIMGroupParam returns the group referenced by the groupID url parameter including its members
+func (g Graph) getSCIMGroupParam(r *http.Request) (*libregraph.Group, error) {
+	groupID, err := url.PathUnescape(chi.URLParam(r, "groupID"))
+	if err != nil || groupID == "" {
+		return nil, newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid group id")
+	}
+	group, err := g.identityBackend.GetGroup(r.Context(), groupID, url.Values{"$expand": {"members"}})
+	if err != nil {
+		return nil, err
+	}
+	if group.GetId() != groupID {
+		return nil, newSCIMError(http.StatusNotFound, "", "group not found")
+	}
+	return group, nil
+}
+
+// toSCIMGroup converts a libregraph group to a SCIM group
+func (g Graph) toSCIMGroup(grp *libregraph.Group, withMembers bool) *SCIMGroup {
+	sg := &SCIMGroup{
+		Schemas:     []string{_scimSchemaGroup},
+		ID:          grp.GetId(),
+		DisplayName: grp.GetDisplayName(),
+		Meta: &SCIMMeta{
+			ResourceType: "Group",
+			Location:     g.scimLocation("Groups", grp.GetId()),
+		},
+	}
+	if withMembers {
+		for _, m := range grp.GetMembers() {
+			sg.Members = append(sg.Members, SCIMMultiValue{
+				Value:   m.GetId(),
+				Display: m.GetDisplayName(),
+				Ref:     g.scimLocation("Users", m.GetId()),
+			})
+		}
+	}
+	return sg
+}
+
+// scimValues returns the values of a multi-valued attribute
+func scimValues(values []SCIMMultiValue) []string {
+	ids := make([]string, 0, len(values))
+	for _, v := range values {
+		if v.Value != "" {
+			ids = append(ids, v.Value)
+		}
+	}
+	return ids
+}
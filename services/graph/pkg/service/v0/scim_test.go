@@ -0,0 +1,332 @@
+package svc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/opencloud-eu/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+
+	"github.com/opencloud-eu/opencloud/pkg/shared"
+	settings "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/settings/v0"
+	"github.com/opencloud-eu/opencloud/services/graph/mocks"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config/defaults"
+	identitymocks "github.com/opencloud-eu/opencloud/services/graph/pkg/identity/mocks"
+	service "github.com/opencloud-eu/opencloud/services/graph/pkg/service/v0"
+)
+
+var _ = Describe("SCIM", func() {
+	var (
+		svc             service.Service
+		cfg             *config.Config
+		gatewayClient   *cs3mocks.GatewayAPIClient
+		eventsPublisher mocks.Publisher
+		roleService     *mocks.RoleService
+		identityBackend *identitymocks.Backend
+
+		rr *httptest.ResponseRecorder
+	)
+
+	newRequest := func(method, target, body string) *http.Request {
+		r := httptest.NewRequest(method, "/graph/scim/v2"+target, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer scim-token")
+		r.Header.Set("Content-Type", "application/scim+json")
+		return r
+	}
+
+	BeforeEach(func() {
+		eventsPublisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		pool.RemoveSelector("GatewaySelector" + "eu.opencloud.api.gateway")
+		gatewayClient = &cs3mocks.GatewayAPIClient{}
+		gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+			"GatewaySelector",
+			"eu.opencloud.api.gateway",
+			func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+				return gatewayClient
+			},
+		)
+		gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{
+			Status: status.NewOK(context.Background()),
+			Token:  "service-token",
+		}, nil)
+
+		identityBackend = &identitymocks.Backend{}
+		roleService = &mocks.RoleService{}
+		rr = httptest.NewRecorder()
+
+		cfg = defaults.FullDefaultConfig()
+		cfg.Identity.LDAP.CACert = "" // skip the startup checks, we don't use LDAP at all in this tests
+		cfg.TokenManager.JWTSecret = "loremipsum"
+		cfg.Commons = &shared.Commons{OpenCloudURL: "https://cloud.example.com"}
+		cfg.GRPCClientTLS = &shared.GRPCClientTLS{}
+		cfg.ServiceAccount.ServiceAccountID = "service-account"
+		cfg.ServiceAccount.ServiceAccountSecret = "secret"
+		cfg.SCIM.Enabled = true
+		cfg.SCIM.Token = "scim-token"
+
+		var err error
+		svc, err = service.NewService(
+			service.Config(cfg),
+			service.WithGatewaySelector(gatewaySelector),
+			service.EventsPublisher(&eventsPublisher),
+			service.WithIdentityBackend(identityBackend),
+			service.WithRoleService(roleService),
+		)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects all requests when no token is configured", func() {
+		cfg.SCIM.Token = ""
+		var err error
+		svc, err = service.NewService(
+			service.Config(cfg),
+			service.WithIdentityBackend(identityBackend),
+			service.WithRoleService(roleService),
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, token := range []string{"", "Bearer "} {
+			r := newRequest(http.MethodGet, "/Users", "")
+			r.Header.Set("Authorization", token)
+			rr = httptest.NewRecorder()
+			svc.ServeHTTP(rr, r)
+
+			Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		}
+		identityBackend.AssertNotCalled(GinkgoT(), "GetUsers", mock.Anything, mock.Anything)
+	})
+
+	It("rejects requests without the token", func() {
+		r := newRequest(http.MethodGet, "/Users", "")
+		r.Header.Del("Authorization")
+		svc.ServeHTTP(rr, r)
+
+		Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		identityBackend.AssertNotCalled(GinkgoT(), "GetUsers", mock.Anything, mock.Anything)
+	})
+
+	Describe("Users", func() {
+		It("creates a user", func() {
+			roleService.On("AssignRoleToUser", mock.Anything, mock.Anything).Return(&settings.AssignRoleToUserResponse{}, nil)
+			identityBackend.On("CreateUser", mock.Anything, mock.MatchedBy(func(u libregraph.User) bool {
+				return u.GetOnPremisesSamAccountName() == "alan" && u.GetMail() == "alan@example.com" && u.GetDisplayName() == "Alan Turing"
+			})).Return(func(ctx context.Context, u libregraph.User) *libregraph.User {
+				u.SetId("alan-id")
+				return &u
+			}, nil)
+
+			svc.ServeHTTP(rr, newRequest(http.MethodPost, "/Users", `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+				"userName": "alan",
+				"displayName": "Alan Turing",
+				"name": {"givenName": "Alan", "familyName": "Turing"},
+				"emails": [{"value": "alan@example.com", "primary": true}],
+				"externalId": "ignored"
+			}`))
+
+			Expect(rr.Code).To(Equal(http.StatusCreated))
+			Expect(rr.Header().Get("Content-Type")).To(Equal("application/scim+json"))
+			user := service.SCIMUser{}
+			Expect(json.Unmarshal(rr.Body.Bytes(), &user)).To(Succeed())
+			Expect(user.ID).To(Equal("alan-id"))
+			Expect(user.UserName).To(Equal("alan"))
+			Expect(user.Meta.Location).To(Equal("https://cloud.example.com/graph/scim/v2/Users/alan-id"))
+			roleService.AssertNumberOfCalls(GinkgoT(), "AssignRoleToUser", 1)
+		})
+
+		It("filters users by userName", func() {
+			identityBackend.On("GetUser", mock.Anything, "alan", mock.Anything).Return(&libregraph.User{
+				Id:                       libregraph.PtrString("alan-id"),
+				OnPremisesSamAccountName: "alan",
+				DisplayName:              "Alan Turing",
+			}, nil)
+
+			svc.ServeHTTP(rr, newRequest(http.MethodGet, "/Users?filter="+url.QueryEscape(`userName eq "alan"`), ""))
+
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			res := struct {
+				TotalResults int
+				Resources    []service.SCIMUser
+			}{}
+			Expect(json.Unmarshal(rr.Body.Bytes(), &res)).To(Succeed())
+			Expect(res.TotalResults).To(Equal(1))
+			Expect(res.Resources[0].ID).To(Equal("alan-id"))
+		})
+
+		It("filters users with 'sw' and 'and'", func() {
+			identityBackend.On("GetUsers", mock.Anything, mock.Anything).Return([]*libregraph.User{
+				{Id: libregraph.PtrString("alan-id"), OnPremisesSamAccountName: "alan", Mail: libregraph.PtrString("alan@example.com")},
+				{Id: libregraph.PtrString("albert-id"), OnPremisesSamAccountName: "albert", Mail: libregraph.PtrString("albert@example.org")},
+				{Id: libregraph.PtrString("grace-id"), OnPremisesSamAccountName: "grace", Mail: libregraph.PtrString("grace@example.com")},
+			}, nil)
+
+			svc.ServeHTTP(rr, newRequest(http.MethodGet, "/Users?filter="+url.QueryEscape(`userName sw "AL" and emails.value eq "alan@example.com"`), ""))
+
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			res := struct {
+				TotalResults int
+				Resources    []service.SCIMUser
+			}{}
+			Expect(json.Unmarshal(rr.Body.Bytes(), &res)).To(Succeed())
+			Expect(res.TotalResults).To(Equal(1))
+			Expect(res.Resources[0].ID).To(Equal("alan-id"))
+			identityBackend.AssertNotCalled(GinkgoT(), "GetUser", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		DescribeTable("rejects unsupported filters",
+			func(filter string) {
+				svc.ServeHTTP(rr, newRequest(http.MethodGet, "/Users?filter="+url.QueryEscape(filter), ""))
+
+				Expect(rr.Code).To(Equal(http.StatusBadRequest))
+				scimErr := service.SCIMError{}
+				Expect(json.Unmarshal(rr.Body.Bytes(), &scimErr)).To(Succeed())
+				Expect(scimErr.SCIMType).To(Equal("invalidFilter"))
+				identityBackend.AssertNotCalled(GinkgoT(), "GetUsers", mock.Anything, mock.Anything)
+			},
+			Entry("unsupported operator", `userName co "x"`),
+			Entry("or", `userName eq "alan" or userName eq "grace"`),
+			Entry("unsupported attribute", `title eq "x"`),
+			Entry("unsupported attribute in and", `userName sw "a" and title eq "x"`),
+			Entry("dangling and", `userName sw "a" and`),
+			Entry("grouping", `(userName eq "alan")`),
+		)
+
+		It("rejects unsupported filters", func() {
+			svc.ServeHTTP(rr, newRequest(http.MethodGet, "/Users?filter="+url.QueryEscape(`title co "x"`), ""))
+
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+			scimErr := service.SCIMError{}
+			Expect(json.Unmarshal(rr.Body.Bytes(), &scimErr)).To(Succeed())
+			Expect(scimErr.SCIMType).To(Equal("invalidFilter"))
+		})
+
+		It("disables deprovisioned users", func() {
+			identityBackend.On("GetUser", mock.Anything, "alan-id", mock.Anything).Return(&libregraph.User{
+				Id:                       libregraph.PtrString("alan-id"),
+				OnPremisesSamAccountName: "alan",
+				AccountEnabled:           libregraph.PtrBool(true),
+			}, nil)
+			identityBackend.On("UpdateUser", mock.Anything, "alan-id", mock.MatchedBy(func(u libregraph.UserUpdate) bool {
+				enabled, ok := u.GetAccountEnabledOk()
+				return ok && !*enabled
+			})).Return(&libregraph.User{
+				Id:                       libregraph.PtrString("alan-id"),
+				OnPremisesSamAccountName: "alan",
+				AccountEnabled:           libregraph.PtrBool(false),
+			}, nil)
+
+			svc.ServeHTTP(rr, newRequest(http.MethodPatch, "/Users/alan-id", `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+				"Operations": [{"op": "Replace", "path": "active", "value": "False"}]
+			}`))
+
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			user := service.SCIMUser{}
+			Expect(json.Unmarshal(rr.Body.Bytes(), &user)).To(Succeed())
+			Expect(*user.Active).To(BeFalse())
+			identityBackend.AssertNumberOfCalls(GinkgoT(), "UpdateUser", 1)
+		})
+	})
+
+	Describe("Groups", func() {
+		It("adds members to a group", func() {
+			identityBackend.On("GetGroup", mock.Anything, "group-id", mock.Anything).Return(&libregraph.Group{
+				Id:          libregraph.PtrString("group-id"),
+				DisplayName: libregraph.PtrString("physicists"),
+			}, nil)
+			identityBackend.On("GetUser", mock.Anything, "alan-id", mock.Anything).Return(&libregraph.User{
+				Id: libregraph.PtrString("alan-id"),
+			}, nil)
+			identityBackend.On("AddMembersToGroup", mock.Anything, "group-id", []string{"alan-id"}).Return(nil)
+
+			svc.ServeHTTP(rr, newRequest(http.MethodPatch, "/Groups/group-id", `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+				"Operations": [{"op": "add", "path": "members", "value": [{"value": "alan-id"}]}]
+			}`))
+
+			Expect(rr.Code).To(Equal(http.StatusNoContent))
+			identityBackend.AssertNumberOfCalls(GinkgoT(), "AddMembersToGroup", 1)
+		})
+
+		It("removes a member selected by a filter", func() {
+			identityBackend.On("GetGroup", mock.Anything, "group-id", mock.Anything).Return(&libregraph.Group{
+				Id:          libregraph.PtrString("group-id"),
+				DisplayName: libregraph.PtrString("physicists"),
+			}, nil)
+			identityBackend.On("RemoveMemberFromGroup", mock.Anything, "group-id", "alan-id").Return(nil)
+
+			svc.ServeHTTP(rr, newRequest(http.MethodPatch, "/Groups/group-id", `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+				"Operations": [{"op": "remove", "path": "members[value eq \"alan-id\"]"}]
+			}`))
+
+			Expect(rr.Code).To(Equal(http.StatusNoContent))
+			identityBackend.AssertNumberOfCalls(GinkgoT(), "RemoveMemberFromGroup", 1)
+		})
+	})
+
+	Describe("Bulk", func() {
+		It("resolves the bulkIds of created resources", func() {
+			roleService.On("AssignRoleToUser", mock.Anything, mock.Anything).Return(&settings.AssignRoleToUserResponse{}, nil)
+			identityBackend.On("CreateUser", mock.Anything, mock.Anything).Return(func(ctx context.Context, u libregraph.User) *libregraph.User {
+				u.SetId("alan-id")
+				return &u
+			}, nil)
+			identityBackend.On("CreateGroup", mock.Anything, mock.Anything).Return(func(ctx context.Context, g libregraph.Group) *libregraph.Group {
+				g.SetId("group-id")
+				return &g
+			}, nil)
+			identityBackend.On("GetUser", mock.Anything, "alan-id", mock.Anything).Return(&libregraph.User{
+				Id: libregraph.PtrString("alan-id"),
+			}, nil)
+			identityBackend.On("AddMembersToGroup", mock.Anything, "group-id", []string{"alan-id"}).Return(nil)
+			identityBackend.On("GetGroup", mock.Anything, "group-id", mock.Anything).Return(&libregraph.Group{
+				Id:          libregraph.PtrString("group-id"),
+				DisplayName: libregraph.PtrString("physicists"),
+				Members:     []libregraph.User{{Id: libregraph.PtrString("alan-id")}},
+			}, nil)
+
+			body := `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+				"Operations": [
+					{"method": "POST", "bulkId": "alan", "path": "/Users", "data": {"userName": "alan"}},
+					{"method": "POST", "bulkId": "physicists", "path": "/Groups", "data": {"displayName": "physicists", "members": [{"value": "bulkId:alan"}]}},
+					{"method": "POST", "bulkId": "unknown", "path": "/Groups", "data": {"displayName": "others", "members": [{"value": "bulkId:missing"}]}}
+				]
+			}`
+			svc.ServeHTTP(rr, newRequest(http.MethodPost, "/Bulk", body))
+
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			res := struct {
+				Operations []service.SCIMBulkOperation
+			}{}
+			Expect(json.Unmarshal(rr.Body.Bytes(), &res)).To(Succeed())
+			Expect(res.Operations).To(HaveLen(3))
+			Expect(res.Operations[0].Status).To(Equal("201"))
+			Expect(res.Operations[1].Status).To(Equal("201"))
+			Expect(res.Operations[1].Location).To(Equal("https://cloud.example.com/graph/scim/v2/Groups/group-id"))
+			Expect(res.Operations[2].Status).To(Equal("409"))
+			identityBackend.AssertNumberOfCalls(GinkgoT(), "AddMembersToGroup", 1)
+		})
+
+		It("rejects too many operations", func() {
+			cfg.SCIM.BulkMaxOperations = 0
+			svc.ServeHTTP(rr, newRequest(http.MethodPost, "/Bulk", `{"Operations": [{"method": "DELETE", "path": "/Users/x"}]}`))
+
+			Expect(rr.Code).To(Equal(http.StatusRequestEntityTooLarge))
+		})
+	})
+})
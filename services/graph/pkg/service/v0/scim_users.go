@@ -0,0 +1,490 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/CiscoM31/godata"
+	"github.com/go-chi/chi/v5"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/identity"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/userstate"
+)
+
+// SCIMUser is a user resource of the SCIM core schema.
+type SCIMUser struct {
+	Schemas           []string         `json:"schemas"`
+	ID                string           `json:"id,omitempty"`
+	ExternalID        string           `json:"externalId,omitempty"`
+	UserName          string           `json:"userName"`
+	Name              *SCIMName        `json:"name,omitempty"`
+	DisplayName       string           `json:"displayName,omitempty"`
+	Emails            []SCIMMultiValue `json:"emails,omitempty"`
+	PreferredLanguage string           `json:"preferredLanguage,omitempty"`
+	Active            *bool            `json:"active,omitempty"`
+	// Password is only used to set the password, it is never returned.
+	Password string    `json:"password,omitempty"`
+	Meta     *SCIMMeta `json:"meta,omitempty"`
+}
+
+// SCIMName is the name of a SCIM user.
+type SCIMName struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMMultiValue is a value of a multi-valued SCIM attribute like the emails of a user or the members of a group.
+type SCIMMultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+var _scimEmailsPathRegex = regexp.MustCompile(`(?i)^emails(\[.*\])?(\.value)?$`)
+
+// GetSCIMUsers lists the users matching the SCIM filter
+func (g Graph) GetSCIMUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filter, err := parseSCIMFilter(r)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	p, startIndex, err := parseSCIMPage(r)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	var users []*libregraph.User
+	if filter != nil {
+		err = filter.validate("username", "id", "displayname", "emails", "emails.value")
+	}
+	if attribute, value, ok := filter.lookup("username", "id"); ok && err == nil {
+		var user *libregraph.User
+		user, err = g.identityBackend.GetUser(ctx, value, &godata.GoDataRequest{})
+		switch {
+		case errors.Is(err, identity.ErrNotFound) || isItemNotFound(err):
+			err = nil
+		case err != nil:
+		case attribute == "username" && strings.EqualFold(user.GetOnPremisesSamAccountName(), value),
+			attribute == "id" && user.GetId() == value:
+			users = append(users, user)
+		}
+	} else if err == nil {
+		users, err = g.identityBackend.GetUsers(ctx, &godata.GoDataRequest{})
+		if filter != nil {
+			users = slices.DeleteFunc(users, func(u *libregraph.User) bool {
+				return !filter.matches(func(attribute string) []string {
+					switch attribute {
+					case "username":
+						return []string{u.GetOnPremisesSamAccountName()}
+					case "id":
+						return []string{u.GetId()}
+					case "displayname":
+						return []string{u.GetDisplayName()}
+					default:
+						return []string{u.GetMail()}
+					}
+				})
+			})
+		}
+	}
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	resources := make([]*SCIMUser, 0, len(users))
+	for _, u := range users {
+		resources = append(resources, g.toSCIMUser(u))
+	}
+	g.renderSCIM(w, r, http.StatusOK, scimListResponse(resources, p, startIndex))
+}
+
+// PostSCIMUser creates a user
+func (g Graph) PostSCIMUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	su := &SCIMUser{}
+	if err := decodeSCIMBody(r, su); err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	u := libregraph.NewUser(su.DisplayName, su.UserName)
+	if su.DisplayName == "" {
+		// the display name is optional in SCIM
+		u.SetDisplayName(su.UserName)
+	}
+	if !g.isValidUsername(su.UserName) {
+		g.renderSCIMError(w, r, newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid userName"))
+		return
+	}
+	if su.Name != nil {
+		if su.Name.GivenName != "" {
+			u.SetGivenName(su.Name.GivenName)
+		}
+		if su.Name.FamilyName != "" {
+			u.SetSurname(su.Name.FamilyName)
+		}
+	}
+	if mail := primarySCIMValue(su.Emails); mail != "" {
+		if !isValidEmail(mail) {
+			g.renderSCIMError(w, r, newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid email address"))
+			return
+		}
+		u.SetMail(mail)
+	}
+	if su.Password != "" {
+		u.SetPasswordProfile(libregraph.PasswordProfile{Password: libregraph.PtrString(su.Password)})
+	}
+	// only disabled accounts need to be marked, enabling is not supported by all disable mechanisms
+	if su.Active != nil && !*su.Active {
+		u.SetAccountEnabled(false)
+	}
+	u.SetUserType(identity.UserTypeMember)
+
+	created, err := g.identityBackend.CreateUser(ctx, *u)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	if err := g.assignDefaultUserRole(ctx, created.GetId()); err != nil {
+		g.renderSCIMError(w, r, newSCIMError(http.StatusInternalServerError, "", "role assignment failed"))
+		return
+	}
+
+	e := events.UserCreated{UserID: created.GetId()}
+	if currentUser, ok := revactx.ContextGetUser(ctx); ok {
+		e.Executant = currentUser.GetId()
+	}
+	g.publishEvent(ctx, e)
+
+	g.renderSCIM(w, r, http.StatusCreated, g.toSCIMUser(created))
+}
+
+// GetSCIMUser returns a single user
+func (g Graph) GetSCIMUser(w http.ResponseWriter, r *http.Request) {
+	user, err := g.getSCIMUserParam(r)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	g.renderSCIM(w, r, http.StatusOK, g.toSCIMUser(user))
+}
+
+// PutSCIMUser replaces the attributes of a user
+func (g Graph) PutSCIMUser(w http.ResponseWriter, r *http.Request) {
+	user, err := g.getSCIMUserParam(r)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	su := &SCIMUser{}
+	if err := decodeSCIMBody(r, su); err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	changes := libregraph.NewUserUpdate()
+	if su.UserName != "" {
+		changes.SetOnPremisesSamAccountName(su.UserName)
+	}
+	if su.DisplayName != "" {
+		changes.SetDisplayName(su.DisplayName)
+	}
+	if su.Name != nil {
+		changes.SetGivenName(su.Name.GivenName)
+		changes.SetSurname(su.Name.FamilyName)
+	}
+	if mail := primarySCIMValue(su.Emails); mail != "" {
+		changes.SetMail(mail)
+	}
+	if su.Active != nil {
+		changes.SetAccountEnabled(*su.Active)
+	}
+	if su.Password != "" {
+		changes.SetPasswordProfile(libregraph.PasswordProfile{Password: libregraph.PtrString(su.Password)})
+	}
+
+	g.updateSCIMUser(w, r, user, changes)
+}
+
+// PatchSCIMUser applies the operations of a SCIM PATCH request to a user. Identity providers
+// deprovision users by replacing the 'active' attribute.
+func (g Graph) PatchSCIMUser(w http.ResponseWriter, r *http.Request) {
+	user, err := g.getSCIMUserParam(r)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	patch := &scimPatchRequest{}
+	if err := decodeSCIMBody(r, patch); err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	changes := libregraph.NewUserUpdate()
+	for _, op := range patch.Operations {
+		if err := applySCIMUserOperation(changes, strings.ToLower(op.Op), op.Path, op.Value); err != nil {
+			g.renderSCIMError(w, r, err)
+			return
+		}
+	}
+
+	g.updateSCIMUser(w, r, user, changes)
+}
+
+// DeleteSCIMUser deletes a user. Like deleting users with the graph api, the user is only soft
+// deleted if a retention time for soft deleted users is configured.
+func (g Graph) DeleteSCIMUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, err := g.getSCIMUserParam(r)
+	if err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+
+	us, err := g.getUserStateFromNatsKeyValue(ctx, user.GetId())
+	if err != nil {
+		us = userstate.UserState{
+			UserId: user.GetId(),
+			State:  userstate.UserStateUnspecified,
+		}
+	}
+	switch {
+	case us.State == userstate.UserStateHardDeleted:
+		g.renderSCIMError(w, r, newSCIMError(http.StatusNotFound, "", "user not found"))
+		return
+	case us.State == userstate.UserStateUnspecified && user.GetAccountEnabled():
+		us.State = userstate.UserStateEnabled
+	case us.State == userstate.UserStateUnspecified:
+		us.State = userstate.UserStateSoftDeleted
+	}
+
+	currentUser, _ := revactx.ContextGetUser(ctx)
+	if err := g.deleteUser(ctx, user, us, false, currentUser.GetId()); err != nil {
+		g.renderSCIMError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateSCIMUser applies the changes to the user and renders the updated user
+func (g Graph) updateSCIMUser(w http.ResponseWriter, r *http.Request, user *libregraph.User, changes *libregraph.UserUpdate) {
+	ctx := r.Context()
+	if name, ok := changes.GetOnPremisesSamAccountNameOk(); ok && !g.isValidUsername(*name) {
+		g.renderSCIMError(w, r, newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid userName"))
+		return
+	}
+	if mail, ok := changes.GetMailOk(); ok && !isValidEmail(*mail) {
+		g.renderSCIMError(w, r, newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid email address"))
+		return
+	}
+
+	var features []events.UserFeature
+	if mail, ok := changes.GetMailOk(); ok && *mail != user.GetMail() {
+		features = append(features, events.UserFeature{Name: "email", Value: *mail, OldValue: user.Mail})
+	}
+	if name, ok := changes.GetDisplayNameOk(); ok && *name != user.GetDisplayName() {
+		features = append(features, events.UserFeature{Name: "displayname", Value: *name, OldValue: &user.DisplayName})
+	}
+	if enabled, ok := changes.GetAccountEnabledOk(); ok && *enabled != user.GetAccountEnabled() {
+		old := strconv.FormatBool(user.GetAccountEnabled())
+		features = append(features, events.UserFeature{Name: "accountEnabled", Value: strconv.FormatBool(*enabled), OldValue: &old})
+	}
+	if changes.HasPasswordProfile() {
+		features = append(features, events.UserFeature{Name: "passwordChanged"})
+	}
+
+	updated := user
+	if m, _ := changes.ToMap(); len(m) > 0 {
+		var err error
+		if updated, err = g.identityBackend.UpdateUser(ctx, user.GetId(), *changes); err != nil {
+			g.renderSCIMError(w, r, err)
+			return
+		}
+	}
+
+	if len(features) > 0 {
+		e := events.UserFeatureChanged{
+			UserID:    updated.GetId(),
+			Features:  features,
+			Timestamp: utils.TSNow(),
+		}
+		if currentUser, ok := revactx.ContextGetUser(ctx); ok {
+			e.Executant = currentUser.GetId()
+		}
+		g.publishEvent(ctx, e)
+	}
+
+	g.renderSCIM(w, r, http.StatusOK, g.toSCIMUser(updated))
+}
+
+// getSCIMUserParam returns the user referenced by the userID url parameter
+func (g Graph) getSCIMUserParam(r *http.Request) (*libregraph.User, error) {
+	userID, err := url.PathUnescape(chi.URLParam(r, "userID"))
+	if err != nil || userID == "" {
+		return nil, newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid user id")
+	}
+	user, err := g.identityBackend.GetUser(r.Context(), userID, &godata.GoDataRequest{})
+	if err != nil {
+		return nil, err
+	}
+	// the SCIM id is the id of the user, usernames are only accepted by the backend
+	if user.GetId() != userID {
+		return nil, newSCIMError(http.StatusNotFound, "", "user not found")
+	}
+	return user, nil
+}
+
+// applySCIMUserOperation applies a single PATCH operation to the user changes.
+// Attributes that are not supported by OpenCloud are ignored.
+func applySCIMUserOperation(changes *libregraph.UserUpdate, op, attrPath string, value json.RawMessage) error {
+	switch op {
+	case "add", "replace":
+	case "remove":
+		// removing attributes of users is not supported, the LDAP attributes are mandatory
+		return nil
+	default:
+		return newSCIMError(http.StatusBadRequest, _scimTypeInvalidSyntax, "unknown patch operation '"+op+"'")
+	}
+
+	if attrPath == "" {
+		// the value contains the attributes to change
+		attributes := map[string]json.RawMessage{}
+		if err := json.Unmarshal(value, &attributes); err != nil {
+			return newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "the value of a patch operation without path must be an object")
+		}
+		for attr, v := range attributes {
+			if err := applySCIMUserOperation(changes, op, attr, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	lowerPath := strings.ToLower(attrPath)
+	switch {
+	case lowerPath == "active":
+		active, err := parseSCIMBool(value)
+		if err != nil {
+			return err
+		}
+		changes.SetAccountEnabled(active)
+	case lowerPath == "username":
+		s, err := parseSCIMString(value)
+		if err != nil {
+			return err
+		}
+		changes.SetOnPremisesSamAccountName(s)
+	case lowerPath == "displayname":
+		s, err := parseSCIMString(value)
+		if err != nil {
+			return err
+		}
+		changes.SetDisplayName(s)
+	case lowerPath == "name.givenname":
+		s, err := parseSCIMString(value)
+		if err != nil {
+			return err
+		}
+		changes.SetGivenName(s)
+	case lowerPath == "name.familyname":
+		s, err := parseSCIMString(value)
+		if err != nil {
+			return err
+		}
+		changes.SetSurname(s)
+	case lowerPath == "name":
+		name := SCIMName{}
+		if err := json.Unmarshal(value, &name); err != nil {
+			return newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid name")
+		}
+		if name.GivenName != "" {
+			changes.SetGivenName(name.GivenName)
+		}
+		if name.FamilyName != "" {
+			changes.SetSurname(name.FamilyName)
+		}
+	case lowerPath == "password":
+		s, err := parseSCIMString(value)
+		if err != nil {
+			return err
+		}
+		changes.SetPasswordProfile(libregraph.PasswordProfile{Password: libregraph.PtrString(s)})
+	case _scimEmailsPathRegex.MatchString(attrPath):
+		if s, err := parseSCIMString(value); err == nil {
+			changes.SetMail(s)
+			return nil
+		}
+		emails := []SCIMMultiValue{}
+		if err := json.Unmarshal(value, &emails); err != nil {
+			return newSCIMError(http.StatusBadRequest, _scimTypeInvalidValue, "invalid emails")
+		}
+		if mail := primarySCIMValue(emails); mail != "" {
+			changes.SetMail(mail)
+		}
+	}
+	return nil
+}
+
+// toSCIMUser converts a libregraph user to a SCIM user
+func (g Graph) toSCIMUser(u *libregraph.User) *SCIMUser {
+	su := &SCIMUser{
+		Schemas:           []string{_scimSchemaUser},
+		ID:                u.GetId(),
+		UserName:          u.GetOnPremisesSamAccountName(),
+		DisplayName:       u.GetDisplayName(),
+		PreferredLanguage: u.GetPreferredLanguage(),
+		Active:            libregraph.PtrBool(u.GetAccountEnabled()),
+		Meta: &SCIMMeta{
+			ResourceType: "User",
+			Location:     g.scimLocation("Users", u.GetId()),
+		},
+	}
+	if _, ok := u.GetAccountEnabledOk(); !ok {
+		// backends without a disable mechanism don't return the attribute
+		su.Active = libregraph.PtrBool(true)
+	}
+	if u.GetGivenName() != "" || u.GetSurname() != "" {
+		su.Name = &SCIMName{GivenName: u.GetGivenName(), FamilyName: u.GetSurname()}
+	}
+	if u.GetMail() != "" {
+		su.Emails = []SCIMMultiValue{{Value: u.GetMail(), Type: "work", Primary: true}}
+	}
+	return su
+}
+
+// primarySCIMValue returns the primary value of a multi-valued attribute or the first value
+// if none is marked as primary.
+func primarySCIMValue(values []SCIMMultiValue) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// isItemNotFound returns true if the error is an errorcode error signaling a missing item
+func isItemNotFound(err error) bool {
+	e, ok := errorcode.ToError(err)
+	return ok && e.GetCode() == errorcode.ItemNotFound
+}
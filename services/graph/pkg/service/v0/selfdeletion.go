@@ -0,0 +1,601 @@
+package svc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CiscoM31/godata"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/walker"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/unifiedrole"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/userstate"
+)
+
+const (
+	_selfDeletionKeyPrefix = "selfdeletion."
+
+	_selfDeletionStatePending   = "pending"
+	_selfDeletionStateConfirmed = "confirmed"
+
+	// _selfDeletionMaxAttempts is the number of wrong confirmation tokens after which a request is dropped
+	_selfDeletionMaxAttempts = 5
+
+	// _selfDeletionChecksumChunkSize is the number of checksums sent with one PersonalFilesPurged event
+	_selfDeletionChecksumChunkSize = 100
+)
+
+var (
+	errSelfDeletionTokenExpired = errorcode.New(errorcode.InvalidRequest, "the deletion request has expired")
+	errSelfDeletionTokenInvalid = errorcode.New(errorcode.InvalidRequest, "invalid confirmation token")
+)
+
+// DeletionRequest is the state of the deletion of the own account
+type DeletionRequest struct {
+	Status             string     `json:"status"`
+	ExpirationDateTime *time.Time `json:"expirationDateTime,omitempty"`
+	PurgeDateTime      *time.Time `json:"purgeDateTime,omitempty"`
+}
+
+// ConfirmDeletionRequest is the body of the request confirming the deletion of the own account
+type ConfirmDeletionRequest struct {
+	Token string `json:"token"`
+}
+
+// selfDeletionRecord is kept per user in the nats key value store
+type selfDeletionRecord struct {
+	UserID string `json:"userId"`
+	State  string `json:"state"`
+	// TokenHash is the hash of the confirmation token of a pending request
+	TokenHash string `json:"tokenHash,omitempty"`
+	// TokenID is the key the confirmation token is handed to the notifications service under
+	TokenID   string    `json:"tokenId,omitempty"`
+	Attempts  int       `json:"attempts,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+	// PurgeAt is the time after which a confirmed deletion is carried out for good
+	PurgeAt time.Time `json:"purgeAt,omitempty"`
+}
+
+func (rec selfDeletionRecord) deletionRequest() DeletionRequest {
+	dr := DeletionRequest{Status: rec.State}
+	if rec.State == _selfDeletionStatePending {
+		dr.ExpirationDateTime = &rec.ExpiresAt
+	} else {
+		dr.PurgeDateTime = &rec.PurgeAt
+	}
+	return dr
+}
+
+// checkSelfDeletionToken validates the confirmation token of a pending deletion request
+func checkSelfDeletionToken(rec selfDeletionRecord, token string, now time.Time) error {
+	switch {
+	case rec.State != _selfDeletionStatePending:
+		return errorcode.New(errorcode.ItemNotFound, "no pending deletion request")
+	case now.After(rec.ExpiresAt):
+		return errSelfDeletionTokenExpired
+	case subtle.ConstantTimeCompare([]byte(hashSelfDeletionToken(token)), []byte(rec.TokenHash)) != 1:
+		return errSelfDeletionTokenInvalid
+	}
+	return nil
+}
+
+// newSelfDeletionToken returns a short random token which is easy to type in
+func newSelfDeletionToken() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+func hashSelfDeletionToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(token))))
+	return hex.EncodeToString(sum[:])
+}
+
+// soleManager tells if the user is the only manager of a space
+func soleManager(userID string, managers []*userpb.UserId, managerGroups []*grouppb.GroupId) bool {
+	return len(managerGroups) == 0 && len(managers) == 1 && managers[0].GetOpaqueId() == userID
+}
+
+// GetDeletionRequest returns the state of the deletion of the own account
+func (g Graph) GetDeletionRequest(w http.ResponseWriter, r *http.Request) {
+	u := revactx.ContextMustGetUser(r.Context())
+	rec, err := g.getSelfDeletionRecord(r.Context(), u.GetId().GetOpaqueId())
+	switch {
+	case err != nil:
+		errorcode.RenderError(w, r, err)
+		return
+	case rec.State == "":
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "no deletion request")
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, rec.deletionRequest())
+}
+
+// CreateDeletionRequest starts the deletion of the own account. The confirmation token is sent by mail.
+func (g Graph) CreateDeletionRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+	u := revactx.ContextMustGetUser(ctx)
+
+	if _, _, err := g.soleManagedProjectSpaces(ctx, u.GetId().GetOpaqueId()); err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	if g.tokenkv == nil {
+		errorcode.ServiceNotAvailable.Render(w, r, http.StatusServiceUnavailable, "the confirmation tokens can not be stored")
+		return
+	}
+
+	token, err := newSelfDeletionToken()
+	if err != nil {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not create the confirmation token")
+		return
+	}
+	rec := selfDeletionRecord{
+		UserID:    u.GetId().GetOpaqueId(),
+		State:     _selfDeletionStatePending,
+		TokenHash: hashSelfDeletionToken(token),
+		TokenID:   uuid.NewString(),
+		ExpiresAt: time.Now().Add(g.config.SelfDeletion.ConfirmationExpiration),
+	}
+	if err := g.putSelfDeletionRecord(ctx, rec); err != nil {
+		logger.Error().Err(err).Msg("could not store the deletion request")
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	// the token itself is not put on the event bus, the notifications service picks it up by its id
+	if _, err := g.tokenkv.Put(ctx, rec.TokenID, []byte(token)); err != nil {
+		logger.Error().Err(err).Msg("could not hand over the confirmation token")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not send the confirmation token")
+		return
+	}
+
+	g.publishEvent(ctx, selfdeletion.AccountDeletionRequested{
+		UserID:              u.GetId(),
+		UserDisplayName:     u.GetDisplayName(),
+		UserMail:            u.GetMail(),
+		ConfirmationTokenID: rec.TokenID,
+		ExpiresAt:           utils.TimeToTS(rec.ExpiresAt),
+		Timestamp:           utils.TSNow(),
+	})
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, rec.deletionRequest())
+}
+
+// CancelDeletionRequest cancels a pending deletion of the own account
+func (g Graph) CancelDeletionRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := revactx.ContextMustGetUser(ctx)
+	rec, err := g.getSelfDeletionRecord(ctx, u.GetId().GetOpaqueId())
+	switch {
+	case err != nil:
+		errorcode.RenderError(w, r, err)
+		return
+	case rec.State != _selfDeletionStatePending:
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "no pending deletion request")
+		return
+	}
+
+	if err := g.natskv.Delete(ctx, selfDeletionKey(rec.UserID)); err != nil {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not cancel the deletion request")
+		return
+	}
+	g.deleteSelfDeletionToken(ctx, rec.TokenID)
+	g.publishEvent(ctx, selfdeletion.AccountDeletionCancelled{
+		UserID:    u.GetId(),
+		Timestamp: utils.TSNow(),
+	})
+
+	render.Status(r, http.StatusNoContent)
+	render.NoContent(w, r)
+}
+
+// ConfirmDeletionRequest deletes the own account after checking the confirmation token. The account is
+// disabled and purged after the soft delete retention time.
+func (g Graph) ConfirmDeletionRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := g.logger.SubloggerWithRequestID(ctx)
+	u := revactx.ContextMustGetUser(ctx)
+
+	var req ConfirmDeletionRequest
+	if err := StrictJSONUnmarshal(r.Body, &req); err != nil {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err.Error()))
+		return
+	}
+
+	rec, err := g.getSelfDeletionRecord(ctx, u.GetId().GetOpaqueId())
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if err := checkSelfDeletionToken(rec, req.Token, time.Now()); err != nil {
+		switch {
+		case errors.Is(err, errSelfDeletionTokenExpired):
+			_ = g.natskv.Delete(ctx, selfDeletionKey(rec.UserID))
+		case errors.Is(err, errSelfDeletionTokenInvalid):
+			rec.Attempts++
+			if rec.Attempts >= _selfDeletionMaxAttempts {
+				_ = g.natskv.Delete(ctx, selfDeletionKey(rec.UserID))
+			} else {
+				_ = g.putSelfDeletionRecord(ctx, rec)
+			}
+		}
+		logger.Info().Err(err).Msg("self deletion: could not confirm the deletion request")
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	transferred, deleted, err := g.handleProjectSpacesOfDeletedUser(ctx, u.GetId().GetOpaqueId())
+	if err != nil {
+		logger.Error().Err(err).Msg("self deletion: could not hand over the project spaces")
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	g.deleteSelfDeletionToken(ctx, rec.TokenID)
+	now := time.Now()
+	rec.State, rec.TokenHash, rec.TokenID, rec.Attempts = _selfDeletionStateConfirmed, "", "", 0
+	rec.PurgeAt = now.Add(g.config.UserSoftDeleteRetentionTime)
+
+	if err := g.deleteOwnAccount(ctx, rec); err != nil {
+		logger.Error().Err(err).Msg("self deletion: could not delete the account")
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	g.publishEvent(ctx, selfdeletion.AccountDeletionConfirmed{
+		UserID:            u.GetId(),
+		UserDisplayName:   u.GetDisplayName(),
+		UserMail:          u.GetMail(),
+		PurgeAt:           utils.TimeToTS(rec.PurgeAt),
+		TransferredSpaces: transferred,
+		DeletedSpaces:     deleted,
+		Timestamp:         utils.TimeToTS(now),
+	})
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, rec.deletionRequest())
+}
+
+// deleteOwnAccount soft deletes the account of a confirmed deletion request and keeps the request until
+// it is purged. Without a retention time the account is purged in the background right away.
+func (g Graph) deleteOwnAccount(ctx context.Context, rec selfDeletionRecord) error {
+	if g.config.UserSoftDeleteRetentionTime == 0 {
+		if err := g.putSelfDeletionRecord(ctx, rec); err != nil {
+			return err
+		}
+		// the purger picks the request up in case the graph service stops in the meantime
+		go g.purgeSelfDeletedAccount(context.WithoutCancel(ctx), *g.logger, selfDeletionKey(rec.UserID), time.Now())
+		return nil
+	}
+
+	ctx, _, err := g.serviceAccountContext(ctx)
+	if err != nil {
+		return err
+	}
+	user, err := g.identityBackend.GetUser(ctx, rec.UserID, &godata.GoDataRequest{})
+	if err != nil {
+		return err
+	}
+	us := userstate.UserState{UserId: rec.UserID, State: userstate.UserStateEnabled}
+	if err := g.deleteUser(ctx, user, us, false, &userpb.UserId{OpaqueId: rec.UserID}); err != nil {
+		return err
+	}
+	return g.putSelfDeletionRecord(ctx, rec)
+}
+
+// purgeAccount deletes the account and the personal space for good and asks all services to remove the
+// personal data of the user
+func (g Graph) purgeAccount(ctx context.Context, userID string) error {
+	ctx, gatewayClient, err := g.serviceAccountContext(ctx)
+	if err != nil {
+		return err
+	}
+	user, err := g.identityBackend.GetUser(ctx, userID, &godata.GoDataRequest{})
+	if err != nil {
+		return err
+	}
+
+	us, err := g.getUserStateFromNatsKeyValue(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if us.State == userstate.UserStateHardDeleted {
+		return nil
+	}
+	if g.config.UserSoftDeleteRetentionTime > 0 && us.State != userstate.UserStateSoftDeleted {
+		// an admin restored the account in the meantime
+		g.logger.Info().Str("userid", userID).Msg("self deletion: the account was restored, dropping the deletion request")
+		return g.natskv.Delete(ctx, selfDeletionKey(userID))
+	}
+
+	var spaceID *storageprovider.StorageSpaceId
+	spaces, err := listSpacesUnrestricted(ctx, gatewayClient,
+		&storageprovider.ListStorageSpacesRequest_Filter{
+			Type: storageprovider.ListStorageSpacesRequest_Filter_TYPE_OWNER,
+			Term: &storageprovider.ListStorageSpacesRequest_Filter_Owner{Owner: &userpb.UserId{OpaqueId: userID}},
+		},
+		&storageprovider.ListStorageSpacesRequest_Filter{
+			Type: storageprovider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+			Term: &storageprovider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: _spaceTypePersonal},
+		},
+	)
+	if err == nil && len(spaces) == 1 {
+		spaceID = spaces[0].GetId()
+		g.purgePersonalFiles(ctx, userID, spaces[0].GetRoot())
+	}
+
+	executant := &userpb.UserId{OpaqueId: userID}
+	if err := g.deleteUser(ctx, user, us, true, executant); err != nil {
+		return err
+	}
+	if err := g.natskv.Delete(ctx, selfDeletionKey(userID)); err != nil {
+		g.logger.Error().Err(err).Str("userid", userID).Msg("self deletion: could not remove the deletion request")
+	}
+
+	g.publishEvent(ctx, selfdeletion.AccountPurged{
+		UserID:          executant,
+		PersonalSpaceID: spaceID,
+		Timestamp:       utils.TSNow(),
+	})
+	return nil
+}
+
+// soleManagedProjectSpaces returns the project spaces the user is the only manager of. It fails when the
+// configured policy blocks the deletion of accounts which still manage spaces alone.
+func (g Graph) soleManagedProjectSpaces(ctx context.Context, userID string) ([]*storageprovider.StorageSpace, []string, error) {
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := gatewayClient.ListStorageSpaces(ctx, &storageprovider.ListStorageSpacesRequest{
+		Filters: []*storageprovider.ListStorageSpacesRequest_Filter{{
+			Type: storageprovider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+			Term: &storageprovider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: _spaceTypeProject},
+		}},
+	})
+	if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+		return nil, nil, errCode
+	}
+
+	var (
+		spaces []*storageprovider.StorageSpace
+		names  []string
+	)
+	for _, space := range res.GetStorageSpaces() {
+		if _, trashed := space.GetOpaque().GetMap()[_spaceStateTrashed]; trashed {
+			continue
+		}
+		if managers, groups := g.spaceManagers(space); soleManager(userID, managers, groups) {
+			spaces = append(spaces, space)
+			names = append(names, space.GetName())
+		}
+	}
+
+	if len(spaces) > 0 && g.config.SelfDeletion.ProjectSpacePolicy == selfdeletion.ProjectSpacePolicyBlock {
+		return nil, nil, errorcode.New(errorcode.PreconditionFailed,
+			"add another manager to these spaces before deleting your account: "+strings.Join(names, ", "))
+	}
+	return spaces, names, nil
+}
+
+// handleProjectSpacesOfDeletedUser applies the project space policy to the spaces the user is the only
+// manager of. It returns the ids of the transferred and of the deleted spaces.
+func (g Graph) handleProjectSpacesOfDeletedUser(ctx context.Context, userID string) ([]string, []string, error) {
+	spaces, _, err := g.soleManagedProjectSpaces(ctx, userID)
+	if err != nil || len(spaces) == 0 {
+		return nil, nil, err
+	}
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var transferred, deleted []string
+	for _, space := range spaces {
+		switch g.config.SelfDeletion.ProjectSpacePolicy {
+		case selfdeletion.ProjectSpacePolicyTransfer:
+			_, err := g.driveItemPermissions.Invite(ctx, space.GetRoot(), libregraph.DriveItemInvite{
+				Recipients: []libregraph.DriveRecipient{{
+					ObjectId:                libregraph.PtrString(g.config.SelfDeletion.ProjectSpaceTransferUID),
+					LibreGraphRecipientType: libregraph.PtrString("user"),
+				}},
+				Roles: []string{unifiedrole.UnifiedRoleManagerID},
+			})
+			if err != nil {
+				return transferred, deleted, fmt.Errorf("could not transfer the space '%s': %w", space.GetName(), err)
+			}
+			transferred = append(transferred, space.GetId().GetOpaqueId())
+		case selfdeletion.ProjectSpacePolicyDelete:
+			// disable the space first, it can only be purged afterwards
+			for _, opaque := range []map[string]string{nil, {"purge": ""}} {
+				req := &storageprovider.DeleteStorageSpaceRequest{Id: space.GetId()}
+				for k, v := range opaque {
+					req.Opaque = utils.AppendPlainToOpaque(req.GetOpaque(), k, v)
+				}
+				res, err := gatewayClient.DeleteStorageSpace(ctx, req)
+				if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+					return transferred, deleted, fmt.Errorf("could not delete the space '%s': %w", space.GetName(), errCode)
+				}
+			}
+			deleted = append(deleted, space.GetId().GetOpaqueId())
+		}
+	}
+	return transferred, deleted, nil
+}
+
+// purgePersonalFiles walks the personal space before it is purged and emits the checksums of the files in
+// small chunks, the thumbnails service removes their thumbnails
+func (g Graph) purgePersonalFiles(ctx context.Context, userID string, root *storageprovider.ResourceId) {
+	checksums := make([]string, 0, _selfDeletionChecksumChunkSize)
+	flush := func() {
+		if len(checksums) == 0 {
+			return
+		}
+		g.publishEvent(ctx, selfdeletion.PersonalFilesPurged{
+			UserID:    &userpb.UserId{OpaqueId: userID},
+			Checksums: checksums,
+			Timestamp: utils.TSNow(),
+		})
+		checksums = make([]string, 0, _selfDeletionChecksumChunkSize)
+	}
+
+	err := walker.NewWalker(g.gatewaySelector).Walk(ctx, root, func(_ string, info *storageprovider.ResourceInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.GetType() == storageprovider.ResourceType_RESOURCE_TYPE_FILE && info.GetChecksum().GetSum() != "" {
+			checksums = append(checksums, info.GetChecksum().GetSum())
+			if len(checksums) == _selfDeletionChecksumChunkSize {
+				flush()
+			}
+		}
+		return nil
+	})
+	flush()
+	if err != nil {
+		g.logger.Error().Err(err).Str("userid", userID).Msg("self deletion: could not walk the personal space, some thumbnails are kept")
+	}
+}
+
+// StartSelfDeletionPurger periodically purges the accounts whose deletion was confirmed once the soft
+// delete retention time has passed. Expired requests are removed.
+func (g Graph) StartSelfDeletionPurger(ctx context.Context, l log.Logger) {
+	if !g.config.SelfDeletion.Enabled {
+		return
+	}
+	if g.natskv == nil {
+		l.Warn().Msg("self deletion requires the nats key value store, confirmed deletions are not purged")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(g.config.SelfDeletion.PurgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				g.purgeSelfDeletedAccounts(ctx, l)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (g Graph) purgeSelfDeletedAccounts(ctx context.Context, l log.Logger) {
+	lister, err := g.natskv.ListKeysFiltered(ctx, _selfDeletionKeyPrefix+">")
+	if err != nil {
+		l.Error().Err(err).Msg("self deletion: could not list the deletion requests")
+		return
+	}
+	now := time.Now()
+	for key := range lister.Keys() {
+		g.purgeSelfDeletedAccount(ctx, l, key, now)
+	}
+}
+
+// purgeSelfDeletedAccount removes an expired deletion request or purges the account of a confirmed one
+// once it is due
+func (g Graph) purgeSelfDeletedAccount(ctx context.Context, l log.Logger, key string, now time.Time) {
+	entry, err := g.natskv.Get(ctx, key)
+	if err != nil {
+		return
+	}
+	var rec selfDeletionRecord
+	if err := json.Unmarshal(entry.Value(), &rec); err != nil {
+		l.Error().Err(err).Str("key", key).Msg("self deletion: could not read the deletion request")
+		return
+	}
+
+	switch {
+	case rec.State == _selfDeletionStatePending && now.After(rec.ExpiresAt):
+		_ = g.natskv.Delete(ctx, key, jetstream.LastRevision(entry.Revision()))
+	case rec.State == _selfDeletionStateConfirmed && !now.Before(rec.PurgeAt):
+		// claim the request, other graph instances skip it then
+		rec.PurgeAt = now.Add(g.config.SelfDeletion.PurgeInterval)
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return
+		}
+		if _, err := g.natskv.Update(ctx, key, data, entry.Revision()); err != nil {
+			return
+		}
+		if err := g.purgeAccount(ctx, rec.UserID); err != nil {
+			l.Error().Err(err).Str("userid", rec.UserID).Msg("self deletion: could not purge the account")
+			return
+		}
+		l.Info().Str("userid", rec.UserID).Msg("self deletion: purged the account")
+	}
+}
+
+// deleteSelfDeletionToken removes a confirmation token the notifications service did not pick up
+func (g Graph) deleteSelfDeletionToken(ctx context.Context, tokenID string) {
+	if g.tokenkv == nil || tokenID == "" {
+		return
+	}
+	if err := g.tokenkv.Delete(ctx, tokenID); err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+		g.logger.Error().Err(err).Msg("self deletion: could not remove the confirmation token")
+	}
+}
+
+func (g Graph) getSelfDeletionRecord(ctx context.Context, userID string) (selfDeletionRecord, error) {
+	rec := selfDeletionRecord{}
+	if g.natskv == nil {
+		return rec, errorcode.New(errorcode.ServiceNotAvailable, "the deletion requests can not be stored")
+	}
+	entry, err := g.natskv.Get(ctx, selfDeletionKey(userID))
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		return rec, nil
+	case err != nil:
+		return rec, err
+	}
+	err = json.Unmarshal(entry.Value(), &rec)
+	return rec, err
+}
+
+func (g Graph) putSelfDeletionRecord(ctx context.Context, rec selfDeletionRecord) error {
+	if g.natskv == nil {
+		return errorcode.New(errorcode.ServiceNotAvailable, "the deletion requests can not be stored")
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = g.natskv.Put(ctx, selfDeletionKey(rec.UserID), data)
+	return err
+}
+
+// selfDeletionKey returns the key of a user in the nats key value store, user ids may contain characters
+// which are not allowed in keys
+func selfDeletionKey(userID string) string {
+	return _selfDeletionKeyPrefix + base64.RawURLEncoding.EncodeToString([]byte(userID))
+}
@@ -0,0 +1,45 @@
+package svc
+
+import (
+	"testing"
+	"time"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSelfDeletionToken(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	token, err := newSelfDeletionToken()
+	assert.NoError(t, err)
+	pending := selfDeletionRecord{
+		State:     _selfDeletionStatePending,
+		TokenHash: hashSelfDeletionToken(token),
+		ExpiresAt: now.Add(time.Hour),
+	}
+
+	assert.NoError(t, checkSelfDeletionToken(pending, token, now))
+	assert.NoError(t, checkSelfDeletionToken(pending, " "+token+" ", now), "surrounding whitespace is ignored")
+	assert.ErrorIs(t, checkSelfDeletionToken(pending, "wrong", now), errSelfDeletionTokenInvalid)
+	assert.ErrorIs(t, checkSelfDeletionToken(pending, token, now.Add(2*time.Hour)), errSelfDeletionTokenExpired)
+
+	confirmed := pending
+	confirmed.State = _selfDeletionStateConfirmed
+	assert.Error(t, checkSelfDeletionToken(confirmed, token, now))
+}
+
+func TestSoleManager(t *testing.T) {
+	alice := &userpb.UserId{OpaqueId: "alice"}
+	bob := &userpb.UserId{OpaqueId: "bob"}
+
+	assert.True(t, soleManager("alice", []*userpb.UserId{alice}, nil))
+	assert.False(t, soleManager("alice", []*userpb.UserId{alice, bob}, nil))
+	assert.False(t, soleManager("alice", []*userpb.UserId{bob}, nil))
+	assert.False(t, soleManager("alice", []*userpb.UserId{alice}, []*grouppb.GroupId{{OpaqueId: "admins"}}), "group managers keep the space managed")
+}
+
+func TestSelfDeletionKey(t *testing.T) {
+	key := selfDeletionKey("uid=alice,ou=users")
+	assert.Regexp(t, `^selfdeletion\.[-_a-zA-Z0-9]+$`, key)
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/opencloud-eu/reva/v2/pkg/events"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
 	"github.com/opencloud-eu/reva/v2/pkg/store"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
 	"github.com/opencloud-eu/reva/v2/pkg/utils/ldap"
@@ -46,6 +47,8 @@ const (
 type Service interface { //nolint:interfacebloat
 	ServeHTTP(w http.ResponseWriter, r *http.Request)
 
+	Batch(w http.ResponseWriter, r *http.Request)
+
 	ListApplications(w http.ResponseWriter, r *http.Request)
 	GetApplication(w http.ResponseWriter, r *http.Request)
 
@@ -110,18 +113,32 @@ type Service interface { //nolint:interfacebloat
 	UpdateDrive(w http.ResponseWriter, r *http.Request)
 	DeleteDrive(w http.ResponseWriter, r *http.Request)
 
+	GetRetentionPolicy(w http.ResponseWriter, r *http.Request)
+	UpdateRetentionPolicy(w http.ResponseWriter, r *http.Request)
+	PlaceLegalHold(w http.ResponseWriter, r *http.Request)
+	ReleaseLegalHold(w http.ResponseWriter, r *http.Request)
+
 	GetSharedByMe(w http.ResponseWriter, r *http.Request)
 	ListSharedWithMe(w http.ResponseWriter, r *http.Request)
 
 	GetRootDriveChildren(w http.ResponseWriter, r *http.Request)
 	GetDriveItem(w http.ResponseWriter, r *http.Request)
 	GetDriveItemChildren(w http.ResponseWriter, r *http.Request)
+	GetDriveItemDelta(w http.ResponseWriter, r *http.Request)
+	UpdateDriveItem(w http.ResponseWriter, r *http.Request)
+	CopyDriveItem(w http.ResponseWriter, r *http.Request)
+	GetAsyncJobStatus(w http.ResponseWriter, r *http.Request)
+	GetDriveItemContent(w http.ResponseWriter, r *http.Request)
+	PutDriveItemContent(w http.ResponseWriter, r *http.Request)
+	PutDriveItemChildContent(w http.ResponseWriter, r *http.Request)
 
 	CreateUploadSession(w http.ResponseWriter, r *http.Request)
 
 	GetTags(w http.ResponseWriter, r *http.Request)
 	AssignTags(w http.ResponseWriter, r *http.Request)
 	UnassignTags(w http.ResponseWriter, r *http.Request)
+
+	GetAccessReport(w http.ResponseWriter, r *http.Request)
 }
 
 // NewService returns a service implementation for Service.
@@ -147,6 +164,11 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 	)
 	go spacePropertiesCache.Start()
 
+	httpClient := options.HTTPClient
+	if httpClient == nil {
+		httpClient = rhttp.GetHTTPClient(rhttp.Insecure(true))
+	}
+
 	identityCache := identity.NewIdentityCache(
 		identity.IdentityCacheWithGatewaySelector(options.GatewaySelector),
 		identity.IdentityCacheWithUsersTTL(time.Duration(options.Config.Spaces.UsersCacheTTL)),
@@ -186,6 +208,11 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 		return Graph{}, err
 	}
 
+	spaceTemplatesApi, err := NewSpaceTemplatesApi(options.SpaceTemplatesService, options.Logger, options.Config)
+	if err != nil {
+		return Graph{}, err
+	}
+
 	svc := Graph{
 		BaseGraphService:         baseGraphService,
 		mux:                      m,
@@ -199,12 +226,26 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 		traceProvider:            options.TraceProvider,
 		valueService:             options.ValueService,
 		natskv:                   options.NatsKeyValue,
+		deltakv:                  options.DeltaKeyValue,
+		copyjobkv:                options.CopyJobKeyValue,
+		copyJobs:                 newCopyJobLimiter(),
+		tokenkv:                  options.TokenKeyValue,
+		spaceTemplates:           options.SpaceTemplatesService,
+		shareIndex:               options.ShareIndex,
+		serviceToken:             &serviceAccountToken{},
+		driveItemPermissions:     driveItemPermissionsService,
+		httpClient:               httpClient,
 	}
 
 	if err := setIdentityBackends(options, &svc); err != nil {
 		return svc, err
 	}
 
+	if err := svc.StartListenForQuotaEvents(options.Context, options.Logger); err != nil {
+		return svc, err
+	}
+	svc.StartSelfDeletionPurger(options.Context, options.Logger)
+
 	if options.PermissionService == nil {
 		grpcClient, err := grpc.NewClient(append(grpc.GetClientOptions(options.Config.GRPCClientTLS), grpc.WithTraceProvider(options.TraceProvider))...)
 		if err != nil {
@@ -243,10 +284,17 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 		requireAdmin = options.RequireAdminMiddleware
 	}
 
+	if options.Config.SCIM.Enabled {
+		svc.scim = chi.NewMux()
+		svc.routeSCIM(svc.scim)
+	}
+
 	m.Route(options.Config.HTTP.Root, func(r chi.Router) {
 		r.Use(middleware.StripSlashes)
 
 		r.Route("/v1beta1", func(r chi.Router) {
+			r.Post(_batchPath, svc.Batch)
+			r.With(requireAdmin).Get("/accessReport", svc.GetAccessReport)
 			r.Route("/me", func(r chi.Router) {
 				r.Get("/drives", svc.GetDrives(APIVersion_1_Beta_1))
 				r.Route("/drive", func(r chi.Router) {
@@ -257,7 +305,10 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 			r.Route("/drives", func(r chi.Router) {
 				r.Get("/", svc.GetAllDrives(APIVersion_1_Beta_1))
 				r.Route("/{driveID}", func(r chi.Router) {
+					r.Get("/retentionPolicy", svc.GetRetentionPolicy)
+					r.With(requireAdmin).Patch("/retentionPolicy", svc.UpdateRetentionPolicy)
 					r.Route("/root", func(r chi.Router) {
+						r.Get("/delta", svc.GetDriveItemDelta)
 						r.Post("/children", drivesDriveItemApi.CreateDriveItem)
 						r.Post("/invite", driveItemPermissionsApi.SpaceRootInvite)
 						r.Post("/createLink", driveItemPermissionsApi.CreateSpaceRootLink)
@@ -274,8 +325,11 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 						r.Get("/", drivesDriveItemApi.GetDriveItem)
 						r.Patch("/", drivesDriveItemApi.UpdateDriveItem)
 						r.Delete("/", drivesDriveItemApi.DeleteDriveItem)
+						r.Get("/delta", svc.GetDriveItemDelta)
 						r.Post("/invite", driveItemPermissionsApi.Invite)
 						r.Post("/createLink", driveItemPermissionsApi.CreateLink)
+						r.With(requireAdmin).Post("/legalHold", svc.PlaceLegalHold)
+						r.With(requireAdmin).Delete("/legalHold", svc.ReleaseLegalHold)
 						r.Route("/permissions", func(r chi.Router) {
 							r.Get("/", driveItemPermissionsApi.ListPermissions)
 							r.Route("/{permissionID}", func(r chi.Router) {
@@ -291,8 +345,19 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 				r.Get("/", svc.GetRoleDefinitions)
 				r.Get("/{roleID}", svc.GetRoleDefinition)
 			})
+			r.Route("/spaceTemplates", func(r chi.Router) {
+				r.Get("/", spaceTemplatesApi.ListSpaceTemplates)
+				r.With(requireAdmin).Post("/", spaceTemplatesApi.CreateSpaceTemplate)
+				r.Route("/{templateName}", func(r chi.Router) {
+					r.Get("/", spaceTemplatesApi.GetSpaceTemplate)
+					r.With(requireAdmin).Patch("/", spaceTemplatesApi.UpdateSpaceTemplate)
+					r.With(requireAdmin).Delete("/", spaceTemplatesApi.DeleteSpaceTemplate)
+				})
+			})
 		})
 		r.Route("/v1.0", func(r chi.Router) {
+			r.Post(_batchPath, svc.Batch)
+			r.Get("/monitor/{jobID}", svc.GetAsyncJobStatus)
 			r.Route("/extensions/org.libregraph", func(r chi.Router) {
 				r.Get("/tags", svc.GetTags)
 				r.Put("/tags", svc.AssignTags)
@@ -311,6 +376,14 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 				})
 				r.Get("/drives", svc.GetDrives(APIVersion_1))
 				r.Post("/changePassword", svc.ChangeOwnPassword)
+				if options.Config.SelfDeletion.Enabled {
+					r.Route("/deletionRequest", func(r chi.Router) {
+						r.Get("/", svc.GetDeletionRequest)
+						r.Post("/", svc.CreateDeletionRequest)
+						r.Delete("/", svc.CancelDeletionRequest)
+						r.Post("/confirm", svc.ConfirmDeletionRequest)
+					})
+				}
 				r.Route("/photo/$value", func(r chi.Router) {
 					r.Get("/", usersUserProfilePhotoApi.GetProfilePhoto(GetUserIDFromCTX))
 					r.Put("/", usersUserProfilePhotoApi.UpsertProfilePhoto(GetUserIDFromCTX))
@@ -360,10 +433,15 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 					r.Patch("/", svc.UpdateDrive)
 					r.Get("/", svc.GetSingleDrive)
 					r.Delete("/", svc.DeleteDrive)
+					r.Put("/items/{driveItemID}:/{fileName}:/content", svc.PutDriveItemChildContent)
 					r.Route("/items/{driveItemID}", func(r chi.Router) {
 						r.Get("/", svc.GetDriveItem)
+						r.Patch("/", svc.UpdateDriveItem)
 						r.Get("/children", svc.GetDriveItemChildren)
 						r.Post("/createUploadSession", svc.CreateUploadSession)
+						r.Post("/copy", svc.CopyDriveItem)
+						r.Get("/content", svc.GetDriveItemContent)
+						r.Put("/content", svc.PutDriveItemContent)
 					})
 				})
 			})
@@ -0,0 +1,62 @@
+package svc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+)
+
+// _serviceAccountTokenTTL is the time a token of the service account is reused, it must be shorter
+// than the lifetime of the tokens issued by the gateway
+const _serviceAccountTokenTTL = 5 * time.Minute
+
+// serviceAccountToken caches the token of the service account, so event handlers do not need to
+// authenticate for every event
+type serviceAccountToken struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// get returns the cached token or a new one from the gateway. The lock is not held while authenticating,
+// concurrent callers may authenticate at the same time.
+func (t *serviceAccountToken) get(ctx context.Context, gatewayClient gateway.GatewayAPIClient, id, secret string) (string, error) {
+	t.mu.Lock()
+	token, expires := t.token, t.expires
+	t.mu.Unlock()
+	if token != "" && time.Now().Before(expires) {
+		return token, nil
+	}
+
+	token, err := utils.GetServiceUserToken(ctx, gatewayClient, id, secret)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	t.token, t.expires = token, time.Now().Add(_serviceAccountTokenTTL)
+	t.mu.Unlock()
+	return token, nil
+}
+
+// serviceAccountContext returns a context authenticated as the service account
+func (g Graph) serviceAccountContext(ctx context.Context) (context.Context, gateway.GatewayAPIClient, error) {
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var token string
+	if g.serviceToken != nil {
+		token, err = g.serviceToken.get(ctx, gatewayClient, g.config.ServiceAccount.ServiceAccountID, g.config.ServiceAccount.ServiceAccountSecret)
+	} else {
+		token, err = utils.GetServiceUserToken(ctx, gatewayClient, g.config.ServiceAccount.ServiceAccountID, g.config.ServiceAccount.ServiceAccountSecret)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return withAccessToken(ctx, token), gatewayClient, nil
+}
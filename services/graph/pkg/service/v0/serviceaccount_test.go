@@ -0,0 +1,28 @@
+package svc
+
+import (
+	"context"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	cs3mocks "github.com/opencloud-eu/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestServiceAccountTokenIsReused(t *testing.T) {
+	gatewayClient := &cs3mocks.GatewayAPIClient{}
+	gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{
+		Status: status.NewOK(context.Background()),
+		Token:  "token",
+	}, nil).Once()
+
+	cache := &serviceAccountToken{}
+	for i := 0; i < 3; i++ {
+		token, err := cache.get(context.Background(), gatewayClient, "service-account", "secret")
+		assert.NoError(t, err)
+		assert.Equal(t, "token", token)
+	}
+	gatewayClient.AssertNumberOfCalls(t, "Authenticate", 1)
+}
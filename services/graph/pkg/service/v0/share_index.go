@@ -0,0 +1,129 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare/manager/json/persistence"
+	publicsharecs3 "github.com/opencloud-eu/reva/v2/pkg/publicshare/manager/json/persistence/cs3"
+	"github.com/opencloud-eu/reva/v2/pkg/share"
+	"github.com/opencloud-eu/reva/v2/pkg/share/manager/jsoncs3/providercache"
+	revaMetadata "github.com/opencloud-eu/reva/v2/pkg/storage/utils/metadata"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	"github.com/opencloud-eu/opencloud/pkg/storage/metadata"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config"
+)
+
+// ErrShareIndexNotSupported is returned by NewShareIndex if the sharing drivers don't keep an index
+var ErrShareIndexNotSupported = errors.New("the share index requires the jsoncs3 sharing drivers")
+
+// ShareIndex lists the shares and public links of all users. The share manager apis only return the shares
+// a user created or received, an index is needed to find all shares of a space.
+type ShareIndex interface {
+	// ListSpaceShares returns the user and group shares on resources of the space
+	ListSpaceShares(ctx context.Context, storageID, spaceID string) ([]*collaboration.Share, error)
+	// ListPublicShares returns the public links of all spaces
+	ListPublicShares(ctx context.Context) ([]*link.PublicShare, error)
+}
+
+// metadataShareIndex reads the indexes the jsoncs3 share managers keep in the system storage
+type metadataShareIndex struct {
+	spaces providercache.Cache
+
+	linksMu sync.Mutex
+	links   persistence.Persistence
+}
+
+// NewShareIndex returns a ShareIndex that reads the share manager indexes from the system storage as the system user
+func NewShareIndex(cfg config.Metadata) (ShareIndex, error) {
+	// only the jsoncs3 share managers keep their indexes in the system storage
+	if cfg.UserSharingDriver != "jsoncs3" || cfg.PublicSharingDriver != "jsoncs3" {
+		return nil, fmt.Errorf("%w, the user sharing driver is '%s', the public sharing driver is '%s'",
+			ErrShareIndexNotSupported, cfg.UserSharingDriver, cfg.PublicSharingDriver)
+	}
+
+	newStorage := func(name string) (revaMetadata.Storage, error) {
+		s, err := revaMetadata.NewCS3Storage(cfg.GatewayAddress, cfg.StorageAddress, cfg.SystemUserID, cfg.SystemUserIDP, cfg.SystemUserAPIKey)
+		if err != nil {
+			return nil, err
+		}
+		lazy, err := metadata.NewLazyStorage(s)
+		if err != nil {
+			return nil, err
+		}
+		if err := lazy.Init(context.Background(), name); err != nil {
+			return nil, err
+		}
+		return lazy, nil
+	}
+
+	sharesStorage, err := newStorage("jsoncs3-share-manager-metadata")
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize the share manager storage: %w", err)
+	}
+	linksStorage, err := newStorage("jsoncs3-public-share-manager-metadata")
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize the public share manager storage: %w", err)
+	}
+
+	links := publicsharecs3.New(linksStorage)
+	if err := links.Init(context.Background()); err != nil {
+		return nil, fmt.Errorf("could not initialize the public share manager storage: %w", err)
+	}
+
+	return &metadataShareIndex{
+		spaces: providercache.New(sharesStorage, 0),
+		links:  links,
+	}, nil
+}
+
+// ListSpaceShares implements the ShareIndex interface, expired shares are skipped
+func (i *metadataShareIndex) ListSpaceShares(ctx context.Context, storageID, spaceID string) ([]*collaboration.Share, error) {
+	shares, err := i.spaces.ListSpace(ctx, storageID, spaceID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*collaboration.Share, 0, len(shares.Shares))
+	for _, s := range shares.Shares {
+		if share.IsExpired(s) {
+			continue
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// ListPublicShares implements the ShareIndex interface, expired links are skipped
+func (i *metadataShareIndex) ListPublicShares(ctx context.Context) ([]*link.PublicShare, error) {
+	// the persistence updates its state when reading
+	i.linksMu.Lock()
+	db, err := i.links.Read(ctx)
+	i.linksMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*link.PublicShare, 0, len(db))
+	for id, v := range db {
+		data, _ := v.(map[string]interface{})
+		encoded, ok := data["share"].(string)
+		if !ok {
+			return nil, errors.New("invalid public share " + id)
+		}
+		var ps link.PublicShare
+		if err := utils.UnmarshalJSONToProtoV1([]byte(encoded), &ps); err != nil {
+			return nil, err
+		}
+		if publicshare.IsExpired(&ps) {
+			continue
+		}
+		list = append(list, &ps)
+	}
+	return list, nil
+}
@@ -0,0 +1,64 @@
+package svc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare/manager/json/persistence"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/config"
+)
+
+// readPersistence returns fixed public shares
+type readPersistence struct {
+	persistence.Persistence
+	db persistence.PublicShares
+}
+
+func (p readPersistence) Read(context.Context) (persistence.PublicShares, error) {
+	return p.db, nil
+}
+
+func TestShareIndexListPublicShares(t *testing.T) {
+	encode := func(ps *link.PublicShare) map[string]interface{} {
+		b, err := utils.MarshalProtoV1ToJSON(ps)
+		require.NoError(t, err)
+		return map[string]interface{}{"share": string(b), "password": ""}
+	}
+
+	index := &metadataShareIndex{links: readPersistence{db: persistence.PublicShares{
+		"valid": encode(&link.PublicShare{
+			Id:         &link.PublicShareId{OpaqueId: "valid"},
+			Expiration: utils.TimeToTS(time.Now().Add(time.Hour)),
+		}),
+		"expired": encode(&link.PublicShare{
+			Id:         &link.PublicShareId{OpaqueId: "expired"},
+			Expiration: &types.Timestamp{Seconds: 1},
+		}),
+	}}}
+
+	links, err := index.ListPublicShares(context.Background())
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "valid", links[0].GetId().GetOpaqueId())
+
+	index.links = readPersistence{db: persistence.PublicShares{"broken": map[string]interface{}{}}}
+	_, err = index.ListPublicShares(context.Background())
+	assert.Error(t, err)
+}
+
+func TestNewShareIndexRequiresJSONCS3(t *testing.T) {
+	for _, cfg := range []config.Metadata{
+		{UserSharingDriver: "owncloudsql", PublicSharingDriver: "jsoncs3"},
+		{UserSharingDriver: "jsoncs3", PublicSharingDriver: "json"},
+	} {
+		_, err := NewShareIndex(cfg)
+		assert.ErrorIs(t, err, ErrShareIndexNotSupported)
+	}
+}
@@ -0,0 +1,387 @@
+package svc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/quota"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/unifiedrole"
+)
+
+const (
+	_spaceQuotaKeyPrefix = "spacequota."
+	// _spaceQuotaUpdateAttempts is the number of times the quota state is compared again after a concurrent change
+	_spaceQuotaUpdateAttempts = 3
+)
+
+// spaceQuotaRecord is kept per space in the nats key value store
+type spaceQuotaRecord struct {
+	// State is the last known quota state of the space
+	State string `json:"state,omitempty"`
+	// NotifiedState and NotifiedAt describe the last quota notification for the space
+	NotifiedState string    `json:"notifiedState,omitempty"`
+	NotifiedAt    time.Time `json:"notifiedAt,omitempty"`
+	// GroupQuota is the quota that was set from the group default quotas
+	GroupQuota *uint64 `json:"groupQuota,omitempty"`
+}
+
+// shouldNotifyQuotaState tells if a change to the given quota state needs to be notified. Changes to a more
+// severe state than the last notified one are always notified, all other warnings only after the cool-down.
+func shouldNotifyQuotaState(rec spaceQuotaRecord, state string, now time.Time, coolDown time.Duration) bool {
+	switch {
+	case rec.State == state:
+		return false
+	case quota.Severity(state) <= quota.Severity(quota.StateNormal):
+		return false
+	case quota.Severity(state) > quota.Severity(rec.NotifiedState):
+		return true
+	default:
+		return now.Sub(rec.NotifiedAt) >= coolDown
+	}
+}
+
+// groupQuotaManaged tells if the quota of a personal space may be replaced with a group default quota.
+// That is the case as long as it was not changed since it was set from the default or group quotas.
+func groupQuotaManaged(rec spaceQuotaRecord, current uint64, defaultQuota string) bool {
+	if rec.GroupQuota != nil {
+		return current == *rec.GroupQuota
+	}
+	return defaultQuota != "" && strconv.FormatUint(current, 10) == defaultQuota
+}
+
+// StartListenForQuotaEvents watches the changes of the space usage to notify about quota state changes and
+// applies the group default quotas to personal spaces. It needs the nats key value store to keep track of
+// the quota states.
+func (g *Graph) StartListenForQuotaEvents(ctx context.Context, l log.Logger) error {
+	notify, groupQuotas := g.config.Spaces.QuotaNotificationsEnabled, len(g.config.Spaces.GroupQuotas) > 0
+	if g.eventsConsumer == nil || (!notify && !groupQuotas) {
+		return nil
+	}
+	if g.natskv == nil {
+		l.Warn().Msg("quota notifications and group quotas require the nats key value store, they are disabled")
+		return nil
+	}
+
+	var registeredEvents []events.Unmarshaller
+	if notify {
+		registeredEvents = append(registeredEvents,
+			events.UploadReady{},
+			events.ItemRestored{},
+			events.ItemPurged{},
+			events.TrashbinPurged{},
+			events.FileVersionRestored{},
+			events.SpaceUpdated{},
+		)
+	}
+	if groupQuotas {
+		registeredEvents = append(registeredEvents,
+			events.SpaceCreated{},
+			events.GroupMemberAdded{},
+			events.GroupMemberRemoved{},
+		)
+	}
+	evChannel, err := events.Consume(g.eventsConsumer, "graph-quota", registeredEvents...)
+	if err != nil {
+		l.Error().Err(err).Msg("cannot consume from nats")
+		return err
+	}
+	go func() {
+		for loop := true; loop; {
+			select {
+			case e := <-evChannel:
+				switch ev := e.Event.(type) {
+				default:
+					l.Error().Interface("event", e).Msg("unhandled event")
+				case events.UploadReady:
+					if !ev.Failed {
+						g.checkSpaceQuotaState(ctx, refSpaceID(ev.FileRef))
+					}
+				case events.ItemRestored:
+					g.checkSpaceQuotaState(ctx, refSpaceID(ev.Ref))
+				case events.ItemPurged:
+					g.checkSpaceQuotaState(ctx, refSpaceID(ev.Ref))
+				case events.TrashbinPurged:
+					g.checkSpaceQuotaState(ctx, refSpaceID(ev.Ref))
+				case events.FileVersionRestored:
+					g.checkSpaceQuotaState(ctx, refSpaceID(ev.Ref))
+				case events.SpaceUpdated:
+					g.checkSpaceQuotaState(ctx, ev.ID.GetOpaqueId())
+				case events.SpaceCreated:
+					if ev.Type == _spaceTypePersonal {
+						g.applyGroupQuota(ctx, ev.Owner.GetOpaqueId(), true)
+					}
+				case events.GroupMemberAdded:
+					g.applyGroupQuota(ctx, ev.UserID, false)
+				case events.GroupMemberRemoved:
+					g.applyGroupQuota(ctx, ev.UserID, false)
+				}
+			case <-ctx.Done():
+				l.Info().Msg("context cancelled")
+				loop = false
+			}
+		}
+	}()
+	return nil
+}
+
+// checkSpaceQuotaState compares the quota state of the space to the last known state and
+// emits a SpaceQuotaStateChanged event if the change needs to be notified. Only the quota of the
+// space is requested, the space itself is only listed when a notification is sent.
+func (g Graph) checkSpaceQuotaState(ctx context.Context, spaceID string) {
+	logger := g.logger.With().Str("space", spaceID).Logger()
+	root, err := storagespace.ParseID(spaceID)
+	if err != nil || root.GetSpaceId() == "" {
+		return
+	}
+	root.OpaqueId = root.GetSpaceId()
+
+	ctx, gatewayClient, err := g.serviceAccountContext(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("quota notifications: could not authenticate the service account")
+		return
+	}
+
+	qta, err := g.getDriveQuota(ctx, &storageprovider.StorageSpace{Root: &root})
+	if err != nil || qta.State == nil || qta.GetTotal() <= 0 {
+		// spaces without a quota have no meaningful quota state
+		return
+	}
+	state := qta.GetState()
+
+	key := spaceQuotaKey(spaceID)
+	for attempt := 0; attempt < _spaceQuotaUpdateAttempts; attempt++ {
+		rec, revision, err := g.getSpaceQuotaRecord(ctx, key)
+		if err != nil {
+			logger.Error().Err(err).Msg("quota notifications: could not read the quota state")
+			return
+		}
+		if rec.State == state {
+			return
+		}
+
+		previous := rec.State
+		now := time.Now()
+		notify := shouldNotifyQuotaState(rec, state, now, g.config.Spaces.QuotaNotificationsCoolDown)
+		var space *storageprovider.StorageSpace
+		if notify {
+			spaces, err := listSpacesUnrestricted(ctx, gatewayClient, &storageprovider.ListStorageSpacesRequest_Filter{
+				Type: storageprovider.ListStorageSpacesRequest_Filter_TYPE_ID,
+				Term: &storageprovider.ListStorageSpacesRequest_Filter_Id{Id: &storageprovider.StorageSpaceId{OpaqueId: spaceID}},
+			})
+			if err != nil || len(spaces) != 1 {
+				logger.Debug().Err(err).Msg("quota notifications: could not get the space")
+				return
+			}
+			space = spaces[0]
+			if t := space.GetSpaceType(); t != _spaceTypePersonal && t != _spaceTypeProject {
+				return
+			}
+			rec.NotifiedState, rec.NotifiedAt = state, now
+		}
+		rec.State = state
+
+		// the revision makes sure only one graph instance notifies about the change
+		err = g.putSpaceQuotaRecord(ctx, key, rec, revision)
+		switch {
+		case errors.Is(err, jetstream.ErrKeyExists):
+			logger.Debug().Err(err).Msg("quota notifications: the quota state was changed concurrently")
+			continue
+		case err != nil:
+			logger.Error().Err(err).Msg("quota notifications: could not store the quota state")
+			return
+		case !notify:
+			return
+		}
+
+		ev := quota.SpaceQuotaStateChanged{
+			SpaceID:       space.GetId(),
+			SpaceName:     space.GetName(),
+			SpaceType:     space.GetSpaceType(),
+			Owner:         space.GetOwner().GetId(),
+			PreviousState: previous,
+			State:         state,
+			Used:          qta.GetUsed(),
+			Total:         qta.GetTotal(),
+			Timestamp:     utils.TSNow(),
+		}
+		if space.GetSpaceType() == _spaceTypeProject {
+			ev.Owner = nil
+			ev.Managers, ev.ManagerGroups = g.spaceManagers(space)
+		}
+		if err := events.Publish(ctx, g.eventsPublisher, ev); err != nil {
+			logger.Error().Err(err).Msg("quota notifications: could not publish the event")
+		}
+		return
+	}
+}
+
+// spaceManagers returns the users and groups with the manager role from the grants of a space
+func (g Graph) spaceManagers(space *storageprovider.StorageSpace) ([]*userpb.UserId, []*grouppb.GroupId) {
+	var (
+		grants map[string]*storageprovider.ResourcePermissions
+		groups map[string]struct{}
+	)
+	for key, target := range map[string]any{"grants": &grants, "groups": &groups} {
+		if entry, ok := space.GetOpaque().GetMap()[key]; ok {
+			if err := json.Unmarshal(entry.GetValue(), target); err != nil {
+				g.logger.Debug().Err(err).Str("space", space.GetId().GetOpaqueId()).Str("key", key).Msg("could not read space opaque")
+			}
+		}
+	}
+
+	var (
+		users     []*userpb.UserId
+		groupsIDs []*grouppb.GroupId
+	)
+	for id, permissions := range grants {
+		role := unifiedrole.CS3ResourcePermissionsToRole(g.availableRoles, permissions, unifiedrole.UnifiedRoleConditionDrive, false)
+		if role == nil || role.GetId() != unifiedrole.UnifiedRoleManagerID {
+			continue
+		}
+		if _, ok := groups[id]; ok {
+			groupsIDs = append(groupsIDs, &grouppb.GroupId{OpaqueId: id})
+			continue
+		}
+		users = append(users, &userpb.UserId{OpaqueId: id})
+	}
+	return users, groupsIDs
+}
+
+// applyGroupQuota sets the quota of the personal space of the user to the largest default quota of the
+// user's groups, or back to the default quota if none of the groups has one. Quotas that were changed
+// manually are kept, new spaces always get the group default quota.
+func (g Graph) applyGroupQuota(ctx context.Context, userID string, created bool) {
+	logger := g.logger.With().Str("userid", userID).Logger()
+	if userID == "" {
+		return
+	}
+	ctx, gatewayClient, err := g.serviceAccountContext(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("group quotas: could not authenticate the service account")
+		return
+	}
+
+	res, err := gatewayClient.GetUserGroups(ctx, &userpb.GetUserGroupsRequest{UserId: &userpb.UserId{OpaqueId: userID}})
+	if errCode := errorcode.FromCS3Status(res.GetStatus(), err); errCode != nil {
+		logger.Debug().Err(errCode).Msg("group quotas: could not get the groups of the user")
+		return
+	}
+	groupQuota, hasGroupQuota := quota.DefaultForGroups(g.config.Spaces.GroupQuotas, res.GetGroups())
+	if created && !hasGroupQuota {
+		return
+	}
+
+	spaces, err := listSpacesUnrestricted(ctx, gatewayClient,
+		&storageprovider.ListStorageSpacesRequest_Filter{
+			Type: storageprovider.ListStorageSpacesRequest_Filter_TYPE_OWNER,
+			Term: &storageprovider.ListStorageSpacesRequest_Filter_Owner{Owner: &userpb.UserId{OpaqueId: userID}},
+		},
+		&storageprovider.ListStorageSpacesRequest_Filter{
+			Type: storageprovider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+			Term: &storageprovider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: _spaceTypePersonal},
+		},
+	)
+	if err != nil || len(spaces) != 1 {
+		// the personal space is created with the first login
+		logger.Debug().Err(err).Msg("group quotas: could not get the personal space")
+		return
+	}
+	space := spaces[0]
+	current := space.GetQuota().GetQuotaMaxBytes()
+
+	key := spaceQuotaKey(space.GetId().GetOpaqueId())
+	rec, revision, err := g.getSpaceQuotaRecord(ctx, key)
+	if err != nil {
+		logger.Error().Err(err).Msg("group quotas: could not read the quota record")
+		return
+	}
+	if !created && !groupQuotaManaged(rec, current, g.config.Spaces.DefaultQuota) {
+		logger.Debug().Uint64("quota", current).Msg("group quotas: keeping the manually changed quota")
+		return
+	}
+
+	target := groupQuota
+	rec.GroupQuota = &groupQuota
+	if !hasGroupQuota {
+		defaultQuota, err := strconv.ParseUint(g.config.Spaces.DefaultQuota, 10, 64)
+		if err != nil {
+			return
+		}
+		target, rec.GroupQuota = defaultQuota, nil
+	}
+
+	if target != current {
+		updateRes, err := gatewayClient.UpdateStorageSpace(ctx, &storageprovider.UpdateStorageSpaceRequest{
+			StorageSpace: &storageprovider.StorageSpace{
+				Id:    space.GetId(),
+				Root:  space.GetRoot(),
+				Quota: &storageprovider.Quota{QuotaMaxBytes: target},
+			},
+		})
+		if errCode := errorcode.FromCS3Status(updateRes.GetStatus(), err); errCode != nil {
+			logger.Error().Err(errCode).Uint64("quota", target).Msg("group quotas: could not update the quota")
+			return
+		}
+	}
+	if err := g.putSpaceQuotaRecord(ctx, key, rec, revision); err != nil {
+		logger.Error().Err(err).Msg("group quotas: could not store the quota record")
+	}
+}
+
+// getSpaceQuotaRecord returns the record of the space and its revision, which is 0 for new records
+func (g Graph) getSpaceQuotaRecord(ctx context.Context, key string) (spaceQuotaRecord, uint64, error) {
+	rec := spaceQuotaRecord{}
+	entry, err := g.natskv.Get(ctx, key)
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		return rec, 0, nil
+	case err != nil:
+		return rec, 0, err
+	}
+	err = json.Unmarshal(entry.Value(), &rec)
+	return rec, entry.Revision(), err
+}
+
+// putSpaceQuotaRecord stores the record if it was not changed since it was read with the given revision.
+// It returns an error matching jetstream.ErrKeyExists otherwise.
+func (g Graph) putSpaceQuotaRecord(ctx context.Context, key string, rec spaceQuotaRecord, revision uint64) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if revision == 0 {
+		_, err = g.natskv.Create(ctx, key, data)
+		return err
+	}
+	_, err = g.natskv.Update(ctx, key, data, revision)
+	return err
+}
+
+// spaceQuotaKey returns the key of a space in the nats key value store, which does not allow
+// the separators of space ids in keys
+func spaceQuotaKey(spaceID string) string {
+	return _spaceQuotaKeyPrefix + base64.RawURLEncoding.EncodeToString([]byte(spaceID))
+}
+
+// refSpaceID returns the id of the space the reference points into
+func refSpaceID(ref *storageprovider.Reference) string {
+	rid := ref.GetResourceId()
+	if rid.GetSpaceId() == "" {
+		return ""
+	}
+	return storagespace.FormatStorageID(rid.GetStorageId(), rid.GetSpaceId())
+}
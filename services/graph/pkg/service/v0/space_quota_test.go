@@ -0,0 +1,83 @@
+package svc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/quota"
+)
+
+func TestShouldNotifyQuotaState(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	coolDown := 24 * time.Hour
+
+	tests := []struct {
+		name  string
+		rec   spaceQuotaRecord
+		state string
+		want  bool
+	}{
+		{"unchanged state", spaceQuotaRecord{State: quota.StateNearing, NotifiedState: quota.StateNearing, NotifiedAt: now.Add(-48 * time.Hour)}, quota.StateNearing, false},
+		{"back to normal", spaceQuotaRecord{State: quota.StateNearing, NotifiedState: quota.StateNearing, NotifiedAt: now.Add(-48 * time.Hour)}, quota.StateNormal, false},
+		{"first warning", spaceQuotaRecord{}, quota.StateNearing, true},
+		{"escalation within cool-down", spaceQuotaRecord{State: quota.StateNearing, NotifiedState: quota.StateNearing, NotifiedAt: now.Add(-time.Hour)}, quota.StateExceeded, true},
+		{"repeated warning within cool-down", spaceQuotaRecord{State: quota.StateNormal, NotifiedState: quota.StateNearing, NotifiedAt: now.Add(-time.Hour)}, quota.StateNearing, false},
+		{"repeated warning after cool-down", spaceQuotaRecord{State: quota.StateNormal, NotifiedState: quota.StateNearing, NotifiedAt: now.Add(-25 * time.Hour)}, quota.StateNearing, true},
+		{"less severe within cool-down", spaceQuotaRecord{State: quota.StateExceeded, NotifiedState: quota.StateExceeded, NotifiedAt: now.Add(-time.Hour)}, quota.StateCritical, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, shouldNotifyQuotaState(tt.rec, tt.state, now, coolDown))
+		})
+	}
+}
+
+func TestGroupQuotaManaged(t *testing.T) {
+	applied := uint64(5000)
+
+	assert.True(t, groupQuotaManaged(spaceQuotaRecord{GroupQuota: &applied}, 5000, "1000"))
+	assert.False(t, groupQuotaManaged(spaceQuotaRecord{GroupQuota: &applied}, 7000, "1000"), "manually changed quotas are kept")
+	assert.True(t, groupQuotaManaged(spaceQuotaRecord{}, 1000, "1000"))
+	assert.False(t, groupQuotaManaged(spaceQuotaRecord{}, 2000, "1000"))
+	assert.False(t, groupQuotaManaged(spaceQuotaRecord{}, 0, ""))
+}
+
+// revisionKeyValue records the creates and updates of the key value store
+type revisionKeyValue struct {
+	jetstream.KeyValue
+	created  []string
+	updated  []uint64
+	conflict bool
+}
+
+func (kv *revisionKeyValue) Create(_ context.Context, key string, _ []byte, _ ...jetstream.KVCreateOpt) (uint64, error) {
+	kv.created = append(kv.created, key)
+	return 1, nil
+}
+
+func (kv *revisionKeyValue) Update(_ context.Context, _ string, _ []byte, revision uint64) (uint64, error) {
+	if kv.conflict {
+		return 0, jetstream.ErrKeyExists
+	}
+	kv.updated = append(kv.updated, revision)
+	return revision + 1, nil
+}
+
+func TestPutSpaceQuotaRecord(t *testing.T) {
+	kv := &revisionKeyValue{}
+	g := Graph{natskv: kv}
+
+	assert.NoError(t, g.putSpaceQuotaRecord(context.Background(), "spacequota.new", spaceQuotaRecord{}, 0))
+	assert.Equal(t, []string{"spacequota.new"}, kv.created)
+
+	assert.NoError(t, g.putSpaceQuotaRecord(context.Background(), "spacequota.existing", spaceQuotaRecord{}, 7))
+	assert.Equal(t, []uint64{7}, kv.updated)
+
+	kv.conflict = true
+	err := g.putSpaceQuotaRecord(context.Background(), "spacequota.existing", spaceQuotaRecord{}, 7)
+	assert.ErrorIs(t, err, jetstream.ErrKeyExists)
+}
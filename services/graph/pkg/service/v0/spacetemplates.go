@@ -3,18 +3,23 @@ package svc
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
+	"path"
 	"path/filepath"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	v1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
 	"github.com/opencloud-eu/opencloud/pkg/l10n"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/errorcode"
 	l10n_pkg "github.com/opencloud-eu/opencloud/services/graph/pkg/l10n"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/metadata"
 	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/opencloud-eu/reva/v2/pkg/tags"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
 )
 
@@ -41,17 +46,116 @@ var (
 	TemplateParameter = "template"
 )
 
-func (g Graph) applySpaceTemplate(ctx context.Context, gwc gateway.GatewayAPIClient, root *storageprovider.ResourceId, template string, locale string) error {
-	switch template {
+// isBuiltinSpaceTemplate returns true for the templates which are not stored in the template store
+func isBuiltinSpaceTemplate(template string) bool {
+	return template == "" || template == "none" || template == "default"
+}
+
+// getSpaceTemplate returns the admin defined template with the given name
+func (g Graph) getSpaceTemplate(ctx context.Context, template string) (*SpaceTemplate, error) {
+	if !_spaceTemplateNameRegex.MatchString(template) {
+		return nil, errorcode.New(errorcode.InvalidRequest, "invalid space template name")
+	}
+	if g.spaceTemplates == nil {
+		return nil, errorcode.New(errorcode.InvalidRequest, fmt.Sprintf("unknown space template '%s'", template))
+	}
+	t, err := g.spaceTemplates.GetTemplate(ctx, template)
+	switch {
+	case errors.Is(err, ErrSpaceTemplateNotFound):
+		return nil, errorcode.New(errorcode.InvalidRequest, fmt.Sprintf("unknown space template '%s'", template))
+	case err != nil:
+		return nil, errorcode.New(errorcode.GeneralException, err.Error())
+	}
+	return &t, nil
+}
+
+func (g Graph) applySpaceTemplate(ctx context.Context, gwc gateway.GatewayAPIClient, root *storageprovider.ResourceId, template string, spaceTemplate *SpaceTemplate, locale string) error {
+	switch {
+	case spaceTemplate != nil:
+		return g.applyCustomTemplate(ctx, gwc, root, spaceTemplate)
+	case template == "default":
+		return g.applyDefaultTemplate(ctx, gwc, root, locale)
 	default:
-		fallthrough
-	case "none":
 		return nil
-	case "default":
-		return g.applyDefaultTemplate(ctx, gwc, root, locale)
 	}
 }
 
+// applyCustomTemplate creates the folders and files of an admin defined template in the space,
+// tags the space and shares it with the member groups. The description and quota of the template
+// are already set when creating the space.
+func (g Graph) applyCustomTemplate(ctx context.Context, gwc gateway.GatewayAPIClient, root *storageprovider.ResourceId, t *SpaceTemplate) error {
+	mdc := metadata.NewCS3(g.config.Reva.Address, g.config.Spaces.StorageUsersAddress)
+	mdc.SpaceRoot = root
+
+	created := map[string]struct{}{}
+	for _, folder := range t.Folders {
+		if err := makeDirAll(ctx, mdc, folder, created); err != nil {
+			return err
+		}
+	}
+	for _, f := range t.Files {
+		if err := makeDirAll(ctx, mdc, path.Dir(f.Path), created); err != nil {
+			return err
+		}
+		content, err := g.spaceTemplates.GetTemplateFile(ctx, t.Name, f.Path)
+		if err != nil {
+			return fmt.Errorf("could not read the template file '%s': %w", f.Path, err)
+		}
+		if err := mdc.SimpleUpload(ctx, f.Path, content); err != nil {
+			return err
+		}
+	}
+
+	if len(t.Tags) > 0 {
+		resp, err := gwc.SetArbitraryMetadata(ctx, &storageprovider.SetArbitraryMetadataRequest{
+			Ref: &storageprovider.Reference{ResourceId: root},
+			ArbitraryMetadata: &storageprovider.ArbitraryMetadata{
+				Metadata: map[string]string{
+					"tags": tags.New(t.Tags...).AsList(),
+				},
+			},
+		})
+		switch {
+		case err != nil:
+			return err
+		case resp.GetStatus().GetCode() != rpc.Code_CODE_OK:
+			return fmt.Errorf("could not tag storage space: %s", resp.GetStatus().GetMessage())
+		}
+	}
+
+	for _, m := range t.Members {
+		_, err := g.driveItemPermissions.Invite(ctx, root, libregraph.DriveItemInvite{
+			Recipients: []libregraph.DriveRecipient{{
+				ObjectId:                libregraph.PtrString(m.GroupID),
+				LibreGraphRecipientType: libregraph.PtrString("group"),
+			}},
+			Roles: []string{m.Role},
+		})
+		if err != nil {
+			return fmt.Errorf("could not add the group '%s' to the space: %w", m.GroupID, err)
+		}
+	}
+	return nil
+}
+
+// makeDirAll creates the folder and its parents in the space
+func makeDirAll(ctx context.Context, mdc *metadata.CS3, folder string, created map[string]struct{}) error {
+	if folder == "." || folder == "/" || folder == "" {
+		return nil
+	}
+	if _, ok := created[folder]; ok {
+		return nil
+	}
+	if err := makeDirAll(ctx, mdc, path.Dir(folder), created); err != nil {
+		return err
+	}
+	if err := mdc.MakeDirIfNotExist(ctx, folder); err != nil {
+		return err
+	}
+	created[folder] = struct{}{}
+	return nil
+}
+
 func (g Graph) applyDefaultTemplate(ctx context.Context, gwc gateway.GatewayAPIClient, root *storageprovider.ResourceId, locale string) error {
 	mdc := metadata.NewCS3(g.config.Reva.Address, g.config.Spaces.StorageUsersAddress)
 	mdc.SpaceRoot = root
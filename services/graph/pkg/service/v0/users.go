@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/CiscoM31/godata"
+	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	invitepb "github.com/cs3org/go-cs3apis/cs3/ocm/invite/v1beta1"
 	cs3rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
@@ -230,7 +231,7 @@ func (g Graph) GetUsers(w http.ResponseWriter, r *http.Request) {
 	logger := g.logger.SubloggerWithRequestID(r.Context())
 	logger.Debug().Interface("query", r.URL.Query()).Msg("calling get users")
 	sanitizedPath := strings.TrimPrefix(r.URL.Path, "/graph/v1.0/")
-	odataReq, err := godata.ParseRequest(r.Context(), sanitizedPath, r.URL.Query())
+	odataReq, pg, err := parsePagedRequest(r.Context(), sanitizedPath, r.URL.Query())
 	if err != nil {
 		logger.Debug().Err(err).Interface("query", r.URL.Query()).Msg("could not get users: query error")
 		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
@@ -280,9 +281,17 @@ func (g Graph) GetUsers(w http.ResponseWriter, r *http.Request) {
 
 	var users []*libregraph.User
 
-	if odataReq.Query.Filter != nil {
+	// let the backend page through the users, unless they need to be filtered or sorted first
+	pagingBackend, backendPaging := g.identityBackend.(identity.PagingBackend)
+	backendPaging = backendPaging && pg.top > 0 && odataReq.Query.Filter == nil && odataReq.Query.OrderBy == nil
+
+	switch {
+	case odataReq.Query.Filter != nil:
 		users, err = g.applyUserFilter(r.Context(), odataReq, nil)
-	} else {
+	case backendPaging:
+		// request one more user to know if there is a next page
+		users, err = pagingBackend.GetUsersPage(r.Context(), odataReq, pg.skip, pg.top+1)
+	default:
 		users, err = g.identityBackend.GetUsers(r.Context(), odataReq)
 	}
 
@@ -319,6 +328,20 @@ func (g Graph) GetUsers(w http.ResponseWriter, r *http.Request) {
 		users = finalUsers
 	}
 
+	users, err = sortUsers(odataReq, users)
+	if err != nil {
+		logger.Debug().Interface("query", odataReq).Msg("error while sorting users according to query")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var hasNextPage bool
+	if backendPaging {
+		users, hasNextPage = paginate(users, page{top: pg.top})
+	} else {
+		users, hasNextPage = paginate(users, pg)
+	}
+
 	exp, err := odata.GetExpandValues(odataReq.Query)
 	if err != nil {
 		logger.Debug().Err(err).Interface("query", r.URL.Query()).Msg("could not get users: $expand error")
@@ -343,14 +366,12 @@ func (g Graph) GetUsers(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	users, err = sortUsers(odataReq, users)
-	if err != nil {
-		logger.Debug().Interface("query", odataReq).Msg("error while sorting users according to query")
-		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
-		return
+	res := &ListResponse{Value: users}
+	if hasNextPage {
+		res.NextLink = g.nextLink(r, pg)
 	}
 	render.Status(r, http.StatusOK)
-	render.JSON(w, r, &ListResponse{Value: users})
+	render.JSON(w, r, res)
 }
 
 // PostUser implements the Service interface.
@@ -414,19 +435,9 @@ func (g Graph) PostUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// assign roles if possible
-	if g.roleService != nil && g.config.API.AssignDefaultUserRole {
-		// All users get the user role by default currently.
-		// to all new users for now, as create Account request does not have any role field
-		if _, err = g.roleService.AssignRoleToUser(r.Context(), &settingssvc.AssignRoleToUserRequest{
-			AccountUuid: *u.Id,
-			RoleId:      ocsettingssvc.BundleUUIDRoleUser,
-		}); err != nil {
-			// log as error, admin eventually needs to do something
-			logger.Error().Err(err).Str("id", *u.Id).Str("role", ocsettingssvc.BundleUUIDRoleUser).Msg("could not create user: role assignment failed")
-			errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "role assignment failed")
-			return
-		}
+	if err := g.assignDefaultUserRole(r.Context(), u.GetId()); err != nil {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "role assignment failed")
+		return
 	}
 
 	e := events.UserCreated{UserID: *u.Id}
@@ -439,6 +450,24 @@ func (g Graph) PostUser(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, u)
 }
 
+// assignDefaultUserRole assigns the default role to a newly created user if configured
+func (g Graph) assignDefaultUserRole(ctx context.Context, userID string) error {
+	if g.roleService == nil || !g.config.API.AssignDefaultUserRole {
+		return nil
+	}
+	// All users get the user role by default currently.
+	// to all new users for now, as create Account request does not have any role field
+	if _, err := g.roleService.AssignRoleToUser(ctx, &settingssvc.AssignRoleToUserRequest{
+		AccountUuid: userID,
+		RoleId:      ocsettingssvc.BundleUUIDRoleUser,
+	}); err != nil {
+		// log as error, admin eventually needs to do something
+		g.logger.Error().Err(err).Str("id", userID).Str("role", ocsettingssvc.BundleUUIDRoleUser).Msg("could not create user: role assignment failed")
+		return err
+	}
+	return nil
+}
+
 // GetUser implements the Service interface.
 func (g Graph) GetUser(w http.ResponseWriter, r *http.Request) {
 	logger := g.logger.SubloggerWithRequestID(r.Context())
@@ -688,6 +717,20 @@ func (g Graph) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := g.deleteUser(r.Context(), user, us, purgeUser, currentUser.GetId()); err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	render.Status(r, http.StatusNoContent)
+	render.NoContent(w, r)
+}
+
+// deleteUser deletes or soft deletes the user depending on the configured retention time and the state of
+// the user. The personal space of the user is deleted along with the user.
+func (g Graph) deleteUser(ctx context.Context, user *libregraph.User, us userstate.UserState, purgeUser bool, executant *userv1beta1.UserId) error {
+	logger := g.logger.SubloggerWithRequestID(ctx)
+
+	var stateErr error
 	if g.gatewaySelector != nil {
 		logger.Debug().
 			Str("user", user.GetId()).
@@ -697,18 +740,16 @@ func (g Graph) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		client, err := g.gatewaySelector.Next()
 		if err != nil {
 			logger.Error().Err(err).Msg("error selecting next gateway client")
-			errorcode.ServiceNotAvailable.Render(w, r, http.StatusInternalServerError, "error selecting next gateway client, aborting")
-			return
+			return errorcode.New(errorcode.ServiceNotAvailable, "error selecting next gateway client, aborting")
 		}
-		lspr, err := client.ListStorageSpaces(r.Context(), &storageprovider.ListStorageSpacesRequest{
+		lspr, err := client.ListStorageSpaces(ctx, &storageprovider.ListStorageSpacesRequest{
 			Opaque:  opaque,
 			Filters: []*storageprovider.ListStorageSpacesRequest_Filter{f},
 		})
 		if err != nil {
 			// transport error, log as error
 			logger.Error().Err(err).Msg("could not fetch spaces: transport error")
-			errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not fetch spaces for deletion, aborting")
-			return
+			return errorcode.New(errorcode.GeneralException, "could not fetch spaces for deletion, aborting")
 		}
 		for _, sp := range lspr.GetStorageSpaces() {
 			// if the spacetype equals _spaceTypePersonal and the owner id equals the user id
@@ -723,21 +764,20 @@ func (g Graph) DeleteUser(w http.ResponseWriter, r *http.Request) {
 			// Deleting a space a two step process (1. disabling/trashing, 2. purging)
 			// Do the "disable/trash" step only if the space is not marked as trashed yet:
 			if _, ok := sp.Opaque.Map[_spaceStateTrashed]; !ok {
-				_, err := client.DeleteStorageSpace(r.Context(), &storageprovider.DeleteStorageSpaceRequest{
+				_, err := client.DeleteStorageSpace(ctx, &storageprovider.DeleteStorageSpaceRequest{
 					Id: &storageprovider.StorageSpaceId{
 						OpaqueId: sp.Id.OpaqueId,
 					},
 				})
 				if err != nil {
 					logger.Error().Err(err).Msg("could not disable homespace: transport error")
-					errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not disable homespace, aborting")
-					return
+					return errorcode.New(errorcode.GeneralException, "could not disable homespace, aborting")
 				}
 			}
 			// the space will if the system does not have a UserSoftDeleteRetentionTime configured, e.g. SoftDelete disabled
 			if g.config.UserSoftDeleteRetentionTime == 0 || (purgeUser && us.State == userstate.UserStateSoftDeleted) {
 				purgeSpaceFlag := utils.AppendPlainToOpaque(nil, "purge", "")
-				_, err := client.DeleteStorageSpace(r.Context(), &storageprovider.DeleteStorageSpaceRequest{
+				_, err := client.DeleteStorageSpace(ctx, &storageprovider.DeleteStorageSpaceRequest{
 					Opaque: purgeSpaceFlag,
 					Id: &storageprovider.StorageSpaceId{
 						OpaqueId: sp.Id.OpaqueId,
@@ -746,8 +786,7 @@ func (g Graph) DeleteUser(w http.ResponseWriter, r *http.Request) {
 				if err != nil {
 					// transport error, log as error
 					logger.Error().Err(err).Msg("could not delete homespace: transport error")
-					errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not delete homespace, aborting")
-					return
+					return errorcode.New(errorcode.GeneralException, "could not delete homespace, aborting")
 				}
 			}
 			break
@@ -757,18 +796,16 @@ func (g Graph) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	if (g.config.UserSoftDeleteRetentionTime > 0 && us.State == userstate.UserStateSoftDeleted && purgeUser) ||
 		(g.config.UserSoftDeleteRetentionTime == 0) {
 		logger.Debug().Str("id", user.GetId()).Msg("calling delete user on backend")
-		err = g.identityBackend.DeleteUser(r.Context(), user.GetId())
+		err := g.identityBackend.DeleteUser(ctx, user.GetId())
 		if err != nil {
 			logger.Debug().Err(err).Msg("could not delete user: backend error")
-			errorcode.RenderError(w, r, err)
-			return
+			return err
 		}
 
 		us.State = userstate.UserStateHardDeleted
-		err = g.setUserStateToNatsKeyValue(r.Context(), userID, us)
-		if err != nil {
-			logger.Error().Err(err).Str("id", userID).Msg("could not set user state")
-			errorcode.RenderError(w, r, err)
+		if err := g.setUserStateToNatsKeyValue(ctx, us.UserId, us); err != nil {
+			logger.Error().Err(err).Str("id", us.UserId).Msg("could not set user state")
+			stateErr = err
 		}
 	} else {
 		logger.Debug().Str("id", user.GetId()).Msg("calling soft delete user on backend")
@@ -778,20 +815,18 @@ func (g Graph) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		us.RetentionPeriod = g.config.UserSoftDeleteRetentionTime
 		us.Reason = "User soft deleted via Graph API" // TODO: this needs a proper implementation through the request
 		us.TimeStamp = time.Now()
-		err = g.setUserStateToNatsKeyValue(r.Context(), userID, us)
-		if err != nil {
-			logger.Error().Err(err).Str("id", userID).Msg("could not set user state")
-			errorcode.RenderError(w, r, err)
-			return
+		if err := g.setUserStateToNatsKeyValue(ctx, us.UserId, us); err != nil {
+			logger.Error().Err(err).Str("id", us.UserId).Msg("could not set user state")
+			return err
 		}
-		g.identityBackend.UpdateUser(r.Context(), user.GetId(), userUpdate)
+		g.identityBackend.UpdateUser(ctx, user.GetId(), userUpdate)
 	}
 
 	if g.config.UserSoftDeleteRetentionTime == 0 ||
 		(g.config.UserSoftDeleteRetentionTime > 0 && purgeUser && us.State == userstate.UserStateSoftDeleted) {
 		e := events.UserDeleted{UserID: user.GetId()}
-		e.Executant = currentUser.GetId()
-		g.publishEvent(r.Context(), e)
+		e.Executant = executant
+		g.publishEvent(ctx, e)
 	} else {
 		e := events.UserSoftDeleted{
 			UserID:        user.GetId(),
@@ -802,11 +837,10 @@ func (g Graph) DeleteUser(w http.ResponseWriter, r *http.Request) {
 			},
 			Reason: "User deleted via Graph API", // TODO: this needs a proper implementation through the request
 		}
-		e.Executant = currentUser.GetId()
-		g.publishEvent(r.Context(), e)
+		e.Executant = executant
+		g.publishEvent(ctx, e)
 	}
-	render.Status(r, http.StatusNoContent)
-	render.NoContent(w, r)
+	return stateErr
 }
 
 // PatchMe implements the Service Interface. Updates the specified attributes of the current user
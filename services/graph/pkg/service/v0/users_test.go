@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"time"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
@@ -42,7 +43,8 @@ import (
 )
 
 type userList struct {
-	Value []*libregraph.User
+	Value    []*libregraph.User
+	NextLink string `json:"@odata.nextLink"`
 }
 
 var _ = Describe("Users", func() {
@@ -279,6 +281,56 @@ var _ = Describe("Users", func() {
 				Expect(len(res.Value)).To(Equal(1))
 				Expect(res.Value[0].GetId()).To(Equal("user1"))
 			})
+			It("pages through the users", func() {
+				permissionService.On("GetPermissionByID", mock.Anything, mock.Anything).Return(&settings.GetPermissionByIDResponse{
+					Permission: &settingsmsg.Permission{
+						Operation:  settingsmsg.Permission_OPERATION_UNKNOWN,
+						Constraint: settingsmsg.Permission_CONSTRAINT_ALL,
+					},
+				}, nil)
+
+				users := []*libregraph.User{}
+				for _, name := range []string{"Carol", "alice", "Bob"} {
+					user := libregraph.NewUser(name, strings.ToLower(name))
+					user.SetId(strings.ToLower(name))
+					users = append(users, user)
+				}
+				identityBackend.On("GetUsers", mock.Anything, mock.Anything, mock.Anything).Return(users, nil)
+
+				r := httptest.NewRequest(http.MethodGet, "/graph/v1.0/users?$orderby=displayName&$top=2", nil)
+				svc.GetUsers(rr, r)
+
+				Expect(rr.Code).To(Equal(http.StatusOK))
+				res := userList{}
+				Expect(json.Unmarshal(rr.Body.Bytes(), &res)).To(Succeed())
+				Expect(len(res.Value)).To(Equal(2))
+				Expect(res.Value[0].GetId()).To(Equal("alice"))
+				Expect(res.Value[1].GetId()).To(Equal("bob"))
+				Expect(res.NextLink).ToNot(BeEmpty())
+
+				next, err := url.Parse(res.NextLink)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(next.Query().Get("$top")).To(Equal("2"))
+
+				rr = httptest.NewRecorder()
+				r = httptest.NewRequest(http.MethodGet, next.RequestURI(), nil)
+				svc.GetUsers(rr, r)
+
+				Expect(rr.Code).To(Equal(http.StatusOK))
+				res = userList{}
+				Expect(json.Unmarshal(rr.Body.Bytes(), &res)).To(Succeed())
+				Expect(len(res.Value)).To(Equal(1))
+				Expect(res.Value[0].GetId()).To(Equal("carol"))
+				Expect(res.NextLink).To(BeEmpty())
+			})
+
+			It("rejects invalid skip tokens", func() {
+				r := httptest.NewRequest(http.MethodGet, "/graph/v1.0/users?$top=2&$skiptoken=invalid", nil)
+				svc.GetUsers(rr, r)
+
+				Expect(rr.Code).To(Equal(http.StatusBadRequest))
+			})
+
 			It("denies listing for unprivileged users", func() {
 				permissionService.On("GetPermissionByID", mock.Anything, mock.Anything).Return(&settings.GetPermissionByIDResponse{}, nil)
 				r := httptest.NewRequest(http.MethodGet, "/graph/v1.0/users", nil)
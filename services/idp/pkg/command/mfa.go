@@ -0,0 +1,95 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/opencloud-eu/opencloud/pkg/config/configlog"
+	"github.com/opencloud-eu/opencloud/pkg/registry"
+	ogrpc "github.com/opencloud-eu/opencloud/pkg/service/grpc"
+	"github.com/opencloud-eu/opencloud/pkg/tracing"
+	settingssvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/settings/v0"
+	"github.com/opencloud-eu/opencloud/services/idp/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/idp/pkg/config/parser"
+	"github.com/opencloud-eu/opencloud/services/idp/pkg/mfa"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/store"
+	"github.com/urfave/cli/v2"
+	microstore "go-micro.dev/v4/store"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ResetMFA is the entrypoint for the reset-mfa command.
+func ResetMFA(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:     "reset-mfa",
+		Usage:    "Remove the second factors of a user, who has to enrol again on the next login",
+		Category: "mfa",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "user-name",
+				Aliases:  []string{"u"},
+				Usage:    "User name",
+				Required: true,
+			},
+		},
+		Before: func(_ *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			traceProvider, err := tracing.GetServiceTraceProvider(cfg.Tracing, cfg.Service.Name)
+			if err != nil {
+				return err
+			}
+			manager, err := newMFAManager(cfg, traceProvider)
+			if err != nil {
+				return err
+			}
+			if err := manager.Reset(c.Context, c.String("user-name")); err != nil {
+				return fmt.Errorf("could not reset the second factors of '%s': %w", c.String("user-name"), err)
+			}
+			fmt.Printf("Removed the second factors of user '%s'.\n", c.String("user-name"))
+			return nil
+		},
+	}
+}
+
+// newMFAManager creates the manager of the second factors with its store and the clients to look
+// up the users and their roles.
+func newMFAManager(cfg *config.Config, traceProvider trace.TracerProvider) (*mfa.Manager, error) {
+	grpcClient, err := ogrpc.NewClient(
+		append(ogrpc.GetClientOptions(cfg.GRPCClientTLS), ogrpc.WithTraceProvider(traceProvider))...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tm, err := pool.StringToTLSMode(cfg.GRPCClientTLS.Mode)
+	if err != nil {
+		return nil, err
+	}
+	gatewaySelector, err := pool.GatewaySelector(
+		cfg.Reva.Address,
+		pool.WithTLSCACert(cfg.GRPCClientTLS.CACert),
+		pool.WithTLSMode(tm),
+		pool.WithRegistry(registry.GetRegistry()),
+		pool.WithTracerProvider(traceProvider),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get reva client selector: %s", err)
+	}
+
+	st := store.Create(
+		store.Store(cfg.MFA.Store.Store),
+		microstore.Nodes(cfg.MFA.Store.Nodes...),
+		microstore.Database(cfg.MFA.Store.Database),
+		microstore.Table(cfg.MFA.Store.Table),
+		store.Authentication(cfg.MFA.Store.AuthUsername, cfg.MFA.Store.AuthPassword),
+	)
+
+	users := mfa.CS3UserResolver{
+		GatewaySelector:   gatewaySelector,
+		MachineAuthAPIKey: cfg.MachineAuthAPIKey,
+		RoleService:       settingssvc.NewRoleService("eu.opencloud.api.settings", grpcClient),
+	}
+	return mfa.NewManager(mfa.NewStore(st), users, cfg.MFA), nil
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/opencloud-eu/opencloud/services/idp/pkg/config/parser"
 	"github.com/opencloud-eu/opencloud/services/idp/pkg/logging"
 	"github.com/opencloud-eu/opencloud/services/idp/pkg/metrics"
+	"github.com/opencloud-eu/opencloud/services/idp/pkg/mfa"
 	"github.com/opencloud-eu/opencloud/services/idp/pkg/server/debug"
 	"github.com/opencloud-eu/opencloud/services/idp/pkg/server/http"
 	"github.com/urfave/cli/v2"
@@ -69,6 +70,13 @@ func Server(cfg *config.Config) *cli.Command {
 			metrics := metrics.New()
 			metrics.BuildInfo.WithLabelValues(version.GetString()).Set(1)
 
+			var mfaManager *mfa.Manager
+			if cfg.MFA.Enabled {
+				if mfaManager, err = newMFAManager(cfg, traceProvider); err != nil {
+					return err
+				}
+			}
+
 			gr := runner.NewGroup()
 			{
 				server, err := http.Server(
@@ -77,6 +85,7 @@ func Server(cfg *config.Config) *cli.Command {
 					http.Config(cfg),
 					http.Metrics(metrics),
 					http.TraceProvider(traceProvider),
+					http.MFA(mfaManager),
 				)
 				if err != nil {
 					logger.Info().
@@ -19,7 +19,8 @@ type Config struct {
 
 	HTTP HTTP `yaml:"http"`
 
-	Reva *shared.Reva `yaml:"reva"`
+	Reva          *shared.Reva          `yaml:"reva"`
+	GRPCClientTLS *shared.GRPCClientTLS `yaml:"grpc_client_tls"`
 
 	MachineAuthAPIKey string `yaml:"machine_auth_api_key" env:"OC_MACHINE_AUTH_API_KEY;IDP_MACHINE_AUTH_API_KEY" desc:"Machine auth API key used to validate internal requests necessary for the access to resources from other services." introductionVersion:"1.0.0"`
 
@@ -27,6 +28,7 @@ type Config struct {
 	IDP     Settings `yaml:"idp"`
 	Clients []Client `yaml:"clients"`
 	Ldap    Ldap     `yaml:"ldap"`
+	MFA     MFA      `yaml:"mfa"`
 
 	Context context.Context `yaml:"-"`
 }
@@ -2,6 +2,7 @@ package defaults
 
 import (
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strings"
 
@@ -133,6 +134,16 @@ func DefaultConfig() *config.Config {
 			ObjectClass:          "inetOrgPerson",
 			UserEnabledAttribute: "openCloudUserEnabled",
 		},
+		MFA: config.MFA{
+			TOTPIssuer:    "OpenCloud",
+			RecoveryCodes: 10,
+			Store: config.MFAStore{
+				Store:    "nats-js-kv",
+				Nodes:    []string{"127.0.0.1:9233"},
+				Database: "idp",
+				Table:    "mfa",
+			},
+		},
 	}
 }
 
@@ -168,6 +179,10 @@ func EnsureDefaults(cfg *config.Config) {
 	if cfg.MachineAuthAPIKey == "" && cfg.Commons != nil && cfg.Commons.MachineAuthAPIKey != "" {
 		cfg.MachineAuthAPIKey = cfg.Commons.MachineAuthAPIKey
 	}
+
+	if cfg.GRPCClientTLS == nil && cfg.Commons != nil {
+		cfg.GRPCClientTLS = structs.CopyOrZeroValue(cfg.Commons.GRPCClientTLS)
+	}
 }
 
 // Sanitize sanitizes the configuration
@@ -176,4 +191,14 @@ func Sanitize(cfg *config.Config) {
 	if cfg.HTTP.Root != "/" {
 		cfg.HTTP.Root = strings.TrimSuffix(cfg.HTTP.Root, "/")
 	}
+
+	// the security keys are bound to the public url of OpenCloud unless configured otherwise
+	if u, err := url.Parse(cfg.IDP.Iss); err == nil && u.Host != "" {
+		if cfg.MFA.WebAuthnRPID == "" {
+			cfg.MFA.WebAuthnRPID = u.Hostname()
+		}
+		if len(cfg.MFA.WebAuthnOrigins) == 0 {
+			cfg.MFA.WebAuthnOrigins = []string{u.Scheme + "://" + u.Host}
+		}
+	}
 }
@@ -0,0 +1,22 @@
+package config
+
+// MFA defines the configuration of the second authentication factors.
+type MFA struct {
+	Enabled         bool     `yaml:"enabled" env:"IDP_MFA_ENABLED" desc:"Enable second authentication factors (TOTP, WebAuthn and recovery codes) for the login with username and password. Requires the 'ldap' or 'cs3' identity manager." introductionVersion:"%%NEXT%%"`
+	RequiredRoles   []string `yaml:"required_roles" env:"IDP_MFA_REQUIRED_ROLES" desc:"A list of role names like 'admin' whose members must log in with a second factor. Members of these roles are asked to enrol on their next login. Other users can enrol voluntarily. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	TOTPIssuer      string   `yaml:"totp_issuer" env:"IDP_MFA_TOTP_ISSUER" desc:"The issuer shown by authenticator apps for TOTP enrolments." introductionVersion:"%%NEXT%%"`
+	WebAuthnRPID    string   `yaml:"webauthn_rp_id" env:"IDP_MFA_WEBAUTHN_RP_ID" desc:"The WebAuthn relying party ID, usually the host name of OpenCloud. Security keys are bound to this ID. Defaults to the host name of OC_URL." introductionVersion:"%%NEXT%%"`
+	WebAuthnOrigins []string `yaml:"webauthn_origins" env:"IDP_MFA_WEBAUTHN_ORIGINS" desc:"A list of origins the WebAuthn ceremonies are accepted from. Defaults to OC_URL. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	RecoveryCodes   int      `yaml:"recovery_codes" env:"IDP_MFA_RECOVERY_CODES" desc:"The number of single-use recovery codes generated on enrolment." introductionVersion:"%%NEXT%%"`
+	Store           MFAStore `yaml:"store"`
+}
+
+// MFAStore configures the store holding the enrolments of the users.
+type MFAStore struct {
+	Store        string   `yaml:"store" env:"OC_PERSISTENT_STORE;IDP_MFA_STORE" desc:"The type of the store. Supported values are: 'memory', 'nats-js-kv', 'redis-sentinel'. Don't use the 'memory' store in production, it loses all enrolments on restart. See the text description for details." introductionVersion:"%%NEXT%%"`
+	Nodes        []string `yaml:"nodes" env:"OC_PERSISTENT_STORE_NODES;IDP_MFA_STORE_NODES" desc:"A list of nodes to access the configured store. This has no effect when 'memory' store is configured. Note that the behaviour how nodes are used is dependent on the library of the configured store. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	Database     string   `yaml:"database" env:"IDP_MFA_STORE_DATABASE" desc:"The database name the configured store should use." introductionVersion:"%%NEXT%%"`
+	Table        string   `yaml:"table" env:"IDP_MFA_STORE_TABLE" desc:"The database table the store should use." introductionVersion:"%%NEXT%%"`
+	AuthUsername string   `yaml:"username" env:"OC_PERSISTENT_STORE_AUTH_USERNAME;IDP_MFA_STORE_AUTH_USERNAME" desc:"The username to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT%%"`
+	AuthPassword string   `yaml:"password" env:"OC_PERSISTENT_STORE_AUTH_PASSWORD;IDP_MFA_STORE_AUTH_PASSWORD" desc:"The password to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT%%"`
+}
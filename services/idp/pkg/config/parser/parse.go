@@ -2,6 +2,7 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 
 	occfg "github.com/opencloud-eu/opencloud/pkg/config"
 	"github.com/opencloud-eu/opencloud/pkg/shared"
@@ -45,5 +46,17 @@ func Validate(cfg *config.Config) error {
 		}
 	}
 
+	if cfg.MFA.Enabled {
+		if cfg.IDP.IdentityManager != "ldap" && cfg.IDP.IdentityManager != "cs3" {
+			return fmt.Errorf("second factors are not supported with the '%s' identity manager", cfg.IDP.IdentityManager)
+		}
+		if cfg.MachineAuthAPIKey == "" {
+			return shared.MissingMachineAuthApiKeyError(cfg.Service.Name)
+		}
+		if cfg.MFA.WebAuthnRPID == "" || len(cfg.MFA.WebAuthnOrigins) == 0 {
+			return errors.New("the WebAuthn relying party ID and origins must be set when second factors are enabled")
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,323 @@
+// Package mfa implements the second authentication factors of the IDP: time-based one-time
+// passwords (TOTP), WebAuthn security keys and single-use recovery codes.
+package mfa
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/opencloud-eu/opencloud/services/idp/pkg/config"
+)
+
+// The second factor methods offered to the users
+const (
+	MethodTOTP     = "totp"
+	MethodWebAuthn = "webauthn"
+	MethodRecovery = "recovery"
+)
+
+const (
+	// _challengeTTL is the time a user has to complete the second factor step
+	_challengeTTL = 5 * time.Minute
+	// _maxFailures is the number of invalid second factors after which the step is locked until
+	// the challenge expires
+	_maxFailures = 5
+)
+
+// ErrLocked is returned when too many invalid second factors were sent.
+var ErrLocked = errors.New("too many invalid second factors")
+
+// Request is the second factor a client sends along with the username and password.
+type Request struct {
+	// Enrol asks to enrol a second factor even though it is not required
+	Enrol                bool                  `json:"enrol,omitempty"`
+	TOTP                 string                `json:"totp,omitempty"`
+	RecoveryCode         string                `json:"recoveryCode,omitempty"`
+	WebAuthn             *AssertionResponse    `json:"webauthn,omitempty"`
+	WebAuthnRegistration *RegistrationResponse `json:"webauthnRegistration,omitempty"`
+}
+
+// hasFactor checks whether the request carries a second factor.
+func (r *Request) hasFactor() bool {
+	return r != nil && (r.TOTP != "" || r.RecoveryCode != "" || r.WebAuthn != nil || r.WebAuthnRegistration != nil)
+}
+
+// Response tells the client which second factor to send, or, after an enrolment, the recovery codes.
+type Response struct {
+	// Required is set when the login needs a second factor
+	Required bool `json:"required,omitempty"`
+	// Enrol is set when the user has to enrol a second factor first
+	Enrol bool `json:"enrol,omitempty"`
+	// Invalid is set when the sent second factor was wrong
+	Invalid bool     `json:"invalid,omitempty"`
+	Methods []string `json:"methods,omitempty"`
+	// TOTPSecret and TOTPURI are the shared secret offered for the enrolment of an authenticator app
+	TOTPSecret string           `json:"totpSecret,omitempty"`
+	TOTPURI    string           `json:"totpUri,omitempty"`
+	WebAuthn   *WebAuthnOptions `json:"webauthn,omitempty"`
+	// RecoveryCodes are shown once after the enrolment
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+// WebAuthnOptions are the parameters of the WebAuthn ceremony the client has to run.
+type WebAuthnOptions struct {
+	Challenge string `json:"challenge"`
+	RPID      string `json:"rpId"`
+	// UserID is the base64url encoded user handle of a new credential
+	UserID   string `json:"userId,omitempty"`
+	UserName string `json:"userName,omitempty"`
+	// Credentials are the ids of the registered credentials
+	Credentials []string `json:"credentials,omitempty"`
+}
+
+// Manager decides whether a login needs a second factor and verifies it.
+type Manager struct {
+	store *Store
+	users UserResolver
+	rp    RelyingParty
+	cfg   config.MFA
+	now   func() time.Time
+	locks userLocks
+}
+
+// userLocks serializes the logins of a user, the store has no atomic updates and parallel logins
+// must not pass the lockout or use a one-time password or a recovery code twice.
+type userLocks struct {
+	mu    sync.Mutex
+	locks map[string]*userLock
+}
+
+type userLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks the user and returns the function to unlock it
+func (l *userLocks) lock(userID string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*userLock{}
+	}
+	ul, ok := l.locks[userID]
+	if !ok {
+		ul = &userLock{}
+		l.locks[userID] = ul
+	}
+	ul.refs++
+	l.mu.Unlock()
+
+	ul.Lock()
+	return func() {
+		ul.Unlock()
+		l.mu.Lock()
+		if ul.refs--; ul.refs == 0 {
+			delete(l.locks, userID)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// NewManager returns a new Manager.
+func NewManager(store *Store, users UserResolver, cfg config.MFA) *Manager {
+	return &Manager{
+		store: store,
+		users: users,
+		rp:    RelyingParty{ID: cfg.WebAuthnRPID, Origins: cfg.WebAuthnOrigins},
+		cfg:   cfg,
+		now:   time.Now,
+	}
+}
+
+// Authenticate is called after the password of a user was verified. It returns true when the login
+// may proceed, either because no second factor is needed or because a valid one was sent. Otherwise
+// the returned response describes the second factor the client has to send. After a successful
+// enrolment the response carries the recovery codes.
+func (m *Manager) Authenticate(ctx context.Context, username string, req *Request) (bool, *Response, error) {
+	user, err := m.users.Resolve(ctx, username)
+	if err != nil {
+		return false, nil, err
+	}
+	unlock := m.locks.lock(user.ID)
+	defer unlock()
+
+	e, err := m.store.Enrolment(user.ID)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if !e.Active() && !m.requiredFor(user) && (req == nil || !req.Enrol) {
+		return true, nil, nil
+	}
+
+	now := m.now()
+	c, err := m.store.challenge(user.ID)
+	if err != nil || now.After(c.Expires) {
+		c = nil
+	}
+	if c != nil && c.Failures >= _maxFailures {
+		return false, nil, ErrLocked
+	}
+
+	if c != nil && req.hasFactor() {
+		// the attempt counts as a failure until the factor was verified, so the lockout holds
+		// when the verification is interrupted
+		c.Failures++
+		if err := m.store.saveChallenge(user.ID, c); err != nil {
+			return false, nil, err
+		}
+
+		ok := false
+		var codes []string
+		if e.Active() {
+			ok = m.verify(e, c, req, now)
+		} else if ok, codes, err = m.enrol(e, c, req, now); err != nil {
+			return false, nil, err
+		}
+		if ok {
+			// the used one-time password or recovery code is stored before the login proceeds
+			if err := m.store.SaveEnrolment(e); err != nil {
+				return false, nil, err
+			}
+			m.store.deleteChallenge(user.ID)
+			if len(codes) > 0 {
+				return true, &Response{RecoveryCodes: codes}, nil
+			}
+			return true, nil, nil
+		}
+
+		if c.Failures >= _maxFailures {
+			return false, nil, ErrLocked
+		}
+		resp := m.response(e, c, username)
+		resp.Invalid = true
+		return false, resp, nil
+	}
+
+	if c == nil {
+		if c, err = m.newChallenge(e, now); err != nil {
+			return false, nil, err
+		}
+		if err := m.store.saveChallenge(user.ID, c); err != nil {
+			return false, nil, err
+		}
+	}
+	return false, m.response(e, c, username), nil
+}
+
+// Reset removes the second factors of a user.
+func (m *Manager) Reset(ctx context.Context, username string) error {
+	user, err := m.users.Resolve(ctx, username)
+	if err != nil {
+		return err
+	}
+	unlock := m.locks.lock(user.ID)
+	defer unlock()
+	return m.store.DeleteEnrolment(user.ID)
+}
+
+// requiredFor checks whether the policy requires a second factor of the user.
+func (m *Manager) requiredFor(user *User) bool {
+	for _, role := range user.Roles {
+		if slices.Contains(m.cfg.RequiredRoles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// verify checks a second factor against the enrolment of a user.
+func (m *Manager) verify(e *Enrolment, c *challenge, req *Request, now time.Time) bool {
+	switch {
+	case req.TOTP != "" && e.TOTPSecret != "":
+		step, ok := ValidateTOTP(e.TOTPSecret, req.TOTP, now, e.TOTPLastStep)
+		if ok {
+			e.TOTPLastStep = step
+		}
+		return ok
+	case req.WebAuthn != nil && len(e.Credentials) > 0:
+		_, err := m.rp.VerifyAssertion(c.WebAuthn, e.Credentials, *req.WebAuthn)
+		return err == nil
+	case req.RecoveryCode != "":
+		var ok bool
+		e.RecoveryCodes, ok = UseRecoveryCode(e.RecoveryCodes, req.RecoveryCode)
+		return ok
+	}
+	return false
+}
+
+// enrol registers the first second factor of a user and generates the recovery codes.
+func (m *Manager) enrol(e *Enrolment, c *challenge, req *Request, now time.Time) (bool, []string, error) {
+	switch {
+	case req.TOTP != "":
+		step, ok := ValidateTOTP(c.TOTPSecret, req.TOTP, now, 0)
+		if !ok {
+			return false, nil, nil
+		}
+		e.TOTPSecret, e.TOTPLastStep = c.TOTPSecret, step
+	case req.WebAuthnRegistration != nil:
+		cred, err := m.rp.VerifyRegistration(c.WebAuthn, *req.WebAuthnRegistration)
+		if err != nil {
+			return false, nil, nil
+		}
+		e.Credentials = append(e.Credentials, *cred)
+	default:
+		return false, nil, nil
+	}
+
+	codes, hashes, err := NewRecoveryCodes(m.cfg.RecoveryCodes)
+	if err != nil {
+		return false, nil, err
+	}
+	e.RecoveryCodes = hashes
+	e.Created = now
+	return true, codes, nil
+}
+
+func (m *Manager) newChallenge(e *Enrolment, now time.Time) (*challenge, error) {
+	c := &challenge{Expires: now.Add(_challengeTTL)}
+	var err error
+	if c.WebAuthn, err = NewChallenge(); err != nil {
+		return nil, err
+	}
+	if !e.Active() {
+		if c.TOTPSecret, err = NewTOTPSecret(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// response describes the pending challenge to the client.
+func (m *Manager) response(e *Enrolment, c *challenge, username string) *Response {
+	opts := &WebAuthnOptions{Challenge: c.WebAuthn, RPID: m.rp.ID}
+	resp := &Response{Required: true, WebAuthn: opts}
+
+	if !e.Active() {
+		resp.Enrol = true
+		resp.Methods = []string{MethodTOTP, MethodWebAuthn}
+		resp.TOTPSecret = c.TOTPSecret
+		resp.TOTPURI = TOTPURI(m.cfg.TOTPIssuer, username, c.TOTPSecret)
+		opts.UserID = _b64.EncodeToString([]byte(e.UserID))
+		opts.UserName = username
+		return resp
+	}
+
+	if e.TOTPSecret != "" {
+		resp.Methods = append(resp.Methods, MethodTOTP)
+	}
+	if len(e.Credentials) > 0 {
+		resp.Methods = append(resp.Methods, MethodWebAuthn)
+		for _, cred := range e.Credentials {
+			opts.Credentials = append(opts.Credentials, cred.ID)
+		}
+	} else {
+		resp.WebAuthn = nil
+	}
+	if len(e.RecoveryCodes) > 0 {
+		resp.Methods = append(resp.Methods, MethodRecovery)
+	}
+	return resp
+}
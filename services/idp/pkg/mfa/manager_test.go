@@ -0,0 +1,200 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencloud-eu/opencloud/services/idp/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	microstore "go-micro.dev/v4/store"
+)
+
+type staticUsers map[string]*User
+
+func (u staticUsers) Resolve(_ context.Context, username string) (*User, error) {
+	return u[username], nil
+}
+
+func newTestManager() *Manager {
+	users := staticUsers{
+		"admin": {ID: "admin-id", Roles: []string{"admin"}},
+		"alice": {ID: "alice-id", Roles: []string{"user"}},
+	}
+	return NewManager(NewStore(microstore.NewMemoryStore()), users, config.MFA{
+		RequiredRoles:   []string{"admin"},
+		TOTPIssuer:      "OpenCloud",
+		WebAuthnRPID:    "cloud.example.com",
+		WebAuthnOrigins: []string{"https://cloud.example.com"},
+		RecoveryCodes:   4,
+	})
+}
+
+func TestManagerPolicy(t *testing.T) {
+	m := newTestManager()
+
+	ok, resp, err := m.Authenticate(context.Background(), "alice", nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Nil(t, resp)
+
+	ok, resp, err = m.Authenticate(context.Background(), "admin", nil)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.True(t, resp.Required)
+	assert.True(t, resp.Enrol)
+	assert.NotEmpty(t, resp.TOTPSecret)
+
+	// users can enrol voluntarily
+	ok, resp, err = m.Authenticate(context.Background(), "alice", &Request{Enrol: true})
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.True(t, resp.Enrol)
+}
+
+func TestManagerTOTP(t *testing.T) {
+	m := newTestManager()
+	now := time.Unix(1700000000, 0)
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, resp, err := m.Authenticate(ctx, "admin", nil)
+	require.NoError(t, err)
+
+	code, _ := TOTPCode(resp.TOTPSecret, now)
+	ok, resp, err := m.Authenticate(ctx, "admin", &Request{TOTP: code})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, resp.RecoveryCodes, 4)
+	recoveryCodes := resp.RecoveryCodes
+
+	// the next login needs the second factor again
+	now = now.Add(time.Minute)
+	ok, resp, err = m.Authenticate(ctx, "admin", nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+	assert.False(t, resp.Enrol)
+	assert.Equal(t, []string{MethodTOTP, MethodRecovery}, resp.Methods)
+
+	ok, resp, err = m.Authenticate(ctx, "admin", &Request{TOTP: "000000"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.True(t, resp.Invalid)
+
+	ok, _, err = m.Authenticate(ctx, "admin", &Request{RecoveryCode: recoveryCodes[0]})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// recovery codes are single-use
+	_, _, _ = m.Authenticate(ctx, "admin", nil)
+	ok, _, err = m.Authenticate(ctx, "admin", &Request{RecoveryCode: recoveryCodes[0]})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, m.Reset(ctx, "admin"))
+	_, resp, err = m.Authenticate(ctx, "admin", nil)
+	require.NoError(t, err)
+	assert.True(t, resp.Enrol)
+}
+
+func TestManagerWebAuthn(t *testing.T) {
+	m := newTestManager()
+	ctx := context.Background()
+	authenticator := newVirtualAuthenticator(t, "cloud.example.com", "https://cloud.example.com")
+
+	_, resp, err := m.Authenticate(ctx, "admin", nil)
+	require.NoError(t, err)
+	reg := authenticator.create(resp.WebAuthn.Challenge)
+	ok, _, err := m.Authenticate(ctx, "admin", &Request{WebAuthnRegistration: &reg})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, resp, err = m.Authenticate(ctx, "admin", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{MethodWebAuthn, MethodRecovery}, resp.Methods)
+	assert.Equal(t, []string{reg.ID}, resp.WebAuthn.Credentials)
+
+	assertion := authenticator.get(resp.WebAuthn.Challenge)
+	ok, _, err = m.Authenticate(ctx, "admin", &Request{WebAuthn: &assertion})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// the challenge is used up
+	ok, _, err = m.Authenticate(ctx, "admin", &Request{WebAuthn: &assertion})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestManagerLocksAfterFailures(t *testing.T) {
+	m := newTestManager()
+	ctx := context.Background()
+
+	_, _, err := m.Authenticate(ctx, "admin", nil)
+	require.NoError(t, err)
+	for i := 0; i < _maxFailures-1; i++ {
+		ok, _, err := m.Authenticate(ctx, "admin", &Request{TOTP: "000000"})
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+	_, _, err = m.Authenticate(ctx, "admin", &Request{TOTP: "000000"})
+	assert.ErrorIs(t, err, ErrLocked)
+	_, _, err = m.Authenticate(ctx, "admin", nil)
+	assert.ErrorIs(t, err, ErrLocked)
+}
+
+func TestManagerParallelLogins(t *testing.T) {
+	m := newTestManager()
+	now := time.Unix(1700000000, 0)
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, resp, err := m.Authenticate(ctx, "admin", nil)
+	require.NoError(t, err)
+	code, _ := TOTPCode(resp.TOTPSecret, now)
+	ok, resp, err := m.Authenticate(ctx, "admin", &Request{TOTP: code})
+	require.NoError(t, err)
+	require.True(t, ok)
+	recoveryCode := resp.RecoveryCodes[0]
+
+	parallel := func(req *Request) (succeeded, rejected, locked int) {
+		_, _, err := m.Authenticate(ctx, "admin", nil)
+		require.NoError(t, err)
+
+		var (
+			wg sync.WaitGroup
+			mu sync.Mutex
+		)
+		for i := 0; i < 2*_maxFailures; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok, _, err := m.Authenticate(ctx, "admin", req)
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case errors.Is(err, ErrLocked):
+					locked++
+				case ok:
+					succeeded++
+				default:
+					rejected++
+				}
+			}()
+		}
+		wg.Wait()
+		return succeeded, rejected, locked
+	}
+
+	// a recovery code is only accepted once
+	succeeded, _, _ := parallel(&Request{RecoveryCode: recoveryCode})
+	assert.Equal(t, 1, succeeded)
+
+	// parallel attempts don't get past the lockout of a new challenge
+	now = now.Add(_challengeTTL + time.Second)
+	_, rejected, locked := parallel(&Request{TOTP: "000000"})
+	assert.Equal(t, _maxFailures-1, rejected)
+	assert.Equal(t, _maxFailures+1, locked)
+}
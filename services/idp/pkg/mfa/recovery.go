@@ -0,0 +1,55 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// _recoveryAlphabet leaves out characters which are easily confused like 0, O, 1 and I
+const _recoveryAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// NewRecoveryCodes generates n single-use recovery codes. It returns the codes to show to the user
+// and their hashes to store.
+func NewRecoveryCodes(n int) ([]string, []string, error) {
+	codes := make([]string, 0, n)
+	hashes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		code := make([]byte, 0, 11)
+		for j, b := range raw {
+			if j == 5 {
+				code = append(code, '-')
+			}
+			code = append(code, _recoveryAlphabet[int(b)%len(_recoveryAlphabet)])
+		}
+		codes = append(codes, string(code))
+		hashes = append(hashes, hashRecoveryCode(string(code)))
+	}
+	return codes, hashes, nil
+}
+
+// UseRecoveryCode checks a recovery code against the stored hashes. It returns the remaining hashes
+// without the used code.
+func UseRecoveryCode(hashes []string, code string) ([]string, bool) {
+	h := hashRecoveryCode(code)
+	for i, stored := range hashes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(h)) == 1 {
+			return append(hashes[:i:i], hashes[i+1:]...), true
+		}
+	}
+	return hashes, false
+}
+
+// hashRecoveryCode hashes a normalized recovery code. The codes have 50 bits of entropy, a plain
+// hash is sufficient to protect them at rest.
+func hashRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,112 @@
+package mfa
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	microstore "go-micro.dev/v4/store"
+)
+
+const (
+	_enrolmentPrefix = "enrolment:"
+	_challengePrefix = "challenge:"
+)
+
+// Enrolment holds the second factors of a user.
+type Enrolment struct {
+	UserID string `json:"user_id"`
+	// TOTPSecret is the shared secret of the authenticator app
+	TOTPSecret string `json:"totp_secret,omitempty"`
+	// TOTPLastStep is the time step of the last accepted one-time password
+	TOTPLastStep uint64 `json:"totp_last_step,omitempty"`
+	// Credentials are the registered security keys
+	Credentials []Credential `json:"credentials,omitempty"`
+	// RecoveryCodes are the hashes of the unused recovery codes
+	RecoveryCodes []string  `json:"recovery_codes,omitempty"`
+	Created       time.Time `json:"created"`
+}
+
+// Active checks whether the user has enrolled a second factor.
+func (e *Enrolment) Active() bool {
+	return e.TOTPSecret != "" || len(e.Credentials) > 0
+}
+
+// challenge is the state of a pending second factor step of a login.
+type challenge struct {
+	WebAuthn string `json:"webauthn"`
+	// TOTPSecret is the shared secret offered to users who enrol
+	TOTPSecret string    `json:"totp_secret,omitempty"`
+	Failures   int       `json:"failures,omitempty"`
+	Expires    time.Time `json:"expires"`
+}
+
+// Store persists the enrolments and the pending challenges in a micro store.
+type Store struct {
+	store microstore.Store
+}
+
+// NewStore returns a new Store.
+func NewStore(store microstore.Store) *Store {
+	return &Store{store: store}
+}
+
+// Enrolment returns the enrolment of a user. Users who haven't enrolled get an inactive enrolment.
+func (s *Store) Enrolment(userID string) (*Enrolment, error) {
+	e := &Enrolment{UserID: userID}
+	if err := s.read(_enrolmentPrefix+userID, e); err != nil && !errors.Is(err, microstore.ErrNotFound) {
+		return nil, err
+	}
+	return e, nil
+}
+
+// SaveEnrolment stores the enrolment of a user.
+func (s *Store) SaveEnrolment(e *Enrolment) error {
+	return s.write(_enrolmentPrefix+e.UserID, e)
+}
+
+// DeleteEnrolment removes all second factors of a user, who has to enrol again on the next login if
+// a second factor is required.
+func (s *Store) DeleteEnrolment(userID string) error {
+	_ = s.store.Delete(_challengePrefix + userID)
+	err := s.store.Delete(_enrolmentPrefix + userID)
+	if errors.Is(err, microstore.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *Store) challenge(userID string) (*challenge, error) {
+	c := &challenge{}
+	if err := s.read(_challengePrefix+userID, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Store) saveChallenge(userID string, c *challenge) error {
+	return s.write(_challengePrefix+userID, c)
+}
+
+func (s *Store) deleteChallenge(userID string) {
+	_ = s.store.Delete(_challengePrefix + userID)
+}
+
+func (s *Store) read(key string, v interface{}) error {
+	recs, err := s.store.Read(key)
+	if err != nil {
+		return err
+	}
+	if len(recs) == 0 {
+		return microstore.ErrNotFound
+	}
+	return json.Unmarshal(recs[0].Value, v)
+}
+
+func (s *Store) write(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.store.Write(&microstore.Record{Key: key, Value: b})
+}
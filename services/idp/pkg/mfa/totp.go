@@ -0,0 +1,109 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// _totpPeriod is the time step of the one-time passwords
+	_totpPeriod = 30 * time.Second
+	// _totpDigits is the number of digits of the one-time passwords
+	_totpDigits = 6
+	// _totpSkew is the number of time steps a one-time password is accepted before and after its step
+	_totpSkew = 1
+	// _totpSecretSize is the size of the shared secrets in bytes as recommended by RFC 4226
+	_totpSecretSize = 20
+)
+
+var _base32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret generates a new base32 encoded shared secret for time-based one-time passwords.
+func NewTOTPSecret() (string, error) {
+	secret := make([]byte, _totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return _base32.EncodeToString(secret), nil
+}
+
+// TOTPURI returns the otpauth URI of a shared secret, which authenticator apps import from a QR code.
+func TOTPURI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(_totpDigits))
+	v.Set("period", fmt.Sprint(int(_totpPeriod.Seconds())))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// TOTPCode computes the one-time password of a shared secret at the given time as defined by RFC 6238.
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, totpStep(t)), nil
+}
+
+// ValidateTOTP checks a one-time password against a shared secret, tolerating a clock skew of one
+// time step. To prevent the replay of a password, the time step of the last accepted password is
+// passed in lastStep and only later steps are accepted. The matched time step is returned.
+func ValidateTOTP(secret, code string, t time.Time, lastStep uint64) (uint64, bool) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != _totpDigits {
+		return 0, false
+	}
+
+	now := totpStep(t)
+	for i := -_totpSkew; i <= _totpSkew; i++ {
+		step := now + uint64(i)
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func totpStep(t time.Time) uint64 {
+	return uint64(t.Unix()) / uint64(_totpPeriod.Seconds())
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "="))
+	key, err := _base32.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	return key, nil
+}
+
+// hotp computes the HMAC-based one-time password of RFC 4226.
+func hotp(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", _totpDigits, value%1000000)
+}
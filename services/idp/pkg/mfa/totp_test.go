@@ -0,0 +1,75 @@
+package mfa
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the SHA1 test vectors of RFC 6238, truncated to six digits
+func TestTOTPCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	for ts, code := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		got, err := TOTPCode(secret, time.Unix(ts, 0))
+		require.NoError(t, err)
+		assert.Equal(t, code, got, "time %d", ts)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+
+	code, err := TOTPCode(secret, now)
+	require.NoError(t, err)
+	step, ok := ValidateTOTP(secret, code, now, 0)
+	assert.True(t, ok)
+
+	// the same code must not be accepted twice
+	_, ok = ValidateTOTP(secret, code, now, step)
+	assert.False(t, ok)
+
+	// codes of the neighbouring time steps are accepted for clock skew
+	previous, _ := TOTPCode(secret, now.Add(-30*time.Second))
+	_, ok = ValidateTOTP(secret, previous, now, 0)
+	assert.True(t, ok)
+	old, _ := TOTPCode(secret, now.Add(-2*time.Minute))
+	_, ok = ValidateTOTP(secret, old, now, 0)
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP(secret, "12345", now, 0)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("OpenCloud", "alice", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/OpenCloud:alice?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=OpenCloud")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes(3)
+	require.NoError(t, err)
+	require.Len(t, codes, 3)
+	assert.Regexp(t, `^[2-9A-Z]{5}-[2-9A-Z]{5}$`, codes[0])
+
+	remaining, ok := UseRecoveryCode(hashes, strings.ToLower(codes[1]))
+	assert.True(t, ok)
+	assert.Len(t, remaining, 2)
+
+	_, ok = UseRecoveryCode(remaining, codes[1])
+	assert.False(t, ok)
+}
@@ -0,0 +1,73 @@
+package mfa
+
+import (
+	"context"
+	"fmt"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	settingssvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/settings/v0"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+)
+
+// User is a user logging in.
+type User struct {
+	ID    string
+	Roles []string
+}
+
+// UserResolver looks up the users logging in.
+type UserResolver interface {
+	// Resolve returns the user with the given username together with the names of the roles
+	// assigned to them.
+	Resolve(ctx context.Context, username string) (*User, error)
+}
+
+// CS3UserResolver resolves users with the machine authentication of the gateway and their roles
+// with the settings service.
+type CS3UserResolver struct {
+	GatewaySelector   pool.Selectable[gateway.GatewayAPIClient]
+	MachineAuthAPIKey string
+	RoleService       settingssvc.RoleService
+}
+
+// Resolve implements the UserResolver interface.
+func (r CS3UserResolver) Resolve(ctx context.Context, username string) (*User, error) {
+	gatewayClient, err := r.GatewaySelector.Next()
+	if err != nil {
+		return nil, err
+	}
+	res, err := gatewayClient.Authenticate(ctx, &gateway.AuthenticateRequest{
+		Type:         "machine",
+		ClientId:     "username:" + username,
+		ClientSecret: r.MachineAuthAPIKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		return nil, fmt.Errorf("could not look up user '%s': %s", username, res.GetStatus().GetMessage())
+	}
+	user := &User{ID: res.GetUser().GetId().GetOpaqueId()}
+
+	assignments, err := r.RoleService.ListRoleAssignments(ctx, &settingssvc.ListRoleAssignmentsRequest{AccountUuid: user.ID})
+	if err != nil {
+		return nil, err
+	}
+	roleIDs := make([]string, 0, len(assignments.GetAssignments()))
+	for _, a := range assignments.GetAssignments() {
+		roleIDs = append(roleIDs, a.GetRoleId())
+	}
+	if len(roleIDs) == 0 {
+		return user, nil
+	}
+
+	roles, err := r.RoleService.ListRoles(ctx, &settingssvc.ListBundlesRequest{BundleIds: roleIDs})
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles.GetBundles() {
+		user.Roles = append(user.Roles, role.GetName())
+	}
+	return user, nil
+}
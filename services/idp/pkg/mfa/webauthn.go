@@ -0,0 +1,248 @@
+package mfa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// COSE algorithm identifiers of the supported WebAuthn public keys.
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// flags of the WebAuthn authenticator data
+const (
+	_flagUserPresent  = 0x01
+	_flagAttestedData = 0x40
+)
+
+var (
+	// ErrInvalidCeremony is returned when a WebAuthn response doesn't match the expected ceremony.
+	ErrInvalidCeremony = errors.New("invalid webauthn response")
+	// ErrInvalidSignature is returned when the signature of a WebAuthn assertion is invalid.
+	ErrInvalidSignature = errors.New("invalid webauthn signature")
+	// ErrCloned is returned when the signature counter of an authenticator didn't increase.
+	ErrCloned = errors.New("webauthn signature counter did not increase")
+)
+
+var _b64 = base64.RawURLEncoding
+
+// RelyingParty verifies the WebAuthn registration and authentication ceremonies of a relying party.
+// Only the "none" attestation is supported, so security keys are not checked against a list of
+// trusted vendors.
+type RelyingParty struct {
+	ID      string
+	Origins []string
+}
+
+// Credential is a registered WebAuthn credential.
+type Credential struct {
+	ID        string `json:"id"`
+	PublicKey []byte `json:"public_key"`
+	Algorithm int    `json:"algorithm"`
+	SignCount uint32 `json:"sign_count"`
+	Name      string `json:"name,omitempty"`
+}
+
+// RegistrationResponse is the response of a navigator.credentials.create() ceremony. The binary
+// values are base64url encoded. The public key is the DER encoded SubjectPublicKeyInfo returned by
+// AuthenticatorAttestationResponse.getPublicKey().
+type RegistrationResponse struct {
+	ID                 string `json:"id"`
+	ClientDataJSON     string `json:"clientDataJSON"`
+	AuthenticatorData  string `json:"authenticatorData"`
+	PublicKey          string `json:"publicKey"`
+	PublicKeyAlgorithm int    `json:"publicKeyAlgorithm"`
+}
+
+// AssertionResponse is the response of a navigator.credentials.get() ceremony. The binary values
+// are base64url encoded.
+type AssertionResponse struct {
+	ID                string `json:"id"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// NewChallenge generates a random base64url encoded challenge for a WebAuthn ceremony.
+func NewChallenge() (string, error) {
+	c := make([]byte, 32)
+	if _, err := rand.Read(c); err != nil {
+		return "", err
+	}
+	return _b64.EncodeToString(c), nil
+}
+
+// VerifyRegistration verifies the response of a registration ceremony for the given challenge and
+// returns the new credential.
+func (rp RelyingParty) VerifyRegistration(challenge string, resp RegistrationResponse) (*Credential, error) {
+	authData, err := rp.verifyCeremony("webauthn.create", challenge, resp.ClientDataJSON, resp.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+	if authData[32]&_flagAttestedData == 0 {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrInvalidCeremony)
+	}
+
+	// the attested credential data starts with the 16 bytes AAGUID followed by the length of the credential id
+	rest := authData[37:]
+	if len(rest) < 18 {
+		return nil, fmt.Errorf("%w: truncated attested credential data", ErrInvalidCeremony)
+	}
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	if len(rest) < 18+idLen {
+		return nil, fmt.Errorf("%w: truncated credential id", ErrInvalidCeremony)
+	}
+	credentialID := rest[18 : 18+idLen]
+	if _b64.EncodeToString(credentialID) != resp.ID {
+		return nil, fmt.Errorf("%w: credential id mismatch", ErrInvalidCeremony)
+	}
+
+	publicKey, err := _b64.DecodeString(resp.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid public key encoding", ErrInvalidCeremony)
+	}
+	if _, err := parsePublicKey(publicKey, resp.PublicKeyAlgorithm); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        resp.ID,
+		PublicKey: publicKey,
+		Algorithm: resp.PublicKeyAlgorithm,
+		SignCount: binary.BigEndian.Uint32(authData[33:37]),
+	}, nil
+}
+
+// VerifyAssertion verifies the response of an authentication ceremony for the given challenge with
+// one of the registered credentials. It returns the index of the used credential and updates its
+// signature counter.
+func (rp RelyingParty) VerifyAssertion(challenge string, credentials []Credential, resp AssertionResponse) (int, error) {
+	i := slices.IndexFunc(credentials, func(c Credential) bool { return c.ID == resp.ID })
+	if i < 0 {
+		return -1, fmt.Errorf("%w: unknown credential", ErrInvalidCeremony)
+	}
+	cred := &credentials[i]
+
+	authData, err := rp.verifyCeremony("webauthn.get", challenge, resp.ClientDataJSON, resp.AuthenticatorData)
+	if err != nil {
+		return -1, err
+	}
+	rawClientData, _ := _b64.DecodeString(resp.ClientDataJSON)
+	signature, err := _b64.DecodeString(resp.Signature)
+	if err != nil {
+		return -1, fmt.Errorf("%w: invalid signature encoding", ErrInvalidCeremony)
+	}
+
+	key, err := parsePublicKey(cred.PublicKey, cred.Algorithm)
+	if err != nil {
+		return -1, err
+	}
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := append(slices.Clone(authData), clientDataHash[:]...)
+	if !verifySignature(key, cred.Algorithm, signed, signature) {
+		return -1, ErrInvalidSignature
+	}
+
+	// authenticators without a counter always report 0
+	count := binary.BigEndian.Uint32(authData[33:37])
+	if (count != 0 || cred.SignCount != 0) && count <= cred.SignCount {
+		return -1, ErrCloned
+	}
+	cred.SignCount = count
+	return i, nil
+}
+
+// verifyCeremony checks the client data and the authenticator data shared by both ceremonies and
+// returns the decoded authenticator data.
+func (rp RelyingParty) verifyCeremony(typ, challenge, clientDataJSON, authenticatorData string) ([]byte, error) {
+	raw, err := _b64.DecodeString(clientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid client data encoding", ErrInvalidCeremony)
+	}
+	cd := clientData{}
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return nil, fmt.Errorf("%w: invalid client data", ErrInvalidCeremony)
+	}
+	switch {
+	case cd.Type != typ:
+		return nil, fmt.Errorf("%w: unexpected type '%s'", ErrInvalidCeremony, cd.Type)
+	case challenge == "" || cd.Challenge != challenge:
+		return nil, fmt.Errorf("%w: challenge mismatch", ErrInvalidCeremony)
+	case !slices.Contains(rp.Origins, cd.Origin):
+		return nil, fmt.Errorf("%w: unexpected origin '%s'", ErrInvalidCeremony, cd.Origin)
+	}
+
+	authData, err := _b64.DecodeString(authenticatorData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid authenticator data encoding", ErrInvalidCeremony)
+	}
+	// rp id hash (32 bytes), flags (1 byte) and signature counter (4 bytes)
+	if len(authData) < 37 {
+		return nil, fmt.Errorf("%w: truncated authenticator data", ErrInvalidCeremony)
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData[:32], rpIDHash[:]) {
+		return nil, fmt.Errorf("%w: relying party id mismatch", ErrInvalidCeremony)
+	}
+	if authData[32]&_flagUserPresent == 0 {
+		return nil, fmt.Errorf("%w: user not present", ErrInvalidCeremony)
+	}
+	return authData, nil
+}
+
+func parsePublicKey(der []byte, alg int) (crypto.PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid public key", ErrInvalidCeremony)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey:
+		if alg == AlgES256 {
+			return key, nil
+		}
+	case *rsa.PublicKey:
+		if alg == AlgRS256 {
+			return key, nil
+		}
+	case ed25519.PublicKey:
+		if alg == AlgEdDSA {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unsupported public key algorithm %d", ErrInvalidCeremony, alg)
+}
+
+func verifySignature(key crypto.PublicKey, alg int, signed, signature []byte) bool {
+	switch alg {
+	case AlgES256:
+		digest := sha256.Sum256(signed)
+		return ecdsa.VerifyASN1(key.(*ecdsa.PublicKey), digest[:], signature)
+	case AlgRS256:
+		digest := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	case AlgEdDSA:
+		return ed25519.Verify(key.(ed25519.PublicKey), signed, signature)
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package mfa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// virtualAuthenticator is a software security key running the WebAuthn ceremonies like a browser.
+type virtualAuthenticator struct {
+	t         *testing.T
+	rpID      string
+	origin    string
+	id        []byte
+	key       *ecdsa.PrivateKey
+	signCount uint32
+}
+
+func newVirtualAuthenticator(t *testing.T, rpID, origin string) *virtualAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return &virtualAuthenticator{t: t, rpID: rpID, origin: origin, id: id, key: key}
+}
+
+func (a *virtualAuthenticator) clientData(typ, challenge string) []byte {
+	b, err := json.Marshal(clientData{Type: typ, Challenge: challenge, Origin: a.origin})
+	require.NoError(a.t, err)
+	return b
+}
+
+func (a *virtualAuthenticator) authData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	d := append(rpIDHash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(d[33:37], a.signCount)
+	return append(d, attested...)
+}
+
+// create runs navigator.credentials.create() with the "none" attestation.
+func (a *virtualAuthenticator) create(challenge string) RegistrationResponse {
+	attested := make([]byte, 16, 18+len(a.id))
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.id)))
+	attested = append(attested, a.id...)
+	publicKey, err := x509.MarshalPKIXPublicKey(&a.key.PublicKey)
+	require.NoError(a.t, err)
+
+	return RegistrationResponse{
+		ID:                 _b64.EncodeToString(a.id),
+		ClientDataJSON:     _b64.EncodeToString(a.clientData("webauthn.create", challenge)),
+		AuthenticatorData:  _b64.EncodeToString(a.authData(_flagUserPresent|_flagAttestedData, attested)),
+		PublicKey:          _b64.EncodeToString(publicKey),
+		PublicKeyAlgorithm: AlgES256,
+	}
+}
+
+// get runs navigator.credentials.get().
+func (a *virtualAuthenticator) get(challenge string) AssertionResponse {
+	a.signCount++
+	cd := a.clientData("webauthn.get", challenge)
+	ad := a.authData(_flagUserPresent, nil)
+	cdHash := sha256.Sum256(cd)
+	digest := sha256.Sum256(append(append([]byte{}, ad...), cdHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(a.t, err)
+
+	return AssertionResponse{
+		ID:                _b64.EncodeToString(a.id),
+		ClientDataJSON:    _b64.EncodeToString(cd),
+		AuthenticatorData: _b64.EncodeToString(ad),
+		Signature:         _b64.EncodeToString(sig),
+	}
+}
+
+func TestWebAuthnCeremonies(t *testing.T) {
+	rp := RelyingParty{ID: "cloud.example.com", Origins: []string{"https://cloud.example.com"}}
+	authenticator := newVirtualAuthenticator(t, rp.ID, "https://cloud.example.com")
+
+	challenge, err := NewChallenge()
+	require.NoError(t, err)
+	cred, err := rp.VerifyRegistration(challenge, authenticator.create(challenge))
+	require.NoError(t, err)
+	credentials := []Credential{*cred}
+
+	challenge, _ = NewChallenge()
+	assertion := authenticator.get(challenge)
+	i, err := rp.VerifyAssertion(challenge, credentials, assertion)
+	require.NoError(t, err)
+	assert.Equal(t, 0, i)
+	assert.Equal(t, uint32(1), credentials[0].SignCount)
+
+	t.Run("replayed assertion", func(t *testing.T) {
+		_, err := rp.VerifyAssertion(challenge, credentials, assertion)
+		assert.ErrorIs(t, err, ErrCloned)
+	})
+
+	t.Run("other challenge", func(t *testing.T) {
+		other, _ := NewChallenge()
+		_, err := rp.VerifyAssertion(other, credentials, authenticator.get(challenge))
+		assert.ErrorIs(t, err, ErrInvalidCeremony)
+	})
+
+	t.Run("other origin", func(t *testing.T) {
+		phishing := *authenticator
+		phishing.origin = "https://cloud.example.org"
+		_, err := rp.VerifyAssertion(challenge, credentials, phishing.get(challenge))
+		assert.ErrorIs(t, err, ErrInvalidCeremony)
+	})
+
+	t.Run("other relying party", func(t *testing.T) {
+		other := *authenticator
+		other.rpID = "example.org"
+		_, err := rp.VerifyAssertion(challenge, credentials, other.get(challenge))
+		assert.ErrorIs(t, err, ErrInvalidCeremony)
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		forged := newVirtualAuthenticator(t, rp.ID, "https://cloud.example.com")
+		forged.id = authenticator.id
+		forged.signCount = 10
+		_, err := rp.VerifyAssertion(challenge, credentials, forged.get(challenge))
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("registration response as assertion", func(t *testing.T) {
+		resp := authenticator.create(challenge)
+		_, err := rp.VerifyAssertion(challenge, credentials, AssertionResponse{
+			ID:                resp.ID,
+			ClientDataJSON:    resp.ClientDataJSON,
+			AuthenticatorData: resp.AuthenticatorData,
+		})
+		assert.ErrorIs(t, err, ErrInvalidCeremony)
+	})
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/idp/pkg/mfa"
+)
+
+// _modeLogonUsernamePassword is the logon mode of the identifier web app sending a username and a password
+const _modeLogonUsernamePassword = "1"
+
+// _maxLogonRequestSize limits the size of the logon requests which are buffered
+const _maxLogonRequestSize = 1 << 20
+
+// logonRequest is the part of the logon request of the identifier web app the second factor step needs.
+type logonRequest struct {
+	State  string       `json:"state"`
+	Params []string     `json:"params"`
+	MFA    *mfa.Request `json:"mfa"`
+}
+
+// MFA is a middleware requiring a second factor for the logon with username and password. The
+// logon is passed to the identifier first. Only when the password was correct and the user needs a
+// second factor, the response of the identifier including its logon cookie is held back and the
+// client is asked for the second factor instead. The client repeats the logon with the second
+// factor in the "mfa" field of the request.
+func MFA(logger log.Logger, manager *mfa.Manager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/identifier/_/logon") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, _maxLogonRequestSize))
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// decode the request like the identifier does, a request which can not be read is rejected
+			// instead of being passed on without checking the second factor
+			req := logonRequest{}
+			if err := json.NewDecoder(bytes.NewReader(body)).Decode(&req); err != nil {
+				logger.Debug().Err(err).Msg("could not decode the logon request")
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			if len(req.Params) < 3 || req.Params[2] != _modeLogonUsernamePassword {
+				// logons with the cookie of an earlier logon already passed the second factor
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if rec.status != http.StatusOK || !logonSucceeded(rec.body.Bytes()) {
+				rec.flush(w)
+				return
+			}
+
+			ok, resp, err := manager.Authenticate(r.Context(), req.Params[0], req.MFA)
+			switch {
+			case errors.Is(err, mfa.ErrLocked):
+				logger.Warn().Str("username", req.Params[0]).Msg("too many invalid second factors, logon locked")
+				// the identifier web app treats this as a failed logon
+				w.Header().Set("Kopano-Konnect-State", req.State)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			case err != nil:
+				logger.Error().Err(err).Str("username", req.Params[0]).Msg("could not check the second factor")
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			case !ok:
+				if resp.Invalid {
+					logger.Info().Str("username", req.Params[0]).Msg("invalid second factor")
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"state":   req.State,
+					"mfa":     resp,
+				})
+				return
+			case resp != nil:
+				// show the recovery codes of the new enrolment
+				body := withMFAResponse(rec.body.Bytes(), resp)
+				rec.body.Reset()
+				rec.body.Write(body)
+				rec.header.Set("Content-Length", strconv.Itoa(rec.body.Len()))
+			}
+			rec.flush(w)
+		})
+	}
+}
+
+// logonSucceeded checks the success flag of the logon response of the identifier.
+func logonSucceeded(body []byte) bool {
+	resp := struct {
+		Success bool `json:"success"`
+	}{}
+	return json.Unmarshal(body, &resp) == nil && resp.Success
+}
+
+// withMFAResponse adds the second factor response to the logon response of the identifier.
+func withMFAResponse(body []byte, resp *mfa.Response) []byte {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+	v, err := json.Marshal(resp)
+	if err != nil {
+		return body
+	}
+	fields["mfa"] = v
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+// bufferedResponse holds back a response until it is flushed.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+func (b *bufferedResponse) WriteHeader(status int) { b.status = status }
+
+func (b *bufferedResponse) flush(w http.ResponseWriter) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(b.status)
+	_, _ = w.Write(b.body.Bytes())
+}
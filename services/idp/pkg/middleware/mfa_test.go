@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/idp/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/idp/pkg/mfa"
+	"github.com/stretchr/testify/assert"
+	microstore "go-micro.dev/v4/store"
+)
+
+type staticUsers map[string]*mfa.User
+
+func (u staticUsers) Resolve(_ context.Context, username string) (*mfa.User, error) {
+	return u[username], nil
+}
+
+// logon sends a logon request through the middleware to an identifier accepting every password. It
+// returns the response and whether the identifier was called.
+func logon(body string) (*httptest.ResponseRecorder, bool) {
+	manager := mfa.NewManager(mfa.NewStore(microstore.NewMemoryStore()), staticUsers{
+		"admin": {ID: "admin-id", Roles: []string{"admin"}},
+	}, config.MFA{
+		RequiredRoles:   []string{"admin"},
+		TOTPIssuer:      "OpenCloud",
+		WebAuthnRPID:    "cloud.example.com",
+		WebAuthnOrigins: []string{"https://cloud.example.com"},
+		RecoveryCodes:   4,
+	})
+
+	called := false
+	identifier := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Header().Set("Set-Cookie", "__Secure-KKT=ticket")
+		_, _ = w.Write([]byte(`{"success":true,"state":"s"}`))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "https://cloud.example.com/signin/v1/identifier/_/logon", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	MFA(log.NopLogger(), manager)(identifier).ServeHTTP(rr, req)
+	return rr, called
+}
+
+func TestMFARejectsMalformedLogons(t *testing.T) {
+	for name, body := range map[string]string{
+		"truncated":     `{"state":"s","params":["admin","secret","1"]`,
+		"mistyped mfa":  `{"state":"s","params":["admin","secret","1"],"mfa":"123456"}`,
+		"mfa array":     `{"state":"s","params":["admin","secret","1"],"mfa":[{"totp":"123456"}]}`,
+		"params object": `{"state":"s","params":{"0":"admin"}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			rr, called := logon(body)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.False(t, called, "the identifier must not see the logon")
+			assert.Empty(t, rr.Header().Get("Set-Cookie"))
+		})
+	}
+}
+
+func TestMFAHoldsBackTheLogonCookie(t *testing.T) {
+	// the identifier decodes only the first JSON value of the body, so does the middleware
+	rr, called := logon(`{"state":"s","params":["admin","secret","1"]} trailing`)
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Set-Cookie"), "the logon cookie is only sent after the second factor")
+	assert.Contains(t, rr.Body.String(), `"success":false`)
+}
+
+func TestMFAPassesOtherLogonModes(t *testing.T) {
+	rr, called := logon(`{"state":"s","params":["admin","","3"]}`)
+	assert.True(t, called)
+	assert.Equal(t, "__Secure-KKT=ticket", rr.Header().Get("Set-Cookie"))
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	"github.com/opencloud-eu/opencloud/services/idp/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/idp/pkg/metrics"
+	"github.com/opencloud-eu/opencloud/services/idp/pkg/mfa"
 	"github.com/urfave/cli/v2"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -22,6 +23,7 @@ type Options struct {
 	Metrics       *metrics.Metrics
 	Flags         []cli.Flag
 	TraceProvider trace.TracerProvider
+	MFA           *mfa.Manager
 }
 
 // newOptions initializes the available default options.
@@ -83,3 +85,10 @@ func TraceProvider(val trace.TracerProvider) Option {
 		o.TraceProvider = val
 	}
 }
+
+// MFA provides a function to set the mfa option.
+func MFA(val *mfa.Manager) Option {
+	return func(o *Options) {
+		o.MFA = val
+	}
+}
@@ -70,6 +70,7 @@ func Server(opts ...Option) (http.Service, error) {
 			),
 		),
 		svc.TraceProvider(options.TraceProvider),
+		svc.MFA(options.MFA),
 	)
 
 	{
@@ -5,6 +5,7 @@ import (
 
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	"github.com/opencloud-eu/opencloud/services/idp/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/idp/pkg/mfa"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -17,6 +18,7 @@ type Options struct {
 	Config        *config.Config
 	Middleware    []func(http.Handler) http.Handler
 	TraceProvider trace.TracerProvider
+	MFA           *mfa.Manager
 }
 
 // newOptions initializes the available default options.
@@ -57,3 +59,10 @@ func TraceProvider(val trace.TracerProvider) Option {
 		o.TraceProvider = val
 	}
 }
+
+// MFA provides a function to set the mfa option.
+func MFA(val *mfa.Manager) Option {
+	return func(o *Options) {
+		o.MFA = val
+	}
+}
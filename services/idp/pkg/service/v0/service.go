@@ -292,6 +292,10 @@ func (idp *IDP) initMux(ctx context.Context, r []server.WithRoutes, h http.Handl
 		),
 	)
 
+	if options.MFA != nil {
+		idp.mux.Use(middleware.MFA(options.Logger, options.MFA))
+	}
+
 	// handle / | index.html with a template that needs to have the BASE_PREFIX replaced
 	idp.mux.Get("/signin/v1/identifier", idp.Index())
 	idp.mux.Get("/signin/v1/identifier/", idp.Index())
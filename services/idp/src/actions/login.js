@@ -44,12 +44,13 @@ export function requestLogon(username, password) {
 }
 
 export function receiveLogon(logon) {
-  const { success, errors } = logon;
+  const { success, errors, mfa } = logon;
 
   return {
     type: types.RECEIVE_LOGON,
     success,
-    errors
+    errors,
+    mfa
   };
 }
 
@@ -69,7 +70,7 @@ export function receiveConsent(logon) {
   };
 }
 
-export function executeLogon(username, password, mode=ModeLogonUsernamePassword) {
+export function executeLogon(username, password, mode=ModeLogonUsernamePassword, mfa=undefined) {
   return function(dispatch, getState) {
     dispatch(requestLogon(username, password));
     dispatch(receiveHello({
@@ -96,7 +97,8 @@ export function executeLogon(username, password, mode=ModeLogonUsernamePassword)
 
     const r = withClientRequestState({
       params: params,
-      hello: newHelloRequest(flow, query)
+      hello: newHelloRequest(flow, query),
+      mfa: mfa
     });
     return axios.post('./identifier/_/logon', r, {
       headers: {
@@ -227,13 +229,13 @@ export function validateUsernamePassword(username, password, isSignedIn) {
   };
 }
 
-export function executeLogonIfFormValid(username, password, isSignedIn) {
+export function executeLogonIfFormValid(username, password, isSignedIn, mfa=undefined) {
   return (dispatch) => {
     return dispatch(
       validateUsernamePassword(username, password, isSignedIn)
     ).then(() => {
       const mode = isSignedIn ? ModeLogonUsernameEmptyPasswordCookie : ModeLogonUsernamePassword;
-      return dispatch(executeLogon(username, password, mode));
+      return dispatch(executeLogon(username, password, mode, mfa));
     }).catch((errors) => {
       return {
         success: false,
@@ -12,6 +12,7 @@ import Typography from "@material-ui/core/Typography";
 import Link from "@material-ui/core/Link";
 
 import TextInput from "../../components/TextInput";
+import SecondFactor, { RecoveryCodes } from "./SecondFactor";
 
 import {
   updateInput,
@@ -62,6 +63,7 @@ function Login(props) {
     classes,
     username,
     password,
+    mfa,
     passwordResetLink,
     branding,
   } = props;
@@ -94,6 +96,10 @@ function Login(props) {
   }
 
   useEffect(() => {
+    if (mfa && mfa.recoveryCodes) {
+      // wait until the user has seen the recovery codes
+      return;
+    }
     if (hello && hello.state && history.action !== "PUSH") {
       if (!query.prompt || query.prompt.indexOf("select_account") === -1) {
         dispatch(advanceLogonFlow(true, history));
@@ -116,13 +122,54 @@ function Login(props) {
 
     dispatch(executeLogonIfFormValid(username, password, false)).then(
       (response) => {
-        if (response.success) {
+        if (response.success && !response.mfa) {
+          dispatch(advanceLogonFlow(response.success, history));
+        }
+      }
+    );
+  };
+
+  const handleSecondFactor = (factor) => {
+    dispatch(executeLogonIfFormValid(username, password, false, factor)).then(
+      (response) => {
+        if (response.success && !response.mfa) {
           dispatch(advanceLogonFlow(response.success, history));
         }
       }
     );
   };
 
+  if (mfa && mfa.recoveryCodes) {
+    return (
+      <div className={classes.main}>
+        <h1 className={classes.header}>
+          {t("konnect.login.mfa.recoveryCodesHeadline", "Recovery codes")}
+        </h1>
+        <RecoveryCodes
+          codes={mfa.recoveryCodes}
+          classes={classes}
+          onContinue={() => dispatch(advanceLogonFlow(true, history))}
+        />
+      </div>
+    );
+  }
+
+  if (mfa && mfa.required) {
+    return (
+      <div className={classes.main}>
+        <h1 className={classes.header}>
+          {t("konnect.login.mfa.headline", "Second factor")}
+        </h1>
+        <SecondFactor
+          mfa={mfa}
+          loading={loading}
+          classes={classes}
+          onSubmit={handleSecondFactor}
+        />
+      </div>
+    );
+  }
+
   return (
     <div className={classes.main}>
       <h1 className={classes.header}>
@@ -209,6 +256,7 @@ Login.propTypes = {
   loading: PropTypes.string.isRequired,
   username: PropTypes.string.isRequired,
   password: PropTypes.string.isRequired,
+  mfa: PropTypes.object,
   passwordResetLink: PropTypes.string.isRequired,
   errors: PropTypes.object.isRequired,
   branding: PropTypes.object,
@@ -220,13 +268,14 @@ Login.propTypes = {
 };
 
 const mapStateToProps = (state) => {
-  const { loading, username, password, errors } = state.login;
+  const { loading, username, password, mfa, errors } = state.login;
   const { branding, hello, query, passwordResetLink } = state.common;
 
   return {
     loading,
     username,
     password,
+    mfa,
     errors,
     branding,
     hello,
@@ -0,0 +1,161 @@
+import React, { useState } from "react";
+import PropTypes from "prop-types";
+
+import { useTranslation } from "react-i18next";
+
+import Button from "@material-ui/core/Button";
+import Typography from "@material-ui/core/Typography";
+
+import TextInput from "../../components/TextInput";
+import {
+  createCredential,
+  getAssertion,
+  isWebAuthnSupported,
+} from "../../webauthn";
+
+// SecondFactor asks for the second factor of a logon, or for the enrolment of the first one.
+function SecondFactor(props) {
+  const { mfa, loading, classes, onSubmit } = props;
+  const { t } = useTranslation();
+  const [code, setCode] = useState("");
+  const [webAuthnFailed, setWebAuthnFailed] = useState(false);
+
+  const methods = mfa.methods || [];
+  const withWebAuthn =
+    methods.includes("webauthn") && mfa.webauthn && isWebAuthnSupported();
+
+  const handleCodeSubmit = (event) => {
+    event.preventDefault();
+    const value = code.replace(/\s/g, "");
+    if (!value) {
+      return;
+    }
+    // one-time passwords are numeric, recovery codes are not
+    onSubmit(/^\d{6}$/.test(value) ? { totp: value } : { recoveryCode: value });
+  };
+
+  const handleSecurityKey = (event) => {
+    event.preventDefault();
+    setWebAuthnFailed(false);
+    const ceremony = mfa.enrol
+      ? createCredential(mfa.webauthn).then((r) => ({ webauthnRegistration: r }))
+      : getAssertion(mfa.webauthn).then((r) => ({ webauthn: r }));
+    ceremony.then(onSubmit).catch(() => setWebAuthnFailed(true));
+  };
+
+  return (
+    <form action="" className="oc-login-form" onSubmit={handleCodeSubmit}>
+      {mfa.enrol ? (
+        <Typography variant="body2" className={classes.message}>
+          {t(
+            "konnect.login.mfa.enrol",
+            "A second factor is required. Add this key to your authenticator app or use a security key."
+          )}
+          <br />
+          <code id="oc-login-mfa-secret">{mfa.totpSecret}</code>
+          <br />
+          <a href={mfa.totpUri}>{mfa.totpUri}</a>
+        </Typography>
+      ) : (
+        <Typography variant="body2" className={classes.message}>
+          {t(
+            "konnect.login.mfa.verify",
+            "Enter the code of your authenticator app or a recovery code."
+          )}
+        </Typography>
+      )}
+      {(mfa.enrol || methods.includes("totp") || methods.includes("recovery")) && (
+        <TextInput
+          autoFocus
+          autoComplete="one-time-code"
+          autoCapitalize="off"
+          spellCheck="false"
+          value={code}
+          onChange={(event) => setCode(event.target.value)}
+          label={t("konnect.login.mfa.codeField.label", "Code")}
+          id="oc-login-mfa-code"
+          extraClassName={mfa.invalid ? "error" : ""}
+        />
+      )}
+      {(mfa.invalid || webAuthnFailed) && (
+        <Typography
+          id="oc-login-error-message"
+          variant="subtitle2"
+          component="span"
+          color="error"
+          className={classes.message}
+        >
+          {t("konnect.login.mfa.invalid", "The second factor is not valid.")}
+        </Typography>
+      )}
+      <div className={classes.wrapper}>
+        <Button
+          type="submit"
+          color="primary"
+          variant="contained"
+          className="oc-button-primary oc-mt-l"
+          disabled={!!loading}
+          onClick={handleCodeSubmit}
+        >
+          {t("konnect.login.nextButton.label", "Log in")}
+        </Button>
+        {withWebAuthn && (
+          <Button
+            color="secondary"
+            variant="outlined"
+            className="oc-mt-l"
+            disabled={!!loading}
+            onClick={handleSecurityKey}
+          >
+            {mfa.enrol
+              ? t("konnect.login.mfa.registerKey", "Register a security key")
+              : t("konnect.login.mfa.useKey", "Use a security key")}
+          </Button>
+        )}
+      </div>
+    </form>
+  );
+}
+
+SecondFactor.propTypes = {
+  mfa: PropTypes.object.isRequired,
+  loading: PropTypes.string.isRequired,
+  classes: PropTypes.object.isRequired,
+  onSubmit: PropTypes.func.isRequired,
+};
+
+// RecoveryCodes shows the recovery codes once after the enrolment.
+export function RecoveryCodes(props) {
+  const { codes, classes, onContinue } = props;
+  const { t } = useTranslation();
+
+  return (
+    <div>
+      <Typography variant="body2" className={classes.message}>
+        {t(
+          "konnect.login.mfa.recoveryCodes",
+          "Store these recovery codes in a safe place. Each of them can be used once if you lose your second factor."
+        )}
+      </Typography>
+      <pre id="oc-login-mfa-recovery-codes">{codes.join("\n")}</pre>
+      <div className={classes.wrapper}>
+        <Button
+          color="primary"
+          variant="contained"
+          className="oc-button-primary oc-mt-l"
+          onClick={onContinue}
+        >
+          {t("konnect.login.mfa.continueButton.label", "Continue")}
+        </Button>
+      </div>
+    </div>
+  );
+}
+
+RecoveryCodes.propTypes = {
+  codes: PropTypes.array.isRequired,
+  classes: PropTypes.object.isRequired,
+  onContinue: PropTypes.func.isRequired,
+};
+
+export default SecondFactor;
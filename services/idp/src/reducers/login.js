@@ -13,6 +13,7 @@ function loginReducer(state = {
   loading: '',
   username: '',
   password: '',
+  mfa: null,
   errors: {}
 }, action) {
   switch (action.type) {
@@ -31,16 +32,23 @@ function loginReducer(state = {
       });
 
     case RECEIVE_CONSENT:
+      return Object.assign({}, state, {
+        errors: !action.success && action.errors ? action.errors : {},
+        loading: ''
+      });
+
     case RECEIVE_LOGON:
       return Object.assign({}, state, {
         errors: !action.success && action.errors ? action.errors : {},
+        mfa: action.mfa || null,
         loading: ''
       });
 
     case RECEIVE_LOGOFF:
       return Object.assign({}, state, {
         username: '',
-        password: ''
+        password: '',
+        mfa: null
       });
 
     case UPDATE_INPUT:
@@ -0,0 +1,60 @@
+// Helpers running the WebAuthn ceremonies requested by the second factor step of the logon. The
+// binary values are exchanged base64url encoded.
+
+function toBase64URL(buffer) {
+  const bytes = new Uint8Array(buffer);
+  let s = '';
+  bytes.forEach(b => { s += String.fromCharCode(b); });
+  return window.btoa(s).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+}
+
+function fromBase64URL(value) {
+  const s = window.atob(value.replace(/-/g, '+').replace(/_/g, '/'));
+  return Uint8Array.from(s, c => c.charCodeAt(0));
+}
+
+export function isWebAuthnSupported() {
+  return !!(window.PublicKeyCredential && navigator.credentials);
+}
+
+// createCredential registers a new security key.
+export function createCredential(options) {
+  return navigator.credentials.create({
+    publicKey: {
+      challenge: fromBase64URL(options.challenge),
+      rp: { id: options.rpId, name: 'OpenCloud' },
+      user: {
+        id: fromBase64URL(options.userId),
+        name: options.userName,
+        displayName: options.userName
+      },
+      // ES256, EdDSA and RS256
+      pubKeyCredParams: [-7, -8, -257].map(alg => ({ type: 'public-key', alg })),
+      attestation: 'none',
+      timeout: 120000
+    }
+  }).then(credential => ({
+    id: credential.id,
+    clientDataJSON: toBase64URL(credential.response.clientDataJSON),
+    authenticatorData: toBase64URL(credential.response.getAuthenticatorData()),
+    publicKey: toBase64URL(credential.response.getPublicKey()),
+    publicKeyAlgorithm: credential.response.getPublicKeyAlgorithm()
+  }));
+}
+
+// getAssertion signs the challenge with a registered security key.
+export function getAssertion(options) {
+  return navigator.credentials.get({
+    publicKey: {
+      challenge: fromBase64URL(options.challenge),
+      rpId: options.rpId,
+      allowCredentials: (options.credentials || []).map(id => ({ type: 'public-key', id: fromBase64URL(id) })),
+      timeout: 120000
+    }
+  }).then(credential => ({
+    id: credential.id,
+    clientDataJSON: toBase64URL(credential.response.clientDataJSON),
+    authenticatorData: toBase64URL(credential.response.authenticatorData),
+    signature: toBase64URL(credential.response.signature)
+  }));
+}
@@ -0,0 +1,224 @@
+// Package ldap offers an invitation backend for the invitation service that
+// provisions guests via the libregraph identity backend, e.g. in the built-in IDM.
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/ldap"
+
+	"github.com/opencloud-eu/opencloud/pkg/generators"
+	ocldap "github.com/opencloud-eu/opencloud/pkg/ldap"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	graphconfig "github.com/opencloud-eu/opencloud/services/graph/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/identity"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
+)
+
+// initialPasswordLength is the length of the random password invited users
+// are created with. It is never handed out and replaced on redemption.
+const initialPasswordLength = 32
+
+// Backend represents the ldap backend.
+type Backend struct {
+	logger  log.Logger
+	backend identity.Backend
+	// disableUsers is true when invited users are kept disabled until they redeem the invitation
+	disableUsers bool
+}
+
+// New instantiates a new ldap.Backend connected to the configured LDAP server.
+func New(logger log.Logger, cfg config.LDAP) (*Backend, error) {
+	logger = log.Logger{
+		Logger: logger.With().
+			Str("invitationBackend", "ldap").
+			Str("uri", cfg.URI).
+			Logger(),
+	}
+
+	var tlsConf *tls.Config
+	if cfg.Insecure {
+		// When insecure is set to true then we don't need a certificate.
+		cfg.CACert = ""
+		tlsConf = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+
+			//nolint:gosec // We need the ability to run with "insecure" (dev/testing)
+			InsecureSkipVerify: cfg.Insecure,
+		}
+	}
+
+	if cfg.CACert != "" {
+		if err := ocldap.WaitForCA(logger, cfg.Insecure, cfg.CACert); err != nil {
+			return nil, err
+		}
+		certs := x509.NewCertPool()
+		pemData, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, err
+		}
+		if !certs.AppendCertsFromPEM(pemData) {
+			return nil, errors.New("adding the LDAP CA cert failed")
+		}
+		tlsConf = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    certs,
+		}
+	}
+
+	conn := ldap.NewLDAPWithReconnect(
+		ldap.Config{
+			URI:          cfg.URI,
+			BindDN:       cfg.BindDN,
+			BindPassword: cfg.BindPassword,
+			TLSConfig:    tlsConf,
+		},
+	)
+	conn.SetLogger(&logger.Logger)
+
+	lb, err := identity.NewLDAPBackend(conn, graphLDAPConfig(cfg), &logger)
+	if err != nil {
+		return nil, err
+	}
+
+	disableMechanism, err := identity.ParseDisableMechanismType(cfg.DisableUserMechanism)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithIdentityBackend(logger, lb, disableMechanism != identity.DisableMechanismNone), nil
+}
+
+// NewWithIdentityBackend creates a new backend with the supplied identity backend.
+func NewWithIdentityBackend(logger log.Logger, backend identity.Backend, disableUsers bool) *Backend {
+	return &Backend{
+		logger:       logger,
+		backend:      backend,
+		disableUsers: disableUsers,
+	}
+}
+
+// CreateUser creates a guest user in the identity backend. The user gets a random
+// password and, if supported, stays disabled until the invitation is redeemed.
+func (b Backend) CreateUser(ctx context.Context, invitation *invitations.Invitation) (string, error) {
+	b.logger.Info().
+		Str("email", invitation.InvitedUserEmailAddress).
+		Msg("Creating new user")
+
+	password, err := generators.GenerateRandomPassword(initialPasswordLength)
+	if err != nil {
+		return "", err
+	}
+
+	displayName := invitation.InvitedUserDisplayName
+	if displayName == "" {
+		displayName = invitation.InvitedUserEmailAddress
+	}
+
+	user := libregraph.NewUser(displayName, invitation.InvitedUserEmailAddress)
+	user.SetMail(invitation.InvitedUserEmailAddress)
+	user.SetUserType(identity.UserTypeGuest)
+	user.SetPasswordProfile(libregraph.PasswordProfile{Password: &password})
+
+	u, err := b.backend.CreateUser(ctx, *user)
+	if err != nil {
+		b.logger.Error().
+			Str("email", invitation.InvitedUserEmailAddress).
+			Err(err).
+			Msg("Failed to create user")
+		return "", err
+	}
+
+	if b.disableUsers {
+		update := libregraph.NewUserUpdate()
+		update.SetAccountEnabled(false)
+		if _, err := b.backend.UpdateUser(ctx, u.GetId(), *update); err != nil {
+			b.logger.Error().
+				Str("userID", u.GetId()).
+				Err(err).
+				Msg("Failed to disable invited user")
+			return "", err
+		}
+		u.SetAccountEnabled(false)
+	}
+
+	invitation.InvitedUser = u
+	return u.GetId(), nil
+}
+
+// CanSendMail returns false, the invitation mail is sent by the notifications service.
+func (b Backend) CanSendMail() bool { return false }
+
+// SendMail is not supported by the ldap backend.
+func (b Backend) SendMail(_ context.Context, _ string) error {
+	return errors.ErrUnsupported
+}
+
+// RedeemUser sets the password chosen by the invited user and enables the account.
+func (b Backend) RedeemUser(ctx context.Context, userID, password string) error {
+	update := libregraph.NewUserUpdate()
+	update.SetPasswordProfile(libregraph.PasswordProfile{Password: &password})
+	if b.disableUsers {
+		update.SetAccountEnabled(true)
+	}
+
+	if _, err := b.backend.UpdateUser(ctx, userID, *update); err != nil {
+		b.logger.Error().
+			Str("userID", userID).
+			Err(err).
+			Msg("Failed to redeem user")
+		return err
+	}
+	return nil
+}
+
+// DeleteUser deletes the account of an invited user.
+func (b Backend) DeleteUser(ctx context.Context, userID string) error {
+	if err := b.backend.DeleteUser(ctx, userID); err != nil {
+		b.logger.Error().
+			Str("userID", userID).
+			Err(err).
+			Msg("Failed to delete user")
+		return err
+	}
+	return nil
+}
+
+// graphLDAPConfig maps the invitations LDAP settings onto the settings of the graph identity backend.
+func graphLDAPConfig(cfg config.LDAP) graphconfig.LDAP {
+	return graphconfig.LDAP{
+		URI:                      cfg.URI,
+		CACert:                   cfg.CACert,
+		Insecure:                 cfg.Insecure,
+		BindDN:                   cfg.BindDN,
+		BindPassword:             cfg.BindPassword,
+		UsePasswordModExOp:       cfg.UsePasswordModExOp,
+		WriteEnabled:             true,
+		UserBaseDN:               cfg.UserBaseDN,
+		UserSearchScope:          cfg.UserSearchScope,
+		UserFilter:               cfg.UserFilter,
+		UserObjectClass:          cfg.UserObjectClass,
+		UserEmailAttribute:       cfg.UserEmailAttribute,
+		UserDisplayNameAttribute: cfg.UserDisplayNameAttribute,
+		UserNameAttribute:        cfg.UserNameAttribute,
+		UserIDAttribute:          cfg.UserIDAttribute,
+		UserTypeAttribute:        cfg.UserTypeAttribute,
+		UserEnabledAttribute:     cfg.UserEnabledAttribute,
+		DisableUserMechanism:     cfg.DisableUserMechanism,
+		LdapDisabledUsersGroupDN: cfg.LdapDisabledUsersGroupDN,
+		GroupBaseDN:              cfg.GroupBaseDN,
+		GroupCreateBaseDN:        cfg.GroupBaseDN,
+		GroupSearchScope:         cfg.GroupSearchScope,
+		GroupFilter:              cfg.GroupFilter,
+		GroupObjectClass:         cfg.GroupObjectClass,
+		GroupNameAttribute:       cfg.GroupNameAttribute,
+		GroupMemberAttribute:     cfg.GroupMemberAttribute,
+		GroupIDAttribute:         cfg.GroupIDAttribute,
+	}
+}
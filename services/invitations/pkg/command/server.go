@@ -4,19 +4,28 @@ import (
 	"context"
 	"fmt"
 	"os/signal"
+	"time"
 
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/opencloud-eu/opencloud/pkg/config/configlog"
+	"github.com/opencloud-eu/opencloud/pkg/eventstream"
+	"github.com/opencloud-eu/opencloud/pkg/generators"
 	"github.com/opencloud-eu/opencloud/pkg/runner"
 	"github.com/opencloud-eu/opencloud/pkg/tracing"
 	"github.com/opencloud-eu/opencloud/pkg/version"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/config/parser"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/logging"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/metrics"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/server/debug"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/server/http"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/service/v0"
+	"github.com/opencloud-eu/reva/v2/pkg/events/stream"
+	"github.com/opencloud-eu/reva/v2/pkg/store"
 	"github.com/urfave/cli/v2"
+	microstore "go-micro.dev/v4/store"
 )
 
 // Server is the entrypoint for the server command.
@@ -45,12 +54,58 @@ func Server(cfg *config.Config) *cli.Command {
 			metrics := metrics.New(metrics.Logger(logger))
 			metrics.BuildInfo.WithLabelValues(version.GetString()).Set(1)
 
+			connName := generators.GenerateConnectionName(cfg.Service.Name, generators.NTypeBus)
+			publisher, err := stream.NatsFromConfig(connName, false, stream.NatsConfig(cfg.Events))
+			if err != nil {
+				return err
+			}
+			publisher = eventstream.Typed(publisher)
+
+			st := store.Create(
+				store.Store(cfg.Store.Store),
+				store.TTL(cfg.Store.TTL),
+				microstore.Nodes(cfg.Store.Nodes...),
+				microstore.Database(cfg.Store.Database),
+				microstore.Table(cfg.Store.Table),
+				store.Authentication(cfg.Store.AuthUsername, cfg.Store.AuthPassword),
+			)
+
+			// the redemption tokens and the redeem links handed to the notifications service are kept in
+			// their own bucket, they expire with the invitations
+			var tokenkv jetstream.KeyValue
+			if cfg.Store.Store == "nats-js-kv" {
+				natsOptions := nats.Options{
+					Servers:  cfg.Store.Nodes,
+					User:     cfg.Store.AuthUsername,
+					Password: cfg.Store.AuthPassword,
+				}
+				conn, err := natsOptions.Connect()
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				js, err := jetstream.New(conn)
+				if err != nil {
+					return err
+				}
+				tokenkv, err = js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+					Bucket: invitations.TokenBucket,
+					TTL:    cfg.Expiration,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to create bucket (%s): %w", invitations.TokenBucket, err)
+				}
+			}
+
 			gr := runner.NewGroup()
 			{
 
 				svc, err := service.New(
 					service.Logger(logger),
 					service.Config(cfg),
+					service.Store(st),
+					service.Publisher(publisher),
+					service.TokenKeyValue(tokenkv),
 					// service.WithRelationProviders(relationProviders),
 				)
 				if err != nil {
@@ -77,6 +132,28 @@ func Server(cfg *config.Config) *cli.Command {
 				}
 
 				gr.Add(runner.NewGoMicroHttpServerRunner(cfg.Service.Name+".http", server))
+
+				if cfg.Expiration > 0 && cfg.CleanupInterval > 0 {
+					cleanupCtx, cleanupCancel := context.WithCancel(ctx)
+					defer cleanupCancel()
+
+					gr.Add(runner.New(cfg.Service.Name+".cleanup", func() error {
+						ticker := time.NewTicker(cfg.CleanupInterval)
+						defer ticker.Stop()
+						for {
+							select {
+							case <-cleanupCtx.Done():
+								return nil
+							case <-ticker.C:
+								if err := svc.Expire(cleanupCtx); err != nil {
+									logger.Error().Err(err).Msg("failed to clean up expired invitations")
+								}
+							}
+						}
+					}, func() {
+						cleanupCancel()
+					}))
+				}
 			}
 
 			{
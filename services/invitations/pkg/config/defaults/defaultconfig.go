@@ -1,8 +1,11 @@
 package defaults
 
 import (
+	"path"
 	"strings"
+	"time"
 
+	"github.com/opencloud-eu/opencloud/pkg/config/defaults"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/config"
 )
 
@@ -32,6 +35,16 @@ func DefaultConfig() *config.Config {
 		Service: config.Service{
 			Name: "invitations",
 		},
+		Backend:         "keycloak",
+		Expiration:      30 * 24 * time.Hour,
+		CleanupInterval: time.Hour,
+		PasswordPolicy: config.PasswordPolicy{
+			MinCharacters:          8,
+			MinLowerCaseCharacters: 1,
+			MinUpperCaseCharacters: 1,
+			MinDigits:              1,
+			MinSpecialCharacters:   1,
+		},
 		Keycloak: config.Keycloak{
 			BasePath:     "",
 			ClientID:     "",
@@ -39,6 +52,43 @@ func DefaultConfig() *config.Config {
 			ClientRealm:  "",
 			UserRealm:    "",
 		},
+		LDAP: config.LDAP{
+			URI:                      "ldaps://localhost:9235",
+			Insecure:                 false,
+			CACert:                   path.Join(defaults.BaseDataPath(), "idm", "ldap.crt"),
+			BindDN:                   "uid=libregraph,ou=sysusers,o=libregraph-idm",
+			UsePasswordModExOp:       true,
+			UserBaseDN:               "ou=users,o=libregraph-idm",
+			UserSearchScope:          "sub",
+			UserFilter:               "",
+			UserObjectClass:          "inetOrgPerson",
+			UserEmailAttribute:       "mail",
+			UserDisplayNameAttribute: "displayName",
+			UserNameAttribute:        "uid",
+			UserIDAttribute:          "openCloudUUID",
+			UserTypeAttribute:        "openCloudUserType",
+			UserEnabledAttribute:     "openCloudUserEnabled",
+			DisableUserMechanism:     "attribute",
+			LdapDisabledUsersGroupDN: "cn=DisabledUsersGroup,ou=groups,o=libregraph-idm",
+			GroupBaseDN:              "ou=groups,o=libregraph-idm",
+			GroupSearchScope:         "sub",
+			GroupFilter:              "",
+			GroupObjectClass:         "groupOfNames",
+			GroupNameAttribute:       "cn",
+			GroupMemberAttribute:     "member",
+			GroupIDAttribute:         "openCloudUUID",
+		},
+		Events: config.Events{
+			Endpoint:  "127.0.0.1:9233",
+			Cluster:   "opencloud-cluster",
+			EnableTLS: false,
+		},
+		Store: config.Store{
+			Store:    "nats-js-kv",
+			Nodes:    []string{"127.0.0.1:9233"},
+			Database: "invitations",
+			Table:    "",
+		},
 	}
 }
 
@@ -72,7 +122,8 @@ func EnsureDefaults(cfg *config.Config) {
 
 	if cfg.TokenManager == nil && cfg.Commons != nil && cfg.Commons.TokenManager != nil {
 		cfg.TokenManager = &config.TokenManager{
-			JWTSecret: cfg.Commons.TokenManager.JWTSecret,
+			JWTSecret:          cfg.Commons.TokenManager.JWTSecret,
+			PreviousJWTSecrets: cfg.Commons.TokenManager.PreviousJWTSecrets,
 		}
 	} else if cfg.TokenManager == nil {
 		cfg.TokenManager = &config.TokenManager{}
@@ -84,6 +135,10 @@ func EnsureDefaults(cfg *config.Config) {
 				cfg.HTTP.CORS.AllowedOrigins[0] == "https://localhost:9200") {
 		cfg.HTTP.CORS.AllowedOrigins = []string{cfg.Commons.OpenCloudURL}
 	}
+
+	if cfg.RedeemURL == "" && cfg.Commons != nil && cfg.Commons.OpenCloudURL != "" {
+		cfg.RedeemURL = strings.TrimRight(cfg.Commons.OpenCloudURL, "/") + path.Join(cfg.HTTP.Root, "invitations", "redeem")
+	}
 }
 
 func Sanitize(cfg *config.Config) {
@@ -2,12 +2,14 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 
 	occfg "github.com/opencloud-eu/opencloud/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/config/defaults"
 
 	"github.com/opencloud-eu/opencloud/pkg/config/envdecode"
+	"github.com/opencloud-eu/opencloud/pkg/shared"
 )
 
 // ParseConfig loads configuration from known paths.
@@ -33,5 +35,14 @@ func ParseConfig(cfg *config.Config) error {
 }
 
 func Validate(cfg *config.Config) error {
+	switch cfg.Backend {
+	case "keycloak":
+	case "ldap":
+		if cfg.LDAP.BindPassword == "" {
+			return shared.MissingLDAPBindPassword(cfg.Service.Name)
+		}
+	default:
+		return fmt.Errorf("unknown invitations backend: '%s'", cfg.Backend)
+	}
 	return nil
 }
@@ -0,0 +1,109 @@
+package invitations
+
+import (
+	"encoding/json"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+// TokenBucket is the nats key value bucket the invitations service keeps the redemption tokens in and
+// hands the redeem links over to the notifications service in. The links are not put on the event bus,
+// the notifications service removes them once the mail was sent.
+const TokenBucket = "invitations-tokens"
+
+// RedeemLinkKey returns the key a redeem link is handed over with in the TokenBucket.
+func RedeemLinkKey(id string) string {
+	return "link." + id
+}
+
+// InvitationCreated is emitted when a guest was invited. SendInvitationMessage
+// is set when the backend leaves sending the invitation mail to the notifications service,
+// which looks up the redeem link by RedeemLinkID in the TokenBucket
+type InvitationCreated struct {
+	Executant               *user.UserId
+	InvitationID            string
+	InvitedUserID           string
+	InvitedUserDisplayName  string
+	InvitedUserEmailAddress string
+	RedeemLinkID            string
+	SendInvitationMessage   bool
+	CustomizedMessageBody   string
+	MessageLanguage         string
+	CcRecipients            []string
+	Timestamp               *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (InvitationCreated) Unmarshal(v []byte) (interface{}, error) {
+	e := InvitationCreated{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// InvitationResent is emitted when an invitation was sent again. It carries the same
+// information as InvitationCreated, including the id of the new redeem link
+type InvitationResent struct {
+	Executant               *user.UserId
+	InvitationID            string
+	InvitedUserID           string
+	InvitedUserDisplayName  string
+	InvitedUserEmailAddress string
+	RedeemLinkID            string
+	SendInvitationMessage   bool
+	CustomizedMessageBody   string
+	MessageLanguage         string
+	CcRecipients            []string
+	Timestamp               *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (InvitationResent) Unmarshal(v []byte) (interface{}, error) {
+	e := InvitationResent{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// InvitationRevoked is emitted when a pending invitation was revoked
+type InvitationRevoked struct {
+	Executant     *user.UserId
+	InvitationID  string
+	InvitedUserID string
+	Timestamp     *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (InvitationRevoked) Unmarshal(v []byte) (interface{}, error) {
+	e := InvitationRevoked{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// InvitationExpired is emitted when a pending invitation was removed because it
+// has not been redeemed in time
+type InvitationExpired struct {
+	InvitationID  string
+	InvitedUserID string
+	Timestamp     *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (InvitationExpired) Unmarshal(v []byte) (interface{}, error) {
+	e := InvitationExpired{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// InvitationRedeemed is emitted when an invited guest redeemed the invitation
+type InvitationRedeemed struct {
+	InvitationID  string
+	InvitedUserID string
+	Timestamp     *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (InvitationRedeemed) Unmarshal(v []byte) (interface{}, error) {
+	e := InvitationRedeemed{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
@@ -1,9 +1,24 @@
 package invitations
 
-import libregraph "github.com/opencloud-eu/libre-graph-api-go"
+import (
+	"time"
+
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+)
+
+// The possible values of the Invitation.Status property
+const (
+	StatusPendingAcceptance = "PendingAcceptance"
+	StatusCompleted         = "Completed"
+	StatusInProgress        = "InProgress"
+	StatusError             = "Error"
+)
 
 // Invitation represents an invitation as per https://learn.microsoft.com/en-us/graph/api/resources/invitation?view=graph-rest-1.0
 type Invitation struct {
+	// The unique identifier of the invitation. Read-only.
+	ID string `json:"id,omitempty"`
+
 	// The display name of the user being invited.
 	InvitedUserDisplayName string `json:"invitedUserDisplayName,omitempty"`
 
@@ -35,6 +50,11 @@ type Invitation struct {
 	// The status of the invitation. Possible values are:
 	// `PendingAcceptance`, `Completed`, `InProgress`, and `Error`.
 	Status string `json:"status,omitempty"`
+	// The date and time the invitation was created. Read-only.
+	CreatedDateTime *time.Time `json:"createdDateTime,omitempty"`
+	// The date and time the invitation expires if it has not been
+	// redeemed until then. Read-only.
+	ExpirationDateTime *time.Time `json:"expirationDateTime,omitempty"`
 
 	// Relations
 
@@ -0,0 +1,22 @@
+package http
+
+import "html/template"
+
+// redeemForm is a minimal page for invited users to choose their password.
+var redeemForm = template.Must(template.New("redeem").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Accept invitation</title>
+</head>
+<body>
+<form method="post">
+<input type="hidden" name="token" value="{{ . }}">
+<label for="password">Choose a password</label>
+<input type="password" id="password" name="password" autocomplete="new-password" required>
+<button type="submit">Accept invitation</button>
+</form>
+</body>
+</html>
+`))
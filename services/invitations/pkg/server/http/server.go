@@ -2,8 +2,10 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
@@ -63,6 +65,7 @@ func Server(opts ...Option) (ohttp.Service, error) {
 	mux.Use(middleware.ExtractAccountUUID(
 		account.Logger(options.Logger),
 		account.JWTSecret(options.Config.TokenManager.JWTSecret),
+		account.PreviousJWTSecrets(options.Config.TokenManager.PreviousJWTSecrets),
 	))
 
 	// this logs http request related data
@@ -72,6 +75,14 @@ func Server(opts ...Option) (ohttp.Service, error) {
 
 	mux.Route(options.Config.HTTP.Root, func(r chi.Router) {
 		r.Post("/invitations", InvitationHandler(service))
+		r.Get("/invitations", ListInvitationsHandler(service))
+		r.Get("/invitations/redeem", RedeemFormHandler())
+		r.Post("/invitations/redeem", RedeemHandler(service))
+		r.Route("/invitations/{invitationID}", func(r chi.Router) {
+			r.Get("/", GetInvitationHandler(service))
+			r.Delete("/", RevokeInvitationHandler(service))
+			r.Post("/resend", ResendInvitationHandler(service))
+		})
 	})
 
 	err = micro.RegisterHandler(svc.Server(), mux)
@@ -95,6 +106,10 @@ func InvitationHandler(service svc.Service) func(w http.ResponseWriter, r *http.
 		}
 
 		res, err := service.Invite(ctx, i)
+		if errors.Is(err, svc.ErrBadRequest) || errors.Is(err, svc.ErrMissingEmail) {
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
 		if err != nil {
 			render.Status(r, http.StatusInternalServerError)
 			render.PlainText(w, r, err.Error())
@@ -105,3 +120,138 @@ func InvitationHandler(service svc.Service) func(w http.ResponseWriter, r *http.
 		render.JSON(w, r, res)
 	}
 }
+
+// ListInvitationsHandler lists all pending and redeemed invitations
+func ListInvitationsHandler(service svc.Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := service.List(r.Context())
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, &listResponse{Value: res})
+	}
+}
+
+// GetInvitationHandler returns a single invitation
+func GetInvitationHandler(service svc.Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := service.Get(r.Context(), chi.URLParam(r, "invitationID"))
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, res)
+	}
+}
+
+// ResendInvitationHandler sends the invitation mail of a pending invitation again
+func ResendInvitationHandler(service svc.Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := service.Resend(r.Context(), chi.URLParam(r, "invitationID"))
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, res)
+	}
+}
+
+// RevokeInvitationHandler revokes a pending invitation
+func RevokeInvitationHandler(service svc.Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := service.Revoke(r.Context(), chi.URLParam(r, "invitationID")); err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		render.NoContent(w, r)
+	}
+}
+
+// listResponse is the collection response of the list endpoint
+type listResponse struct {
+	Value []*invitations.Invitation `json:"value"`
+}
+
+// renderError maps the errors of the invitations service to graph errors
+func renderError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, svc.ErrNotFound):
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, svc.ErrBadRequest):
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, svc.ErrNotPending):
+		errorcode.NotAllowed.Render(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, svc.ErrNotSupported):
+		errorcode.NotSupported.Render(w, r, http.StatusNotImplemented, err.Error())
+	default:
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// redeemRequest is the body of a request to redeem an invitation
+type redeemRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// RedeemFormHandler renders a form the invited user can choose a password with
+func RedeemFormHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := redeemForm.Execute(w, r.URL.Query().Get("token")); err != nil {
+			errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// RedeemHandler redeems an invitation. It accepts JSON as well as form encoded
+// requests. Form submissions get redirected to the inviteRedirectUrl.
+func RedeemHandler(service svc.Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req := redeemRequest{}
+		isForm := strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+		if isForm {
+			req.Token = r.PostFormValue("token")
+			req.Password = r.PostFormValue("password")
+		} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err.Error()))
+			return
+		}
+
+		res, err := service.Redeem(ctx, req.Token, req.Password)
+		switch {
+		case errors.Is(err, svc.ErrMissingToken), errors.Is(err, svc.ErrMissingPassword), errors.Is(err, svc.ErrInvalidToken),
+			errors.Is(err, svc.ErrInvalidPassword):
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, svc.ErrNotSupported):
+			errorcode.NotSupported.Render(w, r, http.StatusNotImplemented, err.Error())
+			return
+		case err != nil:
+			errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if isForm {
+			redirect := res.InviteRedirectUrl
+			if redirect == "" {
+				redirect = "/"
+			}
+			http.Redirect(w, r, redirect, http.StatusSeeOther)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, res)
+	}
+}
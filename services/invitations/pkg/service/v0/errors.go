@@ -3,8 +3,14 @@ package service
 import "errors"
 
 var (
-	ErrNotFound     = errors.New("query target not found")
-	ErrBadRequest   = errors.New("bad request")
-	ErrMissingEmail = errors.New("missing email address")
-	ErrBackend      = errors.New("backend error")
+	ErrNotFound        = errors.New("query target not found")
+	ErrBadRequest      = errors.New("bad request")
+	ErrMissingEmail    = errors.New("missing email address")
+	ErrBackend         = errors.New("backend error")
+	ErrNotSupported    = errors.New("not supported by the backend")
+	ErrInvalidToken    = errors.New("invalid or expired redemption token")
+	ErrMissingToken    = errors.New("missing redemption token")
+	ErrMissingPassword = errors.New("missing password")
+	ErrInvalidPassword = errors.New("password does not comply with the password policy")
+	ErrNotPending      = errors.New("invitation is not pending acceptance")
 )
@@ -36,3 +36,33 @@ func (i instrument) Invite(ctx context.Context, invitation *invitations.Invitati
 
 	return i.next.Invite(ctx, invitation)
 }
+
+// Redeem implements the Service interface.
+func (i instrument) Redeem(ctx context.Context, token, password string) (*invitations.Invitation, error) {
+	return i.next.Redeem(ctx, token, password)
+}
+
+// List implements the Service interface.
+func (i instrument) List(ctx context.Context) ([]*invitations.Invitation, error) {
+	return i.next.List(ctx)
+}
+
+// Get implements the Service interface.
+func (i instrument) Get(ctx context.Context, id string) (*invitations.Invitation, error) {
+	return i.next.Get(ctx, id)
+}
+
+// Resend implements the Service interface.
+func (i instrument) Resend(ctx context.Context, id string) (*invitations.Invitation, error) {
+	return i.next.Resend(ctx, id)
+}
+
+// Revoke implements the Service interface.
+func (i instrument) Revoke(ctx context.Context, id string) error {
+	return i.next.Revoke(ctx, id)
+}
+
+// Expire implements the Service interface.
+func (i instrument) Expire(ctx context.Context) error {
+	return i.next.Expire(ctx)
+}
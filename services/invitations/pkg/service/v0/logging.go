@@ -28,3 +28,54 @@ func (l logging) Invite(ctx context.Context, invitation *invitations.Invitation)
 
 	return l.next.Invite(ctx, invitation)
 }
+
+// Redeem implements the Service interface.
+func (l logging) Redeem(ctx context.Context, token, password string) (*invitations.Invitation, error) {
+	l.logger.Debug().
+		Msg("Redeem")
+
+	return l.next.Redeem(ctx, token, password)
+}
+
+// List implements the Service interface.
+func (l logging) List(ctx context.Context) ([]*invitations.Invitation, error) {
+	l.logger.Debug().
+		Msg("List")
+
+	return l.next.List(ctx)
+}
+
+// Get implements the Service interface.
+func (l logging) Get(ctx context.Context, id string) (*invitations.Invitation, error) {
+	l.logger.Debug().
+		Str("invitation", id).
+		Msg("Get")
+
+	return l.next.Get(ctx, id)
+}
+
+// Resend implements the Service interface.
+func (l logging) Resend(ctx context.Context, id string) (*invitations.Invitation, error) {
+	l.logger.Debug().
+		Str("invitation", id).
+		Msg("Resend")
+
+	return l.next.Resend(ctx, id)
+}
+
+// Revoke implements the Service interface.
+func (l logging) Revoke(ctx context.Context, id string) error {
+	l.logger.Debug().
+		Str("invitation", id).
+		Msg("Revoke")
+
+	return l.next.Revoke(ctx, id)
+}
+
+// Expire implements the Service interface.
+func (l logging) Expire(ctx context.Context) error {
+	l.logger.Debug().
+		Msg("Expire")
+
+	return l.next.Expire(ctx)
+}
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/config"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"go-micro.dev/v4/store"
 )
 
 // Option defines a single option function.
@@ -10,8 +13,12 @@ type Option func(o *Options)
 
 // Options defines the available options for this package.
 type Options struct {
-	Logger log.Logger
-	Config *config.Config
+	Logger    log.Logger
+	Config    *config.Config
+	Store     store.Store
+	Publisher events.Publisher
+	// TokenKeyValue is the invitations.TokenBucket, it is required for sending invitation mails
+	TokenKeyValue jetstream.KeyValue
 }
 
 // newOptions initializes the available default options.
@@ -38,3 +45,24 @@ func Config(val *config.Config) Option {
 		o.Config = val
 	}
 }
+
+// Store provides a function to set the store option.
+func Store(val store.Store) Option {
+	return func(o *Options) {
+		o.Store = val
+	}
+}
+
+// Publisher provides a function to set the events publisher option.
+func Publisher(val events.Publisher) Option {
+	return func(o *Options) {
+		o.Publisher = val
+	}
+}
+
+// TokenKeyValue provides a function to set the token key value option.
+func TokenKeyValue(val jetstream.KeyValue) Option {
+	return func(o *Options) {
+		o.TokenKeyValue = val
+	}
+}
@@ -1,13 +1,31 @@
 package service
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/password"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	"github.com/opencloud-eu/opencloud/pkg/config/defaults"
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/backends/keycloak"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/backends/ldap"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/store"
 )
 
 // Service defines the extension handlers.
@@ -27,6 +45,23 @@ type Service interface {
 	//    invited user has to go through the redemption process to access any
 	//    resources they have been invited to.
 	Invite(ctx context.Context, invitation *invitations.Invitation) (*invitations.Invitation, error)
+	// Redeem redeems an invitation using the one-time token that was sent to the invited user
+	// and sets the password the user chose. Only backends implementing the Redeemer interface
+	// support the redemption through this service.
+	Redeem(ctx context.Context, token, password string) (*invitations.Invitation, error)
+	// List returns all invitations the current user created that are pending or have been redeemed.
+	List(ctx context.Context) ([]*invitations.Invitation, error)
+	// Get returns the invitation with the given id. List, Get, Resend and Revoke only
+	// operate on invitations created by the current user.
+	Get(ctx context.Context, id string) (*invitations.Invitation, error)
+	// Resend sends the invitation mail again. For backends leaving the redemption to this
+	// service a new redemption token is issued, which invalidates the previous one.
+	Resend(ctx context.Context, id string) (*invitations.Invitation, error)
+	// Revoke revokes a pending invitation and deletes the guest account created for it.
+	Revoke(ctx context.Context, id string) error
+	// Expire removes all pending invitations that have not been redeemed in time together
+	// with their never activated guest accounts.
+	Expire(ctx context.Context) error
 }
 
 // Backend defines the behaviour of a user backend.
@@ -39,32 +74,78 @@ type Backend interface {
 	SendMail(ctx context.Context, identifier string) error
 }
 
+// Redeemer is implemented by backends which leave the redemption of invitations to this service.
+type Redeemer interface {
+	// RedeemUser sets the password of the invited user and activates the account.
+	RedeemUser(ctx context.Context, userID, password string) error
+	// DeleteUser deletes the account of an invited user that never redeemed the invitation.
+	DeleteUser(ctx context.Context, userID string) error
+}
+
 // New returns a new instance of Service
 func New(opts ...Option) (Service, error) {
 	options := newOptions(opts...)
 
-	// Harcode keycloak backend for now, but this should be configurable in the future.
-	backend := keycloak.New(
-		options.Logger,
-		options.Config.Keycloak.BasePath,
-		options.Config.Keycloak.ClientID,
-		options.Config.Keycloak.ClientSecret,
-		options.Config.Keycloak.ClientRealm,
-		options.Config.Keycloak.UserRealm,
-		options.Config.Keycloak.InsecureSkipVerify,
-	)
+	var backend Backend
+	switch options.Config.Backend {
+	case "keycloak":
+		backend = keycloak.New(
+			options.Logger,
+			options.Config.Keycloak.BasePath,
+			options.Config.Keycloak.ClientID,
+			options.Config.Keycloak.ClientSecret,
+			options.Config.Keycloak.ClientRealm,
+			options.Config.Keycloak.UserRealm,
+			options.Config.Keycloak.InsecureSkipVerify,
+		)
+	case "ldap":
+		var err error
+		backend, err = ldap.New(options.Logger, options.Config.LDAP)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown invitations backend: '%s'", options.Config.Backend)
+	}
+
+	return NewWithBackend(backend, opts...)
+}
+
+// NewWithBackend returns a new instance of Service using the given backend
+func NewWithBackend(backend Backend, opts ...Option) (Service, error) {
+	options := newOptions(opts...)
+
+	if options.Store == nil {
+		return nil, errors.New("missing store")
+	}
+
+	st := store.New(options.Store)
+	if options.TokenKeyValue != nil {
+		st = store.NewWithTokens(options.Store, options.TokenKeyValue)
+	}
+
+	passwordPolicy, err := newPasswordPolicy(options.Config.PasswordPolicy)
+	if err != nil {
+		return nil, err
+	}
 
 	return svc{
-		log:     options.Logger,
-		config:  options.Config,
-		backend: backend,
+		log:            options.Logger,
+		config:         options.Config,
+		backend:        backend,
+		store:          st,
+		publisher:      options.Publisher,
+		passwordPolicy: passwordPolicy,
 	}, nil
 }
 
 type svc struct {
-	config  *config.Config
-	log     log.Logger
-	backend Backend
+	config         *config.Config
+	log            log.Logger
+	backend        Backend
+	store          *store.Store
+	publisher      events.Publisher
+	passwordPolicy password.Validator
 }
 
 // Invite implements the service interface
@@ -77,13 +158,64 @@ func (s svc) Invite(ctx context.Context, invitation *invitations.Invitation) (*i
 		return nil, ErrMissingEmail
 	}
 
+	if invitation.InviteRedirectUrl != "" && !s.redirectAllowed(invitation.InviteRedirectUrl) {
+		return nil, fmt.Errorf("%w: the redirect url must point to OpenCloud", ErrBadRequest)
+	}
+
+	// everything that can fail without side effects happens before the guest is created
+	sendMessage := !s.backend.CanSendMail() && invitation.SendInvitationMessage
+	if sendMessage && s.publisher == nil {
+		return nil, fmt.Errorf("%w: no events publisher configured for sending mails", ErrBackend)
+	}
+	var token, tokenHash, redeemLinkID string
+	if !s.backend.CanSendMail() {
+		// the backend leaves the redemption to us, hand out a one-time token
+		var err error
+		token, tokenHash, err = store.NewToken()
+		if err != nil {
+			return nil, err
+		}
+		invitation.InviteRedeemUrl, err = s.redeemURL(token)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if sendMessage {
+		var err error
+		redeemLinkID, err = s.store.HandOverRedeemLink(invitation.InviteRedeemUrl)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrBackend, err)
+		}
+	}
+
 	id, err := s.backend.CreateUser(ctx, invitation)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrBackend, err)
 	}
 
-	// As we only have a single backend, and that backend supports email, we don't have
-	// any code to handle mailing ourself yet.
+	record := &store.Record{
+		ID:                      uuid.New().String(),
+		InvitedUserID:           id,
+		InvitedUserDisplayName:  invitation.InvitedUserDisplayName,
+		InvitedUserEmailAddress: invitation.InvitedUserEmailAddress,
+		InviteRedirectURL:       invitation.InviteRedirectUrl,
+		Status:                  invitations.StatusPendingAcceptance,
+		TokenHash:               tokenHash,
+	}
+	if s.config.Expiration > 0 {
+		record.ExpiresAt = time.Now().Add(s.config.Expiration)
+	}
+	if info := invitation.InvitedUserMessageInfo; info != nil {
+		record.CustomizedMessageBody = info.CustomizedMessageBody
+		record.MessageLanguage = info.MessageLanguage
+		for _, r := range info.CcRecipients {
+			record.CcRecipients = append(record.CcRecipients, r.EmailAddress.Aaddress)
+		}
+	}
+	if u, ok := revactx.ContextGetUser(ctx); ok {
+		record.CreatedBy = u.GetId().GetOpaqueId()
+	}
+
 	if s.backend.CanSendMail() {
 		err := s.backend.SendMail(ctx, id)
 		if err != nil {
@@ -91,5 +223,441 @@ func (s svc) Invite(ctx context.Context, invitation *invitations.Invitation) (*i
 		}
 	}
 
+	if err := s.store.Save(record); err != nil {
+		s.rollback(ctx, record)
+		return nil, err
+	}
+
+	invitation.ID = record.ID
+	invitation.Status = record.Status
+	invitation.CreatedDateTime, invitation.ExpirationDateTime = timestamps(record)
+
+	if err := s.publish(ctx, s.mailEvent(ctx, record, redeemLinkID, sendMessage)); err != nil {
+		if sendMessage {
+			// the guest would never learn about the invitation
+			s.rollback(ctx, record)
+			return nil, err
+		}
+		s.log.Error().Err(err).Str("invitation", record.ID).Msg("failed to publish InvitationCreated event")
+	}
+
 	return invitation, nil
 }
+
+// Redeem implements the service interface
+func (s svc) Redeem(ctx context.Context, token, password string) (*invitations.Invitation, error) {
+	switch {
+	case token == "":
+		return nil, ErrMissingToken
+	case password == "":
+		return nil, ErrMissingPassword
+	}
+
+	redeemer, ok := s.backend.(Redeemer)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	if s.passwordPolicy != nil {
+		if err := s.passwordPolicy.Validate(password); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPassword, err)
+		}
+	}
+
+	record, err := s.store.GetByToken(token)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return nil, ErrInvalidToken
+	case err != nil:
+		return nil, err
+	case record.Status != invitations.StatusPendingAcceptance, record.Expired(time.Now()):
+		return nil, ErrInvalidToken
+	}
+
+	// the token can only be used once, only one of concurrent redemptions gets to claim it
+	err = s.store.ClaimToken(record)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return nil, ErrInvalidToken
+	case err != nil:
+		return nil, err
+	}
+
+	if err := redeemer.RedeemUser(ctx, record.InvitedUserID, password); err != nil {
+		// give the token back, the guest may try again
+		if err := s.store.Save(record); err != nil {
+			s.log.Error().Err(err).Str("invitation", record.ID).Msg("failed to restore the redemption token")
+		}
+		return nil, fmt.Errorf("%w: %s", ErrBackend, err)
+	}
+
+	record.TokenHash = ""
+	record.Status = invitations.StatusCompleted
+	if err := s.store.Save(record); err != nil {
+		return nil, err
+	}
+
+	if err := s.publish(ctx, invitations.InvitationRedeemed{
+		InvitationID:  record.ID,
+		InvitedUserID: record.InvitedUserID,
+		Timestamp:     utils.TSNow(),
+	}); err != nil {
+		s.log.Error().Err(err).Str("invitation", record.ID).Msg("failed to publish InvitationRedeemed event")
+	}
+
+	invitation := toInvitation(record)
+	if !s.redirectAllowed(invitation.InviteRedirectUrl) {
+		// invitations created before the redirect url was validated
+		invitation.InviteRedirectUrl = ""
+	}
+	return invitation, nil
+}
+
+// List implements the service interface
+func (s svc) List(ctx context.Context) ([]*invitations.Invitation, error) {
+	records, err := s.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+
+	invs := make([]*invitations.Invitation, 0, len(records))
+	for _, r := range records {
+		if !createdBy(ctx, r) {
+			continue
+		}
+		invs = append(invs, toInvitation(r))
+	}
+	return invs, nil
+}
+
+// Get implements the service interface
+func (s svc) Get(ctx context.Context, id string) (*invitations.Invitation, error) {
+	record, err := s.getRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toInvitation(record), nil
+}
+
+// Resend implements the service interface
+func (s svc) Resend(ctx context.Context, id string) (*invitations.Invitation, error) {
+	record, err := s.getPendingRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := toInvitation(record)
+	var redeemLinkID string
+	if s.backend.CanSendMail() {
+		if err := s.backend.SendMail(ctx, record.InvitedUserID); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrBackend, err)
+		}
+	} else {
+		if s.publisher == nil {
+			return nil, fmt.Errorf("%w: no events publisher configured for sending mails", ErrBackend)
+		}
+		token, hash, err := store.NewToken()
+		if err != nil {
+			return nil, err
+		}
+		invitation.InviteRedeemUrl, err = s.redeemURL(token)
+		if err != nil {
+			return nil, err
+		}
+		redeemLinkID, err = s.store.HandOverRedeemLink(invitation.InviteRedeemUrl)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrBackend, err)
+		}
+		// issue a new token, the previous one must not be usable anymore
+		if err := s.store.DeleteToken(record); err != nil {
+			return nil, err
+		}
+		record.TokenHash = hash
+	}
+
+	if s.config.Expiration > 0 {
+		record.ExpiresAt = time.Now().Add(s.config.Expiration)
+	}
+	if err := s.store.Save(record); err != nil {
+		return nil, err
+	}
+	invitation.CreatedDateTime, invitation.ExpirationDateTime = timestamps(record)
+
+	sendMessage := !s.backend.CanSendMail()
+	if err := s.publish(ctx, invitations.InvitationResent(s.mailEvent(ctx, record, redeemLinkID, sendMessage))); err != nil {
+		if sendMessage {
+			return nil, err
+		}
+		s.log.Error().Err(err).Str("invitation", record.ID).Msg("failed to publish InvitationResent event")
+	}
+
+	return invitation, nil
+}
+
+// Revoke implements the service interface
+func (s svc) Revoke(ctx context.Context, id string) error {
+	// only backends leaving the redemption to us know whether the guest is still pending
+	redeemer, ok := s.backend.(Redeemer)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	record, err := s.getPendingRecord(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.remove(ctx, redeemer, record); err != nil {
+		return err
+	}
+
+	ev := invitations.InvitationRevoked{
+		InvitationID:  record.ID,
+		InvitedUserID: record.InvitedUserID,
+		Timestamp:     utils.TSNow(),
+	}
+	if u, ok := revactx.ContextGetUser(ctx); ok {
+		ev.Executant = u.GetId()
+	}
+	if err := s.publish(ctx, ev); err != nil {
+		s.log.Error().Err(err).Str("invitation", record.ID).Msg("failed to publish InvitationRevoked event")
+	}
+	return nil
+}
+
+// Expire implements the service interface
+func (s svc) Expire(ctx context.Context) error {
+	redeemer, ok := s.backend.(Redeemer)
+	if !ok {
+		// invitations redeemed in the backend are never tracked as completed, so we must not expire them
+		return nil
+	}
+
+	records, err := s.store.List()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var errs []error
+	for _, record := range records {
+		if record.Status != invitations.StatusPendingAcceptance || !record.Expired(now) {
+			continue
+		}
+
+		if err := s.remove(ctx, redeemer, record); err != nil {
+			errs = append(errs, fmt.Errorf("could not expire invitation '%s': %w", record.ID, err))
+			continue
+		}
+
+		s.log.Info().Str("invitation", record.ID).Str("userID", record.InvitedUserID).Msg("invitation expired")
+		if err := s.publish(ctx, invitations.InvitationExpired{
+			InvitationID:  record.ID,
+			InvitedUserID: record.InvitedUserID,
+			Timestamp:     utils.TSNow(),
+		}); err != nil {
+			s.log.Error().Err(err).Str("invitation", record.ID).Msg("failed to publish InvitationExpired event")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// remove deletes the guest account of a pending invitation and the invitation itself
+func (s svc) remove(ctx context.Context, redeemer Redeemer, record *store.Record) error {
+	if err := redeemer.DeleteUser(ctx, record.InvitedUserID); err != nil {
+		return fmt.Errorf("%w: %s", ErrBackend, err)
+	}
+	return s.store.Delete(record)
+}
+
+// rollback removes the guest account and the record of an invitation that could not be completed.
+// Backends that don't support deleting users keep the account.
+func (s svc) rollback(ctx context.Context, record *store.Record) {
+	if redeemer, ok := s.backend.(Redeemer); ok {
+		if err := redeemer.DeleteUser(ctx, record.InvitedUserID); err != nil {
+			s.log.Error().Err(err).Str("userID", record.InvitedUserID).Msg("failed to delete the guest of a failed invitation")
+		}
+	}
+	if err := s.store.Delete(record); err != nil {
+		s.log.Error().Err(err).Str("invitation", record.ID).Msg("failed to delete a failed invitation")
+	}
+}
+
+// getRecord returns the record with the given id if it was created by the current user
+func (s svc) getRecord(ctx context.Context, id string) (*store.Record, error) {
+	if id == "" {
+		return nil, ErrBadRequest
+	}
+	record, err := s.store.Get(id)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, err
+	case !createdBy(ctx, record):
+		// don't leak the existence of invitations created by others
+		return nil, ErrNotFound
+	}
+	return record, nil
+}
+
+// getPendingRecord returns the record with the given id if the invitation has not been redeemed yet
+func (s svc) getPendingRecord(ctx context.Context, id string) (*store.Record, error) {
+	record, err := s.getRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != invitations.StatusPendingAcceptance {
+		return nil, ErrNotPending
+	}
+	return record, nil
+}
+
+// redeemURL returns the URL the invited user can redeem the invitation with
+func (s svc) redeemURL(token string) (string, error) {
+	u, err := url.Parse(s.config.RedeemURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// redirectAllowed returns true if the invited user may be redirected to the given url after the
+// redemption. Only relative paths and urls on the OpenCloud origin are allowed.
+func (s svc) redirectAllowed(redirect string) bool {
+	if redirect == "" {
+		return true
+	}
+	// browsers treat backslashes like slashes, "/\host" would leave the origin
+	if strings.Contains(redirect, "\\") {
+		return false
+	}
+	u, err := url.Parse(redirect)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" && u.Host == "" && u.Opaque == "" {
+		return strings.HasPrefix(redirect, "/") && !strings.HasPrefix(redirect, "//")
+	}
+	if s.config.Commons == nil || s.config.Commons.OpenCloudURL == "" {
+		return false
+	}
+	origin, err := url.Parse(s.config.Commons.OpenCloudURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Scheme, origin.Scheme) && strings.EqualFold(u.Host, origin.Host) && u.User == nil
+}
+
+// mailEvent returns the information needed by the notifications service for sending the invitation mail
+func (s svc) mailEvent(ctx context.Context, record *store.Record, redeemLinkID string, sendMessage bool) invitations.InvitationCreated {
+	ev := invitations.InvitationCreated{
+		InvitationID:            record.ID,
+		InvitedUserID:           record.InvitedUserID,
+		InvitedUserDisplayName:  record.InvitedUserDisplayName,
+		InvitedUserEmailAddress: record.InvitedUserEmailAddress,
+		RedeemLinkID:            redeemLinkID,
+		SendInvitationMessage:   sendMessage,
+		CustomizedMessageBody:   record.CustomizedMessageBody,
+		MessageLanguage:         record.MessageLanguage,
+		CcRecipients:            record.CcRecipients,
+		Timestamp:               utils.TSNow(),
+	}
+	if u, ok := revactx.ContextGetUser(ctx); ok {
+		ev.Executant = u.GetId()
+	}
+	return ev
+}
+
+// publish publishes the event if a publisher is configured
+func (s svc) publish(ctx context.Context, ev interface{}) error {
+	if s.publisher == nil {
+		return nil
+	}
+	return events.Publish(ctx, s.publisher, ev)
+}
+
+// newPasswordPolicy returns the validator for the passwords invited users choose, nil if the policy is disabled
+func newPasswordPolicy(cfg config.PasswordPolicy) (password.Validator, error) {
+	if cfg.Disabled {
+		return nil, nil
+	}
+	var banned map[string]struct{}
+	if cfg.BannedPasswordsList != "" {
+		var err error
+		banned, err = readBannedPasswords(cfg.BannedPasswordsList)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the banned passwords from a file %s: %w", cfg.BannedPasswordsList, err)
+		}
+	}
+	return password.NewPasswordPolicy(
+		cfg.MinCharacters,
+		cfg.MinLowerCaseCharacters,
+		cfg.MinUpperCaseCharacters,
+		cfg.MinDigits,
+		cfg.MinSpecialCharacters,
+		banned,
+	), nil
+}
+
+// readBannedPasswords reads the file with one banned password per line. Relative paths are
+// resolved against the config directory.
+func readBannedPasswords(path string) (map[string]struct{}, error) {
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		path = filepath.Join(defaults.BaseConfigPath(), path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	banned := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			banned[line] = struct{}{}
+		}
+	}
+	return banned, scanner.Err()
+}
+
+// createdBy returns true if the record was created by the user in the context
+func createdBy(ctx context.Context, record *store.Record) bool {
+	u, ok := revactx.ContextGetUser(ctx)
+	return ok && record.CreatedBy != "" && record.CreatedBy == u.GetId().GetOpaqueId()
+}
+
+// toInvitation converts a stored record to an invitation. The redemption
+// token is not part of the result, it is only known at creation time.
+func toInvitation(record *store.Record) *invitations.Invitation {
+	inv := &invitations.Invitation{
+		ID:                      record.ID,
+		InvitedUserDisplayName:  record.InvitedUserDisplayName,
+		InvitedUserEmailAddress: record.InvitedUserEmailAddress,
+		InviteRedirectUrl:       record.InviteRedirectURL,
+		Status:                  record.Status,
+		InvitedUser: &libregraph.User{
+			Id: libregraph.PtrString(record.InvitedUserID),
+		},
+	}
+	inv.CreatedDateTime, inv.ExpirationDateTime = timestamps(record)
+	return inv
+}
+
+// timestamps returns the creation and expiration time of the record as they are exposed on the invitation
+func timestamps(record *store.Record) (*time.Time, *time.Time) {
+	created := record.CreatedAt
+	if record.ExpiresAt.IsZero() {
+		return &created, nil
+	}
+	expires := record.ExpiresAt
+	return &created, &expires
+}
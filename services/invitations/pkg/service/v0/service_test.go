@@ -0,0 +1,380 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+
+	nserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	libregraph "github.com/opencloud-eu/libre-graph-api-go"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	microevents "go-micro.dev/v4/events"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/pkg/shared"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/identity"
+	identitymocks "github.com/opencloud-eu/opencloud/services/graph/pkg/identity/mocks"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/backends/ldap"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
+	service "github.com/opencloud-eu/opencloud/services/invitations/pkg/service/v0"
+)
+
+type publisher struct {
+	events []interface{}
+	err    error
+}
+
+func (p *publisher) Publish(_ string, ev interface{}, _ ...microevents.PublishOption) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, ev)
+	return nil
+}
+
+func newService(t *testing.T, ib *identitymocks.Backend, pub *publisher) (service.Service, jetstream.KeyValue) {
+	return newServiceWithConfig(t, ib, pub, &config.Config{RedeemURL: "https://cloud.example.org/graph/v1.0/invitations/redeem"})
+}
+
+func newServiceWithConfig(t *testing.T, ib *identitymocks.Backend, pub *publisher, cfg *config.Config) (service.Service, jetstream.KeyValue) {
+	s, err := nserver.NewServer(&nserver.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	require.NoError(t, err)
+	s.Start()
+	t.Cleanup(s.Shutdown)
+	require.True(t, s.ReadyForConnections(10*time.Second))
+
+	conn, err := nats.Connect("", nats.InProcessServer(s))
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+	kv, err := js.CreateKeyValue(context.Background(), jetstream.KeyValueConfig{Bucket: invitations.TokenBucket})
+	require.NoError(t, err)
+
+	svc, err := service.NewWithBackend(
+		ldap.NewWithIdentityBackend(log.NopLogger(), ib, true),
+		service.Logger(log.NopLogger()),
+		service.Config(cfg),
+		service.Store(store.Create(store.Store("memory"))),
+		service.Publisher(pub),
+		service.TokenKeyValue(kv),
+	)
+	require.NoError(t, err)
+	return svc, kv
+}
+
+func TestInviteAndRedeem(t *testing.T) {
+	ib := identitymocks.NewBackend(t)
+	pub := &publisher{}
+	svc, kv := newService(t, ib, pub)
+
+	ib.On("CreateUser", mock.Anything, mock.MatchedBy(func(u libregraph.User) bool {
+		return u.GetUserType() == identity.UserTypeGuest &&
+			u.GetMail() == "guest@example.org" &&
+			u.PasswordProfile != nil && u.PasswordProfile.GetPassword() != ""
+	})).Return(&libregraph.User{Id: libregraph.PtrString("guest-id")}, nil)
+	ib.On("UpdateUser", mock.Anything, "guest-id", mock.MatchedBy(func(u libregraph.UserUpdate) bool {
+		return u.AccountEnabled != nil && !*u.AccountEnabled
+	})).Return(&libregraph.User{}, nil)
+
+	inv, err := svc.Invite(context.Background(), &invitations.Invitation{
+		InvitedUserEmailAddress: "guest@example.org",
+		SendInvitationMessage:   true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, invitations.StatusPendingAcceptance, inv.Status)
+	assert.NotEmpty(t, inv.ID)
+	assert.Equal(t, "guest-id", inv.InvitedUser.GetId())
+
+	require.Len(t, pub.events, 1)
+	created, ok := pub.events[0].(invitations.InvitationCreated)
+	require.True(t, ok)
+	assert.True(t, created.SendInvitationMessage)
+	assert.Equal(t, "guest@example.org", created.InvitedUserEmailAddress)
+
+	// the redeem link is handed over in the token bucket, not on the event bus
+	require.NotEmpty(t, created.RedeemLinkID)
+	link, err := kv.Get(context.Background(), invitations.RedeemLinkKey(created.RedeemLinkID))
+	require.NoError(t, err)
+	assert.Equal(t, inv.InviteRedeemUrl, string(link.Value()))
+
+	u, err := url.Parse(inv.InviteRedeemUrl)
+	require.NoError(t, err)
+	token := u.Query().Get("token")
+	require.NotEmpty(t, token)
+
+	_, err = svc.Redeem(context.Background(), "wrong-token", "secret")
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+
+	ib.On("UpdateUser", mock.Anything, "guest-id", mock.MatchedBy(func(u libregraph.UserUpdate) bool {
+		return u.AccountEnabled != nil && *u.AccountEnabled && u.PasswordProfile != nil && u.PasswordProfile.GetPassword() == "secret"
+	})).Return(&libregraph.User{}, nil)
+
+	redeemed, err := svc.Redeem(context.Background(), token, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, invitations.StatusCompleted, redeemed.Status)
+	assert.Equal(t, inv.ID, redeemed.ID)
+
+	// the token can only be used once
+	_, err = svc.Redeem(context.Background(), token, "secret")
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestInviteWithoutMessage(t *testing.T) {
+	ib := identitymocks.NewBackend(t)
+	pub := &publisher{}
+	svc, _ := newService(t, ib, pub)
+
+	ib.On("CreateUser", mock.Anything, mock.Anything).Return(&libregraph.User{Id: libregraph.PtrString("guest-id")}, nil)
+	ib.On("UpdateUser", mock.Anything, "guest-id", mock.Anything).Return(&libregraph.User{}, nil)
+
+	inv, err := svc.Invite(context.Background(), &invitations.Invitation{
+		InvitedUserEmailAddress: "guest@example.org",
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, inv.InviteRedeemUrl)
+
+	// the event is still emitted for the audit log, but no mail is sent
+	require.Len(t, pub.events, 1)
+	created, ok := pub.events[0].(invitations.InvitationCreated)
+	require.True(t, ok)
+	assert.False(t, created.SendInvitationMessage)
+}
+
+func TestLifecycle(t *testing.T) {
+	ib := identitymocks.NewBackend(t)
+	pub := &publisher{}
+	svc, _ := newService(t, ib, pub)
+
+	ib.On("CreateUser", mock.Anything, mock.Anything).Return(&libregraph.User{Id: libregraph.PtrString("guest-id")}, nil)
+	ib.On("UpdateUser", mock.Anything, "guest-id", mock.Anything).Return(&libregraph.User{}, nil)
+
+	ctx := userContext("inviting-user")
+	inv, err := svc.Invite(ctx, &invitations.Invitation{
+		InvitedUserEmailAddress: "guest@example.org",
+	})
+	require.NoError(t, err)
+
+	list, err := svc.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, inv.ID, list[0].ID)
+	assert.Empty(t, list[0].InviteRedeemUrl)
+
+	// invitations of other users are not visible
+	list, err = svc.List(userContext("other-user"))
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	_, err = svc.Get(userContext("other-user"), inv.ID)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	resent, err := svc.Resend(ctx, inv.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, inv.InviteRedeemUrl, resent.InviteRedeemUrl)
+	require.Len(t, pub.events, 2)
+	resentEvent, ok := pub.events[1].(invitations.InvitationResent)
+	require.True(t, ok)
+	assert.True(t, resentEvent.SendInvitationMessage)
+	assert.Equal(t, "inviting-user", resentEvent.Executant.GetOpaqueId())
+
+	// the previous token is no longer valid
+	u, err := url.Parse(inv.InviteRedeemUrl)
+	require.NoError(t, err)
+	_, err = svc.Redeem(context.Background(), u.Query().Get("token"), "secret")
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+
+	ib.On("DeleteUser", mock.Anything, "guest-id").Return(nil)
+	require.NoError(t, svc.Revoke(ctx, inv.ID))
+	_, err = svc.Get(ctx, inv.ID)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+	require.Len(t, pub.events, 3)
+	assert.IsType(t, invitations.InvitationRevoked{}, pub.events[2])
+
+	// the token handed out on resend is gone as well
+	u, err = url.Parse(resent.InviteRedeemUrl)
+	require.NoError(t, err)
+	_, err = svc.Redeem(context.Background(), u.Query().Get("token"), "secret")
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestExpire(t *testing.T) {
+	ib := identitymocks.NewBackend(t)
+	pub := &publisher{}
+	svc, _ := newServiceWithConfig(t, ib, pub, &config.Config{
+		RedeemURL:  "https://cloud.example.org/graph/v1.0/invitations/redeem",
+		Expiration: time.Nanosecond,
+	})
+
+	ib.On("CreateUser", mock.Anything, mock.Anything).Return(&libregraph.User{Id: libregraph.PtrString("guest-id")}, nil)
+	ib.On("UpdateUser", mock.Anything, "guest-id", mock.Anything).Return(&libregraph.User{}, nil)
+
+	ctx := userContext("inviting-user")
+	inv, err := svc.Invite(ctx, &invitations.Invitation{
+		InvitedUserEmailAddress: "guest@example.org",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, inv.ExpirationDateTime)
+	time.Sleep(time.Millisecond)
+
+	// expired tokens can't be redeemed even before the cleanup ran
+	u, err := url.Parse(inv.InviteRedeemUrl)
+	require.NoError(t, err)
+	_, err = svc.Redeem(context.Background(), u.Query().Get("token"), "secret")
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+
+	ib.On("DeleteUser", mock.Anything, "guest-id").Return(nil)
+	require.NoError(t, svc.Expire(context.Background()))
+
+	list, err := svc.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	require.Len(t, pub.events, 2)
+	assert.IsType(t, invitations.InvitationExpired{}, pub.events[1])
+}
+
+func TestInviteMissingEmail(t *testing.T) {
+	svc, _ := newService(t, identitymocks.NewBackend(t), &publisher{})
+
+	_, err := svc.Invite(context.Background(), &invitations.Invitation{})
+	assert.ErrorIs(t, err, service.ErrMissingEmail)
+}
+
+func TestInviteRedirectURL(t *testing.T) {
+	ib := identitymocks.NewBackend(t)
+	svc, _ := newServiceWithConfig(t, ib, &publisher{}, &config.Config{
+		Commons:   &shared.Commons{OpenCloudURL: "https://cloud.example.org"},
+		RedeemURL: "https://cloud.example.org/graph/v1.0/invitations/redeem",
+	})
+
+	for _, redirect := range []string{"https://evil.example.org/", "//evil.example.org", "/\\evil.example.org", "javascript:alert(1)", "https://user@cloud.example.org/"} {
+		_, err := svc.Invite(context.Background(), &invitations.Invitation{
+			InvitedUserEmailAddress: "guest@example.org",
+			InviteRedirectUrl:       redirect,
+		})
+		assert.ErrorIs(t, err, service.ErrBadRequest, redirect)
+	}
+
+	ib.On("CreateUser", mock.Anything, mock.Anything).Return(&libregraph.User{Id: libregraph.PtrString("guest-id")}, nil)
+	ib.On("UpdateUser", mock.Anything, "guest-id", mock.Anything).Return(&libregraph.User{}, nil)
+	for _, redirect := range []string{"/files/spaces", "https://cloud.example.org/files"} {
+		_, err := svc.Invite(context.Background(), &invitations.Invitation{
+			InvitedUserEmailAddress: "guest@example.org",
+			InviteRedirectUrl:       redirect,
+		})
+		assert.NoError(t, err, redirect)
+	}
+}
+
+func TestInviteRollback(t *testing.T) {
+	ib := identitymocks.NewBackend(t)
+	pub := &publisher{err: errors.New("nats unavailable")}
+	svc, _ := newService(t, ib, pub)
+
+	ib.On("CreateUser", mock.Anything, mock.Anything).Return(&libregraph.User{Id: libregraph.PtrString("guest-id")}, nil)
+	ib.On("UpdateUser", mock.Anything, "guest-id", mock.Anything).Return(&libregraph.User{}, nil)
+	ib.On("DeleteUser", mock.Anything, "guest-id").Return(nil).Once()
+
+	ctx := userContext("inviting-user")
+	_, err := svc.Invite(ctx, &invitations.Invitation{
+		InvitedUserEmailAddress: "guest@example.org",
+		SendInvitationMessage:   true,
+	})
+	require.Error(t, err)
+
+	list, err := svc.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestRedeemPasswordPolicy(t *testing.T) {
+	ib := identitymocks.NewBackend(t)
+	svc, _ := newServiceWithConfig(t, ib, &publisher{}, &config.Config{
+		RedeemURL: "https://cloud.example.org/graph/v1.0/invitations/redeem",
+		PasswordPolicy: config.PasswordPolicy{
+			MinCharacters:          8,
+			MinLowerCaseCharacters: 1,
+			MinUpperCaseCharacters: 1,
+			MinDigits:              1,
+			MinSpecialCharacters:   1,
+		},
+	})
+
+	ib.On("CreateUser", mock.Anything, mock.Anything).Return(&libregraph.User{Id: libregraph.PtrString("guest-id")}, nil)
+	ib.On("UpdateUser", mock.Anything, "guest-id", mock.Anything).Return(&libregraph.User{}, nil)
+
+	inv, err := svc.Invite(context.Background(), &invitations.Invitation{
+		InvitedUserEmailAddress: "guest@example.org",
+	})
+	require.NoError(t, err)
+	u, err := url.Parse(inv.InviteRedeemUrl)
+	require.NoError(t, err)
+	token := u.Query().Get("token")
+
+	_, err = svc.Redeem(context.Background(), token, "secret")
+	assert.ErrorIs(t, err, service.ErrInvalidPassword)
+
+	// the token is still usable
+	redeemed, err := svc.Redeem(context.Background(), token, "Secret-123")
+	require.NoError(t, err)
+	assert.Equal(t, invitations.StatusCompleted, redeemed.Status)
+}
+
+func TestRedeemConcurrently(t *testing.T) {
+	ib := identitymocks.NewBackend(t)
+	svc, _ := newService(t, ib, &publisher{})
+
+	ib.On("CreateUser", mock.Anything, mock.Anything).Return(&libregraph.User{Id: libregraph.PtrString("guest-id")}, nil)
+	ib.On("UpdateUser", mock.Anything, "guest-id", mock.Anything).Return(&libregraph.User{}, nil)
+
+	inv, err := svc.Invite(context.Background(), &invitations.Invitation{
+		InvitedUserEmailAddress: "guest@example.org",
+	})
+	require.NoError(t, err)
+	u, err := url.Parse(inv.InviteRedeemUrl)
+	require.NoError(t, err)
+	token := u.Query().Get("token")
+
+	var (
+		wg       sync.WaitGroup
+		redeemed atomic.Int32
+		attempts = 10
+		errs     = make(chan error, attempts)
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.Redeem(context.Background(), token, "secret"); err != nil {
+				errs <- err
+				return
+			}
+			redeemed.Add(1)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	assert.Equal(t, int32(1), redeemed.Load())
+	for err := range errs {
+		assert.ErrorIs(t, err, service.ErrInvalidToken)
+	}
+}
+
+func userContext(id string) context.Context {
+	return revactx.ContextSetUser(context.Background(), &userv1beta1.User{
+		Id: &userv1beta1.UserId{OpaqueId: id},
+	})
+}
@@ -35,3 +35,81 @@ func (t tracing) Invite(ctx context.Context, invitation *invitations.Invitation)
 
 	return t.next.Invite(ctx, invitation)
 }
+
+// Redeem implements the Service interface.
+func (t tracing) Redeem(ctx context.Context, token, password string) (*invitations.Invitation, error) {
+	spanOpts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindServer),
+	}
+	ctx, span := t.tp.Tracer("invitations").Start(ctx, "Redeem", spanOpts...)
+	defer span.End()
+
+	return t.next.Redeem(ctx, token, password)
+}
+
+// List implements the Service interface.
+func (t tracing) List(ctx context.Context) ([]*invitations.Invitation, error) {
+	spanOpts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindServer),
+	}
+	ctx, span := t.tp.Tracer("invitations").Start(ctx, "List", spanOpts...)
+	defer span.End()
+
+	return t.next.List(ctx)
+}
+
+// Get implements the Service interface.
+func (t tracing) Get(ctx context.Context, id string) (*invitations.Invitation, error) {
+	spanOpts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.KeyValue{
+				Key: "invitation", Value: attribute.StringValue(id),
+			}),
+	}
+	ctx, span := t.tp.Tracer("invitations").Start(ctx, "Get", spanOpts...)
+	defer span.End()
+
+	return t.next.Get(ctx, id)
+}
+
+// Resend implements the Service interface.
+func (t tracing) Resend(ctx context.Context, id string) (*invitations.Invitation, error) {
+	spanOpts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.KeyValue{
+				Key: "invitation", Value: attribute.StringValue(id),
+			}),
+	}
+	ctx, span := t.tp.Tracer("invitations").Start(ctx, "Resend", spanOpts...)
+	defer span.End()
+
+	return t.next.Resend(ctx, id)
+}
+
+// Revoke implements the Service interface.
+func (t tracing) Revoke(ctx context.Context, id string) error {
+	spanOpts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.KeyValue{
+				Key: "invitation", Value: attribute.StringValue(id),
+			}),
+	}
+	ctx, span := t.tp.Tracer("invitations").Start(ctx, "Revoke", spanOpts...)
+	defer span.End()
+
+	return t.next.Revoke(ctx, id)
+}
+
+// Expire implements the Service interface.
+func (t tracing) Expire(ctx context.Context) error {
+	spanOpts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindServer),
+	}
+	ctx, span := t.tp.Tracer("invitations").Start(ctx, "Expire", spanOpts...)
+	defer span.End()
+
+	return t.next.Expire(ctx)
+}
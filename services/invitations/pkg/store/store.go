@@ -0,0 +1,285 @@
+// Package store persists the state of invitations.
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+	microstore "go-micro.dev/v4/store"
+
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
+)
+
+const (
+	invitationPrefix = "invitation/"
+	tokenPrefix      = "token/"
+
+	tokenLength = 32
+)
+
+// ErrNotFound is returned when an invitation or token is unknown to the store.
+var ErrNotFound = errors.New("invitation not found")
+
+// Record is the persisted state of an invitation.
+type Record struct {
+	ID                      string    `json:"id"`
+	InvitedUserID           string    `json:"invitedUserId"`
+	InvitedUserDisplayName  string    `json:"invitedUserDisplayName,omitempty"`
+	InvitedUserEmailAddress string    `json:"invitedUserEmailAddress"`
+	InviteRedirectURL       string    `json:"inviteRedirectUrl,omitempty"`
+	Status                  string    `json:"status"`
+	TokenHash               string    `json:"tokenHash,omitempty"`
+	CreatedBy               string    `json:"createdBy,omitempty"`
+	CreatedAt               time.Time `json:"createdAt"`
+	UpdatedAt               time.Time `json:"updatedAt"`
+	// ExpiresAt is the time a pending invitation expires, zero if it never expires
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+
+	// the message info is kept for resending the invitation
+	CustomizedMessageBody string   `json:"customizedMessageBody,omitempty"`
+	MessageLanguage       string   `json:"messageLanguage,omitempty"`
+	CcRecipients          []string `json:"ccRecipients,omitempty"`
+}
+
+// Expired returns true if the record expired at the given time.
+func (r *Record) Expired(t time.Time) bool {
+	return !r.ExpiresAt.IsZero() && t.After(r.ExpiresAt)
+}
+
+// ErrNoTokenBucket is returned when a redeem link should be handed over without a token bucket.
+var ErrNoTokenBucket = errors.New("handing over redeem links requires the nats-js-kv store")
+
+// Store reads and writes invitation records from and to a micro store. With a token bucket, the
+// redemption tokens are indexed in the bucket, where they can be claimed atomically, and the redeem
+// links are handed over to the notifications service in it.
+type Store struct {
+	store  microstore.Store
+	tokens jetstream.KeyValue
+	// mu serializes claiming tokens indexed in the micro store, which only works within one instance
+	mu sync.Mutex
+}
+
+// New returns a Store using the given micro store.
+func New(s microstore.Store) *Store {
+	return &Store{store: s}
+}
+
+// NewWithTokens returns a Store using the given micro store and the invitations.TokenBucket.
+func NewWithTokens(s microstore.Store, tokens jetstream.KeyValue) *Store {
+	return &Store{store: s, tokens: tokens}
+}
+
+// NewToken generates a random redemption token. It returns the token, which
+// is handed out to the invited user, and its hash, which is the only part
+// that gets persisted.
+func NewToken() (string, string, error) {
+	b := make([]byte, tokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hash under which a redemption token is persisted.
+func HashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// Save persists the record and indexes its redemption token, if any.
+func (s *Store) Save(r *Record) error {
+	r.UpdatedAt = time.Now()
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = r.UpdatedAt
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Write(&microstore.Record{Key: invitationPrefix + r.ID, Value: b}); err != nil {
+		return err
+	}
+
+	if r.TokenHash == "" {
+		return nil
+	}
+	if s.tokens != nil {
+		_, err := s.tokens.Put(context.Background(), tokenKey(r.TokenHash), []byte(r.ID))
+		return err
+	}
+	return s.store.Write(&microstore.Record{Key: tokenPrefix + r.TokenHash, Value: []byte(r.ID)})
+}
+
+// Get returns the record with the given invitation id.
+func (s *Store) Get(id string) (*Record, error) {
+	recs, err := s.store.Read(invitationPrefix + id)
+	switch {
+	case errors.Is(err, microstore.ErrNotFound):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, err
+	case len(recs) == 0:
+		return nil, ErrNotFound
+	}
+
+	r := &Record{}
+	if err := json.Unmarshal(recs[0].Value, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// List returns all records.
+func (s *Store) List() ([]*Record, error) {
+	keys, err := s.store.List(microstore.ListPrefix(invitationPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*Record, 0, len(keys))
+	for _, k := range keys {
+		r, err := s.Get(strings.TrimPrefix(k, invitationPrefix))
+		switch {
+		case errors.Is(err, ErrNotFound):
+			// deleted in the meantime
+			continue
+		case err != nil:
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// Delete removes the record and its token index.
+func (s *Store) Delete(r *Record) error {
+	if err := s.DeleteToken(r); err != nil {
+		return err
+	}
+	if err := s.store.Delete(invitationPrefix + r.ID); err != nil && !errors.Is(err, microstore.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// GetByToken returns the record the given redemption token was issued for.
+func (s *Store) GetByToken(token string) (*Record, error) {
+	hash := HashToken(token)
+	id, _, err := s.readToken(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	// the token index may point to an invitation that has since been issued a new token
+	if r.TokenHash != hash {
+		return nil, ErrNotFound
+	}
+	return r, nil
+}
+
+// ClaimToken removes the token index of the record, so the token can't be used again. Only one of
+// concurrent claims of a token succeeds, the others get ErrNotFound. The token hash of the record is
+// kept, saving the record restores the index.
+func (s *Store) ClaimToken(r *Record) error {
+	if r.TokenHash == "" {
+		return ErrNotFound
+	}
+
+	if s.tokens == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+	_, revision, err := s.readToken(r.TokenHash)
+	if err != nil {
+		return err
+	}
+
+	if s.tokens != nil {
+		err := s.tokens.Delete(context.Background(), tokenKey(r.TokenHash), jetstream.LastRevision(revision))
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			// claimed in the meantime
+			return ErrNotFound
+		}
+		return err
+	}
+	if err := s.store.Delete(tokenPrefix + r.TokenHash); err != nil && !errors.Is(err, microstore.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// DeleteToken removes the token index of the record and clears its token hash.
+// The record itself is not persisted, callers need to Save it afterwards.
+func (s *Store) DeleteToken(r *Record) error {
+	if r.TokenHash == "" {
+		return nil
+	}
+	if s.tokens != nil {
+		if err := s.tokens.Delete(context.Background(), tokenKey(r.TokenHash)); err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+			return err
+		}
+	} else if err := s.store.Delete(tokenPrefix + r.TokenHash); err != nil && !errors.Is(err, microstore.ErrNotFound) {
+		return err
+	}
+	r.TokenHash = ""
+	return nil
+}
+
+// HandOverRedeemLink puts the redeem link into the token bucket for the notifications service and
+// returns the id it is found under. The link is not put on the event bus.
+func (s *Store) HandOverRedeemLink(link string) (string, error) {
+	if s.tokens == nil {
+		return "", ErrNoTokenBucket
+	}
+	id := uuid.New().String()
+	if _, err := s.tokens.Put(context.Background(), invitations.RedeemLinkKey(id), []byte(link)); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// readToken returns the invitation id the token hash is indexed for and the revision of the index entry.
+func (s *Store) readToken(hash string) (string, uint64, error) {
+	if s.tokens != nil {
+		entry, err := s.tokens.Get(context.Background(), tokenKey(hash))
+		switch {
+		case errors.Is(err, jetstream.ErrKeyNotFound):
+			return "", 0, ErrNotFound
+		case err != nil:
+			return "", 0, err
+		}
+		return string(entry.Value()), entry.Revision(), nil
+	}
+
+	recs, err := s.store.Read(tokenPrefix + hash)
+	switch {
+	case errors.Is(err, microstore.ErrNotFound):
+		return "", 0, ErrNotFound
+	case err != nil:
+		return "", 0, err
+	case len(recs) == 0:
+		return "", 0, ErrNotFound
+	}
+	return string(recs[0].Value), 0, nil
+}
+
+// tokenKey returns the key of the token index in the token bucket.
+func tokenKey(hash string) string {
+	return "token." + hash
+}
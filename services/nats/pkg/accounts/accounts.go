@@ -0,0 +1,188 @@
+// Package accounts provides the users the services authenticate with on the event bus and their
+// permissions. The passwords of the users are derived from a shared secret, so the nats server and
+// the services only need to agree on the secret.
+package accounts
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+
+	nserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+
+	"github.com/opencloud-eu/opencloud/pkg/eventstream"
+	"github.com/opencloud-eu/opencloud/pkg/retention"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/quota"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/ratelimit"
+)
+
+const (
+	// InternalUser is the user of the nats service itself, it has all permissions.
+	InternalUser = "nats"
+	// StoresUser is the user of the stores, the caches and the registry. It has all permissions except
+	// the access to the event stream.
+	StoresUser = "stores"
+)
+
+// Service describes how a service uses the event bus.
+type Service struct {
+	Name string
+	// Events are the events the service publishes. The service can only publish them to the subjects
+	// of their types.
+	Events []any
+	// Untyped is set when the service publishes its events through reva, which publishes them to the
+	// subject of the event stream. The types of its events can't be restricted.
+	Untyped bool
+	// Consume is set when the service consumes events
+	Consume bool
+}
+
+// Publish reports whether the service publishes events.
+func (s Service) Publish() bool {
+	return s.Untyped || len(s.Events) > 0
+}
+
+// Services lists the services using the event bus. The roles are derived from the events the
+// services publish and consume.
+var Services = []Service{
+	{Name: "activitylog", Consume: true},
+	{Name: "antivirus", Events: []any{events.PostprocessingStepFinished{}}, Consume: true},
+	{Name: "audit", Consume: true},
+	{Name: "clientlog", Events: []any{events.SendSSE{}}, Consume: true},
+	{Name: "eventhistory", Consume: true},
+	{Name: "frontend", Consume: true},
+	{
+		Name: "graph",
+		Events: []any{
+			events.UserCreated{}, events.UserDeleted{}, events.UserSoftDeleted{}, events.UserFeatureChanged{},
+			events.GroupCreated{}, events.GroupDeleted{}, events.GroupFeatureChanged{},
+			events.GroupMemberAdded{}, events.GroupMemberRemoved{},
+			events.TagsAdded{}, events.TagsRemoved{}, events.PersonalDataExtracted{},
+			quota.SpaceQuotaStateChanged{}, retention.PolicyChanged{},
+			selfdeletion.AccountDeletionRequested{}, selfdeletion.AccountDeletionCancelled{},
+			selfdeletion.AccountDeletionConfirmed{}, selfdeletion.AccountPurged{}, selfdeletion.PersonalFilesPurged{},
+		},
+		Consume: true,
+	},
+	{
+		Name: "invitations",
+		Events: []any{
+			invitations.InvitationCreated{}, invitations.InvitationResent{}, invitations.InvitationRedeemed{},
+			invitations.InvitationRevoked{}, invitations.InvitationExpired{},
+		},
+	},
+	{Name: "notifications", Events: []any{events.SendEmailsEvent{}}, Consume: true},
+	// ScienceMeshInviteTokenGenerated
+	{Name: "ocm", Untyped: true},
+	{Name: "policies", Events: []any{events.PostprocessingStepFinished{}}, Consume: true},
+	{
+		Name: "postprocessing",
+		Events: []any{
+			events.StartPostprocessingStep{}, events.PostprocessingRetry{}, events.PostprocessingFinished{},
+			events.RestartPostprocessing{}, events.ResumePostprocessing{},
+		},
+		Consume: true,
+	},
+	{Name: "proxy", Events: []any{events.UserSignedIn{}, events.BackchannelLogout{}, ratelimit.LockoutStarted{}}},
+	{Name: "search", Consume: true},
+	{Name: "settings", Consume: true},
+	// share and link events
+	{Name: "sharing", Untyped: true, Consume: true},
+	{Name: "sse", Consume: true},
+	// file, upload and trash events
+	{Name: "storage-users", Untyped: true, Consume: true},
+	{Name: "thumbnails", Consume: true},
+	{Name: "userlog", Events: []any{events.SendSSE{}}, Consume: true},
+}
+
+// Password derives the password of a user from the shared secret.
+func Password(secret, username string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(username))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Users returns the users of the nats server: one per service, the internal user and the user of the
+// stores.
+func Users(secret string) []*nserver.User {
+	users := []*nserver.User{
+		{
+			Username: InternalUser,
+			Password: Password(secret, InternalUser),
+		},
+		{
+			Username:    StoresUser,
+			Password:    Password(secret, StoresUser),
+			Permissions: storesPermissions(),
+		},
+	}
+	for _, s := range Services {
+		users = append(users, &nserver.User{
+			Username:    s.Name,
+			Password:    Password(secret, s.Name),
+			Permissions: Permissions(s),
+		})
+	}
+	return users
+}
+
+// Permissions returns the permissions of a service on the event stream.
+func Permissions(s Service) *nserver.Permissions {
+	stream := events.MainQueueName
+	pub := []string{
+		"$JS.API.INFO",
+		"$JS.API.STREAM.NAMES",
+		"$JS.API.STREAM.INFO." + stream,
+		"$JS.API.STREAM.CREATE." + stream,
+	}
+	if s.Untyped {
+		pub = append(pub, stream, eventstream.Subjects)
+	}
+	for _, ev := range s.Events {
+		pub = append(pub, eventstream.SubjectOf(ev))
+	}
+	if s.Consume {
+		pub = append(pub,
+			"$JS.API.CONSUMER.INFO."+stream+".>",
+			"$JS.API.CONSUMER.CREATE."+stream,
+			"$JS.API.CONSUMER.CREATE."+stream+".>",
+			"$JS.API.CONSUMER.DURABLE.CREATE."+stream+".>",
+			"$JS.API.CONSUMER.MSG.NEXT."+stream+".>",
+			"$JS.ACK."+stream+".>",
+			"$JS.FC."+stream+".>",
+		)
+	}
+	return &nserver.Permissions{
+		Publish: &nserver.SubjectPermission{Allow: pub},
+		// replies and the deliveries of push consumers
+		Subscribe: &nserver.SubjectPermission{Allow: []string{"_INBOX.>"}},
+	}
+}
+
+// storesPermissions allows everything but publishing to and reading from the event stream.
+func storesPermissions() *nserver.Permissions {
+	stream := events.MainQueueName
+	return &nserver.Permissions{
+		Publish: &nserver.SubjectPermission{
+			Allow: []string{">"},
+			Deny: []string{
+				stream,
+				stream + ".>",
+				// the stream and consumer API like $JS.API.STREAM.INFO.<stream> or $JS.API.CONSUMER.MSG.NEXT.<stream>.<consumer>
+				"$JS.API.*.*." + stream,
+				"$JS.API.*.*." + stream + ".>",
+				"$JS.API.*.*.*." + stream,
+				"$JS.API.*.*.*." + stream + ".>",
+				"$JS.ACK." + stream + ".>",
+				"$JS.FC." + stream + ".>",
+			},
+		},
+		Subscribe: &nserver.SubjectPermission{
+			Allow: []string{">"},
+			Deny:  []string{stream, stream + ".>"},
+		},
+	}
+}
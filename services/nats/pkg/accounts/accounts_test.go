@@ -0,0 +1,162 @@
+package accounts_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-micro/plugins/v4/events/natsjs"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/events/raw"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencloud-eu/opencloud/pkg/eventstream"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/accounts"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/logging"
+	natsserver "github.com/opencloud-eu/opencloud/services/nats/pkg/server/nats"
+)
+
+const _secret = "secret"
+
+// startServer starts a nats server requiring the service users and returns its address.
+func startServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	s, err := natsserver.NewNATSServer(
+		logging.NewLogWrapper(log.NopLogger()),
+		natsserver.Host("127.0.0.1"),
+		natsserver.Port(port),
+		natsserver.StoreDir(t.TempDir()),
+		natsserver.Users(accounts.Users(_secret)),
+	)
+	require.NoError(t, err)
+	go func() { _ = s.ListenAndServe() }()
+	t.Cleanup(s.Shutdown)
+	require.True(t, s.Ready(10*time.Second))
+
+	// the nats service sets up the subjects of the event stream
+	conn, err := s.Connect(nats.UserInfo(accounts.InternalUser, accounts.Password(_secret, accounts.InternalUser)))
+	require.NoError(t, err)
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+	_, err = natsserver.EnsureEventStream(context.Background(), js)
+	require.NoError(t, err)
+	return fmt.Sprintf("127.0.0.1:%d", port)
+}
+
+func connect(t *testing.T, addr, service string) events.Stream {
+	t.Helper()
+	s, err := natsjs.NewStream(
+		natsjs.Address(addr),
+		natsjs.SynchronousPublish(true),
+		natsjs.Authenticate(service, accounts.Password(_secret, service)),
+	)
+	require.NoError(t, err)
+	return eventstream.Typed(s)
+}
+
+func TestServicePermissions(t *testing.T) {
+	addr := startServer(t)
+
+	// consuming creates the event stream
+	consumer := connect(t, addr, "thumbnails")
+	ch, err := events.Consume(consumer, "thumbnails", events.UserDeleted{})
+	require.NoError(t, err)
+
+	// search consumes with a pull consumer
+	pull, err := raw.FromConfig(context.Background(), "search", raw.Config{
+		Endpoint:     addr,
+		AuthUsername: "search",
+		AuthPassword: accounts.Password(_secret, "search"),
+	})
+	require.NoError(t, err)
+	pullCh, err := pull.Consume("search-pull", events.UserDeleted{})
+	require.NoError(t, err)
+
+	require.NoError(t, events.Publish(context.Background(), connect(t, addr, "graph"), events.UserDeleted{UserID: "einstein"}))
+	select {
+	case ev := <-ch:
+		require.Equal(t, "einstein", ev.Event.(events.UserDeleted).UserID)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the event was not delivered")
+	}
+	select {
+	case ev := <-pullCh:
+		require.NoError(t, ev.Ack())
+	case <-time.After(10 * time.Second):
+		t.Fatal("the event was not delivered to the pull consumer")
+	}
+
+	// graph can only publish the events it produces
+	require.Error(t, events.Publish(context.Background(), connect(t, addr, "graph"), events.ShareCreated{}))
+	require.Error(t, connect(t, addr, "graph").Publish(events.MainQueueName, events.UserDeleted{UserID: "marie"}))
+
+	// storage-users publishes through reva, which doesn't use the subjects of the event types
+	require.NoError(t, connect(t, addr, "storage-users").Publish(events.MainQueueName, events.UserDeleted{UserID: "marie"}))
+
+	// thumbnails only consumes events
+	require.Error(t, events.Publish(context.Background(), consumer, events.UserDeleted{UserID: "marie"}))
+
+	// invitations only publishes events
+	_, err = events.Consume(connect(t, addr, "invitations"), "invitations", events.UserDeleted{})
+	require.Error(t, err)
+
+	// wrong password
+	_, err = natsjs.NewStream(natsjs.Address(addr), natsjs.Authenticate("graph", "wrong"))
+	require.Error(t, err)
+}
+
+func TestStoresPermissions(t *testing.T) {
+	addr := startServer(t)
+
+	// clients need credentials
+	_, err := nats.Connect(addr)
+	require.Error(t, err)
+
+	stores, err := nats.Connect(addr, nats.UserInfo(accounts.StoresUser, accounts.Password(_secret, accounts.StoresUser)))
+	require.NoError(t, err)
+	defer stores.Close()
+	js, err := jetstream.New(stores)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	kv, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "registry"})
+	require.NoError(t, err)
+	_, err = kv.PutString(ctx, "key", "value")
+	require.NoError(t, err)
+	entry, err := kv.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value", string(entry.Value()))
+
+	// requests violating the permissions are not answered
+	denied := func(f func(ctx context.Context) error) {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		require.Error(t, f(ctx))
+	}
+
+	// the events are out of reach
+	denied(func(ctx context.Context) error {
+		_, err := js.Publish(ctx, events.MainQueueName, []byte("{}"))
+		return err
+	})
+	denied(func(ctx context.Context) error {
+		_, err := js.Publish(ctx, eventstream.Subject("events.UserDeleted"), []byte("{}"))
+		return err
+	})
+	denied(func(ctx context.Context) error {
+		_, err := js.Stream(ctx, events.MainQueueName)
+		return err
+	})
+}
@@ -0,0 +1,57 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/renderer"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/urfave/cli/v2"
+
+	"github.com/opencloud-eu/opencloud/pkg/config/configlog"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/accounts"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/config/parser"
+)
+
+// Credentials is the entrypoint for the credentials command.
+func Credentials(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:     "credentials",
+		Usage:    "print the credentials of the services for the event bus",
+		Category: "maintenance",
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnError(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			if !cfg.Nats.Authorization.Enabled {
+				return errors.New("authorization is not enabled")
+			}
+
+			r := tw.Rendition{
+				Settings: tw.Settings{
+					Separators: tw.Separators{
+						BetweenRows: tw.On,
+					},
+				},
+			}
+			tbl := tablewriter.NewTable(os.Stdout, tablewriter.WithRenderer(renderer.NewBlueprint(r)))
+			tbl.Header([]string{"Service", "Username", "Password", "Publish", "Consume"})
+			if err := tbl.Append([]string{
+				"stores, caches and registry", accounts.StoresUser, accounts.Password(cfg.Nats.Authorization.Secret, accounts.StoresUser), "false", "false",
+			}); err != nil {
+				return err
+			}
+			for _, s := range accounts.Services {
+				if err := tbl.Append([]string{
+					s.Name, s.Name, accounts.Password(cfg.Nats.Authorization.Secret, s.Name), strconv.FormatBool(s.Publish()), strconv.FormatBool(s.Consume),
+				}); err != nil {
+					return err
+				}
+			}
+			return tbl.Render()
+		},
+	}
+}
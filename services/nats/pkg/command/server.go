@@ -4,13 +4,16 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"os"
 	"os/signal"
 
+	natsgo "github.com/nats-io/nats.go"
 	"github.com/urfave/cli/v2"
 
 	"github.com/opencloud-eu/opencloud/pkg/config/configlog"
 	pkgcrypto "github.com/opencloud-eu/opencloud/pkg/crypto"
 	"github.com/opencloud-eu/opencloud/pkg/runner"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/accounts"
 	"github.com/opencloud-eu/opencloud/services/nats/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/nats/pkg/config/parser"
 	"github.com/opencloud-eu/opencloud/services/nats/pkg/logging"
@@ -52,7 +55,7 @@ func Server(cfg *config.Config) *cli.Command {
 				gr.Add(runner.NewGolangHttpServerRunner(cfg.Service.Name+".debug", debugServer))
 			}
 
-			var tlsConf *tls.Config
+			var tlsConf, clusterTLSConf *tls.Config
 			if cfg.Nats.EnableTLS {
 				// Generate a self-signing cert if no certificate is present
 				if err := pkgcrypto.GenCert(cfg.Nats.TLSCert, cfg.Nats.TLSKey, logger); err != nil {
@@ -74,16 +77,64 @@ func Server(cfg *config.Config) *cli.Command {
 					ClientAuth:   clientAuth,
 					Certificates: []tls.Certificate{crt},
 				}
+
+				if cfg.Nats.Cluster.Port != 0 {
+					f, err := os.Open(cfg.Nats.Cluster.TLSCACert)
+					if err != nil {
+						return err
+					}
+					ca, err := pkgcrypto.NewCertPoolFromPEM(f)
+					f.Close()
+					if err != nil {
+						return err
+					}
+					clusterTLSConf = nats.ClusterTLS(crt, ca)
+				}
 			}
-			natsServer, err := nats.NewNATSServer(
-				logging.NewLogWrapper(logger),
+			natsOpts := []nats.NatsOption{
 				nats.Host(cfg.Nats.Host),
 				nats.Port(cfg.Nats.Port),
+				nats.ServerName(cfg.Nats.ServerName),
 				nats.ClusterID(cfg.Nats.ClusterID),
 				nats.StoreDir(cfg.Nats.StoreDir),
+				nats.JetStreamDomain(cfg.Nats.JetStreamDomain),
 				nats.TLSConfig(tlsConf),
 				nats.AllowNonTLS(!cfg.Nats.EnableTLS),
-			)
+			}
+			if cfg.Nats.Cluster.Port != 0 {
+				routes, err := nats.ParseURLs(cfg.Nats.Cluster.Routes, cfg.Nats.Cluster.AuthUsername, cfg.Nats.Cluster.AuthPassword)
+				if err != nil {
+					return err
+				}
+				natsOpts = append(natsOpts,
+					nats.ClusterListen(cfg.Nats.Cluster.Host, cfg.Nats.Cluster.Port),
+					nats.ClusterAdvertise(cfg.Nats.Cluster.Advertise),
+					nats.ClusterAuth(cfg.Nats.Cluster.AuthUsername, cfg.Nats.Cluster.AuthPassword),
+					nats.ClusterTLSConfig(clusterTLSConf),
+					nats.Routes(routes),
+				)
+			}
+			if cfg.Nats.Leafnodes.Port != 0 {
+				natsOpts = append(natsOpts,
+					nats.LeafNodeListen(cfg.Nats.Leafnodes.Host, cfg.Nats.Leafnodes.Port),
+					nats.LeafNodeTLSConfig(tlsConf),
+				)
+			}
+			var clientOpts []natsgo.Option
+			if cfg.Nats.Authorization.Enabled {
+				secret := cfg.Nats.Authorization.Secret
+				natsOpts = append(natsOpts, nats.Users(accounts.Users(secret)))
+				clientOpts = append(clientOpts, natsgo.UserInfo(accounts.InternalUser, accounts.Password(secret, accounts.InternalUser)))
+			}
+			if len(cfg.Nats.Leafnodes.Remotes) > 0 {
+				remotes, err := nats.ParseURLs(cfg.Nats.Leafnodes.Remotes, "", "")
+				if err != nil {
+					return err
+				}
+				natsOpts = append(natsOpts, nats.LeafNodeRemotes(remotes))
+			}
+
+			natsServer, err := nats.NewNATSServer(logging.NewLogWrapper(logger), natsOpts...)
 			if err != nil {
 				return err
 			}
@@ -94,6 +145,14 @@ func Server(cfg *config.Config) *cli.Command {
 				natsServer.Shutdown()
 			}))
 
+			eventStream := nats.NewEventStream(natsServer, logger, clientOpts...)
+			gr.Add(runner.New(cfg.Service.Name+".eventstream", eventStream.Run, eventStream.Stop))
+
+			if cfg.Nats.JetStreamReplicas > 1 {
+				replicator := nats.NewReplicator(natsServer, cfg.Nats.JetStreamReplicas, logger, clientOpts...)
+				gr.Add(runner.New(cfg.Service.Name+".replicator", replicator.Run, replicator.Stop))
+			}
+
 			grResults := gr.Run(ctx)
 
 			// return the first non-nil error found in the results
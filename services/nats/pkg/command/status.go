@@ -0,0 +1,139 @@
+package command
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/renderer"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/urfave/cli/v2"
+
+	"github.com/opencloud-eu/opencloud/pkg/config/configlog"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/accounts"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/config/parser"
+	natsserver "github.com/opencloud-eu/opencloud/services/nats/pkg/server/nats"
+)
+
+// Status is the entrypoint for the status command.
+func Status(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:     "status",
+		Usage:    "show the health of the JetStream streams and consumers",
+		Category: "maintenance",
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnError(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			conn, err := connect(cfg)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			js, err := jetstream.New(conn)
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(c.Context, 30*time.Second)
+			defer cancel()
+			streams, err := natsserver.Status(ctx, js)
+			if err != nil {
+				return err
+			}
+
+			r := tw.Rendition{
+				Settings: tw.Settings{
+					Separators: tw.Separators{
+						BetweenRows: tw.On,
+					},
+				},
+			}
+			tbl := tablewriter.NewTable(os.Stdout, tablewriter.WithRenderer(renderer.NewBlueprint(r)))
+			tbl.Header([]string{"Stream", "Consumer", "Replicas", "Messages", "Pending", "Ack pending", "Redelivered", "Leader", "Problems"})
+
+			unhealthy := 0
+			for _, s := range streams {
+				if !s.Healthy() {
+					unhealthy++
+				}
+				if err := tbl.Append([]string{
+					s.Name, "", strconv.Itoa(s.Replicas), strconv.FormatUint(s.Messages, 10), "", "", "", s.Leader, strings.Join(s.Problems, "\n"),
+				}); err != nil {
+					return err
+				}
+				for _, co := range s.Consumers {
+					if err := tbl.Append([]string{
+						s.Name, co.Name, "", "", strconv.FormatUint(co.Pending, 10), strconv.Itoa(co.AckPending), strconv.Itoa(co.Redelivered), co.Leader, strings.Join(co.Problems, "\n"),
+					}); err != nil {
+						return err
+					}
+				}
+			}
+			if err := tbl.Render(); err != nil {
+				return err
+			}
+
+			if unhealthy > 0 {
+				return fmt.Errorf("%d of %d streams are unhealthy", unhealthy, len(streams))
+			}
+			return nil
+		},
+	}
+}
+
+// connect connects to the nats server of the configuration.
+func connect(cfg *config.Config) (*nats.Conn, error) {
+	host := cfg.Nats.Host
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(cfg.Nats.Port))
+
+	opts := []nats.Option{nats.Name("opencloud-nats-status")}
+	if cfg.Nats.Authorization.Enabled {
+		opts = append(opts, nats.UserInfo(accounts.InternalUser, accounts.Password(cfg.Nats.Authorization.Secret, accounts.InternalUser)))
+	}
+	if cfg.Nats.EnableTLS {
+		tlsConf, err := clientTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		tlsConf.ServerName = host
+		opts = append(opts, nats.Secure(tlsConf))
+	}
+	return nats.Connect(addr, opts...)
+}
+
+// clientTLSConfig trusts the certificate of the server and presents it as client certificate,
+// which the server requires unless the verification of client certificates is skipped.
+func clientTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	crt, err := tls.LoadX509KeyPair(cfg.Nats.TLSCert, cfg.Nats.TLSKey)
+	if err != nil {
+		return nil, err
+	}
+	pem, err := os.ReadFile(cfg.Nats.TLSCert)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("could not load the nats certificate")
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{crt},
+	}, nil
+}
@@ -1,6 +1,7 @@
 package defaults
 
 import (
+	"os"
 	"path/filepath"
 
 	"github.com/opencloud-eu/opencloud/pkg/config/defaults"
@@ -38,6 +39,14 @@ func DefaultConfig() *config.Config {
 			TLSCert:   filepath.Join(defaults.BaseDataPath(), "nats/tls.crt"),
 			TLSKey:    filepath.Join(defaults.BaseDataPath(), "nats/tls.key"),
 			EnableTLS: false,
+
+			JetStreamReplicas: 1,
+			Cluster: config.Cluster{
+				Host: "0.0.0.0",
+			},
+			Leafnodes: config.Leafnodes{
+				Host: "0.0.0.0",
+			},
 		},
 	}
 }
@@ -71,5 +80,10 @@ func EnsureDefaults(cfg *config.Config) {
 
 // Sanitize sanitizes the configuration
 func Sanitize(cfg *config.Config) {
-	// nothing to sanitize here atm
+	// JetStream clustering needs stable server names
+	if cfg.Nats.ServerName == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			cfg.Nats.ServerName = hostname
+		}
+	}
 }
@@ -2,6 +2,9 @@ package parser
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
+	"slices"
 
 	occfg "github.com/opencloud-eu/opencloud/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/nats/pkg/config"
@@ -33,5 +36,29 @@ func ParseConfig(cfg *config.Config) error {
 }
 
 func Validate(cfg *config.Config) error {
+	// JetStream supports up to five replicas
+	if cfg.Nats.JetStreamReplicas < 1 || cfg.Nats.JetStreamReplicas > 5 {
+		return fmt.Errorf("the number of JetStream replicas must be between 1 and 5, got %d", cfg.Nats.JetStreamReplicas)
+	}
+	if cfg.Nats.JetStreamReplicas > 1 && cfg.Nats.Cluster.Port == 0 {
+		return errors.New("more than one JetStream replica requires a cluster, set the cluster port")
+	}
+	if cfg.Nats.Cluster.Port != 0 && cfg.Nats.ServerName == "" {
+		return errors.New("the server name must be set when clustering is enabled")
+	}
+	if cfg.Nats.Cluster.Port != 0 && len(cfg.Nats.Cluster.Routes) == 0 {
+		return errors.New("the routes of the cluster must be set when clustering is enabled")
+	}
+	if cfg.Nats.Cluster.Port != 0 && cfg.Nats.EnableTLS && cfg.Nats.Cluster.TLSCACert == "" {
+		return errors.New("the CA certificate of the cluster must be set when clustering and TLS are enabled")
+	}
+	if cfg.Nats.Authorization.Enabled && cfg.Nats.Authorization.Secret == "" {
+		return errors.New("the authorization secret must be set when authorization is enabled")
+	}
+	for _, u := range append(slices.Clone(cfg.Nats.Cluster.Routes), cfg.Nats.Leafnodes.Remotes...) {
+		if _, err := url.Parse(u); err != nil {
+			return fmt.Errorf("invalid url '%s': %w", u, err)
+		}
+	}
 	return nil
 }
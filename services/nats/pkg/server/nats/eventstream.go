@@ -0,0 +1,108 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+
+	"github.com/opencloud-eu/opencloud/pkg/eventstream"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+)
+
+// EventStreamInterval is the interval the subjects of the event stream are checked in
+var EventStreamInterval = 30 * time.Second
+
+// EnsureEventStream makes the event stream accept the events on the subjects of their types and
+// transform the subjects to the subject of the stream, so the consumers receive the events of all
+// types. It reports whether the stream was created or updated.
+func EnsureEventStream(ctx context.Context, js jetstream.JetStream) (bool, error) {
+	typed := eventstream.Subjects
+	transform := &jetstream.SubjectTransformConfig{Source: typed, Destination: events.MainQueueName}
+
+	s, err := js.Stream(ctx, events.MainQueueName)
+	switch {
+	case errors.Is(err, jetstream.ErrStreamNotFound):
+		_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:             events.MainQueueName,
+			Subjects:         []string{events.MainQueueName, typed},
+			SubjectTransform: transform,
+		})
+		return err == nil, err
+	case err != nil:
+		return false, err
+	}
+
+	cfg := s.CachedInfo().Config
+	hasSubject := slices.Contains(cfg.Subjects, typed)
+	if hasSubject && cfg.SubjectTransform != nil && *cfg.SubjectTransform == *transform {
+		return false, nil
+	}
+	if !hasSubject {
+		cfg.Subjects = append(cfg.Subjects, typed)
+	}
+	cfg.SubjectTransform = transform
+	_, err = js.UpdateStream(ctx, cfg)
+	return err == nil, err
+}
+
+// EventStream keeps the subjects of the event stream. The services create the event stream with the
+// subject of the stream only when it doesn't exist yet, so it is checked periodically.
+type EventStream struct {
+	server *NATSServer
+	logger log.Logger
+	opts   []nats.Option
+	cancel context.CancelFunc
+	ctx    context.Context
+}
+
+// NewEventStream returns a new EventStream. The options configure its connection to the server.
+func NewEventStream(server *NATSServer, logger log.Logger, opts ...nats.Option) *EventStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EventStream{
+		server: server,
+		logger: logger,
+		opts:   opts,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Run checks the subjects of the event stream periodically until the EventStream is stopped.
+func (e *EventStream) Run() error {
+	js, closeConn, err := connectWhenReady(e.ctx, e.server, e.opts...)
+	if err != nil || js == nil {
+		return err
+	}
+	defer closeConn()
+
+	ticker := time.NewTicker(EventStreamInterval)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(e.ctx, EventStreamInterval)
+		updated, err := EnsureEventStream(ctx, js)
+		cancel()
+		if updated {
+			e.logger.Info().Str("stream", events.MainQueueName).Msg("updated the subjects of the event stream")
+		}
+		if err != nil && e.ctx.Err() == nil {
+			// expected until the JetStream cluster has elected a leader
+			e.logger.Warn().Err(err).Msg("could not update the subjects of the event stream")
+		}
+
+		select {
+		case <-e.ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop stops the EventStream.
+func (e *EventStream) Stop() {
+	e.cancel()
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	nserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
 )
 
 var NATSListenAndServeLoopTimer = 1 * time.Second
@@ -44,6 +45,16 @@ func (n *NATSServer) ListenAndServe() (err error) {
 	return nil
 }
 
+// Ready waits until the NATSServer accepts connections
+func (n *NATSServer) Ready(timeout time.Duration) bool {
+	return n.server.ReadyForConnections(timeout)
+}
+
+// Connect returns an in-process client connection to the NATSServer
+func (n *NATSServer) Connect(opts ...nats.Option) (*nats.Conn, error) {
+	return nats.Connect("", append(opts, nats.InProcessServer(n.server))...)
+}
+
 // Shutdown stops the NATSServer gracefully
 func (n *NATSServer) Shutdown() {
 	n.server.Shutdown()
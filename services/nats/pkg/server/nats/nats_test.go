@@ -0,0 +1,199 @@
+package nats
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencloud-eu/opencloud/pkg/eventstream"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/nats/pkg/logging"
+)
+
+// startCluster starts n clustered in-process servers.
+func startCluster(t *testing.T, n int, opts ...NatsOption) []*NATSServer {
+	t.Helper()
+	// JetStream clusters need the routes to all servers up front
+	ports := make([]int, n)
+	routes := make([]*url.URL, n)
+	for i := range ports {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		ports[i] = l.Addr().(*net.TCPAddr).Port
+		require.NoError(t, l.Close())
+		routes[i] = &url.URL{Scheme: "nats-route", Host: fmt.Sprintf("127.0.0.1:%d", ports[i])}
+	}
+
+	var servers []*NATSServer
+	for i := 0; i < n; i++ {
+		s, err := NewNATSServer(
+			logging.NewLogWrapper(log.NopLogger()),
+			append([]NatsOption{
+				Host("127.0.0.1"),
+				Port(-1),
+				ServerName(fmt.Sprintf("node%d", i+1)),
+				ClusterID("test-cluster"),
+				StoreDir(t.TempDir()),
+				ClusterListen("127.0.0.1", ports[i]),
+				Routes(routes),
+			}, opts...)...,
+		)
+		require.NoError(t, err)
+		go func() { _ = s.ListenAndServe() }()
+		t.Cleanup(s.Shutdown)
+		require.True(t, s.Ready(10*time.Second))
+		servers = append(servers, s)
+	}
+	return servers
+}
+
+func TestEnsureReplicas(t *testing.T) {
+	servers := startCluster(t, 3)
+
+	conn, err := servers[0].Connect()
+	require.NoError(t, err)
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// the JetStream cluster needs a meta leader before streams can be created
+	require.Eventually(t, func() bool {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "main-queue", Subjects: []string{"main-queue"}})
+		return err == nil
+	}, 20*time.Second, 100*time.Millisecond)
+	_, err = js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "settings-cache"})
+	require.NoError(t, err)
+
+	updated, err := EnsureReplicas(ctx, js, 3)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"main-queue", "KV_settings-cache"}, updated)
+
+	require.Eventually(t, func() bool {
+		status, err := Status(ctx, js)
+		if err != nil || len(status) != 2 {
+			return false
+		}
+		for _, s := range status {
+			if s.Replicas != 3 || !s.Healthy() {
+				return false
+			}
+		}
+		return true
+	}, 20*time.Second, 200*time.Millisecond)
+
+	// nothing left to update
+	updated, err = EnsureReplicas(ctx, js, 3)
+	require.NoError(t, err)
+	require.Empty(t, updated)
+}
+
+func TestEnsureEventStream(t *testing.T) {
+	s, err := NewNATSServer(logging.NewLogWrapper(log.NopLogger()), Host("127.0.0.1"), Port(-1), StoreDir(t.TempDir()))
+	require.NoError(t, err)
+	go func() { _ = s.ListenAndServe() }()
+	t.Cleanup(s.Shutdown)
+	require.True(t, s.Ready(10*time.Second))
+
+	conn, err := s.Connect()
+	require.NoError(t, err)
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// the event stream as the services create it
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{Name: "main-queue"})
+	require.NoError(t, err)
+	c, err := js.CreateConsumer(ctx, "main-queue", jetstream.ConsumerConfig{Durable: "consumer", FilterSubject: "main-queue"})
+	require.NoError(t, err)
+
+	updated, err := EnsureEventStream(ctx, js)
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	_, err = js.Publish(ctx, eventstream.Subject("events.UserCreated"), []byte("{}"))
+	require.NoError(t, err)
+	msg, err := c.Next(jetstream.FetchMaxWait(5 * time.Second))
+	require.NoError(t, err)
+	require.Equal(t, "main-queue", msg.Subject())
+
+	// nothing left to update
+	updated, err = EnsureEventStream(ctx, js)
+	require.NoError(t, err)
+	require.False(t, updated)
+}
+
+// issueCert returns a certificate for 127.0.0.1 signed by the given CA, a self-signed CA when ca is nil.
+func issueCert(t *testing.T, ca *tls.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	parent, signer := tmpl, any(key)
+	if ca == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		parent, signer = ca.Leaf, ca.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestClusterTLS(t *testing.T) {
+	ca := issueCert(t, nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	servers := startCluster(t, 2, ClusterTLSConfig(ClusterTLS(issueCert(t, &ca), pool)))
+	require.Eventually(t, func() bool {
+		return servers[0].server.NumRoutes() > 0 && servers[1].server.NumRoutes() > 0
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestClusterTLSRejectsUnknownCA(t *testing.T) {
+	ca := issueCert(t, nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	// the certificate is not issued by the CA of the cluster
+	other := issueCert(t, nil)
+	servers := startCluster(t, 2, ClusterTLSConfig(ClusterTLS(issueCert(t, &other), pool)))
+	require.Never(t, func() bool {
+		return servers[0].server.NumRoutes() > 0 || servers[1].server.NumRoutes() > 0
+	}, 2*time.Second, 100*time.Millisecond)
+}
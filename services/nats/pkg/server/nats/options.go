@@ -2,6 +2,8 @@ package nats
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"net/url"
 
 	nserver "github.com/nats-io/nats-server/v2/server"
 )
@@ -50,3 +52,113 @@ func AllowNonTLS(v bool) NatsOption {
 		o.AllowNonTLS = v
 	}
 }
+
+// ServerName sets the name of the nats server
+func ServerName(name string) NatsOption {
+	return func(o *nserver.Options) {
+		o.ServerName = name
+	}
+}
+
+// ClusterListen sets the bind address and port for the routes of the other servers of the cluster
+func ClusterListen(host string, port int) NatsOption {
+	return func(o *nserver.Options) {
+		o.Cluster.Host = host
+		o.Cluster.Port = port
+	}
+}
+
+// ClusterAdvertise sets the address advertised to the other servers of the cluster
+func ClusterAdvertise(addr string) NatsOption {
+	return func(o *nserver.Options) {
+		o.Cluster.Advertise = addr
+	}
+}
+
+// ClusterAuth sets the credentials the servers of the cluster authenticate their routes with
+func ClusterAuth(username, password string) NatsOption {
+	return func(o *nserver.Options) {
+		o.Cluster.Username = username
+		o.Cluster.Password = password
+	}
+}
+
+// ClusterTLSConfig sets the tls config for the routes of the cluster
+func ClusterTLSConfig(c *tls.Config) NatsOption {
+	return func(o *nserver.Options) {
+		o.Cluster.TLSConfig = c
+	}
+}
+
+// ClusterTLS returns the tls config for the routes of the cluster. The servers of the cluster connect
+// to each other, so the certificates are verified against the CA in both directions.
+func ClusterTLS(cert tls.Certificate, ca *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      ca,
+		ClientCAs:    ca,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+}
+
+// Routes sets the routes to the other servers of the cluster
+func Routes(routes []*url.URL) NatsOption {
+	return func(o *nserver.Options) {
+		o.Routes = routes
+	}
+}
+
+// LeafNodeListen sets the bind address and port for incoming leafnode connections
+func LeafNodeListen(host string, port int) NatsOption {
+	return func(o *nserver.Options) {
+		o.LeafNode.Host = host
+		o.LeafNode.Port = port
+	}
+}
+
+// LeafNodeTLSConfig sets the tls config for incoming leafnode connections
+func LeafNodeTLSConfig(c *tls.Config) NatsOption {
+	return func(o *nserver.Options) {
+		o.LeafNode.TLSConfig = c
+	}
+}
+
+// LeafNodeRemotes sets the remote servers this server connects to as a leafnode
+func LeafNodeRemotes(remotes []*url.URL) NatsOption {
+	return func(o *nserver.Options) {
+		for _, r := range remotes {
+			o.LeafNode.Remotes = append(o.LeafNode.Remotes, &nserver.RemoteLeafOpts{URLs: []*url.URL{r}})
+		}
+	}
+}
+
+// ParseURLs parses route or leafnode URLs. The credentials are added to URLs without user info.
+func ParseURLs(values []string, username, password string) ([]*url.URL, error) {
+	urls := make([]*url.URL, 0, len(values))
+	for _, v := range values {
+		u, err := url.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		if u.User == nil && username != "" {
+			u.User = url.UserPassword(username, password)
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+// JetStreamDomain sets the JetStream domain, which separates the JetStream of servers connected as leafnodes
+func JetStreamDomain(domain string) NatsOption {
+	return func(o *nserver.Options) {
+		o.JetStreamDomain = domain
+	}
+}
+
+// Users sets the users allowed to connect to the nats server
+func Users(users []*nserver.User) NatsOption {
+	return func(o *nserver.Options) {
+		o.Users = users
+	}
+}
@@ -0,0 +1,121 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+)
+
+// ReplicationInterval is the interval the replicas of the streams are checked in
+var ReplicationInterval = 30 * time.Second
+
+// EnsureReplicas raises the number of replicas of all JetStream streams, which include the
+// key-value buckets, to the given number. It returns the names of the updated streams.
+func EnsureReplicas(ctx context.Context, js jetstream.JetStream, replicas int) ([]string, error) {
+	var updated []string
+	var errs []error
+
+	lister := js.ListStreams(ctx)
+	for info := range lister.Info() {
+		if info.Config.Replicas >= replicas {
+			continue
+		}
+		cfg := info.Config
+		cfg.Replicas = replicas
+		if _, err := js.UpdateStream(ctx, cfg); err != nil {
+			errs = append(errs, fmt.Errorf("could not update the replicas of stream '%s': %w", cfg.Name, err))
+			continue
+		}
+		updated = append(updated, cfg.Name)
+	}
+	if err := lister.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return updated, errors.Join(errs...)
+}
+
+// Replicator keeps the number of replicas of the JetStream streams at the configured number. The
+// services create their event streams and key-value buckets with a single replica, the replicator
+// updates them once the JetStream cluster is available.
+type Replicator struct {
+	server   *NATSServer
+	replicas int
+	logger   log.Logger
+	opts     []nats.Option
+	cancel   context.CancelFunc
+	ctx      context.Context
+}
+
+// NewReplicator returns a new Replicator. The options configure its connection to the server.
+func NewReplicator(server *NATSServer, replicas int, logger log.Logger, opts ...nats.Option) *Replicator {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Replicator{
+		server:   server,
+		replicas: replicas,
+		logger:   logger,
+		opts:     opts,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Run checks the replicas of the streams periodically until the Replicator is stopped.
+func (r *Replicator) Run() error {
+	js, closeConn, err := connectWhenReady(r.ctx, r.server, r.opts...)
+	if err != nil || js == nil {
+		return err
+	}
+	defer closeConn()
+
+	ticker := time.NewTicker(ReplicationInterval)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(r.ctx, ReplicationInterval)
+		updated, err := EnsureReplicas(ctx, js, r.replicas)
+		cancel()
+		for _, name := range updated {
+			r.logger.Info().Str("stream", name).Int("replicas", r.replicas).Msg("updated the replicas of the stream")
+		}
+		if err != nil && r.ctx.Err() == nil {
+			// expected until the JetStream cluster has elected a leader
+			r.logger.Warn().Err(err).Msg("could not update the replicas of the streams")
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop stops the Replicator.
+func (r *Replicator) Stop() {
+	r.cancel()
+}
+
+// connectWhenReady waits for the server and connects to it. It returns no JetStream context when the
+// context is done before the server is ready: a stopped runner ends its group, so the runners wait
+// for the server instead of failing.
+func connectWhenReady(ctx context.Context, server *NATSServer, opts ...nats.Option) (jetstream.JetStream, func(), error) {
+	for !server.Ready(time.Second) {
+		if ctx.Err() != nil {
+			return nil, nil, nil
+		}
+	}
+	conn, err := server.Connect(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return js, conn.Close, nil
+}
@@ -0,0 +1,104 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// StreamStatus describes the health of a JetStream stream and its consumers.
+type StreamStatus struct {
+	Name      string
+	Replicas  int
+	Messages  uint64
+	Bytes     uint64
+	Leader    string
+	Problems  []string
+	Consumers []ConsumerStatus
+}
+
+// ConsumerStatus describes the health of a JetStream consumer.
+type ConsumerStatus struct {
+	Name        string
+	Pending     uint64
+	AckPending  int
+	Redelivered int
+	Leader      string
+	Problems    []string
+}
+
+// Healthy checks whether the stream and all its consumers are healthy.
+func (s StreamStatus) Healthy() bool {
+	if len(s.Problems) > 0 {
+		return false
+	}
+	for _, c := range s.Consumers {
+		if len(c.Problems) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Status returns the health of all JetStream streams and their consumers.
+func Status(ctx context.Context, js jetstream.JetStream) ([]StreamStatus, error) {
+	var status []StreamStatus
+	lister := js.ListStreams(ctx)
+	for info := range lister.Info() {
+		s := StreamStatus{
+			Name:     info.Config.Name,
+			Replicas: info.Config.Replicas,
+			Messages: info.State.Msgs,
+			Bytes:    info.State.Bytes,
+		}
+		s.Leader, s.Problems = clusterProblems(info.Cluster, info.Config.Replicas)
+
+		stream, err := js.Stream(ctx, info.Config.Name)
+		if err != nil {
+			s.Problems = append(s.Problems, err.Error())
+			status = append(status, s)
+			continue
+		}
+		consumers := stream.ListConsumers(ctx)
+		for ci := range consumers.Info() {
+			c := ConsumerStatus{
+				Name:        ci.Name,
+				Pending:     ci.NumPending,
+				AckPending:  ci.NumAckPending,
+				Redelivered: ci.NumRedelivered,
+			}
+			c.Leader, c.Problems = clusterProblems(ci.Cluster, ci.Config.Replicas)
+			s.Consumers = append(s.Consumers, c)
+		}
+		if err := consumers.Err(); err != nil {
+			s.Problems = append(s.Problems, err.Error())
+		}
+		status = append(status, s)
+	}
+	return status, lister.Err()
+}
+
+// clusterProblems returns the leader of a clustered stream or consumer and the problems of its replicas.
+func clusterProblems(ci *jetstream.ClusterInfo, replicas int) (string, []string) {
+	if ci == nil {
+		return "", nil
+	}
+	var problems []string
+	if ci.Leader == "" {
+		problems = append(problems, "no leader")
+	}
+	// the leader is not part of the replicas
+	if replicas > 1 && len(ci.Replicas) < replicas-1 {
+		problems = append(problems, fmt.Sprintf("%d of %d replicas", len(ci.Replicas)+1, replicas))
+	}
+	for _, p := range ci.Replicas {
+		switch {
+		case p.Offline:
+			problems = append(problems, fmt.Sprintf("replica %s offline", p.Name))
+		case !p.Current:
+			problems = append(problems, fmt.Sprintf("replica %s lags %d operations", p.Name, p.Lag))
+		}
+	}
+	return ci.Leader, problems
+}
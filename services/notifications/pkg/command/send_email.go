@@ -1,6 +1,7 @@
 package command
 
 import (
+	"github.com/opencloud-eu/opencloud/pkg/eventstream"
 	"github.com/opencloud-eu/opencloud/pkg/generators"
 	"github.com/opencloud-eu/opencloud/services/notifications/pkg/config"
 	"github.com/opencloud-eu/reva/v2/pkg/events"
@@ -37,6 +38,7 @@ func SendEmail(cfg *config.Config) *cli.Command {
 			if err != nil {
 				return err
 			}
+			s = eventstream.Typed(s)
 			if daily {
 				err = events.Publish(c.Context, s, events.SendEmailsEvent{
 					Interval: "daily",
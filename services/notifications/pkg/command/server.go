@@ -6,6 +6,8 @@ import (
 	"os/signal"
 	"reflect"
 
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	ehsvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/eventhistory/v0"
 	"github.com/opencloud-eu/reva/v2/pkg/store"
 	microstore "go-micro.dev/v4/store"
@@ -23,6 +25,9 @@ import (
 	"github.com/opencloud-eu/opencloud/pkg/service/grpc"
 	"github.com/opencloud-eu/opencloud/pkg/tracing"
 	settingssvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/settings/v0"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/quota"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
 	"github.com/opencloud-eu/opencloud/services/notifications/pkg/channels"
 	"github.com/opencloud-eu/opencloud/services/notifications/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/notifications/pkg/config/parser"
@@ -89,6 +94,11 @@ func Server(cfg *config.Config) *cli.Command {
 				events.SpaceMembershipExpired{},
 				events.ScienceMeshInviteTokenGenerated{},
 				events.SendEmailsEvent{},
+				invitations.InvitationCreated{},
+				invitations.InvitationResent{},
+				quota.SpaceQuotaStateChanged{},
+				selfdeletion.AccountDeletionRequested{},
+				selfdeletion.AccountDeletionConfirmed{},
 			}
 			registeredEvents := make(map[string]events.Unmarshaller)
 			for _, e := range evs {
@@ -135,10 +145,28 @@ func Server(cfg *config.Config) *cli.Command {
 				store.Authentication(cfg.Store.AuthUsername, cfg.Store.AuthPassword),
 			)
 
+			// the graph service hands over the confirmation tokens of self deletion requests in a nats bucket
+			var tokenStream jetstream.JetStream
+			if cfg.Store.Store == "nats-js-kv" {
+				natsOptions := nats.Options{
+					Servers:  cfg.Store.Nodes,
+					User:     cfg.Store.AuthUsername,
+					Password: cfg.Store.AuthPassword,
+				}
+				conn, err := natsOptions.Connect()
+				if err != nil {
+					return err
+				}
+				tokenStream, err = jetstream.New(conn)
+				if err != nil {
+					return err
+				}
+			}
+
 			svc := service.NewEventsNotifier(evts, channel, logger, gatewaySelector, valueService,
 				cfg.ServiceAccount.ServiceAccountID, cfg.ServiceAccount.ServiceAccountSecret,
 				cfg.Notifications.EmailTemplatePath, cfg.Notifications.DefaultLanguage, cfg.WebUIURL,
-				cfg.Notifications.TranslationPath, cfg.Notifications.SMTP.Sender, notificationStore, historyClient, registeredEvents)
+				cfg.Notifications.TranslationPath, cfg.Notifications.SMTP.Sender, notificationStore, tokenStream, historyClient, registeredEvents)
 
 			gr.Add(runner.New(cfg.Service.Name+".svc", func() error {
 				return svc.Run()
@@ -109,6 +109,120 @@ Please visit your federation settings and use the following details:
   ProviderDomain: {ProviderDomain}`),
 	}
 
+	// Invitations templates
+	InvitationCreated = MessageTemplate{
+		textTemplate: _textTemplate,
+		htmlTemplate: _htmlTemplate,
+		// InvitationCreated email template, Subject field (resolves directly)
+		Subject: l10n.Template(`{InvitingUser} invited you to OpenCloud`),
+		// InvitationCreated email template, resolves via {{ .Greeting }}
+		Greeting: l10n.Template(`Hello {InvitedUser},`),
+		// InvitationCreated email template, resolves via {{ .MessageBody }}
+		MessageBody: l10n.Template(`{InvitingUser} has invited you to collaborate as a guest.`),
+		// InvitationCreated email template, resolves via {{ .CallToAction }}
+		CallToAction: l10n.Template(`Click here to accept the invitation and choose your password: {RedeemLink}`),
+	}
+
+	InvitationCreatedWithMessage = MessageTemplate{
+		textTemplate: _textTemplate,
+		htmlTemplate: _htmlTemplate,
+		// InvitationCreatedWithMessage email template, Subject field (resolves directly)
+		Subject: l10n.Template(`{InvitingUser} invited you to OpenCloud`),
+		// InvitationCreatedWithMessage email template, resolves via {{ .Greeting }}
+		Greeting: l10n.Template(`Hello {InvitedUser},`),
+		// InvitationCreatedWithMessage email template, resolves via {{ .MessageBody }}
+		MessageBody: l10n.Template(`{InvitingUser} has invited you to collaborate as a guest:
+
+{CustomizedMessage}`),
+		// InvitationCreatedWithMessage email template, resolves via {{ .CallToAction }}
+		CallToAction: l10n.Template(`Click here to accept the invitation and choose your password: {RedeemLink}`),
+	}
+
+	InvitationCopy = MessageTemplate{
+		textTemplate: _textTemplate,
+		htmlTemplate: _htmlTemplate,
+		// InvitationCopy email template, Subject field (resolves directly)
+		Subject: l10n.Template(`{InvitingUser} invited {InvitedUser} to OpenCloud`),
+		// InvitationCopy email template, resolves via {{ .Greeting }}
+		Greeting: l10n.Template(`Hello,`),
+		// InvitationCopy email template, resolves via {{ .MessageBody }}
+		MessageBody: l10n.Template(`{InvitingUser} has invited {InvitedUser} to collaborate as a guest. This is a copy of the invitation, only {InvitedUser} received the link to accept it.`),
+	}
+
+	InvitationCopyWithMessage = MessageTemplate{
+		textTemplate: _textTemplate,
+		htmlTemplate: _htmlTemplate,
+		// InvitationCopyWithMessage email template, Subject field (resolves directly)
+		Subject: l10n.Template(`{InvitingUser} invited {InvitedUser} to OpenCloud`),
+		// InvitationCopyWithMessage email template, resolves via {{ .Greeting }}
+		Greeting: l10n.Template(`Hello,`),
+		// InvitationCopyWithMessage email template, resolves via {{ .MessageBody }}
+		MessageBody: l10n.Template(`{InvitingUser} has invited {InvitedUser} to collaborate as a guest. This is a copy of the invitation, only {InvitedUser} received the link to accept it:
+
+{CustomizedMessage}`),
+	}
+
+	// Quota templates
+	SpaceQuotaNearing = MessageTemplate{
+		textTemplate: _textTemplate,
+		htmlTemplate: _htmlTemplate,
+		// SpaceQuotaNearing email template, Subject field (resolves directly)
+		Subject: l10n.Template(`The space '{SpaceName}' is running out of storage`),
+		// SpaceQuotaNearing email template, resolves via {{ .Greeting }}
+		Greeting: l10n.Template(`Hello {SpaceGrantee},`),
+		// SpaceQuotaNearing email template, resolves via {{ .MessageBody }}
+		MessageBody: l10n.Template(`The space "{SpaceName}" uses {UsedPercent} of its quota of {Total}.
+
+Please free up storage space or ask an administrator to increase the quota.`),
+		// SpaceQuotaNearing email template, resolves via {{ .CallToAction }}
+		CallToAction: l10n.Template(`Click here to view it: {SpaceLink}`),
+	}
+
+	SpaceQuotaExceeded = MessageTemplate{
+		textTemplate: _textTemplate,
+		htmlTemplate: _htmlTemplate,
+		// SpaceQuotaExceeded email template, Subject field (resolves directly)
+		Subject: l10n.Template(`The space '{SpaceName}' has exceeded its quota`),
+		// SpaceQuotaExceeded email template, resolves via {{ .Greeting }}
+		Greeting: l10n.Template(`Hello {SpaceGrantee},`),
+		// SpaceQuotaExceeded email template, resolves via {{ .MessageBody }}
+		MessageBody: l10n.Template(`The space "{SpaceName}" uses {Used} of its quota of {Total}.
+
+No more files can be uploaded until storage space has been freed up or the quota has been increased.`),
+		// SpaceQuotaExceeded email template, resolves via {{ .CallToAction }}
+		CallToAction: l10n.Template(`Click here to view it: {SpaceLink}`),
+	}
+
+	AccountDeletionRequested = MessageTemplate{
+		textTemplate: _textTemplate,
+		htmlTemplate: _htmlTemplate,
+		// AccountDeletionRequested email template, Subject field (resolves directly)
+		Subject: l10n.Template(`Confirm the deletion of your account`),
+		// AccountDeletionRequested email template, resolves via {{ .Greeting }}
+		Greeting: l10n.Template(`Hello {DisplayName},`),
+		// AccountDeletionRequested email template, resolves via {{ .MessageBody }}
+		MessageBody: l10n.Template(`You requested the deletion of your account. Your account and all your files will be deleted for good.
+
+Enter the following code to confirm the deletion before {ExpiresAt}:
+
+{Token}
+
+If you did not request the deletion, you can ignore this email. Your account stays untouched.`),
+	}
+
+	AccountDeletionConfirmed = MessageTemplate{
+		textTemplate: _textTemplate,
+		htmlTemplate: _htmlTemplate,
+		// AccountDeletionConfirmed email template, Subject field (resolves directly)
+		Subject: l10n.Template(`Your account has been deleted`),
+		// AccountDeletionConfirmed email template, resolves via {{ .Greeting }}
+		Greeting: l10n.Template(`Hello {DisplayName},`),
+		// AccountDeletionConfirmed email template, resolves via {{ .MessageBody }}
+		MessageBody: l10n.Template(`Your account has been deleted as requested. You can no longer log in.
+
+Your files and personal data will be removed for good on {PurgeAt}. Until then an administrator can restore your account.`),
+	}
+
 	Grouped = GroupedMessageTemplate{
 		textTemplate: _textTemplate,
 		htmlTemplate: _htmlTemplate,
@@ -122,18 +236,28 @@ Please visit your federation settings and use the following details:
 
 // holds the information to turn the raw template into a parseable go template
 var _placeholders = map[string]string{
-	"{ShareSharer}":     "{{ .ShareSharer }}",
-	"{ShareFolder}":     "{{ .ShareFolder }}",
-	"{ShareGrantee}":    "{{ .ShareGrantee }}",
-	"{ShareLink}":       "{{ .ShareLink }}",
-	"{SpaceName}":       "{{ .SpaceName }}",
-	"{SpaceGrantee}":    "{{ .SpaceGrantee }}",
-	"{SpaceSharer}":     "{{ .SpaceSharer }}",
-	"{ExpiredAt}":       "{{ .ExpiredAt }}",
-	"{ShareSharerMail}": "{{ .ShareSharerMail }}",
-	"{ProviderDomain}":  "{{ .ProviderDomain }}",
-	"{Token}":           "{{ .Token }}",
-	"{DisplayName}":     "{{ .DisplayName }}",
+	"{ShareSharer}":       "{{ .ShareSharer }}",
+	"{ShareFolder}":       "{{ .ShareFolder }}",
+	"{ShareGrantee}":      "{{ .ShareGrantee }}",
+	"{ShareLink}":         "{{ .ShareLink }}",
+	"{SpaceName}":         "{{ .SpaceName }}",
+	"{SpaceGrantee}":      "{{ .SpaceGrantee }}",
+	"{SpaceSharer}":       "{{ .SpaceSharer }}",
+	"{ExpiredAt}":         "{{ .ExpiredAt }}",
+	"{ShareSharerMail}":   "{{ .ShareSharerMail }}",
+	"{ProviderDomain}":    "{{ .ProviderDomain }}",
+	"{Token}":             "{{ .Token }}",
+	"{DisplayName}":       "{{ .DisplayName }}",
+	"{InvitingUser}":      "{{ .InvitingUser }}",
+	"{InvitedUser}":       "{{ .InvitedUser }}",
+	"{RedeemLink}":        "{{ .RedeemLink }}",
+	"{CustomizedMessage}": "{{ .CustomizedMessage }}",
+	"{SpaceLink}":         "{{ .SpaceLink }}",
+	"{Used}":              "{{ .Used }}",
+	"{UsedPercent}":       "{{ .UsedPercent }}",
+	"{Total}":             "{{ .Total }}",
+	"{ExpiresAt}":         "{{ .ExpiresAt }}",
+	"{PurgeAt}":           "{{ .PurgeAt }}",
 }
 
 // MessageTemplate is the data structure for the email
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
+	"github.com/opencloud-eu/opencloud/services/notifications/pkg/channels"
+	"github.com/opencloud-eu/opencloud/services/notifications/pkg/email"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+)
+
+func (s eventsNotifier) handleInvitationCreated(e invitations.InvitationCreated) {
+	s.sendInvitation("InvitationCreated", e)
+}
+
+func (s eventsNotifier) handleInvitationResent(e invitations.InvitationResent) {
+	// both events carry the same information
+	s.sendInvitation("InvitationResent", invitations.InvitationCreated(e))
+}
+
+func (s eventsNotifier) sendInvitation(event string, e invitations.InvitationCreated) {
+	if !e.SendInvitationMessage {
+		// the backend takes care of the mail
+		return
+	}
+
+	logger := s.logger.With().
+		Str("event", event).
+		Str("invitationid", e.InvitationID).
+		Logger()
+
+	if errs := validate.Var(e.InvitedUserEmailAddress, "required,email"); errs != nil {
+		logger.Error().Err(errs).Msg("invalid recipient, skipping invitation mail")
+		return
+	}
+
+	// the redeem link lets the receiver choose the password of the guest, it is only sent to the guest
+	redeemLink, err := s.takeRedeemLink(e.RedeemLinkID)
+	if err != nil {
+		logger.Error().Err(err).Msg("could not read the redeem link")
+		return
+	}
+
+	gatewayClient, err := s.gatewaySelector.Next()
+	if err != nil {
+		logger.Error().Err(err).Msg("could not select next gateway client")
+		return
+	}
+
+	ctx, err := utils.GetServiceUserContextWithContext(context.Background(), gatewayClient, s.serviceAccountID, s.serviceAccountSecret)
+	if err != nil {
+		logger.Error().Err(err).Msg("Could not impersonate service user")
+		return
+	}
+
+	invitingUser := "OpenCloud"
+	if e.Executant != nil {
+		owner, err := utils.GetUserNoGroups(ctx, e.Executant, gatewayClient)
+		if err != nil {
+			logger.Error().Err(err).Msg("unable to get user")
+			return
+		}
+		invitingUser = owner.GetDisplayName()
+	}
+
+	invitedUser := e.InvitedUserDisplayName
+	if invitedUser == "" {
+		invitedUser = e.InvitedUserEmailAddress
+	}
+
+	msgENV := map[string]string{
+		"InvitingUser":      invitingUser,
+		"InvitedUser":       invitedUser,
+		"RedeemLink":        redeemLink,
+		"CustomizedMessage": e.CustomizedMessageBody,
+	}
+
+	emailTpl, copyTpl := email.InvitationCreated, email.InvitationCopy
+	if e.CustomizedMessageBody != "" {
+		emailTpl, copyTpl = email.InvitationCreatedWithMessage, email.InvitationCopyWithMessage
+	}
+
+	locale := e.MessageLanguage
+	if locale == "" {
+		locale = s.defaultLanguage
+	}
+
+	msg, err := email.RenderEmailTemplate(
+		emailTpl,
+		locale,
+		s.defaultLanguage,
+		s.emailTemplatePath,
+		s.translationPath,
+		msgENV,
+	)
+	if err != nil {
+		logger.Error().Err(err).Msg("building the message has failed")
+		return
+	}
+	msg.Sender = invitingUser
+	msg.Recipient = []string{e.InvitedUserEmailAddress}
+	msgs := []*channels.Message{msg}
+
+	if len(e.CcRecipients) > 0 {
+		// the cc recipients get a copy without the redeem link
+		copyMsg, err := email.RenderEmailTemplate(
+			copyTpl,
+			locale,
+			s.defaultLanguage,
+			s.emailTemplatePath,
+			s.translationPath,
+			msgENV,
+		)
+		if err != nil {
+			logger.Error().Err(err).Msg("building the copy of the message has failed")
+		} else {
+			copyMsg.Sender = invitingUser
+			copyMsg.Recipient = e.CcRecipients
+			msgs = append(msgs, copyMsg)
+		}
+	}
+
+	s.send(ctx, msgs)
+}
+
+// takeRedeemLink reads a redeem link from the bucket the invitations service handed it over in and
+// removes it, the link is not carried by the event
+func (s eventsNotifier) takeRedeemLink(id string) (string, error) {
+	if s.tokenStream == nil {
+		return "", errors.New("the redeem links require the nats-js-kv store")
+	}
+	if id == "" {
+		return "", errors.New("the event carries no redeem link id")
+	}
+	ctx := context.Background()
+	kv, err := s.tokenStream.KeyValue(ctx, invitations.TokenBucket)
+	if err != nil {
+		return "", err
+	}
+	key := invitations.RedeemLinkKey(id)
+	entry, err := kv.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if err := kv.Delete(ctx, key); err != nil {
+		s.logger.Error().Err(err).Msg("could not remove the redeem link")
+	}
+	return string(entry.Value()), nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/quota"
+	"github.com/opencloud-eu/opencloud/services/notifications/pkg/email"
+)
+
+func (s eventsNotifier) handleSpaceQuotaStateChanged(e quota.SpaceQuotaStateChanged) {
+	logger := s.logger.With().
+		Str("event", "SpaceQuotaStateChanged").
+		Str("itemid", e.SpaceID.GetOpaqueId()).
+		Str("state", e.State).
+		Logger()
+
+	tpl := email.SpaceQuotaNearing
+	switch e.State {
+	case quota.StateNearing, quota.StateCritical:
+	case quota.StateExceeded:
+		tpl = email.SpaceQuotaExceeded
+	default:
+		// only warnings are sent
+		return
+	}
+
+	gatewayClient, err := s.gatewaySelector.Next()
+	if err != nil {
+		logger.Error().Err(err).Msg("could not select next gateway client")
+		return
+	}
+
+	ctx, err := utils.GetServiceUserContextWithContext(context.Background(), gatewayClient, s.serviceAccountID, s.serviceAccountSecret)
+	if err != nil {
+		logger.Error().Err(err).Msg("could not get service user context")
+		return
+	}
+
+	recipients := s.quotaRecipients(ctx, e)
+	if len(recipients) == 0 {
+		return
+	}
+
+	spaceLink, err := urlJoinPath(s.openCloudURL, "f", e.SpaceID.GetOpaqueId())
+	if err != nil {
+		logger.Error().Err(err).Msg("could not create link to the space")
+		return
+	}
+
+	usedPercent := 100
+	if e.Total > 0 {
+		usedPercent = int(e.Used * 100 / e.Total)
+	}
+	emails, err := s.render(ctx, tpl,
+		"SpaceGrantee",
+		map[string]string{
+			"SpaceName":   e.SpaceName,
+			"SpaceLink":   spaceLink,
+			"Used":        formatBytes(e.Used),
+			"Total":       formatBytes(e.Total),
+			"UsedPercent": fmt.Sprintf("%d%%", usedPercent),
+		}, recipients, s.defaultEmailSender)
+	if err != nil {
+		logger.Error().Err(err).Msg("could not render the email")
+		return
+	}
+	s.send(ctx, emails)
+}
+
+// quotaRecipients returns the owner of a personal space or the managers of a project space
+func (s eventsNotifier) quotaRecipients(ctx context.Context, e quota.SpaceQuotaStateChanged) []*user.User {
+	var recipients []*user.User
+	seen := map[string]struct{}{}
+	add := func(users []*user.User) {
+		for _, u := range users {
+			if _, ok := seen[u.GetId().GetOpaqueId()]; ok {
+				continue
+			}
+			seen[u.GetId().GetOpaqueId()] = struct{}{}
+			recipients = append(recipients, u)
+		}
+	}
+
+	if e.Owner != nil {
+		add(s.ensureGranteeList(ctx, nil, e.Owner, nil))
+	}
+	for _, m := range e.Managers {
+		add(s.ensureGranteeList(ctx, nil, m, nil))
+	}
+	for _, g := range e.ManagerGroups {
+		add(s.ensureGranteeList(ctx, nil, nil, g))
+	}
+	return recipients
+}
+
+// formatBytes formats a number of bytes with a decimal unit, e.g. '1.5 GB'
+func formatBytes(b int64) string {
+	const unit = 1000
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "kMGTP"[exp])
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
+	"github.com/opencloud-eu/opencloud/services/notifications/pkg/email"
+)
+
+func (s eventsNotifier) handleAccountDeletionRequested(e selfdeletion.AccountDeletionRequested) {
+	token, err := s.takeSelfDeletionToken(e.ConfirmationTokenID)
+	if err != nil {
+		s.logger.Error().Err(err).Str("event", "AccountDeletionRequested").Str("userid", e.UserID.GetOpaqueId()).Msg("could not read the confirmation token")
+		return
+	}
+	s.sendAccountDeletionMail("AccountDeletionRequested", email.AccountDeletionRequested, e.UserID, e.UserDisplayName, e.UserMail, map[string]string{
+		"Token":     token,
+		"ExpiresAt": utils.TSToTime(e.ExpiresAt).Format("2006-01-02 15:04:05"),
+	})
+}
+
+func (s eventsNotifier) handleAccountDeletionConfirmed(e selfdeletion.AccountDeletionConfirmed) {
+	s.sendAccountDeletionMail("AccountDeletionConfirmed", email.AccountDeletionConfirmed, e.UserID, e.UserDisplayName, e.UserMail, map[string]string{
+		"PurgeAt": utils.TSToTime(e.PurgeAt).Format("2006-01-02 15:04:05"),
+	})
+}
+
+// sendAccountDeletionMail sends the mail to the address carried by the event, the account might already
+// be disabled when the mail is sent
+func (s eventsNotifier) sendAccountDeletionMail(event string, tpl email.MessageTemplate, userID *user.UserId, displayName, mail string, fields map[string]string) {
+	logger := s.logger.With().
+		Str("event", event).
+		Str("userid", userID.GetOpaqueId()).
+		Logger()
+
+	if errs := validate.Var(mail, "required,email"); errs != nil {
+		logger.Debug().Err(errs).Msg("user has no valid email, skipped")
+		return
+	}
+
+	gatewayClient, err := s.gatewaySelector.Next()
+	if err != nil {
+		logger.Error().Err(err).Msg("could not select next gateway client")
+		return
+	}
+
+	ctx, err := utils.GetServiceUserContextWithContext(context.Background(), gatewayClient, s.serviceAccountID, s.serviceAccountSecret)
+	if err != nil {
+		logger.Error().Err(err).Msg("could not get service user context")
+		return
+	}
+
+	recipient := &user.User{Id: userID, DisplayName: displayName, Mail: mail}
+	emails, err := s.render(ctx, tpl, "DisplayName", fields, []*user.User{recipient}, s.defaultEmailSender)
+	if err != nil {
+		logger.Error().Err(err).Msg("could not render the email")
+		return
+	}
+	s.send(ctx, emails)
+}
+
+// takeSelfDeletionToken reads a confirmation token from the bucket the graph service handed it over in and
+// removes it, the token is not carried by the event
+func (s eventsNotifier) takeSelfDeletionToken(id string) (string, error) {
+	if s.tokenStream == nil {
+		return "", errors.New("the confirmation tokens require the nats-js-kv store")
+	}
+	ctx := context.Background()
+	kv, err := s.tokenStream.KeyValue(ctx, selfdeletion.TokenBucket)
+	if err != nil {
+		return "", err
+	}
+	entry, err := kv.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if err := kv.Delete(ctx, id); err != nil {
+		s.logger.Error().Err(err).Msg("could not remove the confirmation token")
+	}
+	return string(entry.Value()), nil
+}
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"github.com/nats-io/nats.go/jetstream"
 	ehsvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/eventhistory/v0"
 	"go-micro.dev/v4/store"
 
@@ -26,6 +27,9 @@ import (
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	"github.com/opencloud-eu/opencloud/pkg/middleware"
 	settingssvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/settings/v0"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/quota"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
+	"github.com/opencloud-eu/opencloud/services/invitations/pkg/invitations"
 	"github.com/opencloud-eu/opencloud/services/notifications/pkg/channels"
 	"github.com/opencloud-eu/opencloud/services/notifications/pkg/email"
 	"github.com/opencloud-eu/opencloud/services/settings/pkg/store/defaults"
@@ -55,6 +59,7 @@ func NewEventsNotifier(
 	valueService settingssvc.ValueService,
 	serviceAccountID, serviceAccountSecret, emailTemplatePath, defaultLanguage, openCloudURL, translationPath, emailSender string,
 	store store.Store,
+	tokenStream jetstream.JetStream,
 	historyClient ehsvc.EventHistoryService,
 	registeredEvents map[string]events.Unmarshaller) Service {
 
@@ -74,6 +79,7 @@ func NewEventsNotifier(
 		filter:               newNotificationFilter(logger, valueService),
 		splitter:             newIntervalSplitter(logger, valueService),
 		userEventStore:       newUserEventStore(logger, store, historyClient),
+		tokenStream:          tokenStream,
 		registeredEvents:     registeredEvents,
 		stopCh:               make(chan struct{}, 1),
 		stopped:              new(atomic.Bool),
@@ -96,6 +102,7 @@ type eventsNotifier struct {
 	filter               *notificationFilter
 	splitter             *intervalSplitter
 	userEventStore       *userEventStore
+	tokenStream          jetstream.JetStream
 	registeredEvents     map[string]events.Unmarshaller
 	stopCh               chan struct{}
 	stopped              *atomic.Bool
@@ -133,6 +140,16 @@ EventLoop:
 					s.handleScienceMeshInviteTokenGenerated(e)
 				case events.SendEmailsEvent:
 					s.sendGroupedEmailsJob(e, evt.ID)
+				case invitations.InvitationCreated:
+					s.handleInvitationCreated(e)
+				case invitations.InvitationResent:
+					s.handleInvitationResent(e)
+				case quota.SpaceQuotaStateChanged:
+					s.handleSpaceQuotaStateChanged(e)
+				case selfdeletion.AccountDeletionRequested:
+					s.handleAccountDeletionRequested(e)
+				case selfdeletion.AccountDeletionConfirmed:
+					s.handleAccountDeletionConfirmed(e)
 				}
 			}()
 
@@ -95,7 +95,7 @@ var _ = Describe("Notifications", func() {
 			ch := make(chan events.Event)
 			evts := service.NewEventsNotifier(ch, tc, log.NewLogger(), gatewaySelector, vs, "",
 				"", "", "", "", "", "",
-				store.Create(), nil, nil)
+				store.Create(), nil, nil, nil)
 			go evts.Run()
 
 			ch <- ev
@@ -308,7 +308,7 @@ var _ = Describe("Notifications X-Site Scripting", func() {
 			ch := make(chan events.Event)
 			evts := service.NewEventsNotifier(ch, tc, log.NewLogger(), gatewaySelector, vs, "",
 				"", "", "", "", "", "",
-				store.Create(), nil, nil)
+				store.Create(), nil, nil, nil)
 			go evts.Run()
 
 			ch <- ev
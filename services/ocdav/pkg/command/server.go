@@ -9,6 +9,7 @@ import (
 	"github.com/opencloud-eu/opencloud/pkg/config/configlog"
 	"github.com/opencloud-eu/opencloud/pkg/registry"
 	"github.com/opencloud-eu/opencloud/pkg/runner"
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	ohttp "github.com/opencloud-eu/opencloud/pkg/service/http"
 	"github.com/opencloud-eu/opencloud/pkg/tracing"
 	"github.com/opencloud-eu/opencloud/pkg/version"
@@ -57,6 +58,9 @@ func Server(cfg *config.Config) *cli.Command {
 			if err := sharedconf.Decode(sc); err != nil {
 				logger.Error().Err(err).Msg("error decoding shared config for ocdav")
 			}
+			// the go-micro based ocdav builds its token manager config itself, so the previous
+			// secrets can't be passed in and are registered for the current secret instead
+			secrets.RegisterPreviousJWTSecrets(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets)
 			opts := []ocdav.Option{
 				ocdav.Name(cfg.HTTP.Namespace + "." + cfg.Service.Name),
 				ocdav.Version(version.GetString()),
@@ -41,7 +41,8 @@ func NewService(opts ...Option) Service {
 		r.Use(middleware.StripSlashes)
 		r.Use(opkgm.ExtractAccountUUID(
 			account.Logger(options.Logger),
-			account.JWTSecret(options.Config.TokenManager.JWTSecret)),
+			account.JWTSecret(options.Config.TokenManager.JWTSecret),
+			account.PreviousJWTSecrets(options.Config.TokenManager.PreviousJWTSecrets)),
 		)
 		r.Use(ocsm.OCSFormatCtx) // updates request Accept header according to format=(json|xml) query parameter
 		r.Route("/v{version:(1|2)}.php", func(r chi.Router) {
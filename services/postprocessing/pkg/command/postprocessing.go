@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/opencloud-eu/opencloud/pkg/config/configlog"
+	"github.com/opencloud-eu/opencloud/pkg/eventstream"
 	"github.com/opencloud-eu/opencloud/pkg/generators"
 	"github.com/opencloud-eu/opencloud/services/postprocessing/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/postprocessing/pkg/config/parser"
@@ -54,6 +55,7 @@ func RestartPostprocessing(cfg *config.Config) *cli.Command {
 			if err != nil {
 				return err
 			}
+			stream = eventstream.Typed(stream)
 
 			uid, step := c.String("upload-id"), ""
 			if uid == "" {
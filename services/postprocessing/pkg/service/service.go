@@ -9,6 +9,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/opencloud-eu/opencloud/pkg/eventstream"
 	"github.com/opencloud-eu/opencloud/pkg/generators"
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	"github.com/opencloud-eu/opencloud/pkg/version"
@@ -63,6 +64,7 @@ func NewPostprocessingService(ctx context.Context, logger log.Logger, sto store.
 	if err != nil {
 		return nil, err
 	}
+	pub = eventstream.Typed(pub)
 
 	raw, err := raw.FromConfig(ctx, connName, raw.Config{
 		Endpoint:             cfg.Postprocessing.Events.Endpoint,
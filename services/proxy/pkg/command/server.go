@@ -3,15 +3,21 @@ package command
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"os/signal"
 	"time"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/justinas/alice"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/opencloud-eu/opencloud/pkg/config/configlog"
+	"github.com/opencloud-eu/opencloud/pkg/eventstream"
 	"github.com/opencloud-eu/opencloud/pkg/generators"
 	"github.com/opencloud-eu/opencloud/pkg/log"
 	pkgmiddleware "github.com/opencloud-eu/opencloud/pkg/middleware"
@@ -28,7 +34,9 @@ import (
 	"github.com/opencloud-eu/opencloud/services/proxy/pkg/logging"
 	"github.com/opencloud-eu/opencloud/services/proxy/pkg/metrics"
 	"github.com/opencloud-eu/opencloud/services/proxy/pkg/middleware"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/mtls"
 	"github.com/opencloud-eu/opencloud/services/proxy/pkg/proxy"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/ratelimit"
 	"github.com/opencloud-eu/opencloud/services/proxy/pkg/router"
 	"github.com/opencloud-eu/opencloud/services/proxy/pkg/server/debug"
 	proxyHTTP "github.com/opencloud-eu/opencloud/services/proxy/pkg/server/http"
@@ -76,6 +84,15 @@ func Server(cfg *config.Config) *cli.Command {
 				store.Authentication(cfg.PreSignedURL.SigningKeys.AuthUsername, cfg.PreSignedURL.SigningKeys.AuthPassword),
 			)
 
+			var rateLimiter *ratelimit.Limiter
+			if cfg.RateLimit.Enabled {
+				rateLimitStore, err := newRateLimitStore(cfg)
+				if err != nil {
+					return err
+				}
+				rateLimiter = ratelimit.NewLimiter(rateLimitStore, cfg.RateLimit)
+			}
+
 			logger := logging.Configure(cfg.Service.Name, cfg.Log)
 			traceProvider, err := tracing.GetServiceTraceProvider(cfg.Tracing, cfg.Service.Name)
 			if err != nil {
@@ -167,6 +184,19 @@ func Server(cfg *config.Config) *cli.Command {
 						Msg("Error initializing events publisher")
 					return fmt.Errorf("could not initialize events publisher %w", err)
 				}
+				publisher = eventstream.Typed(publisher)
+			}
+
+			var mtlsVerifier *mtls.Verifier
+			if cfg.MTLS.Enabled {
+				var err error
+				mtlsVerifier, err = mtls.NewVerifier(cfg.MTLS)
+				if err != nil {
+					logger.Error().
+						Err(err).
+						Msg("Error initializing the mtls authentication")
+					return fmt.Errorf("could not initialize the mtls authentication: %w", err)
+				}
 			}
 
 			lh := staticroutes.StaticRouteHandler{
@@ -186,7 +216,12 @@ func Server(cfg *config.Config) *cli.Command {
 
 			gr := runner.NewGroup()
 			{
-				middlewares := loadMiddlewares(logger, cfg, userInfoCache, signingKeyStore, traceProvider, *m, userProvider, publisher, gatewaySelector, serviceSelector)
+				middlewares := loadMiddlewares(logger, cfg, userInfoCache, signingKeyStore, rateLimiter, mtlsVerifier, traceProvider, *m, userProvider, publisher, gatewaySelector, serviceSelector)
+
+				var clientCAs *x509.CertPool
+				if mtlsVerifier != nil {
+					clientCAs = mtlsVerifier.ClientCAs()
+				}
 
 				server, err := proxyHTTP.Server(
 					proxyHTTP.Handler(lh.Handler()),
@@ -195,6 +230,7 @@ func Server(cfg *config.Config) *cli.Command {
 					proxyHTTP.Config(cfg),
 					proxyHTTP.Metrics(metrics.New()),
 					proxyHTTP.Middlewares(middlewares),
+					proxyHTTP.ClientCAs(clientCAs),
 				)
 				if err != nil {
 					logger.Error().
@@ -235,8 +271,38 @@ func Server(cfg *config.Config) *cli.Command {
 	}
 }
 
+// newRateLimitStore returns the store of the rate limits. The nats-js-kv store is shared by all proxies.
+func newRateLimitStore(cfg *config.Config) (ratelimit.Store, error) {
+	ttl := ratelimit.StoreTTL(cfg.RateLimit)
+	if cfg.RateLimit.Store.Store != "nats-js-kv" {
+		return ratelimit.NewMemoryStore(ttl), nil
+	}
+
+	natsOptions := nats.Options{
+		Servers:  cfg.RateLimit.Store.Nodes,
+		User:     cfg.RateLimit.Store.AuthUsername,
+		Password: cfg.RateLimit.Store.AuthPassword,
+	}
+	conn, err := natsOptions.Connect()
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to the rate limit store: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, err
+	}
+	kv, err := js.CreateOrUpdateKeyValue(context.Background(), jetstream.KeyValueConfig{
+		Bucket: cfg.RateLimit.Store.Database,
+		TTL:    ttl,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bucket (%s): %w", cfg.RateLimit.Store.Database, err)
+	}
+	return ratelimit.NewNatsStore(kv), nil
+}
+
 func loadMiddlewares(logger log.Logger, cfg *config.Config,
-	userInfoCache, signingKeyStore microstore.Store,
+	userInfoCache, signingKeyStore microstore.Store, rateLimiter *ratelimit.Limiter, mtlsVerifier *mtls.Verifier,
 	traceProvider trace.TracerProvider, metrics metrics.Metrics,
 	userProvider backend.UserBackend, publisher events.Publisher,
 	gatewaySelector pool.Selectable[gateway.GatewayAPIClient], serviceSelector selector.Selector) alice.Chain {
@@ -306,6 +372,21 @@ func loadMiddlewares(logger log.Logger, cfg *config.Config,
 		)),
 		middleware.SkipUserInfo(cfg.OIDC.SkipUserInfo),
 	))
+	if mtlsVerifier != nil {
+		trustedProxies, err := middleware.ParseNetworks(cfg.MTLS.TrustedProxies)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to parse the trusted proxies of the mtls authentication.")
+		}
+		authenticators = append(authenticators, middleware.MTLSAuthenticator{
+			Logger:           logger,
+			UserProvider:     userProvider,
+			UserRoleAssigner: roleAssigner,
+			Verifier:         mtlsVerifier,
+			UserClaim:        cfg.MTLS.UserClaim,
+			TrustedHeader:    cfg.MTLS.TrustedHeader,
+			TrustedProxies:   trustedProxies,
+		})
+	}
 	authenticators = append(authenticators, middleware.PublicShareAuthenticator{
 		Logger:              logger,
 		RevaGatewaySelector: gatewaySelector,
@@ -330,13 +411,27 @@ func loadMiddlewares(logger log.Logger, cfg *config.Config,
 		URLVerifier:        signURLVerifier,
 	})
 
+	trustedProxies, err := middleware.ParseNetworks(cfg.TrustedProxies)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to parse the trusted proxies.")
+	}
+
+	var accessLogFile io.Writer
+	if cfg.AccessLog.File != "" {
+		accessLogFile, err = os.OpenFile(cfg.AccessLog.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+		if err != nil {
+			logger.Fatal().Err(err).Str("file", cfg.AccessLog.File).Msg("Failed to open the access log file.")
+		}
+	}
+
 	cspConfig, err := middleware.LoadCSPConfig(cfg)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to load CSP configuration.")
 	}
 
 	return alice.New(
-		chimiddleware.RealIP,
+		middleware.PeerAddr,
+		middleware.RealIP(trustedProxies),
 		chimiddleware.RequestID,
 		// first make sure we log all requests and redirect to https if necessary
 		otelhttp.NewMiddleware("proxy",
@@ -348,10 +443,16 @@ func loadMiddlewares(logger log.Logger, cfg *config.Config,
 		middleware.Tracer(traceProvider),
 		pkgmiddleware.TraceContext,
 		middleware.Instrumenter(metrics),
-		middleware.AccessLog(logger),
+		middleware.AccessLog(logger, cfg.AccessLog.Format, accessLogFile),
 		middleware.ContextLogger(logger),
 		middleware.HTTPSRedirect,
 		middleware.Security(cspConfig),
+		// limit requests and failed authentication attempts before they reach the authenticators
+		middleware.RateLimit(
+			middleware.Logger(logger),
+			middleware.RateLimiter(rateLimiter),
+			middleware.EventsPublisher(publisher),
+		),
 		router.Middleware(serviceSelector, cfg.PolicySelector, cfg.Policies, logger),
 		middleware.Authentication(
 			authenticators,
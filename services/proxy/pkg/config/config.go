@@ -45,10 +45,54 @@ type Config struct {
 	PoliciesMiddleware    PoliciesMiddleware  `yaml:"policies_middleware"`
 	CSPConfigFileLocation string              `yaml:"csp_config_file_location" env:"PROXY_CSP_CONFIG_FILE_LOCATION" desc:"The location of the CSP configuration file." introductionVersion:"1.0.0"`
 	Events                Events              `yaml:"events"`
+	RateLimit             RateLimit           `yaml:"rate_limit"`
+	MTLS                  MTLS                `yaml:"mtls"`
+	TrustedProxies        []string            `yaml:"trusted_proxies" env:"PROXY_TRUSTED_PROXIES" desc:"A list of IP addresses or CIDR ranges of reverse proxies in front of the proxy. The client IP address is only taken from the X-Forwarded-For and X-Real-IP headers of requests from these addresses. Defaults to the loopback addresses, add the address or network of a reverse proxy or ingress controller running on another host. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	AccessLog             AccessLog           `yaml:"access_log"`
 
 	Context context.Context `json:"-" yaml:"-"`
 }
 
+// RateLimit configures the rate limiting of requests and the protection against brute-force attacks.
+type RateLimit struct {
+	Enabled              bool            `yaml:"enabled" env:"PROXY_RATE_LIMIT_ENABLED" desc:"Enable the rate limiting of requests and the protection against brute-force attacks on passwords and password protected public links." introductionVersion:"%%NEXT%%"`
+	RequestsPerSecond    float64         `yaml:"requests_per_second" env:"PROXY_RATE_LIMIT_REQUESTS_PER_SECOND" desc:"The number of requests per second a client IP address may send on average. Set to 0 to not limit the number of requests." introductionVersion:"%%NEXT%%"`
+	RequestBurst         int             `yaml:"request_burst" env:"PROXY_RATE_LIMIT_REQUEST_BURST" desc:"The number of requests a client IP address may send at once before the limit of PROXY_RATE_LIMIT_REQUESTS_PER_SECOND applies." introductionVersion:"%%NEXT%%"`
+	FailedAttempts       int             `yaml:"failed_attempts" env:"PROXY_RATE_LIMIT_FAILED_ATTEMPTS" desc:"The number of failed authentication attempts per client IP address, username and public link after which further attempts are locked out." introductionVersion:"%%NEXT%%"`
+	FailedAttemptsRefill time.Duration   `yaml:"failed_attempts_refill" env:"PROXY_RATE_LIMIT_FAILED_ATTEMPTS_REFILL" desc:"The time after which a failed authentication attempt is forgotten. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	ProgressiveDelay     time.Duration   `yaml:"progressive_delay" env:"PROXY_RATE_LIMIT_PROGRESSIVE_DELAY" desc:"The delay added to a request for every recent failed authentication attempt of the same client IP address, username or public link. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	MaxDelay             time.Duration   `yaml:"max_delay" env:"PROXY_RATE_LIMIT_MAX_DELAY" desc:"The maximum delay added to a request. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	LockoutDuration      time.Duration   `yaml:"lockout_duration" env:"PROXY_RATE_LIMIT_LOCKOUT_DURATION" desc:"The time a client IP address, username or public link is locked out after too many failed authentication attempts. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	Store                *RateLimitStore `yaml:"store"`
+}
+
+// RateLimitStore configures the store holding the rate limits. Use a shared store to apply the limits across replicas.
+type RateLimitStore struct {
+	Store        string   `yaml:"store" env:"PROXY_RATE_LIMIT_STORE" desc:"The type of the rate limit store. Supported values are: 'memory' and 'nats-js-kv'. Use 'nats-js-kv' when running more than one proxy. See the text description for details." introductionVersion:"%%NEXT%%"`
+	Nodes        []string `yaml:"addresses" env:"OC_CACHE_STORE_NODES;PROXY_RATE_LIMIT_STORE_NODES" desc:"A list of nodes to access the configured store. This has no effect when 'memory' store is configured. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	Database     string   `yaml:"database" env:"PROXY_RATE_LIMIT_STORE_DATABASE" desc:"The name of the NATS key value bucket holding the rate limits." introductionVersion:"%%NEXT%%"`
+	AuthUsername string   `yaml:"username" env:"OC_CACHE_AUTH_USERNAME;PROXY_RATE_LIMIT_STORE_AUTH_USERNAME" desc:"The username to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT%%"`
+	AuthPassword string   `yaml:"password" env:"OC_CACHE_AUTH_PASSWORD;PROXY_RATE_LIMIT_STORE_AUTH_PASSWORD" desc:"The password to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT%%" mask:"password"`
+}
+
+// AccessLog configures the access log.
+type AccessLog struct {
+	Format string `yaml:"format" env:"PROXY_ACCESS_LOG_FORMAT" desc:"The format of the access log. Supported values are 'structured' for log entries in the format of the service log, 'common' for the Common Log Format and 'combined' for the Combined Log Format." introductionVersion:"%%NEXT%%"`
+	File   string `yaml:"file" env:"PROXY_ACCESS_LOG_FILE" desc:"The path of a file the access log is written to. Leave empty to write the access log to the service log for the 'structured' format and to the standard output for the other formats." introductionVersion:"%%NEXT%%"`
+}
+
+// MTLS configures the authentication with X.509 client certificates.
+type MTLS struct {
+	Enabled        bool     `yaml:"enabled" env:"PROXY_MTLS_ENABLED" desc:"Enable the authentication with X.509 client certificates. The certificates are either requested by the TLS listener of the proxy or passed by a TLS terminating reverse proxy, see PROXY_MTLS_TRUSTED_HEADER." introductionVersion:"%%NEXT%%"`
+	CACerts        []string `yaml:"ca_certs" env:"PROXY_MTLS_CA_CERTS" desc:"A list of paths to PEM encoded CA bundles which client certificates must be issued by. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	CRLs           []string `yaml:"crls" env:"PROXY_MTLS_CRLS" desc:"A list of paths to PEM or DER encoded certificate revocation lists. Client certificates revoked by one of the lists are rejected. The files are reloaded when they change. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+	IdentityField  string   `yaml:"identity_field" env:"PROXY_MTLS_IDENTITY_FIELD" desc:"The field of the client certificate identifying the user. Supported values are 'subject' for the distinguished name of the subject, 'subject_cn' for the common name of the subject, 'san_email' for the first email address of the subject alternative names and 'oid' for a custom attribute, see PROXY_MTLS_IDENTITY_OID." introductionVersion:"%%NEXT%%"`
+	IdentityOID    string   `yaml:"identity_oid" env:"PROXY_MTLS_IDENTITY_OID" desc:"The object identifier of the subject attribute or certificate extension identifying the user, like '0.9.2342.19200300.100.1.1' for the user ID. Only applies when PROXY_MTLS_IDENTITY_FIELD is set to 'oid'." introductionVersion:"%%NEXT%%"`
+	UserClaim      string   `yaml:"user_claim" env:"PROXY_MTLS_USER_CLAIM" desc:"The claim the identity of the client certificate is looked up by in the account backend. Supported values are 'username', 'mail' and 'userid'." introductionVersion:"%%NEXT%%"`
+	TrustedHeader  string   `yaml:"trusted_header" env:"PROXY_MTLS_TRUSTED_HEADER" desc:"The name of a header a TLS terminating reverse proxy passes the verified client certificate in, like 'X-SSL-Client-Cert'. The certificate can be URL encoded PEM or base64 encoded DER. Leave empty to only accept certificates from the TLS listener of the proxy." introductionVersion:"%%NEXT%%"`
+	TrustedProxies []string `yaml:"trusted_proxies" env:"PROXY_MTLS_TRUSTED_PROXIES" desc:"A list of IP addresses or CIDR ranges of the reverse proxies which are allowed to pass client certificates in PROXY_MTLS_TRUSTED_HEADER. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT%%"`
+}
+
 // Policy enables us to use multiple directors.
 type Policy struct {
 	Name   string  `yaml:"name"`
@@ -109,6 +153,21 @@ const (
 	// AccessTokenVerificationIntrospect = "introspect"
 )
 
+// The formats of the access log
+const (
+	AccessLogFormatStructured = "structured"
+	AccessLogFormatCommon     = "common"
+	AccessLogFormatCombined   = "combined"
+)
+
+// The fields of a client certificate identifying the user
+const (
+	MTLSIdentitySubject   = "subject"
+	MTLSIdentitySubjectCN = "subject_cn"
+	MTLSIdentitySANEmail  = "san_email"
+	MTLSIdentityOID       = "oid"
+)
+
 // OIDC is the config for the OpenID-Connect middleware. If set the proxy will try to authenticate every request
 // with the configured oidc-provider
 type OIDC struct {
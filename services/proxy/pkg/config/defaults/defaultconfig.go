@@ -103,6 +103,33 @@ func DefaultConfig() *config.Config {
 		AuthMiddleware: config.AuthMiddleware{
 			AllowAppAuth: true,
 		},
+		RateLimit: config.RateLimit{
+			Enabled:              false,
+			RequestsPerSecond:    0,
+			RequestBurst:         100,
+			FailedAttempts:       10,
+			FailedAttemptsRefill: time.Minute,
+			ProgressiveDelay:     200 * time.Millisecond,
+			MaxDelay:             5 * time.Second,
+			LockoutDuration:      15 * time.Minute,
+			Store: &config.RateLimitStore{
+				Store:    "memory",
+				Nodes:    []string{"127.0.0.1:9233"},
+				Database: "cache-ratelimit",
+			},
+		},
+		TrustedProxies: []string{
+			"127.0.0.0/8",
+			"::1/128",
+		},
+		AccessLog: config.AccessLog{
+			Format: config.AccessLogFormatStructured,
+		},
+		MTLS: config.MTLS{
+			Enabled:       false,
+			IdentityField: "subject_cn",
+			UserClaim:     "username",
+		},
 	}
 }
 
@@ -256,10 +283,21 @@ func DefaultPolicies() []config.Policy {
 					Endpoint: "/graph/v1beta1/extensions/org.libregraph/activities",
 					Service:  "eu.opencloud.web.activitylog",
 				},
+				{
+					Endpoint:    "/graph/v1.0/invitations/redeem",
+					Service:     "eu.opencloud.web.invitations",
+					Unprotected: true,
+				},
 				{
 					Endpoint: "/graph/v1.0/invitations",
 					Service:  "eu.opencloud.web.invitations",
 				},
+				{
+					Endpoint:         "/graph/scim/v2/",
+					Service:          "eu.opencloud.web.graph",
+					Unprotected:      true,
+					SkipXAccessToken: true,
+				},
 				{
 					Endpoint: "/graph/",
 					Service:  "eu.opencloud.web.graph",
@@ -317,6 +355,15 @@ func EnsureDefaults(cfg *config.Config) {
 		cfg.OIDC.UserinfoCache = &config.Cache{}
 	}
 
+	if cfg.RateLimit.Store == nil && cfg.Commons != nil && cfg.Commons.Cache != nil {
+		cfg.RateLimit.Store = &config.RateLimitStore{
+			Store: "memory",
+			Nodes: cfg.Commons.Cache.Nodes,
+		}
+	} else if cfg.RateLimit.Store == nil {
+		cfg.RateLimit.Store = &config.RateLimitStore{}
+	}
+
 	if cfg.MachineAuthAPIKey == "" && cfg.Commons != nil && cfg.Commons.MachineAuthAPIKey != "" {
 		cfg.MachineAuthAPIKey = cfg.Commons.MachineAuthAPIKey
 	}
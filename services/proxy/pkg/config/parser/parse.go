@@ -53,6 +53,43 @@ func Validate(cfg *config.Config) error {
 		)
 	}
 
+	switch cfg.AccessLog.Format {
+	case config.AccessLogFormatStructured, config.AccessLogFormatCommon, config.AccessLogFormatCombined:
+	default:
+		return fmt.Errorf(
+			"Invalid value '%s' for 'access_log.format' in service %s. Possible values are: '%s', '%s' or '%s'.",
+			cfg.AccessLog.Format, cfg.Service.Name,
+			config.AccessLogFormatStructured, config.AccessLogFormatCommon, config.AccessLogFormatCombined,
+		)
+	}
+
+	if cfg.MTLS.Enabled {
+		if len(cfg.MTLS.CACerts) == 0 {
+			return fmt.Errorf("The mtls authentication of service %s needs at least one CA certificate, see PROXY_MTLS_CA_CERTS.", cfg.Service.Name)
+		}
+		switch cfg.MTLS.IdentityField {
+		case config.MTLSIdentitySubject, config.MTLSIdentitySubjectCN, config.MTLSIdentitySANEmail:
+		case config.MTLSIdentityOID:
+			if cfg.MTLS.IdentityOID == "" {
+				return fmt.Errorf("The mtls authentication of service %s needs an object identifier when the identity field is '%s', see PROXY_MTLS_IDENTITY_OID.", cfg.Service.Name, config.MTLSIdentityOID)
+			}
+		default:
+			return fmt.Errorf(
+				"Invalid value '%s' for 'identity_field' in service %s. Possible values are: '%s', '%s', '%s' or '%s'.",
+				cfg.MTLS.IdentityField, cfg.Service.Name,
+				config.MTLSIdentitySubject, config.MTLSIdentitySubjectCN, config.MTLSIdentitySANEmail, config.MTLSIdentityOID,
+			)
+		}
+		switch cfg.MTLS.UserClaim {
+		case "username", "mail", "userid":
+		default:
+			return fmt.Errorf("Invalid value '%s' for 'user_claim' in service %s. Possible values are: 'username', 'mail' or 'userid'.", cfg.MTLS.UserClaim, cfg.Service.Name)
+		}
+		if cfg.MTLS.TrustedHeader != "" && len(cfg.MTLS.TrustedProxies) == 0 {
+			return fmt.Errorf("The mtls authentication of service %s needs the trusted proxies to accept client certificates in the header '%s', see PROXY_MTLS_TRUSTED_PROXIES.", cfg.Service.Name, cfg.MTLS.TrustedHeader)
+		}
+	}
+
 	if cfg.ServiceAccount.ServiceAccountID == "" {
 		return shared.MissingServiceAccountID(cfg.Service.Name)
 	}
@@ -60,5 +97,12 @@ func Validate(cfg *config.Config) error {
 		return shared.MissingServiceAccountSecret(cfg.Service.Name)
 	}
 
+	if cfg.RateLimit.Enabled && cfg.RateLimit.Store.Store != "memory" && cfg.RateLimit.Store.Store != "nats-js-kv" {
+		return fmt.Errorf(
+			"Invalid value '%s' for 'rate_limit.store.store' in service %s. Possible values are: 'memory' or 'nats-js-kv'.",
+			cfg.RateLimit.Store.Store, cfg.Service.Name,
+		)
+	}
+
 	return nil
 }
@@ -1,16 +1,56 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/config"
+	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// AccessLog is a middleware to log http requests at info level logging.
-func AccessLog(logger log.Logger) func(http.Handler) http.Handler {
+// _clfTimeFormat is the time format of the Common Log Format
+const _clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+type accessLogDetailsKey struct{}
+
+// accessLogDetails collects details about a request while it passes the middlewares.
+type accessLogDetails struct {
+	authMethod string
+	userID     string
+	username   string
+	policy     string
+	backend    string
+}
+
+// accessLogDetailsFromContext returns the details of the access log entry of a request. It returns
+// nil when the request is not logged.
+func accessLogDetailsFromContext(ctx context.Context) *accessLogDetails {
+	d, _ := ctx.Value(accessLogDetailsKey{}).(*accessLogDetails)
+	return d
+}
+
+// AccessLog is a middleware to log http requests. With the structured format the requests are
+// logged at info level to the logger or, when out is set, unconditionally to out. The Common and
+// Combined Log Formats are written to out or the standard output.
+func AccessLog(logger log.Logger, format string, out io.Writer) func(http.Handler) http.Handler {
+	var writeLine func(string)
+	switch {
+	case format == config.AccessLogFormatCommon || format == config.AccessLogFormatCombined:
+		writeLine = lineWriter(out)
+	case out != nil:
+		logger = log.Logger{Logger: zerolog.New(out).With().Timestamp().Logger()}
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -18,7 +58,22 @@ func AccessLog(logger log.Logger) func(http.Handler) http.Handler {
 			// add Request Id to all responses
 			w.Header().Set(middleware.RequestIDHeader, requestID)
 			wrap := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
-			next.ServeHTTP(wrap, r)
+			details := &accessLogDetails{}
+			next.ServeHTTP(wrap, r.WithContext(context.WithValue(r.Context(), accessLogDetailsKey{}, details)))
+
+			status := wrap.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if writeLine != nil {
+				line := commonLogLine(r, details, start, status, wrap.BytesWritten())
+				if format == config.AccessLogFormatCombined {
+					line += fmt.Sprintf(" %s %s", clfQuote(withoutQuery(r.Referer())), clfQuote(r.UserAgent()))
+				}
+				writeLine(line)
+				return
+			}
 
 			spanContext := trace.SpanContextFromContext(r.Context())
 			logger.Info().
@@ -27,11 +82,64 @@ func AccessLog(logger log.Logger) func(http.Handler) http.Handler {
 				Str("traceid", spanContext.TraceID().String()).
 				Str("remote-addr", r.RemoteAddr).
 				Str("method", r.Method).
-				Int("status", wrap.Status()).
+				Int("status", status).
 				Str("path", r.URL.Path).
 				Dur("duration", time.Since(start)).
 				Int("bytes", wrap.BytesWritten()).
+				Str("user-agent", r.UserAgent()).
+				Str("userid", details.userID).
+				Str("auth-method", details.authMethod).
+				Str("policy", details.policy).
+				Str("backend", details.backend).
 				Msg("access-log")
 		})
 	}
 }
+
+// commonLogLine formats a request in the Common Log Format. The query is not logged, it can carry
+// credentials like public link tokens and the signatures of pre-signed urls.
+func commonLogLine(r *http.Request, d *accessLogDetails, start time.Time, status, bytes int) string {
+	return fmt.Sprintf("%s - %s [%s] %s %d %s",
+		clfValue(hostOf(r.RemoteAddr)),
+		clfValue(d.username),
+		start.Format(_clfTimeFormat),
+		strconv.Quote(fmt.Sprintf("%s %s %s", r.Method, r.URL.EscapedPath(), r.Proto)),
+		status,
+		clfValue(strconv.Itoa(bytes)),
+	)
+}
+
+// withoutQuery strips the query and the fragment from an url
+func withoutQuery(u string) string {
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		return u[:i]
+	}
+	return u
+}
+
+func clfValue(v string) string {
+	if v == "" || v == "0" {
+		return "-"
+	}
+	return v
+}
+
+func clfQuote(v string) string {
+	if v == "" {
+		return `"-"`
+	}
+	return strconv.Quote(v)
+}
+
+// lineWriter returns a function writing whole lines to out or the standard output.
+func lineWriter(out io.Writer) func(string) {
+	if out == nil {
+		out = os.Stdout
+	}
+	var mu sync.Mutex
+	return func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = io.WriteString(out, line+"\n")
+	}
+}
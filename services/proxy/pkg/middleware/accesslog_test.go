@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/config"
+)
+
+var _ = Describe("Logging requests", Label("AccessLog"), func() {
+	serve := func(format string, out *bytes.Buffer) {
+		handler := AccessLog(log.NopLogger(), format, out)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d := accessLogDetailsFromContext(r.Context())
+			d.userID = "alice-id"
+			d.username = "alice"
+			d.authMethod = "basic"
+			d.policy = "default"
+			d.backend = "eu.opencloud.web.graph"
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("hello"))
+		}))
+
+		req := httptest.NewRequest(http.MethodPut, "http://example.com/graph/v1.0/me?x=1&public-token=secret", http.NoBody)
+		req.RemoteAddr = "203.0.113.7:1234"
+		req.Header.Set("Referer", "https://example.com/s/link?signature=secret")
+		req.Header.Set("User-Agent", "curl/8.0")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	It("writes structured entries with the request details", func() {
+		out := &bytes.Buffer{}
+		serve(config.AccessLogFormatStructured, out)
+
+		entry := map[string]interface{}{}
+		Expect(json.Unmarshal(out.Bytes(), &entry)).To(Succeed())
+		Expect(entry["status"]).To(BeEquivalentTo(http.StatusCreated))
+		Expect(entry["userid"]).To(Equal("alice-id"))
+		Expect(entry["auth-method"]).To(Equal("basic"))
+		Expect(entry["policy"]).To(Equal("default"))
+		Expect(entry["backend"]).To(Equal("eu.opencloud.web.graph"))
+		Expect(entry["user-agent"]).To(Equal("curl/8.0"))
+	})
+
+	It("writes the Common Log Format", func() {
+		out := &bytes.Buffer{}
+		serve(config.AccessLogFormatCommon, out)
+
+		Expect(out.String()).To(MatchRegexp(`^203\.0\.113\.7 - alice \[[^\]]+\] "PUT /graph/v1\.0/me HTTP/1\.1" 201 5\n$`))
+	})
+
+	It("writes the Combined Log Format", func() {
+		out := &bytes.Buffer{}
+		serve(config.AccessLogFormatCombined, out)
+
+		Expect(out.String()).To(HaveSuffix(`201 5 "https://example.com/s/link" "curl/8.0"` + "\n"))
+		Expect(out.String()).ToNot(ContainSubstring("secret"))
+	})
+})
@@ -235,6 +235,11 @@ func (m accountResolver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
+	if details := accessLogDetailsFromContext(ctx); details != nil {
+		details.userID = user.GetId().GetOpaqueId()
+		details.username = user.GetUsername()
+	}
+
 	ri := router.ContextRoutingInfo(ctx)
 	if ri.RemoteUserHeader() != "" {
 		req.Header.Set(ri.RemoteUserHeader(), user.GetId().GetOpaqueId())
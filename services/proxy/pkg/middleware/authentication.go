@@ -66,6 +66,11 @@ func Authentication(auths []Authenticator, opts ...Option) func(next http.Handle
 			defer span.End()
 
 			ri := router.ContextRoutingInfo(ctx)
+			details := accessLogDetailsFromContext(ctx)
+			if details != nil {
+				details.policy = ri.Policy()
+				details.backend = ri.Backend()
+			}
 			if isOIDCTokenAuth(r) || ri.IsRouteUnprotected() || r.Method == "OPTIONS" {
 				// Either this is a request that does not need any authentication or
 				// the authentication for this request is handled by the IdP.
@@ -77,6 +82,9 @@ func Authentication(auths []Authenticator, opts ...Option) func(next http.Handle
 
 			for _, a := range auths {
 				if req, ok := a.Authenticate(r); ok {
+					if details != nil {
+						details.authMethod = authMethod(a)
+					}
 					span.End()
 					next.ServeHTTP(w, req)
 					return
@@ -130,6 +138,26 @@ func Authentication(auths []Authenticator, opts ...Option) func(next http.Handle
 	}
 }
 
+// authMethod returns the name of the authentication method of an authenticator for the access log.
+func authMethod(a Authenticator) string {
+	switch a.(type) {
+	case BasicAuthenticator:
+		return "basic"
+	case AppAuthAuthenticator:
+		return "app_auth"
+	case *OIDCAuthenticator:
+		return "oidc"
+	case MTLSAuthenticator:
+		return "mtls"
+	case PublicShareAuthenticator:
+		return "public_share"
+	case SignedURLAuthenticator:
+		return "signed_url"
+	default:
+		return fmt.Sprintf("%T", a)
+	}
+}
+
 // The token auth endpoint uses basic auth for clients, see https://openid.net/specs/openid-connect-basic-1_0.html#TokenRequest
 // > The Client MUST authenticate to the Token Endpoint using the HTTP Basic method, as described in 2.3.1 of OAuth 2.0.
 func isOIDCTokenAuth(req *http.Request) bool {
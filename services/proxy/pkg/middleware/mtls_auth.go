@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"net"
+	"net/http"
+
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/mtls"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/user/backend"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/userroles"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+)
+
+// MTLSAuthenticator is the authenticator responsible for the authentication with X.509 client certificates.
+type MTLSAuthenticator struct {
+	Logger           log.Logger
+	UserProvider     backend.UserBackend
+	UserRoleAssigner userroles.UserRoleAssigner
+	Verifier         *mtls.Verifier
+	// UserClaim is the claim the identity of the certificate is looked up by
+	UserClaim string
+	// TrustedHeader is the header a TLS terminating reverse proxy passes the client certificate in
+	TrustedHeader string
+	// TrustedProxies are the networks of the reverse proxies allowed to set the TrustedHeader
+	TrustedProxies []*net.IPNet
+}
+
+// Authenticate implements the authenticator interface to authenticate requests via client certificates.
+func (m MTLSAuthenticator) Authenticate(r *http.Request) (*http.Request, bool) {
+	if isPublicPath(r.URL.Path) && isPublicWithShareToken(r) {
+		// The authentication of public path requests is handled by another authenticator.
+		return nil, false
+	}
+
+	chain := m.certificateChain(r)
+	if len(chain) == 0 {
+		return nil, false
+	}
+
+	cert, err := m.Verifier.Verify(chain)
+	if err != nil {
+		m.Logger.Warn().
+			Err(err).
+			Str("authenticator", "mtls").
+			Str("subject", chain[0].Subject.String()).
+			Str("path", r.URL.Path).
+			Msg("client certificate rejected")
+		return nil, false
+	}
+
+	identity, err := m.Verifier.Identity(cert)
+	if err != nil {
+		m.Logger.Warn().
+			Err(err).
+			Str("authenticator", "mtls").
+			Str("subject", cert.Subject.String()).
+			Msg("could not read the identity of the client certificate")
+		return nil, false
+	}
+
+	user, token, err := m.UserProvider.GetUserByClaims(r.Context(), m.UserClaim, identity)
+	if err != nil {
+		m.Logger.Error().
+			Err(err).
+			Str("authenticator", "mtls").
+			Str("identity", identity).
+			Str("path", r.URL.Path).
+			Msg("failed to authenticate request")
+		return nil, false
+	}
+
+	if user, err = m.UserRoleAssigner.ApplyUserRole(r.Context(), user); err != nil {
+		m.Logger.Error().Err(err).Str("authenticator", "mtls").Str("identity", identity).Msg("failed to load user roles")
+		return nil, false
+	}
+
+	ctx := revactx.ContextSetUser(r.Context(), user)
+	ctx = revactx.ContextSetToken(ctx, token)
+
+	m.Logger.Debug().
+		Str("authenticator", "mtls").
+		Str("path", r.URL.Path).
+		Msg("successfully authenticated request")
+	return r.WithContext(ctx), true
+}
+
+// certificateChain returns the client certificate followed by its intermediates, either from the
+// TLS connection or from the trusted header set by a reverse proxy.
+func (m MTLSAuthenticator) certificateChain(r *http.Request) []*x509.Certificate {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return r.TLS.PeerCertificates
+	}
+
+	if m.TrustedHeader == "" {
+		return nil
+	}
+	value := r.Header.Get(m.TrustedHeader)
+	if value == "" {
+		return nil
+	}
+
+	peer := hostOf(peerAddr(r))
+	if !containsIP(m.TrustedProxies, peer) {
+		m.Logger.Warn().
+			Str("authenticator", "mtls").
+			Str("peer", peer).
+			Str("header", m.TrustedHeader).
+			Msg("ignoring client certificate header of an untrusted peer")
+		return nil
+	}
+
+	chain, err := mtls.ParseHeader(value)
+	if err != nil {
+		m.Logger.Warn().Err(err).Str("authenticator", "mtls").Msg("could not parse the client certificate header")
+		return nil
+	}
+	return chain
+}
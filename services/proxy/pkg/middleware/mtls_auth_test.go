@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/mtls"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/user/backend"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/user/backend/mocks"
+	userRoleMocks "github.com/opencloud-eu/opencloud/services/proxy/pkg/userroles/mocks"
+	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/stretchr/testify/mock"
+)
+
+var _ = Describe("Authenticating requests", Label("MTLSAuthenticator"), func() {
+	var (
+		authenticator Authenticator
+		clientCert    *x509.Certificate
+	)
+
+	BeforeEach(func() {
+		caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		caTpl := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "Test CA"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}
+		caDER, err := x509.CreateCertificate(rand.Reader, caTpl, caTpl, &caKey.PublicKey, caKey)
+		Expect(err).ToNot(HaveOccurred())
+		caCert, err := x509.ParseCertificate(caDER)
+		Expect(err).ToNot(HaveOccurred())
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: "device-1"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}, caCert, &key.PublicKey, caKey)
+		Expect(err).ToNot(HaveOccurred())
+		clientCert, err = x509.ParseCertificate(der)
+		Expect(err).ToNot(HaveOccurred())
+
+		caFile := filepath.Join(GinkgoT().TempDir(), "ca.pem")
+		Expect(os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0600)).To(Succeed())
+		verifier, err := mtls.NewVerifier(config.MTLS{CACerts: []string{caFile}, IdentityField: config.MTLSIdentitySubjectCN})
+		Expect(err).ToNot(HaveOccurred())
+
+		ub := &mocks.UserBackend{}
+		ub.On("GetUserByClaims", mock.Anything, "username", "device-1").Return(
+			&userv1beta1.User{Id: &userv1beta1.UserId{OpaqueId: "device-1-id"}, Username: "device-1"}, "reva-token", nil,
+		)
+		ub.On("GetUserByClaims", mock.Anything, mock.Anything, mock.Anything).Return(nil, "", backend.ErrAccountNotFound)
+		ra := &userRoleMocks.UserRoleAssigner{}
+		ra.On("ApplyUserRole", mock.Anything, mock.Anything).Return(
+			func(_ context.Context, u *userv1beta1.User) *userv1beta1.User { return u }, nil,
+		)
+
+		_, trusted, _ := net.ParseCIDR("10.0.0.0/8")
+		authenticator = MTLSAuthenticator{
+			Logger:           log.NopLogger(),
+			UserProvider:     ub,
+			UserRoleAssigner: ra,
+			Verifier:         verifier,
+			UserClaim:        "username",
+			TrustedHeader:    "X-SSL-Client-Cert",
+			TrustedProxies:   []*net.IPNet{trusted},
+		}
+	})
+
+	headerValue := func() string {
+		return url.PathEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientCert.Raw})))
+	}
+
+	When("the client presents a certificate on the TLS connection", func() {
+		It("should successfully authenticate", func() {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/example/path", http.NoBody)
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert}}
+
+			req2, valid := authenticator.Authenticate(req)
+
+			Expect(valid).To(Equal(true))
+			user, ok := revactx.ContextGetUser(req2.Context())
+			Expect(ok).To(Equal(true))
+			Expect(user.GetId().GetOpaqueId()).To(Equal("device-1-id"))
+			token, ok := revactx.ContextGetToken(req2.Context())
+			Expect(ok).To(Equal(true))
+			Expect(token).To(Equal("reva-token"))
+		})
+	})
+
+	When("a trusted reverse proxy passes the certificate in the header", func() {
+		It("should successfully authenticate", func() {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/example/path", http.NoBody)
+			req.RemoteAddr = "10.1.2.3:4711"
+			req.Header.Set("X-SSL-Client-Cert", headerValue())
+
+			_, valid := authenticator.Authenticate(req)
+
+			Expect(valid).To(Equal(true))
+		})
+	})
+
+	When("an untrusted peer passes the certificate in the header", func() {
+		It("should fail to authenticate", func() {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/example/path", http.NoBody)
+			req.RemoteAddr = "192.0.2.1:4711"
+			req.Header.Set("X-SSL-Client-Cert", headerValue())
+
+			req2, valid := authenticator.Authenticate(req)
+
+			Expect(valid).To(Equal(false))
+			Expect(req2).To(BeNil())
+		})
+		It("should use the peer address instead of the forwarded client address", func() {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/example/path", http.NoBody)
+			req.RemoteAddr = "192.0.2.1:4711"
+			req.Header.Set("X-SSL-Client-Cert", headerValue())
+
+			var valid bool
+			PeerAddr(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.RemoteAddr = "10.1.2.3"
+				_, valid = authenticator.Authenticate(r)
+			})).ServeHTTP(httptest.NewRecorder(), req)
+
+			Expect(valid).To(Equal(false))
+		})
+	})
+
+	When("the request has no client certificate", func() {
+		It("should fail to authenticate", func() {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/example/path", http.NoBody)
+
+			req2, valid := authenticator.Authenticate(req)
+
+			Expect(valid).To(Equal(false))
+			Expect(req2).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parsing networks", Label("Networks"), func() {
+	It("parses addresses and CIDR ranges", func() {
+		nets, err := ParseNetworks([]string{"10.0.0.0/8", "192.168.1.1", "::1"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nets).To(HaveLen(3))
+		Expect(nets[1].String()).To(Equal("192.168.1.1/32"))
+		Expect(nets[2].String()).To(Equal("::1/128"))
+
+		Expect(containsIP(nets, "10.1.2.3")).To(BeTrue())
+		Expect(containsIP(nets, "192.168.1.2")).To(BeFalse())
+		Expect(containsIP(nets, "not-an-ip")).To(BeFalse())
+	})
+
+	It("rejects invalid networks", func() {
+		_, err := ParseNetworks([]string{"example.org"})
+		Expect(err).To(HaveOccurred())
+		_, err = ParseNetworks([]string{"10.0.0.0/33"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -10,6 +10,7 @@ import (
 	policiessvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/policies/v0"
 	settingssvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/settings/v0"
 	"github.com/opencloud-eu/opencloud/services/proxy/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/ratelimit"
 	"github.com/opencloud-eu/opencloud/services/proxy/pkg/user/backend"
 	"github.com/opencloud-eu/opencloud/services/proxy/pkg/userroles"
 	"github.com/opencloud-eu/reva/v2/pkg/events"
@@ -74,6 +75,8 @@ type Options struct {
 	// MultiTenantEnabled causes the account resolve middleware to reject users that don't have a tenant id assigned
 	MultiTenantEnabled bool
 	EventsPublisher    events.Publisher
+	// RateLimiter limits the requests and failed authentication attempts
+	RateLimiter *ratelimit.Limiter
 }
 
 // newOptions initializes the available default options.
@@ -254,3 +257,10 @@ func EventsPublisher(ep events.Publisher) Option {
 		o.EventsPublisher = ep
 	}
 }
+
+// RateLimiter sets the rate limiter.
+func RateLimiter(l *ratelimit.Limiter) Option {
+	return func(o *Options) {
+		o.RateLimiter = l
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type peerAddrKey struct{}
+
+// PeerAddr remembers the address of the peer connected to the proxy before the remote address of
+// the request is replaced with the client address forwarded by a reverse proxy.
+func PeerAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), peerAddrKey{}, r.RemoteAddr)))
+	})
+}
+
+// peerAddr returns the address of the peer connected to the proxy.
+func peerAddr(r *http.Request) string {
+	if addr, ok := r.Context().Value(peerAddrKey{}).(string); ok {
+		return addr
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/ratelimit"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+)
+
+// RateLimit is a middleware limiting the number of requests per client IP address and the number of
+// failed authentication attempts per client IP address, username and public link. Requests are
+// delayed progressively with every recent failed attempt and rejected with 429 Too Many Requests
+// while a lockout is active.
+func RateLimit(optionSetters ...Option) func(next http.Handler) http.Handler {
+	options := newOptions(optionSetters...)
+	logger := options.Logger
+	limiter := options.RateLimiter
+
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr := remoteIP(r)
+			if retryAfter, ok := limiter.AllowRequest(r.Context(), addr); !ok {
+				tooManyRequests(w, retryAfter)
+				return
+			}
+
+			keys := rateLimitKeys(r, addr)
+			if len(keys) == 0 {
+				// only requests with credentials count as authentication attempts
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			delay, retryAfter, locked := limiter.Check(r.Context(), keys...)
+			if locked {
+				logger.Debug().Str("remote-addr", addr).Str("path", r.URL.Path).Msg("rejecting request of locked out client")
+				tooManyRequests(w, retryAfter)
+				return
+			}
+			if delay > 0 {
+				t := time.NewTimer(delay)
+				select {
+				case <-r.Context().Done():
+					t.Stop()
+					return
+				case <-t.C:
+				}
+			}
+
+			wrap := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(wrap, r)
+
+			switch status := wrap.Status(); {
+			case status == http.StatusUnauthorized:
+				for _, lockout := range limiter.Fail(r.Context(), keys...) {
+					k, lockedUntil := lockout.Key, lockout.Until
+					logger.Warn().
+						Str("kind", k.Kind).
+						Str("subject", lockoutSubject(k)).
+						Str("remote-addr", addr).
+						Time("locked-until", lockedUntil).
+						Msg("too many failed authentication attempts, lockout started")
+
+					if options.EventsPublisher == nil {
+						continue
+					}
+					if err := events.Publish(r.Context(), options.EventsPublisher, ratelimit.LockoutStarted{
+						Kind:        k.Kind,
+						Subject:     lockoutSubject(k),
+						RemoteAddr:  addr,
+						LockedUntil: utils.TimeToTS(lockedUntil),
+						Timestamp:   utils.TSNow(),
+					}); err != nil {
+						logger.Error().Err(err).Msg("could not publish lockout event")
+					}
+				}
+			case status < http.StatusBadRequest:
+				// a status of 0 means the handler did not write a header, which defaults to 200
+				limiter.Succeed(r.Context(), keys...)
+			}
+		})
+	}
+}
+
+// rateLimitKeys returns the keys the authentication attempt of a request counts for.
+func rateLimitKeys(r *http.Request, addr string) []ratelimit.Key {
+	var keys []ratelimit.Key
+	if token := r.Header.Get(headerShareToken); token != "" {
+		keys = append(keys, ratelimit.Link(token))
+	} else if token := r.URL.Query().Get(headerShareToken); token != "" {
+		keys = append(keys, ratelimit.Link(token))
+	} else if username, _, ok := r.BasicAuth(); ok && username != "" {
+		keys = append(keys, ratelimit.User(username))
+	}
+	if len(keys) > 0 && addr != "" {
+		keys = append(keys, ratelimit.IP(addr))
+	}
+	return keys
+}
+
+// lockoutSubject returns the subject of a key as it may be logged. Public link tokens are secret,
+// only the hashed key is logged for them.
+func lockoutSubject(k ratelimit.Key) string {
+	if k.Kind == ratelimit.KindLink {
+		return k.String()
+	}
+	return k.Subject
+}
+
+// remoteIP returns the client IP address of a request without the port. The forwarded client address
+// is only used for requests from trusted proxies, see RealIP.
+func remoteIP(r *http.Request) string {
+	return hostOf(r.RemoteAddr)
+}
+
+// hostOf returns an address without the port.
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	w.WriteHeader(http.StatusTooManyRequests)
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/ratelimit"
+)
+
+var _ = Describe("Rate limiting requests", Label("RateLimit"), func() {
+	var handler http.Handler
+
+	BeforeEach(func() {
+		limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(0), config.RateLimit{
+			FailedAttempts:       2,
+			FailedAttemptsRefill: time.Minute,
+			LockoutDuration:      time.Minute,
+		})
+		handler = RateLimit(
+			Logger(log.NopLogger()),
+			RateLimiter(limiter),
+		)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, password, _ := r.BasicAuth(); password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}))
+	})
+
+	request := func(username, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/dav/files", http.NoBody)
+		req.SetBasicAuth(username, password)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	It("locks out a username after too many failed attempts", func() {
+		Expect(request("alice", "wrong").Code).To(Equal(http.StatusUnauthorized))
+		Expect(request("alice", "wrong").Code).To(Equal(http.StatusUnauthorized))
+
+		rr := request("alice", "secret")
+		Expect(rr.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(rr.Header().Get("Retry-After")).To(Equal("60"))
+	})
+
+	It("forgets the failed attempts of a username after a successful attempt", func() {
+		Expect(request("bob", "wrong").Code).To(Equal(http.StatusUnauthorized))
+		Expect(request("bob", "secret").Code).To(Equal(http.StatusOK))
+	})
+
+	It("passes requests without credentials", func() {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", http.NoBody)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("ignores the forwarded addresses of untrusted peers", func() {
+		for i, user := range []string{"carol", "dave"} {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/dav/files", http.NoBody)
+			req.SetBasicAuth(user, "wrong")
+			req.Header.Set("X-Forwarded-For", fmt.Sprintf("192.0.2.%d", i))
+			rr := httptest.NewRecorder()
+			RealIP(nil)(handler).ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/dav/files", http.NoBody)
+		req.SetBasicAuth("erin", "secret")
+		req.Header.Set("X-Forwarded-For", "192.0.2.99")
+		rr := httptest.NewRecorder()
+		RealIP(nil)(handler).ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusTooManyRequests))
+	})
+
+	It("does nothing without a limiter", func() {
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		Expect(RateLimit(Logger(log.NopLogger()))(next)).To(BeAssignableToTypeOf(next))
+	})
+})
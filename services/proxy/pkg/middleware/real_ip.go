@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+var (
+	_xForwardedFor = http.CanonicalHeaderKey("X-Forwarded-For")
+	_xRealIP       = http.CanonicalHeaderKey("X-Real-IP")
+)
+
+// RealIP replaces the remote address of requests from trusted reverse proxies with the client
+// address forwarded in the X-Forwarded-For or X-Real-IP header. The forwarded addresses of
+// requests from other peers are ignored, so that clients can't spoof their address.
+func RealIP(trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if containsIP(trustedProxies, hostOf(r.RemoteAddr)) {
+				if ip := forwardedIP(r, trustedProxies); ip != "" {
+					r.RemoteAddr = ip
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedIP returns the client address forwarded by trusted reverse proxies. The X-Forwarded-For
+// header is read from right to left, the first address not belonging to a trusted proxy is the
+// client.
+func forwardedIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	if xff := r.Header.Values(_xForwardedFor); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		client := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			client = hop
+			if !containsIP(trustedProxies, hop) {
+				break
+			}
+		}
+		if client != "" {
+			return client
+		}
+	}
+
+	if xrip := strings.TrimSpace(r.Header.Get(_xRealIP)); net.ParseIP(xrip) != nil {
+		return xrip
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resolving the client address", Label("RealIP"), func() {
+	remoteAddr := func(peer string, headers map[string]string) string {
+		trusted, err := ParseNetworks([]string{"10.0.0.0/8", "192.168.1.1"})
+		Expect(err).ToNot(HaveOccurred())
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", http.NoBody)
+		req.RemoteAddr = peer
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		var addr string
+		RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr = r.RemoteAddr
+		})).ServeHTTP(httptest.NewRecorder(), req)
+		return addr
+	}
+
+	It("uses the forwarded address of trusted proxies", func() {
+		Expect(remoteAddr("10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"})).To(Equal("203.0.113.7"))
+		Expect(remoteAddr("192.168.1.1:1234", map[string]string{"X-Real-IP": "203.0.113.7"})).To(Equal("203.0.113.7"))
+	})
+
+	It("skips trusted proxies in the chain of forwarded addresses", func() {
+		Expect(remoteAddr("10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7, 10.0.0.2"})).To(Equal("203.0.113.7"))
+	})
+
+	It("ignores the forwarded address of other peers", func() {
+		Expect(remoteAddr("198.51.100.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.5", "X-Real-IP": "10.0.0.5"})).To(Equal("198.51.100.1:1234"))
+	})
+
+	It("ignores invalid forwarded addresses", func() {
+		Expect(remoteAddr("10.0.0.1:1234", map[string]string{"X-Forwarded-For": "not-an-ip"})).To(Equal("10.0.0.1:1234"))
+	})
+})
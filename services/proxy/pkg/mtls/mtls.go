@@ -0,0 +1,350 @@
+// Package mtls verifies X.509 client certificates and extracts the identity of their users.
+package mtls
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/config"
+)
+
+var (
+	// ErrRevoked is returned when a client certificate was revoked.
+	ErrRevoked = errors.New("the client certificate was revoked")
+	// ErrNoIdentity is returned when a client certificate does not contain the configured identity field.
+	ErrNoIdentity = errors.New("the client certificate does not contain the identity field")
+)
+
+// _crlCheckInterval is the minimum time between two checks whether the CRL files changed
+const _crlCheckInterval = time.Minute
+
+// Verifier verifies client certificates against the configured CAs and revocation lists.
+type Verifier struct {
+	roots *x509.CertPool
+	field string
+	oid   asn1.ObjectIdentifier
+
+	crlFiles   []string
+	mu         sync.Mutex
+	crls       []*x509.RevocationList
+	crlModTime map[string]time.Time
+	crlChecked time.Time
+	now        func() time.Time
+}
+
+// NewVerifier loads the CA bundles and revocation lists of the configuration.
+func NewVerifier(cfg config.MTLS) (*Verifier, error) {
+	roots := x509.NewCertPool()
+	for _, f := range cfg.CACerts {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle: %w", err)
+		}
+		if !roots.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", f)
+		}
+	}
+
+	v := &Verifier{
+		roots:      roots,
+		field:      cfg.IdentityField,
+		crlFiles:   cfg.CRLs,
+		crlModTime: map[string]time.Time{},
+		now:        time.Now,
+	}
+	if cfg.IdentityField == config.MTLSIdentityOID {
+		oid, err := ParseOID(cfg.IdentityOID)
+		if err != nil {
+			return nil, err
+		}
+		v.oid = oid
+	}
+	if err := v.reloadCRLs(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ClientCAs returns the pool of CAs client certificates must be issued by.
+func (v *Verifier) ClientCAs() *x509.CertPool {
+	return v.roots
+}
+
+// Verify verifies a client certificate followed by its intermediate certificates and returns the
+// client certificate.
+func (v *Verifier) Verify(chain []*x509.Certificate) (*x509.Certificate, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("no client certificate")
+	}
+	leaf := chain[0]
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+
+	verified, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   v.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	crls := v.revocationLists()
+	for _, c := range verified {
+		if isRevoked(c, crls) {
+			return nil, ErrRevoked
+		}
+	}
+	return leaf, nil
+}
+
+// Identity returns the value of the configured identity field of a client certificate.
+func (v *Verifier) Identity(cert *x509.Certificate) (string, error) {
+	var id string
+	switch v.field {
+	case config.MTLSIdentitySubject:
+		id = cert.Subject.String()
+	case config.MTLSIdentitySubjectCN:
+		id = cert.Subject.CommonName
+	case config.MTLSIdentitySANEmail:
+		if len(cert.EmailAddresses) > 0 {
+			id = cert.EmailAddresses[0]
+		}
+	case config.MTLSIdentityOID:
+		id = oidValue(cert, v.oid)
+	}
+	if id == "" {
+		return "", ErrNoIdentity
+	}
+	return id, nil
+}
+
+// revocationLists returns the revocation lists and reloads them when the files changed.
+func (v *Verifier) revocationLists() []*x509.RevocationList {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.crlFiles) == 0 || v.now().Sub(v.crlChecked) < _crlCheckInterval {
+		return v.crls
+	}
+	// keep the previous lists when the new ones can't be loaded, the error surfaced on startup
+	_ = v.reloadCRLsLocked()
+	return v.crls
+}
+
+func (v *Verifier) reloadCRLs() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.reloadCRLsLocked()
+}
+
+func (v *Verifier) reloadCRLsLocked() error {
+	v.crlChecked = v.now()
+
+	changed := v.crls == nil
+	modTimes := make(map[string]time.Time, len(v.crlFiles))
+	for _, f := range v.crlFiles {
+		info, err := os.Stat(f)
+		if err != nil {
+			return fmt.Errorf("could not read revocation list: %w", err)
+		}
+		modTimes[f] = info.ModTime()
+		if !info.ModTime().Equal(v.crlModTime[f]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	crls := []*x509.RevocationList{}
+	for _, f := range v.crlFiles {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("could not read revocation list: %w", err)
+		}
+		list, err := parseCRLs(b)
+		if err != nil {
+			return fmt.Errorf("could not parse revocation list %s: %w", f, err)
+		}
+		crls = append(crls, list...)
+	}
+	v.crls = crls
+	v.crlModTime = modTimes
+	return nil
+}
+
+// parseCRLs parses PEM encoded revocation lists and falls back to a single DER encoded one.
+func parseCRLs(b []byte) ([]*x509.RevocationList, error) {
+	var crls []*x509.RevocationList
+	rest := b
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "X509 CRL" {
+			continue
+		}
+		crl, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		crls = append(crls, crl)
+	}
+	if crls != nil {
+		return crls, nil
+	}
+
+	crl, err := x509.ParseRevocationList(b)
+	if err != nil {
+		return nil, err
+	}
+	return []*x509.RevocationList{crl}, nil
+}
+
+// isRevoked checks whether one of the certificates of a verified chain was revoked by its issuer.
+func isRevoked(chain []*x509.Certificate, crls []*x509.RevocationList) bool {
+	for i := 0; i < len(chain)-1; i++ {
+		cert, issuer := chain[i], chain[i+1]
+		for _, crl := range crls {
+			if crl.CheckSignatureFrom(issuer) != nil {
+				continue
+			}
+			for _, entry := range crl.RevokedCertificateEntries {
+				if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// oidValue returns the value of the subject attribute or the certificate extension with the given
+// object identifier.
+func oidValue(cert *x509.Certificate, oid asn1.ObjectIdentifier) string {
+	for _, name := range cert.Subject.Names {
+		if name.Type.Equal(oid) {
+			if s, ok := name.Value.(string); ok {
+				return s
+			}
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oid) {
+			var s string
+			if _, err := asn1.Unmarshal(ext.Value, &s); err == nil {
+				return s
+			}
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// ParseOID parses an object identifier in dotted notation.
+func ParseOID(s string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid object identifier '%s'", s)
+	}
+	oid := make(asn1.ObjectIdentifier, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid object identifier '%s'", s)
+		}
+		oid = append(oid, n)
+	}
+	return oid, nil
+}
+
+// ParseHeader parses the client certificate chain passed in a header by a reverse proxy. The
+// certificates can be URL encoded PEM, like the $ssl_client_escaped_cert of nginx, PEM with the
+// line breaks replaced by spaces or base64 encoded DER.
+func ParseHeader(value string) ([]*x509.Certificate, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "%") {
+		// path unescaping keeps the '+' of base64 encoded content
+		unescaped, err := url.PathUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode client certificate: %w", err)
+		}
+		value = unescaped
+	}
+
+	if !strings.Contains(value, "-----BEGIN") {
+		der, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode client certificate: %w", err)
+		}
+		return x509.ParseCertificates(der)
+	}
+
+	var chain []*x509.Certificate
+	rest := []byte(restoreLineBreaks(value))
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no client certificate found in header")
+	}
+	return chain, nil
+}
+
+// restoreLineBreaks restores the line breaks of PEM encoded certificates some reverse proxies
+// replace with spaces.
+func restoreLineBreaks(s string) string {
+	if strings.Contains(s, "\n") {
+		return s
+	}
+	var b strings.Builder
+	for s != "" {
+		start := strings.Index(s, "-----BEGIN ")
+		if start < 0 {
+			break
+		}
+		headerEnd := strings.Index(s[start+11:], "-----")
+		if headerEnd < 0 {
+			break
+		}
+		header := s[start : start+11+headerEnd+5]
+		label := strings.TrimSuffix(strings.TrimPrefix(header, "-----BEGIN "), "-----")
+		footer := "-----END " + label + "-----"
+		end := strings.Index(s, footer)
+		if end < 0 {
+			break
+		}
+		body := strings.Join(strings.Fields(s[start+len(header):end]), "\n")
+		b.WriteString(header + "\n" + body + "\n" + footer + "\n")
+		s = s[end+len(footer):]
+	}
+	return b.String()
+}
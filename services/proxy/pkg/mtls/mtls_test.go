@@ -0,0 +1,166 @@
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _uidOID = asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 1}
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return testCA{cert: cert, key: key}
+}
+
+func (ca testCA) issue(t *testing.T, serial int64, cn string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject: pkix.Name{
+			CommonName: cn,
+			ExtraNames: []pkix.AttributeTypeAndValue{{Type: _uidOID, Value: "uid-" + cn}},
+		},
+		EmailAddresses: []string{cn + "@example.org"},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func (ca testCA) writeFiles(t *testing.T, revoked ...int64) (string, string) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600))
+
+	entries := make([]x509.RevocationListEntry, 0, len(revoked))
+	for _, s := range revoked {
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: big.NewInt(s), RevocationTime: time.Now()})
+	}
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now(),
+		NextUpdate:                time.Now().Add(time.Hour),
+		RevokedCertificateEntries: entries,
+	}, ca.cert, ca.key)
+	require.NoError(t, err)
+	crlFile := filepath.Join(dir, "ca.crl")
+	require.NoError(t, os.WriteFile(crlFile, crl, 0600))
+	return caFile, crlFile
+}
+
+func TestVerify(t *testing.T) {
+	ca := newTestCA(t)
+	caFile, crlFile := ca.writeFiles(t, 3)
+
+	v, err := NewVerifier(config.MTLS{CACerts: []string{caFile}, CRLs: []string{crlFile}, IdentityField: config.MTLSIdentitySubjectCN})
+	require.NoError(t, err)
+
+	leaf, err := v.Verify([]*x509.Certificate{ca.issue(t, 2, "alice")})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", leaf.Subject.CommonName)
+
+	_, err = v.Verify([]*x509.Certificate{ca.issue(t, 3, "mallory")})
+	assert.ErrorIs(t, err, ErrRevoked)
+
+	_, err = v.Verify([]*x509.Certificate{newTestCA(t).issue(t, 2, "eve")})
+	assert.Error(t, err, "certificates of other CAs are rejected")
+
+	_, err = v.Verify(nil)
+	assert.Error(t, err)
+}
+
+func TestIdentity(t *testing.T) {
+	ca := newTestCA(t)
+	caFile, _ := ca.writeFiles(t)
+	cert := ca.issue(t, 2, "alice")
+
+	tests := []struct {
+		field string
+		oid   string
+		want  string
+	}{
+		{field: config.MTLSIdentitySubjectCN, want: "alice"},
+		{field: config.MTLSIdentitySANEmail, want: "alice@example.org"},
+		{field: config.MTLSIdentityOID, oid: "0.9.2342.19200300.100.1.1", want: "uid-alice"},
+		{field: config.MTLSIdentitySubject, want: "CN=alice,0.9.2342.19200300.100.1.1=uid-alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			v, err := NewVerifier(config.MTLS{CACerts: []string{caFile}, IdentityField: tt.field, IdentityOID: tt.oid})
+			require.NoError(t, err)
+			id, err := v.Identity(cert)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, id)
+		})
+	}
+
+	v, err := NewVerifier(config.MTLS{CACerts: []string{caFile}, IdentityField: config.MTLSIdentityOID, IdentityOID: "1.2.3.4"})
+	require.NoError(t, err)
+	_, err = v.Identity(cert)
+	assert.ErrorIs(t, err, ErrNoIdentity)
+}
+
+func TestParseHeader(t *testing.T) {
+	cert := newTestCA(t).issue(t, 2, "alice")
+	pemCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+
+	for name, value := range map[string]string{
+		"url encoded pem":  url.PathEscape(pemCert),
+		"pem with spaces":  strings.ReplaceAll(strings.TrimSpace(pemCert), "\n", " "),
+		"base64 der":       base64.StdEncoding.EncodeToString(cert.Raw),
+		"pem with newline": pemCert,
+	} {
+		t.Run(name, func(t *testing.T) {
+			chain, err := ParseHeader(value)
+			require.NoError(t, err)
+			require.Len(t, chain, 1)
+			assert.True(t, chain[0].Equal(cert))
+		})
+	}
+
+	_, err := ParseHeader("not a certificate")
+	assert.Error(t, err)
+}
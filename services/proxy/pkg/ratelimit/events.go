@@ -0,0 +1,24 @@
+package ratelimit
+
+import (
+	"encoding/json"
+
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+// LockoutStarted is emitted when a client IP address, a username or a public link got locked out
+// after too many failed authentication attempts.
+type LockoutStarted struct {
+	Kind        string
+	Subject     string
+	RemoteAddr  string
+	LockedUntil *types.Timestamp
+	Timestamp   *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (LockoutStarted) Unmarshal(v []byte) (interface{}, error) {
+	e := LockoutStarted{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
@@ -0,0 +1,244 @@
+// Package ratelimit implements token buckets limiting the requests and the failed authentication
+// attempts per client IP address, username and public link.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/config"
+)
+
+// The kinds of subjects the failed authentication attempts are counted for
+const (
+	KindIP   = "ip"
+	KindUser = "user"
+	KindLink = "link"
+)
+
+const (
+	_requestPrefix = "request."
+	_failurePrefix = "failure."
+
+	// _updateAttempts is the number of times an update of a bucket is retried when it was changed concurrently
+	_updateAttempts = 5
+)
+
+// Key identifies a subject the limits apply to.
+type Key struct {
+	Kind    string
+	Subject string
+}
+
+// IP returns the key of a client IP address.
+func IP(addr string) Key { return Key{Kind: KindIP, Subject: addr} }
+
+// User returns the key of a username.
+func User(name string) Key { return Key{Kind: KindUser, Subject: name} }
+
+// Link returns the key of a public link token.
+func Link(token string) Key { return Key{Kind: KindLink, Subject: token} }
+
+// String returns the key as it is stored. The subject is hashed to neither leak usernames nor
+// public link tokens into the store.
+func (k Key) String() string {
+	sum := sha256.Sum256([]byte(k.Subject))
+	return k.Kind + "." + hex.EncodeToString(sum[:])
+}
+
+// bucket is the stored state of a token bucket.
+type bucket struct {
+	Tokens      float64   `json:"tokens"`
+	Updated     time.Time `json:"updated"`
+	LockedUntil time.Time `json:"locked_until,omitempty"`
+}
+
+// Limiter holds the token buckets in a Store, so that the limits apply across all proxies sharing
+// the store. Buckets are updated optimistically, no lock is held while reading or writing them.
+type Limiter struct {
+	store Store
+	cfg   config.RateLimit
+	now   func() time.Time
+}
+
+// NewLimiter returns a new Limiter.
+func NewLimiter(store Store, cfg config.RateLimit) *Limiter {
+	return &Limiter{
+		store: store,
+		cfg:   cfg,
+		now:   time.Now,
+	}
+}
+
+// StoreTTL returns the time after which a bucket is full again and its record can be dropped.
+func StoreTTL(cfg config.RateLimit) time.Duration {
+	ttl := cfg.LockoutDuration
+	if refill := time.Duration(cfg.FailedAttempts) * cfg.FailedAttemptsRefill; refill > ttl {
+		ttl = refill
+	}
+	if cfg.RequestsPerSecond > 0 {
+		if refill := time.Duration(float64(cfg.RequestBurst) / cfg.RequestsPerSecond * float64(time.Second)); refill > ttl {
+			ttl = refill
+		}
+	}
+	return ttl
+}
+
+// AllowRequest takes a token from the request bucket of the client IP address. If the bucket is
+// empty it returns false and the time after which the next request is allowed. Requests are allowed
+// when the store can not be reached.
+func (l *Limiter) AllowRequest(ctx context.Context, addr string) (time.Duration, bool) {
+	if l.cfg.RequestsPerSecond <= 0 || addr == "" {
+		return 0, true
+	}
+
+	capacity := float64(max(l.cfg.RequestBurst, 1))
+	var retryAfter time.Duration
+	err := l.update(ctx, _requestPrefix+IP(addr).String(), capacity, func(b *bucket, now time.Time) bool {
+		b.Tokens = math.Min(capacity, b.Tokens+now.Sub(b.Updated).Seconds()*l.cfg.RequestsPerSecond)
+		b.Updated = now
+		if b.Tokens < 1 {
+			retryAfter = time.Duration((1 - b.Tokens) / l.cfg.RequestsPerSecond * float64(time.Second))
+			return false
+		}
+		retryAfter = 0
+		b.Tokens--
+		return true
+	})
+	switch {
+	case errors.Is(err, ErrConflict):
+		// the concurrent requests of the address took the tokens
+		return time.Duration(float64(time.Second) / l.cfg.RequestsPerSecond), false
+	case err != nil:
+		return 0, true
+	}
+	return retryAfter, retryAfter == 0
+}
+
+// Check returns the delay to apply to an authentication attempt of the given keys. If one of the
+// keys is locked out, it returns true and the time until the lockout ends.
+func (l *Limiter) Check(ctx context.Context, keys ...Key) (delay time.Duration, retryAfter time.Duration, locked bool) {
+	capacity := float64(l.cfg.FailedAttempts)
+	for _, k := range keys {
+		now := l.now()
+		b, _, err := l.get(ctx, _failurePrefix+k.String(), capacity, now)
+		if err != nil {
+			continue
+		}
+		b = l.refillFailures(b, now)
+		if b.LockedUntil.After(now) {
+			locked = true
+			retryAfter = max(retryAfter, b.LockedUntil.Sub(now))
+			continue
+		}
+		failed := capacity - b.Tokens
+		delay = max(delay, time.Duration(failed*float64(l.cfg.ProgressiveDelay)))
+	}
+	return min(delay, l.cfg.MaxDelay), retryAfter, locked
+}
+
+// Lockout is a lockout of a key after too many failed authentication attempts.
+type Lockout struct {
+	Key   Key
+	Until time.Time
+}
+
+// Fail records a failed authentication attempt for the given keys. It returns the lockouts started
+// by this attempt.
+func (l *Limiter) Fail(ctx context.Context, keys ...Key) []Lockout {
+	capacity := float64(l.cfg.FailedAttempts)
+	var locked []Lockout
+	for _, k := range keys {
+		var lockout *Lockout
+		err := l.update(ctx, _failurePrefix+k.String(), capacity, func(b *bucket, now time.Time) bool {
+			lockout = nil
+			*b = l.refillFailures(*b, now)
+			if b.LockedUntil.After(now) {
+				return false
+			}
+			b.Tokens--
+			if b.Tokens < 1 {
+				b.LockedUntil = now.Add(l.cfg.LockoutDuration)
+				b.Tokens = 0
+				lockout = &Lockout{Key: k, Until: b.LockedUntil}
+			}
+			return true
+		})
+		if err == nil && lockout != nil {
+			locked = append(locked, *lockout)
+		}
+	}
+	return locked
+}
+
+// Succeed forgets the failed authentication attempts of the given usernames and public links.
+// Failures of client IP addresses are kept, so that an attacker can not reset the counter by
+// logging in with an own account in between.
+func (l *Limiter) Succeed(ctx context.Context, keys ...Key) {
+	for _, k := range keys {
+		if k.Kind == KindIP {
+			continue
+		}
+		_ = l.store.Delete(ctx, _failurePrefix+k.String())
+	}
+}
+
+// refillFailures returns the refilled failure bucket.
+func (l *Limiter) refillFailures(b bucket, now time.Time) bucket {
+	capacity := float64(l.cfg.FailedAttempts)
+	if b.LockedUntil.After(now) {
+		return b
+	}
+	if !b.LockedUntil.IsZero() {
+		// the lockout ended, start over with a full bucket
+		return bucket{Tokens: capacity, Updated: now}
+	}
+	if l.cfg.FailedAttemptsRefill > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+float64(now.Sub(b.Updated))/float64(l.cfg.FailedAttemptsRefill))
+	}
+	b.Updated = now
+	return b
+}
+
+// get returns the bucket of a key and its revision, missing buckets are full.
+func (l *Limiter) get(ctx context.Context, key string, capacity float64, now time.Time) (bucket, uint64, error) {
+	full := bucket{Tokens: capacity, Updated: now}
+	v, revision, err := l.store.Get(ctx, key)
+	if err != nil || revision == 0 {
+		return full, revision, err
+	}
+	var b bucket
+	if err := json.Unmarshal(v, &b); err != nil {
+		// overwrite the broken bucket
+		return full, revision, nil
+	}
+	return b, revision, nil
+}
+
+// update applies fn to the bucket of a key and writes it back unless it was changed in the meantime,
+// in which case the update is retried. fn returns false to leave the bucket as it is.
+func (l *Limiter) update(ctx context.Context, key string, capacity float64, fn func(b *bucket, now time.Time) bool) error {
+	for i := 0; i < _updateAttempts; i++ {
+		now := l.now()
+		b, revision, err := l.get(ctx, key, capacity, now)
+		if err != nil {
+			return err
+		}
+		if !fn(&b, now) {
+			return nil
+		}
+		v, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		if err := l.store.Update(ctx, key, v, revision); !errors.Is(err, ErrConflict) {
+			return err
+		}
+	}
+	return ErrConflict
+}
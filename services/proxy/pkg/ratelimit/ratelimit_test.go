@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opencloud-eu/opencloud/services/proxy/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLimiter(now *time.Time) *Limiter {
+	l := NewLimiter(NewMemoryStore(0), config.RateLimit{
+		RequestsPerSecond:    1,
+		RequestBurst:         2,
+		FailedAttempts:       3,
+		FailedAttemptsRefill: time.Minute,
+		ProgressiveDelay:     100 * time.Millisecond,
+		MaxDelay:             150 * time.Millisecond,
+		LockoutDuration:      10 * time.Minute,
+	})
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestAllowRequest(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(&now)
+
+	_, ok := l.AllowRequest(ctx, "10.0.0.1")
+	assert.True(t, ok)
+	_, ok = l.AllowRequest(ctx, "10.0.0.1")
+	assert.True(t, ok)
+	retryAfter, ok := l.AllowRequest(ctx, "10.0.0.1")
+	assert.False(t, ok, "the burst is used up")
+	assert.Equal(t, time.Second, retryAfter)
+
+	_, ok = l.AllowRequest(ctx, "10.0.0.2")
+	assert.True(t, ok, "other addresses have their own bucket")
+
+	now = now.Add(time.Second)
+	_, ok = l.AllowRequest(ctx, "10.0.0.1")
+	assert.True(t, ok, "the bucket refills over time")
+}
+
+func TestFailedAttempts(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(&now)
+	keys := []Key{User("alice"), IP("10.0.0.1")}
+
+	delay, _, locked := l.Check(ctx, keys...)
+	assert.False(t, locked)
+	assert.Zero(t, delay)
+
+	assert.Empty(t, l.Fail(ctx, keys...))
+	delay, _, _ = l.Check(ctx, keys...)
+	assert.Equal(t, 100*time.Millisecond, delay)
+
+	assert.Empty(t, l.Fail(ctx, keys...))
+	delay, _, _ = l.Check(ctx, keys...)
+	assert.Equal(t, 150*time.Millisecond, delay, "the delay is capped")
+
+	lockouts := l.Fail(ctx, keys...)
+	assert.Len(t, lockouts, 2)
+	assert.Equal(t, now.Add(10*time.Minute), lockouts[0].Until)
+
+	_, retryAfter, locked := l.Check(ctx, User("alice"))
+	assert.True(t, locked)
+	assert.Equal(t, 10*time.Minute, retryAfter)
+	_, _, locked = l.Check(ctx, User("bob"))
+	assert.False(t, locked)
+
+	now = now.Add(10 * time.Minute)
+	delay, _, locked = l.Check(ctx, keys...)
+	assert.False(t, locked, "the lockout ended")
+	assert.Zero(t, delay)
+}
+
+func TestFailedAttemptsRefill(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(&now)
+
+	l.Fail(ctx, User("alice"))
+	l.Fail(ctx, User("alice"))
+	now = now.Add(2 * time.Minute)
+	assert.Empty(t, l.Fail(ctx, User("alice")), "failed attempts are forgotten over time")
+}
+
+func TestSucceed(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(&now)
+	keys := []Key{Link("token"), IP("10.0.0.1")}
+
+	l.Fail(ctx, keys...)
+	l.Fail(ctx, keys...)
+	l.Succeed(ctx, keys...)
+
+	assert.Len(t, l.Fail(ctx, keys...), 1, "only the failed attempts of the address are kept")
+}
+
+func TestKeyString(t *testing.T) {
+	k := Link("secret-token")
+	assert.Regexp(t, `^link\.[0-9a-f]{64}$`, k.String())
+	assert.NotContains(t, k.String(), "secret-token")
+}
+
+func TestAllowRequestConcurrently(t *testing.T) {
+	l := NewLimiter(NewMemoryStore(0), config.RateLimit{RequestsPerSecond: 0.001, RequestBurst: 10})
+
+	var (
+		wg      sync.WaitGroup
+		allowed atomic.Int32
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := l.AllowRequest(context.Background(), "10.0.0.1"); ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(10), allowed.Load(), "concurrent requests never take more tokens than the burst")
+}
+
+func TestMemoryStoreUpdate(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(0)
+
+	assert.NoError(t, s.Update(ctx, "key", []byte("a"), 0))
+	assert.ErrorIs(t, s.Update(ctx, "key", []byte("b"), 0), ErrConflict, "the key exists already")
+
+	v, revision, err := s.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(v))
+	assert.NoError(t, s.Update(ctx, "key", []byte("b"), revision))
+	assert.ErrorIs(t, s.Update(ctx, "key", []byte("c"), revision), ErrConflict, "the revision is outdated")
+
+	assert.NoError(t, s.Delete(ctx, "key"))
+	_, revision, err = s.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.Zero(t, revision)
+}
@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// ErrConflict is returned when a bucket was changed since it was read.
+var ErrConflict = errors.New("the bucket was changed concurrently")
+
+// Store keeps the token buckets. Writes only succeed if the bucket was not changed since it was read,
+// so that concurrent requests and proxies don't overwrite each other's updates and no lock has to be
+// held while talking to the store.
+type Store interface {
+	// Get returns the value and the revision of a key. The revision is 0 when the key does not exist.
+	Get(ctx context.Context, key string) ([]byte, uint64, error)
+	// Update writes the value of a key if it still has the given revision, a revision of 0 creates the
+	// key. It returns ErrConflict otherwise.
+	Update(ctx context.Context, key string, value []byte, revision uint64) error
+	// Delete removes a key.
+	Delete(ctx context.Context, key string) error
+}
+
+type memoryEntry struct {
+	value    []byte
+	revision uint64
+	expires  time.Time
+}
+
+// memoryStore keeps the buckets of a single proxy in memory.
+type memoryStore struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	revision uint64
+	entries  map[string]memoryEntry
+	swept    time.Time
+}
+
+// NewMemoryStore returns a Store keeping the buckets in memory. Buckets are dropped after the ttl,
+// a ttl of 0 keeps them forever.
+func NewMemoryStore(ttl time.Duration) Store {
+	return &memoryStore{
+		ttl:     ttl,
+		entries: make(map[string]memoryEntry),
+		swept:   time.Now(),
+	}
+}
+
+// Get implements the Store interface.
+func (s *memoryStore) Get(_ context.Context, key string) ([]byte, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || s.expired(e, time.Now()) {
+		return nil, 0, nil
+	}
+	return e.value, e.revision, nil
+}
+
+// Update implements the Store interface.
+func (s *memoryStore) Update(_ context.Context, key string, value []byte, revision uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var current uint64
+	if e, ok := s.entries[key]; ok && !s.expired(e, now) {
+		current = e.revision
+	}
+	if current != revision {
+		return ErrConflict
+	}
+
+	s.revision++
+	e := memoryEntry{value: value, revision: s.revision}
+	if s.ttl > 0 {
+		e.expires = now.Add(s.ttl)
+	}
+	s.entries[key] = e
+	s.sweep(now)
+	return nil
+}
+
+// Delete implements the Store interface.
+func (s *memoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *memoryStore) expired(e memoryEntry, now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+// sweep drops the expired buckets once per ttl.
+func (s *memoryStore) sweep(now time.Time) {
+	if s.ttl <= 0 || now.Sub(s.swept) < s.ttl {
+		return
+	}
+	for key, e := range s.entries {
+		if s.expired(e, now) {
+			delete(s.entries, key)
+		}
+	}
+	s.swept = now
+}
+
+// natsStore keeps the buckets in a NATS key value bucket shared by all proxies.
+type natsStore struct {
+	kv jetstream.KeyValue
+}
+
+// NewNatsStore returns a Store keeping the buckets in a NATS key value bucket. The revisions of the
+// NATS keys make the updates atomic across proxies.
+func NewNatsStore(kv jetstream.KeyValue) Store {
+	return natsStore{kv: kv}
+}
+
+// Get implements the Store interface.
+func (s natsStore) Get(ctx context.Context, key string) ([]byte, uint64, error) {
+	entry, err := s.kv.Get(ctx, key)
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		return nil, 0, nil
+	case err != nil:
+		return nil, 0, err
+	}
+	return entry.Value(), entry.Revision(), nil
+}
+
+// Update implements the Store interface.
+func (s natsStore) Update(ctx context.Context, key string, value []byte, revision uint64) error {
+	var err error
+	if revision == 0 {
+		_, err = s.kv.Create(ctx, key, value)
+	} else {
+		_, err = s.kv.Update(ctx, key, value, revision)
+	}
+	if errors.Is(err, jetstream.ErrKeyExists) {
+		return ErrConflict
+	}
+	return err
+}
+
+// Delete implements the Store interface.
+func (s natsStore) Delete(ctx context.Context, key string) error {
+	if err := s.kv.Delete(ctx, key); err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
@@ -91,6 +91,8 @@ type RoutingInfo struct {
 	unprotected      bool
 	remoteUserHeader string
 	skipXAccessToken bool
+	policy           string
+	backend          string
 }
 
 // Rewrite returns the proxy rewrite hook.
@@ -114,6 +116,16 @@ func (r RoutingInfo) SkipXAccessToken() bool {
 	return r.skipXAccessToken
 }
 
+// Policy returns the name of the policy the route belongs to.
+func (r RoutingInfo) Policy() string {
+	return r.policy
+}
+
+// Backend returns the service or backend URL the route forwards to.
+func (r RoutingInfo) Backend() string {
+	return r.backend
+}
+
 // Router handles the routing of HTTP requests according to the given policies.
 type Router struct {
 	logger          log.Logger
@@ -138,11 +150,17 @@ func (rt Router) addHost(policy string, target *url.URL, route config.Route) {
 		rt.rewriters[policy][routeType][route.Method] = make([]RoutingInfo, 0)
 	}
 
+	backend := route.Service
+	if backend == "" {
+		backend = route.Backend
+	}
 	rt.rewriters[policy][routeType][route.Method] = append(rt.rewriters[policy][routeType][route.Method], RoutingInfo{
 		endpoint:         route.Endpoint,
 		unprotected:      route.Unprotected,
 		remoteUserHeader: route.RemoteUserHeader,
 		skipXAccessToken: route.SkipXAccessToken,
+		policy:           policy,
+		backend:          backend,
 		rewrite: func(req *httputil.ProxyRequest) {
 			if route.Service != "" {
 				// select next node
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"crypto/x509"
 	"net/http"
 
 	"github.com/justinas/alice"
@@ -23,6 +24,7 @@ type Options struct {
 	Metrics     *metrics.Metrics
 	Flags       []cli.Flag
 	Middlewares alice.Chain
+	ClientCAs   *x509.CertPool
 }
 
 // newOptions initializes the available default options.
@@ -84,3 +86,10 @@ func Middlewares(val alice.Chain) Option {
 		o.Middlewares = val
 	}
 }
+
+// ClientCAs provides a function to set the ClientCAs option.
+func ClientCAs(val *x509.CertPool) Option {
+	return func(o *Options) {
+		o.ClientCAs = val
+	}
+}
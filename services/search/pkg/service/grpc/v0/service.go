@@ -15,13 +15,13 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/token"
-	"github.com/opencloud-eu/reva/v2/pkg/token/manager/jwt"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
 	merrors "go-micro.dev/v4/errors"
 	"go-micro.dev/v4/metadata"
 	grpcmetadata "google.golang.org/grpc/metadata"
 
 	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	v0 "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/messages/search/v0"
 	searchsvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/search/v0"
 	"github.com/opencloud-eu/opencloud/services/search/pkg/config"
@@ -44,10 +44,9 @@ func NewHandler(opts ...Option) (searchsvc.SearchProviderHandler, error) {
 		return nil, err
 	}
 
-	tokenManager, err := jwt.New(map[string]interface{}{
-		"secret":  options.JWTSecret,
-		"expires": int64(24 * 60 * 60),
-	})
+	tokenManagerConfig := secrets.TokenManagerConfig(options.JWTSecret, options.Config.TokenManager.PreviousJWTSecrets)
+	tokenManagerConfig["expires"] = int64(24 * 60 * 60)
+	tokenManager, err := secrets.NewTokenManager(tokenManagerConfig)
 	if err != nil {
 		return nil, err
 	}
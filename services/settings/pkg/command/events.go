@@ -0,0 +1,52 @@
+package command
+
+import (
+	"context"
+
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/events/stream"
+
+	"github.com/opencloud-eu/opencloud/pkg/generators"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
+	"github.com/opencloud-eu/opencloud/services/settings/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/settings/pkg/settings"
+)
+
+var _registeredEvents = []events.Unmarshaller{
+	selfdeletion.AccountPurged{},
+}
+
+// ListenForEvents removes the settings of purged accounts
+func ListenForEvents(ctx context.Context, cfg *config.Config, handle settings.ServiceHandler, l log.Logger) error {
+	connName := generators.GenerateConnectionName(cfg.Service.Name, generators.NTypeBus)
+	bus, err := stream.NatsFromConfig(connName, false, stream.NatsConfig(cfg.Events))
+	if err != nil {
+		l.Error().Err(err).Msg("cannot connect to nats")
+		return err
+	}
+
+	evChannel, err := events.Consume(bus, "settings", _registeredEvents...)
+	if err != nil {
+		l.Error().Err(err).Msg("cannot consume from nats")
+		return err
+	}
+
+	for {
+		select {
+		case e, ok := <-evChannel:
+			if !ok {
+				return nil
+			}
+			switch ev := e.Event.(type) {
+			case selfdeletion.AccountPurged:
+				if err := handle.RemoveAccountData(ev.UserID.GetOpaqueId()); err != nil {
+					l.Error().Err(err).Str("userid", ev.UserID.GetOpaqueId()).Msg("could not remove the settings of a purged account")
+				}
+			}
+		case <-ctx.Done():
+			l.Info().Msg("context cancelled")
+			return nil
+		}
+	}
+}
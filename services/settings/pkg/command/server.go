@@ -100,6 +100,15 @@ func Server(cfg *config.Config) *cli.Command {
 
 			gr.Add(runner.NewGolangHttpServerRunner(cfg.Service.Name+".debug", debugServer))
 
+			// add event handler
+			gr.Add(runner.New(cfg.Service.Name+".event",
+				func() error {
+					return ListenForEvents(ctx, cfg, handle, logger)
+				}, func() {
+					logger.Info().Msg("stopping event handler")
+				},
+			))
+
 			grResults := gr.Run(ctx)
 
 			// return the first non-nil error found in the results
@@ -54,7 +54,8 @@ func Server(opts ...Option) (ohttp.Service, error) {
 	))
 	mux.Use(middleware.ExtractAccountUUID(
 		account.Logger(options.Logger),
-		account.JWTSecret(options.Config.TokenManager.JWTSecret)),
+		account.JWTSecret(options.Config.TokenManager.JWTSecret),
+		account.PreviousJWTSecrets(options.Config.TokenManager.PreviousJWTSecrets)),
 	)
 
 	mux.Use(middleware.Version(
@@ -718,3 +718,35 @@ func translateBundle(bundle *settingsmsg.Bundle, t *gotext.Locale) *settingsmsg.
 	}
 	return bundle
 }
+
+// RemoveAccountData removes the values and role assignments of a deleted account
+func (g Service) RemoveAccountData(accountUUID string) error {
+	if accountUUID == "" {
+		return errors.New("account uuid can not be empty")
+	}
+
+	values, err := g.manager.ListValues("", accountUUID)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		// values without an account belong to everyone
+		if v.GetAccountUuid() != accountUUID {
+			continue
+		}
+		if err := g.manager.DeleteValue(v.GetId()); err != nil && !errors.Is(err, settings.ErrNotFound) {
+			return err
+		}
+	}
+
+	assignments, err := g.manager.ListRoleAssignments(accountUUID)
+	if err != nil {
+		return err
+	}
+	for _, a := range assignments {
+		if err := g.manager.RemoveRoleAssignment(a.GetId()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -104,6 +104,57 @@ func (_c *Manager_AddSettingToBundle_Call) RunAndReturn(run func(bundleID string
 	return _c
 }
 
+// DeleteValue provides a mock function for the type Manager
+func (_mock *Manager) DeleteValue(valueID string) error {
+	ret := _mock.Called(valueID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteValue")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string) error); ok {
+		r0 = returnFunc(valueID)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// Manager_DeleteValue_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteValue'
+type Manager_DeleteValue_Call struct {
+	*mock.Call
+}
+
+// DeleteValue is a helper method to define mock.On call
+//   - valueID string
+func (_e *Manager_Expecter) DeleteValue(valueID interface{}) *Manager_DeleteValue_Call {
+	return &Manager_DeleteValue_Call{Call: _e.mock.On("DeleteValue", valueID)}
+}
+
+func (_c *Manager_DeleteValue_Call) Run(run func(valueID string)) *Manager_DeleteValue_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *Manager_DeleteValue_Call) Return(err error) *Manager_DeleteValue_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *Manager_DeleteValue_Call) RunAndReturn(run func(valueID string) error) *Manager_DeleteValue_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // ListBundles provides a mock function for the type Manager
 func (_mock *Manager) ListBundles(bundleType v0.Bundle_Type, bundleIDs []string) ([]*v0.Bundle, error) {
 	ret := _mock.Called(bundleType, bundleIDs)
@@ -28,6 +28,9 @@ type ServiceHandler interface {
 	settingssvc.RoleServiceHandler
 	settingssvc.PermissionServiceHandler
 	cs3permissions.PermissionsAPIServer
+
+	// RemoveAccountData removes the values and role assignments of a deleted account
+	RemoveAccountData(accountUUID string) error
 }
 
 // Manager combines service interfaces for abstraction of storage implementations
@@ -54,6 +57,7 @@ type ValueManager interface {
 	ReadValue(valueID string) (*settingsmsg.Value, error)
 	ReadValueByUniqueIdentifiers(accountUUID, settingID string) (*settingsmsg.Value, error)
 	WriteValue(value *settingsmsg.Value) (*settingsmsg.Value, error)
+	DeleteValue(valueID string) error
 }
 
 // RoleAssignmentManager is a role assignment service interface for abstraction of storage implementations
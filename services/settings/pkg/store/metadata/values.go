@@ -136,6 +136,22 @@ func (s *Store) WriteValue(value *settingsmsg.Value) (*settingsmsg.Value, error)
 	return value, s.mdc.SimpleUpload(ctx, valuePath(value.Id), b)
 }
 
+// DeleteValue removes the value with the given valueId
+func (s *Store) DeleteValue(valueID string) error {
+	s.Init()
+	ctx := context.TODO()
+
+	err := s.mdc.Delete(ctx, valuePath(valueID))
+	switch err.(type) {
+	case nil:
+		return nil
+	case errtypes.NotFound:
+		return fmt.Errorf("valueID '%s' %w", valueID, settings.ErrNotFound)
+	default:
+		return err
+	}
+}
+
 func valuePath(id string) string {
 	return fmt.Sprintf("%s/%s", valuesFolderLocation, id)
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	settingsmsg "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/messages/settings/v0"
+	"github.com/opencloud-eu/opencloud/services/settings/pkg/settings"
 	"github.com/stretchr/testify/require"
 )
 
@@ -102,3 +103,21 @@ func TestListValues(t *testing.T) {
 	require.Len(t, vs, 1)
 
 }
+
+func TestDeleteValue(t *testing.T) {
+	s := initStore()
+	setupRoles(s)
+	for _, v := range valueScenarios {
+		_, err := s.WriteValue(v.value)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, s.DeleteValue(value1))
+
+	_, err := s.ReadValue(value1)
+	require.ErrorIs(t, err, settings.ErrNotFound)
+
+	vs, err := s.ListValues("", accountUUID1)
+	require.NoError(t, err)
+	require.Len(t, vs, 2)
+}
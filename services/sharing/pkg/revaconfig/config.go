@@ -9,6 +9,7 @@ import (
 	"github.com/opencloud-eu/opencloud/pkg/config/defaults"
 	"github.com/opencloud-eu/opencloud/pkg/generators"
 	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/sharing/pkg/config"
 )
 
@@ -129,6 +130,9 @@ func SharingConfigFromStruct(cfg *config.Config, logger log.Logger) (map[string]
 				},
 			},
 			"interceptors": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"eventsmiddleware": map[string]interface{}{
 					"group":            "sharing",
 					"type":             "nats",
@@ -1,6 +1,7 @@
 package revaconfig
 
 import (
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/storage-publiclink/pkg/config"
 )
 
@@ -23,6 +24,9 @@ func StoragePublicLinkConfigFromStruct(cfg *config.Config) map[string]interface{
 				"key":         cfg.GRPC.TLS.Key,
 			},
 			"interceptors": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"log": map[string]interface{}{},
 				"prometheus": map[string]interface{}{
 					"namespace": "opencloud",
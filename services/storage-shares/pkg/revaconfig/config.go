@@ -2,6 +2,7 @@
 package revaconfig
 
 import (
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/storage-shares/pkg/config"
 )
 
@@ -30,6 +31,9 @@ func StorageSharesConfigFromStruct(cfg *config.Config) map[string]interface{} {
 				},
 			},
 			"interceptors": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"prometheus": map[string]interface{}{
 					"namespace": "opencloud",
 					"subsystem": "storage_shares",
@@ -40,6 +44,9 @@ func StorageSharesConfigFromStruct(cfg *config.Config) map[string]interface{} {
 	if cfg.ReadOnly {
 		gcfg := rcfg["grpc"].(map[string]interface{})
 		gcfg["interceptors"] = map[string]interface{}{
+			"auth": map[string]interface{}{
+				"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+			},
 			"readonly": map[string]interface{}{},
 		}
 	}
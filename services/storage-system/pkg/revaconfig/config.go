@@ -3,6 +3,7 @@ package revaconfig
 import (
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	pkgconfig "github.com/opencloud-eu/opencloud/pkg/config"
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/storage-system/pkg/config"
 )
 
@@ -103,6 +104,9 @@ func StorageSystemFromStruct(cfg *config.Config) map[string]interface{} {
 				},
 			},
 			"interceptors": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"prometheus": map[string]interface{}{
 					"namespace": "opencloud",
 					"subsystem": "storage_system",
@@ -138,6 +142,9 @@ func StorageSystemFromStruct(cfg *config.Config) map[string]interface{} {
 				},
 			},
 			"middlewares": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"prometheus": map[string]interface{}{
 					"namespace": "opencloud",
 					"subsystem": "storage_system",
@@ -0,0 +1,51 @@
+package command
+
+import (
+	"time"
+
+	"github.com/opencloud-eu/opencloud/pkg/config/configlog"
+	"github.com/opencloud-eu/opencloud/services/storage-users/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/storage-users/pkg/config/parser"
+	"github.com/opencloud-eu/opencloud/services/storage-users/pkg/event"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/urfave/cli/v2"
+)
+
+// Revisions wraps revision related sub-commands.
+func Revisions(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "revisions",
+		Usage: "manage revisions",
+		Subcommands: []*cli.Command{
+			PurgeExpiredRevisions(cfg),
+		},
+	}
+}
+
+// PurgeExpiredRevisions cli command removes the revisions the retention policies of the spaces don't keep.
+func PurgeExpiredRevisions(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "purge-expired",
+		Usage: "Purge revisions expired by the retention policies of the spaces",
+		Flags: []cli.Flag{},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			stream, err := event.NewStream(cfg)
+			if err != nil {
+				return err
+			}
+
+			if err := events.Publish(c.Context, stream, event.PurgeRevisions{ExecutionTime: time.Now()}); err != nil {
+				return err
+			}
+
+			// go-micro nats implementation uses async publishing,
+			// therefore we need to manually wait.
+			time.Sleep(5 * time.Second)
+
+			return nil
+		},
+	}
+}
@@ -1,6 +1,7 @@
 package event
 
 import (
+	"github.com/opencloud-eu/opencloud/pkg/eventstream"
 	"github.com/opencloud-eu/opencloud/pkg/generators"
 	"github.com/opencloud-eu/opencloud/services/storage-users/pkg/config"
 	"github.com/opencloud-eu/reva/v2/pkg/events/stream"
@@ -10,7 +11,7 @@ import (
 // NewStream prepares the requested nats stream and returns it.
 func NewStream(cfg *config.Config) (events.Stream, error) {
 	connName := generators.GenerateConnectionName(cfg.Service.Name, generators.NTypeBus)
-	return stream.NatsFromConfig(connName, false, stream.NatsConfig{
+	s, err := stream.NatsFromConfig(connName, false, stream.NatsConfig{
 		Endpoint:             cfg.Events.Addr,
 		Cluster:              cfg.Events.ClusterID,
 		EnableTLS:            cfg.Events.EnableTLS,
@@ -19,4 +20,8 @@ func NewStream(cfg *config.Config) (events.Stream, error) {
 		AuthUsername:         cfg.Events.AuthUsername,
 		AuthPassword:         cfg.Events.AuthPassword,
 	})
+	if err != nil {
+		return nil, err
+	}
+	return eventstream.Typed(s), nil
 }
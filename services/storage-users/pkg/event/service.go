@@ -2,6 +2,7 @@ package event
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	apiGateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
@@ -10,6 +11,8 @@ import (
 	"github.com/opencloud-eu/opencloud/services/storage-users/pkg/task"
 	"github.com/opencloud-eu/reva/v2/pkg/events"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	decomposedbs "github.com/opencloud-eu/reva/v2/pkg/storage/fs/decomposed/blobstore"
+	decomposeds3bs "github.com/opencloud-eu/reva/v2/pkg/storage/fs/decomposeds3/blobstore"
 )
 
 const (
@@ -40,7 +43,7 @@ func NewService(ctx context.Context, gatewaySelector pool.Selectable[apiGateway.
 
 // Run to fulfil Runner interface
 func (s Service) Run() error {
-	ch, err := events.Consume(s.eventStream, consumerGroup, PurgeTrashBin{})
+	ch, err := events.Consume(s.eventStream, consumerGroup, PurgeTrashBin{}, PurgeRevisions{})
 	if err != nil {
 		return err
 	}
@@ -62,10 +65,14 @@ func (s Service) Run() error {
 }
 
 func (s Service) handleEvent(e events.Event) {
-	var errs []error
+	var (
+		errs []error
+		name string
+	)
 
 	switch ev := e.Event.(type) {
 	case PurgeTrashBin:
+		name = "PurgeTrashBin"
 		executionTime := ev.ExecutionTime
 		if executionTime.IsZero() {
 			executionTime = time.Now()
@@ -77,20 +84,59 @@ func (s Service) handleEvent(e events.Event) {
 		}
 
 		for spaceType, deleteBefore := range tasks {
-			// skip task execution if the deleteBefore time is the same as the now time,
-			// which indicates that the duration configuration for this space type is set to 0 which is the equivalent to disabled.
+			// if the deleteBefore time is the same as the now time, the duration configuration for this space type is set to 0
+			// which is the equivalent to disabled. Only the spaces with a retention policy are purged then.
 			if deleteBefore.Equal(executionTime) {
-				continue
+				deleteBefore = time.Time{}
 			}
 
 			if err := task.PurgeTrashBin(s.config.ServiceAccount.ServiceAccountID, deleteBefore, spaceType, s.gatewaySelector, s.config.ServiceAccount.ServiceAccountSecret); err != nil {
 				errs = append(errs, err)
 			}
 		}
+	case PurgeRevisions:
+		name = "PurgeRevisions"
+		executionTime := ev.ExecutionTime
+		if executionTime.IsZero() {
+			executionTime = time.Now()
+		}
+
+		root, bs, err := s.revisionsStorage()
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
 
+		for _, spaceType := range []task.SpaceType{task.Project, task.Personal} {
+			if err := task.PurgeRevisions(s.config.ServiceAccount.ServiceAccountID, executionTime, root, bs, spaceType, s.gatewaySelector, s.config.ServiceAccount.ServiceAccountSecret); err != nil {
+				errs = append(errs, err)
+			}
+		}
 	}
 
 	for _, err := range errs {
-		s.logger.Error().Err(err).Interface("event", e).Msg("Error running PurgeTrashBin task")
+		s.logger.Error().Err(err).Interface("event", e).Msgf("Error running %s task", name)
+	}
+}
+
+// revisionsStorage returns the root and the blobstore of the storage driver. Revisions can only be purged
+// for the decomposed drivers.
+func (s Service) revisionsStorage() (string, task.Blobstore, error) {
+	switch s.config.Driver {
+	case "decomposed", "ocis":
+		bs, err := decomposedbs.New(s.config.Drivers.Decomposed.Root)
+		return s.config.Drivers.Decomposed.Root, bs, err
+	case "decomposeds3", "s3ng":
+		bs, err := decomposeds3bs.New(
+			s.config.Drivers.DecomposedS3.Endpoint,
+			s.config.Drivers.DecomposedS3.Region,
+			s.config.Drivers.DecomposedS3.Bucket,
+			s.config.Drivers.DecomposedS3.AccessKey,
+			s.config.Drivers.DecomposedS3.SecretKey,
+			decomposeds3bs.Options{},
+		)
+		return s.config.Drivers.DecomposedS3.Root, bs, err
+	default:
+		return "", nil, fmt.Errorf("purging revisions is not supported by the '%s' driver", s.config.Driver)
 	}
 }
@@ -19,3 +19,16 @@ func (PurgeTrashBin) Unmarshal(v []byte) (interface{}, error) {
 	err := json.Unmarshal(v, &e)
 	return e, err
 }
+
+// PurgeRevisions wraps all needed information to purge the revisions the retention policies don't keep
+type PurgeRevisions struct {
+	ExecutantID   *apiUser.UserId
+	ExecutionTime time.Time
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (PurgeRevisions) Unmarshal(v []byte) (interface{}, error) {
+	e := PurgeRevisions{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
@@ -0,0 +1,394 @@
+// Package interceptor contains the grpc interceptors the storage-users service adds to the storage provider.
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/opencloud-eu/opencloud/pkg/retention"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/events/stream"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	_retentionPriority = 200
+
+	// _serviceAccountTokenTTL is the time a token of the service account is reused, it must be shorter
+	// than the lifetime of the tokens issued by the gateway
+	_serviceAccountTokenTTL = 5 * time.Minute
+
+	// _policyTTL is the time the policy of a space is reused. Changes made through this storage provider
+	// and changes announced on the event bus are picked up right away, other changes after the ttl.
+	_policyTTL = 30 * time.Second
+)
+
+func init() {
+	rgrpc.RegisterUnaryInterceptor("retention", NewRetention)
+}
+
+type retentionConfig struct {
+	GatewayAddr          string                `mapstructure:"gateway_addr"`
+	ServiceAccountID     string                `mapstructure:"service_account_id"`
+	ServiceAccountSecret string                `mapstructure:"service_account_secret"`
+	Events               retentionEventsConfig `mapstructure:"events"`
+}
+
+// retentionEventsConfig is the event bus the changes of the policies are announced on.
+type retentionEventsConfig struct {
+	Name              string `mapstructure:"name"`
+	stream.NatsConfig `mapstructure:",squash"`
+}
+
+// retentionInterceptor enforces the retention policies of the spaces.
+type retentionInterceptor struct {
+	conf     retentionConfig
+	gateway  func() (gateway.GatewayAPIClient, error)
+	token    *serviceAccountToken
+	policies *policyCache
+}
+
+// NewRetention returns a new unary interceptor that enforces the retention policies of the spaces. It blocks
+// deleting resources that are under legal hold or were changed within the minimum age of the policy, moving,
+// overwriting and restoring versions of resources under legal hold, and purging trash-bin items or deleting
+// spaces under legal hold.
+func NewRetention(m map[string]interface{}) (grpc.UnaryServerInterceptor, int, error) {
+	c := retentionConfig{}
+	if err := mapstructure.Decode(m, &c); err != nil {
+		return nil, 0, err
+	}
+
+	ri := newRetentionInterceptor(c, func() (gateway.GatewayAPIClient, error) {
+		return pool.GetGatewayServiceClient(c.GatewayAddr)
+	})
+	if c.Events.Endpoint != "" {
+		// every instance needs to see all changes, so each one consumes in its own group
+		s, err := stream.NatsFromConfig(c.Events.Name, true, c.Events.NatsConfig)
+		if err != nil {
+			return nil, 0, err
+		}
+		ch, err := events.Consume(s, "retention-"+uuid.New().String(), retention.PolicyChanged{})
+		if err != nil {
+			return nil, 0, err
+		}
+		go ri.listen(ch)
+	}
+	return ri.intercept, _retentionPriority, nil
+}
+
+func newRetentionInterceptor(c retentionConfig, gw func() (gateway.GatewayAPIClient, error)) *retentionInterceptor {
+	return &retentionInterceptor{
+		conf:     c,
+		gateway:  gw,
+		token:    &serviceAccountToken{},
+		policies: &policyCache{entries: map[string]cachedPolicy{}},
+	}
+}
+
+func (ri *retentionInterceptor) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	switch r := req.(type) {
+	case *provider.DeleteRequest:
+		if st := ri.checkDelete(ctx, r.GetRef()); st != nil {
+			return &provider.DeleteResponse{Status: st}, nil
+		}
+	case *provider.MoveRequest:
+		if st := ri.checkHeld(ctx, r.GetSource()); st != nil {
+			return &provider.MoveResponse{Status: st}, nil
+		}
+	case *provider.RestoreFileVersionRequest:
+		if st := ri.checkHeld(ctx, r.GetRef()); st != nil {
+			return &provider.RestoreFileVersionResponse{Status: st}, nil
+		}
+	case *provider.InitiateFileUploadRequest:
+		// uploads of new files are not affected, the check only applies to existing ones
+		if st := ri.checkHeld(ctx, r.GetRef()); st != nil {
+			return &provider.InitiateFileUploadResponse{Status: st}, nil
+		}
+	case *provider.PurgeRecycleRequest:
+		if st := ri.checkPurgeRecycle(ctx, r.GetRef(), r.GetKey()); st != nil {
+			return &provider.PurgeRecycleResponse{Status: st}, nil
+		}
+	case *provider.DeleteStorageSpaceRequest:
+		if st := ri.checkDeleteSpace(ctx, r.GetId()); st != nil {
+			return &provider.DeleteStorageSpaceResponse{Status: st}, nil
+		}
+	case *provider.SetArbitraryMetadataRequest:
+		defer ri.invalidate(r.GetRef(), keys(r.GetArbitraryMetadata().GetMetadata()))
+	case *provider.UnsetArbitraryMetadataRequest:
+		defer ri.invalidate(r.GetRef(), r.GetArbitraryMetadataKeys())
+	}
+	return handler(ctx, req)
+}
+
+// serviceContext returns a context authenticated as the service account and a gateway client to use it with.
+func (ri *retentionInterceptor) serviceContext(ctx context.Context) (context.Context, gateway.GatewayAPIClient, error) {
+	gatewayClient, err := ri.gateway()
+	if err != nil {
+		return nil, nil, err
+	}
+	token, err := ri.token.get(ctx, gatewayClient, ri.conf.ServiceAccountID, ri.conf.ServiceAccountSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), ctxpkg.TokenHeader, token), gatewayClient, nil
+}
+
+// policy returns the retention policy of the space the resource belongs to. When the policy can't be read,
+// the last known one is used. Without one, the request is refused, as the resource might be under legal hold.
+func (ri *retentionInterceptor) policy(ctx context.Context, id *provider.ResourceId) (retention.Policy, *rpc.Status) {
+	if id.GetSpaceId() == "" {
+		return retention.Policy{}, nil
+	}
+	space := storagespace.FormatStorageID(id.GetStorageId(), id.GetSpaceId())
+	p, known, fresh := ri.policies.get(space, time.Now())
+	if fresh {
+		return p, nil
+	}
+
+	log := appctx.GetLogger(ctx)
+	unavailable := &rpc.Status{Code: rpc.Code_CODE_UNAVAILABLE, Message: "could not read the retention policy"}
+	sctx, gatewayClient, err := ri.serviceContext(ctx)
+	if err != nil {
+		log.Error().Err(err).Bool("known", known).Msg("retention: could not authenticate the service account")
+		if known {
+			return p, nil
+		}
+		return retention.Policy{}, unavailable
+	}
+	np, err := retention.Get(sctx, gatewayClient, id)
+	var notFound errtypes.IsNotFound
+	switch {
+	case errors.As(err, &notFound):
+		// let the storage provider report the error
+		return retention.Policy{}, nil
+	case err != nil:
+		log.Error().Err(err).Str("spaceid", space).Bool("known", known).Msg("retention: could not read the retention policy")
+		if known {
+			return p, nil
+		}
+		return retention.Policy{}, unavailable
+	}
+	ri.policies.set(space, np, time.Now())
+	return np, nil
+}
+
+// listen drops the cached policies of the spaces whose policy was changed through another instance.
+func (ri *retentionInterceptor) listen(ch <-chan events.Event) {
+	for e := range ch {
+		if ev, ok := e.Event.(retention.PolicyChanged); ok {
+			ri.policies.invalidate(ev.SpaceID)
+		}
+	}
+}
+
+// invalidate drops the cached policy of the space when its root metadata was changed. It is called after
+// the request was handled.
+func (ri *retentionInterceptor) invalidate(ref *provider.Reference, keys []string) {
+	id := ref.GetResourceId()
+	if id.GetSpaceId() == "" || id.GetOpaqueId() != id.GetSpaceId() || (ref.GetPath() != "" && ref.GetPath() != ".") {
+		return
+	}
+	for _, k := range keys {
+		if retention.IsKey(k) {
+			ri.policies.invalidate(storagespace.FormatStorageID(id.GetStorageId(), id.GetSpaceId()))
+			return
+		}
+	}
+}
+
+// stat returns the resource and, when asked for, its path in the space. ok is false when it doesn't exist.
+func (ri *retentionInterceptor) stat(ctx context.Context, ref *provider.Reference, withPath bool) (*provider.ResourceInfo, string, bool, *rpc.Status) {
+	sctx, gatewayClient, err := ri.serviceContext(ctx)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("retention: could not authenticate the service account")
+		return nil, "", false, status.NewInternal(ctx, "could not check the retention policy")
+	}
+	res, err := gatewayClient.Stat(sctx, &provider.StatRequest{Ref: ref})
+	if err != nil || res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		// let the storage provider report the error
+		return nil, "", false, nil
+	}
+	if !withPath {
+		return res.GetInfo(), "", true, nil
+	}
+	pres, err := gatewayClient.GetPath(sctx, &provider.GetPathRequest{ResourceId: res.GetInfo().GetId()})
+	if err != nil || pres.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		return nil, "", false, status.NewInternal(ctx, "could not check the legal holds")
+	}
+	return res.GetInfo(), pres.GetPath(), true, nil
+}
+
+// checkHeld blocks changing an existing resource under legal hold.
+func (ri *retentionInterceptor) checkHeld(ctx context.Context, ref *provider.Reference) *rpc.Status {
+	p, st := ri.policy(ctx, ref.GetResourceId())
+	if st != nil || !p.Holding() {
+		return st
+	}
+	_, resourcePath, ok, st := ri.stat(ctx, ref, true)
+	if st != nil || !ok {
+		return st
+	}
+	if p.Held(resourcePath) {
+		return status.NewPermissionDenied(ctx, nil, "the resource is under legal hold")
+	}
+	return nil
+}
+
+func (ri *retentionInterceptor) checkDelete(ctx context.Context, ref *provider.Reference) *rpc.Status {
+	p, st := ri.policy(ctx, ref.GetResourceId())
+	if st != nil || (!p.Holding() && p.MinAgeDays == 0) {
+		return st
+	}
+	info, resourcePath, ok, st := ri.stat(ctx, ref, p.Holding())
+	if st != nil || !ok {
+		return st
+	}
+	if p.TooYoung(utils.TSToTime(info.GetMtime()), time.Now()) {
+		return status.NewPermissionDenied(ctx, nil, "the resource was changed too recently to be deleted")
+	}
+	if p.Holding() && p.Held(resourcePath) {
+		return status.NewPermissionDenied(ctx, nil, "the resource is under legal hold")
+	}
+	return nil
+}
+
+func (ri *retentionInterceptor) checkPurgeRecycle(ctx context.Context, ref *provider.Reference, key string) *rpc.Status {
+	p, st := ri.policy(ctx, ref.GetResourceId())
+	if st != nil || !p.Holding() {
+		return st
+	}
+	if p.LegalHold {
+		return status.NewPermissionDenied(ctx, nil, "the space is under legal hold")
+	}
+
+	sctx, gatewayClient, err := ri.serviceContext(ctx)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("retention: could not authenticate the service account")
+		return status.NewInternal(ctx, "could not check the legal holds")
+	}
+	res, err := gatewayClient.ListRecycle(sctx, &provider.ListRecycleRequest{Ref: ref})
+	if err != nil || res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		return status.NewInternal(ctx, "could not check the legal holds")
+	}
+	// keys of items within a deleted folder are the key of the folder followed by their path in it
+	itemKey, rest, _ := strings.Cut(key, "/")
+	for _, item := range res.GetRecycleItems() {
+		if key != "" && item.GetKey() != itemKey {
+			continue
+		}
+		if p.Held(path.Join(item.GetRef().GetPath(), rest)) {
+			return status.NewPermissionDenied(ctx, nil, "the item is under legal hold")
+		}
+	}
+	return nil
+}
+
+// checkDeleteSpace blocks disabling and purging spaces under legal hold. Disabled spaces can't be read and
+// can't be put under legal hold, so they are purged without a check.
+func (ri *retentionInterceptor) checkDeleteSpace(ctx context.Context, id *provider.StorageSpaceId) *rpc.Status {
+	rid, err := storagespace.ParseID(id.GetOpaqueId())
+	if err != nil {
+		// let the storage provider report the error
+		return nil
+	}
+	p, st := ri.policy(ctx, &rid)
+	if st != nil {
+		return st
+	}
+	if p.Holding() {
+		return status.NewPermissionDenied(ctx, nil, "the space is under legal hold")
+	}
+	return nil
+}
+
+func keys(m map[string]string) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	return ks
+}
+
+// cachedPolicy is the policy of a space and the time it is read again.
+type cachedPolicy struct {
+	policy  retention.Policy
+	expires time.Time
+}
+
+// policyCache keeps the policies of the spaces. Expired policies are kept as the last known ones.
+type policyCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedPolicy
+}
+
+// get returns the policy of the space, whether it was ever read and whether it can be used without
+// reading it again.
+func (c *policyCache) get(space string, now time.Time) (retention.Policy, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[space]
+	return e.policy, ok, ok && now.Before(e.expires)
+}
+
+func (c *policyCache) set(space string, p retention.Policy, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[space] = cachedPolicy{policy: p, expires: now.Add(_policyTTL)}
+}
+
+// invalidate makes the policy of the space be read again on the next request.
+func (c *policyCache) invalidate(space string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[space]; ok {
+		e.expires = time.Time{}
+		c.entries[space] = e
+	}
+}
+
+// serviceAccountToken caches the token of the service account, so the interceptor does not need to
+// authenticate for every request
+type serviceAccountToken struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// get returns the cached token or a new one from the gateway. The lock is not held while authenticating,
+// concurrent callers may authenticate at the same time.
+func (t *serviceAccountToken) get(ctx context.Context, gatewayClient gateway.GatewayAPIClient, id, secret string) (string, error) {
+	t.mu.Lock()
+	token, expires := t.token, t.expires
+	t.mu.Unlock()
+	if token != "" && time.Now().Before(expires) {
+		return token, nil
+	}
+
+	token, err := utils.GetServiceUserToken(ctx, gatewayClient, id, secret)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	t.token, t.expires = token, time.Now().Add(_serviceAccountTokenTTL)
+	t.mu.Unlock()
+	return token, nil
+}
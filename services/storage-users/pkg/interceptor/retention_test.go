@@ -0,0 +1,144 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/opencloud/pkg/retention"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	cs3mocks "github.com/opencloud-eu/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+)
+
+var _ = Describe("retention", func() {
+	var (
+		gatewayClient *cs3mocks.GatewayAPIClient
+		gatewayErr    error
+		ri            *retentionInterceptor
+		ctx           context.Context
+		rootMetadata  map[string]string
+		handled       int
+	)
+
+	root := &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "space"}
+	held := &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "held"}
+	ref := func(id *provider.ResourceId) *provider.Reference { return &provider.Reference{ResourceId: id} }
+
+	handler := func(_ context.Context, _ interface{}) (interface{}, error) {
+		handled++
+		return nil, nil
+	}
+	isRootStat := func(req *provider.StatRequest) bool { return len(req.GetArbitraryMetadataKeys()) > 0 }
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		handled = 0
+		gatewayErr = nil
+		rootMetadata = map[string]string{}
+		gatewayClient = &cs3mocks.GatewayAPIClient{}
+		ri = newRetentionInterceptor(retentionConfig{}, func() (gateway.GatewayAPIClient, error) {
+			return gatewayClient, gatewayErr
+		})
+
+		gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{Status: status.NewOK(ctx), Token: "token"}, nil)
+		gatewayClient.On("Stat", mock.Anything, mock.MatchedBy(isRootStat)).Return(func(_ context.Context, _ *provider.StatRequest, _ ...grpc.CallOption) (*provider.StatResponse, error) {
+			return &provider.StatResponse{Status: status.NewOK(ctx), Info: &provider.ResourceInfo{
+				Id:                root,
+				ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: rootMetadata},
+			}}, nil
+		})
+		gatewayClient.On("Stat", mock.Anything, mock.MatchedBy(func(req *provider.StatRequest) bool {
+			return !isRootStat(req) && req.GetRef().GetResourceId().GetOpaqueId() == "held"
+		})).Return(&provider.StatResponse{Status: status.NewOK(ctx), Info: &provider.ResourceInfo{Id: held}}, nil)
+		gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{Status: status.NewNotFound(ctx, "not found")}, nil)
+		gatewayClient.On("GetPath", mock.Anything, mock.Anything).Return(&provider.GetPathResponse{Status: status.NewOK(ctx), Path: "/held"}, nil)
+	})
+
+	It("reads the policy of a space and the service account token once", func() {
+		for range 3 {
+			_, err := ri.intercept(ctx, &provider.DeleteRequest{Ref: ref(held)}, nil, handler)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(handled).To(Equal(3))
+		gatewayClient.AssertNumberOfCalls(GinkgoT(), "Authenticate", 1)
+		gatewayClient.AssertNumberOfCalls(GinkgoT(), "Stat", 1)
+	})
+
+	It("reads the policy again after it was changed", func() {
+		_, _ = ri.intercept(ctx, &provider.DeleteRequest{Ref: ref(held)}, nil, handler)
+		Expect(handled).To(Equal(1))
+
+		rootMetadata[retention.HoldKeyPrefix+"held"] = "/held"
+		_, _ = ri.intercept(ctx, &provider.SetArbitraryMetadataRequest{
+			Ref:               ref(root),
+			ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{retention.HoldKeyPrefix + "held": "/held"}},
+		}, nil, handler)
+		Expect(handled).To(Equal(2))
+
+		res, err := ri.intercept(ctx, &provider.DeleteRequest{Ref: ref(held)}, nil, handler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handled).To(Equal(2))
+		Expect(res.(*provider.DeleteResponse).GetStatus().GetMessage()).To(Equal("the resource is under legal hold"))
+	})
+
+	It("blocks moving, overwriting and restoring versions of held resources", func() {
+		rootMetadata[retention.HoldKeyPrefix+"held"] = "/held"
+
+		res, _ := ri.intercept(ctx, &provider.MoveRequest{Source: ref(held), Destination: ref(root)}, nil, handler)
+		Expect(res.(*provider.MoveResponse).GetStatus().GetMessage()).To(Equal("the resource is under legal hold"))
+		res, _ = ri.intercept(ctx, &provider.RestoreFileVersionRequest{Ref: ref(held), Key: "v1"}, nil, handler)
+		Expect(res.(*provider.RestoreFileVersionResponse).GetStatus().GetMessage()).To(Equal("the resource is under legal hold"))
+		res, _ = ri.intercept(ctx, &provider.InitiateFileUploadRequest{Ref: ref(held)}, nil, handler)
+		Expect(res.(*provider.InitiateFileUploadResponse).GetStatus().GetMessage()).To(Equal("the resource is under legal hold"))
+		Expect(handled).To(Equal(0))
+
+		// new files can be uploaded
+		_, _ = ri.intercept(ctx, &provider.InitiateFileUploadRequest{Ref: &provider.Reference{ResourceId: root, Path: "./new.txt"}}, nil, handler)
+		Expect(handled).To(Equal(1))
+	})
+
+	It("reads the policy again after a change event", func() {
+		_, _ = ri.intercept(ctx, &provider.DeleteRequest{Ref: ref(held)}, nil, handler)
+		Expect(handled).To(Equal(1))
+
+		// the hold was placed through another instance
+		rootMetadata[retention.HoldKeyPrefix+"held"] = "/held"
+		ch := make(chan events.Event, 1)
+		ch <- events.Event{Event: retention.PolicyChanged{SpaceID: "storage$space"}}
+		close(ch)
+		ri.listen(ch)
+
+		res, _ := ri.intercept(ctx, &provider.DeleteRequest{Ref: ref(held)}, nil, handler)
+		Expect(res.(*provider.DeleteResponse).GetStatus().GetMessage()).To(Equal("the resource is under legal hold"))
+		Expect(handled).To(Equal(1))
+	})
+
+	It("refuses requests when no policy could be read", func() {
+		gatewayErr = errors.New("unavailable")
+		res, err := ri.intercept(ctx, &provider.DeleteRequest{Ref: ref(held)}, nil, handler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.(*provider.DeleteResponse).GetStatus().GetCode()).To(Equal(rpc.Code_CODE_UNAVAILABLE))
+		Expect(handled).To(Equal(0))
+	})
+
+	It("uses the last known policy when the gateway is unavailable", func() {
+		gatewayErr = errors.New("unavailable")
+		ri.policies.set("storage$space", retention.Policy{}, time.Time{})
+		_, _ = ri.intercept(ctx, &provider.DeleteRequest{Ref: ref(held)}, nil, handler)
+		Expect(handled).To(Equal(1))
+
+		// an expired policy of a space under legal hold
+		ri.policies.set("storage$space", retention.Policy{LegalHold: true}, time.Time{})
+		res, _ := ri.intercept(ctx, &provider.DeleteStorageSpaceRequest{Id: &provider.StorageSpaceId{OpaqueId: "storage$space"}}, nil, handler)
+		Expect(res.(*provider.DeleteStorageSpaceResponse).GetStatus().GetMessage()).To(Equal("the space is under legal hold"))
+		Expect(handled).To(Equal(1))
+	})
+})
@@ -6,7 +6,11 @@ import (
 	"strings"
 
 	"github.com/opencloud-eu/opencloud/pkg/generators"
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/storage-users/pkg/config"
+
+	// register the retention interceptor
+	_ "github.com/opencloud-eu/opencloud/services/storage-users/pkg/interceptor"
 )
 
 // StorageUsersConfigFromStruct will adapt an OpenCloud config struct into a reva mapstructure to start a reva service.
@@ -51,6 +55,9 @@ func StorageUsersConfigFromStruct(cfg *config.Config) map[string]interface{} {
 				},
 			},
 			"interceptors": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"eventsmiddleware": map[string]interface{}{
 					"group":            "sharing",
 					"type":             "nats",
@@ -67,12 +74,30 @@ func StorageUsersConfigFromStruct(cfg *config.Config) map[string]interface{} {
 					"namespace": "opencloud",
 					"subsystem": "storage_users",
 				},
+				"retention": map[string]interface{}{
+					"gateway_addr":           cfg.Reva.Address,
+					"service_account_id":     cfg.ServiceAccount.ServiceAccountID,
+					"service_account_secret": cfg.ServiceAccount.ServiceAccountSecret,
+					"events": map[string]interface{}{
+						"name":             generators.GenerateConnectionName(cfg.Service.Name, generators.NTypeBus),
+						"address":          cfg.Events.Addr,
+						"clusterID":        cfg.Events.ClusterID,
+						"tls-insecure":     cfg.Events.TLSInsecure,
+						"tls-root-ca-cert": cfg.Events.TLSRootCaCertPath,
+						"enable-tls":       cfg.Events.EnableTLS,
+						"username":         cfg.Events.AuthUsername,
+						"password":         cfg.Events.AuthPassword,
+					},
+				},
 			},
 		},
 		"http": map[string]interface{}{
 			"network": cfg.HTTP.Protocol,
 			"address": cfg.HTTP.Addr,
 			"middlewares": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"requestid": map[string]interface{}{},
 			},
 			// TODO build services dynamically
@@ -120,6 +145,9 @@ func StorageUsersConfigFromStruct(cfg *config.Config) map[string]interface{} {
 	if cfg.ReadOnly {
 		gcfg := rcfg["grpc"].(map[string]interface{})
 		gcfg["interceptors"] = map[string]interface{}{
+			"auth": map[string]interface{}{
+				"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+			},
 			"readonly": map[string]interface{}{},
 		}
 	}
@@ -0,0 +1,180 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	apiGateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	apiRpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	apiProvider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/opencloud/pkg/retention"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/lookup"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Blobstore deletes the blobs of purged revisions.
+type Blobstore interface {
+	Delete(node *node.Node) error
+}
+
+// revision is a version of a file in the decomposed storage.
+type revision struct {
+	path  string
+	mtime time.Time
+}
+
+// PurgeRevisions removes the versions of files the retention policy of their space doesn't keep anymore.
+// It works on the metadata of the decomposed storage in root, which is why it needs to run next to the storage.
+// Spaces without a version rule are not touched, files protected by a legal hold keep all their versions.
+func PurgeRevisions(serviceAccountID string, executionTime time.Time, root string, bs Blobstore, spaceType SpaceType, gatewaySelector pool.Selectable[apiGateway.GatewayAPIClient], serviceAccountSecret string) error {
+	gatewayClient, err := gatewaySelector.Next()
+	if err != nil {
+		return err
+	}
+
+	ctx, err := utils.GetServiceUserContext(serviceAccountID, gatewayClient, serviceAccountSecret)
+	if err != nil {
+		return err
+	}
+
+	listStorageSpacesResponse, err := gatewayClient.ListStorageSpaces(ctx, &apiProvider.ListStorageSpacesRequest{
+		Filters: []*apiProvider.ListStorageSpacesRequest_Filter{
+			{
+				Type: apiProvider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+				Term: &apiProvider.ListStorageSpacesRequest_Filter_SpaceType{
+					SpaceType: string(spaceType),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, storageSpace := range listStorageSpacesResponse.StorageSpaces {
+		if typ := storageSpace.GetSpaceType(); typ != "personal" && typ != "project" {
+			// ignore spaces that are neither personal nor project
+			continue
+		}
+		spaceRoot := storageSpace.GetRoot()
+
+		gatewayClient, err = gatewaySelector.Next()
+		if err != nil {
+			return err
+		}
+		policy, err := retention.Get(ctx, gatewayClient, spaceRoot)
+		if err != nil {
+			return err
+		}
+		if !policy.PrunesVersions() || policy.LegalHold {
+			continue
+		}
+
+		revisions, err := listRevisions(filepath.Join(root, "spaces", lookup.Pathify(spaceRoot.GetSpaceId(), 1, 2), "nodes"))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for nodeID, revs := range revisions {
+			if held(ctx, gatewayClient, policy, &apiProvider.ResourceId{StorageId: spaceRoot.GetStorageId(), SpaceId: spaceRoot.GetSpaceId(), OpaqueId: nodeID}) {
+				continue
+			}
+
+			// newest first, so the index is the rank of the version
+			slices.SortFunc(revs, func(a, b revision) int { return b.mtime.Compare(a.mtime) })
+			for i, rev := range revs {
+				if policy.KeepVersion(i, rev.mtime, executionTime) {
+					continue
+				}
+				if err := deleteRevision(rev, spaceRoot.GetSpaceId(), bs); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// held tells if the node is protected by a legal hold of a folder. Nodes whose path can't be resolved are
+// treated as held, so nothing is deleted by mistake.
+func held(ctx context.Context, gatewayClient apiGateway.GatewayAPIClient, policy retention.Policy, id *apiProvider.ResourceId) bool {
+	if len(policy.Holds) == 0 {
+		return false
+	}
+	res, err := gatewayClient.GetPath(ctx, &apiProvider.GetPathRequest{ResourceId: id})
+	if err != nil || res.GetStatus().GetCode() != apiRpc.Code_CODE_OK {
+		return true
+	}
+	return policy.Held(res.GetPath())
+}
+
+// listRevisions returns the revisions below the nodes folder of a space by the id of their node.
+func listRevisions(nodesPath string) (map[string][]revision, error) {
+	revisions := map[string][]revision{}
+	err := filepath.WalkDir(nodesPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name, ts, ok := strings.Cut(d.Name(), node.RevisionIDDelimiter)
+		if !ok || filepath.Ext(ts) == ".mpk" || filepath.Ext(ts) == ".mlock" {
+			return nil
+		}
+		mtime, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(nodesPath, filepath.Join(filepath.Dir(p), name))
+		if err != nil {
+			return nil
+		}
+		nodeID := strings.ReplaceAll(rel, string(filepath.Separator), "")
+		revisions[nodeID] = append(revisions[nodeID], revision{path: p, mtime: mtime})
+		return nil
+	})
+	return revisions, err
+}
+
+// deleteRevision removes the blob and the files of a revision.
+func deleteRevision(rev revision, spaceID string, bs Blobstore) error {
+	b, err := os.ReadFile(rev.path + ".mpk")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if len(b) > 0 {
+		m := map[string][]byte{}
+		if err := msgpack.Unmarshal(b, &m); err != nil {
+			return err
+		}
+		if blobID := string(m[prefixes.BlobIDAttr]); blobID != "" {
+			if err := bs.Delete(&node.Node{BaseNode: node.BaseNode{SpaceID: spaceID}, BlobID: blobID}); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range []string{rev.path, rev.path + ".mpk", rev.path + ".mlock"} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
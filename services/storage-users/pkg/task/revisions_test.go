@@ -0,0 +1,138 @@
+package task_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	apiProvider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/opencloud/pkg/retention"
+	"github.com/opencloud-eu/opencloud/services/storage-users/pkg/task"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/lookup"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
+	cs3mocks "github.com/opencloud-eu/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/grpc"
+)
+
+type blobstore struct {
+	deleted []string
+}
+
+func (bs *blobstore) Delete(n *node.Node) error {
+	bs.deleted = append(bs.deleted, n.SpaceID+"/"+n.BlobID)
+	return nil
+}
+
+var _ = Describe("revisions", func() {
+	var (
+		gatewayClient   *cs3mocks.GatewayAPIClient
+		gatewaySelector pool.Selectable[gateway.GatewayAPIClient]
+		ctx             context.Context
+		now             time.Time
+		root            string
+		bs              *blobstore
+		metadata        map[string]string
+	)
+
+	const spaceID = "1c5e2d3b-project"
+
+	writeRevision := func(nodeID string, mtime time.Time, blobID string) string {
+		p := filepath.Join(root, "spaces", lookup.Pathify(spaceID, 1, 2), "nodes", lookup.Pathify(nodeID, 4, 2)) + node.RevisionIDDelimiter + mtime.UTC().Format(time.RFC3339Nano)
+		Expect(os.MkdirAll(filepath.Dir(p), 0700)).To(Succeed())
+		Expect(os.WriteFile(p, nil, 0600)).To(Succeed())
+		md, err := msgpack.Marshal(map[string][]byte{"user.oc.blobid": []byte(blobID)})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(p+".mpk", md, 0600)).To(Succeed())
+		return p
+	}
+
+	BeforeEach(func() {
+		pool.RemoveSelector("GatewaySelector" + "eu.opencloud.api.gateway")
+		gatewayClient = &cs3mocks.GatewayAPIClient{}
+		gatewaySelector = pool.GetSelector[gateway.GatewayAPIClient](
+			"GatewaySelector",
+			"eu.opencloud.api.gateway",
+			func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+				return gatewayClient
+			},
+		)
+		ctx = context.Background()
+		now = time.Now()
+		root = GinkgoT().TempDir()
+		bs = &blobstore{}
+		metadata = map[string]string{}
+
+		gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{Status: status.NewOK(ctx)}, nil)
+		gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&apiProvider.ListStorageSpacesResponse{
+			Status: status.NewOK(ctx),
+			StorageSpaces: []*apiProvider.StorageSpace{{
+				SpaceType: "project",
+				Root:      &apiProvider.ResourceId{SpaceId: spaceID, OpaqueId: spaceID},
+			}},
+		}, nil)
+		gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(
+			func(_ context.Context, _ *apiProvider.StatRequest, _ ...grpc.CallOption) *apiProvider.StatResponse {
+				return &apiProvider.StatResponse{
+					Status: status.NewOK(ctx),
+					Info:   &apiProvider.ResourceInfo{ArbitraryMetadata: &apiProvider.ArbitraryMetadata{Metadata: metadata}},
+				}
+			}, nil,
+		)
+		gatewayClient.On("GetPath", mock.Anything, mock.Anything).Return(
+			func(_ context.Context, req *apiProvider.GetPathRequest, _ ...grpc.CallOption) *apiProvider.GetPathResponse {
+				return &apiProvider.GetPathResponse{Status: status.NewOK(ctx), Path: "/" + req.ResourceId.OpaqueId}
+			}, nil,
+		)
+	})
+
+	Describe("PurgeRevisions", func() {
+		It("keeps all revisions of spaces without a version rule", func() {
+			p := writeRevision("file-a", now.Add(-1000*time.Hour), "blob-1")
+
+			err := task.PurgeRevisions("service-user-id", now, root, bs, task.Project, gatewaySelector, "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p).To(BeAnExistingFile())
+			Expect(bs.deleted).To(BeEmpty())
+		})
+		It("removes the revisions the policy doesn't keep", func() {
+			metadata[retention.VersionsCountKey] = "1"
+			metadata[retention.VersionsDaysKey] = "2"
+			newest := writeRevision("file-a", now.Add(-100*time.Hour), "blob-1")
+			older := writeRevision("file-a", now.Add(-200*time.Hour), "blob-2")
+			recent := writeRevision("file-b", now.Add(-time.Hour), "blob-3")
+			recentOlder := writeRevision("file-b", now.Add(-2*time.Hour), "blob-4")
+
+			err := task.PurgeRevisions("service-user-id", now, root, bs, task.Project, gatewaySelector, "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(newest).To(BeAnExistingFile())
+			Expect(older).ToNot(BeAnExistingFile())
+			Expect(older + ".mpk").ToNot(BeAnExistingFile())
+			Expect(recent).To(BeAnExistingFile())
+			Expect(recentOlder).To(BeAnExistingFile())
+			Expect(bs.deleted).To(ConsistOf(spaceID + "/blob-2"))
+		})
+		It("keeps the revisions of files under legal hold", func() {
+			metadata[retention.VersionsCountKey] = "1"
+			// the path of the held folder is updated to where it is now
+			metadata[retention.HoldKeyPrefix+"file-held"] = "/moved"
+			held := writeRevision("file-held", now.Add(-200*time.Hour), "blob-1")
+			writeRevision("file-held", now.Add(-100*time.Hour), "blob-2")
+			free := writeRevision("file-free", now.Add(-200*time.Hour), "blob-3")
+			writeRevision("file-free", now.Add(-100*time.Hour), "blob-4")
+
+			err := task.PurgeRevisions("service-user-id", now, root, bs, task.Project, gatewaySelector, "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(held).To(BeAnExistingFile())
+			Expect(free).ToNot(BeAnExistingFile())
+			Expect(bs.deleted).To(ConsistOf(spaceID + "/blob-3"))
+		})
+	})
+})
@@ -6,6 +6,7 @@ import (
 	apiGateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	apiRpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	apiProvider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/opencloud/pkg/retention"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
@@ -15,6 +16,9 @@ import (
 // the provided executantID must have space access.
 // removeBefore specifies how long an item must be in the trash-bin to be deleted,
 // items that stay there for a shorter time are ignored and kept in place.
+// A zero removeBefore only purges the spaces with a retention policy.
+// The retention policy of a space replaces removeBefore with its own trash-bin period,
+// items protected by a legal hold are never purged.
 func PurgeTrashBin(serviceAccountID string, deleteBefore time.Time, spaceType SpaceType, gatewaySelector pool.Selectable[apiGateway.GatewayAPIClient], serviceAccountSecret string) error {
 	gatewayClient, err := gatewaySelector.Next()
 	if err != nil {
@@ -57,6 +61,14 @@ func PurgeTrashBin(serviceAccountID string, deleteBefore time.Time, spaceType Sp
 		if err != nil {
 			return err
 		}
+		policy, err := retention.Get(ctx, gatewayClient, storageSpace.GetRoot())
+		if err != nil {
+			return err
+		}
+		if policy.TrashDays == 0 && deleteBefore.IsZero() {
+			continue
+		}
+
 		listRecycleResponse, err := gatewayClient.ListRecycle(ctx, &apiProvider.ListRecycleRequest{Ref: storageSpaceReference})
 		if err != nil {
 			return err
@@ -64,7 +76,10 @@ func PurgeTrashBin(serviceAccountID string, deleteBefore time.Time, spaceType Sp
 
 		for _, recycleItem := range listRecycleResponse.GetRecycleItems() {
 			doDelete := utils.TSToUnixNano(recycleItem.DeletionTime) < utils.TSToUnixNano(utils.TimeToTS(deleteBefore))
-			if !doDelete {
+			if policy.TrashDays > 0 {
+				doDelete = policy.TrashExpired(utils.TSToTime(recycleItem.DeletionTime), time.Now())
+			}
+			if !doDelete || policy.Held(recycleItem.GetRef().GetPath()) {
 				continue
 			}
 
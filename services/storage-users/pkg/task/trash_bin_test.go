@@ -14,6 +14,7 @@ import (
 	apiTypes "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/opencloud/pkg/retention"
 	"github.com/opencloud-eu/opencloud/services/storage-users/pkg/task"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
@@ -42,6 +43,7 @@ var _ = Describe("trash", func() {
 		getUserResponse           *apiUser.GetUserResponse
 		authenticateResponse      *apiGateway.AuthenticateResponse
 		listStorageSpacesResponse *apiProvider.ListStorageSpacesResponse
+		statResponse              *apiProvider.StatResponse
 		personalSpace             *apiProvider.StorageSpace
 		projectSpace              *apiProvider.StorageSpace
 		virtualSpace              *apiProvider.StorageSpace
@@ -72,6 +74,10 @@ var _ = Describe("trash", func() {
 			Status:        status.NewOK(ctx),
 			StorageSpaces: []*apiProvider.StorageSpace{},
 		}
+		statResponse = &apiProvider.StatResponse{
+			Status: status.NewOK(ctx),
+			Info:   &apiProvider.ResourceInfo{},
+		}
 		personalSpace = &apiProvider.StorageSpace{
 			SpaceType: "personal",
 			Id: &apiProvider.StorageSpaceId{
@@ -167,6 +173,7 @@ var _ = Describe("trash", func() {
 			gatewayClient.On("GetUser", mock.Anything, mock.Anything).Return(getUserResponse, nil)
 			gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(authenticateResponse, nil)
 			gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(listStorageSpacesResponse, nil)
+			gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(statResponse, nil)
 			gatewayClient.On("ListRecycle", mock.Anything, mock.Anything).Return(
 				func(_ context.Context, req *apiProvider.ListRecycleRequest, _ ...grpc.CallOption) *apiProvider.ListRecycleResponse {
 					return &apiProvider.ListRecycleResponse{
@@ -202,5 +209,62 @@ var _ = Describe("trash", func() {
 			// virtual spaces are ignored
 			Expect(recycleItems["virtual"]).To(HaveLen(3))
 		})
+		It("applies the retention policies of the spaces", func() {
+			var (
+				recycleItems = map[string][]*apiProvider.RecycleItem{
+					"personal": {
+						{Key: "old", DeletionTime: utils.TimeToTS(now.Add(-72 * time.Hour))},
+					},
+					"project": {
+						{Key: "old", Ref: &apiProvider.Reference{Path: "/a"}, DeletionTime: utils.TimeToTS(now.Add(-72 * time.Hour))},
+						{Key: "held", Ref: &apiProvider.Reference{Path: "/held/b"}, DeletionTime: utils.TimeToTS(now.Add(-72 * time.Hour))},
+						{Key: "recent", Ref: &apiProvider.Reference{Path: "/c"}, DeletionTime: utils.TimeToTS(now.Add(-1 * time.Hour))},
+					},
+				}
+				metadata = map[string]map[string]string{
+					"project": {
+						retention.TrashDaysKey:                "1",
+						retention.HoldKeyPrefix + "folder-id": "/held",
+					},
+				}
+				purged []string
+			)
+			personalSpace.Root.SpaceId = "personal"
+			projectSpace.Root.SpaceId = "project"
+			listStorageSpacesResponse.StorageSpaces = []*apiProvider.StorageSpace{personalSpace, projectSpace}
+
+			gatewayClient.On("GetUser", mock.Anything, mock.Anything).Return(getUserResponse, nil)
+			gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(authenticateResponse, nil)
+			gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(listStorageSpacesResponse, nil)
+			gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(
+				func(_ context.Context, req *apiProvider.StatRequest, _ ...grpc.CallOption) *apiProvider.StatResponse {
+					return &apiProvider.StatResponse{
+						Status: status.NewOK(ctx),
+						Info: &apiProvider.ResourceInfo{
+							ArbitraryMetadata: &apiProvider.ArbitraryMetadata{Metadata: metadata[req.Ref.ResourceId.SpaceId]},
+						},
+					}
+				}, nil,
+			)
+			gatewayClient.On("GetPath", mock.Anything, mock.Anything).Return(&apiProvider.GetPathResponse{Status: status.NewNotFound(ctx, "")}, nil)
+			gatewayClient.On("ListRecycle", mock.Anything, mock.Anything).Return(
+				func(_ context.Context, req *apiProvider.ListRecycleRequest, _ ...grpc.CallOption) *apiProvider.ListRecycleResponse {
+					return &apiProvider.ListRecycleResponse{
+						RecycleItems: recycleItems[req.Ref.ResourceId.OpaqueId],
+					}
+				}, nil,
+			)
+			gatewayClient.On("PurgeRecycle", mock.Anything, mock.Anything).Return(
+				func(_ context.Context, req *apiProvider.PurgeRecycleRequest, _ ...grpc.CallOption) *apiProvider.PurgeRecycleResponse {
+					purged = append(purged, req.Ref.ResourceId.OpaqueId+"/"+req.Key)
+					return &apiProvider.PurgeRecycleResponse{Status: status.NewOK(ctx)}
+				}, nil,
+			)
+
+			// the trash-bins are not purged by the global settings
+			err := task.PurgeTrashBin("service-user-id", time.Time{}, task.Project, gatewaySelector, "")
+			Expect(err).To(BeNil())
+			Expect(purged).To(ConsistOf("project/old"))
+		})
 	})
 })
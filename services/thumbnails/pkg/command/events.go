@@ -0,0 +1,55 @@
+package command
+
+import (
+	"context"
+
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/events/stream"
+
+	"github.com/opencloud-eu/opencloud/pkg/generators"
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
+	"github.com/opencloud-eu/opencloud/services/thumbnails/pkg/config"
+	"github.com/opencloud-eu/opencloud/services/thumbnails/pkg/thumbnail/storage"
+)
+
+var _registeredEvents = []events.Unmarshaller{
+	selfdeletion.PersonalFilesPurged{},
+}
+
+// ListenForEvents removes the thumbnails of the files of deleted accounts
+func ListenForEvents(ctx context.Context, cfg *config.Config, l log.Logger) error {
+	connName := generators.GenerateConnectionName(cfg.Service.Name, generators.NTypeBus)
+	bus, err := stream.NatsFromConfig(connName, false, stream.NatsConfig(cfg.Events))
+	if err != nil {
+		l.Error().Err(err).Msg("cannot connect to nats")
+		return err
+	}
+
+	evChannel, err := events.Consume(bus, "thumbnails", _registeredEvents...)
+	if err != nil {
+		l.Error().Err(err).Msg("cannot consume from nats")
+		return err
+	}
+
+	thumbnailStorage := storage.NewFileSystemStorage(cfg.Thumbnail.FileSystemStorage, l)
+	for {
+		select {
+		case e, ok := <-evChannel:
+			if !ok {
+				return nil
+			}
+			switch ev := e.Event.(type) {
+			case selfdeletion.PersonalFilesPurged:
+				for _, checksum := range ev.Checksums {
+					if err := thumbnailStorage.Purge(checksum); err != nil {
+						l.Error().Err(err).Str("userid", ev.UserID.GetOpaqueId()).Msg("could not remove thumbnails of a deleted account")
+					}
+				}
+			}
+		case <-ctx.Done():
+			l.Info().Msg("context cancelled")
+			return nil
+		}
+	}
+}
@@ -95,6 +95,15 @@ func Server(cfg *config.Config) *cli.Command {
 			}
 			gr.Add(runner.NewGoMicroHttpServerRunner(cfg.Service.Name+".http", httpServer))
 
+			// add event handler
+			gr.Add(runner.New(cfg.Service.Name+".event",
+				func() error {
+					return ListenForEvents(ctx, cfg, logger)
+				}, func() {
+					logger.Info().Msg("stopping event handler")
+				},
+			))
+
 			grResults := gr.Run(ctx)
 
 			// return the first non-nil error found in the results
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
@@ -128,7 +129,11 @@ func (s Thumbnails) TransferTokenValidator(next http.Handler) http.Handler {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
-			return []byte(s.config.Thumbnail.TransferSecret), nil
+			keys := jwt.VerificationKeySet{Keys: []jwt.VerificationKey{[]byte(s.config.Thumbnail.TransferSecret)}}
+			for _, secret := range s.config.Thumbnail.PreviousTransferSecrets.Active(time.Now()) {
+				keys.Keys = append(keys.Keys, []byte(secret))
+			}
+			return keys, nil
 		})
 		if err != nil {
 			logger.Debug().
@@ -94,6 +94,18 @@ func (s FileSystem) Put(key string, img []byte) error {
 	return nil
 }
 
+// Purge removes all thumbnails of the source file with the given checksum
+func (s FileSystem) Purge(checksum string) error {
+	if len(checksum) < 5 || strings.ContainsAny(checksum, `/\.`) {
+		return errors.Errorf("invalid checksum \"%s\"", checksum)
+	}
+	dir := filepath.Join(s.root, filesDir, checksum[:2], checksum[2:4], checksum[4:])
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.Wrapf(err, "could not remove the thumbnails of \"%s\"", checksum)
+	}
+	return nil
+}
+
 // BuildKey generate the unique key for a thumbnail.
 // The key is structure as follows:
 //
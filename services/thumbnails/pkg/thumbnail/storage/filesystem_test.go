@@ -6,6 +6,8 @@ import (
 
 	tAssert "github.com/stretchr/testify/assert"
 
+	"github.com/opencloud-eu/opencloud/pkg/log"
+	"github.com/opencloud-eu/opencloud/services/thumbnails/pkg/config"
 	"github.com/opencloud-eu/opencloud/services/thumbnails/pkg/thumbnail/storage"
 )
 
@@ -63,3 +65,24 @@ func TestFileSystem_BuildKey(t *testing.T) {
 	}
 
 }
+
+func TestFileSystem_Purge(t *testing.T) {
+	assert := tAssert.New(t)
+	s := storage.NewFileSystemStorage(config.FileSystemStorage{RootDirectory: t.TempDir()}, log.NopLogger())
+	r := storage.Request{
+		Checksum:   "120EA8A25E5D487BF68B5F7096440019",
+		Types:      []string{"png"},
+		Resolution: image.Rect(0, 0, 32, 32),
+	}
+	other := r
+	other.Checksum = "120EB8A25E5D487BF68B5F7096440019"
+
+	assert.NoError(s.Put(s.BuildKey(r), []byte("thumbnail")))
+	assert.NoError(s.Put(s.BuildKey(other), []byte("thumbnail")))
+
+	assert.NoError(s.Purge(r.Checksum))
+	assert.False(s.Stat(s.BuildKey(r)))
+	assert.True(s.Stat(s.BuildKey(other)))
+
+	assert.Error(s.Purge("../.."))
+}
@@ -29,4 +29,6 @@ type Storage interface {
 	Get(key string) ([]byte, error)
 	Put(key string, img []byte) error
 	BuildKey(r Request) string
+	// Purge removes all thumbnails of the source file with the given checksum
+	Purge(checksum string) error
 }
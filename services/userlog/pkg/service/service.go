@@ -23,6 +23,7 @@ import (
 	ehmsg "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/messages/eventhistory/v0"
 	ehsvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/eventhistory/v0"
 	settingssvc "github.com/opencloud-eu/opencloud/protogen/gen/opencloud/services/settings/v0"
+	"github.com/opencloud-eu/opencloud/services/graph/pkg/selfdeletion"
 	"github.com/opencloud-eu/opencloud/services/userlog/pkg/config"
 )
 
@@ -183,6 +184,14 @@ func (ul *UserlogService) processEvent(event events.Event) {
 		users, err = utils.ResolveID(ctx, e.GranteeUserID, e.GranteeGroupID, gwc)
 	case events.ShareExpired:
 		users, err = utils.ResolveID(ctx, e.GranteeUserID, e.GranteeGroupID, gwc)
+
+	// account related
+	case selfdeletion.AccountPurged:
+		// forget the events of the purged account
+		if err := ul.store.Delete(e.UserID.GetOpaqueId()); err != nil && err != store.ErrNotFound {
+			ul.log.Error().Err(err).Str("userID", e.UserID.GetOpaqueId()).Msg("failed to remove the events of a purged account")
+		}
+		return
 	}
 
 	if err != nil {
@@ -2,6 +2,7 @@ package defaults
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/opencloud-eu/opencloud/pkg/config/defaults"
 	"github.com/opencloud-eu/opencloud/pkg/shared"
@@ -38,23 +39,26 @@ func DefaultConfig() *config.Config {
 		Driver: "ldap",
 		Drivers: config.Drivers{
 			LDAP: config.LDAPDriver{
-				URI:                      "ldaps://localhost:9235",
-				CACert:                   filepath.Join(defaults.BaseDataPath(), "idm", "ldap.crt"),
-				Insecure:                 false,
-				UserBaseDN:               "ou=users,o=libregraph-idm",
-				GroupBaseDN:              "ou=groups,o=libregraph-idm",
-				UserScope:                "sub",
-				GroupScope:               "sub",
-				UserSubstringFilterType:  "any",
-				UserFilter:               "",
-				GroupFilter:              "",
-				UserObjectClass:          "inetOrgPerson",
-				GroupObjectClass:         "groupOfNames",
-				BindDN:                   "uid=reva,ou=sysusers,o=libregraph-idm",
-				DisableUserMechanism:     "attribute",
-				LdapDisabledUsersGroupDN: "cn=DisabledUsersGroup,ou=groups,o=libregraph-idm",
-				UserTypeAttribute:        "openCloudUserType",
-				IDP:                      "https://localhost:9200",
+				URI:                             "ldaps://localhost:9235",
+				CACert:                          filepath.Join(defaults.BaseDataPath(), "idm", "ldap.crt"),
+				Insecure:                        false,
+				UserBaseDN:                      "ou=users,o=libregraph-idm",
+				GroupBaseDN:                     "ou=groups,o=libregraph-idm",
+				UserScope:                       "sub",
+				GroupScope:                      "sub",
+				UserSubstringFilterType:         "any",
+				UserFilter:                      "",
+				GroupFilter:                     "",
+				UserObjectClass:                 "inetOrgPerson",
+				GroupObjectClass:                "groupOfNames",
+				BindDN:                          "uid=reva,ou=sysusers,o=libregraph-idm",
+				DisableUserMechanism:            "attribute",
+				LdapDisabledUsersGroupDN:        "cn=DisabledUsersGroup,ou=groups,o=libregraph-idm",
+				UserTypeAttribute:               "openCloudUserType",
+				IDP:                             "https://localhost:9200",
+				GroupNestingMaxDepth:            10,
+				GroupNestingMatchingRuleInChain: "auto",
+				GroupNestingCacheTTL:            time.Minute,
 				UserSchema: config.LDAPUserSchema{
 					ID:          "openclouduuid",
 					Mail:        "mail",
@@ -118,7 +122,8 @@ func EnsureDefaults(cfg *config.Config) {
 
 	if cfg.TokenManager == nil && cfg.Commons != nil && cfg.Commons.TokenManager != nil {
 		cfg.TokenManager = &config.TokenManager{
-			JWTSecret: cfg.Commons.TokenManager.JWTSecret,
+			JWTSecret:          cfg.Commons.TokenManager.JWTSecret,
+			PreviousJWTSecrets: cfg.Commons.TokenManager.PreviousJWTSecrets,
 		}
 	} else if cfg.TokenManager == nil {
 		cfg.TokenManager = &config.TokenManager{}
@@ -1,11 +1,18 @@
 package revaconfig
 
 import (
+	"github.com/opencloud-eu/opencloud/pkg/ldap"
+	"github.com/opencloud-eu/opencloud/pkg/secrets"
 	"github.com/opencloud-eu/opencloud/services/users/pkg/config"
 )
 
 // UsersConfigFromStruct will adapt an OpenCloud config struct into a reva mapstructure to start a reva service.
 func UsersConfigFromStruct(cfg *config.Config) map[string]interface{} {
+	driver := cfg.Driver
+	if driver == "ldap" && cfg.Drivers.LDAP.GroupNestingEnabled {
+		driver = ldap.NestedGroupsUserManagerName
+	}
+
 	rcfg := map[string]interface{}{
 		"shared": map[string]interface{}{
 			"jwt_secret":                cfg.TokenManager.JWTSecret,
@@ -25,12 +32,13 @@ func UsersConfigFromStruct(cfg *config.Config) map[string]interface{} {
 			// TODO build services dynamically
 			"services": map[string]interface{}{
 				"userprovider": map[string]interface{}{
-					"driver": cfg.Driver,
+					"driver": driver,
 					"drivers": map[string]interface{}{
 						"json": map[string]interface{}{
 							"users": cfg.Drivers.JSON.File,
 						},
-						"ldap": ldapConfigFromString(cfg.Drivers.LDAP),
+						"ldap":                           ldapConfigFromString(cfg.Drivers.LDAP),
+						ldap.NestedGroupsUserManagerName: ldapConfigFromString(cfg.Drivers.LDAP),
 						"owncloudsql": map[string]interface{}{
 							"dbusername":           cfg.Drivers.OwnCloudSQL.DBUsername,
 							"dbpassword":           cfg.Drivers.OwnCloudSQL.DBPassword,
@@ -47,6 +55,9 @@ func UsersConfigFromStruct(cfg *config.Config) map[string]interface{} {
 				},
 			},
 			"interceptors": map[string]interface{}{
+				"auth": map[string]interface{}{
+					"token_managers": secrets.TokenManagersConfig(cfg.TokenManager.JWTSecret, cfg.TokenManager.PreviousJWTSecrets),
+				},
 				"prometheus": map[string]interface{}{
 					"namespace": "opencloud",
 					"subsystem": "users",
@@ -94,5 +105,10 @@ func ldapConfigFromString(cfg config.LDAPDriver) map[string]interface{} {
 			"groupName":       cfg.GroupSchema.Groupname,
 			"member":          cfg.GroupSchema.Member,
 		},
+		"group_nesting": map[string]interface{}{
+			"max_depth":              cfg.GroupNestingMaxDepth,
+			"matching_rule_in_chain": cfg.GroupNestingMatchingRuleInChain,
+			"cache_ttl":              cfg.GroupNestingCacheTTL,
+		},
 	}
 }
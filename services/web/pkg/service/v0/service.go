@@ -74,6 +74,7 @@ func NewService(opts ...Option) (Service, error) {
 			r.Use(middleware.ExtractAccountUUID(
 				account.Logger(options.Logger),
 				account.JWTSecret(options.Config.TokenManager.JWTSecret),
+				account.PreviousJWTSecrets(options.Config.TokenManager.PreviousJWTSecrets),
 			))
 			r.Post("/", themeService.LogoUpload)
 			r.Delete("/", themeService.LogoReset)